@@ -28,6 +28,60 @@ type ClusterKeycloakInstanceSpec struct {
 	// Token contains optional token caching configuration
 	// +optional
 	Token *TokenSpec `json:"token,omitempty"`
+
+	// AllowedNamespaces restricts which namespaces may reference this instance
+	// via clusterInstanceRef. Unset allows every namespace; a policy without
+	// rules allows none.
+	// +optional
+	AllowedNamespaces *NamespaceAccessPolicy `json:"allowedNamespaces,omitempty"`
+
+	// DeletionPolicy is the default deletion policy of the realms and
+	// resources that use this instance. Deleting the instance itself never
+	// changes Keycloak.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Suspend stops the operator from reconciling this resource and every
+	// resource that depends on it. Deletion is deferred until it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SyncInterval overrides --sync-period for this resource and, unless they
+	// set their own, for the resources that depend on it. Up to 10% jitter is
+	// added to spread the checks.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+}
+
+// NamespaceAccessPolicy limits the namespaces that may reference a
+// cluster-scoped resource. It is a struct rather than a list so that a policy
+// without rules, which allows no namespace, is kept when the resource is
+// written back and is never mistaken for an unset policy.
+type NamespaceAccessPolicy struct {
+	// Rules admit namespaces; a reference is allowed when any rule matches.
+	// +optional
+	Rules []NamespaceAccessRule `json:"rules,omitempty"`
+}
+
+// NamespaceAccessRule grants namespaces access to a cluster-scoped resource.
+// A namespace matches when it is listed in names or selected by selector; at
+// least one of the two must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.names) || has(self.selector)",message="one of names or selector must be set"
+type NamespaceAccessRule struct {
+	// Names lists namespaces by name.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Selector selects namespaces by label. An empty selector matches every
+	// namespace.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Kinds limits the rule to referring resources of these kinds (e.g.
+	// KeycloakClient, KeycloakUser). Empty applies the rule to every kind.
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
 }
 
 // ClusterTLSSpec is the cluster-scoped equivalent of TLSSpec; namespace is
@@ -154,6 +208,20 @@ type ClusterKeycloakInstanceStatus struct {
 	// +optional
 	Version string `json:"version,omitempty"`
 
+	// Capabilities lists the optional Admin API features the server supports,
+	// derived from its version and enabled features
+	// +optional
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// CapabilitiesDetected is set once capabilities were detected, so that an
+	// empty list means the server supports none of them
+	// +optional
+	CapabilitiesDetected bool `json:"capabilitiesDetected,omitempty"`
+
+	// Health reports the outcome of the last successful connection probe
+	// +optional
+	Health *InstanceHealthStatus `json:"health,omitempty"`
+
 	// Status is a human-readable status message
 	// +optional
 	Status string `json:"status,omitempty"`
@@ -166,6 +234,15 @@ type ClusterKeycloakInstanceStatus struct {
 	// +optional
 	ResourcePath string `json:"resourcePath,omitempty"`
 
+	// ObservedGeneration is the generation of the spec that was last processed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastHandledReconcileAt is the value of the
+	// keycloak.hostzero.com/reconcile-requested-at annotation last handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -8,6 +8,7 @@ import (
 // ClusterKeycloakRealmSpec defines the desired state of ClusterKeycloakRealm
 // +kubebuilder:validation:XValidation:rule="has(self.instanceRef) != has(self.clusterInstanceRef)",message="exactly one of instanceRef or clusterInstanceRef must be set"
 // +kubebuilder:validation:XValidation:rule="!has(oldSelf.realmName) || self.realmName == oldSelf.realmName",message="spec.realmName is immutable once set"
+// +kubebuilder:validation:XValidation:rule="!(has(self.deletionProtection) && self.deletionProtection && has(self.cascadeDeletion) && self.cascadeDeletion)",message="deletionProtection and cascadeDeletion are mutually exclusive"
 type ClusterKeycloakRealmSpec struct {
 	// InstanceRef is a reference to a namespaced KeycloakInstance
 	// One of instanceRef or clusterInstanceRef must be specified
@@ -36,6 +37,73 @@ type ClusterKeycloakRealmSpec struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Definition runtime.RawExtension `json:"definition"`
+
+	// AllowedNamespaces restricts which namespaces may reference this realm
+	// via clusterRealmRef. Unset allows every namespace; a policy without
+	// rules allows none.
+	// +optional
+	AllowedNamespaces *NamespaceAccessPolicy `json:"allowedNamespaces,omitempty"`
+
+	// NamingPolicy constrains the identifiers that namespaced resources create
+	// in this realm, so tenants sharing it cannot claim each other's names.
+	// +optional
+	NamingPolicy *NamingPolicy `json:"namingPolicy,omitempty"`
+
+	// Prune removes, or only reports in status.unmanaged, objects in the realm
+	// that are not backed by a resource or by the definition.
+	// +optional
+	Prune *RealmPruneSpec `json:"prune,omitempty"`
+
+	// DeletionPolicy decides what happens to the Keycloak object when this
+	// resource is deleted and, unless they set their own, when the resources
+	// that depend on it are. Defaults to the policy of the nearest parent that
+	// sets one, otherwise Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DeletionProtection blocks deletion of the realm while resources that
+	// depend on it exist.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// CascadeDeletion deletes the resources that depend on the realm, and
+	// waits for them to be gone, before the realm itself is deleted.
+	// +optional
+	CascadeDeletion bool `json:"cascadeDeletion,omitempty"`
+
+	// Suspend stops the operator from reconciling this resource and every
+	// resource that depends on it. Deletion is deferred until it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SyncInterval overrides --sync-period for this resource and, unless they
+	// set their own, for the resources that depend on it. Up to 10% jitter is
+	// added to spread the checks.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+}
+
+// NamingPolicy constrains realm-wide identifiers created from namespaced
+// resources: clientId, realm role names, top-level group names, client scope
+// names and identity provider aliases. Client roles and child groups are
+// scoped by their parent and are not checked.
+type NamingPolicy struct {
+	// Prefix every identifier must start with. "{{namespace}}" expands to the
+	// namespace of the resource, e.g. "{{namespace}}-".
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// AutoPrefix prepends the prefix to identifiers that lack it instead of
+	// rejecting them.
+	// +optional
+	AutoPrefix bool `json:"autoPrefix,omitempty"`
+
+	// AllowedPatterns are regular expressions of which the (prefixed)
+	// identifier must match at least one in full. "{{namespace}}" expands to
+	// the namespace of the resource. Empty allows every identifier.
+	// +optional
+	AllowedPatterns []string `json:"allowedPatterns,omitempty"`
 }
 
 // NamespacedRef is a reference to a namespaced resource (required namespace)
@@ -74,6 +142,25 @@ type ClusterKeycloakRealmStatus struct {
 	// +optional
 	Instance *InstanceRef `json:"instance,omitempty"`
 
+	// Unmanaged lists the objects found by spec.prune in DryRun mode
+	// +optional
+	Unmanaged []UnmanagedObject `json:"unmanaged,omitempty"`
+
+	// Orphaned lists the objects left in Keycloak by resources deleted with
+	// the Orphan deletion policy. spec.prune never removes them; an entry is
+	// dropped once its object is gone or managed again
+	// +optional
+	Orphaned []UnmanagedObject `json:"orphaned,omitempty"`
+
+	// ObservedGeneration is the generation of the spec that was last processed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastHandledReconcileAt is the value of the
+	// keycloak.hostzero.com/reconcile-requested-at annotation last handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -38,6 +38,25 @@ type KeycloakClientSpec struct {
 	// is no client_secret to store.
 	// +optional
 	ClientSecretRef *ClientSecretRefSpec `json:"clientSecretRef,omitempty"`
+
+	// DeletionPolicy decides what happens to the Keycloak object when this
+	// resource is deleted and, unless they set their own, when the resources
+	// that depend on it are. Defaults to the policy of the nearest parent that
+	// sets one, otherwise Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Suspend stops the operator from reconciling this resource and every
+	// resource that depends on it. Deletion is deferred until it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SyncInterval overrides --sync-period for this resource and, unless they
+	// set their own, for the resources that depend on it. Up to 10% jitter is
+	// added to spread the checks.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
 }
 
 // ClientSecretRefSpec references a Kubernetes Secret for the client credentials.
@@ -104,6 +123,11 @@ type KeycloakClientStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
+	// LastHandledReconcileAt is the value of the
+	// keycloak.hostzero.com/reconcile-requested-at annotation last handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
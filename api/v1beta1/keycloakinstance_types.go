@@ -26,6 +26,24 @@ type KeycloakInstanceSpec struct {
 	// Token contains optional token caching configuration
 	// +optional
 	Token *TokenSpec `json:"token,omitempty"`
+
+	// DeletionPolicy is the default deletion policy of the realms and
+	// resources that use this instance. Deleting the instance itself never
+	// changes Keycloak.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Suspend stops the operator from reconciling this resource and every
+	// resource that depends on it. Deletion is deferred until it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SyncInterval overrides --sync-period for this resource and, unless they
+	// set their own, for the resources that depend on it. Up to 10% jitter is
+	// added to spread the checks.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
 }
 
 // TLSSpec configures TLS verification for the Keycloak HTTPS endpoint.
@@ -181,6 +199,20 @@ type KeycloakInstanceStatus struct {
 	// +optional
 	Version string `json:"version,omitempty"`
 
+	// Capabilities lists the optional Admin API features the server supports,
+	// derived from its version and enabled features
+	// +optional
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// CapabilitiesDetected is set once capabilities were detected, so that an
+	// empty list means the server supports none of them
+	// +optional
+	CapabilitiesDetected bool `json:"capabilitiesDetected,omitempty"`
+
+	// Health reports the outcome of the last successful connection probe
+	// +optional
+	Health *InstanceHealthStatus `json:"health,omitempty"`
+
 	// Status is a human-readable status message
 	// +optional
 	Status string `json:"status,omitempty"`
@@ -193,11 +225,46 @@ type KeycloakInstanceStatus struct {
 	// +optional
 	ResourcePath string `json:"resourcePath,omitempty"`
 
+	// ObservedGeneration is the generation of the spec that was last processed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastHandledReconcileAt is the value of the
+	// keycloak.hostzero.com/reconcile-requested-at annotation last handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// InstanceHealthStatus reports connection health of a Keycloak instance
+type InstanceHealthStatus struct {
+	// LastContactTime is when the server last answered a probe; it is
+	// refreshed, with the latency and token expiry, once per sync interval
+	// +optional
+	LastContactTime *metav1.Time `json:"lastContactTime,omitempty"`
+
+	// LatencyMilliseconds is the round-trip time of the last probe
+	// +optional
+	LatencyMilliseconds int64 `json:"latencyMilliseconds,omitempty"`
+
+	// TokenExpiryTime is when the operator's admin access token expires
+	// +optional
+	TokenExpiryTime *metav1.Time `json:"tokenExpiryTime,omitempty"`
+
+	// CertificateExpiryTime is the earliest notAfter in the server's TLS
+	// certificate chain. Unset when the server is reached over plain HTTP.
+	// +optional
+	CertificateExpiryTime *metav1.Time `json:"certificateExpiryTime,omitempty"`
+
+	// AdminPrincipal is the user the operator authenticates as; for the
+	// client credentials grant this is the client's service account
+	// +optional
+	AdminPrincipal string `json:"adminPrincipal,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`,description="Whether the instance is ready"
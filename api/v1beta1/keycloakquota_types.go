@@ -0,0 +1,98 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KeycloakQuotaSpec defines the desired state of KeycloakQuota
+// +kubebuilder:validation:XValidation:rule="!(has(self.realmRef) && has(self.clusterRealmRef))",message="at most one of realmRef or clusterRealmRef may be set"
+type KeycloakQuotaSpec struct {
+	// RealmRef limits the quota to objects in this KeycloakRealm.
+	// If neither realmRef nor clusterRealmRef is set, the quota counts objects
+	// in every realm.
+	// +optional
+	RealmRef *ResourceRef `json:"realmRef,omitempty"`
+
+	// ClusterRealmRef limits the quota to objects in this ClusterKeycloakRealm.
+	// +optional
+	ClusterRealmRef *ClusterResourceRef `json:"clusterRealmRef,omitempty"`
+
+	// Hard is the maximum number of objects of each kind the namespace may
+	// create in Keycloak. Kinds left unset are not limited.
+	// +kubebuilder:validation:Required
+	Hard QuotaCounts `json:"hard"`
+}
+
+// QuotaCounts holds a count per limited kind.
+type QuotaCounts struct {
+	// Clients counts KeycloakClients.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Clients *int32 `json:"clients,omitempty"`
+
+	// Users counts KeycloakUsers, including service account users.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Users *int32 `json:"users,omitempty"`
+
+	// Roles counts KeycloakRoles, realm and client roles alike.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Roles *int32 `json:"roles,omitempty"`
+
+	// Groups counts KeycloakGroups, including child groups.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Groups *int32 `json:"groups,omitempty"`
+
+	// IdentityProviders counts KeycloakIdentityProviders.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	IdentityProviders *int32 `json:"identityProviders,omitempty"`
+
+	// ProtocolMappers counts KeycloakProtocolMappers.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ProtocolMappers *int32 `json:"protocolMappers,omitempty"`
+}
+
+// KeycloakQuotaStatus defines the observed state of KeycloakQuota
+type KeycloakQuotaStatus struct {
+	// Used is the number of objects of each limited kind that currently exist
+	// in Keycloak.
+	// +optional
+	Used QuotaCounts `json:"used,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Realm",type=string,JSONPath=`.spec.realmRef.name`,description="Namespaced realm the quota is limited to"
+// +kubebuilder:printcolumn:name="Cluster Realm",type=string,JSONPath=`.spec.clusterRealmRef.name`,description="Cluster realm the quota is limited to"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:resource:shortName=kcq,categories={keycloak,all}
+
+// KeycloakQuota limits how many Keycloak objects a namespace may create
+type KeycloakQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KeycloakQuotaSpec   `json:"spec,omitempty"`
+	Status KeycloakQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KeycloakQuotaList contains a list of KeycloakQuota
+type KeycloakQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KeycloakQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KeycloakQuota{}, &KeycloakQuotaList{})
+}
@@ -29,9 +29,96 @@ type ConfigSecretRef struct {
 	Name string `json:"name"`
 }
 
+// DeletionPolicy decides what happens to the Keycloak object when its
+// resource is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the object from Keycloak.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan leaves the object in Keycloak untouched.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+
+	// DeletionPolicyDisable keeps the object but disables it. Only users
+	// support it.
+	DeletionPolicyDisable DeletionPolicy = "Disable"
+)
+
+// PruneMode decides what happens to Keycloak objects of a realm that no
+// resource manages.
+type PruneMode string
+
+const (
+	// PruneModeDelete deletes unmanaged objects from Keycloak.
+	PruneModeDelete PruneMode = "Delete"
+
+	// PruneModeDryRun only lists unmanaged objects in status.unmanaged.
+	PruneModeDryRun PruneMode = "DryRun"
+)
+
+// RealmPruneSpec selects, per object type, how objects in the realm that are
+// not backed by a resource or by the realm definition are handled. Types left
+// unset are not pruned. Built-in objects Keycloak creates for every realm are
+// never pruned.
+type RealmPruneSpec struct {
+	// Clients prunes clients.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	Clients PruneMode `json:"clients,omitempty"`
+
+	// Roles prunes realm roles and the client roles of managed clients.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	Roles PruneMode `json:"roles,omitempty"`
+
+	// Groups prunes groups. Subgroups are only checked below managed groups.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	Groups PruneMode `json:"groups,omitempty"`
+
+	// ClientScopes prunes client scopes.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	ClientScopes PruneMode `json:"clientScopes,omitempty"`
+
+	// IdentityProviders prunes identity providers.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	IdentityProviders PruneMode `json:"identityProviders,omitempty"`
+
+	// Components prunes components whose parent is the realm.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	Components PruneMode `json:"components,omitempty"`
+
+	// RequiredActions prunes registered required actions.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	RequiredActions PruneMode `json:"requiredActions,omitempty"`
+
+	// AuthenticationFlows prunes top-level authentication flows. Flows bound
+	// to the realm are kept.
+	// +kubebuilder:validation:Enum=Delete;DryRun
+	// +optional
+	AuthenticationFlows PruneMode `json:"authenticationFlows,omitempty"`
+}
+
+// UnmanagedObject identifies a Keycloak object of a realm that no resource
+// manages.
+type UnmanagedObject struct {
+	// Type is the object type, e.g. clients or client-scopes
+	Type string `json:"type"`
+
+	// Name is the object's identifier: clientId, alias, role name (prefixed
+	// with the clientId for client roles) or group path
+	Name string `json:"name"`
+}
+
 // KeycloakRealmSpec defines the desired state of KeycloakRealm
 // +kubebuilder:validation:XValidation:rule="has(self.instanceRef) != has(self.clusterInstanceRef)",message="exactly one of instanceRef or clusterInstanceRef must be set"
 // +kubebuilder:validation:XValidation:rule="!has(oldSelf.realmName) || self.realmName == oldSelf.realmName",message="spec.realmName is immutable once set"
+// +kubebuilder:validation:XValidation:rule="!(has(self.deletionProtection) && self.deletionProtection && has(self.cascadeDeletion) && self.cascadeDeletion)",message="deletionProtection and cascadeDeletion are mutually exclusive"
 type KeycloakRealmSpec struct {
 	// InstanceRef is a reference to a KeycloakInstance
 	// One of instanceRef or clusterInstanceRef must be specified
@@ -61,6 +148,40 @@ type KeycloakRealmSpec struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Definition runtime.RawExtension `json:"definition"`
+
+	// Prune removes, or only reports in status.unmanaged, objects in the realm
+	// that are not backed by a resource or by the definition.
+	// +optional
+	Prune *RealmPruneSpec `json:"prune,omitempty"`
+
+	// DeletionPolicy decides what happens to the Keycloak object when this
+	// resource is deleted and, unless they set their own, when the resources
+	// that depend on it are. Defaults to the policy of the nearest parent that
+	// sets one, otherwise Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DeletionProtection blocks deletion of the realm while resources that
+	// depend on it exist.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// CascadeDeletion deletes the resources that depend on the realm, and
+	// waits for them to be gone, before the realm itself is deleted.
+	// +optional
+	CascadeDeletion bool `json:"cascadeDeletion,omitempty"`
+
+	// Suspend stops the operator from reconciling this resource and every
+	// resource that depends on it. Deletion is deferred until it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SyncInterval overrides --sync-period for this resource and, unless they
+	// set their own, for the resources that depend on it. Up to 10% jitter is
+	// added to spread the checks.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
 }
 
 // SmtpSecretRefSpec references a Kubernetes Secret containing SMTP credentials.
@@ -127,6 +248,25 @@ type KeycloakRealmStatus struct {
 	// +optional
 	Instance *InstanceRef `json:"instance,omitempty"`
 
+	// Unmanaged lists the objects found by spec.prune in DryRun mode
+	// +optional
+	Unmanaged []UnmanagedObject `json:"unmanaged,omitempty"`
+
+	// Orphaned lists the objects left in Keycloak by resources deleted with
+	// the Orphan deletion policy. spec.prune never removes them; an entry is
+	// dropped once its object is gone or managed again
+	// +optional
+	Orphaned []UnmanagedObject `json:"orphaned,omitempty"`
+
+	// ObservedGeneration is the generation of the spec that was last processed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastHandledReconcileAt is the value of the
+	// keycloak.hostzero.com/reconcile-requested-at annotation last handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
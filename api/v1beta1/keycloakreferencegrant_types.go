@@ -0,0 +1,68 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KeycloakReferenceGrantSpec defines which resources in other namespaces may
+// read Secrets and ConfigMaps in the grant's namespace. It is modelled on the
+// Gateway API ReferenceGrant: the owner of the referenced objects consents by
+// creating the grant next to them.
+type KeycloakReferenceGrantSpec struct {
+	// From lists the referring resources this grant admits.
+	// +kubebuilder:validation:MinItems=1
+	From []ReferenceGrantFrom `json:"from"`
+
+	// To lists the objects in this namespace that may be referenced.
+	// +kubebuilder:validation:MinItems=1
+	To []ReferenceGrantTo `json:"to"`
+}
+
+// ReferenceGrantFrom identifies referring resources by kind and namespace.
+type ReferenceGrantFrom struct {
+	// Kind of the referring resource.
+	// +kubebuilder:validation:Enum=KeycloakInstance
+	Kind string `json:"kind"`
+
+	// Namespace of the referring resource.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+}
+
+// ReferenceGrantTo identifies objects in the grant's namespace.
+type ReferenceGrantTo struct {
+	// Kind of the referenced object.
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// Name restricts the grant to a single object. If unset, every object of
+	// Kind in the namespace may be referenced.
+	// +optional
+	Name *string `json:"name,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:resource:shortName=kcrg,categories={keycloak,all}
+
+// KeycloakReferenceGrant allows resources in other namespaces to reference
+// Secrets and ConfigMaps in its namespace
+type KeycloakReferenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KeycloakReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KeycloakReferenceGrantList contains a list of KeycloakReferenceGrant
+type KeycloakReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KeycloakReferenceGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KeycloakReferenceGrant{}, &KeycloakReferenceGrantList{})
+}
@@ -67,6 +67,25 @@ type KeycloakUserSpec struct {
 	// For managed credentials stored in a Kubernetes secret, use KeycloakUserCredential.
 	// +optional
 	InitialPassword *InitialPassword `json:"initialPassword,omitempty"`
+
+	// DeletionPolicy decides what happens to the Keycloak user when this
+	// resource is deleted: Delete removes it, Orphan leaves it untouched and
+	// Disable keeps it with enabled set to false. Defaults to the policy of
+	// the nearest parent that sets one, otherwise Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan;Disable
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Suspend stops the operator from reconciling this resource and every
+	// resource that depends on it. Deletion is deferred until it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SyncInterval overrides --sync-period for this resource and, unless they
+	// set their own, for the resources that depend on it. Up to 10% jitter is
+	// added to spread the checks.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
 }
 
 // InitialPassword defines the initial password for a user
@@ -124,6 +143,11 @@ type KeycloakUserStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
+	// LastHandledReconcileAt is the value of the
+	// keycloak.hostzero.com/reconcile-requested-at annotation last handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
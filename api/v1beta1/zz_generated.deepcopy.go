@@ -370,6 +370,16 @@ func (in *ClusterKeycloakInstanceSpec) DeepCopyInto(out *ClusterKeycloakInstance
 		*out = new(TokenSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(NamespaceAccessPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterKeycloakInstanceSpec.
@@ -385,6 +395,16 @@ func (in *ClusterKeycloakInstanceSpec) DeepCopy() *ClusterKeycloakInstanceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterKeycloakInstanceStatus) DeepCopyInto(out *ClusterKeycloakInstanceStatus) {
 	*out = *in
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(InstanceHealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -487,6 +507,26 @@ func (in *ClusterKeycloakRealmSpec) DeepCopyInto(out *ClusterKeycloakRealmSpec)
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(NamespaceAccessPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamingPolicy != nil {
+		in, out := &in.NamingPolicy, &out.NamingPolicy
+		*out = new(NamingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prune != nil {
+		in, out := &in.Prune, &out.Prune
+		*out = new(RealmPruneSpec)
+		**out = **in
+	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterKeycloakRealmSpec.
@@ -507,6 +547,16 @@ func (in *ClusterKeycloakRealmStatus) DeepCopyInto(out *ClusterKeycloakRealmStat
 		*out = new(InstanceRef)
 		**out = **in
 	}
+	if in.Unmanaged != nil {
+		in, out := &in.Unmanaged, &out.Unmanaged
+		*out = make([]UnmanagedObject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Orphaned != nil {
+		in, out := &in.Orphaned, &out.Orphaned
+		*out = make([]UnmanagedObject, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -697,6 +747,33 @@ func (in *InitialPassword) DeepCopy() *InitialPassword {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceHealthStatus) DeepCopyInto(out *InstanceHealthStatus) {
+	*out = *in
+	if in.LastContactTime != nil {
+		in, out := &in.LastContactTime, &out.LastContactTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TokenExpiryTime != nil {
+		in, out := &in.TokenExpiryTime, &out.TokenExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CertificateExpiryTime != nil {
+		in, out := &in.CertificateExpiryTime, &out.CertificateExpiryTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceHealthStatus.
+func (in *InstanceHealthStatus) DeepCopy() *InstanceHealthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceHealthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceRef) DeepCopyInto(out *InstanceRef) {
 	*out = *in
@@ -785,6 +862,11 @@ func (in *KeycloakAuthenticationFlowSpec) DeepCopyInto(out *KeycloakAuthenticati
 		**out = **in
 	}
 	in.Executions.DeepCopyInto(&out.Executions)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakAuthenticationFlowSpec.
@@ -966,6 +1048,11 @@ func (in *KeycloakClientScopeSpec) DeepCopyInto(out *KeycloakClientScopeSpec) {
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakClientScopeSpec.
@@ -1038,6 +1125,11 @@ func (in *KeycloakClientSpec) DeepCopyInto(out *KeycloakClientSpec) {
 		*out = new(ClientSecretRefSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakClientSpec.
@@ -1165,6 +1257,11 @@ func (in *KeycloakComponentSpec) DeepCopyInto(out *KeycloakComponentSpec) {
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakComponentSpec.
@@ -1292,6 +1389,11 @@ func (in *KeycloakGroupSpec) DeepCopyInto(out *KeycloakGroupSpec) {
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakGroupSpec.
@@ -1469,6 +1571,11 @@ func (in *KeycloakIdentityProviderMapperSpec) DeepCopyInto(out *KeycloakIdentity
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakIdentityProviderMapperSpec.
@@ -1547,6 +1654,11 @@ func (in *KeycloakIdentityProviderSpec) DeepCopyInto(out *KeycloakIdentityProvid
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakIdentityProviderSpec.
@@ -1674,6 +1786,11 @@ func (in *KeycloakInstanceSpec) DeepCopyInto(out *KeycloakInstanceSpec) {
 		*out = new(TokenSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakInstanceSpec.
@@ -1689,6 +1806,16 @@ func (in *KeycloakInstanceSpec) DeepCopy() *KeycloakInstanceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeycloakInstanceStatus) DeepCopyInto(out *KeycloakInstanceStatus) {
 	*out = *in
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(InstanceHealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -1786,6 +1913,11 @@ func (in *KeycloakOrganizationSpec) DeepCopyInto(out *KeycloakOrganizationSpec)
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakOrganizationSpec.
@@ -1913,6 +2045,11 @@ func (in *KeycloakProtocolMapperSpec) DeepCopyInto(out *KeycloakProtocolMapperSp
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakProtocolMapperSpec.
@@ -1957,6 +2094,107 @@ func (in *KeycloakProtocolMapperStatus) DeepCopy() *KeycloakProtocolMapperStatus
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakQuota) DeepCopyInto(out *KeycloakQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakQuota.
+func (in *KeycloakQuota) DeepCopy() *KeycloakQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KeycloakQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakQuotaList) DeepCopyInto(out *KeycloakQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KeycloakQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakQuotaList.
+func (in *KeycloakQuotaList) DeepCopy() *KeycloakQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KeycloakQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakQuotaSpec) DeepCopyInto(out *KeycloakQuotaSpec) {
+	*out = *in
+	if in.RealmRef != nil {
+		in, out := &in.RealmRef, &out.RealmRef
+		*out = new(ResourceRef)
+		**out = **in
+	}
+	if in.ClusterRealmRef != nil {
+		in, out := &in.ClusterRealmRef, &out.ClusterRealmRef
+		*out = new(ClusterResourceRef)
+		**out = **in
+	}
+	in.Hard.DeepCopyInto(&out.Hard)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakQuotaSpec.
+func (in *KeycloakQuotaSpec) DeepCopy() *KeycloakQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakQuotaStatus) DeepCopyInto(out *KeycloakQuotaStatus) {
+	*out = *in
+	in.Used.DeepCopyInto(&out.Used)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakQuotaStatus.
+func (in *KeycloakQuotaStatus) DeepCopy() *KeycloakQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeycloakRealm) DeepCopyInto(out *KeycloakRealm) {
 	*out = *in
@@ -2040,6 +2278,16 @@ func (in *KeycloakRealmSpec) DeepCopyInto(out *KeycloakRealmSpec) {
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.Prune != nil {
+		in, out := &in.Prune, &out.Prune
+		*out = new(RealmPruneSpec)
+		**out = **in
+	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakRealmSpec.
@@ -2060,6 +2308,16 @@ func (in *KeycloakRealmStatus) DeepCopyInto(out *KeycloakRealmStatus) {
 		*out = new(InstanceRef)
 		**out = **in
 	}
+	if in.Unmanaged != nil {
+		in, out := &in.Unmanaged, &out.Unmanaged
+		*out = make([]UnmanagedObject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Orphaned != nil {
+		in, out := &in.Orphaned, &out.Orphaned
+		*out = make([]UnmanagedObject, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -2079,6 +2337,91 @@ func (in *KeycloakRealmStatus) DeepCopy() *KeycloakRealmStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakReferenceGrant) DeepCopyInto(out *KeycloakReferenceGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakReferenceGrant.
+func (in *KeycloakReferenceGrant) DeepCopy() *KeycloakReferenceGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakReferenceGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KeycloakReferenceGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakReferenceGrantList) DeepCopyInto(out *KeycloakReferenceGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KeycloakReferenceGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakReferenceGrantList.
+func (in *KeycloakReferenceGrantList) DeepCopy() *KeycloakReferenceGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakReferenceGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KeycloakReferenceGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakReferenceGrantSpec) DeepCopyInto(out *KeycloakReferenceGrantSpec) {
+	*out = *in
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = make([]ReferenceGrantFrom, len(*in))
+		copy(*out, *in)
+	}
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]ReferenceGrantTo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakReferenceGrantSpec.
+func (in *KeycloakReferenceGrantSpec) DeepCopy() *KeycloakReferenceGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakReferenceGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeycloakRequiredAction) DeepCopyInto(out *KeycloakRequiredAction) {
 	*out = *in
@@ -2162,6 +2505,11 @@ func (in *KeycloakRequiredActionSpec) DeepCopyInto(out *KeycloakRequiredActionSp
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakRequiredActionSpec.
@@ -2338,6 +2686,11 @@ func (in *KeycloakRoleMappingSpec) DeepCopyInto(out *KeycloakRoleMappingSpec) {
 		*out = new(ResourceRef)
 		**out = **in
 	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakRoleMappingSpec.
@@ -2406,6 +2759,11 @@ func (in *KeycloakRoleSpec) DeepCopyInto(out *KeycloakRoleSpec) {
 		**out = **in
 	}
 	in.Definition.DeepCopyInto(&out.Definition)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakRoleSpec.
@@ -2541,6 +2899,11 @@ func (in *KeycloakUserCredentialSpec) DeepCopyInto(out *KeycloakUserCredentialSp
 	*out = *in
 	out.UserRef = in.UserRef
 	in.UserSecret.DeepCopyInto(&out.UserSecret)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakUserCredentialSpec.
@@ -2688,6 +3051,11 @@ func (in *KeycloakUserSpec) DeepCopyInto(out *KeycloakUserSpec) {
 		*out = new(InitialPassword)
 		**out = **in
 	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakUserSpec.
@@ -2732,6 +3100,58 @@ func (in *KeycloakUserStatus) DeepCopy() *KeycloakUserStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceAccessPolicy) DeepCopyInto(out *NamespaceAccessPolicy) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]NamespaceAccessRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceAccessPolicy.
+func (in *NamespaceAccessPolicy) DeepCopy() *NamespaceAccessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceAccessPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceAccessRule) DeepCopyInto(out *NamespaceAccessRule) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceAccessRule.
+func (in *NamespaceAccessRule) DeepCopy() *NamespaceAccessRule {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceAccessRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespacedRef) DeepCopyInto(out *NamespacedRef) {
 	*out = *in
@@ -2747,6 +3167,26 @@ func (in *NamespacedRef) DeepCopy() *NamespacedRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamingPolicy) DeepCopyInto(out *NamingPolicy) {
+	*out = *in
+	if in.AllowedPatterns != nil {
+		in, out := &in.AllowedPatterns, &out.AllowedPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamingPolicy.
+func (in *NamingPolicy) DeepCopy() *NamingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NamingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PasswordGrantSecretRefSpec) DeepCopyInto(out *PasswordGrantSecretRefSpec) {
 	*out = *in
@@ -2813,6 +3253,66 @@ func (in *PasswordPolicySpec) DeepCopy() *PasswordPolicySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaCounts) DeepCopyInto(out *QuotaCounts) {
+	*out = *in
+	if in.Clients != nil {
+		in, out := &in.Clients, &out.Clients
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IdentityProviders != nil {
+		in, out := &in.IdentityProviders, &out.IdentityProviders
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ProtocolMappers != nil {
+		in, out := &in.ProtocolMappers, &out.ProtocolMappers
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaCounts.
+func (in *QuotaCounts) DeepCopy() *QuotaCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RealmPruneSpec) DeepCopyInto(out *RealmPruneSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RealmPruneSpec.
+func (in *RealmPruneSpec) DeepCopy() *RealmPruneSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RealmPruneSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RealmRef) DeepCopyInto(out *RealmRef) {
 	*out = *in
@@ -2828,6 +3328,41 @@ func (in *RealmRef) DeepCopy() *RealmRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReferenceGrantFrom) DeepCopyInto(out *ReferenceGrantFrom) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReferenceGrantFrom.
+func (in *ReferenceGrantFrom) DeepCopy() *ReferenceGrantFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(ReferenceGrantFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReferenceGrantTo) DeepCopyInto(out *ReferenceGrantTo) {
+	*out = *in
+	if in.Name != nil {
+		in, out := &in.Name, &out.Name
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReferenceGrantTo.
+func (in *ReferenceGrantTo) DeepCopy() *ReferenceGrantTo {
+	if in == nil {
+		return nil
+	}
+	out := new(ReferenceGrantTo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceRef) DeepCopyInto(out *ResourceRef) {
 	*out = *in
@@ -3003,3 +3538,18 @@ func (in *TokenSpec) DeepCopy() *TokenSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnmanagedObject) DeepCopyInto(out *UnmanagedObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnmanagedObject.
+func (in *UnmanagedObject) DeepCopy() *UnmanagedObject {
+	if in == nil {
+		return nil
+	}
+	out := new(UnmanagedObject)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,114 @@
+// Package apply provides the CLI for reconciling manifests without Kubernetes.
+package apply
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/apply"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// Run executes the apply command with the given arguments
+func Run(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	opts := &Options{}
+	opts.BindFlags(fs)
+
+	// Parse flags
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Setup logger; the reconcilers only log with --verbose
+	log := logr.Discard()
+	if opts.Verbose {
+		zapOpts := zap.Options{Development: true}
+		log = zap.New(zap.UseFlagOptions(&zapOpts))
+	}
+
+	// Validate options
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Create context with signal handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	result, err := runApply(ctx, opts, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+func runApply(ctx context.Context, opts *Options, log logr.Logger) (*apply.Result, error) {
+	objects, err := manifest.Load(opts.Namespace, opts.Files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifests: %w", err)
+	}
+	if opts.SecretsDir != "" {
+		secrets, err := manifest.LoadSecretsDir(opts.Namespace, opts.SecretsDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load secrets: %w", err)
+		}
+		objects = append(objects, secrets...)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Loaded %d objects\n", len(objects))
+	}
+
+	result, err := apply.Apply(ctx, objects, apply.Options{
+		Getenv:    os.LookupEnv,
+		MaxRounds: opts.MaxRounds,
+		Log:       log,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.Write(os.Stdout); err != nil {
+		return nil, err
+	}
+
+	// Generated Secrets only exist in memory; keep them for the next run.
+	for _, secret := range result.GeneratedSecrets {
+		if opts.SecretsDir == "" {
+			fmt.Fprintf(os.Stderr, "Warning: generated Secret %s/%s is not saved; use --secrets-dir to keep it\n", secret.Namespace, secret.Name)
+			continue
+		}
+		if secret.Namespace != opts.Namespace {
+			fmt.Fprintf(os.Stderr, "Warning: generated Secret %s/%s is not saved; --secrets-dir only holds namespace %s\n", secret.Namespace, secret.Name, opts.Namespace)
+			continue
+		}
+		if err := manifest.WriteSecretsDir(opts.SecretsDir, secret); err != nil {
+			return nil, fmt.Errorf("failed to save generated Secret %s: %w", secret.Name, err)
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "Saved generated Secret %s to %s\n", secret.Name, opts.SecretsDir)
+		}
+	}
+	return result, nil
+}
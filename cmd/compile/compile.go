@@ -0,0 +1,85 @@
+// Package compile provides the CLI for compiling CRD manifests into a Keycloak realm import.
+package compile
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/compile"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// Run executes the compile command with the given arguments
+func Run(args []string) {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	opts := &Options{}
+	opts.BindFlags(fs)
+
+	// Parse flags
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate options
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if err := runCompile(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runCompile(opts *Options) error {
+	objects, err := manifest.Load(opts.Namespace, opts.Files...)
+	if err != nil {
+		return fmt.Errorf("failed to load manifests: %w", err)
+	}
+	if opts.SecretsDir != "" {
+		secrets, err := manifest.LoadSecretsDir(opts.Namespace, opts.SecretsDir)
+		if err != nil {
+			return fmt.Errorf("failed to load secrets: %w", err)
+		}
+		objects = append(objects, secrets...)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Loaded %d objects\n", len(objects))
+	}
+
+	result, err := compile.Compile(objects, compile.Options{Realm: opts.Realm})
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if len(result.Placeholders) > 0 {
+		fmt.Fprintln(os.Stderr, "Secrets not found; set these environment variables when importing:")
+		for _, p := range result.Placeholders {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+	}
+
+	data, err := json.MarshalIndent(result.Realm, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal realm: %w", err)
+	}
+	data = append(data, '\n')
+
+	if opts.Output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(opts.Output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
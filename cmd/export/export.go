@@ -59,10 +59,110 @@ func Run(args []string) {
 }
 
 func runExport(ctx context.Context, opts *Options, log logr.Logger) error {
+	exporterOpts := export.ExporterOptions{
+		Realm:           opts.Realm,
+		TargetNamespace: opts.TargetNamespace,
+		InstanceRef:     opts.InstanceRef,
+		RealmRef:        opts.RealmRef,
+		ClusterRealm:    opts.ClusterRealm,
+		ClusterInstance: opts.ClusterInstance,
+		Secrets:         export.SecretMode(opts.Secrets),
+		SecretStore: export.SecretStoreOptions{
+			Name: opts.SecretStore,
+			Kind: opts.SecretStoreKind,
+			Path: opts.SecretStorePath,
+		},
+		Include:      opts.Include,
+		Exclude:      opts.Exclude,
+		SkipDefaults: opts.SkipDefaults,
+	}
+
+	var resources []export.ExportedResource
+	var cfg *keycloak.Config
+	var err error
+	if opts.FromFile != "" {
+		resources, err = exportFile(opts, log, exporterOpts)
+	} else {
+		resources, cfg, err = exportServer(ctx, opts, log, exporterOpts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.GenerateInstance {
+		baseURL, username := opts.InstanceURL, ""
+		if cfg != nil {
+			if baseURL == "" {
+				baseURL = cfg.BaseURL
+			}
+			username = cfg.Username
+		}
+		resources = append(export.InstanceManifests(exporterOpts, baseURL, username), resources...)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Exported %d resources\n", len(resources))
+	}
+
+	// Write output
+	writer := export.NewWriter(export.WriterOptions{
+		OutputFile:  opts.Output,
+		OutputDir:   opts.OutputDir,
+		PerRealm:    opts.AllRealms,
+		Layout:      export.Layout(opts.Layout),
+		Namespace:   opts.TargetNamespace,
+		InstanceRef: opts.InstanceRef,
+	})
+
+	if err := writer.Write(resources); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+// exportFile converts a realm export file without contacting Keycloak.
+func exportFile(opts *Options, log logr.Logger, exporterOpts export.ExporterOptions) ([]export.ExportedResource, error) {
+	data, err := os.ReadFile(opts.FromFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read realm export: %w", err)
+	}
+
+	// Without a server, defaults can only come from the cache
+	if opts.Minimal {
+		version, err := export.FileVersion(data)
+		if err != nil {
+			return nil, err
+		}
+		exporterOpts.Defaults, err = export.CachedDefaults(opts.DefaultsCacheDir, version)
+		if err != nil {
+			return nil, fmt.Errorf("--minimal: %w (run a --minimal export against a Keycloak %s server once to cache them)", err, version)
+		}
+	}
+
+	if opts.AllRealms {
+		return export.ExportAllRealmsFromFile(data, log, exporterOpts)
+	}
+
+	exporter, err := export.NewFileExporter(data, log, exporterOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := exporter.Export()
+	if err != nil {
+		return nil, fmt.Errorf("export failed: %w", err)
+	}
+	return resources, nil
+}
+
+// exportServer exports from a running Keycloak and also returns the
+// configuration used to connect to it.
+func exportServer(ctx context.Context, opts *Options, log logr.Logger, exporterOpts export.ExporterOptions) ([]export.ExportedResource, *keycloak.Config, error) {
 	// Get Keycloak configuration
 	cfg, err := opts.GetKeycloakConfig(ctx, log)
 	if err != nil {
-		return fmt.Errorf("failed to get Keycloak configuration: %w", err)
+		return nil, nil, fmt.Errorf("failed to get Keycloak configuration: %w", err)
 	}
 
 	// Create Keycloak client
@@ -70,43 +170,39 @@ func runExport(ctx context.Context, opts *Options, log logr.Logger) error {
 
 	// Test connection
 	if err := client.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to connect to Keycloak at %s: %w", cfg.BaseURL, err)
+		return nil, nil, fmt.Errorf("failed to connect to Keycloak at %s: %w", cfg.BaseURL, err)
 	}
 
 	if opts.Verbose {
 		fmt.Fprintf(os.Stderr, "Connected to Keycloak at %s\n", cfg.BaseURL)
 	}
 
-	// Create exporter
-	exporter := export.NewExporter(client, log, export.ExporterOptions{
-		Realm:           opts.Realm,
-		TargetNamespace: opts.TargetNamespace,
-		InstanceRef:     opts.InstanceRef,
-		RealmRef:        opts.RealmRef,
-		Include:         opts.Include,
-		Exclude:         opts.Exclude,
-		SkipDefaults:    opts.SkipDefaults,
-	})
-
-	// Run export
-	resources, err := exporter.Export(ctx)
-	if err != nil {
-		return fmt.Errorf("export failed: %w", err)
+	if opts.Minimal {
+		exporterOpts.Defaults, err = export.LoadDefaults(ctx, client, opts.DefaultsCacheDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load defaults: %w", err)
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "Pruning the defaults of Keycloak %s\n", exporterOpts.Defaults.Version)
+		}
 	}
 
-	if opts.Verbose {
-		fmt.Fprintf(os.Stderr, "Exported %d resources\n", len(resources))
+	if opts.AllRealms {
+		resources, err := export.ExportAllRealms(ctx, client, log, exporterOpts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("export failed: %w", err)
+		}
+		return resources, cfg, nil
 	}
 
-	// Write output
-	writer := export.NewWriter(export.WriterOptions{
-		OutputFile: opts.Output,
-		OutputDir:  opts.OutputDir,
-	})
+	// Create exporter
+	exporter := export.NewExporter(client, log, exporterOpts)
 
-	if err := writer.Write(resources); err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+	// Run export
+	resources, err := exporter.Export(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("export failed: %w", err)
 	}
 
-	return nil
+	return resources, cfg, nil
 }
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -14,6 +15,7 @@ import (
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/export"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
@@ -29,17 +31,36 @@ type Options struct {
 	FromClusterInstance string
 	Namespace           string
 
+	// Offline options (from-file mode)
+	FromFile string
+
 	// Export options
-	Realm string
+	Realm     string
+	AllRealms bool
 
 	// Output options
 	Output    string
 	OutputDir string
+	Layout    string
 
 	// Manifest generation options
-	TargetNamespace string
-	InstanceRef     string
-	RealmRef        string
+	TargetNamespace  string
+	InstanceRef      string
+	RealmRef         string
+	ClusterRealm     bool
+	ClusterInstance  bool
+	GenerateInstance bool
+	InstanceURL      string
+
+	// Secret options
+	Secrets         string
+	SecretStore     string
+	SecretStoreKind string
+	SecretStorePath string
+
+	// Pruning options
+	Minimal          bool
+	DefaultsCacheDir string
 
 	// Filtering options
 	Include      []string
@@ -66,17 +87,36 @@ func (o *Options) BindFlags(fs *flag.FlagSet) {
 	fs.StringVar(&o.FromClusterInstance, "from-cluster-instance", "", "Name of ClusterKeycloakInstance CR to read connection details from")
 	fs.StringVar(&o.Namespace, "namespace", "", "Namespace of the KeycloakInstance CR (required with --from-instance)")
 
+	// Offline options (from-file mode)
+	fs.StringVar(&o.FromFile, "from-file", "", "Realm export JSON file to convert without connecting to Keycloak")
+
 	// Export options
-	fs.StringVar(&o.Realm, "realm", "", "Realm to export (required)")
+	fs.StringVar(&o.Realm, "realm", "", "Realm to export (required unless --from-file holds a single realm)")
+	fs.BoolVar(&o.AllRealms, "all-realms", false, "Export every realm except master (including master with --skip-defaults=false)")
 
 	// Output options
 	fs.StringVar(&o.Output, "output", "", "Output file path (default: stdout)")
 	fs.StringVar(&o.OutputDir, "output-dir", "", "Output directory for multiple files (creates directory structure)")
+	fs.StringVar(&o.Layout, "layout", string(export.LayoutDirectory), "Layout of --output-dir: directory, kustomize or helm")
 
 	// Manifest generation options
 	fs.StringVar(&o.TargetNamespace, "target-namespace", "default", "Namespace for generated manifests")
 	fs.StringVar(&o.InstanceRef, "instance-ref", "", "Name of KeycloakInstance to reference in generated manifests")
 	fs.StringVar(&o.RealmRef, "realm-ref", "", "Name of KeycloakRealm to reference (defaults to realm name)")
+	fs.BoolVar(&o.ClusterRealm, "cluster-realm", false, "Generate ClusterKeycloakRealm and clusterRealmRef instead of KeycloakRealm and realmRef")
+	fs.BoolVar(&o.ClusterInstance, "cluster-instance", false, "Reference --instance-ref as a ClusterKeycloakInstance")
+	fs.BoolVar(&o.GenerateInstance, "generate-instance", false, "Also generate the instance and a Secret with placeholder admin credentials")
+	fs.StringVar(&o.InstanceURL, "instance-url", "", "Base URL of the generated instance (defaults to the Keycloak exported from)")
+
+	// Secret options
+	fs.StringVar(&o.Secrets, "secrets", string(export.SecretModeNone), "How to export secrets: none, stub, value or external-secret")
+	fs.StringVar(&o.SecretStore, "secret-store", "", "SecretStore the ExternalSecrets read from (required with --secrets=external-secret)")
+	fs.StringVar(&o.SecretStoreKind, "secret-store-kind", "SecretStore", "Kind of --secret-store: SecretStore or ClusterSecretStore")
+	fs.StringVar(&o.SecretStorePath, "secret-store-path", "", "Path in the secret store the remote keys are under")
+
+	// Pruning options
+	fs.BoolVar(&o.Minimal, "minimal", false, "Omit fields equal to the defaults of the Keycloak version")
+	fs.StringVar(&o.DefaultsCacheDir, "defaults-cache-dir", "", "Directory the defaults of each Keycloak version are cached in (default: user cache directory)")
 
 	// Filtering options
 	fs.StringVar(&o.includeRaw, "include", "", "Comma-separated list of resource types to include (e.g., clients,users,groups)")
@@ -103,17 +143,58 @@ Connection Options (choose one mode):
     --from-cluster-instance  Name of ClusterKeycloakInstance CR
     --namespace              Namespace of KeycloakInstance
 
+  Offline, from a realm export file (kc.sh export or partial export):
+    --from-file     Realm export JSON file; no Keycloak connection is made
+
 Export Options:
-    --realm         Realm to export (required)
+    --realm         Realm to export (required, except with a single-realm --from-file)
+    --all-realms    Export every realm except master; object names are
+                    prefixed with their realm
 
 Output Options:
     --output        Output file path (default: stdout)
-    --output-dir    Output directory (creates file structure)
+    --output-dir    Output directory (creates file structure; with
+                    --all-realms, one subdirectory per realm)
+    --layout        Layout of --output-dir:
+                      directory  a file per resource, a directory per kind (default)
+                      kustomize  the directory layout as base/, listed by its
+                                 kustomization.yaml, and an overlay setting the
+                                 namespace and instance
+                      helm       a Helm chart with the directory layout as
+                                 templates/; the namespace, instance, realm
+                                 names and Secret names are values
 
 Manifest Options:
-    --target-namespace  Namespace for generated manifests (default: "default")
-    --instance-ref      KeycloakInstance name to reference
-    --realm-ref         KeycloakRealm name to reference
+    --target-namespace   Namespace for generated manifests (default: "default")
+    --instance-ref       KeycloakInstance name to reference (default: "keycloak-instance")
+    --realm-ref          KeycloakRealm name to reference
+    --cluster-realm      Generate a ClusterKeycloakRealm referenced by clusterRealmRef
+    --cluster-instance   Reference a ClusterKeycloakInstance instead of a KeycloakInstance
+    --generate-instance  Also generate the instance and its admin credentials Secret;
+                         the password is the placeholder ${KEYCLOAK_ADMIN_PASSWORD}
+    --instance-url       Base URL of the generated instance (default: the server
+                         exported from; required with --from-file)
+
+Secret Options:
+    --secrets            How to export client secrets, identity provider client
+                         secrets, LDAP bind credentials and SMTP passwords:
+                           none             drop them (default)
+                           stub             Secrets with ${VAR} placeholders
+                           value            Secrets with the values Keycloak
+                                            reveals, placeholders otherwise
+                           external-secret  ExternalSecrets reading from
+                                            --secret-store
+    --secret-store       SecretStore name for --secrets=external-secret
+    --secret-store-kind  SecretStore or ClusterSecretStore (default: "SecretStore")
+    --secret-store-path  Path of the remote keys; a Secret's remote key is
+                         <path>/<secret name>
+
+Pruning Options:
+    --minimal             Omit fields equal to Keycloak's defaults. The defaults
+                          are derived once per Keycloak version by creating blank
+                          objects in a temporary realm, and cached; --from-file
+                          uses the cached defaults of the version in the file
+    --defaults-cache-dir  Cache directory (default: <user cache dir>/keycloak-operator/defaults)
 
 Filtering Options:
     --include       Resource types to include (comma-separated)
@@ -122,7 +203,8 @@ Filtering Options:
 
 Resource types: realm, clients, client-scopes, users, groups, roles, 
                 role-mappings, identity-providers, components, 
-                protocol-mappers, organizations
+                protocol-mappers, organizations, required-actions,
+                authentication-flows
 
 Examples:
 
@@ -147,6 +229,44 @@ Examples:
     --namespace keycloak-operator \
     --realm my-realm
 
+  # Convert a realm export file without a running Keycloak
+  keycloak-operator export \
+    --from-file ./realm-export.json \
+    --output-dir ./manifests
+
+  # Export every realm with the instance, one directory per realm
+  keycloak-operator export \
+    --url https://keycloak.example.com \
+    --username admin \
+    --password "$KEYCLOAK_PASSWORD" \
+    --all-realms \
+    --generate-instance \
+    --output-dir ./manifests
+
+  # Export as a Helm chart
+  keycloak-operator export \
+    --from-file ./realm-export.json \
+    --layout helm \
+    --output-dir ./charts/my-realm
+
+  # Export with ExternalSecrets reading from Vault
+  keycloak-operator export \
+    --from-instance my-keycloak \
+    --namespace keycloak-operator \
+    --realm my-realm \
+    --secrets external-secret \
+    --secret-store vault \
+    --secret-store-kind ClusterSecretStore \
+    --secret-store-path keycloak/my-realm
+
+  # Export without the fields Keycloak fills in by default
+  keycloak-operator export \
+    --url https://keycloak.example.com \
+    --username admin \
+    --password "$KEYCLOAK_PASSWORD" \
+    --realm my-realm \
+    --minimal
+
   # Export only clients and users
   keycloak-operator export \
     --url https://keycloak.example.com \
@@ -184,13 +304,14 @@ func (o *Options) Validate() error {
 	// Validate connection mode
 	directMode := o.URL != ""
 	instanceMode := o.FromInstance != "" || o.FromClusterInstance != ""
+	fileMode := o.FromFile != ""
 
-	if !directMode && !instanceMode {
-		return fmt.Errorf("either --url or --from-instance/--from-cluster-instance is required")
+	if !directMode && !instanceMode && !fileMode {
+		return fmt.Errorf("either --url, --from-instance/--from-cluster-instance or --from-file is required")
 	}
 
-	if directMode && instanceMode {
-		return fmt.Errorf("cannot use both --url and --from-instance/--from-cluster-instance")
+	if (directMode && instanceMode) || (fileMode && (directMode || instanceMode)) {
+		return fmt.Errorf("only one of --url, --from-instance/--from-cluster-instance and --from-file can be used")
 	}
 
 	if directMode {
@@ -206,15 +327,57 @@ func (o *Options) Validate() error {
 		return fmt.Errorf("--namespace is required when using --from-instance")
 	}
 
-	// Validate realm
-	if o.Realm == "" {
-		return fmt.Errorf("--realm is required")
+	// Validate realm; a realm export file names its own realm
+	if o.AllRealms {
+		if o.Realm != "" || o.RealmRef != "" {
+			return fmt.Errorf("--all-realms cannot be used with --realm or --realm-ref")
+		}
+	} else if o.Realm == "" && !fileMode {
+		return fmt.Errorf("--realm or --all-realms is required")
+	}
+
+	// Validate instance generation
+	if o.InstanceURL != "" && !o.GenerateInstance {
+		return fmt.Errorf("--instance-url requires --generate-instance")
+	}
+	if o.GenerateInstance && fileMode && o.InstanceURL == "" {
+		return fmt.Errorf("--instance-url is required with --generate-instance and --from-file")
+	}
+
+	// Validate secret options
+	if !slices.Contains(export.SecretModes, export.SecretMode(o.Secrets)) {
+		return fmt.Errorf("--secrets must be one of none, stub, value or external-secret")
+	}
+	if o.Secrets == string(export.SecretModeExternalSecret) {
+		if o.SecretStore == "" {
+			return fmt.Errorf("--secret-store is required with --secrets=external-secret")
+		}
+		if o.SecretStoreKind != "SecretStore" && o.SecretStoreKind != "ClusterSecretStore" {
+			return fmt.Errorf("--secret-store-kind must be SecretStore or ClusterSecretStore")
+		}
+	} else if o.SecretStore != "" || o.SecretStorePath != "" {
+		return fmt.Errorf("--secret-store and --secret-store-path require --secrets=external-secret")
+	}
+
+	// Validate pruning options
+	if o.Minimal && o.DefaultsCacheDir == "" {
+		dir, err := export.DefaultsCacheDir()
+		if err != nil {
+			return fmt.Errorf("cannot determine the defaults cache directory, set --defaults-cache-dir: %w", err)
+		}
+		o.DefaultsCacheDir = dir
 	}
 
 	// Validate output options
 	if o.Output != "" && o.OutputDir != "" {
 		return fmt.Errorf("cannot use both --output and --output-dir")
 	}
+	if !slices.Contains(export.Layouts, export.Layout(o.Layout)) {
+		return fmt.Errorf("--layout must be one of directory, kustomize or helm")
+	}
+	if o.Layout != string(export.LayoutDirectory) && o.OutputDir == "" {
+		return fmt.Errorf("--layout %s requires --output-dir", o.Layout)
+	}
 
 	return nil
 }
@@ -17,7 +17,11 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	applycmd "github.com/Hostzero-GmbH/keycloak-operator/cmd/apply"
+	compilecmd "github.com/Hostzero-GmbH/keycloak-operator/cmd/compile"
 	exportcmd "github.com/Hostzero-GmbH/keycloak-operator/cmd/export"
+	plancmd "github.com/Hostzero-GmbH/keycloak-operator/cmd/plan"
+	validatecmd "github.com/Hostzero-GmbH/keycloak-operator/cmd/validate"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
@@ -32,18 +36,31 @@ func init() {
 	utilruntime.Must(keycloakv1beta1.AddToScheme(scheme))
 }
 
+// subcommands maps each offline subcommand to its entry point, which prints
+// the subcommand's usage when run with -h.
+var subcommands = map[string]func(args []string){
+	"export":   exportcmd.Run,
+	"compile":  compilecmd.Run,
+	"plan":     plancmd.Run,
+	"apply":    applycmd.Run,
+	"validate": validatecmd.Run,
+}
+
 func main() {
 	// Check for subcommands before parsing flags
 	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "export":
-			exportcmd.Run(os.Args[2:])
+		if run, ok := subcommands[os.Args[1]]; ok {
+			run(os.Args[2:])
 			return
+		}
+		switch os.Args[1] {
 		case "help", "-h", "--help":
 			// Show help for subcommands
-			if len(os.Args) > 2 && os.Args[2] == "export" {
-				exportcmd.Run([]string{"-h"})
-				return
+			if len(os.Args) > 2 {
+				if run, ok := subcommands[os.Args[2]]; ok {
+					run([]string{"-h"})
+					return
+				}
 			}
 			// Fall through to default operator help
 		}
@@ -54,6 +71,14 @@ func main() {
 	var probeAddr string
 	var syncPeriod time.Duration
 	var maxConcurrentRequests int
+	var breakerFailureThreshold int
+	var breakerCooldown time.Duration
+	var watchNamespaces string
+	var watchLabelSelector string
+	var clusterScopedResources bool
+	var leaderElectionID string
+	var requireReferenceGrants bool
+	var deletionTimeout time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -66,6 +91,27 @@ func main() {
 	flag.IntVar(&maxConcurrentRequests, "max-concurrent-requests", 10,
 		"Maximum number of concurrent requests to Keycloak. Set to 0 for no limit. "+
 			"Lower values reduce Keycloak load but increase reconciliation time.")
+	flag.IntVar(&breakerFailureThreshold, "breaker-failure-threshold", 5,
+		"Consecutive failed Keycloak requests after which an instance is considered unavailable "+
+			"and dependent resources stop calling it. Set to 0 to disable the circuit breaker.")
+	flag.DurationVar(&breakerCooldown, "breaker-cooldown", 30*time.Second,
+		"How long an unavailable instance is left alone before a trial request is let through.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma-separated namespaces to watch for namespaced resources. Empty watches all namespaces.")
+	flag.StringVar(&watchLabelSelector, "watch-label-selector", "",
+		"Only manage Keycloak resources matching this label selector (e.g. shard=a). "+
+			"Applies to namespaced and cluster-scoped kinds; Secrets and ConfigMaps are not filtered.")
+	flag.BoolVar(&clusterScopedResources, "cluster-scoped-resources", true,
+		"Reconcile ClusterKeycloakInstance and ClusterKeycloakRealm resources. "+
+			"Disable on all but one deployment when several operators split the cluster by namespace.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "keycloak-operator.hostzero.com",
+		"Name of the leader election lease. Give each operator deployment sharing a namespace its own ID.")
+	flag.BoolVar(&requireReferenceGrants, "require-reference-grants", true,
+		"Require a KeycloakReferenceGrant in the target namespace before a KeycloakInstance may read "+
+			"a Secret or ConfigMap from another namespace. Disable only on single-tenant clusters.")
+	flag.DurationVar(&deletionTimeout, "deletion-timeout", controller.DefaultDeletionTimeout,
+		"How long a deleted resource keeps its finalizer while removing its Keycloak object fails. "+
+			"After that the object is left in Keycloak. Set to 0 to retry until the removal succeeds.")
 
 	opts := zap.Options{
 		Development: true,
@@ -79,6 +125,26 @@ func main() {
 	controller.SetSyncPeriod(syncPeriod)
 	setupLog.Info("configured sync period", "syncPeriod", syncPeriod)
 	setupLog.Info("configured max concurrent requests", "maxConcurrentRequests", maxConcurrentRequests)
+	setupLog.Info("configured circuit breaker", "failureThreshold", breakerFailureThreshold, "cooldown", breakerCooldown)
+
+	controller.SetRequireReferenceGrants(requireReferenceGrants)
+	if !requireReferenceGrants {
+		setupLog.Info("cross-namespace secret references do not require a KeycloakReferenceGrant")
+	}
+
+	controller.SetDeletionTimeout(deletionTimeout)
+	setupLog.Info("configured deletion timeout", "deletionTimeout", deletionTimeout)
+
+	watchScope, err := controller.ParseWatchScope(watchNamespaces, watchLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid watch scope")
+		os.Exit(1)
+	}
+	if watchScope.IsClusterWide() {
+		setupLog.Info("watching all namespaces")
+	} else {
+		setupLog.Info("configured watch scope", "namespaces", watchScope.Namespaces, "labelSelector", watchLabelSelector)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
@@ -87,7 +153,9 @@ func main() {
 		},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "keycloak-operator.hostzero.com",
+		LeaderElectionID:       leaderElectionID,
+		Cache:                  watchScope.CacheOptions(),
+		NewClient:              watchScope.NewClient,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -96,7 +164,9 @@ func main() {
 
 	// Create shared Keycloak client manager with rate limiting
 	clientManager := keycloak.NewClientManagerWithConfig(ctrl.Log, keycloak.ClientManagerConfig{
-		MaxConcurrentRequests: maxConcurrentRequests,
+		MaxConcurrentRequests:   maxConcurrentRequests,
+		BreakerFailureThreshold: breakerFailureThreshold,
+		BreakerCooldown:         breakerCooldown,
 	})
 
 	// Setup controllers
@@ -113,6 +183,7 @@ func main() {
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		ClientManager: clientManager,
+		WatchScope:    watchScope,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "KeycloakRealm")
 		os.Exit(1)
@@ -154,22 +225,29 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.ClusterKeycloakInstanceReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		ClientManager: clientManager,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ClusterKeycloakInstance")
-		os.Exit(1)
-	}
+	// Cluster-scoped kinds are shared by every deployment; with
+	// --cluster-scoped-resources=false this one only reads them.
+	if clusterScopedResources {
+		if err = (&controller.ClusterKeycloakInstanceReconciler{
+			Client:        mgr.GetClient(),
+			Scheme:        mgr.GetScheme(),
+			ClientManager: clientManager,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterKeycloakInstance")
+			os.Exit(1)
+		}
 
-	if err = (&controller.ClusterKeycloakRealmReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		ClientManager: clientManager,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ClusterKeycloakRealm")
-		os.Exit(1)
+		if err = (&controller.ClusterKeycloakRealmReconciler{
+			Client:        mgr.GetClient(),
+			Scheme:        mgr.GetScheme(),
+			ClientManager: clientManager,
+			WatchScope:    watchScope,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterKeycloakRealm")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("not reconciling cluster-scoped resources")
 	}
 
 	if err = (&controller.KeycloakClientScopeReconciler{
@@ -262,6 +340,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&controller.KeycloakQuotaReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "KeycloakQuota")
+		os.Exit(1)
+	}
+
 	// Add health checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
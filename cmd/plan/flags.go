@@ -0,0 +1,192 @@
+package plan
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/cmdflags"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// Options holds the plan command options
+type Options struct {
+	// Input options
+	Files      []string
+	Namespace  string
+	SecretsDir string
+
+	// Connection options (direct mode)
+	URL      string
+	Username string
+	Password string
+
+	// Connection options (from-instance mode)
+	FromInstance        string
+	FromClusterInstance string
+	InstanceNamespace   string
+
+	// Plan options
+	Realm string
+
+	// General options
+	Verbose bool
+
+	// Internal
+	filesRaw cmdflags.StringList
+}
+
+// BindFlags binds the options to the given flag set
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	// Input options
+	fs.Var(&o.filesRaw, "f", "Manifest file or directory (repeatable)")
+	fs.Var(&o.filesRaw, "filename", "Manifest file or directory (repeatable)")
+	fs.StringVar(&o.Namespace, "namespace", "default", "Namespace assumed for manifests without metadata.namespace")
+	fs.StringVar(&o.SecretsDir, "secrets-dir", "", "Directory of Secrets laid out like mounted volumes (<dir>/<secret>/<key>)")
+
+	// Connection options (direct mode)
+	fs.StringVar(&o.URL, "url", "", "Keycloak server URL (e.g., https://keycloak.example.com)")
+	fs.StringVar(&o.Username, "username", "", "Keycloak admin username")
+	fs.StringVar(&o.Password, "password", "", "Keycloak admin password (use env var KEYCLOAK_PASSWORD for security)")
+
+	// Connection options (from-instance mode)
+	fs.StringVar(&o.FromInstance, "from-instance", "", "Name of KeycloakInstance CR to read connection details from")
+	fs.StringVar(&o.FromClusterInstance, "from-cluster-instance", "", "Name of ClusterKeycloakInstance CR to read connection details from")
+	fs.StringVar(&o.InstanceNamespace, "instance-namespace", "", "Namespace of the KeycloakInstance CR (required with --from-instance)")
+
+	// Plan options
+	fs.StringVar(&o.Realm, "realm", "", "Realm to plan, by realm name or CR name (required if the manifests define several)")
+
+	// General options
+	fs.BoolVar(&o.Verbose, "verbose", false, "Enable verbose output")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: keycloak-operator plan [options]
+
+Show the changes the operator would make to a realm for a set of manifests,
+without a Kubernetes cluster.
+
+Input Options:
+    -f, --filename  Manifest file or directory (repeatable, comma-separated)
+    --namespace     Namespace assumed for manifests without one (default: "default")
+    --secrets-dir   Directory of Secrets laid out like mounted volumes:
+                    <dir>/<secret-name>/<key>
+
+Connection Options (choose one mode):
+
+  Direct connection:
+    --url           Keycloak server URL
+    --username      Admin username
+    --password      Admin password (or use KEYCLOAK_PASSWORD env var)
+
+  From existing KeycloakInstance CR:
+    --from-instance          Name of KeycloakInstance CR
+    --from-cluster-instance  Name of ClusterKeycloakInstance CR
+    --instance-namespace     Namespace of KeycloakInstance
+
+Plan Options:
+    --realm         Realm to plan, by realm name or CR name
+                    (required if the manifests define several realms)
+
+Exit status is 0 if Keycloak matches the manifests, 2 if the operator would
+change something and 1 on errors.
+
+Examples:
+
+  # Review the changes of a pull request
+  keycloak-operator plan -f ./manifests \
+    --url https://keycloak.example.com \
+    --username admin \
+    --password "$KEYCLOAK_PASSWORD"
+
+  # Supply secrets from files
+  keycloak-operator plan -f ./manifests --secrets-dir ./secrets \
+    --url https://keycloak.example.com --username admin
+
+`)
+		fs.PrintDefaults()
+	}
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	o.Files = o.filesRaw
+	if len(o.Files) == 0 {
+		return fmt.Errorf("-f is required")
+	}
+
+	// Check password from environment if not provided
+	if o.Password == "" {
+		o.Password = os.Getenv("KEYCLOAK_PASSWORD")
+	}
+
+	directMode := o.URL != ""
+	instanceMode := o.FromInstance != "" || o.FromClusterInstance != ""
+	if !directMode && !instanceMode {
+		return fmt.Errorf("either --url or --from-instance/--from-cluster-instance is required")
+	}
+	if directMode && instanceMode {
+		return fmt.Errorf("only one of --url and --from-instance/--from-cluster-instance can be used")
+	}
+
+	if directMode {
+		if o.Username == "" {
+			return fmt.Errorf("--username is required when using --url")
+		}
+		if o.Password == "" {
+			return fmt.Errorf("--password is required when using --url (or set KEYCLOAK_PASSWORD env var)")
+		}
+	}
+
+	if o.FromInstance != "" && o.InstanceNamespace == "" {
+		return fmt.Errorf("--instance-namespace is required when using --from-instance")
+	}
+	return nil
+}
+
+// GetKeycloakConfig returns the Keycloak client configuration
+func (o *Options) GetKeycloakConfig(ctx context.Context) (*keycloak.Config, error) {
+	if o.URL != "" {
+		return &keycloak.Config{
+			BaseURL:  o.URL,
+			Realm:    "master",
+			Username: o.Username,
+			Password: o.Password,
+		}, nil
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig: %w (ensure KUBECONFIG is set or ~/.kube/config exists)", err)
+	}
+	k8sClient, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	var kcConfig keycloak.Config
+	if o.FromClusterInstance != "" {
+		instance := &keycloakv1beta1.ClusterKeycloakInstance{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: o.FromClusterInstance}, instance); err != nil {
+			return nil, fmt.Errorf("failed to get ClusterKeycloakInstance %s: %w", o.FromClusterInstance, err)
+		}
+		kcConfig, err = controller.GetKeycloakConfigFromClusterInstance(ctx, k8sClient, instance)
+	} else {
+		instance := &keycloakv1beta1.KeycloakInstance{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: o.FromInstance, Namespace: o.InstanceNamespace}, instance); err != nil {
+			return nil, fmt.Errorf("failed to get KeycloakInstance %s/%s: %w", o.InstanceNamespace, o.FromInstance, err)
+		}
+		kcConfig, err = controller.GetKeycloakConfigFromInstance(ctx, k8sClient, instance)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &kcConfig, nil
+}
@@ -0,0 +1,112 @@
+// Package plan provides the CLI for showing the changes the operator would make for a set of manifests.
+package plan
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/plan"
+)
+
+// exitChanges is the exit status when the plan has changes, as for
+// `terraform plan -detailed-exitcode`.
+const exitChanges = 2
+
+// Run executes the plan command with the given arguments
+func Run(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	opts := &Options{}
+	opts.BindFlags(fs)
+
+	// Parse flags
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Setup logger
+	zapOpts := zap.Options{Development: opts.Verbose}
+	log := zap.New(zap.UseFlagOptions(&zapOpts))
+
+	// Validate options
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Create context with signal handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	result, err := runPlan(ctx, opts, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if result.HasChanges() {
+		os.Exit(exitChanges)
+	}
+}
+
+func runPlan(ctx context.Context, opts *Options, log logr.Logger) (*plan.Result, error) {
+	objects, err := manifest.Load(opts.Namespace, opts.Files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifests: %w", err)
+	}
+	if opts.SecretsDir != "" {
+		secrets, err := manifest.LoadSecretsDir(opts.Namespace, opts.SecretsDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load secrets: %w", err)
+		}
+		objects = append(objects, secrets...)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Loaded %d objects\n", len(objects))
+	}
+
+	cfg, err := opts.GetKeycloakConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Keycloak configuration: %w", err)
+	}
+	kc := keycloak.NewClient(*cfg, log)
+	if err := kc.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to Keycloak at %s: %w", cfg.BaseURL, err)
+	}
+
+	result, err := plan.Plan(ctx, kc, objects, plan.Options{Realm: opts.Realm})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if len(result.Placeholders) > 0 {
+		fmt.Fprintln(os.Stderr, "Secrets not found; fields set from them were not compared:")
+		for _, p := range result.Placeholders {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+	}
+	if err := result.Write(os.Stdout); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
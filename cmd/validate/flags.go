@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/cmdflags"
+)
+
+// Options holds the validate command options
+type Options struct {
+	// Input options
+	Files     []string
+	Namespace string
+
+	// General options
+	Verbose bool
+
+	// Internal
+	filesRaw cmdflags.StringList
+}
+
+// BindFlags binds the options to the given flag set
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	// Input options
+	fs.Var(&o.filesRaw, "f", "Manifest file or directory (repeatable)")
+	fs.Var(&o.filesRaw, "filename", "Manifest file or directory (repeatable)")
+	fs.StringVar(&o.Namespace, "namespace", "default", "Namespace assumed for manifests without metadata.namespace")
+
+	// General options
+	fs.BoolVar(&o.Verbose, "verbose", false, "Enable verbose output")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: keycloak-operator validate [options]
+
+Check a set of manifests without a cluster or Keycloak: against the CRD
+schemas and validation rules, with the checks the operator makes before
+calling Keycloak, and against each other for references to objects that are
+not defined and identifiers used twice in the same realm.
+
+The manifests are taken to be complete: every referenced object must be
+among them.
+
+Input Options:
+    -f, --filename  Manifest file or directory (repeatable, comma-separated)
+    --namespace     Namespace assumed for manifests without one (default: "default")
+
+Exit status is 0 if no issues were found and 1 otherwise.
+
+Examples:
+
+  # Validate a directory of manifests
+  keycloak-operator validate -f ./manifests
+
+  # Validate the output of kustomize
+  kustomize build overlays/prod > /tmp/prod.yaml
+  keycloak-operator validate -f /tmp/prod.yaml
+
+`)
+		fs.PrintDefaults()
+	}
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	o.Files = o.filesRaw
+	if len(o.Files) == 0 {
+		return fmt.Errorf("-f is required")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Package validate provides the CLI for checking manifests offline.
+package validate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/validate"
+)
+
+// Run executes the validate command with the given arguments
+func Run(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	opts := &Options{}
+	opts.BindFlags(fs)
+
+	// Parse flags
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate options
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	result, err := runValidate(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(result.Issues) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runValidate(ctx context.Context, opts *Options) (*validate.Result, error) {
+	docs, err := manifest.LoadDocuments(opts.Namespace, opts.Files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifests: %w", err)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Loaded %d objects\n", len(docs))
+	}
+
+	result, err := validate.Validate(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Write(os.Stdout); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,10 @@
+// Package crd embeds the operator's CustomResourceDefinitions, for the
+// subcommands that validate manifests without a cluster.
+package crd
+
+import "embed"
+
+// Bases holds the generated CustomResourceDefinitions under bases/.
+//
+//go:embed bases/*.yaml
+var Bases embed.FS
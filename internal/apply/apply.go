@@ -0,0 +1,336 @@
+// Package apply reconciles operator manifests against Keycloak without a
+// Kubernetes API server. The operator's own reconcilers run against an
+// in-memory object store, so the result is the same as in a cluster.
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+const (
+	// DefaultMaxRounds is the default number of passes over the objects that
+	// are not ready yet.
+	DefaultMaxRounds = 3
+
+	// maxRequeues bounds the immediate requeues of one reconcile, such as
+	// the one after adding the finalizer.
+	maxRequeues = 5
+)
+
+// kindOrder is the order objects are reconciled in: every kind after the
+// kinds it may reference.
+var kindOrder = []string{
+	"ClusterKeycloakInstance",
+	"KeycloakInstance",
+	"ClusterKeycloakRealm",
+	"KeycloakRealm",
+	"KeycloakRequiredAction",
+	"KeycloakAuthenticationFlow",
+	"KeycloakClientScope",
+	"KeycloakClient",
+	"KeycloakProtocolMapper",
+	"KeycloakComponent",
+	"KeycloakRole",
+	"KeycloakGroup",
+	"KeycloakOrganization",
+	"KeycloakIdentityProvider",
+	"KeycloakIdentityProviderMapper",
+	"KeycloakUser",
+	"KeycloakUserCredential",
+	"KeycloakRoleMapping",
+}
+
+// Options configures Apply.
+type Options struct {
+	// Getenv looks up the environment variables of ${VAR} Secret values.
+	// If nil, such values are kept as they are.
+	Getenv func(key string) (string, bool)
+
+	// MaxRounds is the number of passes over the objects that are not ready
+	// yet, for references the kind order does not cover, such as subgroups
+	// or composite roles. Defaults to DefaultMaxRounds.
+	MaxRounds int
+
+	// Log receives the reconcilers' logs.
+	Log logr.Logger
+}
+
+// ObjectResult is the outcome of reconciling one object.
+type ObjectResult struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Ready     bool
+	Reason    string
+	Message   string
+}
+
+// Result is the outcome of Apply.
+type Result struct {
+	// Objects are the reconciled objects in the order they were applied.
+	Objects []ObjectResult
+
+	// GeneratedSecrets are the Secrets the reconcilers created, such as
+	// generated client secrets and passwords. They only exist in memory and
+	// must be kept for later runs to reuse them.
+	GeneratedSecrets []*corev1.Secret
+}
+
+// Failed returns the number of objects that did not become ready.
+func (r *Result) Failed() int {
+	failed := 0
+	for _, obj := range r.Objects {
+		if !obj.Ready {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Apply reconciles objects against the Keycloak instances they define.
+// Secrets among objects are available to the reconcilers; other kinds the
+// reconcilers do not handle are only stored.
+func Apply(ctx context.Context, objects []client.Object, opts Options) (*Result, error) {
+	if opts.MaxRounds <= 0 {
+		opts.MaxRounds = DefaultMaxRounds
+	}
+	ctx = log.IntoContext(ctx, opts.Log)
+
+	stored := make([]client.Object, 0, len(objects))
+	inputSecrets := map[types.NamespacedName]bool{}
+	for _, obj := range objects {
+		obj = obj.DeepCopyObject().(client.Object)
+		obj.SetResourceVersion("")
+		if secret, ok := obj.(*corev1.Secret); ok {
+			if err := expandSecret(secret, opts.Getenv); err != nil {
+				return nil, err
+			}
+			inputSecrets[client.ObjectKeyFromObject(secret)] = true
+		}
+		stored = append(stored, obj)
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(manifest.Scheme).
+		WithStatusSubresource(statusKinds()...).
+		WithObjects(stored...).
+		Build()
+	reconcilers := newReconcilers(c, keycloak.NewClientManager(opts.Log))
+
+	pending := orderedObjects(stored)
+	results := make(map[string]ObjectResult, len(pending))
+	for round := 0; round < opts.MaxRounds && len(pending) > 0; round++ {
+		var notReady []client.Object
+		for _, obj := range pending {
+			result := reconcileObject(ctx, c, reconcilers[kindOf(obj)], obj)
+			results[objectKey(obj)] = result
+			if !result.Ready {
+				notReady = append(notReady, obj)
+			}
+		}
+		if len(notReady) == len(pending) {
+			break
+		}
+		pending = notReady
+	}
+
+	res := &Result{}
+	for _, obj := range orderedObjects(stored) {
+		res.Objects = append(res.Objects, results[objectKey(obj)])
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets); err != nil {
+		return nil, fmt.Errorf("failed to list Secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		if !inputSecrets[client.ObjectKeyFromObject(&secrets.Items[i])] {
+			res.GeneratedSecrets = append(res.GeneratedSecrets, &secrets.Items[i])
+		}
+	}
+	return res, nil
+}
+
+// reconcileObject runs r for obj until it stops asking for an immediate
+// requeue and reports the object's Ready condition.
+func reconcileObject(ctx context.Context, c client.Client, r reconcile.Reconciler, obj client.Object) ObjectResult {
+	key := client.ObjectKeyFromObject(obj)
+	result := ObjectResult{Kind: kindOf(obj), Namespace: key.Namespace, Name: key.Name}
+
+	var reconcileErr error
+	for i := 0; i < maxRequeues; i++ {
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		reconcileErr = err
+		if err == nil && !res.Requeue {
+			break
+		}
+	}
+
+	current := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, key, current); err != nil {
+		result.Reason, result.Message = "Error", err.Error()
+		return result
+	}
+	result.Ready, result.Reason, result.Message = controller.ReadyStatus(current)
+	if reconcileErr != nil && !result.Ready {
+		result.Reason, result.Message = "Error", reconcileErr.Error()
+	}
+	return result
+}
+
+// newReconcilers returns the operator's reconcilers by kind, sharing c and
+// the Keycloak clients of cm.
+func newReconcilers(c client.Client, cm *keycloak.ClientManager) map[string]reconcile.Reconciler {
+	scheme := manifest.Scheme
+	return map[string]reconcile.Reconciler{
+		"ClusterKeycloakInstance":        &controller.ClusterKeycloakInstanceReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakInstance":               &controller.KeycloakInstanceReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"ClusterKeycloakRealm":           &controller.ClusterKeycloakRealmReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakRealm":                  &controller.KeycloakRealmReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakRequiredAction":         &controller.KeycloakRequiredActionReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakAuthenticationFlow":     &controller.KeycloakAuthenticationFlowReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakClientScope":            &controller.KeycloakClientScopeReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakClient":                 &controller.KeycloakClientReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakProtocolMapper":         &controller.KeycloakProtocolMapperReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakComponent":              &controller.KeycloakComponentReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakRole":                   &controller.KeycloakRoleReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakGroup":                  &controller.KeycloakGroupReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakOrganization":           &controller.KeycloakOrganizationReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakIdentityProvider":       &controller.KeycloakIdentityProviderReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakIdentityProviderMapper": &controller.KeycloakIdentityProviderMapperReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakUser":                   &controller.KeycloakUserReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakUserCredential":         &controller.KeycloakUserCredentialReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+		"KeycloakRoleMapping":            &controller.KeycloakRoleMappingReconciler{Client: c, Scheme: scheme, ClientManager: cm},
+	}
+}
+
+// statusKinds returns an object of every operator kind, whose status the
+// store keeps apart from the spec as the API server does.
+func statusKinds() []client.Object {
+	var objects []client.Object
+	for gvk := range manifest.Scheme.AllKnownTypes() {
+		if gvk.GroupVersion() != keycloakv1beta1.GroupVersion || strings.HasSuffix(gvk.Kind, "List") {
+			continue
+		}
+		obj, err := manifest.Scheme.New(gvk)
+		if err != nil {
+			continue
+		}
+		if cobj, ok := obj.(client.Object); ok {
+			objects = append(objects, cobj)
+		}
+	}
+	return objects
+}
+
+// orderedObjects returns the objects of reconciled kinds in kindOrder, and by
+// namespace and name within a kind.
+func orderedObjects(objects []client.Object) []client.Object {
+	rank := make(map[string]int, len(kindOrder))
+	for i, kind := range kindOrder {
+		rank[kind] = i
+	}
+	var ordered []client.Object
+	for _, obj := range objects {
+		if _, ok := rank[kindOf(obj)]; ok {
+			ordered = append(ordered, obj)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if rank[kindOf(a)] != rank[kindOf(b)] {
+			return rank[kindOf(a)] < rank[kindOf(b)]
+		}
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+	return ordered
+}
+
+func objectKey(obj client.Object) string {
+	return kindOf(obj) + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func kindOf(obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	gvks, _, err := manifest.Scheme.ObjectKinds(obj)
+	if err != nil || len(gvks) == 0 {
+		return ""
+	}
+	return gvks[0].Kind
+}
+
+// envReference matches a Secret value that consists of a single ${VAR}.
+var envReference = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// expandSecret replaces the values of secret that are a single ${VAR} with
+// the environment variable VAR. Values are only expanded as a whole, so that
+// secrets that merely contain a dollar sign are kept.
+func expandSecret(secret *corev1.Secret, getenv func(string) (string, bool)) error {
+	if getenv == nil {
+		return nil
+	}
+	for key, value := range secret.Data {
+		match := envReference.FindSubmatch(value)
+		if match == nil {
+			continue
+		}
+		env, ok := getenv(string(match[1]))
+		if !ok {
+			return fmt.Errorf("Secret %s/%s: key %s references environment variable %s, which is not set",
+				secret.Namespace, secret.Name, key, match[1])
+		}
+		secret.Data[key] = []byte(env)
+	}
+	return nil
+}
+
+// Write prints one line per object and a summary.
+func (r *Result) Write(w io.Writer) error {
+	var b strings.Builder
+	for _, obj := range r.Objects {
+		name := obj.Name
+		if obj.Namespace != "" {
+			name = obj.Namespace + "/" + name
+		}
+		status := "ready"
+		if !obj.Ready {
+			status = "failed"
+		}
+		fmt.Fprintf(&b, "%-7s %s %s", status, obj.Kind, name)
+		if obj.Reason != "" && !obj.Ready {
+			fmt.Fprintf(&b, " (%s)", obj.Reason)
+		}
+		if obj.Message != "" {
+			fmt.Fprintf(&b, ": %s", obj.Message)
+		}
+		b.WriteString("\n")
+	}
+	failed := r.Failed()
+	fmt.Fprintf(&b, "\nApplied %d objects: %d ready, %d failed.\n", len(r.Objects), len(r.Objects)-failed, failed)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
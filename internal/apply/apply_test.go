@@ -0,0 +1,215 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// manifests decodes the manifests the way the apply subcommand reads them,
+// with the instance pointing at url.
+func manifests(t *testing.T, url string) []client.Object {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# Listed before their dependencies on purpose.
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: alice-reader
+spec:
+  subject:
+    userRef:
+      name: alice
+  roleRef:
+    name: reader
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUser
+metadata:
+  name: alice
+spec:
+  realmRef:
+    name: realm
+  username: alice
+  definition:
+    enabled: true
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: ops
+spec:
+  parentGroupRef:
+    name: team
+  name: ops
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: team
+spec:
+  realmRef:
+    name: realm
+  name: team
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: reader
+spec:
+  realmRef:
+    name: realm
+  name: reader
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  realmRef:
+    name: realm
+  clientId: app
+  definition:
+    publicClient: false
+  clientSecretRef:
+    name: app-credentials
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  instanceRef:
+    name: keycloak
+  realmName: test
+  definition:
+    enabled: true
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakInstance
+metadata:
+  name: keycloak
+spec:
+  baseUrl: `+url+`
+  auth:
+    passwordGrant:
+      secretRef:
+        name: admin
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: admin
+stringData:
+  username: `+fake.AdminUsername+`
+  password: ${ADMIN_PASSWORD}
+`), 0644))
+	objects, err := manifest.Load("team", path)
+	require.NoError(t, err)
+	return objects
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+
+	objects := manifests(t, srv.URL)
+	result, err := Apply(ctx, objects, Options{
+		Getenv: func(key string) (string, bool) {
+			if key == "ADMIN_PASSWORD" {
+				return fake.AdminPassword, true
+			}
+			return "", false
+		},
+		Log: testr.New(t),
+	})
+	require.NoError(t, err)
+
+	var kinds []string
+	for _, obj := range result.Objects {
+		require.True(t, obj.Ready, "%s %s: %s %s", obj.Kind, obj.Name, obj.Reason, obj.Message)
+		kinds = append(kinds, obj.Kind+" "+obj.Name)
+	}
+	require.Equal(t, []string{
+		"KeycloakInstance keycloak",
+		"KeycloakRealm realm",
+		"KeycloakClient app",
+		"KeycloakRole reader",
+		"KeycloakGroup ops",
+		"KeycloakGroup team",
+		"KeycloakUser alice",
+		"KeycloakRoleMapping alice-reader",
+	}, kinds)
+	require.Zero(t, result.Failed())
+	require.Len(t, result.GeneratedSecrets, 1)
+	require.Equal(t, "app-credentials", result.GeneratedSecrets[0].Name)
+	require.NotEmpty(t, result.GeneratedSecrets[0].Data["client-secret"])
+	require.Equal(t, []byte("${ADMIN_PASSWORD}"), objects[len(objects)-1].(*corev1.Secret).Data["password"], "input modified")
+
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+	groups, err := kc.GetGroups(ctx, "test", nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	users, err := kc.GetUsers(ctx, "test", map[string]string{"username": "alice"})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	roles, err := kc.GetUserRealmRoleMappings(ctx, "test", *users[0].ID)
+	require.NoError(t, err)
+	require.Contains(t, roleNames(roles), "reader")
+
+	var out bytes.Buffer
+	require.NoError(t, result.Write(&out))
+	require.Contains(t, out.String(), "ready   KeycloakRealm team/realm")
+	require.Contains(t, out.String(), "Applied 8 objects: 8 ready, 0 failed.\n")
+}
+
+func TestApplyFailures(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+
+	t.Run("missing environment variable", func(t *testing.T) {
+		_, err := Apply(ctx, manifests(t, srv.URL), Options{
+			Getenv: func(string) (string, bool) { return "", false },
+		})
+		require.ErrorContains(t, err, "environment variable ADMIN_PASSWORD")
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		result, err := Apply(ctx, manifests(t, srv.URL), Options{Log: testr.New(t)})
+		require.NoError(t, err)
+		require.Equal(t, len(result.Objects), result.Failed())
+		require.Equal(t, "ConnectionFailed", result.Objects[0].Reason)
+
+		var out bytes.Buffer
+		require.NoError(t, result.Write(&out))
+		require.Contains(t, out.String(), "failed  KeycloakInstance team/keycloak (ConnectionFailed)")
+	})
+}
+
+func roleNames(roles []keycloak.RoleRepresentation) []string {
+	var names []string
+	for _, role := range roles {
+		names = append(names, *role.Name)
+	}
+	return names
+}
@@ -0,0 +1,18 @@
+// Package cmdflags holds the flag types shared by the subcommands.
+package cmdflags
+
+import "strings"
+
+// StringList is a flag that may be repeated or given comma-separated values.
+type StringList []string
+
+func (l *StringList) String() string { return strings.Join(*l, ",") }
+
+func (l *StringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
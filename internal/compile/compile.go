@@ -0,0 +1,1012 @@
+// Package compile turns operator manifests into a Keycloak realm import, the
+// inverse of package export.
+package compile
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// Options configures Compile.
+type Options struct {
+	// Realm selects the realm to compile, by realm name or by the name of its
+	// KeycloakRealm or ClusterKeycloakRealm. Required when the manifests
+	// define more than one realm.
+	Realm string
+
+	// FlowIDs are the IDs of flows that already exist, by alias. Other
+	// flows get IDs derived from the realm name and alias.
+	FlowIDs map[string]string
+}
+
+// Result is a compiled realm.
+type Result struct {
+	// Realm is the RealmRepresentation, ready for `kc.sh import` or
+	// `start --import-realm`.
+	Realm map[string]interface{}
+
+	// Placeholders are the environment variables the import expects for
+	// secrets that were not found. Keycloak substitutes `${VAR}`
+	// placeholders in realm files on import.
+	Placeholders []string
+
+	// Warnings describe settings that a realm import cannot express.
+	Warnings []string
+
+	// Objects are the compiled resources one by one, the realm first, in
+	// the order they were compiled.
+	Objects []Object
+}
+
+// Object is one compiled resource and the custom resource it came from.
+type Object struct {
+	// Source is the custom resource.
+	Source client.Object
+
+	// Parent is the identifier Name is scoped to: the clientId of client
+	// roles, service account users and client protocol mappers, the name of
+	// the client scope of scope protocol mappers, the alias of the identity
+	// provider of IdP mappers and the providerType of components. Empty for
+	// objects that are unique in the realm.
+	Parent string
+
+	// Name is the resolved identifier: the realm name, clientId, alias,
+	// username, name, or group path. For role mappings it is the role name.
+	Name string
+
+	// Definition is a copy of the representation as compiled. Role mappings
+	// are represented by the "user" (username) or "group" (path) they apply
+	// to and the role in "realmRoles" or "clientRoles".
+	Definition map[string]interface{}
+}
+
+// Compile builds the RealmRepresentation of one realm from objects, resolving
+// references among them the way the reconcilers do. Secrets referenced by the
+// manifests are read from the corev1.Secrets in objects; missing ones become
+// placeholders.
+func Compile(objects []client.Object, opts Options) (*Result, error) {
+	c := newCompiler(objects)
+	c.opts = opts
+	if err := c.selectRealm(opts.Realm); err != nil {
+		return nil, err
+	}
+	if err := c.compile(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(c.placeholders)
+	return &Result{Realm: c.realm, Placeholders: c.placeholders, Warnings: c.warnings, Objects: c.objects}, nil
+}
+
+// compiler holds the objects by kind, sorted by namespace and name so the
+// output is deterministic, and the state built up while compiling.
+type compiler struct {
+	realms          []*keycloakv1beta1.KeycloakRealm
+	clusterRealms   []*keycloakv1beta1.ClusterKeycloakRealm
+	clientScopes    []*keycloakv1beta1.KeycloakClientScope
+	clients         []*keycloakv1beta1.KeycloakClient
+	protocolMappers []*keycloakv1beta1.KeycloakProtocolMapper
+	roles           []*keycloakv1beta1.KeycloakRole
+	groups          []*keycloakv1beta1.KeycloakGroup
+	users           []*keycloakv1beta1.KeycloakUser
+	credentials     []*keycloakv1beta1.KeycloakUserCredential
+	roleMappings    []*keycloakv1beta1.KeycloakRoleMapping
+	idps            []*keycloakv1beta1.KeycloakIdentityProvider
+	idpMappers      []*keycloakv1beta1.KeycloakIdentityProviderMapper
+	components      []*keycloakv1beta1.KeycloakComponent
+	requiredActions []*keycloakv1beta1.KeycloakRequiredAction
+	flows           []*keycloakv1beta1.KeycloakAuthenticationFlow
+	organizations   []*keycloakv1beta1.KeycloakOrganization
+	secrets         map[types.NamespacedName]*corev1.Secret
+
+	opts Options
+
+	// The selected realm: exactly one of namespaced and cluster is set.
+	namespaced *keycloakv1beta1.KeycloakRealm
+	cluster    *keycloakv1beta1.ClusterKeycloakRealm
+	realmName  string
+
+	realm        map[string]interface{}
+	placeholders []string
+	warnings     []string
+	objects      []Object
+
+	// Compiled objects, keyed by namespace/name of their CR.
+	clientIDs       map[types.NamespacedName]string
+	groupNodes      map[types.NamespacedName]*groupNode
+	userDefs        map[types.NamespacedName]map[string]interface{}
+	idpAliases      map[types.NamespacedName]string
+	orgIdPs         map[types.NamespacedName][]string
+	flowIDs         map[string]string
+	serviceAccounts map[string]map[string]interface{}
+}
+
+func newCompiler(objects []client.Object) *compiler {
+	sorted := append([]client.Object(nil), objects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].GetNamespace() != sorted[j].GetNamespace() {
+			return sorted[i].GetNamespace() < sorted[j].GetNamespace()
+		}
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+
+	c := &compiler{secrets: make(map[types.NamespacedName]*corev1.Secret)}
+	for _, obj := range sorted {
+		switch o := obj.(type) {
+		case *keycloakv1beta1.KeycloakRealm:
+			c.realms = append(c.realms, o)
+		case *keycloakv1beta1.ClusterKeycloakRealm:
+			c.clusterRealms = append(c.clusterRealms, o)
+		case *keycloakv1beta1.KeycloakClientScope:
+			c.clientScopes = append(c.clientScopes, o)
+		case *keycloakv1beta1.KeycloakClient:
+			c.clients = append(c.clients, o)
+		case *keycloakv1beta1.KeycloakProtocolMapper:
+			c.protocolMappers = append(c.protocolMappers, o)
+		case *keycloakv1beta1.KeycloakRole:
+			c.roles = append(c.roles, o)
+		case *keycloakv1beta1.KeycloakGroup:
+			c.groups = append(c.groups, o)
+		case *keycloakv1beta1.KeycloakUser:
+			c.users = append(c.users, o)
+		case *keycloakv1beta1.KeycloakUserCredential:
+			c.credentials = append(c.credentials, o)
+		case *keycloakv1beta1.KeycloakRoleMapping:
+			c.roleMappings = append(c.roleMappings, o)
+		case *keycloakv1beta1.KeycloakIdentityProvider:
+			c.idps = append(c.idps, o)
+		case *keycloakv1beta1.KeycloakIdentityProviderMapper:
+			c.idpMappers = append(c.idpMappers, o)
+		case *keycloakv1beta1.KeycloakComponent:
+			c.components = append(c.components, o)
+		case *keycloakv1beta1.KeycloakRequiredAction:
+			c.requiredActions = append(c.requiredActions, o)
+		case *keycloakv1beta1.KeycloakAuthenticationFlow:
+			c.flows = append(c.flows, o)
+		case *keycloakv1beta1.KeycloakOrganization:
+			c.organizations = append(c.organizations, o)
+		case *corev1.Secret:
+			c.secrets[client.ObjectKeyFromObject(o)] = o
+		}
+	}
+	return c
+}
+
+// selectRealm picks the realm to compile.
+func (c *compiler) selectRealm(selector string) error {
+	type candidate struct {
+		namespaced *keycloakv1beta1.KeycloakRealm
+		cluster    *keycloakv1beta1.ClusterKeycloakRealm
+		objName    string
+		realmName  string
+	}
+	var candidates []candidate
+	for _, r := range c.realms {
+		name, err := controller.ResolveIdentifier("realmName", r.Spec.RealmName, definitionString(r.Spec.Definition.Raw, "realm"))
+		if err != nil {
+			return objectError("KeycloakRealm", r, err)
+		}
+		candidates = append(candidates, candidate{namespaced: r, objName: r.Name, realmName: name})
+	}
+	for _, r := range c.clusterRealms {
+		name, err := controller.ResolveIdentifier("realmName", r.Spec.RealmName, definitionString(r.Spec.Definition.Raw, "realm"))
+		if err != nil {
+			return objectError("ClusterKeycloakRealm", r, err)
+		}
+		candidates = append(candidates, candidate{cluster: r, objName: r.Name, realmName: name})
+	}
+
+	var matches []candidate
+	for _, cand := range candidates {
+		if selector == "" || cand.realmName == selector || cand.objName == selector {
+			matches = append(matches, cand)
+		}
+	}
+	switch {
+	case len(candidates) == 0:
+		return fmt.Errorf("no KeycloakRealm or ClusterKeycloakRealm found")
+	case len(matches) == 0:
+		return fmt.Errorf("realm %q not found", selector)
+	case len(matches) > 1:
+		names := make([]string, 0, len(matches))
+		for _, m := range matches {
+			names = append(names, m.realmName)
+		}
+		return fmt.Errorf("found %d realms (%s); select one with --realm", len(matches), strings.Join(names, ", "))
+	}
+
+	c.namespaced, c.cluster, c.realmName = matches[0].namespaced, matches[0].cluster, matches[0].realmName
+	return nil
+}
+
+func (c *compiler) compile() error {
+	c.clientIDs = make(map[types.NamespacedName]string)
+	c.groupNodes = make(map[types.NamespacedName]*groupNode)
+	c.userDefs = make(map[types.NamespacedName]map[string]interface{})
+	c.idpAliases = make(map[types.NamespacedName]string)
+	c.orgIdPs = make(map[types.NamespacedName][]string)
+	c.flowIDs = make(map[string]string)
+	c.serviceAccounts = make(map[string]map[string]interface{})
+
+	steps := []func() error{
+		c.compileRealm,
+		c.compileRequiredActions,
+		c.compileFlows,
+		c.compileClientScopes,
+		c.compileClients,
+		c.compileRoles,
+		c.compileGroups,
+		c.compileUsers,
+		c.compileRoleMappings,
+		c.compileIdentityProviders,
+		c.compileComponents,
+		c.compileOrganizations,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileRealm() error {
+	var definition json.RawMessage
+	if c.namespaced != nil {
+		definition = c.namespaced.Spec.Definition.Raw
+		if ref := c.namespaced.Spec.SmtpSecretRef; ref != nil {
+			definition = controller.MergeSmtpCredentials(definition,
+				c.secretValue(c.namespaced.Namespace, ref.Name, ref.UserKey, "user"),
+				c.secretValue(c.namespaced.Namespace, ref.Name, ref.PasswordKey, "password"))
+		}
+	} else {
+		definition = c.cluster.Spec.Definition.Raw
+		if ref := c.cluster.Spec.SmtpSecretRef; ref != nil {
+			definition = controller.MergeSmtpCredentials(definition,
+				c.secretValue(ref.Namespace, ref.Name, ref.UserKey, "user"),
+				c.secretValue(ref.Namespace, ref.Name, ref.PasswordKey, "password"))
+		}
+	}
+
+	realm, err := decodeDefinition(definition)
+	if err != nil {
+		return err
+	}
+	delete(realm, "id")
+	realm["realm"] = c.realmName
+	c.realm = realm
+	if c.namespaced != nil {
+		c.record(c.namespaced, "", c.realmName, realm)
+	} else {
+		c.record(c.cluster, "", c.realmName, realm)
+	}
+	return nil
+}
+
+// inRealm reports whether a resource with the given realm references
+// belongs to the realm being compiled.
+func (c *compiler) inRealm(namespace string, ref *keycloakv1beta1.ResourceRef, clusterRef *keycloakv1beta1.ClusterResourceRef) bool {
+	if c.cluster != nil {
+		return clusterRef != nil && clusterRef.Name == c.cluster.Name
+	}
+	return ref != nil && ref.Name == c.namespaced.Name && namespace == c.namespaced.Namespace
+}
+
+// realmIdentifier resolves an identifier that is unique per realm and
+// applies the naming policy of a ClusterKeycloakRealm to it.
+func (c *compiler) realmIdentifier(obj client.Object, specField string, specVal *string, defVal string) (string, error) {
+	id, err := controller.ResolveIdentifier(specField, specVal, defVal)
+	if err != nil || c.cluster == nil {
+		return id, err
+	}
+	return controller.ApplyNamingPolicy(c.cluster.Spec.NamingPolicy, c.cluster.Name, obj.GetNamespace(), specField, id)
+}
+
+func (c *compiler) compileRequiredActions() error {
+	for _, ra := range c.requiredActions {
+		if !c.inRealm(ra.Namespace, ra.Spec.RealmRef, ra.Spec.ClusterRealmRef) {
+			continue
+		}
+		def, err := decodeDefinition(ra.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakRequiredAction", ra, err)
+		}
+		alias, err := controller.ResolveIdentifier("alias", ra.Spec.Alias, stringField(def, "alias"))
+		if err != nil {
+			return objectError("KeycloakRequiredAction", ra, err)
+		}
+		def["alias"] = alias
+		setDefault(def, "providerId", alias)
+		setDefault(def, "name", alias)
+		if ref := ra.Spec.ConfigSecretRef; ref != nil {
+			if def, err = c.mergeConfigSecret(ra.Namespace, ref, def, false); err != nil {
+				return objectError("KeycloakRequiredAction", ra, err)
+			}
+		}
+		c.record(ra, "", alias, def)
+		appendList(c.realm, "requiredActions", def)
+	}
+	return nil
+}
+
+func (c *compiler) compileFlows() error {
+	for _, flow := range c.flows {
+		if !c.inRealm(flow.Namespace, flow.Spec.RealmRef, flow.Spec.ClusterRealmRef) {
+			continue
+		}
+		fi, err := controller.BuildFlowImport(flow)
+		if err != nil {
+			return objectError("KeycloakAuthenticationFlow", flow, err)
+		}
+		for _, f := range fi.Flows {
+			alias := f["alias"].(string)
+			if _, ok := c.flowIDs[alias]; ok {
+				return objectError("KeycloakAuthenticationFlow", flow, fmt.Errorf("flow alias %q is defined twice", alias))
+			}
+			// Client flow overrides refer to flows by ID, so imported flows
+			// get IDs that are stable across compilations.
+			id, ok := c.opts.FlowIDs[alias]
+			if !ok {
+				id = uuid.NewSHA1(uuid.NameSpaceOID, []byte(c.realmName+"/"+alias)).String()
+			}
+			f["id"] = id
+			c.flowIDs[alias] = id
+			appendList(c.realm, "authenticationFlows", f)
+		}
+		for _, config := range fi.Configs {
+			appendList(c.realm, "authenticatorConfig", config)
+		}
+		c.record(flow, "", flow.Spec.Alias, fi.Flows[0])
+	}
+	return nil
+}
+
+func (c *compiler) compileClientScopes() error {
+	for _, scope := range c.clientScopes {
+		if !c.inRealm(scope.Namespace, scope.Spec.RealmRef, scope.Spec.ClusterRealmRef) {
+			continue
+		}
+		def, err := decodeDefinition(scope.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakClientScope", scope, err)
+		}
+		name, err := c.realmIdentifier(scope, "name", scope.Spec.Name, stringField(def, "name"))
+		if err != nil {
+			return objectError("KeycloakClientScope", scope, err)
+		}
+		delete(def, "id")
+		def["name"] = name
+		if err := c.addProtocolMappers(def, name, func(m *keycloakv1beta1.KeycloakProtocolMapper) bool {
+			return m.Spec.ClientScopeRef != nil && m.Spec.ClientScopeRef.Name == scope.Name && m.Namespace == scope.Namespace
+		}); err != nil {
+			return err
+		}
+		c.record(scope, "", name, def)
+		appendList(c.realm, "clientScopes", def)
+	}
+	return nil
+}
+
+func (c *compiler) compileClients() error {
+	for _, kcClient := range c.clients {
+		if !c.inRealm(kcClient.Namespace, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef) {
+			continue
+		}
+		var definition json.RawMessage
+		if kcClient.Spec.Definition != nil {
+			definition = kcClient.Spec.Definition.Raw
+		}
+		definition, err := controller.ResolveFlowBindingAliases(definition, func(alias string) (string, error) {
+			id, ok := c.flowIDs[alias]
+			if !ok {
+				return "", fmt.Errorf("no KeycloakAuthenticationFlow with alias %q in realm %s", alias, c.realmName)
+			}
+			return id, nil
+		})
+		if err != nil {
+			return objectError("KeycloakClient", kcClient, err)
+		}
+		def, err := decodeDefinition(definition)
+		if err != nil {
+			return objectError("KeycloakClient", kcClient, err)
+		}
+		clientID, err := c.realmIdentifier(kcClient, "clientId", kcClient.Spec.ClientId, stringField(def, "clientId"))
+		if err != nil {
+			return objectError("KeycloakClient", kcClient, err)
+		}
+		delete(def, "id")
+		def["clientId"] = clientID
+
+		if ref := kcClient.Spec.ClientSecretRef; ref != nil && def["publicClient"] != true {
+			key := ""
+			if ref.ClientSecretKey != nil {
+				key = *ref.ClientSecretKey
+			}
+			def["secret"] = c.secretValue(kcClient.Namespace, ref.Name, key, "client-secret")
+		}
+
+		if err := c.addProtocolMappers(def, clientID, func(m *keycloakv1beta1.KeycloakProtocolMapper) bool {
+			return m.Spec.ClientRef != nil && m.Spec.ClientRef.Name == kcClient.Name && m.Namespace == kcClient.Namespace
+		}); err != nil {
+			return err
+		}
+		c.clientIDs[client.ObjectKeyFromObject(kcClient)] = clientID
+		c.record(kcClient, "", clientID, def)
+		appendList(c.realm, "clients", def)
+	}
+	return nil
+}
+
+func (c *compiler) addProtocolMappers(owner map[string]interface{}, ownerName string, belongs func(*keycloakv1beta1.KeycloakProtocolMapper) bool) error {
+	for _, mapper := range c.protocolMappers {
+		if !belongs(mapper) {
+			continue
+		}
+		def, err := decodeDefinition(mapper.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakProtocolMapper", mapper, err)
+		}
+		name, err := controller.ResolveIdentifier("name", mapper.Spec.Name, stringField(def, "name"))
+		if err != nil {
+			return objectError("KeycloakProtocolMapper", mapper, err)
+		}
+		delete(def, "id")
+		def["name"] = name
+		if ref := mapper.Spec.ConfigSecretRef; ref != nil {
+			if def, err = c.mergeConfigSecret(mapper.Namespace, ref, def, false); err != nil {
+				return objectError("KeycloakProtocolMapper", mapper, err)
+			}
+		}
+		c.record(mapper, ownerName, name, def)
+		appendList(owner, "protocolMappers", def)
+	}
+	return nil
+}
+
+func (c *compiler) compileRoles() error {
+	for _, role := range c.roles {
+		clientID := ""
+		if role.Spec.ClientRef != nil {
+			var ok bool
+			clientID, ok = c.clientIDs[types.NamespacedName{Namespace: role.Namespace, Name: role.Spec.ClientRef.Name}]
+			if !ok {
+				continue
+			}
+		} else if !c.inRealm(role.Namespace, role.Spec.RealmRef, role.Spec.ClusterRealmRef) {
+			continue
+		}
+
+		def, err := decodeDefinition(role.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakRole", role, err)
+		}
+		var name string
+		if clientID != "" {
+			name, err = controller.ResolveIdentifier("name", role.Spec.Name, stringField(def, "name"))
+		} else {
+			name, err = c.realmIdentifier(role, "name", role.Spec.Name, stringField(def, "name"))
+		}
+		if err != nil {
+			return objectError("KeycloakRole", role, err)
+		}
+		delete(def, "id")
+		def["name"] = name
+		c.record(role, clientID, name, def)
+
+		roles := mapField(c.realm, "roles")
+		if clientID == "" {
+			appendList(roles, "realm", def)
+			continue
+		}
+		def["clientRole"] = true
+		appendList(mapField(roles, "client"), clientID, def)
+	}
+	return nil
+}
+
+// groupNode is a compiled group and the CR it came from.
+type groupNode struct {
+	group *keycloakv1beta1.KeycloakGroup
+	def   map[string]interface{}
+	path  string
+}
+
+func (c *compiler) compileGroups() error {
+	byKey := make(map[types.NamespacedName]*keycloakv1beta1.KeycloakGroup, len(c.groups))
+	for _, group := range c.groups {
+		byKey[client.ObjectKeyFromObject(group)] = group
+	}
+
+	var top []*groupNode
+	children := make(map[types.NamespacedName][]*groupNode)
+	for _, group := range c.groups {
+		// Child groups take their realm from the root of their parent chain.
+		root := group
+		for depth := 0; root.Spec.ParentGroupRef != nil; depth++ {
+			parent, ok := byKey[types.NamespacedName{Namespace: root.Namespace, Name: root.Spec.ParentGroupRef.Name}]
+			if !ok || depth > len(c.groups) {
+				root = nil
+				break
+			}
+			root = parent
+		}
+		if root == nil || !c.inRealm(root.Namespace, root.Spec.RealmRef, root.Spec.ClusterRealmRef) {
+			continue
+		}
+
+		def, err := decodeDefinition(group.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakGroup", group, err)
+		}
+		var name string
+		if group.Spec.ParentGroupRef != nil {
+			name, err = controller.ResolveIdentifier("name", group.Spec.Name, stringField(def, "name"))
+		} else {
+			name, err = c.realmIdentifier(group, "name", group.Spec.Name, stringField(def, "name"))
+		}
+		if err != nil {
+			return objectError("KeycloakGroup", group, err)
+		}
+		for _, key := range []string{"id", "path", "subGroups"} {
+			delete(def, key)
+		}
+		def["name"] = name
+
+		node := &groupNode{group: group, def: def}
+		c.groupNodes[client.ObjectKeyFromObject(group)] = node
+		if group.Spec.ParentGroupRef == nil {
+			top = append(top, node)
+		} else {
+			parentKey := types.NamespacedName{Namespace: group.Namespace, Name: group.Spec.ParentGroupRef.Name}
+			children[parentKey] = append(children[parentKey], node)
+		}
+	}
+
+	var attach func(node *groupNode, parentPath string) map[string]interface{}
+	attach = func(node *groupNode, parentPath string) map[string]interface{} {
+		node.path = parentPath + "/" + node.def["name"].(string)
+		for _, child := range children[client.ObjectKeyFromObject(node.group)] {
+			appendList(node.def, "subGroups", attach(child, node.path))
+		}
+		c.record(node.group, "", node.path, node.def)
+		return node.def
+	}
+	for _, node := range top {
+		appendList(c.realm, "groups", attach(node, ""))
+	}
+	return nil
+}
+
+// groupPath returns the path of the group a KeycloakUser's spec.groups entry
+// names. Like the reconciler, it matches group names, preferring top-level
+// groups.
+func (c *compiler) groupPath(name string) (string, bool) {
+	var found string
+	for _, node := range c.groupNodes {
+		if node.def["name"] != name {
+			continue
+		}
+		if node.group.Spec.ParentGroupRef == nil {
+			return node.path, true
+		}
+		if found == "" || node.path < found {
+			found = node.path
+		}
+	}
+	return found, found != ""
+}
+
+func (c *compiler) compileUsers() error {
+	for _, user := range c.users {
+		clientID := ""
+		if user.Spec.ClientRef != nil {
+			var ok bool
+			clientID, ok = c.clientIDs[types.NamespacedName{Namespace: user.Namespace, Name: user.Spec.ClientRef.Name}]
+			if !ok {
+				continue
+			}
+		} else if !c.inRealm(user.Namespace, user.Spec.RealmRef, user.Spec.ClusterRealmRef) {
+			continue
+		}
+
+		var definition json.RawMessage
+		if user.Spec.Definition != nil {
+			definition = user.Spec.Definition.Raw
+		}
+		def, err := decodeDefinition(definition)
+		if err != nil {
+			return objectError("KeycloakUser", user, err)
+		}
+		delete(def, "id")
+
+		if clientID != "" {
+			def = c.serviceAccountUser(clientID, def)
+		} else {
+			username, err := controller.ResolveIdentifier("username", user.Spec.Username, stringField(def, "username"))
+			if err != nil {
+				return objectError("KeycloakUser", user, err)
+			}
+			def["username"] = username
+			appendList(c.realm, "users", def)
+		}
+
+		if user.Spec.RealmRoles != nil {
+			for _, role := range *user.Spec.RealmRoles {
+				appendUnique(def, "realmRoles", role)
+			}
+		}
+		if user.Spec.ClientRoles != nil {
+			for client, roles := range *user.Spec.ClientRoles {
+				for _, role := range roles {
+					appendUnique(mapField(def, "clientRoles"), client, role)
+				}
+			}
+		}
+		if user.Spec.Groups != nil {
+			for _, name := range *user.Spec.Groups {
+				path, ok := c.groupPath(name)
+				if !ok {
+					return objectError("KeycloakUser", user, fmt.Errorf("spec.groups: no KeycloakGroup named %q in realm %s", name, c.realmName))
+				}
+				appendUnique(def, "groups", path)
+			}
+		}
+		if pw := user.Spec.InitialPassword; pw != nil {
+			def["credentials"] = []interface{}{map[string]interface{}{
+				"type":      "password",
+				"value":     pw.Value,
+				"temporary": pw.Temporary,
+			}}
+		}
+		c.userDefs[client.ObjectKeyFromObject(user)] = def
+		c.record(user, clientID, stringField(def, "username"), def)
+	}
+
+	for _, cred := range c.credentials {
+		def, ok := c.userDefs[types.NamespacedName{Namespace: cred.Namespace, Name: cred.Spec.UserRef.Name}]
+		if !ok {
+			continue
+		}
+		def["credentials"] = []interface{}{map[string]interface{}{
+			"type":      "password",
+			"value":     c.secretValue(cred.Namespace, cred.Spec.UserSecret.SecretName, cred.Spec.UserSecret.PasswordKey, "password"),
+			"temporary": false,
+		}}
+	}
+	return nil
+}
+
+// serviceAccountUser returns the service account user of clientID, creating
+// it on first use. def, if not nil, is merged into it.
+func (c *compiler) serviceAccountUser(clientID string, def map[string]interface{}) map[string]interface{} {
+	user, ok := c.serviceAccounts[clientID]
+	if !ok {
+		user = map[string]interface{}{}
+		c.serviceAccounts[clientID] = user
+		appendList(c.realm, "users", user)
+	}
+	for k, v := range def {
+		user[k] = v
+	}
+	user["username"] = "service-account-" + strings.ToLower(clientID)
+	user["serviceAccountClientId"] = clientID
+	user["enabled"] = true
+	return user
+}
+
+func (c *compiler) compileRoleMappings() error {
+	roles := make(map[types.NamespacedName]*keycloakv1beta1.KeycloakRole, len(c.roles))
+	for _, role := range c.roles {
+		roles[client.ObjectKeyFromObject(role)] = role
+	}
+
+	for _, rm := range c.roleMappings {
+		var target map[string]interface{}
+		mapping := map[string]interface{}{}
+		subject := rm.Spec.Subject
+		switch {
+		case subject.UserRef != nil:
+			target = c.userDefs[types.NamespacedName{Namespace: rm.Namespace, Name: subject.UserRef.Name}]
+			mapping["user"] = stringField(target, "username")
+		case subject.GroupRef != nil:
+			if node, ok := c.groupNodes[types.NamespacedName{Namespace: rm.Namespace, Name: subject.GroupRef.Name}]; ok {
+				target = node.def
+				mapping["group"] = node.path
+			}
+		case subject.ServiceAccountRef != nil:
+			if clientID, ok := c.clientIDs[types.NamespacedName{Namespace: rm.Namespace, Name: subject.ServiceAccountRef.Name}]; ok {
+				target = c.serviceAccountUser(clientID, nil)
+				mapping["user"] = stringField(target, "username")
+			}
+		}
+		if target == nil {
+			// The subject belongs to another realm.
+			continue
+		}
+
+		var roleName, clientID string
+		switch {
+		case rm.Spec.RoleRef != nil:
+			role, ok := roles[types.NamespacedName{Namespace: rm.Namespace, Name: rm.Spec.RoleRef.Name}]
+			if !ok {
+				return objectError("KeycloakRoleMapping", rm, fmt.Errorf("roleRef: KeycloakRole %q not found", rm.Spec.RoleRef.Name))
+			}
+			var err error
+			if roleName, err = controller.ResolveIdentifier("name", role.Spec.Name, definitionString(role.Spec.Definition.Raw, "name")); err != nil {
+				return objectError("KeycloakRole", role, err)
+			}
+			if role.Spec.ClientRef != nil {
+				if clientID, ok = c.clientIDs[types.NamespacedName{Namespace: role.Namespace, Name: role.Spec.ClientRef.Name}]; !ok {
+					return objectError("KeycloakRoleMapping", rm, fmt.Errorf("roleRef: client of KeycloakRole %q not found in realm %s", role.Name, c.realmName))
+				}
+			}
+		case rm.Spec.Role != nil:
+			roleName = rm.Spec.Role.Name
+			switch {
+			case rm.Spec.Role.ClientRef != nil:
+				var ok bool
+				if clientID, ok = c.clientIDs[types.NamespacedName{Namespace: rm.Namespace, Name: rm.Spec.Role.ClientRef.Name}]; !ok {
+					return objectError("KeycloakRoleMapping", rm, fmt.Errorf("role.clientRef: KeycloakClient %q not found in realm %s", rm.Spec.Role.ClientRef.Name, c.realmName))
+				}
+			case rm.Spec.Role.ClientID != nil:
+				clientID = *rm.Spec.Role.ClientID
+			}
+		default:
+			return objectError("KeycloakRoleMapping", rm, fmt.Errorf("one of role or roleRef is required"))
+		}
+
+		if clientID == "" {
+			appendUnique(target, "realmRoles", roleName)
+			appendUnique(mapping, "realmRoles", roleName)
+		} else {
+			appendUnique(mapField(target, "clientRoles"), clientID, roleName)
+			appendUnique(mapField(mapping, "clientRoles"), clientID, roleName)
+		}
+		c.record(rm, "", roleName, mapping)
+	}
+	return nil
+}
+
+func (c *compiler) compileIdentityProviders() error {
+	for _, idp := range c.idps {
+		if !c.inRealm(idp.Namespace, idp.Spec.RealmRef, idp.Spec.ClusterRealmRef) {
+			continue
+		}
+		def, err := decodeDefinition(idp.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakIdentityProvider", idp, err)
+		}
+		alias, err := c.realmIdentifier(idp, "alias", idp.Spec.Alias, stringField(def, "alias"))
+		if err != nil {
+			return objectError("KeycloakIdentityProvider", idp, err)
+		}
+		delete(def, "internalId")
+		delete(def, "organizationId")
+		def["alias"] = alias
+		if ref := idp.Spec.ConfigSecretRef; ref != nil {
+			if def, err = c.mergeConfigSecret(idp.Namespace, ref, def, false); err != nil {
+				return objectError("KeycloakIdentityProvider", idp, err)
+			}
+		}
+		if ref := idp.Spec.OrganizationRef; ref != nil {
+			key := types.NamespacedName{Namespace: idp.Namespace, Name: ref.Name}
+			c.orgIdPs[key] = append(c.orgIdPs[key], alias)
+		}
+		if idp.Spec.TokenExchange != nil {
+			c.warnings = append(c.warnings, fmt.Sprintf("KeycloakIdentityProvider %s/%s: spec.tokenExchange is not part of a realm import and was skipped", idp.Namespace, idp.Name))
+		}
+		c.idpAliases[client.ObjectKeyFromObject(idp)] = alias
+		c.record(idp, "", alias, def)
+		appendList(c.realm, "identityProviders", def)
+	}
+
+	for _, mapper := range c.idpMappers {
+		alias, ok := c.idpAliases[types.NamespacedName{Namespace: mapper.Namespace, Name: mapper.Spec.IdentityProviderRef.Name}]
+		if !ok {
+			continue
+		}
+		def, err := decodeDefinition(mapper.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakIdentityProviderMapper", mapper, err)
+		}
+		name, err := controller.ResolveIdentifier("name", mapper.Spec.Name, stringField(def, "name"))
+		if err != nil {
+			return objectError("KeycloakIdentityProviderMapper", mapper, err)
+		}
+		delete(def, "id")
+		def["name"] = name
+		def["identityProviderAlias"] = alias
+		if ref := mapper.Spec.ConfigSecretRef; ref != nil {
+			if def, err = c.mergeConfigSecret(mapper.Namespace, ref, def, false); err != nil {
+				return objectError("KeycloakIdentityProviderMapper", mapper, err)
+			}
+		}
+		c.record(mapper, alias, name, def)
+		appendList(c.realm, "identityProviderMappers", def)
+	}
+	return nil
+}
+
+func (c *compiler) compileComponents() error {
+	for _, component := range c.components {
+		if !c.inRealm(component.Namespace, component.Spec.RealmRef, component.Spec.ClusterRealmRef) {
+			continue
+		}
+		def, err := decodeDefinition(component.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakComponent", component, err)
+		}
+		name, err := controller.ResolveIdentifier("name", component.Spec.Name, stringField(def, "name"))
+		if err != nil {
+			return objectError("KeycloakComponent", component, err)
+		}
+		providerType := stringField(def, "providerType")
+		if providerType == "" {
+			return objectError("KeycloakComponent", component, fmt.Errorf("definition.providerType is required"))
+		}
+		for _, key := range []string{"id", "parentId", "providerType"} {
+			delete(def, key)
+		}
+		def["name"] = name
+		if ref := component.Spec.ConfigSecretRef; ref != nil {
+			if def, err = c.mergeConfigSecret(component.Namespace, ref, def, true); err != nil {
+				return objectError("KeycloakComponent", component, err)
+			}
+		}
+		c.record(component, providerType, name, def)
+		appendList(mapField(c.realm, "components"), providerType, def)
+	}
+	return nil
+}
+
+func (c *compiler) compileOrganizations() error {
+	for _, org := range c.organizations {
+		if !c.inRealm(org.Namespace, org.Spec.RealmRef, org.Spec.ClusterRealmRef) {
+			continue
+		}
+		def, err := decodeDefinition(org.Spec.Definition.Raw)
+		if err != nil {
+			return objectError("KeycloakOrganization", org, err)
+		}
+		name, err := controller.ResolveIdentifier("name", org.Spec.Name, stringField(def, "name"))
+		if err != nil {
+			return objectError("KeycloakOrganization", org, err)
+		}
+		delete(def, "id")
+		def["name"] = name
+		for _, alias := range c.orgIdPs[client.ObjectKeyFromObject(org)] {
+			appendList(def, "identityProviders", map[string]interface{}{"alias": alias})
+		}
+		c.record(org, "", name, def)
+		appendList(c.realm, "organizations", def)
+	}
+	return nil
+}
+
+// record adds a copy of def, as compiled so far, to the compiled objects.
+func (c *compiler) record(source client.Object, parent, name string, def map[string]interface{}) {
+	// def holds decoded JSON values only, so the round trip cannot fail.
+	raw, _ := json.Marshal(def)
+	definition, _ := decodeDefinition(raw)
+	c.objects = append(c.objects, Object{Source: source, Parent: parent, Name: name, Definition: definition})
+}
+
+// secretValue returns key of the named Secret, or a placeholder for it if
+// the Secret or key is missing. key defaults to defaultKey.
+func (c *compiler) secretValue(namespace, name, key, defaultKey string) string {
+	if key == "" {
+		key = defaultKey
+	}
+	if secret, ok := c.secrets[types.NamespacedName{Namespace: namespace, Name: name}]; ok {
+		if value, ok := secret.Data[key]; ok {
+			return string(value)
+		}
+	}
+
+	variable := manifest.PlaceholderName(name, key)
+	found := false
+	for _, p := range c.placeholders {
+		found = found || p == variable
+	}
+	if !found {
+		c.placeholders = append(c.placeholders, variable)
+	}
+	return "${" + variable + "}"
+}
+
+// mergeConfigSecret merges a configSecretRef into def.config. Since the keys
+// of a missing Secret are unknown, nothing is merged and a warning recorded.
+func (c *compiler) mergeConfigSecret(namespace string, ref *keycloakv1beta1.ConfigSecretRef, def map[string]interface{}, wrapAsList bool) (map[string]interface{}, error) {
+	secret, ok := c.secrets[types.NamespacedName{Namespace: namespace, Name: ref.Name}]
+	if !ok {
+		c.warnings = append(c.warnings, fmt.Sprintf("Secret %s/%s referenced by configSecretRef was not found; its keys are missing from the import", namespace, ref.Name))
+		return def, nil
+	}
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	raw, err := json.Marshal(def)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDefinition(controller.MergeDefinitionConfig(raw, data, wrapAsList))
+}
+
+func decodeDefinition(raw json.RawMessage) (map[string]interface{}, error) {
+	def := map[string]interface{}{}
+	if len(raw) == 0 {
+		return def, nil
+	}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return nil, fmt.Errorf("invalid definition: %w", err)
+	}
+	if def == nil {
+		def = map[string]interface{}{}
+	}
+	return def, nil
+}
+
+func definitionString(raw json.RawMessage, key string) string {
+	def, err := decodeDefinition(raw)
+	if err != nil {
+		return ""
+	}
+	return stringField(def, key)
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func setDefault(m map[string]interface{}, key string, value interface{}) {
+	if _, ok := m[key]; !ok {
+		m[key] = value
+	}
+}
+
+// mapField returns the object under key, creating it if needed.
+func mapField(m map[string]interface{}, key string) map[string]interface{} {
+	if nested, ok := m[key].(map[string]interface{}); ok {
+		return nested
+	}
+	nested := map[string]interface{}{}
+	m[key] = nested
+	return nested
+}
+
+// appendList appends value to the list under key, after any entries the
+// definition already has.
+func appendList(m map[string]interface{}, key string, value interface{}) {
+	list, _ := m[key].([]interface{})
+	m[key] = append(list, value)
+}
+
+func appendUnique(m map[string]interface{}, key string, value string) {
+	list, _ := m[key].([]interface{})
+	for _, v := range list {
+		if v == value {
+			return
+		}
+	}
+	m[key] = append(list, value)
+}
+
+func objectError(kind string, obj client.Object, err error) error {
+	if obj.GetNamespace() == "" {
+		return fmt.Errorf("%s %s: %w", kind, obj.GetName(), err)
+	}
+	return fmt.Errorf("%s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+}
@@ -0,0 +1,434 @@
+package compile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/export"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// load decodes manifests the way the compile subcommand reads them.
+func load(t *testing.T, manifests string) []client.Object {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(manifests), 0644))
+	objects, err := manifest.Load("team", path)
+	require.NoError(t, err)
+	return objects
+}
+
+// compiled marshals the compiled realm so tests can compare it as JSON.
+func compiled(t *testing.T, objects []client.Object, opts Options) (string, *Result) {
+	t.Helper()
+	result, err := Compile(objects, opts)
+	require.NoError(t, err)
+	data, err := json.Marshal(result.Realm)
+	require.NoError(t, err)
+	return string(data), result
+}
+
+func TestCompile(t *testing.T) {
+	objects := load(t, `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  realmName: test
+  definition:
+    enabled: true
+    smtpServer:
+      host: mail
+  smtpSecretRef:
+    name: smtp
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: smtp
+stringData:
+  user: mailer
+  password: hunter2
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakAuthenticationFlow
+metadata:
+  name: flow
+spec:
+  realmRef:
+    name: realm
+  alias: custom-browser
+  providerId: basic-flow
+  executions:
+  - authenticator: auth-cookie
+    requirement: ALTERNATIVE
+  - subFlow:
+      alias: forms
+      providerId: basic-flow
+    requirement: ALTERNATIVE
+    executions:
+    - authenticator: auth-otp-form
+      requirement: REQUIRED
+      authenticatorConfig:
+        otpPolicy: totp
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  realmRef:
+    name: realm
+  clientId: app
+  definition:
+    publicClient: false
+    serviceAccountsEnabled: true
+    authenticationFlowBindingOverrides:
+      browserFlowAlias: custom-browser
+  clientSecretRef:
+    name: app-credentials
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakProtocolMapper
+metadata:
+  name: tenant
+spec:
+  clientRef:
+    name: app
+  name: tenant
+  definition:
+    protocol: openid-connect
+    protocolMapper: oidc-hardcoded-claim-mapper
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: reader
+spec:
+  realmRef:
+    name: realm
+  name: reader
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: app-admin
+spec:
+  clientRef:
+    name: app
+  name: admin
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: team
+spec:
+  realmRef:
+    name: realm
+  name: team
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: ops
+spec:
+  parentGroupRef:
+    name: team
+  name: ops
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUser
+metadata:
+  name: alice
+spec:
+  realmRef:
+    name: realm
+  username: alice
+  groups: [ops]
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUserCredential
+metadata:
+  name: alice-password
+spec:
+  userRef:
+    name: alice
+  userSecret:
+    secretName: alice
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: alice-reader
+spec:
+  subject:
+    userRef:
+      name: alice
+  roleRef:
+    name: reader
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: ops-admin
+spec:
+  subject:
+    groupRef:
+      name: ops
+  roleRef:
+    name: app-admin
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: app-sa
+spec:
+  subject:
+    serviceAccountRef:
+      name: app
+  role:
+    name: view-users
+    clientId: realm-management
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakComponent
+metadata:
+  name: ldap
+spec:
+  realmRef:
+    name: realm
+  name: ldap
+  configSecretRef:
+    name: ldap
+  definition:
+    providerId: ldap
+    providerType: org.keycloak.storage.UserStorageProvider
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: ldap
+stringData:
+  bindCredential: s3cret
+---
+# Belongs to another realm.
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: other
+spec:
+  realmRef:
+    name: other
+  clientId: other
+`)
+
+	realm, result := compiled(t, objects, Options{})
+	require.Equal(t, []string{"ALICE_PASSWORD", "APP_CREDENTIALS_CLIENT_SECRET"}, result.Placeholders)
+
+	browserID := flowID(t, result, 0)
+	require.NotEmpty(t, browserID)
+
+	require.JSONEq(t, `{
+		"realm": "test",
+		"enabled": true,
+		"smtpServer": {"host": "mail", "user": "mailer", "password": "hunter2"},
+		"authenticationFlows": [
+			{
+				"id": "`+browserID+`", "alias": "custom-browser", "providerId": "basic-flow", "topLevel": true, "builtIn": false,
+				"authenticationExecutions": [
+					{"authenticator": "auth-cookie", "authenticatorFlow": false, "requirement": "ALTERNATIVE", "priority": 10},
+					{"flowAlias": "forms", "authenticatorFlow": true, "requirement": "ALTERNATIVE", "priority": 20}
+				]
+			},
+			{
+				"id": "`+flowID(t, result, 1)+`", "alias": "forms", "providerId": "basic-flow", "topLevel": false, "builtIn": false,
+				"authenticationExecutions": [
+					{"authenticator": "auth-otp-form", "authenticatorConfig": "forms-auth-otp-form-config", "authenticatorFlow": false, "requirement": "REQUIRED", "priority": 10}
+				]
+			}
+		],
+		"authenticatorConfig": [{"alias": "forms-auth-otp-form-config", "config": {"otpPolicy": "totp"}}],
+		"clients": [{
+			"clientId": "app",
+			"publicClient": false,
+			"serviceAccountsEnabled": true,
+			"secret": "${APP_CREDENTIALS_CLIENT_SECRET}",
+			"authenticationFlowBindingOverrides": {"browser": "`+browserID+`"},
+			"protocolMappers": [{"name": "tenant", "protocol": "openid-connect", "protocolMapper": "oidc-hardcoded-claim-mapper"}]
+		}],
+		"roles": {
+			"realm": [{"name": "reader"}],
+			"client": {"app": [{"name": "admin", "clientRole": true}]}
+		},
+		"groups": [{
+			"name": "team",
+			"subGroups": [{"name": "ops", "clientRoles": {"app": ["admin"]}}]
+		}],
+		"users": [
+			{
+				"username": "alice",
+				"groups": ["/team/ops"],
+				"realmRoles": ["reader"],
+				"credentials": [{"type": "password", "value": "${ALICE_PASSWORD}", "temporary": false}]
+			},
+			{
+				"username": "service-account-app",
+				"serviceAccountClientId": "app",
+				"enabled": true,
+				"clientRoles": {"realm-management": ["view-users"]}
+			}
+		],
+		"components": {
+			"org.keycloak.storage.UserStorageProvider": [
+				{"name": "ldap", "providerId": "ldap", "config": {"bindCredential": ["s3cret"]}}
+			]
+		}
+	}`, realm)
+}
+
+// flowID returns the ID of the i-th compiled flow.
+func flowID(t *testing.T, result *Result, i int) string {
+	t.Helper()
+	return result.Realm["authenticationFlows"].([]interface{})[i].(map[string]interface{})["id"].(string)
+}
+
+func TestCompileErrors(t *testing.T) {
+	const realmA = `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: a
+spec:
+  realmName: a
+  definition: {}
+`
+	const realmB = `---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: b
+spec:
+  realmName: b
+  definition: {}
+`
+	const realm = `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  realmName: test
+  definition: {}
+---
+`
+	tests := []struct {
+		name      string
+		manifests string
+		opts      Options
+		wantErr   string
+	}{
+		{name: "no realm", wantErr: "no KeycloakRealm"},
+		{
+			name:      "several realms",
+			manifests: realmA + realmB,
+			wantErr:   "select one with --realm",
+		},
+		{
+			name:      "unknown realm",
+			manifests: realmA,
+			opts:      Options{Realm: "b"},
+			wantErr:   `realm "b" not found`,
+		},
+		{
+			name: "missing identifier",
+			manifests: realm + `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  realmRef:
+    name: realm
+`,
+			wantErr: "KeycloakClient team/app: spec.clientId is required",
+		},
+		{
+			name: "unknown flow binding",
+			manifests: realm + `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  realmRef:
+    name: realm
+  clientId: app
+  definition:
+    authenticationFlowBindingOverrides:
+      browserFlowAlias: missing
+`,
+			wantErr: `no KeycloakAuthenticationFlow with alias "missing"`,
+		},
+		{
+			name: "unknown group",
+			manifests: realm + `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUser
+metadata:
+  name: alice
+spec:
+  realmRef:
+    name: realm
+  username: alice
+  groups: [missing]
+`,
+			wantErr: `no KeycloakGroup named "missing"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(load(t, tt.manifests), tt.opts)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := Compile(load(t, realmA+realmB), Options{Realm: "b"})
+	require.NoError(t, err, "--realm selects one of several realms")
+}
+
+// TestCompileExportRoundTrip compiles the manifests the exporter writes for a
+// realm export file and checks that the realm survives the round trip.
+func TestCompileExportRoundTrip(t *testing.T) {
+	exporter, err := export.NewFileExporter([]byte(`{
+		"realm": "test",
+		"enabled": true,
+		"roles": {"realm": [{"name": "reader"}]},
+		"groups": [{"name": "team", "realmRoles": ["reader"], "subGroups": [{"name": "ops"}]}],
+		"users": [{"username": "alice", "realmRoles": ["reader"]}],
+		"clients": [{"clientId": "app", "publicClient": true}]
+	}`), testr.New(t), export.ExporterOptions{TargetNamespace: "team", SkipDefaults: true})
+	require.NoError(t, err)
+	resources, err := exporter.Export()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, export.NewWriter(export.WriterOptions{OutputDir: dir}).Write(resources))
+	objects, err := manifest.Load("default", dir)
+	require.NoError(t, err)
+	require.Len(t, objects, len(resources))
+
+	realm, _ := compiled(t, objects, Options{})
+	require.JSONEq(t, `{
+		"realm": "test",
+		"enabled": true,
+		"roles": {"realm": [{"name": "reader"}]},
+		"groups": [{"name": "team", "realmRoles": ["reader"], "subGroups": [{"name": "ops"}]}],
+		"users": [{"username": "alice", "realmRoles": ["reader"]}],
+		"clients": [{"clientId": "app", "publicClient": true}]
+	}`, realm)
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// ReferenceNotAllowedReason is the status/condition reason used when a CR
+// references a ClusterKeycloakInstance or ClusterKeycloakRealm whose
+// spec.allowedNamespaces does not admit the CR's namespace and kind, or a
+// Secret or ConfigMap in another namespace without a KeycloakReferenceGrant.
+const ReferenceNotAllowedReason = "ReferenceNotAllowed"
+
+// ReferenceNotAllowedError is returned when a cluster-scoped resource's
+// allowedNamespaces policy, or the absence of a KeycloakReferenceGrant,
+// rejects a reference.
+type ReferenceNotAllowedError struct {
+	// Kind and Name identify the resource being referenced.
+	Kind string
+	Name string
+	// ReferrerKind and Namespace identify the rejected reference.
+	ReferrerKind string
+	Namespace    string
+	// Hint tells the user how to admit the reference.
+	Hint string
+}
+
+func (e *ReferenceNotAllowedError) Error() string {
+	return fmt.Sprintf("%s %s does not allow references from %s in namespace %s (%s)",
+		e.Kind, e.Name, e.ReferrerKind, e.Namespace, e.Hint)
+}
+
+// IsReferenceNotAllowed reports whether err (or anything it wraps) is a
+// *ReferenceNotAllowedError.
+func IsReferenceNotAllowed(err error) bool {
+	var target *ReferenceNotAllowedError
+	return errors.As(err, &target)
+}
+
+// checkNamespaceAccess returns a *ReferenceNotAllowedError unless one of the
+// rules of policy admits referrer. Cluster-scoped referrers and resources
+// without a policy (policy == nil) are always admitted; a policy without rules
+// admits no namespace.
+func checkNamespaceAccess(ctx context.Context, c client.Client, policy *keycloakv1beta1.NamespaceAccessPolicy, kind, name string, referrer client.Object) error {
+	namespace := referrer.GetNamespace()
+	if policy == nil || namespace == "" {
+		return nil
+	}
+	referrerKind := kindOf(referrer)
+
+	// The Namespace object is only needed for selector rules; fetch it once.
+	var nsLabels map[string]string
+	var nsErr error
+	var nsFetched bool
+	for _, rule := range policy.Rules {
+		if len(rule.Kinds) > 0 && !slices.Contains(rule.Kinds, referrerKind) {
+			continue
+		}
+		if slices.Contains(rule.Names, namespace) {
+			return nil
+		}
+		if rule.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(rule.Selector)
+		if err != nil {
+			return fmt.Errorf("%s %s has an invalid allowedNamespaces selector: %w", kind, name, err)
+		}
+		if !nsFetched {
+			ns := &corev1.Namespace{}
+			nsErr = c.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+			nsLabels, nsFetched = ns.Labels, true
+		}
+		if nsErr != nil {
+			return fmt.Errorf("failed to get namespace %s to check %s %s allowedNamespaces: %w", namespace, kind, name, nsErr)
+		}
+		if selector.Matches(labels.Set(nsLabels)) {
+			return nil
+		}
+	}
+	return &ReferenceNotAllowedError{
+		Kind:         kind,
+		Name:         name,
+		ReferrerKind: referrerKind,
+		Namespace:    namespace,
+		Hint:         "see spec.allowedNamespaces",
+	}
+}
+
+// kindOf returns the Kind of a typed Keycloak CR. Objects read through the
+// client usually have an empty TypeMeta, so the Go type name is used instead;
+// it matches the Kind for every type in api/v1beta1.
+func kindOf(obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+func TestCheckNamespaceAccess(t *testing.T) {
+	c := newAuthTestClient(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"tenant": "shared"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+	)
+	ctx := context.Background()
+	kcUser := &keycloakv1beta1.KeycloakUser{ObjectMeta: metav1.ObjectMeta{Name: "u", Namespace: "team-a"}}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "shared"}}
+
+	cases := []struct {
+		name     string
+		policy   *keycloakv1beta1.NamespaceAccessPolicy
+		referrer client.Object
+		allowed  bool
+	}{
+		{"no policy", nil, kcClientIn("team-b"), true},
+		{"empty policy", &keycloakv1beta1.NamespaceAccessPolicy{}, kcClientIn("team-a"), false},
+		{"listed name", allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"team-b"}}), kcClientIn("team-b"), true},
+		{"unlisted name", allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"team-b"}}), kcClientIn("team-a"), false},
+		{"selector match", allowRules(keycloakv1beta1.NamespaceAccessRule{Selector: selector}), kcClientIn("team-a"), true},
+		{"selector mismatch", allowRules(keycloakv1beta1.NamespaceAccessRule{Selector: selector}), kcClientIn("team-b"), false},
+		{"kind match", allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"team-a"}, Kinds: []string{"KeycloakClient"}}), kcClientIn("team-a"), true},
+		{"kind mismatch", allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"team-a"}, Kinds: []string{"KeycloakClient"}}), kcUser, false},
+		{"cluster-scoped referrer", &keycloakv1beta1.NamespaceAccessPolicy{}, &keycloakv1beta1.ClusterKeycloakRealm{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkNamespaceAccess(ctx, c, tc.policy, "ClusterKeycloakRealm", "shared", tc.referrer)
+			if tc.allowed && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tc.allowed && !IsReferenceNotAllowed(err) {
+				t.Errorf("got %v, want ReferenceNotAllowedError", err)
+			}
+		})
+	}
+}
+
+// TestNamespaceAccessPolicy_EmptySurvivesUpdate guards against a policy
+// without rules being dropped when the resource is written back, e.g. when
+// the finalizer is added, which would turn deny-all into allow-all.
+func TestNamespaceAccessPolicy_EmptySurvivesUpdate(t *testing.T) {
+	realm := &keycloakv1beta1.ClusterKeycloakRealm{}
+	if err := json.Unmarshal([]byte(`{"metadata":{"name":"shared"},"spec":{"allowedNamespaces":{}}}`), realm); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if realm.Spec.AllowedNamespaces == nil {
+		t.Fatal("allowedNamespaces: {} decoded as unset")
+	}
+
+	c := newAuthTestClient(t, realm)
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(realm)
+	stored := &keycloakv1beta1.ClusterKeycloakRealm{}
+	if err := c.Get(ctx, key, stored); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	controllerutil.AddFinalizer(stored, FinalizerName)
+	if err := c.Update(ctx, stored); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	updated := &keycloakv1beta1.ClusterKeycloakRealm{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	data, err := json.Marshal(updated.Spec)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"allowedNamespaces":{}`) {
+		t.Errorf("spec after update = %s, want allowedNamespaces: {}", data)
+	}
+	err = checkNamespaceAccess(ctx, c, updated.Spec.AllowedNamespaces, "ClusterKeycloakRealm", "shared", kcClientIn("team-a"))
+	if !IsReferenceNotAllowed(err) {
+		t.Errorf("got %v, want ReferenceNotAllowedError after update", err)
+	}
+}
+
+func TestResolveRealm_ReferenceNotAllowed(t *testing.T) {
+	objs := resolveRealmFixtures()
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *keycloakv1beta1.ClusterKeycloakRealm:
+			o.Spec.AllowedNamespaces = allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"team-a"}})
+		case *keycloakv1beta1.ClusterKeycloakInstance:
+			o.Spec.AllowedNamespaces = allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"kc"}})
+		}
+	}
+	c := newAuthTestClient(t, objs...)
+	cm := keycloak.NewClientManager(logr.Discard())
+	ctx := context.Background()
+	clusterRealmRef := &keycloakv1beta1.ClusterResourceRef{Name: "crealm-cluster-instance"}
+
+	if _, err := ResolveRealm(ctx, c, cm, kcClientIn("team-a"), nil, clusterRealmRef); err != nil {
+		t.Errorf("allowed namespace: unexpected error: %v", err)
+	}
+
+	_, err := ResolveRealm(ctx, c, cm, kcClientIn("team-b"), nil, clusterRealmRef)
+	if got := notReadyReason(err, "RealmNotReady"); got != ReferenceNotAllowedReason {
+		t.Errorf("cluster realm: got reason %q (%v), want %q", got, err, ReferenceNotAllowedReason)
+	}
+
+	// A namespaced realm in a namespace the cluster instance does not admit.
+	_, err = ResolveRealm(ctx, c, cm, kcClientIn("demo"), &keycloakv1beta1.ResourceRef{Name: "realm-cluster-instance"}, nil)
+	if !IsReferenceNotAllowed(err) {
+		t.Errorf("cluster instance: got %v, want ReferenceNotAllowedError", err)
+	}
+}
+
+// TestProtocolMapper_ReferrerKind checks that allowedNamespaces kinds are
+// matched against the mapper itself, not the client it belongs to.
+func TestProtocolMapper_ReferrerKind(t *testing.T) {
+	kcClient := kcClientIn("team-a")
+	kcClient.Spec.ClusterRealmRef = &keycloakv1beta1.ClusterResourceRef{Name: "crealm-cluster-instance"}
+	mapper := &keycloakv1beta1.KeycloakProtocolMapper{ObjectMeta: metav1.ObjectMeta{Name: "m", Namespace: "team-a"}}
+
+	for _, tc := range []struct {
+		kind    string
+		allowed bool
+	}{
+		{"KeycloakProtocolMapper", true},
+		{"KeycloakClient", false},
+	} {
+		t.Run(tc.kind, func(t *testing.T) {
+			objs := resolveRealmFixtures()
+			for _, obj := range objs {
+				if o, ok := obj.(*keycloakv1beta1.ClusterKeycloakRealm); ok {
+					o.Spec.AllowedNamespaces = allowRules(keycloakv1beta1.NamespaceAccessRule{Names: []string{"team-a"}, Kinds: []string{tc.kind}})
+				}
+			}
+			r := &KeycloakProtocolMapperReconciler{Client: newAuthTestClient(t, objs...), ClientManager: keycloak.NewClientManager(logr.Discard())}
+			_, _, err := r.getKeycloakClientAndRealmFromClient(context.Background(), mapper, kcClient)
+			if got := notReadyReason(err, "RealmNotReady") == ReferenceNotAllowedReason; got == tc.allowed {
+				t.Errorf("kinds [%s]: got %v, want allowed=%v", tc.kind, err, tc.allowed)
+			}
+		})
+	}
+}
+
+// allowRules returns a NamespaceAccessPolicy of rules.
+func allowRules(rules ...keycloakv1beta1.NamespaceAccessRule) *keycloakv1beta1.NamespaceAccessPolicy {
+	return &keycloakv1beta1.NamespaceAccessPolicy{Rules: rules}
+}
+
+func kcClientIn(namespace string) *keycloakv1beta1.KeycloakClient {
+	return &keycloakv1beta1.KeycloakClient{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace}}
+}
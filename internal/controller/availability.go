@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// InstanceUnavailableReason is the status/condition reason used while the
+// circuit breaker of the resource's Keycloak instance is open. The reconciler
+// does not call the Admin API in that state; it requeues with jitter and is
+// enqueued again once the instance (and the realm in between) becomes ready.
+const InstanceUnavailableReason = "InstanceUnavailable"
+
+// notReadyReason returns InstanceUnavailableReason when err comes from an open
+// circuit breaker, ReferenceNotAllowedReason when an allowedNamespaces policy
+// rejected the reference, QuotaExceededReason when a KeycloakQuota is full,
+// and fallback otherwise.
+func notReadyReason(err error, fallback string) string {
+	if keycloak.IsInstanceUnavailable(err) {
+		return InstanceUnavailableReason
+	}
+	if IsReferenceNotAllowed(err) {
+		return ReferenceNotAllowedReason
+	}
+	if IsQuotaExceeded(err) {
+		return QuotaExceededReason
+	}
+	return fallback
+}
+
+// unavailableRequeueDelay spreads requeues of resources whose instance is
+// unavailable over [ErrorRequeueDelay, 2*ErrorRequeueDelay), so they do not
+// all hit the instance in the same second once it is back.
+func unavailableRequeueDelay() time.Duration {
+	return wait.Jitter(ErrorRequeueDelay, 1.0)
+}
+
+// readyReason returns the reason of obj's Ready condition, or "" if it has
+// none.
+func readyReason(obj client.Object) string {
+	status := statusOf(obj)
+	if status == nil {
+		return ""
+	}
+	field := reflect.ValueOf(status).FieldByName("Conditions")
+	if !field.IsValid() {
+		return ""
+	}
+	conditions, ok := field.Interface().([]metav1.Condition)
+	if !ok {
+		return ""
+	}
+	if c := meta.FindStatusCondition(conditions, ReadyConditionType); c != nil {
+		return c.Reason
+	}
+	return ""
+}
+
+// isReady reports obj's Status.Ready.
+func isReady(obj client.Object) bool {
+	status := statusOf(obj)
+	if status == nil {
+		return false
+	}
+	field := reflect.ValueOf(status).FieldByName("Ready")
+	return field.IsValid() && field.Kind() == reflect.Bool && field.Bool()
+}
+
+// becameReady passes only updates that flip Status.Ready from false to true.
+// Dependents are reconciled on their own at startup and on their sync period;
+// this only fans out recovery after an outage. Realm children are enqueued
+// through findDependentsForRealm, their own children through
+// findDependentsForParent, so recovery reaches every level of the tree.
+var becameReady = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !isReady(e.ObjectOld) && isReady(e.ObjectNew)
+	},
+}
+
+// findDependentsForRealm returns a map function that enqueues every object in
+// the list returned by newList whose spec.realmRef or spec.clusterRealmRef
+// points at the KeycloakRealm or ClusterKeycloakRealm being mapped.
+func findDependentsForRealm(c client.Client, newList func() client.ObjectList) func(context.Context, client.Object) []reconcile.Request {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		list := newList()
+		var opts []client.ListOption
+		field, name := "RealmRef", obj.GetName()
+		switch obj.(type) {
+		case *keycloakv1beta1.KeycloakRealm:
+			opts = append(opts, client.InNamespace(obj.GetNamespace()))
+		case *keycloakv1beta1.ClusterKeycloakRealm:
+			field = "ClusterRealmRef"
+		default:
+			return nil
+		}
+		if err := c.List(ctx, list, opts...); err != nil {
+			return nil
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, item := range items {
+			dependent, ok := item.(client.Object)
+			if !ok || specRefName(dependent, field) != name {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: dependent.GetName(), Namespace: dependent.GetNamespace()},
+			})
+		}
+		return requests
+	}
+}
+
+// findDependentsForParent returns a map function that enqueues every object
+// in the list returned by newList, in the namespace of the object being
+// mapped, whose spec.<path>.name names that object for one of paths. A path
+// may descend into nested structs, e.g. "Subject.UserRef".
+func findDependentsForParent(c client.Client, newList func() client.ObjectList, paths ...string) func(context.Context, client.Object) []reconcile.Request {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		list := newList()
+		if err := c.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+			return nil
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, item := range items {
+			dependent, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			for _, path := range paths {
+				if specRefName(dependent, path) == obj.GetName() {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: dependent.GetName(), Namespace: dependent.GetNamespace()},
+					})
+					break
+				}
+			}
+		}
+		return requests
+	}
+}
+
+// specRefName returns spec.<path>.name of obj, or "" when the reference (or
+// a struct on the way to it) is unset. path names Go fields separated by
+// dots.
+func specRefName(obj client.Object, path string) string {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ""
+	}
+	ref := v.Elem().FieldByName("Spec")
+	for _, field := range strings.Split(path, ".") {
+		if !ref.IsValid() {
+			return ""
+		}
+		ref = ref.FieldByName(field)
+		if ref.Kind() == reflect.Ptr {
+			if ref.IsNil() {
+				return ""
+			}
+			ref = ref.Elem()
+		}
+		if ref.Kind() != reflect.Struct {
+			return ""
+		}
+	}
+	name := ref.FieldByName("Name")
+	if !name.IsValid() || name.Kind() != reflect.String {
+		return ""
+	}
+	return name.String()
+}
+
+// breakerEvents returns a channel receiving an event whenever the circuit
+// breaker of an instance opens or closes, so the instance reconciler probes
+// the server right away instead of waiting for its sync period. toObject maps
+// a ClientManager key to the instance, or returns nil for keys of the other
+// instance kind. Events are dropped when the channel is full; the instance is
+// requeued periodically anyway.
+func breakerEvents(m *keycloak.ClientManager, toObject func(key string) client.Object) <-chan event.GenericEvent {
+	ch := make(chan event.GenericEvent, 128)
+	m.AddBreakerListener(func(key string, _ bool) {
+		obj := toObject(key)
+		if obj == nil {
+			return
+		}
+		select {
+		case ch <- event.GenericEvent{Object: obj}:
+		default:
+		}
+	})
+	return ch
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+func TestNotReadyReason(t *testing.T) {
+	unavailable := fmt.Errorf("KeycloakInstance kc/kci: %w", keycloak.ErrInstanceUnavailable)
+	if got := notReadyReason(unavailable, "RealmNotReady"); got != InstanceUnavailableReason {
+		t.Errorf("got %q, want %q", got, InstanceUnavailableReason)
+	}
+	if got := notReadyReason(errors.New("KeycloakRealm demo/x is not ready"), "RealmNotReady"); got != "RealmNotReady" {
+		t.Errorf("got %q, want RealmNotReady", got)
+	}
+}
+
+func TestBecameReady(t *testing.T) {
+	notReady := &keycloakv1beta1.KeycloakRealm{}
+	ready := &keycloakv1beta1.KeycloakRealm{Status: keycloakv1beta1.KeycloakRealmStatus{Ready: true}}
+
+	if !becameReady.Update(event.UpdateEvent{ObjectOld: notReady, ObjectNew: ready}) {
+		t.Error("not ready -> ready should pass")
+	}
+	if becameReady.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: ready}) {
+		t.Error("ready -> ready should be filtered")
+	}
+	if becameReady.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: notReady}) {
+		t.Error("ready -> not ready should be filtered")
+	}
+	if becameReady.Create(event.CreateEvent{Object: ready}) {
+		t.Error("create should be filtered")
+	}
+}
+
+func TestWriteStatusIfChanged_JittersInstanceUnavailable(t *testing.T) {
+	realm := &keycloakv1beta1.KeycloakRealm{ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: "demo"}}
+	realm.Status.Conditions = []metav1.Condition{{
+		Type:               ReadyConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             InstanceUnavailableReason,
+		Message:            "down",
+		LastTransitionTime: metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}}
+	c := fake.NewClientBuilder().
+		WithScheme(newScheme(t)).
+		WithStatusSubresource(&keycloakv1beta1.KeycloakRealm{}).
+		WithObjects(realm).
+		Build()
+
+	res, err := writeStatusIfChanged(context.Background(), c, realm, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.RequeueAfter < ErrorRequeueDelay || res.RequeueAfter >= 2*ErrorRequeueDelay {
+		t.Errorf("RequeueAfter %v outside [%v, %v)", res.RequeueAfter, ErrorRequeueDelay, 2*ErrorRequeueDelay)
+	}
+}
+
+func TestFindDependentsForRealm(t *testing.T) {
+	c := newAuthTestClient(t,
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "demo"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "r"}},
+		},
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-realm", Namespace: "demo"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "other"}},
+		},
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "elsewhere"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "r"}},
+		},
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "elsewhere"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "r"}},
+		},
+	)
+	find := findDependentsForRealm(c, func() client.ObjectList { return &keycloakv1beta1.KeycloakClientList{} })
+	ctx := context.Background()
+
+	got := find(ctx, &keycloakv1beta1.KeycloakRealm{ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: "demo"}})
+	want := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "app", Namespace: "demo"}}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("realm dependents: got %v, want %v", got, want)
+	}
+
+	got = find(ctx, &keycloakv1beta1.ClusterKeycloakRealm{ObjectMeta: metav1.ObjectMeta{Name: "r"}})
+	want = []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "shared", Namespace: "elsewhere"}}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("cluster realm dependents: got %v, want %v", got, want)
+	}
+}
+
+func TestFindDependentsForParent(t *testing.T) {
+	c := newAuthTestClient(t,
+		&keycloakv1beta1.KeycloakUserCredential{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "demo"},
+			Spec:       keycloakv1beta1.KeycloakUserCredentialSpec{UserRef: keycloakv1beta1.ResourceRef{Name: "alice"}},
+		},
+		&keycloakv1beta1.KeycloakUserCredential{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "elsewhere"},
+			Spec:       keycloakv1beta1.KeycloakUserCredentialSpec{UserRef: keycloakv1beta1.ResourceRef{Name: "alice"}},
+		},
+		&keycloakv1beta1.KeycloakRoleMapping{
+			ObjectMeta: metav1.ObjectMeta{Name: "inline", Namespace: "demo"},
+			Spec: keycloakv1beta1.KeycloakRoleMappingSpec{
+				Subject: keycloakv1beta1.RoleMappingSubject{UserRef: &keycloakv1beta1.ResourceRef{Name: "alice"}},
+				Role:    &keycloakv1beta1.RoleDefinition{Name: "admin", ClientRef: &keycloakv1beta1.ResourceRef{Name: "app"}},
+			},
+		},
+		&keycloakv1beta1.KeycloakRoleMapping{
+			ObjectMeta: metav1.ObjectMeta{Name: "realm-role", Namespace: "demo"},
+			Spec: keycloakv1beta1.KeycloakRoleMappingSpec{
+				Subject: keycloakv1beta1.RoleMappingSubject{UserRef: &keycloakv1beta1.ResourceRef{Name: "alice"}},
+				Role:    &keycloakv1beta1.RoleDefinition{Name: "admin"},
+			},
+		},
+	)
+	ctx := context.Background()
+
+	find := findDependentsForParent(c, func() client.ObjectList { return &keycloakv1beta1.KeycloakUserCredentialList{} }, "UserRef")
+	got := find(ctx, &keycloakv1beta1.KeycloakUser{ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "demo"}})
+	want := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "alice", Namespace: "demo"}}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("user dependents: got %v, want %v", got, want)
+	}
+
+	find = findDependentsForParent(c, func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleMappingList{} }, "Role.ClientRef")
+	got = find(ctx, &keycloakv1beta1.KeycloakClient{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "demo"}})
+	want = []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "inline", Namespace: "demo"}}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("client dependents: got %v, want %v", got, want)
+	}
+}
+
+func TestResolveRealm_InstanceUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	objs := resolveRealmFixtures()
+	for _, obj := range objs {
+		if instance, ok := obj.(*keycloakv1beta1.KeycloakInstance); ok {
+			instance.Spec.BaseUrl = srv.URL
+		}
+	}
+	c := newAuthTestClient(t, objs...)
+	cm := keycloak.NewClientManagerWithConfig(logr.Discard(), keycloak.ClientManagerConfig{
+		BreakerFailureThreshold: 1,
+		BreakerCooldown:         time.Hour,
+	})
+	ctx := context.Background()
+	realmRef := &keycloakv1beta1.ResourceRef{Name: "realm-ns-instance"}
+	referrer := &keycloakv1beta1.KeycloakClient{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "kc"}}
+
+	res, err := ResolveRealm(ctx, c, cm, referrer, realmRef, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := res.Client.Ping(ctx); err == nil {
+		t.Fatal("expected the token request to fail")
+	}
+
+	_, err = ResolveRealm(ctx, c, cm, referrer, realmRef, nil)
+	if !keycloak.IsInstanceUnavailable(err) {
+		t.Fatalf("got error %v, want ErrInstanceUnavailable", err)
+	}
+	if got := notReadyReason(err, "RealmNotReady"); got != InstanceUnavailableReason {
+		t.Errorf("reason: got %q, want %q", got, InstanceUnavailableReason)
+	}
+
+	// Realms on other instances are unaffected.
+	if _, err := ResolveRealm(ctx, c, cm, referrer, nil, &keycloakv1beta1.ClusterResourceRef{Name: "crealm-cluster-instance"}); err != nil {
+		t.Errorf("cluster instance realm: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package controller
+
+// UnsupportedByServerReason is the status/condition reason used when a CR
+// needs an Admin API feature that the Keycloak server does not offer, either
+// because it is too old or because the feature is disabled. The reconciler
+// sets it instead of calling an endpoint that would fail with an opaque HTTP
+// error; see keycloak.Capabilities.
+const UnsupportedByServerReason = "UnsupportedByServer"
@@ -3,14 +3,19 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
@@ -26,6 +31,7 @@ type ClusterKeycloakInstanceReconciler struct {
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakinstances,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakinstances/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakinstances/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // Reconcile handles ClusterKeycloakInstance reconciliation
 func (r *ClusterKeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -40,6 +46,7 @@ func (r *ClusterKeycloakInstanceReconciler) Reconcile(ctx context.Context, req c
 			// Object deleted, remove client from manager
 			r.ClientManager.RemoveClient(clusterInstanceKey(req.Name))
 			SetKeycloakConnectionStatus(req.Name, "_cluster", false)
+			DeleteInstanceHealth(req.Name, "_cluster")
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "unable to fetch ClusterKeycloakInstance")
@@ -53,6 +60,10 @@ func (r *ClusterKeycloakInstanceReconciler) Reconcile(ctx context.Context, req c
 		RecordReconcile(controllerName, instance.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, instance); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !instance.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(instance, FinalizerName) {
@@ -93,13 +104,21 @@ func (r *ClusterKeycloakInstanceReconciler) Reconcile(ctx context.Context, req c
 		return r.updateStatus(ctx, instance, false, "", "ConnectionFailed", fmt.Sprintf("Failed to connect: %v", err))
 	}
 
-	// Get server version
+	// Probe the server: version, latency, token and certificate expiry
 	version := ""
-	serverInfo, err := kc.GetServerInfo(ctx)
+	health, err := kc.Probe(ctx)
 	if err != nil {
 		log.Error(err, "failed to get server info")
-	} else if serverInfo != nil && serverInfo.SystemInfo.Version != "" {
-		version = serverInfo.SystemInfo.Version
+	} else {
+		if health.ServerInfo.SystemInfo.Version != "" {
+			version = health.ServerInfo.SystemInfo.Version
+		}
+		// Record which optional Admin API features dependent resources can use
+		caps := keycloak.DetectCapabilities(health.ServerInfo)
+		instance.Status.Capabilities = caps.List()
+		instance.Status.CapabilitiesDetected = caps.Probed()
+		instance.Status.Health = instanceHealthStatus(instance.Status.Health, health, syncInterval(ctx, r.Client, instance))
+		SetInstanceHealth(instance.Name, "_cluster", health)
 	}
 
 	// Update connection status metric
@@ -129,7 +148,16 @@ func (r *ClusterKeycloakInstanceReconciler) updateStatus(ctx context.Context, in
 
 // SetupWithManager sets up the controller with the Manager
 func (r *ClusterKeycloakInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	breakerChanges := breakerEvents(r.ClientManager, func(key string) client.Object {
+		name, ok := strings.CutPrefix(key, clusterInstanceKey(""))
+		if !ok {
+			return nil
+		}
+		return &keycloakv1beta1.ClusterKeycloakInstance{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&keycloakv1beta1.ClusterKeycloakInstance{}).
+		For(&keycloakv1beta1.ClusterKeycloakInstance{}, builder.WithPredicates(specOrAnnotationChanged)).
+		WatchesRawSource(source.Channel(breakerChanges, &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
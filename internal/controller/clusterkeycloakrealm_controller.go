@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -26,12 +27,17 @@ type ClusterKeycloakRealmReconciler struct {
 	client.Client
 	Scheme        *runtime.Scheme
 	ClientManager *keycloak.ClientManager
+
+	// WatchScope is the operator's watch scope. Under a label selector,
+	// pruning never deletes.
+	WatchScope WatchScope
 }
 
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakrealms,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakrealms/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakrealms/finalizers,verbs=update
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=clusterkeycloakinstances,verbs=get;list;watch
+// +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=keycloakclients;keycloakclientscopes;keycloakprotocolmappers;keycloakroles;keycloakgroups;keycloakusers;keycloakusercredentials;keycloakrolemappings;keycloakidentityproviders;keycloakidentityprovidermappers;keycloakcomponents;keycloakrequiredactions;keycloakauthenticationflows;keycloakorganizations,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // Reconcile handles ClusterKeycloakRealm reconciliation
@@ -57,24 +63,18 @@ func (r *ClusterKeycloakRealmReconciler) Reconcile(ctx context.Context, req ctrl
 		RecordReconcile(controllerName, realm.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, realm); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !realm.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(realm, FinalizerName) {
-			// Delete realm from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(realm) {
-				log.Info("preserving realm in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteRealm(ctx, realm); err != nil {
-				log.Error(err, "failed to delete realm from Keycloak")
-				// Continue with finalizer removal even on error
-			}
-
-			// Remove finalizer
-			controllerutil.RemoveFinalizer(realm, FinalizerName)
-			if err := r.Update(ctx, realm); err != nil {
-				return ctrl.Result{}, err
-			}
+		if result, waiting, err := awaitRealmDependents(ctx, r.Client, realm, realm.Spec.DeletionProtection, realm.Spec.CascadeDeletion); waiting {
+			return result, err
 		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, realm, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteRealm(ctx, realm)
+		})
 	}
 
 	// Add finalizer if not present
@@ -87,10 +87,10 @@ func (r *ClusterKeycloakRealmReconciler) Reconcile(ctx context.Context, req ctrl
 	}
 
 	// Get Keycloak client for this realm's instance
-	kc, instanceRef, err := r.getKeycloakClient(ctx, realm)
+	kc, instanceRef, caps, err := r.getKeycloakClient(ctx, realm)
 	if err != nil {
 		RecordError(controllerName, "instance_not_ready")
-		return r.updateStatus(ctx, realm, false, "InstanceNotReady", err.Error(), instanceRef)
+		return r.updateStatus(ctx, realm, false, notReadyReason(err, "InstanceNotReady"), err.Error(), instanceRef)
 	}
 
 	// Resolve the realm name from spec.realmName. realmDef.Realm is parsed only
@@ -168,10 +168,37 @@ func (r *ClusterKeycloakRealmReconciler) Reconcile(ctx context.Context, req ctrl
 
 	// Update status
 	realm.Status.ResourcePath = fmt.Sprintf("/admin/realms/%s", realmName)
+	if err := r.prune(ctx, kc, caps, realm, realmName); err != nil {
+		log.Error(err, "failed to prune realm", "realm", realmName)
+		RecordError(controllerName, "prune_failed")
+		result, statusErr := r.updateStatus(ctx, realm, true, "Ready", fmt.Sprintf("Realm synchronized; pruning failed: %v", err), instanceRef)
+		if statusErr != nil {
+			return result, statusErr
+		}
+		result.RequeueAfter = ErrorRequeueDelay
+		return result, nil
+	}
 	return r.updateStatus(ctx, realm, true, "Ready", "Realm synchronized", instanceRef)
 }
 
-func (r *ClusterKeycloakRealmReconciler) getKeycloakClient(ctx context.Context, realm *keycloakv1beta1.ClusterKeycloakRealm) (*keycloak.Client, *keycloakv1beta1.InstanceRef, error) {
+// prune applies spec.prune, records the objects found in DryRun mode and
+// sets the Pruned condition.
+func (r *ClusterKeycloakRealmReconciler) prune(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realm *keycloakv1beta1.ClusterKeycloakRealm, realmName string) error {
+	policy := restrictPrunePolicy(r.WatchScope, realm.Spec.Prune, &realm.Status.Conditions, realm.Generation)
+	target := realmPruneTarget(realm)
+	target.realmName = realmName
+	target.definition = realm.Spec.Definition.Raw
+	unmanaged, orphaned, err := pruneRealm(ctx, r.Client, kc, caps, target, policy)
+	setPrunedCondition(&realm.Status.Conditions, policy, err, realm.Generation)
+	if err != nil {
+		return err
+	}
+	realm.Status.Unmanaged = unmanaged
+	realm.Status.Orphaned = orphaned
+	return nil
+}
+
+func (r *ClusterKeycloakRealmReconciler) getKeycloakClient(ctx context.Context, realm *keycloakv1beta1.ClusterKeycloakRealm) (*keycloak.Client, *keycloakv1beta1.InstanceRef, *keycloak.Capabilities, error) {
 	// Determine if we're using cluster or namespaced instance
 	if realm.Spec.ClusterInstanceRef != nil {
 		// Using ClusterKeycloakInstance
@@ -181,24 +208,28 @@ func (r *ClusterKeycloakRealmReconciler) getKeycloakClient(ctx context.Context,
 
 		instance := &keycloakv1beta1.ClusterKeycloakInstance{}
 		if err := r.Get(ctx, types.NamespacedName{Name: realm.Spec.ClusterInstanceRef.Name}, instance); err != nil {
-			return nil, instanceRef, fmt.Errorf("failed to get ClusterKeycloakInstance %s: %w", realm.Spec.ClusterInstanceRef.Name, err)
+			return nil, instanceRef, nil, fmt.Errorf("failed to get ClusterKeycloakInstance %s: %w", realm.Spec.ClusterInstanceRef.Name, err)
 		}
 
 		if !instance.Status.Ready {
-			return nil, instanceRef, fmt.Errorf("ClusterKeycloakInstance %s is not ready", realm.Spec.ClusterInstanceRef.Name)
+			return nil, instanceRef, nil, fmt.Errorf("ClusterKeycloakInstance %s is not ready", realm.Spec.ClusterInstanceRef.Name)
 		}
 
 		cfg, err := GetKeycloakConfigFromClusterInstance(ctx, r.Client, instance)
 		if err != nil {
-			return nil, instanceRef, fmt.Errorf("failed to get Keycloak config from ClusterKeycloakInstance %s: %w", realm.Spec.ClusterInstanceRef.Name, err)
+			return nil, instanceRef, nil, fmt.Errorf("failed to get Keycloak config from ClusterKeycloakInstance %s: %w", realm.Spec.ClusterInstanceRef.Name, err)
 		}
 
 		kc := r.ClientManager.GetOrCreateClient(clusterInstanceKey(realm.Spec.ClusterInstanceRef.Name), cfg)
 		if kc == nil {
-			return nil, instanceRef, fmt.Errorf("keycloak client not available for cluster instance %s", realm.Spec.ClusterInstanceRef.Name)
+			return nil, instanceRef, nil, fmt.Errorf("keycloak client not available for cluster instance %s", realm.Spec.ClusterInstanceRef.Name)
+		}
+
+		if err := kc.Available(); err != nil {
+			return nil, instanceRef, nil, fmt.Errorf("ClusterKeycloakInstance %s: %w", realm.Spec.ClusterInstanceRef.Name, err)
 		}
 
-		return kc, instanceRef, nil
+		return kc, instanceRef, keycloak.CapabilitiesFromStatus(instance.Status.Version, instance.Status.Capabilities, instance.Status.CapabilitiesDetected), nil
 	}
 
 	if realm.Spec.InstanceRef != nil {
@@ -214,31 +245,35 @@ func (r *ClusterKeycloakRealmReconciler) getKeycloakClient(ctx context.Context,
 
 		instance := &keycloakv1beta1.KeycloakInstance{}
 		if err := r.Get(ctx, instanceName, instance); err != nil {
-			return nil, instanceRef, fmt.Errorf("failed to get KeycloakInstance %s: %w", instanceName, err)
+			return nil, instanceRef, nil, fmt.Errorf("failed to get KeycloakInstance %s: %w", instanceName, err)
 		}
 
 		if !instance.Status.Ready {
-			return nil, instanceRef, fmt.Errorf("KeycloakInstance %s is not ready", instanceName)
+			return nil, instanceRef, nil, fmt.Errorf("KeycloakInstance %s is not ready", instanceName)
 		}
 
 		cfg, err := GetKeycloakConfigFromInstance(ctx, r.Client, instance)
 		if err != nil {
-			return nil, instanceRef, fmt.Errorf("failed to get Keycloak config from KeycloakInstance %s: %w", instanceName, err)
+			return nil, instanceRef, nil, fmt.Errorf("failed to get Keycloak config from KeycloakInstance %s: %w", instanceName, err)
 		}
 
 		kc := r.ClientManager.GetOrCreateClient(instanceName.String(), cfg)
 		if kc == nil {
-			return nil, instanceRef, fmt.Errorf("keycloak client not available for instance %s", instanceName)
+			return nil, instanceRef, nil, fmt.Errorf("keycloak client not available for instance %s", instanceName)
 		}
 
-		return kc, instanceRef, nil
+		if err := kc.Available(); err != nil {
+			return nil, instanceRef, nil, fmt.Errorf("KeycloakInstance %s: %w", instanceName, err)
+		}
+
+		return kc, instanceRef, keycloak.CapabilitiesFromStatus(instance.Status.Version, instance.Status.Capabilities, instance.Status.CapabilitiesDetected), nil
 	}
 
-	return nil, nil, fmt.Errorf("either instanceRef or clusterInstanceRef must be specified")
+	return nil, nil, nil, fmt.Errorf("either instanceRef or clusterInstanceRef must be specified")
 }
 
 func (r *ClusterKeycloakRealmReconciler) deleteRealm(ctx context.Context, realm *keycloakv1beta1.ClusterKeycloakRealm) error {
-	kc, _, err := r.getKeycloakClient(ctx, realm)
+	kc, _, _, err := r.getKeycloakClient(ctx, realm)
 	if err != nil {
 		return err
 	}
@@ -299,9 +334,50 @@ func (r *ClusterKeycloakRealmReconciler) SetupWithManager(mgr ctrl.Manager) erro
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.findClusterRealmsForSecret),
 		).
+		Watches(
+			&keycloakv1beta1.KeycloakInstance{},
+			handler.EnqueueRequestsFromMapFunc(r.findClusterRealmsForInstance),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakInstance{},
+			handler.EnqueueRequestsFromMapFunc(r.findClusterRealmsForInstance),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
 
+// findClusterRealmsForInstance maps a KeycloakInstance or
+// ClusterKeycloakInstance that became ready to the ClusterKeycloakRealms that
+// use it
+func (r *ClusterKeycloakRealmReconciler) findClusterRealmsForInstance(ctx context.Context, obj client.Object) []reconcile.Request {
+	var realmList keycloakv1beta1.ClusterKeycloakRealmList
+	if err := r.List(ctx, &realmList); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, realm := range realmList.Items {
+		var matches bool
+		switch obj.(type) {
+		case *keycloakv1beta1.KeycloakInstance:
+			matches = realm.Spec.InstanceRef != nil &&
+				realm.Spec.InstanceRef.Name == obj.GetName() &&
+				realm.Spec.InstanceRef.Namespace == obj.GetNamespace()
+		case *keycloakv1beta1.ClusterKeycloakInstance:
+			matches = realm.Spec.ClusterInstanceRef != nil && realm.Spec.ClusterInstanceRef.Name == obj.GetName()
+		}
+		if matches {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: realm.Name,
+				},
+			})
+		}
+	}
+	return requests
+}
+
 // findClusterRealmsForSecret maps a Secret to ClusterKeycloakRealms that reference it via smtpSecretRef
 func (r *ClusterKeycloakRealmReconciler) findClusterRealmsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
 	secret := obj.(*corev1.Secret)
@@ -33,3 +33,29 @@ func rejectDefinitionKey(definition []byte, key, ownerKind string) error {
 
 	return fmt.Errorf("spec.definition.%s is not supported; declare each entry as a %s resource instead", key, ownerKind)
 }
+
+// specError is a problem with a resource's spec that its reconciler finds
+// before calling Keycloak. The check<Kind>Spec functions return it so that
+// the reconciler and ValidateSpec share one set of checks.
+type specError struct {
+	// reason is the status reason the reconciler reports.
+	reason string
+	// metric is the error label the reconciler records.
+	metric string
+	err    error
+}
+
+func (e *specError) Error() string { return e.err.Error() }
+
+func (e *specError) Unwrap() error { return e.err }
+
+// invalidDefinition is the specError for a spec.definition that cannot be
+// parsed or carries forbidden keys.
+func invalidDefinition(err error) *specError {
+	return &specError{reason: "InvalidDefinition", metric: "invalid_definition", err: err}
+}
+
+// unsupportedDefinitionField is the specError of rejectDefinitionKey.
+func unsupportedDefinitionField(err error) *specError {
+	return &specError{reason: UnsupportedDefinitionFieldReason, metric: "unsupported_definition_field", err: err}
+}
@@ -0,0 +1,357 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// DeletionBlockedConditionType is the condition set to True while a deleted
+// resource keeps its finalizer because its Keycloak object could not be
+// cleaned up.
+const DeletionBlockedConditionType = "DeletionBlocked"
+
+// Reasons of the DeletionBlocked condition.
+const (
+	// DeletionFailedReason means the Keycloak object could not be cleaned up.
+	DeletionFailedReason = "DeletionFailed"
+
+	// DependentsExistReason means a realm with deletionProtection still has
+	// dependent resources.
+	DependentsExistReason = "DependentsExist"
+
+	// DeletingDependentsReason means a realm with cascadeDeletion waits for
+	// its dependent resources to be deleted.
+	DeletingDependentsReason = "DeletingDependents"
+)
+
+const (
+	// DefaultDeletionTimeout is how long a failing cleanup is retried by default.
+	DefaultDeletionTimeout = 15 * time.Minute
+
+	// deletionRetryMinDelay and deletionRetryMaxDelay bound the backoff between
+	// cleanup attempts.
+	deletionRetryMinDelay = 5 * time.Second
+	deletionRetryMaxDelay = 5 * time.Minute
+)
+
+// deletionTimeout is how long after the deletion a failing cleanup is given
+// up, orphaning the Keycloak object. Set at startup from --deletion-timeout;
+// zero retries forever.
+var deletionTimeout = DefaultDeletionTimeout
+
+// SetDeletionTimeout sets how long a failing cleanup is retried before the
+// finalizer is removed anyway. Zero retries until the cleanup succeeds.
+func SetDeletionTimeout(d time.Duration) {
+	deletionTimeout = d
+}
+
+// deletionCleanup removes or disables the Keycloak object of a deleted
+// resource according to policy, which is never Orphan.
+type deletionCleanup func(ctx context.Context, policy keycloakv1beta1.DeletionPolicy) error
+
+// finalizeDeletion handles a deleted obj: it runs cleanup under obj's deletion
+// policy and removes the finalizer once the cleanup succeeded. A failing
+// cleanup keeps the finalizer and is retried with backoff, reported by the
+// DeletionBlocked condition, until deletionTimeout has passed.
+//
+// An object left with the Orphan policy is recorded in its realm's
+// status.orphaned so that spec.prune keeps it.
+//
+// A cleanup failing because a parent resource no longer exists cannot succeed
+// later, so the finalizer is removed right away. When the realm is gone, or is
+// being deleted with the Delete policy, cleanup is skipped altogether: the
+// realm takes its objects with it.
+func finalizeDeletion(ctx context.Context, c client.Client, obj client.Object, cleanup deletionCleanup) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(obj, FinalizerName) {
+		return ctrl.Result{}, nil
+	}
+	log := ctrl.LoggerFrom(ctx)
+
+	policy, err := deletionPolicyFor(ctx, c, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	realmGone, err := realmGoneFor(ctx, c, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if policy == keycloakv1beta1.DeletionPolicyOrphan {
+		log.Info("leaving object in Keycloak", "deletionPolicy", policy)
+		if !realmGone {
+			if err := recordOrphan(ctx, c, obj); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to record orphaned object: %w", err)
+			}
+		}
+	} else if realmGone {
+		log.Info("realm is gone or being deleted, leaving cleanup to it")
+	} else if err := cleanup(ctx, policy); err != nil {
+		switch {
+		case apierrors.IsNotFound(err):
+			log.Info("parent resource is gone, leaving object in Keycloak", "reason", err.Error())
+		case deletionTimedOut(obj):
+			log.Error(err, "giving up cleaning up Keycloak object", "deletionTimeout", deletionTimeout)
+		default:
+			return blockDeletion(ctx, c, obj, err)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(obj, FinalizerName)
+	if err := c.Update(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// blockDeletion records the failed cleanup of obj in the DeletionBlocked
+// condition and schedules the next attempt.
+func blockDeletion(ctx context.Context, c client.Client, obj client.Object, cause error) (ctrl.Result, error) {
+	ctrl.LoggerFrom(ctx).Error(cause, "failed to clean up Keycloak object, keeping finalizer")
+
+	message := cause.Error()
+	if deletionTimeout > 0 {
+		deadline := obj.GetDeletionTimestamp().Add(deletionTimeout)
+		message = fmt.Sprintf("%s; retrying until %s", message, deadline.UTC().Format(time.RFC3339))
+	}
+	if err := setDeletionBlocked(ctx, c, obj, DeletionFailedReason, message); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: deletionRetryDelay(obj, time.Now())}, nil
+}
+
+// setDeletionBlocked sets obj's DeletionBlocked condition and writes the
+// status if it changed.
+func setDeletionBlocked(ctx context.Context, c client.Client, obj client.Object, reason, message string) error {
+	conditions := conditionsOf(obj)
+	if conditions == nil {
+		return nil
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               DeletionBlockedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: obj.GetGeneration(),
+	})
+	if statusMatchesStored(ctx, c, obj) {
+		return nil
+	}
+	return c.Status().Update(ctx, obj)
+}
+
+// deletionRetryDelay returns the wait before the next cleanup attempt. It
+// grows with the time since the deletion, doubling the interval between
+// attempts, and never passes the deadline.
+func deletionRetryDelay(obj client.Object, now time.Time) time.Duration {
+	deleted := obj.GetDeletionTimestamp()
+	if deleted == nil {
+		return deletionRetryMinDelay
+	}
+	delay := now.Sub(deleted.Time)
+	if delay < deletionRetryMinDelay {
+		delay = deletionRetryMinDelay
+	}
+	if delay > deletionRetryMaxDelay {
+		delay = deletionRetryMaxDelay
+	}
+	if deletionTimeout > 0 {
+		if remaining := deleted.Add(deletionTimeout).Sub(now); remaining > 0 && remaining < delay {
+			delay = remaining
+		}
+	}
+	return delay
+}
+
+// deletionTimedOut reports whether deletionTimeout has passed since obj was
+// deleted.
+func deletionTimedOut(obj client.Object) bool {
+	deleted := obj.GetDeletionTimestamp()
+	return deletionTimeout > 0 && deleted != nil && time.Since(deleted.Time) >= deletionTimeout
+}
+
+// deletionPolicyFor returns obj's spec.deletionPolicy, Orphan if it carries
+// the deprecated PreserveResourceAnnotation, otherwise the policy of its
+// nearest parent that sets one, and Delete if none does.
+func deletionPolicyFor(ctx context.Context, c client.Client, obj client.Object) (keycloakv1beta1.DeletionPolicy, error) {
+	if policy := specDeletionPolicy(obj); policy != "" {
+		return policy, nil
+	}
+	if ShouldPreserveResource(obj) {
+		return keycloakv1beta1.DeletionPolicyOrphan, nil
+	}
+	parent, err := nearestInChain(ctx, c, obj, func(o client.Object) bool {
+		return specDeletionPolicy(o) != ""
+	})
+	if err != nil {
+		return "", err
+	}
+	if parent == nil {
+		return keycloakv1beta1.DeletionPolicyDelete, nil
+	}
+	return specDeletionPolicy(parent), nil
+}
+
+// specDeletionPolicy returns obj's spec.deletionPolicy, or "" if unset.
+func specDeletionPolicy(obj client.Object) keycloakv1beta1.DeletionPolicy {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ""
+	}
+	spec := v.Elem().FieldByName("Spec")
+	if !spec.IsValid() {
+		return ""
+	}
+	field := spec.FieldByName("DeletionPolicy")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return keycloakv1beta1.DeletionPolicy(field.String())
+}
+
+// realmGoneFor reports whether the realm obj lives in no longer exists, or is
+// being deleted with the Delete policy, so that obj's Keycloak object goes
+// with it. Realms themselves are never reported.
+func realmGoneFor(ctx context.Context, c client.Client, obj client.Object) (bool, error) {
+	switch obj.(type) {
+	case *keycloakv1beta1.KeycloakRealm, *keycloakv1beta1.ClusterKeycloakRealm:
+		return false, nil
+	}
+	realm, err := nearestInChain(ctx, c, obj, isRealm)
+	if err != nil {
+		return false, err
+	}
+	if realm == nil {
+		return true, nil
+	}
+	if realm.GetDeletionTimestamp().IsZero() {
+		return false, nil
+	}
+	policy, err := deletionPolicyFor(ctx, c, realm)
+	if err != nil {
+		return false, err
+	}
+	return policy == keycloakv1beta1.DeletionPolicyDelete, nil
+}
+
+func isRealm(obj client.Object) bool {
+	switch obj.(type) {
+	case *keycloakv1beta1.KeycloakRealm, *keycloakv1beta1.ClusterKeycloakRealm:
+		return true
+	}
+	return false
+}
+
+// realmDependentLists returns an empty list of each kind that lives in a realm.
+var realmDependentLists = []func() client.ObjectList{
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakClientList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakClientScopeList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakProtocolMapperList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakGroupList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakUserList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakUserCredentialList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleMappingList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakIdentityProviderList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakIdentityProviderMapperList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakComponentList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakRequiredActionList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakAuthenticationFlowList{} },
+	func() client.ObjectList { return &keycloakv1beta1.KeycloakOrganizationList{} },
+}
+
+// realmDependents returns the resources that live in realm, directly or
+// nested in another resource. The dependents of a cluster realm may live in
+// any namespace, including those outside the watch scope, so they are listed
+// from the API server when the cache is namespace-restricted.
+func realmDependents(ctx context.Context, c client.Client, realm client.Object) ([]client.Object, error) {
+	match := func(o client.Object) bool {
+		return isRealm(o) && o.GetName() == realm.GetName() && o.GetNamespace() == realm.GetNamespace()
+	}
+	reader := client.Reader(c)
+	if realm.GetNamespace() == "" {
+		reader = allNamespacesReader(c)
+	}
+	var dependents []client.Object
+	for _, newList := range realmDependentLists {
+		list := newList()
+		if err := reader.List(ctx, list, client.InNamespace(realm.GetNamespace())); err != nil {
+			return nil, fmt.Errorf("failed to list dependents: %w", err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			found, err := nearestInChain(ctx, c, obj, match)
+			if err != nil {
+				return nil, err
+			}
+			if found != nil {
+				dependents = append(dependents, obj)
+			}
+		}
+	}
+	return dependents, nil
+}
+
+// maxListedDependents caps the dependents named in the DeletionBlocked message.
+const maxListedDependents = 5
+
+// awaitRealmDependents holds the deletion of a realm with deletionProtection
+// or cascadeDeletion while resources depend on it; with cascadeDeletion it
+// deletes them first. It reports whether the deletion has to wait.
+func awaitRealmDependents(ctx context.Context, c client.Client, realm client.Object, protect, cascade bool) (ctrl.Result, bool, error) {
+	if (!protect && !cascade) || !controllerutil.ContainsFinalizer(realm, FinalizerName) {
+		return ctrl.Result{}, false, nil
+	}
+	dependents, err := realmDependents(ctx, c, realm)
+	if err != nil {
+		return ctrl.Result{}, true, err
+	}
+	if len(dependents) == 0 {
+		return ctrl.Result{}, false, nil
+	}
+
+	reason, action := DependentsExistReason, "deletion is blocked by deletionProtection"
+	if cascade {
+		reason, action = DeletingDependentsReason, "waiting for them to be deleted"
+		for _, dependent := range dependents {
+			if !dependent.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			ctrl.LoggerFrom(ctx).Info("deleting dependent resource", "kind", kindOf(dependent), "name", objectName(dependent))
+			if err := c.Delete(ctx, dependent); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, true, fmt.Errorf("failed to delete %s %s: %w", kindOf(dependent), objectName(dependent), err)
+			}
+		}
+	}
+
+	names := make([]string, 0, maxListedDependents)
+	for _, dependent := range dependents {
+		if len(names) == maxListedDependents {
+			names = append(names, fmt.Sprintf("and %d more", len(dependents)-maxListedDependents))
+			break
+		}
+		names = append(names, kindOf(dependent)+" "+objectName(dependent))
+	}
+	message := fmt.Sprintf("%d resources depend on this realm (%s); %s", len(dependents), strings.Join(names, ", "), action)
+	if err := setDeletionBlocked(ctx, c, realm, reason, message); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	return ctrl.Result{RequeueAfter: deletionRetryDelay(realm, time.Now())}, true, nil
+}
@@ -0,0 +1,385 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func TestDeletionPolicyFor(t *testing.T) {
+	ctx := context.Background()
+	chain := func(instance, realm keycloakv1beta1.DeletionPolicy) []client.Object {
+		return []client.Object{
+			&keycloakv1beta1.ClusterKeycloakInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: "kc"},
+				Spec:       keycloakv1beta1.ClusterKeycloakInstanceSpec{DeletionPolicy: instance},
+			},
+			&keycloakv1beta1.KeycloakRealm{
+				ObjectMeta: metav1.ObjectMeta{Name: "realm", Namespace: "team"},
+				Spec: keycloakv1beta1.KeycloakRealmSpec{
+					ClusterInstanceRef: &keycloakv1beta1.ClusterResourceRef{Name: "kc"},
+					DeletionPolicy:     realm,
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		instance, realm keycloakv1beta1.DeletionPolicy
+		own             keycloakv1beta1.DeletionPolicy
+		preserve        bool
+		want            keycloakv1beta1.DeletionPolicy
+	}{
+		{name: "default", want: keycloakv1beta1.DeletionPolicyDelete},
+		{name: "own", own: keycloakv1beta1.DeletionPolicyDisable, want: keycloakv1beta1.DeletionPolicyDisable},
+		{name: "realm default", realm: keycloakv1beta1.DeletionPolicyOrphan, want: keycloakv1beta1.DeletionPolicyOrphan},
+		{name: "instance default", instance: keycloakv1beta1.DeletionPolicyOrphan, want: keycloakv1beta1.DeletionPolicyOrphan},
+		{name: "nearest first", instance: keycloakv1beta1.DeletionPolicyOrphan, realm: keycloakv1beta1.DeletionPolicyDelete, want: keycloakv1beta1.DeletionPolicyDelete},
+		{name: "preserve annotation", realm: keycloakv1beta1.DeletionPolicyDelete, preserve: true, want: keycloakv1beta1.DeletionPolicyOrphan},
+		{name: "own overrides annotation", own: keycloakv1beta1.DeletionPolicyDelete, preserve: true, want: keycloakv1beta1.DeletionPolicyDelete},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := &keycloakv1beta1.KeycloakUser{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "team"},
+				Spec: keycloakv1beta1.KeycloakUserSpec{
+					RealmRef:       &keycloakv1beta1.ResourceRef{Name: "realm"},
+					DeletionPolicy: tc.own,
+				},
+			}
+			if tc.preserve {
+				user.Annotations = map[string]string{PreserveResourceAnnotation: "true"}
+			}
+			got, err := deletionPolicyFor(ctx, newAuthTestClient(t, chain(tc.instance, tc.realm)...), user)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func deletedGroup(deletedAgo time.Duration, policy keycloakv1beta1.DeletionPolicy) *keycloakv1beta1.KeycloakGroup {
+	return &keycloakv1beta1.KeycloakGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "devs",
+			Namespace:         "team",
+			Finalizers:        []string{FinalizerName},
+			DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-deletedAgo).Truncate(time.Second)},
+		},
+		Spec: keycloakv1beta1.KeycloakGroupSpec{
+			RealmRef:       &keycloakv1beta1.ResourceRef{Name: "realm"},
+			DeletionPolicy: policy,
+		},
+	}
+}
+
+func TestFinalizeDeletion(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "devs", Namespace: "team"}
+	outage := errors.New("Keycloak instance unavailable")
+	parentGone := fmt.Errorf("failed to get KeycloakRealm team/realm: %w",
+		apierrors.NewNotFound(schema.GroupResource{Group: "keycloak.hostzero.com", Resource: "keycloakrealms"}, "realm"))
+
+	cases := []struct {
+		name       string
+		deletedAgo time.Duration
+		policy     keycloakv1beta1.DeletionPolicy
+		cleanupErr error
+		wantCalled bool
+		wantKept   bool
+		noRealm    bool
+	}{
+		{name: "deleted", wantCalled: true},
+		{name: "orphaned", policy: keycloakv1beta1.DeletionPolicyOrphan},
+		{name: "blocked", cleanupErr: outage, wantCalled: true, wantKept: true},
+		{name: "timed out", deletedAgo: DefaultDeletionTimeout, cleanupErr: outage, wantCalled: true},
+		{name: "parent gone", cleanupErr: parentGone, wantCalled: true},
+		{name: "realm gone", noRealm: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			group := deletedGroup(tc.deletedAgo, tc.policy)
+			objs := []client.Object{group}
+			if !tc.noRealm {
+				objs = append(objs, &keycloakv1beta1.KeycloakRealm{
+					ObjectMeta: metav1.ObjectMeta{Name: "realm", Namespace: "team"},
+				})
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(newScheme(t)).
+				WithStatusSubresource(&keycloakv1beta1.KeycloakGroup{}).
+				WithObjects(objs...).
+				Build()
+			if err := c.Get(ctx, key, group); err != nil {
+				t.Fatalf("get group: %v", err)
+			}
+
+			called := false
+			result, err := finalizeDeletion(ctx, c, group, func(context.Context, keycloakv1beta1.DeletionPolicy) error {
+				called = true
+				return tc.cleanupErr
+			})
+			if err != nil {
+				t.Fatalf("finalizeDeletion: %v", err)
+			}
+			if called != tc.wantCalled {
+				t.Errorf("cleanup called: got %v, want %v", called, tc.wantCalled)
+			}
+
+			got := &keycloakv1beta1.KeycloakGroup{}
+			err = c.Get(ctx, key, got)
+			if !tc.wantKept {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("group still present (err=%v, finalizers=%v)", err, got.Finalizers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get group: %v", err)
+			}
+			if !controllerutil.ContainsFinalizer(got, FinalizerName) {
+				t.Error("finalizer removed while cleanup fails")
+			}
+			if result.RequeueAfter < deletionRetryMinDelay {
+				t.Errorf("requeueAfter: got %v, want at least %v", result.RequeueAfter, deletionRetryMinDelay)
+			}
+			cond := meta.FindStatusCondition(got.Status.Conditions, DeletionBlockedConditionType)
+			if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != DeletionFailedReason {
+				t.Fatalf("DeletionBlocked condition: got %+v", cond)
+			}
+			deadline := group.DeletionTimestamp.Add(DefaultDeletionTimeout).UTC().Format(time.RFC3339)
+			if want := "Keycloak instance unavailable; retrying until " + deadline; cond.Message != want {
+				t.Errorf("message: got %q, want %q", cond.Message, want)
+			}
+		})
+	}
+}
+
+func TestDeletionRetryDelay(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		deletedAgo time.Duration
+		want       time.Duration
+	}{
+		{deletedAgo: 0, want: deletionRetryMinDelay},
+		{deletedAgo: 20 * time.Second, want: 20 * time.Second},
+		{deletedAgo: 8 * time.Minute, want: deletionRetryMaxDelay},
+		{deletedAgo: 13 * time.Minute, want: 2 * time.Minute},
+	}
+	for _, tc := range cases {
+		group := deletedGroup(0, "")
+		group.DeletionTimestamp = &metav1.Time{Time: now.Add(-tc.deletedAgo)}
+		if got := deletionRetryDelay(group, now); got != tc.want {
+			t.Errorf("deleted %v ago: got %v, want %v", tc.deletedAgo, got, tc.want)
+		}
+	}
+}
+
+func TestRealmGoneFor(t *testing.T) {
+	ctx := context.Background()
+	realm := func(deleted bool, policy keycloakv1beta1.DeletionPolicy) *keycloakv1beta1.KeycloakRealm {
+		r := &keycloakv1beta1.KeycloakRealm{
+			ObjectMeta: metav1.ObjectMeta{Name: "realm", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakRealmSpec{DeletionPolicy: policy},
+		}
+		if deleted {
+			r.Finalizers = []string{FinalizerName}
+			r.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		}
+		return r
+	}
+	group := deletedGroup(0, "")
+
+	cases := []struct {
+		name  string
+		objs  []client.Object
+		want  bool
+		check client.Object
+	}{
+		{name: "realm missing", want: true},
+		{name: "realm present", objs: []client.Object{realm(false, "")}},
+		{name: "realm deleted", objs: []client.Object{realm(true, "")}, want: true},
+		{name: "realm orphaned", objs: []client.Object{realm(true, keycloakv1beta1.DeletionPolicyOrphan)}},
+		{name: "realm itself", objs: []client.Object{realm(true, "")}, check: realm(true, "")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := tc.check
+			if obj == nil {
+				obj = group
+			}
+			got, err := realmGoneFor(ctx, newAuthTestClient(t, tc.objs...), obj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAwaitRealmDependents(t *testing.T) {
+	ctx := context.Background()
+	realmKey := types.NamespacedName{Name: "realm", Namespace: "team"}
+	newRealm := func() *keycloakv1beta1.KeycloakRealm {
+		return &keycloakv1beta1.KeycloakRealm{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "realm",
+				Namespace:         "team",
+				Finalizers:        []string{FinalizerName},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+		}
+	}
+	newClient := func(t *testing.T, withDependents bool) client.Client {
+		objs := []client.Object{newRealm()}
+		if withDependents {
+			objs = append(objs,
+				&keycloakv1beta1.KeycloakClient{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team", Finalizers: []string{FinalizerName}},
+					Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "realm"}},
+				},
+				// Nested in the client rather than referencing the realm.
+				&keycloakv1beta1.KeycloakRole{
+					ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "team"},
+					Spec:       keycloakv1beta1.KeycloakRoleSpec{ClientRef: &keycloakv1beta1.ResourceRef{Name: "app"}},
+				},
+				&keycloakv1beta1.KeycloakUser{
+					ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "team"},
+					Spec:       keycloakv1beta1.KeycloakUserSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "other"}},
+				},
+			)
+		}
+		return fake.NewClientBuilder().
+			WithScheme(newScheme(t)).
+			WithStatusSubresource(&keycloakv1beta1.KeycloakRealm{}).
+			WithObjects(objs...).
+			Build()
+	}
+	get := func(t *testing.T, c client.Client) *keycloakv1beta1.KeycloakRealm {
+		realm := &keycloakv1beta1.KeycloakRealm{}
+		if err := c.Get(ctx, realmKey, realm); err != nil {
+			t.Fatalf("get realm: %v", err)
+		}
+		return realm
+	}
+
+	t.Run("no policy", func(t *testing.T) {
+		c := newClient(t, true)
+		if _, waiting, err := awaitRealmDependents(ctx, c, get(t, c), false, false); waiting || err != nil {
+			t.Errorf("got waiting=%v err=%v, want no wait", waiting, err)
+		}
+	})
+
+	t.Run("no dependents", func(t *testing.T) {
+		c := newClient(t, false)
+		if _, waiting, err := awaitRealmDependents(ctx, c, get(t, c), true, false); waiting || err != nil {
+			t.Errorf("got waiting=%v err=%v, want no wait", waiting, err)
+		}
+	})
+
+	t.Run("protected", func(t *testing.T) {
+		c := newClient(t, true)
+		result, waiting, err := awaitRealmDependents(ctx, c, get(t, c), true, false)
+		if err != nil || !waiting {
+			t.Fatalf("got waiting=%v err=%v, want wait", waiting, err)
+		}
+		if result.RequeueAfter == 0 {
+			t.Error("expected a requeue")
+		}
+		cond := meta.FindStatusCondition(get(t, c).Status.Conditions, DeletionBlockedConditionType)
+		if cond == nil || cond.Reason != DependentsExistReason {
+			t.Fatalf("DeletionBlocked condition: got %+v", cond)
+		}
+		want := "2 resources depend on this realm (KeycloakClient team/app, KeycloakRole team/reader); deletion is blocked by deletionProtection"
+		if cond.Message != want {
+			t.Errorf("message: got %q, want %q", cond.Message, want)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "reader", Namespace: "team"}, &keycloakv1beta1.KeycloakRole{}); err != nil {
+			t.Errorf("protected dependent was deleted: %v", err)
+		}
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		c := newClient(t, true)
+		_, waiting, err := awaitRealmDependents(ctx, c, get(t, c), false, true)
+		if err != nil || !waiting {
+			t.Fatalf("got waiting=%v err=%v, want wait", waiting, err)
+		}
+		cond := meta.FindStatusCondition(get(t, c).Status.Conditions, DeletionBlockedConditionType)
+		if cond == nil || cond.Reason != DeletingDependentsReason {
+			t.Fatalf("DeletionBlocked condition: got %+v", cond)
+		}
+		app := &keycloakv1beta1.KeycloakClient{}
+		if err := c.Get(ctx, types.NamespacedName{Name: "app", Namespace: "team"}, app); err != nil || app.DeletionTimestamp.IsZero() {
+			t.Errorf("client not marked for deletion (err=%v)", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "reader", Namespace: "team"}, &keycloakv1beta1.KeycloakRole{}); !apierrors.IsNotFound(err) {
+			t.Errorf("nested role not deleted: %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "alice", Namespace: "team"}, &keycloakv1beta1.KeycloakUser{}); err != nil {
+			t.Errorf("user of another realm was deleted: %v", err)
+		}
+	})
+}
+
+func TestAwaitRealmDependents_ScopedClient(t *testing.T) {
+	ctx := context.Background()
+	realm := &keycloakv1beta1.ClusterKeycloakRealm{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "shared",
+			Finalizers:        []string{FinalizerName},
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+		},
+	}
+	outOfScope := &keycloakv1beta1.KeycloakClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-b"},
+		Spec:       keycloakv1beta1.KeycloakClientSpec{ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "shared"}},
+	}
+
+	// The cache only holds the watched namespace team-a; the dependent in
+	// team-b is only visible to the direct reader.
+	cached := fake.NewClientBuilder().
+		WithScheme(newScheme(t)).
+		WithStatusSubresource(&keycloakv1beta1.ClusterKeycloakRealm{}).
+		WithObjects(realm).
+		Build()
+	direct := newAuthTestClient(t, realm.DeepCopy(), outOfScope)
+	scope, _ := ParseWatchScope("team-a", "")
+	c := &scopedClient{Client: cached, direct: direct, scope: scope}
+
+	stored := &keycloakv1beta1.ClusterKeycloakRealm{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "shared"}, stored); err != nil {
+		t.Fatalf("get realm: %v", err)
+	}
+	_, waiting, err := awaitRealmDependents(ctx, c, stored, true, false)
+	if err != nil || !waiting {
+		t.Fatalf("got waiting=%v err=%v, want the dependent outside the watch scope to block deletion", waiting, err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "shared"}, stored); err != nil {
+		t.Fatalf("get realm: %v", err)
+	}
+	cond := meta.FindStatusCondition(stored.Status.Conditions, DeletionBlockedConditionType)
+	if cond == nil || !strings.Contains(cond.Message, "KeycloakClient team-b/app") {
+		t.Errorf("DeletionBlocked condition: got %+v", cond)
+	}
+}
@@ -3,8 +3,16 @@ package controller
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 )
 
 // InvalidIdentifierReason is the status/condition reason used when a CR's
@@ -32,6 +40,107 @@ func resolveIdentifier(specField string, specVal *string, defVal string) (string
 	return spec, nil
 }
 
+// namespaceTemplate is expanded to the resource's namespace in naming policies.
+const namespaceTemplate = "{{namespace}}"
+
+// resolveIdentifierWithPolicy is resolveIdentifier for identifiers that share
+// one namespace per realm in Keycloak (clientId, realm role, top-level group
+// and client scope names, identity provider aliases). When obj lives in a
+// ClusterKeycloakRealm with a naming policy, the identifier is prefixed and
+// checked against it. Client roles and child groups are scoped by their
+// parent and skip the policy.
+func resolveIdentifierWithPolicy(ctx context.Context, c client.Client, obj client.Object, specField string, specVal *string, defVal string) (string, error) {
+	id, err := resolveIdentifier(specField, specVal, defVal)
+	if err != nil {
+		return "", err
+	}
+	if specRefName(obj, "ClientRef") != "" || specRefName(obj, "ParentGroupRef") != "" {
+		return id, nil
+	}
+	// A missing realm reference or realm is reported by realm resolution.
+	ref, err := placementRealm(ctx, c, obj)
+	if err != nil || ref.ClusterRealmRef == "" {
+		return id, nil
+	}
+	realm := &keycloakv1beta1.ClusterKeycloakRealm{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.ClusterRealmRef}, realm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return id, nil
+		}
+		return "", fmt.Errorf("failed to get ClusterKeycloakRealm %s: %w", ref.ClusterRealmRef, err)
+	}
+	id, err = applyNamingPolicy(realm.Spec.NamingPolicy, realm.Name, obj.GetNamespace(), specField, id)
+	if err != nil || realm.Spec.NamingPolicy == nil {
+		return id, err
+	}
+	other, err := longerNamespacePrefix(ctx, c, realm.Spec.NamingPolicy.Prefix, obj.GetNamespace(), id)
+	if err != nil {
+		return "", err
+	}
+	if other != "" {
+		return "", fmt.Errorf("spec.%s %q falls under the prefix of namespace %q (naming policy of ClusterKeycloakRealm %s)", specField, id, other, realm.Name)
+	}
+	return id, nil
+}
+
+// applyNamingPolicy prefixes id if the policy asks for it and returns an
+// error if id violates the policy of the named realm.
+func applyNamingPolicy(policy *keycloakv1beta1.NamingPolicy, realm, namespace, specField, id string) (string, error) {
+	if policy == nil {
+		return id, nil
+	}
+	prefix := strings.ReplaceAll(policy.Prefix, namespaceTemplate, namespace)
+	if !strings.HasPrefix(id, prefix) {
+		if !policy.AutoPrefix {
+			return "", fmt.Errorf("spec.%s %q must start with %q (naming policy of ClusterKeycloakRealm %s)", specField, id, prefix, realm)
+		}
+		id = prefix + id
+	}
+	if len(policy.AllowedPatterns) == 0 {
+		return id, nil
+	}
+	for _, pattern := range policy.AllowedPatterns {
+		expanded := strings.ReplaceAll(pattern, namespaceTemplate, regexp.QuoteMeta(namespace))
+		re, err := regexp.Compile("^(?:" + expanded + ")$")
+		if err != nil {
+			return "", fmt.Errorf("naming policy of ClusterKeycloakRealm %s has an invalid pattern %q: %w", realm, pattern, err)
+		}
+		if re.MatchString(id) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("%s %q matches no allowed pattern (naming policy of ClusterKeycloakRealm %s)", specField, id, realm)
+}
+
+// longerNamespacePrefix returns the existing namespace, other than
+// namespace, whose longer expansion of the prefix template id starts with, or
+// "" if there is none. With the prefix "{{namespace}}-", "team-a-app" created
+// in namespace team would take an identifier of namespace team-a if that
+// exists; the longest matching prefix owns an identifier.
+func longerNamespacePrefix(ctx context.Context, c client.Client, template, namespace, id string) (string, error) {
+	start := strings.Index(template, namespaceTemplate)
+	if start < 0 {
+		return "", nil
+	}
+	for end := len(id); end > start+len(namespace); end-- {
+		candidate := id[start:end]
+		if len(validation.IsDNS1123Label(candidate)) > 0 {
+			continue
+		}
+		if !strings.HasPrefix(id, strings.ReplaceAll(template, namespaceTemplate, candidate)) {
+			continue
+		}
+		err := c.Get(ctx, types.NamespacedName{Name: candidate}, &corev1.Namespace{})
+		if err == nil {
+			return candidate, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get Namespace %s: %w", candidate, err)
+		}
+	}
+	return "", nil
+}
+
 // persistResolvedIdentifier records the resolved identifier in status and
 // persists it immediately when it changed. It is needed by reconcilers whose
 // updateStatus skips the API write when ready/status/message are unchanged:
@@ -55,3 +164,14 @@ func identifierValue(specVal *string) string {
 	}
 	return *specVal
 }
+
+// syncedIdentifier returns the identifier recorded in status by the last
+// reconcile, falling back to the spec value for objects not reconciled since.
+// Paths that address the Keycloak object must prefer it: a naming policy may
+// have prefixed the spec value.
+func syncedIdentifier(statusVal string, specVal *string) string {
+	if statusVal != "" {
+		return statusVal
+	}
+	return identifierValue(specVal)
+}
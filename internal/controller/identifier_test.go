@@ -1,6 +1,15 @@
 package controller
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
 
 func strptr(s string) *string { return &s }
 
@@ -79,3 +88,213 @@ func TestIdentifierValue(t *testing.T) {
 		t.Errorf("identifierValue(\"x\") = %q, want \"x\"", got)
 	}
 }
+
+func TestSyncedIdentifier(t *testing.T) {
+	if got := syncedIdentifier("team-a-app", strptr("app")); got != "team-a-app" {
+		t.Errorf("got %q, want the status value", got)
+	}
+	if got := syncedIdentifier("", strptr("app")); got != "app" {
+		t.Errorf("got %q, want the spec value", got)
+	}
+}
+
+func TestApplyNamingPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       *keycloakv1beta1.NamingPolicy
+		id           string
+		wantResolved string
+		wantErr      bool
+	}{
+		{name: "no policy", id: "anything", wantResolved: "anything"},
+		{
+			name:         "prefix present",
+			policy:       &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}-"},
+			id:           "team-a-app",
+			wantResolved: "team-a-app",
+		},
+		{
+			name:    "prefix missing is rejected",
+			policy:  &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}-"},
+			id:      "team-b-app",
+			wantErr: true,
+		},
+		{
+			name:         "prefix missing is added with autoPrefix",
+			policy:       &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}-", AutoPrefix: true},
+			id:           "app",
+			wantResolved: "team-a-app",
+		},
+		{
+			name:         "separator namespace names cannot contain",
+			policy:       &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}_", AutoPrefix: true},
+			id:           "b_app",
+			wantResolved: "team-a_b_app",
+		},
+		{
+			name:         "pattern checked after prefixing",
+			policy:       &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}-", AutoPrefix: true, AllowedPatterns: []string{"{{namespace}}-[a-z]+"}},
+			id:           "app",
+			wantResolved: "team-a-app",
+		},
+		{
+			name:    "pattern must match in full",
+			policy:  &keycloakv1beta1.NamingPolicy{AllowedPatterns: []string{"[a-z]+"}},
+			id:      "app-1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern is rejected",
+			policy:  &keycloakv1beta1.NamingPolicy{AllowedPatterns: []string{"("}},
+			id:      "app",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := applyNamingPolicy(tt.policy, "shared", "team-a", "clientId", tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got resolved=%q, nil error", resolved)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resolved != tt.wantResolved {
+				t.Errorf("resolved = %q, want %q", resolved, tt.wantResolved)
+			}
+		})
+	}
+}
+
+func TestResolveIdentifierWithPolicy(t *testing.T) {
+	realm := &keycloakv1beta1.ClusterKeycloakRealm{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: keycloakv1beta1.ClusterKeycloakRealmSpec{
+			NamingPolicy: &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}-", AutoPrefix: true},
+		},
+	}
+	c := newAuthTestClient(t, realm)
+	ctx := context.Background()
+
+	inShared := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "team-a"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "shared"}},
+	}
+	if got, err := resolveIdentifierWithPolicy(ctx, c, inShared, "name", strptr("admin"), ""); err != nil || got != "team-a-admin" {
+		t.Errorf("realm role in shared realm: got %q, %v; want team-a-admin", got, err)
+	}
+
+	clientRole := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "team-a"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{ClientRef: &keycloakv1beta1.ResourceRef{Name: "app"}},
+	}
+	if got, err := resolveIdentifierWithPolicy(ctx, c, clientRole, "name", strptr("admin"), ""); err != nil || got != "admin" {
+		t.Errorf("client role: got %q, %v; want admin", got, err)
+	}
+
+	namespaced := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "team-a"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "own"}},
+	}
+	if got, err := resolveIdentifierWithPolicy(ctx, c, namespaced, "name", strptr("admin"), ""); err != nil || got != "admin" {
+		t.Errorf("namespaced realm: got %q, %v; want admin", got, err)
+	}
+}
+
+func TestLongerNamespacePrefix(t *testing.T) {
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	tests := []struct {
+		name       string
+		template   string
+		namespace  string
+		id         string
+		namespaces []client.Object
+		want       string
+	}{
+		{
+			name:      "multi-dash id without a longer namespace",
+			template:  "{{namespace}}-",
+			namespace: "team-a",
+			id:        "team-a-web-app",
+		},
+		{
+			name:       "multi-dash id with unrelated namespaces",
+			template:   "{{namespace}}-",
+			namespace:  "team-a",
+			id:         "team-a-web-app",
+			namespaces: []client.Object{namespace("team-a"), namespace("team-b-web")},
+		},
+		{
+			name:       "multi-dash id under an existing longer namespace",
+			template:   "{{namespace}}-",
+			namespace:  "team-a",
+			id:         "team-a-web-app",
+			namespaces: []client.Object{namespace("team-a"), namespace("team-a-web")},
+			want:       "team-a-web",
+		},
+		{
+			name:       "longest existing namespace wins",
+			template:   "{{namespace}}-",
+			namespace:  "team",
+			id:         "team-a-web-app",
+			namespaces: []client.Object{namespace("team-a"), namespace("team-a-web")},
+			want:       "team-a-web",
+		},
+		{
+			name:       "separator namespace names cannot contain",
+			template:   "{{namespace}}_",
+			namespace:  "team",
+			id:         "team_a-web_app",
+			namespaces: []client.Object{namespace("team-a")},
+		},
+		{
+			name:       "template without namespace",
+			template:   "tenant-",
+			namespace:  "team",
+			id:         "tenant-a-app",
+			namespaces: []client.Object{namespace("team-a")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newAuthTestClient(t, tt.namespaces...)
+			got, err := longerNamespacePrefix(context.Background(), c, tt.template, tt.namespace, tt.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveIdentifierWithPolicy_LongerNamespace(t *testing.T) {
+	realm := &keycloakv1beta1.ClusterKeycloakRealm{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: keycloakv1beta1.ClusterKeycloakRealmSpec{
+			NamingPolicy: &keycloakv1beta1.NamingPolicy{Prefix: "{{namespace}}-", AutoPrefix: true},
+		},
+	}
+	role := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "shared"}},
+	}
+	ctx := context.Background()
+
+	c := newAuthTestClient(t, realm)
+	if got, err := resolveIdentifierWithPolicy(ctx, c, role, "name", strptr("a-admin"), ""); err != nil || got != "team-a-admin" {
+		t.Errorf("without namespace team-a: got %q, %v; want team-a-admin", got, err)
+	}
+
+	c = newAuthTestClient(t, realm, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})
+	if _, err := resolveIdentifierWithPolicy(ctx, c, role, "name", strptr("a-admin"), ""); err == nil {
+		t.Error("with namespace team-a: expected an error")
+	}
+}
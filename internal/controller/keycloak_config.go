@@ -54,14 +54,14 @@ func GetKeycloakConfigFromInstance(ctx context.Context, c client.Client, instanc
 	auth := instance.Spec.Auth
 	switch {
 	case auth.ClientCredentials != nil:
-		clientID, clientSecret, err := resolveClientCredentials(ctx, c, auth.ClientCredentials, instance.Namespace)
+		clientID, clientSecret, err := resolveClientCredentials(ctx, c, auth.ClientCredentials, instance)
 		if err != nil {
 			return cfg, err
 		}
 		cfg.ClientID = clientID
 		cfg.ClientSecret = clientSecret
 	case auth.PasswordGrant != nil:
-		username, password, err := resolvePasswordGrant(ctx, c, auth.PasswordGrant, instance.Namespace)
+		username, password, err := resolvePasswordGrant(ctx, c, auth.PasswordGrant, instance)
 		if err != nil {
 			return cfg, err
 		}
@@ -74,7 +74,7 @@ func GetKeycloakConfigFromInstance(ctx context.Context, c client.Client, instanc
 	if instance.Spec.TLS != nil {
 		cfg.InsecureSkipVerify = instance.Spec.TLS.InsecureSkipVerify
 		if instance.Spec.TLS.CACert != nil {
-			pem, err := resolveCACert(ctx, c, instance.Spec.TLS.CACert, instance.Namespace)
+			pem, err := resolveCACert(ctx, c, instance.Spec.TLS.CACert, instance)
 			if err != nil {
 				return cfg, err
 			}
@@ -131,12 +131,17 @@ func GetKeycloakConfigFromClusterInstance(ctx context.Context, c client.Client,
 
 // resolvePasswordGrant loads the admin credentials Secret referenced by spec
 // and returns (username, password). The inline Username takes precedence over
-// the value stored under SecretRef.UsernameKey.
-func resolvePasswordGrant(ctx context.Context, c client.Client, spec *keycloakv1beta1.PasswordGrantSpec, defaultNamespace string) (string, string, error) {
-	namespace := defaultNamespace
+// the value stored under SecretRef.UsernameKey. The Secret defaults to the
+// referrer's namespace; reading it from another namespace needs a
+// KeycloakReferenceGrant there.
+func resolvePasswordGrant(ctx context.Context, c client.Client, spec *keycloakv1beta1.PasswordGrantSpec, referrer client.Object) (string, string, error) {
+	namespace := referrer.GetNamespace()
 	if spec.SecretRef.Namespace != nil {
 		namespace = *spec.SecretRef.Namespace
 	}
+	if err := checkReferenceGrant(ctx, c, referrer, "Secret", namespace, spec.SecretRef.Name); err != nil {
+		return "", "", err
+	}
 	secret := &corev1.Secret{}
 	if err := c.Get(ctx, types.NamespacedName{Name: spec.SecretRef.Name, Namespace: namespace}, secret); err != nil {
 		return "", "", fmt.Errorf("failed to get credentials secret: %w", err)
@@ -169,12 +174,16 @@ func resolvePasswordGrant(ctx context.Context, c client.Client, spec *keycloakv1
 
 // resolveClientCredentials loads the client-credentials Secret referenced by
 // spec and returns (clientID, clientSecret). The inline ClientID takes
-// precedence over the value stored under SecretRef.ClientIdKey.
-func resolveClientCredentials(ctx context.Context, c client.Client, spec *keycloakv1beta1.ClientCredentialsSpec, defaultNamespace string) (string, string, error) {
-	namespace := defaultNamespace
+// precedence over the value stored under SecretRef.ClientIdKey. Cross-namespace
+// reads need a KeycloakReferenceGrant, as for resolvePasswordGrant.
+func resolveClientCredentials(ctx context.Context, c client.Client, spec *keycloakv1beta1.ClientCredentialsSpec, referrer client.Object) (string, string, error) {
+	namespace := referrer.GetNamespace()
 	if spec.SecretRef.Namespace != nil {
 		namespace = *spec.SecretRef.Namespace
 	}
+	if err := checkReferenceGrant(ctx, c, referrer, "Secret", namespace, spec.SecretRef.Name); err != nil {
+		return "", "", err
+	}
 	secret := &corev1.Secret{}
 	if err := c.Get(ctx, types.NamespacedName{Name: spec.SecretRef.Name, Namespace: namespace}, secret); err != nil {
 		return "", "", fmt.Errorf("failed to get client credentials secret: %w", err)
@@ -240,14 +249,18 @@ func resolveClusterPasswordGrant(ctx context.Context, c client.Client, spec *key
 
 // resolveCACert loads a PEM-encoded CA bundle from the referenced Secret or
 // ConfigMap. The CEL XValidation on CACertSource guarantees exactly one of
-// secretRef / configMapRef is set.
-func resolveCACert(ctx context.Context, c client.Client, src *keycloakv1beta1.CACertSource, defaultNamespace string) (string, error) {
+// secretRef / configMapRef is set. Cross-namespace reads need a
+// KeycloakReferenceGrant, as for resolvePasswordGrant.
+func resolveCACert(ctx context.Context, c client.Client, src *keycloakv1beta1.CACertSource, referrer client.Object) (string, error) {
 	switch {
 	case src.SecretRef != nil:
-		namespace := defaultNamespace
+		namespace := referrer.GetNamespace()
 		if src.SecretRef.Namespace != nil {
 			namespace = *src.SecretRef.Namespace
 		}
+		if err := checkReferenceGrant(ctx, c, referrer, "Secret", namespace, src.SecretRef.Name); err != nil {
+			return "", err
+		}
 		key := src.SecretRef.Key
 		if key == "" {
 			key = "ca.crt"
@@ -262,10 +275,13 @@ func resolveCACert(ctx context.Context, c client.Client, src *keycloakv1beta1.CA
 		}
 		return string(data), nil
 	case src.ConfigMapRef != nil:
-		namespace := defaultNamespace
+		namespace := referrer.GetNamespace()
 		if src.ConfigMapRef.Namespace != nil {
 			namespace = *src.ConfigMapRef.Namespace
 		}
+		if err := checkReferenceGrant(ctx, c, referrer, "ConfigMap", namespace, src.ConfigMapRef.Name); err != nil {
+			return "", err
+		}
 		key := src.ConfigMapRef.Key
 		if key == "" {
 			key = "ca.crt"
@@ -407,45 +423,56 @@ func mergeSmtpCredentials(definition json.RawMessage, user, password string) jso
 }
 
 // getKeycloakClientForInstance resolves a ready namespaced KeycloakInstance to
-// an admin client. Also returns the server version from the instance status.
-func getKeycloakClientForInstance(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, key types.NamespacedName) (*keycloak.Client, string, error) {
+// an admin client. Also returns the server capabilities recorded in the
+// instance status.
+func getKeycloakClientForInstance(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, key types.NamespacedName) (*keycloak.Client, *keycloak.Capabilities, error) {
 	instance := &keycloakv1beta1.KeycloakInstance{}
 	if err := c.Get(ctx, key, instance); err != nil {
-		return nil, "", fmt.Errorf("failed to get KeycloakInstance %s: %w", key, err)
+		return nil, nil, fmt.Errorf("failed to get KeycloakInstance %s: %w", key, err)
 	}
 	if !instance.Status.Ready {
-		return nil, "", fmt.Errorf("KeycloakInstance %s is not ready", key)
+		return nil, nil, fmt.Errorf("KeycloakInstance %s is not ready", key)
 	}
 	cfg, err := GetKeycloakConfigFromInstance(ctx, c, instance)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get Keycloak config from KeycloakInstance %s: %w", key, err)
+		return nil, nil, fmt.Errorf("failed to get Keycloak config from KeycloakInstance %s: %w", key, err)
 	}
 	kc := clientManager.GetOrCreateClient(key.String(), cfg)
 	if kc == nil {
-		return nil, "", fmt.Errorf("Keycloak client not available for instance %s", key)
+		return nil, nil, fmt.Errorf("Keycloak client not available for instance %s", key)
 	}
-	return kc, instance.Status.Version, nil
+	if err := kc.Available(); err != nil {
+		return nil, nil, fmt.Errorf("KeycloakInstance %s: %w", key, err)
+	}
+	return kc, keycloak.CapabilitiesFromStatus(instance.Status.Version, instance.Status.Capabilities, instance.Status.CapabilitiesDetected), nil
 }
 
 // getKeycloakClientForClusterInstance is the ClusterKeycloakInstance variant of
-// getKeycloakClientForInstance.
-func getKeycloakClientForClusterInstance(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, name string) (*keycloak.Client, string, error) {
+// getKeycloakClientForInstance. referrer is the object referencing the
+// instance; the instance's allowedNamespaces policy is checked against it.
+func getKeycloakClientForClusterInstance(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, name string, referrer client.Object) (*keycloak.Client, *keycloak.Capabilities, error) {
 	instance := &keycloakv1beta1.ClusterKeycloakInstance{}
 	if err := c.Get(ctx, types.NamespacedName{Name: name}, instance); err != nil {
-		return nil, "", fmt.Errorf("failed to get ClusterKeycloakInstance %s: %w", name, err)
+		return nil, nil, fmt.Errorf("failed to get ClusterKeycloakInstance %s: %w", name, err)
+	}
+	if err := checkNamespaceAccess(ctx, c, instance.Spec.AllowedNamespaces, "ClusterKeycloakInstance", name, referrer); err != nil {
+		return nil, nil, err
 	}
 	if !instance.Status.Ready {
-		return nil, "", fmt.Errorf("ClusterKeycloakInstance %s is not ready", name)
+		return nil, nil, fmt.Errorf("ClusterKeycloakInstance %s is not ready", name)
 	}
 	cfg, err := GetKeycloakConfigFromClusterInstance(ctx, c, instance)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get Keycloak config from ClusterKeycloakInstance %s: %w", name, err)
+		return nil, nil, fmt.Errorf("failed to get Keycloak config from ClusterKeycloakInstance %s: %w", name, err)
 	}
 	kc := clientManager.GetOrCreateClient(clusterInstanceKey(name), cfg)
 	if kc == nil {
-		return nil, "", fmt.Errorf("Keycloak client not available for cluster instance %s", name)
+		return nil, nil, fmt.Errorf("Keycloak client not available for cluster instance %s", name)
+	}
+	if err := kc.Available(); err != nil {
+		return nil, nil, fmt.Errorf("ClusterKeycloakInstance %s: %w", name, err)
 	}
-	return kc, instance.Status.Version, nil
+	return kc, keycloak.CapabilitiesFromStatus(instance.Status.Version, instance.Status.Capabilities, instance.Status.CapabilitiesDetected), nil
 }
 
 // RealmResolution is the result of resolving a realmRef/clusterRealmRef pair.
@@ -454,9 +481,11 @@ type RealmResolution struct {
 	// RealmName is the realm's resolved identifier (spec.realmName, surfaced
 	// via status); it is never read from the realm's definition.
 	RealmName string
-	// Version is the Keycloak server version reported by the resolved instance,
-	// usable for version-gated features (organizations, etc.).
+	// Version is the Keycloak server version reported by the resolved instance.
 	Version string
+	// Capabilities is the feature set reported by the resolved instance; gate
+	// optional Admin API calls on it (organizations, execution priority, etc.).
+	Capabilities *keycloak.Capabilities
 	// Exactly one of Realm / ClusterRealm is set, matching the reference kind.
 	Realm        *keycloakv1beta1.KeycloakRealm
 	ClusterRealm *keycloakv1beta1.ClusterKeycloakRealm
@@ -466,26 +495,31 @@ type RealmResolution struct {
 // Keycloak admin client, following the realm's instanceRef or
 // clusterInstanceRef. It is the single source of truth for realm reference
 // resolution: controllers must not re-implement it, so that every valid
-// reference combination is supported everywhere. namespace is the namespace of
-// the resource carrying the reference.
-func ResolveRealm(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, namespace string, realmRef *keycloakv1beta1.ResourceRef, clusterRealmRef *keycloakv1beta1.ClusterResourceRef) (*RealmResolution, error) {
+// reference combination is supported everywhere. referrer is the resource
+// carrying the reference: realmRef is looked up in its namespace, and the
+// allowedNamespaces policies of cluster-scoped realms and instances are
+// checked against it.
+func ResolveRealm(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, referrer client.Object, realmRef *keycloakv1beta1.ResourceRef, clusterRealmRef *keycloakv1beta1.ClusterResourceRef) (*RealmResolution, error) {
 	if clusterRealmRef != nil {
 		clusterRealm := &keycloakv1beta1.ClusterKeycloakRealm{}
 		if err := c.Get(ctx, types.NamespacedName{Name: clusterRealmRef.Name}, clusterRealm); err != nil {
 			return nil, fmt.Errorf("failed to get ClusterKeycloakRealm %s: %w", clusterRealmRef.Name, err)
 		}
+		if err := checkNamespaceAccess(ctx, c, clusterRealm.Spec.AllowedNamespaces, "ClusterKeycloakRealm", clusterRealmRef.Name, referrer); err != nil {
+			return nil, err
+		}
 		if !clusterRealm.Status.Ready || clusterRealm.Status.RealmName == "" {
 			return nil, fmt.Errorf("ClusterKeycloakRealm %s is not ready", clusterRealmRef.Name)
 		}
 
 		var kc *keycloak.Client
-		var version string
+		var caps *keycloak.Capabilities
 		var err error
 		switch {
 		case clusterRealm.Spec.ClusterInstanceRef != nil:
-			kc, version, err = getKeycloakClientForClusterInstance(ctx, c, clientManager, clusterRealm.Spec.ClusterInstanceRef.Name)
+			kc, caps, err = getKeycloakClientForClusterInstance(ctx, c, clientManager, clusterRealm.Spec.ClusterInstanceRef.Name, clusterRealm)
 		case clusterRealm.Spec.InstanceRef != nil:
-			kc, version, err = getKeycloakClientForInstance(ctx, c, clientManager, types.NamespacedName{
+			kc, caps, err = getKeycloakClientForInstance(ctx, c, clientManager, types.NamespacedName{
 				Name:      clusterRealm.Spec.InstanceRef.Name,
 				Namespace: clusterRealm.Spec.InstanceRef.Namespace,
 			})
@@ -495,14 +529,14 @@ func ResolveRealm(ctx context.Context, c client.Client, clientManager *keycloak.
 		if err != nil {
 			return nil, err
 		}
-		return &RealmResolution{Client: kc, RealmName: clusterRealm.Status.RealmName, Version: version, ClusterRealm: clusterRealm}, nil
+		return &RealmResolution{Client: kc, RealmName: clusterRealm.Status.RealmName, Version: caps.Version, Capabilities: caps, ClusterRealm: clusterRealm}, nil
 	}
 
 	if realmRef == nil {
 		return nil, fmt.Errorf("either realmRef or clusterRealmRef must be specified")
 	}
 
-	realmKey := types.NamespacedName{Name: realmRef.Name, Namespace: namespace}
+	realmKey := types.NamespacedName{Name: realmRef.Name, Namespace: referrer.GetNamespace()}
 	realm := &keycloakv1beta1.KeycloakRealm{}
 	if err := c.Get(ctx, realmKey, realm); err != nil {
 		return nil, fmt.Errorf("failed to get KeycloakRealm %s: %w", realmKey, err)
@@ -512,13 +546,13 @@ func ResolveRealm(ctx context.Context, c client.Client, clientManager *keycloak.
 	}
 
 	var kc *keycloak.Client
-	var version string
+	var caps *keycloak.Capabilities
 	var err error
 	switch {
 	case realm.Spec.ClusterInstanceRef != nil:
-		kc, version, err = getKeycloakClientForClusterInstance(ctx, c, clientManager, realm.Spec.ClusterInstanceRef.Name)
+		kc, caps, err = getKeycloakClientForClusterInstance(ctx, c, clientManager, realm.Spec.ClusterInstanceRef.Name, realm)
 	case realm.Spec.InstanceRef != nil:
-		kc, version, err = getKeycloakClientForInstance(ctx, c, clientManager, types.NamespacedName{
+		kc, caps, err = getKeycloakClientForInstance(ctx, c, clientManager, types.NamespacedName{
 			Name:      realm.Spec.InstanceRef.Name,
 			Namespace: realm.Namespace,
 		})
@@ -528,19 +562,21 @@ func ResolveRealm(ctx context.Context, c client.Client, clientManager *keycloak.
 	if err != nil {
 		return nil, err
 	}
-	return &RealmResolution{Client: kc, RealmName: realm.Status.RealmName, Version: version, Realm: realm}, nil
+	return &RealmResolution{Client: kc, RealmName: realm.Status.RealmName, Version: caps.Version, Capabilities: caps, Realm: realm}, nil
 }
 
 // GetKeycloakClientAndRealmForIDP resolves the Keycloak admin client and the
 // realm name for a KeycloakIdentityProvider, following its realmRef or
 // clusterRealmRef. This is the shared resolver used by both the
 // KeycloakIdentityProvider and KeycloakIdentityProviderMapper controllers.
-func GetKeycloakClientAndRealmForIDP(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, idp *keycloakv1beta1.KeycloakIdentityProvider) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, c, clientManager, idp.Namespace, idp.Spec.RealmRef, idp.Spec.ClusterRealmRef)
+// The server capabilities gate the token-exchange permission. referrer is the
+// resource being reconciled, the identity provider or one of its mappers.
+func GetKeycloakClientAndRealmForIDP(ctx context.Context, c client.Client, clientManager *keycloak.ClientManager, referrer client.Object, idp *keycloakv1beta1.KeycloakIdentityProvider) (*keycloak.Client, string, *keycloak.Capabilities, error) {
+	res, err := ResolveRealm(ctx, c, clientManager, referrer, idp.Spec.RealmRef, idp.Spec.ClusterRealmRef)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
-	return res.Client, res.RealmName, nil
+	return res.Client, res.RealmName, res.Capabilities, nil
 }
 
 // mergeDefinitionConfig merges secretData into definition.config. If the
@@ -180,7 +180,14 @@ func TestGetKeycloakConfigFromInstance_PasswordGrant_SecretInOtherNamespace(t *t
 			},
 		},
 	}
-	cfg, err := GetKeycloakConfigFromInstance(context.Background(), newAuthTestClient(t, secret, instance), instance)
+	grant := &keycloakv1beta1.KeycloakReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "kc-admin", Namespace: "secrets"},
+		Spec: keycloakv1beta1.KeycloakReferenceGrantSpec{
+			From: []keycloakv1beta1.ReferenceGrantFrom{{Kind: "KeycloakInstance", Namespace: "kc"}},
+			To:   []keycloakv1beta1.ReferenceGrantTo{{Kind: "Secret", Name: strPtr("admin")}},
+		},
+	}
+	cfg, err := GetKeycloakConfigFromInstance(context.Background(), newAuthTestClient(t, secret, instance, grant), instance)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -140,7 +140,8 @@ func TestResolveRealm(t *testing.T) {
 			c := newAuthTestClient(t, resolveRealmFixtures()...)
 			cm := keycloak.NewClientManager(logr.Discard())
 
-			res, err := ResolveRealm(context.Background(), c, cm, tc.namespace, tc.realmRef, tc.clusterRealmRef)
+			referrer := &keycloakv1beta1.KeycloakClient{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: tc.namespace}}
+			res, err := ResolveRealm(context.Background(), c, cm, referrer, tc.realmRef, tc.clusterRealmRef)
 			if tc.wantErr != "" {
 				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
 					t.Fatalf("got error %v, want it to contain %q", err, tc.wantErr)
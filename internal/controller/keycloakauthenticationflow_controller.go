@@ -11,8 +11,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
@@ -69,15 +71,15 @@ func (e flowExecution) children() []flowExecution {
 }
 
 // parseExecutions decodes the spec's executions field into the recursive
-// representation. Validation errors include the JSON-pointer-style path of
-// the offending node, e.g. "[1].executions[0].requirement is required".
+// representation. Validation errors are *executionError values carrying the
+// path of the offending node.
 func parseExecutions(raw runtime.RawExtension) ([]flowExecution, error) {
 	if len(raw.Raw) == 0 {
 		return nil, nil
 	}
 	var execs []flowExecution
 	if err := json.Unmarshal(raw.Raw, &execs); err != nil {
-		return nil, fmt.Errorf("decoding executions: %w", err)
+		return nil, &executionError{Problem: fmt.Sprintf("decoding executions: %v", err)}
 	}
 	if err := validateExecutions(execs, ""); err != nil {
 		return nil, err
@@ -85,29 +87,44 @@ func parseExecutions(raw runtime.RawExtension) ([]flowExecution, error) {
 	return execs, nil
 }
 
-func validateExecutions(execs []flowExecution, path string) error {
+// executionError is a problem with spec.executions. Path locates the
+// offending execution below spec.executions, e.g. "[1].executions[0]", and
+// is empty for the list as a whole.
+type executionError struct {
+	Path    string
+	Problem string
+}
+
+func (e *executionError) Error() string {
+	return fmt.Sprintf("spec.executions%s: %s", e.Path, e.Problem)
+}
+
+func validateExecutions(execs []flowExecution, path string) *executionError {
 	for i, e := range execs {
 		nodePath := fmt.Sprintf("%s[%d]", path, i)
+		invalid := func(format string, args ...interface{}) *executionError {
+			return &executionError{Path: nodePath, Problem: fmt.Sprintf(format, args...)}
+		}
 		hasAuth := e.Authenticator != ""
 		hasSub := e.SubFlow != nil
 		if hasAuth == hasSub {
-			return fmt.Errorf("%s: exactly one of authenticator or subFlow must be set", nodePath)
+			return invalid("exactly one of authenticator or subFlow must be set")
 		}
 		if hasSub {
 			if strings.TrimSpace(e.SubFlow.Alias) == "" {
-				return fmt.Errorf("%s.subFlow.alias is required", nodePath)
+				return invalid("subFlow.alias is required")
 			}
 			if strings.TrimSpace(e.SubFlow.ProviderID) == "" {
-				return fmt.Errorf("%s.subFlow.providerId is required", nodePath)
+				return invalid("subFlow.providerId is required")
 			}
 		}
 		if e.Requirement == "" {
-			return fmt.Errorf("%s.requirement is required", nodePath)
+			return invalid("requirement is required")
 		}
 		switch e.Requirement {
 		case "REQUIRED", "ALTERNATIVE", "DISABLED", "CONDITIONAL":
 		default:
-			return fmt.Errorf("%s.requirement %q is not one of REQUIRED|ALTERNATIVE|DISABLED|CONDITIONAL", nodePath, e.Requirement)
+			return invalid("requirement %q is not one of REQUIRED|ALTERNATIVE|DISABLED|CONDITIONAL", e.Requirement)
 		}
 		if hasSub {
 			if err := validateExecutions(e.children(), nodePath+".executions"); err != nil {
@@ -118,6 +135,16 @@ func validateExecutions(execs []flowExecution, path string) error {
 	return nil
 }
 
+// checkAuthenticationFlowSpec makes the checks on flow's spec that need no
+// Keycloak call and returns its parsed executions.
+func checkAuthenticationFlowSpec(flow *keycloakv1beta1.KeycloakAuthenticationFlow) ([]flowExecution, *specError) {
+	executions, err := parseExecutions(flow.Spec.Executions)
+	if err != nil {
+		return nil, &specError{reason: "InvalidSpec", metric: "invalid_spec", err: err}
+	}
+	return executions, nil
+}
+
 // KeycloakAuthenticationFlowReconciler reconciles a KeycloakAuthenticationFlow object
 type KeycloakAuthenticationFlowReconciler struct {
 	client.Client
@@ -150,21 +177,15 @@ func (r *KeycloakAuthenticationFlowReconciler) Reconcile(ctx context.Context, re
 		RecordReconcile(controllerName, flow.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, flow); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !flow.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(flow, FinalizerName) {
-			if ShouldPreserveResource(flow) {
-				log.Info("preserving flow in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteFlow(ctx, flow); err != nil {
-				log.Error(err, "failed to delete authentication flow from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(flow, FinalizerName)
-			if err := r.Update(ctx, flow); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, flow, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteFlow(ctx, flow)
+		})
 	}
 
 	// Add finalizer
@@ -177,18 +198,18 @@ func (r *KeycloakAuthenticationFlowReconciler) Reconcile(ctx context.Context, re
 	}
 
 	// Get Keycloak client and realm
-	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, flow)
+	kc, realmName, caps, err := r.getKeycloakClientAndRealm(ctx, flow)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, flow, false, "RealmNotReady", err.Error(), "", "")
+		return r.updateStatus(ctx, flow, false, notReadyReason(err, "RealmNotReady"), err.Error(), "", "")
 	}
 
 	// Validate the spec early so we report decoding/shape errors with a clear
 	// message instead of failing later inside a Keycloak API call.
-	executions, err := parseExecutions(flow.Spec.Executions)
-	if err != nil {
-		RecordError(controllerName, "invalid_spec")
-		return r.updateStatus(ctx, flow, false, "InvalidSpec", err.Error(), "", realmName)
+	executions, specErr := checkAuthenticationFlowSpec(flow)
+	if specErr != nil {
+		RecordError(controllerName, specErr.metric)
+		return r.updateStatus(ctx, flow, false, specErr.reason, specErr.Error(), "", realmName)
 	}
 
 	// Find existing flow by alias
@@ -199,9 +220,12 @@ func (r *KeycloakAuthenticationFlowReconciler) Reconcile(ctx context.Context, re
 	}
 
 	if existingFlowID != "" {
-		stats, err := r.updateExistingFlow(ctx, kc, realmName, flow, existingFlowID, executions)
+		stats, err := r.updateExistingFlow(ctx, kc, caps, realmName, flow, existingFlowID, executions)
 		if err != nil {
 			RecordError(controllerName, "keycloak_api_error")
+			if keycloak.IsUnsupported(err) {
+				return r.updateStatus(ctx, flow, false, UnsupportedByServerReason, err.Error(), existingFlowID, realmName)
+			}
 			if stderrors.Is(err, errProviderChangeUnsupported) {
 				return r.updateStatus(ctx, flow, false, "ProviderChangeUnsupported", err.Error(), existingFlowID, realmName)
 			}
@@ -224,9 +248,12 @@ func (r *KeycloakAuthenticationFlowReconciler) Reconcile(ctx context.Context, re
 
 	// Create flow and execution tree
 	log.Info("creating authentication flow", "alias", flow.Spec.Alias, "realm", realmName)
-	flowID, err := r.createFlowTree(ctx, kc, realmName, flow, executions)
+	flowID, err := r.createFlowTree(ctx, kc, caps, realmName, flow, executions)
 	if err != nil {
 		RecordError(controllerName, "keycloak_api_error")
+		if keycloak.IsUnsupported(err) {
+			return r.updateStatus(ctx, flow, false, UnsupportedByServerReason, err.Error(), "", realmName)
+		}
 		return r.updateStatus(ctx, flow, false, "CreateFailed", fmt.Sprintf("Failed to create flow: %v", err), "", realmName)
 	}
 	log.Info("authentication flow created", "alias", flow.Spec.Alias, "id", flowID)
@@ -246,7 +273,7 @@ func (r *KeycloakAuthenticationFlowReconciler) findFlowByAlias(ctx context.Conte
 	return "", nil
 }
 
-func (r *KeycloakAuthenticationFlowReconciler) createFlowTree(ctx context.Context, kc *keycloak.Client, realmName string, flow *keycloakv1beta1.KeycloakAuthenticationFlow, executions []flowExecution) (string, error) {
+func (r *KeycloakAuthenticationFlowReconciler) createFlowTree(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName string, flow *keycloakv1beta1.KeycloakAuthenticationFlow, executions []flowExecution) (string, error) {
 	topLevel := true
 	builtIn := false
 	flowRep := keycloak.AuthenticationFlowRepresentation{
@@ -262,7 +289,7 @@ func (r *KeycloakAuthenticationFlowReconciler) createFlowTree(ctx context.Contex
 		return "", fmt.Errorf("creating top-level flow %q: %w", flow.Spec.Alias, err)
 	}
 
-	if err := r.addExecutions(ctx, kc, realmName, flow.Spec.Alias, executions); err != nil {
+	if err := r.addExecutions(ctx, kc, caps, realmName, flow.Spec.Alias, executions); err != nil {
 		// Best-effort cleanup on failure
 		_ = kc.DeleteAuthenticationFlow(ctx, realmName, flowID)
 		return "", err
@@ -275,10 +302,10 @@ func (r *KeycloakAuthenticationFlowReconciler) createFlowTree(ctx context.Contex
 // recurses into any sub-flows. Reordering is performed once per parent at the
 // end so each new node sits at the correct position regardless of the order
 // Keycloak assigns to newly added executions.
-func (r *KeycloakAuthenticationFlowReconciler) addExecutions(ctx context.Context, kc *keycloak.Client, realmName, parentAlias string, executions []flowExecution) error {
+func (r *KeycloakAuthenticationFlowReconciler) addExecutions(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName, parentAlias string, executions []flowExecution) error {
 	for _, exec := range executions {
 		if exec.SubFlow != nil {
-			if err := r.addSubFlow(ctx, kc, realmName, parentAlias, exec); err != nil {
+			if err := r.addSubFlow(ctx, kc, caps, realmName, parentAlias, exec); err != nil {
 				return err
 			}
 		} else {
@@ -288,7 +315,7 @@ func (r *KeycloakAuthenticationFlowReconciler) addExecutions(ctx context.Context
 		}
 	}
 	if len(executions) > 1 {
-		if _, err := r.reorderChildren(ctx, kc, realmName, parentAlias, executions); err != nil {
+		if _, err := r.reorderChildren(ctx, kc, caps, realmName, parentAlias, executions); err != nil {
 			return fmt.Errorf("reordering executions in flow %q: %w", parentAlias, err)
 		}
 	}
@@ -326,7 +353,7 @@ func (r *KeycloakAuthenticationFlowReconciler) addAuthenticatorExecution(ctx con
 	return nil
 }
 
-func (r *KeycloakAuthenticationFlowReconciler) addSubFlow(ctx context.Context, kc *keycloak.Client, realmName, parentAlias string, exec flowExecution) error {
+func (r *KeycloakAuthenticationFlowReconciler) addSubFlow(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName, parentAlias string, exec flowExecution) error {
 	subFlowDef := buildSubFlowDef(exec.SubFlow.Alias, exec.SubFlow.Description, exec.SubFlow.ProviderID)
 	if _, err := kc.AddFlowSubFlow(ctx, realmName, parentAlias, subFlowDef); err != nil {
 		return fmt.Errorf("adding sub-flow %q to flow %q: %w", exec.SubFlow.Alias, parentAlias, err)
@@ -346,7 +373,7 @@ func (r *KeycloakAuthenticationFlowReconciler) addSubFlow(ctx context.Context, k
 
 	children := exec.children()
 	if len(children) > 0 {
-		if err := r.addExecutions(ctx, kc, realmName, exec.SubFlow.Alias, children); err != nil {
+		if err := r.addExecutions(ctx, kc, caps, realmName, exec.SubFlow.Alias, children); err != nil {
 			return err
 		}
 	}
@@ -375,8 +402,11 @@ const reorderPriorityStep = 10
 // the live order matches desired. Requires Keycloak 25+: older versions drop
 // the priority field, and the raise-/lower-priority swap endpoints don't
 // help on KC 25/26 either because new executions all share priority 0
-// (keycloak/keycloak#35765). Returns true when at least one PUT was issued.
-func (r *KeycloakAuthenticationFlowReconciler) reorderChildren(ctx context.Context, kc *keycloak.Client, realmName, parentAlias string, desired []flowExecution) (bool, error) {
+// (keycloak/keycloak#35765). On servers without the execution-priority
+// capability an order that already matches is accepted as is and any other
+// order yields a keycloak.UnsupportedError. Returns true when at least one PUT
+// was issued.
+func (r *KeycloakAuthenticationFlowReconciler) reorderChildren(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName, parentAlias string, desired []flowExecution) (bool, error) {
 	execs, err := kc.GetFlowExecutions(ctx, realmName, parentAlias)
 	if err != nil {
 		return false, err
@@ -403,12 +433,18 @@ func (r *KeycloakAuthenticationFlowReconciler) reorderChildren(ctx context.Conte
 		}
 	}
 
-	// Position equality alone is not enough: with all priorities at 0,
-	// Keycloak's sort order for ties is non-deterministic, so we'd skip
-	// the PUTs and never lock the order in. Require a positive priority.
-	if liveMatchesDesiredOrder(topLevel, matchIdx) {
+	priorities := make([]int, len(matchIdx))
+	for i, j := range matchIdx {
+		if topLevel[j].Priority != nil {
+			priorities[i] = *topLevel[j].Priority
+		}
+	}
+	if executionsInOrder(caps, matchIdx, priorities) {
 		return false, nil
 	}
+	if err := caps.Require(keycloak.CapabilityExecutionPriority); err != nil {
+		return false, err
+	}
 
 	for i, j := range matchIdx {
 		match := topLevel[j]
@@ -433,12 +469,22 @@ func (r *KeycloakAuthenticationFlowReconciler) reorderChildren(ctx context.Conte
 	return true, nil
 }
 
-func liveMatchesDesiredOrder(topLevel []keycloak.AuthenticationExecutionInfo, matchIdx []int) bool {
-	for i, j := range matchIdx {
+// livePositionsMatch reports whether every desired child already sits at its
+// desired position, regardless of priority.
+// executionsInOrder reports whether the executions matched to a flow's
+// desired children need no reordering: positions[i] is the live position of
+// the i-th desired child and priorities[i] its live priority. Without the
+// execution-priority capability positions are all that can be checked.
+// With it, position equality alone is not enough: with all priorities at 0,
+// Keycloak's sort order for ties is non-deterministic, so we'd skip the PUTs
+// and never lock the order in. Require a positive priority.
+func executionsInOrder(caps *keycloak.Capabilities, positions, priorities []int) bool {
+	checkPriority := caps.Supports(keycloak.CapabilityExecutionPriority)
+	for i, j := range positions {
 		if i != j {
 			return false
 		}
-		if topLevel[j].Priority == nil || *topLevel[j].Priority <= 0 {
+		if checkPriority && priorities[i] <= 0 {
 			return false
 		}
 	}
@@ -508,15 +554,19 @@ func matchesIdentifier(e keycloak.AuthenticationExecutionInfo, id execIdentifier
 type liveExecution struct {
 	ID                   string
 	Requirement          string
+	Priority             int
 	AuthenticationConfig string
+	ConfigAlias          string
+	Config               map[string]string
 	IsFlow               bool
 	Authenticator        string
 	SubFlowAlias         string
 	Children             []liveExecution
 }
 
-// readLiveTree fetches the live execution tree under flowAlias and returns it
-// in a comparable shape so reconcileChildren can diff it against the spec.
+// readLiveTree fetches the live execution tree under flowAlias, with the
+// configs of its executions, and returns it in a comparable shape so
+// diffExecutions can diff it against the spec.
 func (r *KeycloakAuthenticationFlowReconciler) readLiveTree(ctx context.Context, kc *keycloak.Client, realmName, flowAlias string) ([]liveExecution, error) {
 	execs, err := kc.GetFlowExecutions(ctx, realmName, flowAlias)
 	if err != nil {
@@ -532,8 +582,19 @@ func (r *KeycloakAuthenticationFlowReconciler) readLiveTree(ctx context.Context,
 		if e.Requirement != nil {
 			le.Requirement = *e.Requirement
 		}
-		if e.AuthenticationConfig != nil {
+		if e.Priority != nil {
+			le.Priority = *e.Priority
+		}
+		if e.AuthenticationConfig != nil && *e.AuthenticationConfig != "" {
 			le.AuthenticationConfig = *e.AuthenticationConfig
+			cfg, err := kc.GetExecutionConfig(ctx, realmName, le.AuthenticationConfig)
+			if err != nil {
+				return nil, fmt.Errorf("fetching live config of execution in flow %q: %w", flowAlias, err)
+			}
+			le.Config = cfg.Config
+			if cfg.Alias != nil {
+				le.ConfigAlias = *cfg.Alias
+			}
 		}
 		if e.AuthenticationFlow != nil && *e.AuthenticationFlow {
 			le.IsFlow = true
@@ -602,26 +663,63 @@ func matchExecutions(desired []flowExecution, live []liveExecution) (matches []i
 	return matches, matchedLive
 }
 
-// reconcileChildren brings the live children of parentAlias into the shape
-// described by desired with the minimum set of API calls (Add / Delete /
-// Update / Reorder), recursing into matched sub-flows. The top-level flow
-// itself is never deleted from this path, so flows that are referenced as a
-// sub-flow execution by another flow or as a realm binding override stay
-// usable throughout the update.
-func (r *KeycloakAuthenticationFlowReconciler) reconcileChildren(
-	ctx context.Context, kc *keycloak.Client, realmName, parentAlias string,
-	desired []flowExecution, live []liveExecution, stats *updateStats,
-) error {
+// executionAction is the kind of an executionChange.
+type executionAction int
+
+const (
+	removeExecution executionAction = iota
+	setExecutionRequirement
+	createExecutionConfig
+	deleteExecutionConfig
+	updateExecutionConfig
+	addExecution
+	reorderExecutions
+)
+
+// executionChange is one change that brings the live children of parentAlias
+// into the desired shape. live is the execution a change applies to, desired
+// its spec; reorderExecutions carries the desired children of parentAlias.
+type executionChange struct {
+	action      executionAction
+	parentAlias string
+	live        liveExecution
+	desired     flowExecution
+	order       []flowExecution
+}
+
+// String describes the change for plans and logs.
+func (c executionChange) String() string {
+	switch c.action {
+	case removeExecution:
+		return fmt.Sprintf("remove %s from flow %q", liveIdentity(c.live), c.parentAlias)
+	case setExecutionRequirement:
+		return fmt.Sprintf("set requirement of %s in flow %q to %s", liveIdentity(c.live), c.parentAlias, c.desired.Requirement)
+	case createExecutionConfig, deleteExecutionConfig, updateExecutionConfig:
+		return fmt.Sprintf("update config of %s in flow %q", liveIdentity(c.live), c.parentAlias)
+	case addExecution:
+		if c.desired.SubFlow != nil {
+			return fmt.Sprintf("add sub-flow %q to flow %q", c.desired.SubFlow.Alias, c.parentAlias)
+		}
+		return fmt.Sprintf("add authenticator %q to flow %q", c.desired.Authenticator, c.parentAlias)
+	default:
+		return fmt.Sprintf("reorder executions of flow %q", c.parentAlias)
+	}
+}
+
+// diffExecutions returns the minimum set of changes (remove / update / add /
+// reorder) that bring the live children of parentAlias into the shape
+// described by desired, recursing into matched sub-flows, in the order
+// reconcileChildren makes them. It makes no calls, so that plan reports
+// exactly what the reconciler does.
+func diffExecutions(caps *keycloak.Capabilities, parentAlias string, desired []flowExecution, live []liveExecution) []executionChange {
 	matches, matchedLive := matchExecutions(desired, live)
+	var changes []executionChange
 
 	for li, l := range live {
 		if matchedLive[li] || l.ID == "" {
 			continue
 		}
-		if err := kc.DeleteExecution(ctx, realmName, l.ID); err != nil {
-			return fmt.Errorf("removing %s from flow %q: %w", liveIdentity(l), parentAlias, err)
-		}
-		stats.removed++
+		changes = append(changes, executionChange{action: removeExecution, parentAlias: parentAlias, live: l})
 	}
 
 	for di, d := range desired {
@@ -631,45 +729,106 @@ func (r *KeycloakAuthenticationFlowReconciler) reconcileChildren(
 		}
 		l := live[li]
 		if l.Requirement != d.Requirement {
-			if err := r.setExecutionRequirement(ctx, kc, realmName, parentAlias, l.ID, d.Requirement, l.IsFlow); err != nil {
-				return err
-			}
-			stats.updated++
+			changes = append(changes, executionChange{action: setExecutionRequirement, parentAlias: parentAlias, live: l, desired: d})
 		}
 		if l.IsFlow {
-			if err := r.reconcileChildren(ctx, kc, realmName, d.SubFlow.Alias, d.children(), l.Children, stats); err != nil {
-				return err
-			}
+			changes = append(changes, diffExecutions(caps, d.SubFlow.Alias, d.children(), l.Children)...)
 			continue
 		}
-		if err := r.reconcileLeafConfig(ctx, kc, realmName, parentAlias, l, d, stats); err != nil {
-			return err
+		hasDesired := len(d.AuthenticatorConfig) > 0
+		hasLive := l.AuthenticationConfig != ""
+		action := executionAction(-1)
+		switch {
+		case hasDesired && !hasLive:
+			action = createExecutionConfig
+		case !hasDesired && hasLive:
+			action = deleteExecutionConfig
+		case hasDesired && hasLive && !configMapsEqual(l.Config, d.AuthenticatorConfig):
+			action = updateExecutionConfig
+		}
+		if action >= 0 {
+			changes = append(changes, executionChange{action: action, parentAlias: parentAlias, live: l, desired: d})
 		}
 	}
 
+	// Added executions are appended after the matched ones, which keep their
+	// live order; from Keycloak 25 on they start at priority 0.
+	positions := make([]int, len(desired))
+	priorities := make([]int, len(desired))
+	matchedDesired := make(map[int]int, len(desired))
+	for di, li := range matches {
+		if li >= 0 {
+			matchedDesired[li] = di
+		}
+	}
+	next := 0
+	for li := range live {
+		if di, ok := matchedDesired[li]; ok {
+			positions[di], priorities[di] = next, live[li].Priority
+			next++
+		}
+	}
 	for di, d := range desired {
 		if matches[di] >= 0 {
 			continue
 		}
-		if d.SubFlow != nil {
-			if err := r.addSubFlow(ctx, kc, realmName, parentAlias, d); err != nil {
+		changes = append(changes, executionChange{action: addExecution, parentAlias: parentAlias, desired: d})
+		positions[di] = next
+		next++
+	}
+
+	if len(desired) > 1 && !executionsInOrder(caps, positions, priorities) {
+		changes = append(changes, executionChange{action: reorderExecutions, parentAlias: parentAlias, order: desired})
+	}
+	return changes
+}
+
+// reconcileChildren brings the live children of parentAlias into the shape
+// described by desired by making the changes diffExecutions finds. The
+// top-level flow itself is never deleted from this path, so flows that are
+// referenced as a sub-flow execution by another flow or as a realm binding
+// override stay usable throughout the update.
+func (r *KeycloakAuthenticationFlowReconciler) reconcileChildren(
+	ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName, parentAlias string,
+	desired []flowExecution, live []liveExecution, stats *updateStats,
+) error {
+	for _, change := range diffExecutions(caps, parentAlias, desired, live) {
+		l, d := change.live, change.desired
+		switch change.action {
+		case removeExecution:
+			if err := kc.DeleteExecution(ctx, realmName, l.ID); err != nil {
+				return fmt.Errorf("removing %s from flow %q: %w", liveIdentity(l), change.parentAlias, err)
+			}
+			stats.removed++
+		case setExecutionRequirement:
+			if err := r.setExecutionRequirement(ctx, kc, realmName, change.parentAlias, l.ID, d.Requirement, l.IsFlow); err != nil {
 				return err
 			}
-		} else {
-			if err := r.addAuthenticatorExecution(ctx, kc, realmName, parentAlias, d); err != nil {
+			stats.updated++
+		case createExecutionConfig, deleteExecutionConfig, updateExecutionConfig:
+			if err := r.reconcileLeafConfig(ctx, kc, realmName, change); err != nil {
 				return err
 			}
-		}
-		stats.added++
-	}
-
-	if len(desired) > 1 {
-		changed, err := r.reorderChildren(ctx, kc, realmName, parentAlias, desired)
-		if err != nil {
-			return fmt.Errorf("reordering executions in flow %q: %w", parentAlias, err)
-		}
-		if changed {
-			stats.reorderedParents++
+			stats.updated++
+		case addExecution:
+			if d.SubFlow != nil {
+				if err := r.addSubFlow(ctx, kc, caps, realmName, change.parentAlias, d); err != nil {
+					return err
+				}
+			} else {
+				if err := r.addAuthenticatorExecution(ctx, kc, realmName, change.parentAlias, d); err != nil {
+					return err
+				}
+			}
+			stats.added++
+		case reorderExecutions:
+			changed, err := r.reorderChildren(ctx, kc, caps, realmName, change.parentAlias, change.order)
+			if err != nil {
+				return fmt.Errorf("reordering executions in flow %q: %w", change.parentAlias, err)
+			}
+			if changed {
+				stats.reorderedParents++
+			}
 		}
 	}
 	return nil
@@ -695,20 +854,13 @@ func (r *KeycloakAuthenticationFlowReconciler) setExecutionRequirement(
 	return nil
 }
 
-// reconcileLeafConfig converges the authenticatorConfig of a matched leaf
-// execution. Combinations handled: none/none -> no-op; spec/none -> create;
-// none/live -> delete; spec/live equal -> no-op; spec/live differ -> update.
-func (r *KeycloakAuthenticationFlowReconciler) reconcileLeafConfig(
-	ctx context.Context, kc *keycloak.Client, realmName, parentAlias string,
-	l liveExecution, d flowExecution, stats *updateStats,
-) error {
-	hasDesired := len(d.AuthenticatorConfig) > 0
-	hasLive := l.AuthenticationConfig != ""
-
-	switch {
-	case !hasDesired && !hasLive:
-		return nil
-	case hasDesired && !hasLive:
+// reconcileLeafConfig makes a config change of a matched leaf execution:
+// create a config the spec sets, delete one it no longer sets, or update one
+// that differs.
+func (r *KeycloakAuthenticationFlowReconciler) reconcileLeafConfig(ctx context.Context, kc *keycloak.Client, realmName string, change executionChange) error {
+	l, d, parentAlias := change.live, change.desired, change.parentAlias
+	switch change.action {
+	case createExecutionConfig:
 		configAlias := parentAlias + "-" + d.Authenticator + "-config"
 		config := keycloak.AuthenticatorConfigRepresentation{
 			Alias:  &configAlias,
@@ -717,25 +869,14 @@ func (r *KeycloakAuthenticationFlowReconciler) reconcileLeafConfig(
 		if _, err := kc.CreateExecutionConfig(ctx, realmName, l.ID, config); err != nil {
 			return fmt.Errorf("setting config on execution %q in flow %q: %w", d.Authenticator, parentAlias, err)
 		}
-		stats.updated++
-		return nil
-	case !hasDesired && hasLive:
+	case deleteExecutionConfig:
 		if err := kc.DeleteExecutionConfig(ctx, realmName, l.AuthenticationConfig); err != nil {
 			return fmt.Errorf("removing config from execution %q in flow %q: %w", d.Authenticator, parentAlias, err)
 		}
-		stats.updated++
-		return nil
 	default:
-		liveCfg, err := kc.GetExecutionConfig(ctx, realmName, l.AuthenticationConfig)
-		if err != nil {
-			return fmt.Errorf("fetching live config for execution %q: %w", d.Authenticator, err)
-		}
-		if configMapsEqual(liveCfg.Config, d.AuthenticatorConfig) {
-			return nil
-		}
 		configAlias := parentAlias + "-" + d.Authenticator + "-config"
-		if liveCfg.Alias != nil && *liveCfg.Alias != "" {
-			configAlias = *liveCfg.Alias
+		if l.ConfigAlias != "" {
+			configAlias = l.ConfigAlias
 		}
 		update := keycloak.AuthenticatorConfigRepresentation{
 			ID:     &l.AuthenticationConfig,
@@ -745,9 +886,8 @@ func (r *KeycloakAuthenticationFlowReconciler) reconcileLeafConfig(
 		if err := kc.UpdateExecutionConfig(ctx, realmName, l.AuthenticationConfig, update); err != nil {
 			return fmt.Errorf("updating config on execution %q in flow %q: %w", d.Authenticator, parentAlias, err)
 		}
-		stats.updated++
-		return nil
 	}
+	return nil
 }
 
 func configMapsEqual(a, b map[string]string) bool {
@@ -776,7 +916,7 @@ func liveIdentity(l liveExecution) string {
 // top-level flow ID stable, which is necessary for flows referenced as a
 // sub-flow execution by another flow or as a realm binding override.
 func (r *KeycloakAuthenticationFlowReconciler) updateExistingFlow(
-	ctx context.Context, kc *keycloak.Client, realmName string,
+	ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName string,
 	flow *keycloakv1beta1.KeycloakAuthenticationFlow, existingFlowID string, executions []flowExecution,
 ) (*updateStats, error) {
 	flows, err := kc.GetAuthenticationFlows(ctx, realmName)
@@ -827,7 +967,7 @@ func (r *KeycloakAuthenticationFlowReconciler) updateExistingFlow(
 		return nil, fmt.Errorf("reading live execution tree for flow %q: %w", flow.Spec.Alias, err)
 	}
 
-	if err := r.reconcileChildren(ctx, kc, realmName, flow.Spec.Alias, executions, liveTree, stats); err != nil {
+	if err := r.reconcileChildren(ctx, kc, caps, realmName, flow.Spec.Alias, executions, liveTree, stats); err != nil {
 		return nil, err
 	}
 	return stats, nil
@@ -837,19 +977,19 @@ func (r *KeycloakAuthenticationFlowReconciler) deleteFlow(ctx context.Context, f
 	if flow.Status.FlowID == "" {
 		return nil
 	}
-	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, flow)
+	kc, realmName, _, err := r.getKeycloakClientAndRealm(ctx, flow)
 	if err != nil {
 		return err
 	}
 	return kc.DeleteAuthenticationFlow(ctx, realmName, flow.Status.FlowID)
 }
 
-func (r *KeycloakAuthenticationFlowReconciler) getKeycloakClientAndRealm(ctx context.Context, flow *keycloakv1beta1.KeycloakAuthenticationFlow) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, flow.Namespace, flow.Spec.RealmRef, flow.Spec.ClusterRealmRef)
+func (r *KeycloakAuthenticationFlowReconciler) getKeycloakClientAndRealm(ctx context.Context, flow *keycloakv1beta1.KeycloakAuthenticationFlow) (*keycloak.Client, string, *keycloak.Capabilities, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, flow, flow.Spec.RealmRef, flow.Spec.ClusterRealmRef)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
-	return res.Client, res.RealmName, nil
+	return res.Client, res.RealmName, res.Capabilities, nil
 }
 
 func (r *KeycloakAuthenticationFlowReconciler) updateStatus(ctx context.Context, flow *keycloakv1beta1.KeycloakAuthenticationFlow, ready bool, status, message, flowID, realmName string) (ctrl.Result, error) {
@@ -861,10 +1001,6 @@ func (r *KeycloakAuthenticationFlowReconciler) updateStatus(ctx context.Context,
 		flow.Status.ResourcePath = fmt.Sprintf("/admin/realms/%s/authentication/flows/%s", realmName, flowID)
 	}
 
-	if ready {
-		flow.Status.ObservedGeneration = flow.Generation
-	}
-
 	flow.Status.Conditions = setReadyCondition(flow.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, flow, ready)
@@ -874,5 +1010,15 @@ func (r *KeycloakAuthenticationFlowReconciler) updateStatus(ctx context.Context,
 func (r *KeycloakAuthenticationFlowReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakAuthenticationFlow{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakAuthenticationFlowList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakAuthenticationFlowList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
+	"github.com/go-logr/logr/testr"
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
 )
 
 func TestFilterTopLevelExecutions(t *testing.T) {
@@ -264,6 +268,19 @@ func TestParseExecutionsRejectsInvalidShape(t *testing.T) {
 	}
 }
 
+func TestParseExecutionsErrorPath(t *testing.T) {
+	_, err := parseExecutions(rawExt(t, `[{"authenticator":"x","requirement":"REQUIRED"},{"subFlow":{"alias":"a","providerId":"basic-flow","executions":[{"authenticator":"y"}]},"requirement":"REQUIRED"}]`))
+	var execErr *executionError
+	require.ErrorAs(t, err, &execErr)
+	require.Equal(t, "[1].executions[0]", execErr.Path)
+	require.Equal(t, "requirement is required", execErr.Problem)
+	require.EqualError(t, err, "spec.executions[1].executions[0]: requirement is required")
+
+	_, err = parseExecutions(runtime.RawExtension{Raw: []byte(`{}`)})
+	require.ErrorAs(t, err, &execErr)
+	require.Empty(t, execErr.Path)
+}
+
 func TestParseExecutionsEmpty(t *testing.T) {
 	got, err := parseExecutions(runtime.RawExtension{})
 	require.NoError(t, err)
@@ -343,6 +360,79 @@ func TestMatchExecutions(t *testing.T) {
 	})
 }
 
+func TestDiffExecutions(t *testing.T) {
+	withPriority := keycloak.CapabilitiesFromStatus("26.0.0", nil, false)
+	withoutPriority := keycloak.CapabilitiesFromStatus("24.0.5", nil, false)
+	leaf := func(name, requirement string) flowExecution {
+		return flowExecution{Authenticator: name, Requirement: requirement}
+	}
+	liveLeaf := func(id, name, requirement string, priority int) liveExecution {
+		return liveExecution{ID: id, Authenticator: name, Requirement: requirement, Priority: priority}
+	}
+	describe := func(changes []executionChange) []string {
+		out := []string{}
+		for _, c := range changes {
+			out = append(out, c.String())
+		}
+		return out
+	}
+
+	t.Run("in sync", func(t *testing.T) {
+		desired := []flowExecution{leaf("auth-cookie", "ALTERNATIVE"), leaf("auth-otp-form", "REQUIRED")}
+		live := []liveExecution{liveLeaf("1", "auth-cookie", "ALTERNATIVE", 10), liveLeaf("2", "auth-otp-form", "REQUIRED", 20)}
+		require.Empty(t, diffExecutions(withPriority, "browser", desired, live))
+	})
+
+	t.Run("changes in reconcile order", func(t *testing.T) {
+		desired := []flowExecution{
+			leaf("auth-cookie", "REQUIRED"),
+			{Authenticator: "auth-otp-form", Requirement: "REQUIRED", AuthenticatorConfig: map[string]string{"a": "2"}},
+			{SubFlow: &flowDefinition{Alias: "forms", ProviderID: "basic-flow"}, Requirement: "ALTERNATIVE",
+				Executions: []flowExecution{leaf("auth-username-password-form", "REQUIRED")}},
+		}
+		otp := liveLeaf("2", "auth-otp-form", "REQUIRED", 20)
+		otp.AuthenticationConfig, otp.Config = "cfg", map[string]string{"a": "1"}
+		live := []liveExecution{
+			liveLeaf("1", "auth-cookie", "ALTERNATIVE", 10),
+			otp,
+			liveLeaf("3", "identity-provider-redirector", "ALTERNATIVE", 30),
+		}
+		require.Equal(t, []string{
+			`remove authenticator "identity-provider-redirector" from flow "browser"`,
+			`set requirement of authenticator "auth-cookie" in flow "browser" to REQUIRED`,
+			`update config of authenticator "auth-otp-form" in flow "browser"`,
+			`add sub-flow "forms" to flow "browser"`,
+			`reorder executions of flow "browser"`,
+		}, describe(diffExecutions(withPriority, "browser", desired, live)))
+
+		// Without priorities, executions appended in order need no reorder.
+		require.NotContains(t, describe(diffExecutions(withoutPriority, "browser", desired, live)), `reorder executions of flow "browser"`)
+	})
+
+	t.Run("matched sub-flows are diffed recursively", func(t *testing.T) {
+		desired := []flowExecution{{SubFlow: &flowDefinition{Alias: "forms", ProviderID: "basic-flow", Executions: []flowExecution{
+			leaf("auth-username-password-form", "REQUIRED"),
+		}}, Requirement: "ALTERNATIVE"}}
+		live := []liveExecution{{ID: "1", IsFlow: true, SubFlowAlias: "forms", Requirement: "ALTERNATIVE", Priority: 10, Children: []liveExecution{
+			liveLeaf("2", "auth-username-password-form", "ALTERNATIVE", 10),
+		}}}
+		require.Equal(t, []string{
+			`set requirement of authenticator "auth-username-password-form" in flow "forms" to REQUIRED`,
+		}, describe(diffExecutions(withPriority, "browser", desired, live)))
+	})
+
+	t.Run("order", func(t *testing.T) {
+		desired := []flowExecution{leaf("auth-cookie", "ALTERNATIVE"), leaf("auth-otp-form", "REQUIRED")}
+		swapped := []liveExecution{liveLeaf("2", "auth-otp-form", "REQUIRED", 10), liveLeaf("1", "auth-cookie", "ALTERNATIVE", 20)}
+		require.Equal(t, []string{`reorder executions of flow "browser"`}, describe(diffExecutions(withoutPriority, "browser", desired, swapped)))
+
+		// Tied priorities leave the order to chance until they are set.
+		unset := []liveExecution{liveLeaf("1", "auth-cookie", "ALTERNATIVE", 0), liveLeaf("2", "auth-otp-form", "REQUIRED", 0)}
+		require.Equal(t, []string{`reorder executions of flow "browser"`}, describe(diffExecutions(withPriority, "browser", desired, unset)))
+		require.Empty(t, diffExecutions(withoutPriority, "browser", desired, unset))
+	})
+}
+
 func TestDesiredIdentifier(t *testing.T) {
 	tests := []struct {
 		name string
@@ -386,3 +476,41 @@ func TestConfigMapsEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestReorderChildrenCapabilityGate(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+
+	r := &KeycloakAuthenticationFlowReconciler{}
+	caps := keycloak.CapabilitiesFromStatus("24.0.5", nil, false)
+	execs := []flowExecution{
+		{Authenticator: "auth-cookie", Requirement: "ALTERNATIVE"},
+		{Authenticator: "auth-otp-form", Requirement: "REQUIRED"},
+	}
+	flow := &keycloakv1beta1.KeycloakAuthenticationFlow{
+		Spec: keycloakv1beta1.KeycloakAuthenticationFlowSpec{Alias: "gated", ProviderId: "basic-flow"},
+	}
+
+	// Executions are appended in order, so creation needs no reordering.
+	_, err := r.createFlowTree(ctx, kc, caps, "test", flow, execs)
+	require.NoError(t, err)
+
+	changed, err := r.reorderChildren(ctx, kc, caps, "test", "gated", execs)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// A different order cannot be applied without priorities; no PUT is sent.
+	puts := srv.Calls("PUT", "/admin/realms/test/authentication/flows/gated/executions")
+	reversed := []flowExecution{execs[1], execs[0]}
+	_, err = r.reorderChildren(ctx, kc, caps, "test", "gated", reversed)
+	require.Error(t, err)
+	require.True(t, keycloak.IsUnsupported(err))
+	require.Equal(t, puts, srv.Calls("PUT", "/admin/realms/test/authentication/flows/gated/executions"))
+}
@@ -14,8 +14,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
@@ -58,24 +60,15 @@ func (r *KeycloakClientReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		RecordReconcile(controllerName, kcClient.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, kcClient); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !kcClient.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(kcClient, FinalizerName) {
-			// Delete client from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(kcClient) {
-				log.Info("preserving client in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteClient(ctx, kcClient); err != nil {
-				log.Error(err, "failed to delete client from Keycloak")
-				// Continue with finalizer removal even on error
-			}
-
-			// Remove finalizer
-			controllerutil.RemoveFinalizer(kcClient, FinalizerName)
-			if err := r.Update(ctx, kcClient); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, kcClient, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteClient(ctx, kcClient)
+		})
 	}
 
 	// Add finalizer if not present
@@ -91,38 +84,21 @@ func (r *KeycloakClientReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	kc, realmName, instanceRef, realmRef, err := r.getKeycloakClientAndRealm(ctx, kcClient)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, kcClient, false, "RealmNotReady", err.Error(), "", instanceRef, realmRef)
+		return r.updateStatus(ctx, kcClient, false, notReadyReason(err, "RealmNotReady"), err.Error(), "", instanceRef, realmRef)
 	}
 
-	// Parse client definition to extract clientId
-	var clientDef struct {
-		ID       string `json:"id,omitempty"`
-		ClientID string `json:"clientId,omitempty"`
-	}
-	if kcClient.Spec.Definition != nil {
-		if err := json.Unmarshal(kcClient.Spec.Definition.Raw, &clientDef); err != nil {
-			RecordError(controllerName, "invalid_definition")
-			return r.updateStatus(ctx, kcClient, false, "InvalidDefinition", fmt.Sprintf("Failed to parse client definition: %v", err), "", instanceRef, realmRef)
-		}
-	}
-
-	// Protocol mappers have their own CRD. Keycloak does honour them on the client
-	// PUT, but allowing both homes would let a KeycloakProtocolMapper and this
-	// definition key fight over the same mapper.
-	if kcClient.Spec.Definition != nil {
-		if err := rejectDefinitionKey(kcClient.Spec.Definition.Raw, "protocolMappers", "KeycloakProtocolMapper"); err != nil {
-			RecordError(controllerName, "unsupported_definition_field")
-			return r.updateStatus(ctx, kcClient, false, UnsupportedDefinitionFieldReason, err.Error(), "", instanceRef, realmRef)
-		}
+	defClientID, specErr := checkClientSpec(kcClient)
+	if specErr != nil {
+		RecordError(controllerName, specErr.metric)
+		return r.updateStatus(ctx, kcClient, false, specErr.reason, specErr.Error(), "", instanceRef, realmRef)
 	}
 
 	// Resolve the clientId from spec.clientId.
-	resolvedClientID, err := resolveIdentifier("clientId", kcClient.Spec.ClientId, clientDef.ClientID)
+	resolvedClientID, err := resolveIdentifierWithPolicy(ctx, r.Client, kcClient, "clientId", kcClient.Spec.ClientId, defClientID)
 	if err != nil {
 		RecordError(controllerName, "invalid_identifier")
 		return r.updateStatus(ctx, kcClient, false, InvalidIdentifierReason, err.Error(), "", instanceRef, realmRef)
 	}
-	clientDef.ClientID = resolvedClientID
 	if err := persistResolvedIdentifier(ctx, r.Client, kcClient, &kcClient.Status.ClientID, resolvedClientID); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -135,7 +111,7 @@ func (r *KeycloakClientReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if definition == nil {
 		definition = []byte("{}")
 	}
-	definition = setFieldInDefinition(definition, "clientId", clientDef.ClientID)
+	definition = setFieldInDefinition(definition, "clientId", resolvedClientID)
 
 	// Handle client secret - check if we should use a pre-existing secret
 	var preExistingSecret string
@@ -171,18 +147,22 @@ func (r *KeycloakClientReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	}
 
 	// Check if client exists
-	existingClient, err := kc.GetClientByClientID(ctx, realmName, clientDef.ClientID)
+	existingClient, err := kc.GetClientByClientID(ctx, realmName, resolvedClientID)
 
 	var clientUUID string
 	if err != nil {
 		// Client doesn't exist, create it
-		log.Info("creating client", "clientId", clientDef.ClientID, "realm", realmName)
+		if err := checkQuota(ctx, r.Client, kcClient); err != nil {
+			RecordError(controllerName, "quota_exceeded")
+			return r.updateStatus(ctx, kcClient, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "", instanceRef, realmRef)
+		}
+		log.Info("creating client", "clientId", resolvedClientID, "realm", realmName)
 		clientUUID, err = kc.CreateClient(ctx, realmName, definition)
 		if err != nil {
 			RecordError(controllerName, "keycloak_api_error")
 			return r.updateStatus(ctx, kcClient, false, "CreateFailed", fmt.Sprintf("Failed to create client: %v", err), "", instanceRef, realmRef)
 		}
-		log.Info("client created successfully", "clientId", clientDef.ClientID, "uuid", clientUUID)
+		log.Info("client created successfully", "clientId", resolvedClientID, "uuid", clientUUID)
 	} else {
 		// Client exists — check if update is needed
 		clientUUID = *existingClient.ID
@@ -199,14 +179,14 @@ func (r *KeycloakClientReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 
 		if needsUpdate {
-			log.Info("updating client", "clientId", clientDef.ClientID, "realm", realmName)
+			log.Info("updating client", "clientId", resolvedClientID, "realm", realmName)
 			if err := kc.UpdateClient(ctx, realmName, clientUUID, definition); err != nil {
 				RecordError(controllerName, "keycloak_api_error")
 				return r.updateStatus(ctx, kcClient, false, "UpdateFailed", fmt.Sprintf("Failed to update client: %v", err), clientUUID, instanceRef, realmRef)
 			}
-			log.Info("client updated successfully", "clientId", clientDef.ClientID)
+			log.Info("client updated successfully", "clientId", resolvedClientID)
 		} else {
-			log.V(1).Info("client already in sync, skipping update", "clientId", clientDef.ClientID)
+			log.V(1).Info("client already in sync, skipping update", "clientId", resolvedClientID)
 		}
 	}
 
@@ -242,7 +222,7 @@ func (r *KeycloakClientReconciler) getKeycloakClientAndRealm(ctx context.Context
 		realmRef.RealmRef = fmt.Sprintf("%s/%s", kcClient.Namespace, kcClient.Spec.RealmRef.Name)
 	}
 
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, kcClient.Namespace, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, kcClient, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", instanceRef, realmRef, err
 	}
@@ -339,8 +319,8 @@ func (r *KeycloakClientReconciler) syncClientSecret(ctx context.Context, kcClien
 		return fmt.Errorf("failed to get client secret: %w", err)
 	}
 
-	// Use the clientId from spec.clientId.
-	clientId := identifierValue(kcClient.Spec.ClientId)
+	// Use the clientId last synchronized to Keycloak.
+	clientId := syncedIdentifier(kcClient.Status.ClientID, kcClient.Spec.ClientId)
 
 	// Determine secret keys
 	clientIdKey := "client-id"
@@ -506,20 +486,24 @@ func (r *KeycloakClientReconciler) deleteClient(ctx context.Context, kcClient *k
 		return err
 	}
 
-	// Use the clientId from spec.clientId. Empty means never synchronized
-	// (unmigrated object); an empty search term would match arbitrary clients.
-	clientId := identifierValue(kcClient.Spec.ClientId)
+	// Use the clientId last synchronized to Keycloak. Empty means never
+	// synchronized (unmigrated object); an empty search term would match
+	// arbitrary clients.
+	clientId := syncedIdentifier(kcClient.Status.ClientID, kcClient.Spec.ClientId)
 	if clientId == "" {
 		return nil
 	}
 
 	// Find client by clientId
-	existingClient, err := kc.GetClientByClientID(ctx, realmName, clientId)
+	clients, err := kc.GetClients(ctx, realmName, map[string]string{"clientId": clientId})
 	if err != nil {
+		return err
+	}
+	if len(clients) == 0 {
 		return nil // Client doesn't exist
 	}
 
-	return kc.DeleteClient(ctx, realmName, *existingClient.ID)
+	return kc.DeleteClient(ctx, realmName, *clients[0].ID)
 }
 
 func (r *KeycloakClientReconciler) updateStatus(ctx context.Context, kcClient *keycloakv1beta1.KeycloakClient, ready bool, status, message, clientUUID string, instanceRef *keycloakv1beta1.InstanceRef, realmRef *keycloakv1beta1.RealmRef) (ctrl.Result, error) {
@@ -530,11 +514,6 @@ func (r *KeycloakClientReconciler) updateStatus(ctx context.Context, kcClient *k
 	kcClient.Status.Instance = instanceRef
 	kcClient.Status.Realm = realmRef
 
-	// Track observed generation to detect spec changes
-	if ready {
-		kcClient.Status.ObservedGeneration = kcClient.Generation
-	}
-
 	kcClient.Status.Conditions = setReadyCondition(kcClient.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, kcClient, ready)
@@ -694,10 +673,42 @@ func toObjectSlice(v interface{}) ([]map[string]interface{}, bool) {
 	return result, true
 }
 
+// checkClientSpec makes the checks on kcClient's spec that need no Keycloak
+// call and returns the clientId found in spec.definition.
+func checkClientSpec(kcClient *keycloakv1beta1.KeycloakClient) (string, *specError) {
+	if kcClient.Spec.Definition == nil {
+		return "", nil
+	}
+	var def struct {
+		ClientID string `json:"clientId,omitempty"`
+	}
+	if err := json.Unmarshal(kcClient.Spec.Definition.Raw, &def); err != nil {
+		return "", invalidDefinition(fmt.Errorf("Failed to parse client definition: %w", err))
+	}
+
+	// Protocol mappers have their own CRD. Keycloak does honour them on the client
+	// PUT, but allowing both homes would let a KeycloakProtocolMapper and this
+	// definition key fight over the same mapper.
+	if err := rejectDefinitionKey(kcClient.Spec.Definition.Raw, "protocolMappers", "KeycloakProtocolMapper"); err != nil {
+		return "", unsupportedDefinitionField(err)
+	}
+	return def.ClientID, nil
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *KeycloakClientReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakClient{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakClientList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakClientList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Owns(&corev1.Secret{}).
 		Complete(r)
 }
@@ -9,8 +9,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
@@ -51,22 +53,15 @@ func (r *KeycloakClientScopeReconciler) Reconcile(ctx context.Context, req ctrl.
 		RecordReconcile(controllerName, clientScope.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, clientScope); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !clientScope.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(clientScope, FinalizerName) {
-			// Delete client scope from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(clientScope) {
-				log.Info("preserving client scope in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteClientScope(ctx, clientScope); err != nil {
-				log.Error(err, "failed to delete client scope from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(clientScope, FinalizerName)
-			if err := r.Update(ctx, clientScope); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, clientScope, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteClientScope(ctx, clientScope)
+		})
 	}
 
 	// Add finalizer if not present
@@ -82,43 +77,32 @@ func (r *KeycloakClientScopeReconciler) Reconcile(ctx context.Context, req ctrl.
 	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, clientScope)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, clientScope, false, "RealmNotReady", err.Error(), "")
+		return r.updateStatus(ctx, clientScope, false, notReadyReason(err, "RealmNotReady"), err.Error(), "")
 	}
 
-	// Parse client scope definition to extract name
-	var scopeDef struct {
-		Name string `json:"name"`
-	}
-	if err := json.Unmarshal(clientScope.Spec.Definition.Raw, &scopeDef); err != nil {
-		RecordError(controllerName, "invalid_definition")
-		return r.updateStatus(ctx, clientScope, false, "InvalidDefinition", fmt.Sprintf("Failed to parse client scope definition: %v", err), "")
-	}
-
-	// Keycloak's client scope PUT silently discards protocolMappers, so they are
-	// only ever managed through KeycloakProtocolMapper.
-	if err := rejectDefinitionKey(clientScope.Spec.Definition.Raw, "protocolMappers", "KeycloakProtocolMapper"); err != nil {
-		RecordError(controllerName, "unsupported_definition_field")
-		return r.updateStatus(ctx, clientScope, false, UnsupportedDefinitionFieldReason, err.Error(), "")
+	defName, specErr := checkClientScopeSpec(clientScope)
+	if specErr != nil {
+		RecordError(controllerName, specErr.metric)
+		return r.updateStatus(ctx, clientScope, false, specErr.reason, specErr.Error(), "")
 	}
 
 	// Resolve the client scope name from spec.name.
-	scopeName, err := resolveIdentifier("name", clientScope.Spec.Name, scopeDef.Name)
+	scopeName, err := resolveIdentifierWithPolicy(ctx, r.Client, clientScope, "name", clientScope.Spec.Name, defName)
 	if err != nil {
 		RecordError(controllerName, "invalid_identifier")
 		return r.updateStatus(ctx, clientScope, false, InvalidIdentifierReason, err.Error(), "")
 	}
-	scopeDef.Name = scopeName
 	clientScope.Status.ClientScopeName = scopeName
 
 	// Prepare definition JSON with name set
-	definition := setFieldInDefinition(clientScope.Spec.Definition.Raw, "name", scopeDef.Name)
+	definition := setFieldInDefinition(clientScope.Spec.Definition.Raw, "name", scopeName)
 
 	// Check if client scope exists by name
 	existingScopes, err := kc.GetClientScopes(ctx, realmName)
 	var existingScope *keycloak.ClientScopeRepresentation
 	if err == nil {
 		for i := range existingScopes {
-			if existingScopes[i].Name != nil && *existingScopes[i].Name == scopeDef.Name {
+			if existingScopes[i].Name != nil && *existingScopes[i].Name == scopeName {
 				existingScope = &existingScopes[i]
 				break
 			}
@@ -128,24 +112,24 @@ func (r *KeycloakClientScopeReconciler) Reconcile(ctx context.Context, req ctrl.
 	var scopeID string
 	if existingScope == nil {
 		// Client scope doesn't exist, create it
-		log.Info("creating client scope", "name", scopeDef.Name, "realm", realmName)
+		log.Info("creating client scope", "name", scopeName, "realm", realmName)
 		scopeID, err = kc.CreateClientScope(ctx, realmName, definition)
 		if err != nil {
 			RecordError(controllerName, "keycloak_api_error")
 			return r.updateStatus(ctx, clientScope, false, "CreateFailed", fmt.Sprintf("Failed to create client scope: %v", err), "")
 		}
-		log.Info("client scope created successfully", "name", scopeDef.Name, "id", scopeID)
+		log.Info("client scope created successfully", "name", scopeName, "id", scopeID)
 	} else {
 		// Client scope exists, update it
 		scopeID = *existingScope.ID
 		definition = mergeIDIntoDefinition(definition, existingScope.ID)
 
-		log.Info("updating client scope", "name", scopeDef.Name, "realm", realmName)
+		log.Info("updating client scope", "name", scopeName, "realm", realmName)
 		if err := kc.UpdateClientScope(ctx, realmName, scopeID, definition); err != nil {
 			RecordError(controllerName, "keycloak_api_error")
 			return r.updateStatus(ctx, clientScope, false, "UpdateFailed", fmt.Sprintf("Failed to update client scope: %v", err), scopeID)
 		}
-		log.Info("client scope updated successfully", "name", scopeDef.Name)
+		log.Info("client scope updated successfully", "name", scopeName)
 	}
 
 	// Update status
@@ -154,7 +138,7 @@ func (r *KeycloakClientScopeReconciler) Reconcile(ctx context.Context, req ctrl.
 }
 
 func (r *KeycloakClientScopeReconciler) getKeycloakClientAndRealm(ctx context.Context, clientScope *keycloakv1beta1.KeycloakClientScope) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, clientScope.Namespace, clientScope.Spec.RealmRef, clientScope.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, clientScope, clientScope.Spec.RealmRef, clientScope.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
@@ -167,9 +151,9 @@ func (r *KeycloakClientScopeReconciler) deleteClientScope(ctx context.Context, c
 		return err
 	}
 
-	// Use spec.name so deletion targets the synchronized scope. Empty means
-	// never synchronized (unmigrated object).
-	scopeName := identifierValue(clientScope.Spec.Name)
+	// Use the synchronized name so deletion targets the right scope. Empty
+	// means never synchronized (unmigrated object).
+	scopeName := syncedIdentifier(clientScope.Status.ClientScopeName, clientScope.Spec.Name)
 	if scopeName == "" {
 		return nil
 	}
@@ -199,9 +183,37 @@ func (r *KeycloakClientScopeReconciler) updateStatus(ctx context.Context, client
 	return writeStatusIfChanged(ctx, r.Client, clientScope, ready)
 }
 
+// checkClientScopeSpec makes the checks on clientScope's spec that need no
+// Keycloak call and returns the name found in spec.definition.
+func checkClientScopeSpec(clientScope *keycloakv1beta1.KeycloakClientScope) (string, *specError) {
+	var def struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(clientScope.Spec.Definition.Raw, &def); err != nil {
+		return "", invalidDefinition(fmt.Errorf("Failed to parse client scope definition: %w", err))
+	}
+
+	// Keycloak's client scope PUT silently discards protocolMappers, so they are
+	// only ever managed through KeycloakProtocolMapper.
+	if err := rejectDefinitionKey(clientScope.Spec.Definition.Raw, "protocolMappers", "KeycloakProtocolMapper"); err != nil {
+		return "", unsupportedDefinitionField(err)
+	}
+	return def.Name, nil
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *KeycloakClientScopeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakClientScope{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakClientScopeList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakClientScopeList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -71,22 +72,15 @@ func (r *KeycloakComponentReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		RecordReconcile(controllerName, component.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, component); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !component.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(component, FinalizerName) {
-			// Delete component from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(component) {
-				log.Info("preserving component in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteComponent(ctx, component); err != nil {
-				log.Error(err, "failed to delete component from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(component, FinalizerName)
-			if err := r.Update(ctx, component); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, component, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteComponent(ctx, component)
+		})
 	}
 
 	// Add finalizer if not present
@@ -102,7 +96,7 @@ func (r *KeycloakComponentReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	kc, realmName, realmID, err := r.getKeycloakClientAndRealm(ctx, component)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, component, false, "RealmNotReady", err.Error(), "", "", "")
+		return r.updateStatus(ctx, component, false, notReadyReason(err, "RealmNotReady"), err.Error(), "", "", "")
 	}
 
 	// Parse component definition to extract identity fields
@@ -275,7 +269,7 @@ func (c componentIdentity) isDeclarativeUserProfile() bool {
 }
 
 func (r *KeycloakComponentReconciler) getKeycloakClientAndRealm(ctx context.Context, component *keycloakv1beta1.KeycloakComponent) (*keycloak.Client, string, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, component.Namespace, component.Spec.RealmRef, component.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, component, component.Spec.RealmRef, component.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -333,10 +327,6 @@ func (r *KeycloakComponentReconciler) updateStatus(ctx context.Context, componen
 	component.Status.ComponentName = componentName
 	component.Status.ProviderType = providerType
 
-	if ready {
-		component.Status.ObservedGeneration = component.Generation
-	}
-
 	component.Status.Conditions = setReadyCondition(component.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, component, ready)
@@ -346,6 +336,16 @@ func (r *KeycloakComponentReconciler) updateStatus(ctx context.Context, componen
 func (r *KeycloakComponentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakComponent{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakComponentList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakComponentList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.findComponentsForSecret),
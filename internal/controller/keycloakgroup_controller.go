@@ -10,8 +10,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
@@ -52,22 +54,15 @@ func (r *KeycloakGroupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		RecordReconcile(controllerName, group.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, group); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !group.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(group, FinalizerName) {
-			// Delete group from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(group) {
-				log.Info("preserving group in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteGroup(ctx, group); err != nil {
-				log.Error(err, "failed to delete group from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(group, FinalizerName)
-			if err := r.Update(ctx, group); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, group, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteGroup(ctx, group)
+		})
 	}
 
 	// Add finalizer if not present
@@ -80,12 +75,13 @@ func (r *KeycloakGroupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	// Get Keycloak client and realm info
-	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, group)
+	kc, realmName, caps, err := r.getKeycloakClientAndRealm(ctx, group)
 	if err != nil {
 		reason, metric := "RealmNotReady", "realm_not_ready"
 		if group.Spec.ParentGroupRef != nil {
 			reason, metric = "ParentNotReady", "parent_not_ready"
 		}
+		reason = notReadyReason(err, reason)
 		RecordError(controllerName, metric)
 		return r.updateStatus(ctx, group, false, reason, err.Error(), "")
 	}
@@ -100,7 +96,7 @@ func (r *KeycloakGroupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	// Resolve the group name from spec.name.
-	groupName, err := resolveIdentifier("name", group.Spec.Name, groupDef.Name)
+	groupName, err := resolveIdentifierWithPolicy(ctx, r.Client, group, "name", group.Spec.Name, groupDef.Name)
 	if err != nil {
 		RecordError(controllerName, "invalid_identifier")
 		return r.updateStatus(ctx, group, false, InvalidIdentifierReason, err.Error(), "")
@@ -133,10 +129,7 @@ func (r *KeycloakGroupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	// realm-wide /groups response, so we cannot rely on a recursive walk.
 	var existingGroup *keycloak.GroupRepresentation
 	if parentGroupID != "" {
-		children, err := kc.GetGroupChildren(ctx, realmName, parentGroupID, map[string]string{
-			"search": groupDef.Name,
-			"exact":  "true",
-		})
+		children, err := listChildGroups(ctx, kc, caps, realmName, parentGroupID, groupDef.Name)
 		if err == nil {
 			existingGroup = findTopLevelGroupByName(children, groupDef.Name)
 		}
@@ -153,6 +146,10 @@ func (r *KeycloakGroupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	var groupID string
 	if existingGroup == nil {
 		// Group doesn't exist, create it
+		if err := checkQuota(ctx, r.Client, group); err != nil {
+			RecordError(controllerName, "quota_exceeded")
+			return r.updateStatus(ctx, group, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "")
+		}
 		log.Info("creating group", "name", groupDef.Name, "realm", realmName)
 
 		if parentGroupID != "" {
@@ -186,6 +183,22 @@ func (r *KeycloakGroupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	return r.updateStatus(ctx, group, true, "Ready", "Group synchronized", groupID)
 }
 
+// listChildGroups returns the children of parentID matching name. Servers
+// without the children endpoint inline them in the parent's subGroups.
+func listChildGroups(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName, parentID, name string) ([]keycloak.GroupRepresentation, error) {
+	if caps.Supports(keycloak.CapabilityGroupChildren) {
+		return kc.GetGroupChildren(ctx, realmName, parentID, map[string]string{
+			"search": name,
+			"exact":  "true",
+		})
+	}
+	parent, err := kc.GetGroup(ctx, realmName, parentID)
+	if err != nil {
+		return nil, err
+	}
+	return parent.SubGroups, nil
+}
+
 // findTopLevelGroupByName returns the first group in the list whose name
 // matches exactly. The list is expected to already be scoped to the right
 // parent (either top-level or a specific parent's children); we deliberately
@@ -206,19 +219,19 @@ func findTopLevelGroupByName(groups []keycloak.GroupRepresentation, name string)
 // than an unbounded walk.
 const maxGroupNestingDepth = 100
 
-func (r *KeycloakGroupReconciler) getKeycloakClientAndRealm(ctx context.Context, group *keycloakv1beta1.KeycloakGroup) (*keycloak.Client, string, error) {
+func (r *KeycloakGroupReconciler) getKeycloakClientAndRealm(ctx context.Context, group *keycloakv1beta1.KeycloakGroup) (*keycloak.Client, string, *keycloak.Capabilities, error) {
 	// A nested group names no realm of its own; it inherits the one carried by the
 	// root of its parent chain.
 	owner, err := resolveGroupRealmOwner(ctx, r.Client, group)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, owner.Namespace, owner.Spec.RealmRef, owner.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, group, owner.Spec.RealmRef, owner.Spec.ClusterRealmRef)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
-	return res.Client, res.RealmName, nil
+	return res.Client, res.RealmName, res.Capabilities, nil
 }
 
 // resolveGroupRealmOwner walks parentGroupRef upwards and returns the ancestor
@@ -257,7 +270,7 @@ func resolveGroupRealmOwner(ctx context.Context, c client.Client, group *keycloa
 }
 
 func (r *KeycloakGroupReconciler) deleteGroup(ctx context.Context, group *keycloakv1beta1.KeycloakGroup) error {
-	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, group)
+	kc, realmName, _, err := r.getKeycloakClientAndRealm(ctx, group)
 	if err != nil {
 		return err
 	}
@@ -286,5 +299,15 @@ func (r *KeycloakGroupReconciler) updateStatus(ctx context.Context, group *keycl
 func (r *KeycloakGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakGroup{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakGroupList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakGroupList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
@@ -2,14 +2,18 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 	"testing"
 
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	keycloakfake "github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
 )
 
 // TestFindTopLevelGroupByName documents that the helper only matches against
@@ -111,3 +115,31 @@ func TestResolveGroupRealmOwner_MissingParentIsReported(t *testing.T) {
 		t.Errorf("error should name the missing parent, got %q", err)
 	}
 }
+
+// TestListChildGroups checks the subgroup lookup on servers with and without
+// the children endpoint.
+func TestListChildGroups(t *testing.T) {
+	for _, version := range []string{"22.0.5", "24.0.5"} {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+			srv := keycloakfake.NewServer(keycloakfake.WithVersion(version))
+			defer srv.Close()
+			kc := keycloak.NewClient(keycloak.Config{
+				BaseURL:  srv.URL,
+				Username: keycloakfake.AdminUsername,
+				Password: keycloakfake.AdminPassword,
+			}, testr.New(t))
+
+			require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+			parentID, err := kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"team"}`))
+			require.NoError(t, err)
+			_, err = kc.CreateChildGroup(ctx, "test", parentID, json.RawMessage(`{"name":"sub"}`))
+			require.NoError(t, err)
+
+			caps := keycloak.CapabilitiesFromStatus(version, nil, false)
+			children, err := listChildGroups(ctx, kc, caps, "test", parentID, "sub")
+			require.NoError(t, err)
+			require.NotNil(t, findTopLevelGroupByName(children, "sub"))
+		})
+	}
+}
@@ -8,9 +8,12 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -57,32 +60,23 @@ func (r *KeycloakIdentityProviderReconciler) Reconcile(ctx context.Context, req
 		RecordReconcile(controllerName, idp.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, idp); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !idp.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(idp, FinalizerName) {
-			// Delete identity provider from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(idp) {
-				log.Info("preserving identity provider in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else {
-				// Best-effort cleanup of the operator-managed token-exchange policy
-				// in realm-management's authz resource server before the IdP itself
-				// goes away. Errors are logged but don't block deletion.
-				if kc, realmName, resolveErr := r.getKeycloakClientAndRealm(ctx, idp); resolveErr == nil {
-					if cleanupErr := r.cleanupTokenExchange(ctx, kc, realmName, idp); cleanupErr != nil {
-						log.Error(cleanupErr, "failed to clean up token-exchange policy")
-					}
-				}
-				if err := r.deleteIdentityProvider(ctx, idp); err != nil {
-					log.Error(err, "failed to delete identity provider from Keycloak")
+		return finalizeDeletion(ctx, r.Client, idp, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			// Best-effort cleanup of the operator-managed token-exchange policy
+			// in realm-management's authz resource server before the IdP itself
+			// goes away. Errors are logged but don't block deletion.
+			if kc, realmName, caps, resolveErr := r.getKeycloakClientAndRealm(ctx, idp); resolveErr == nil && requireTokenExchange(caps) == nil {
+				if cleanupErr := r.cleanupTokenExchange(ctx, kc, realmName, idp); cleanupErr != nil {
+					log.Error(cleanupErr, "failed to clean up token-exchange policy")
 				}
 			}
-
-			controllerutil.RemoveFinalizer(idp, FinalizerName)
-			if err := r.Update(ctx, idp); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+			return r.deleteIdentityProvider(ctx, idp)
+		})
 	}
 
 	// Add finalizer if not present
@@ -95,10 +89,10 @@ func (r *KeycloakIdentityProviderReconciler) Reconcile(ctx context.Context, req
 	}
 
 	// Get Keycloak client and realm info
-	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, idp)
+	kc, realmName, caps, err := r.getKeycloakClientAndRealm(ctx, idp)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, idp, false, "RealmNotReady", err.Error(), "")
+		return r.updateStatus(ctx, idp, false, notReadyReason(err, "RealmNotReady"), err.Error(), "")
 	}
 
 	// Parse identity provider definition to extract alias and reject inline organizationId
@@ -116,7 +110,7 @@ func (r *KeycloakIdentityProviderReconciler) Reconcile(ctx context.Context, req
 	}
 
 	// Resolve the alias from spec.alias.
-	alias, err := resolveIdentifier("alias", idp.Spec.Alias, idpDef.Alias)
+	alias, err := resolveIdentifierWithPolicy(ctx, r.Client, idp, "alias", idp.Spec.Alias, idpDef.Alias)
 	if err != nil {
 		RecordError(controllerName, "invalid_identifier")
 		return r.updateStatus(ctx, idp, false, InvalidIdentifierReason, err.Error(), "")
@@ -151,6 +145,10 @@ func (r *KeycloakIdentityProviderReconciler) Reconcile(ctx context.Context, req
 
 	if err != nil || existingIdp == nil {
 		// Identity provider doesn't exist, create it
+		if err := checkQuota(ctx, r.Client, idp); err != nil {
+			RecordError(controllerName, "quota_exceeded")
+			return r.updateStatus(ctx, idp, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "")
+		}
 		log.Info("creating identity provider", "alias", alias, "realm", realmName)
 		_, err = kc.CreateIdentityProvider(ctx, realmName, definition)
 		if err != nil {
@@ -186,11 +184,25 @@ func (r *KeycloakIdentityProviderReconciler) Reconcile(ctx context.Context, req
 	// Ready=false — the IdP itself is in sync regardless of the TE side, and
 	// flapping the parent Ready bit on transient authz-API hiccups would
 	// cascade into dependent resources (KeycloakIdentityProviderMapper, etc.).
+	var teUnsupported error
 	if idp.Spec.TokenExchange != nil {
-		teStatus, teErr := r.reconcileTokenExchange(ctx, kc, realmName, alias, idp)
+		var teStatus *keycloakv1beta1.IDPTokenExchangeStatus
+		teErr := requireTokenExchange(caps)
+		if teErr == nil {
+			teStatus, teErr = r.reconcileTokenExchange(ctx, kc, realmName, alias, idp)
+		}
 		switch {
 		case teErr == nil:
 			idp.Status.TokenExchange = teStatus
+		case keycloak.IsUnsupported(teErr):
+			// The server lacks token exchange or fine-grained admin permissions;
+			// calling the management endpoints would only produce a 404.
+			log.Info("token-exchange not supported by server", "alias", alias, "reason", teErr.Error())
+			teUnsupported = teErr
+			if idp.Status.TokenExchange == nil {
+				idp.Status.TokenExchange = &keycloakv1beta1.IDPTokenExchangeStatus{}
+			}
+			idp.Status.TokenExchange.Message = fmt.Sprintf("%s: %s", UnsupportedByServerReason, teErr)
 		case IsTokenExchangeWaiting(teErr):
 			// Soft wait — referenced state (typically one of the allowedClients)
 			// isn't there yet. Log at INFO level and surface a friendly status
@@ -209,14 +221,47 @@ func (r *KeycloakIdentityProviderReconciler) Reconcile(ctx context.Context, req
 			idp.Status.TokenExchange.Message = teErr.Error()
 		}
 	}
+	setTokenExchangeCondition(idp, teUnsupported)
 
 	// Update status
 	idp.Status.ResourcePath = fmt.Sprintf("/admin/realms/%s/identity-provider/instances/%s", realmName, alias)
 	return r.updateStatus(ctx, idp, true, "Ready", "Identity provider synchronized", alias)
 }
 
-func (r *KeycloakIdentityProviderReconciler) getKeycloakClientAndRealm(ctx context.Context, idp *keycloakv1beta1.KeycloakIdentityProvider) (*keycloak.Client, string, error) {
-	return GetKeycloakClientAndRealmForIDP(ctx, r.Client, r.ClientManager, idp)
+func (r *KeycloakIdentityProviderReconciler) getKeycloakClientAndRealm(ctx context.Context, idp *keycloakv1beta1.KeycloakIdentityProvider) (*keycloak.Client, string, *keycloak.Capabilities, error) {
+	return GetKeycloakClientAndRealmForIDP(ctx, r.Client, r.ClientManager, idp, idp)
+}
+
+// requireTokenExchange reports whether the server can host the token-exchange
+// permission, which needs both token exchange and fine-grained admin
+// permissions.
+func requireTokenExchange(caps *keycloak.Capabilities) error {
+	if err := caps.Require(keycloak.CapabilityTokenExchange); err != nil {
+		return err
+	}
+	return caps.Require(keycloak.CapabilityAdminFineGrainedAuthz)
+}
+
+// TokenExchangeConditionType is the condition set to False with reason
+// UnsupportedByServer while spec.tokenExchange cannot be applied because the
+// server lacks the features it needs. The identity provider itself stays
+// Ready.
+const TokenExchangeConditionType = "TokenExchange"
+
+// setTokenExchangeCondition sets the TokenExchange condition of idp for the
+// error requireTokenExchange returned, or removes it when unsupported is nil.
+func setTokenExchangeCondition(idp *keycloakv1beta1.KeycloakIdentityProvider, unsupported error) {
+	if unsupported == nil {
+		meta.RemoveStatusCondition(&idp.Status.Conditions, TokenExchangeConditionType)
+		return
+	}
+	meta.SetStatusCondition(&idp.Status.Conditions, metav1.Condition{
+		Type:               TokenExchangeConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             UnsupportedByServerReason,
+		Message:            unsupported.Error(),
+		ObservedGeneration: idp.Generation,
+	})
 }
 
 type organizationRealmMismatchError struct {
@@ -269,14 +314,14 @@ func sameRealmPlacement(idp *keycloakv1beta1.KeycloakIdentityProvider, org *keyc
 }
 
 func (r *KeycloakIdentityProviderReconciler) deleteIdentityProvider(ctx context.Context, idp *keycloakv1beta1.KeycloakIdentityProvider) error {
-	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, idp)
+	kc, realmName, _, err := r.getKeycloakClientAndRealm(ctx, idp)
 	if err != nil {
 		return err
 	}
 
-	// Use spec.alias so deletion targets the synchronized identity provider.
-	// Empty means never synchronized (unmigrated object).
-	alias := identifierValue(idp.Spec.Alias)
+	// Use the synchronized alias so deletion targets the right identity
+	// provider. Empty means never synchronized (unmigrated object).
+	alias := syncedIdentifier(idp.Status.Alias, idp.Spec.Alias)
 	if alias == "" {
 		return nil
 	}
@@ -297,6 +342,16 @@ func (r *KeycloakIdentityProviderReconciler) updateStatus(ctx context.Context, i
 func (r *KeycloakIdentityProviderReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakIdentityProvider{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakIdentityProviderList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakIdentityProviderList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.findIDPsForSecret),
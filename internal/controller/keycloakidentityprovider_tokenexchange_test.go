@@ -6,7 +6,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
@@ -132,3 +135,22 @@ func TestIsTokenExchangeWaiting(t *testing.T) {
 		require.True(t, IsTokenExchangeWaiting(outer))
 	})
 }
+
+func TestSetTokenExchangeCondition(t *testing.T) {
+	idp := &keycloakv1beta1.KeycloakIdentityProvider{}
+	idp.Generation = 3
+
+	// A server that was probed and reported neither feature.
+	unsupported := requireTokenExchange(keycloak.CapabilitiesFromStatus("24.0.5", nil, true))
+	require.True(t, keycloak.IsUnsupported(unsupported))
+	setTokenExchangeCondition(idp, unsupported)
+	cond := meta.FindStatusCondition(idp.Status.Conditions, TokenExchangeConditionType)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, UnsupportedByServerReason, cond.Reason)
+	require.Contains(t, cond.Message, "token-exchange")
+	require.Equal(t, int64(3), cond.ObservedGeneration)
+
+	setTokenExchangeCondition(idp, nil)
+	require.Empty(t, idp.Status.Conditions)
+}
@@ -54,20 +54,14 @@ func (r *KeycloakIdentityProviderMapperReconciler) Reconcile(ctx context.Context
 		RecordReconcile(controllerName, mapper.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
-	if !mapper.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(mapper, FinalizerName) {
-			if ShouldPreserveResource(mapper) {
-				log.Info("preserving identity provider mapper in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteMapper(ctx, mapper); err != nil {
-				log.Error(err, "failed to delete identity provider mapper from Keycloak")
-			}
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, mapper); suspended {
+		return result, err
+	}
 
-			controllerutil.RemoveFinalizer(mapper, FinalizerName)
-			if err := r.Update(ctx, mapper); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+	if !mapper.DeletionTimestamp.IsZero() {
+		return finalizeDeletion(ctx, r.Client, mapper, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteMapper(ctx, mapper)
+		})
 	}
 
 	if !controllerutil.ContainsFinalizer(mapper, FinalizerName) {
@@ -81,7 +75,7 @@ func (r *KeycloakIdentityProviderMapperReconciler) Reconcile(ctx context.Context
 	kc, realmName, alias, err := r.getKeycloakClientAndParent(ctx, mapper)
 	if err != nil {
 		RecordError(controllerName, "parent_not_ready")
-		return r.updateStatus(ctx, mapper, false, "ParentNotReady", err.Error(), "", "", "")
+		return r.updateStatus(ctx, mapper, false, notReadyReason(err, "ParentNotReady"), err.Error(), "", "", "")
 	}
 
 	var mapperDef struct {
@@ -204,7 +198,7 @@ func (r *KeycloakIdentityProviderMapperReconciler) getKeycloakClientAndParent(ct
 		return nil, "", "", fmt.Errorf("KeycloakIdentityProvider %s has no resolved alias yet", idpKey)
 	}
 
-	kc, realmName, err := GetKeycloakClientAndRealmForIDP(ctx, r.Client, r.ClientManager, idp)
+	kc, realmName, _, err := GetKeycloakClientAndRealmForIDP(ctx, r.Client, r.ClientManager, mapper, idp)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -216,10 +210,7 @@ func (r *KeycloakIdentityProviderMapperReconciler) getKeycloakClientAndParent(ct
 // KeycloakIdentityProvider, preferring the resolved status value over
 // spec.alias (a legacy parent may not have re-reconciled yet).
 func identityProviderAlias(idp *keycloakv1beta1.KeycloakIdentityProvider) string {
-	if idp.Status.Alias != "" {
-		return idp.Status.Alias
-	}
-	return identifierValue(idp.Spec.Alias)
+	return syncedIdentifier(idp.Status.Alias, idp.Spec.Alias)
 }
 
 func (r *KeycloakIdentityProviderMapperReconciler) deleteMapper(ctx context.Context, mapper *keycloakv1beta1.KeycloakIdentityProviderMapper) error {
@@ -243,10 +234,6 @@ func (r *KeycloakIdentityProviderMapperReconciler) updateStatus(ctx context.Cont
 	mapper.Status.MapperName = mapperName
 	mapper.Status.IdentityProviderAlias = alias
 
-	if ready {
-		mapper.Status.ObservedGeneration = mapper.Generation
-	}
-
 	mapper.Status.Conditions = setReadyCondition(mapper.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, mapper, ready)
@@ -9,11 +9,15 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
@@ -25,6 +29,9 @@ const (
 
 	// PreserveResourceAnnotation is the annotation that prevents deletion of the resource in Keycloak
 	// when the CR is deleted. Set to "true" to preserve the resource.
+	//
+	// Deprecated: set spec.deletionPolicy to Orphan instead. The annotation is
+	// only honoured on resources that do not set a deletion policy.
 	PreserveResourceAnnotation = "keycloak.hostzero.com/preserve-resource"
 
 	// RequeueDelay is the default requeue delay
@@ -62,6 +69,7 @@ type KeycloakInstanceReconciler struct {
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=keycloakinstances/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=keycloakinstances/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=keycloakreferencegrants,verbs=get;list;watch
 
 // Reconcile handles KeycloakInstance reconciliation
 func (r *KeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -76,6 +84,7 @@ func (r *KeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			// Object deleted, remove client from manager
 			r.ClientManager.RemoveClient(req.String())
 			SetKeycloakConnectionStatus(req.Name, req.Namespace, false)
+			DeleteInstanceHealth(req.Name, req.Namespace)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "unable to fetch KeycloakInstance")
@@ -89,6 +98,10 @@ func (r *KeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		RecordReconcile(controllerName, instance.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, instance); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !instance.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(instance, FinalizerName) {
@@ -115,7 +128,11 @@ func (r *KeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	cfg, err := GetKeycloakConfigFromInstance(ctx, r.Client, instance)
 	if err != nil {
-		return r.updateStatus(ctx, instance, false, "", "Error", err.Error())
+		if IsReferenceNotAllowed(err) {
+			// A revoked grant must also stop the use of cached credentials.
+			r.ClientManager.RemoveClient(req.String())
+		}
+		return r.updateStatus(ctx, instance, false, "", notReadyReason(err, "Error"), err.Error())
 	}
 
 	// Create/get Keycloak client
@@ -129,17 +146,20 @@ func (r *KeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return r.updateStatus(ctx, instance, false, "", "ConnectionFailed", fmt.Sprintf("Failed to connect: %v", err))
 	}
 
-	// Get server version
+	// Probe the server: version, latency, token and certificate expiry
 	version := ""
-	serverInfo, err := kc.GetServerInfo(ctx)
+	health, err := kc.Probe(ctx)
 	if err != nil {
 		log.Error(err, "failed to get server info")
 		RecordError(controllerName, "server_info_failed")
 		return r.updateStatus(ctx, instance, false, "", "ServerInfoFailed", fmt.Sprintf("Failed to get server info: %v", err))
 	}
-	if serverInfo != nil && serverInfo.SystemInfo.Version != "" {
+	serverInfo := health.ServerInfo
+	if serverInfo.SystemInfo.Version != "" {
 		version = serverInfo.SystemInfo.Version
 	}
+	instance.Status.Health = instanceHealthStatus(instance.Status.Health, health, syncInterval(ctx, r.Client, instance))
+	SetInstanceHealth(instance.Name, instance.Namespace, health)
 
 	// Validate Keycloak version
 	if version == "" {
@@ -154,6 +174,11 @@ func (r *KeycloakInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return r.updateStatus(ctx, instance, false, version, "VersionUnsupported", err.Error())
 	}
 
+	// Record which optional Admin API features dependent resources can use
+	caps := keycloak.DetectCapabilities(serverInfo)
+	instance.Status.Capabilities = caps.List()
+	instance.Status.CapabilitiesDetected = caps.Probed()
+
 	// Update connection status metric
 	SetKeycloakConnectionStatus(instance.Name, instance.Namespace, true)
 
@@ -176,12 +201,47 @@ func (r *KeycloakInstanceReconciler) updateStatus(ctx context.Context, instance
 
 // SetupWithManager sets up the controller with the Manager
 func (r *KeycloakInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	breakerChanges := breakerEvents(r.ClientManager, func(key string) client.Object {
+		namespace, name, ok := strings.Cut(key, "/")
+		if !ok || strings.HasPrefix(key, clusterInstanceKey("")) {
+			return nil
+		}
+		return &keycloakv1beta1.KeycloakInstance{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&keycloakv1beta1.KeycloakInstance{}).
+		For(&keycloakv1beta1.KeycloakInstance{}, builder.WithPredicates(specOrAnnotationChanged)).
 		Owns(&corev1.Secret{}).
+		Watches(&keycloakv1beta1.KeycloakReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(findInstancesForGrant(r.Client))).
+		WatchesRawSource(source.Channel(breakerChanges, &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
 
+// instanceHealthStatus converts a probe result to its status representation.
+// The fields that change with every probe, the contact time, the latency and
+// the token expiry, are kept from previous until its contact time is older
+// than interval: a steady instance writes its status once per sync interval
+// rather than on every reconcile. The metrics always get the probe result.
+func instanceHealthStatus(previous *keycloakv1beta1.InstanceHealthStatus, health *keycloak.Health, interval time.Duration) *keycloakv1beta1.InstanceHealthStatus {
+	status := &keycloakv1beta1.InstanceHealthStatus{
+		LastContactTime:     &metav1.Time{Time: time.Now()},
+		LatencyMilliseconds: health.Latency.Milliseconds(),
+		AdminPrincipal:      health.Principal,
+	}
+	if !health.TokenExpiry.IsZero() {
+		status.TokenExpiryTime = &metav1.Time{Time: health.TokenExpiry}
+	}
+	if !health.CertNotAfter.IsZero() {
+		status.CertificateExpiryTime = &metav1.Time{Time: health.CertNotAfter}
+	}
+	if previous != nil && previous.LastContactTime != nil && time.Since(previous.LastContactTime.Time) < interval {
+		status.LastContactTime = previous.LastContactTime
+		status.LatencyMilliseconds = previous.LatencyMilliseconds
+		status.TokenExpiryTime = previous.TokenExpiryTime
+	}
+	return status
+}
+
 // validateKeycloakVersion checks if the Keycloak version is supported
 func validateKeycloakVersion(version string) error {
 	// Parse major version from version string (e.g., "20.0.1", "21.1.2", "24.0.0-SNAPSHOT")
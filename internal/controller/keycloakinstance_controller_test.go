@@ -2,6 +2,12 @@ package controller
 
 import (
 	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
 func TestValidateKeycloakVersion(t *testing.T) {
@@ -44,3 +50,33 @@ func TestValidateKeycloakVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestInstanceHealthStatus(t *testing.T) {
+	probe := func(latency time.Duration, tokenExpiry time.Time) *keycloak.Health {
+		return &keycloak.Health{Latency: latency, TokenExpiry: tokenExpiry, Principal: "admin"}
+	}
+	first := instanceHealthStatus(nil, probe(40*time.Millisecond, time.Now().Add(time.Minute)), time.Hour)
+	if first.LastContactTime == nil || first.LatencyMilliseconds != 40 {
+		t.Fatalf("first probe: got %+v", first)
+	}
+
+	// A probe within the interval must not change the status, or every
+	// reconcile would write it.
+	second := instanceHealthStatus(first, probe(55*time.Millisecond, time.Now().Add(2*time.Minute)), time.Hour)
+	if !equality.Semantic.DeepEqual(first, second) {
+		t.Errorf("probe within the interval changed the status: %+v -> %+v", first, second)
+	}
+
+	// Other fields are not carried over.
+	second = instanceHealthStatus(first, &keycloak.Health{Principal: "service-account-operator"}, time.Hour)
+	if second.AdminPrincipal != "service-account-operator" {
+		t.Errorf("got principal %q, want the probed one", second.AdminPrincipal)
+	}
+
+	stale := first.DeepCopy()
+	stale.LastContactTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	refreshed := instanceHealthStatus(stale, probe(55*time.Millisecond, time.Now().Add(2*time.Minute)), time.Hour)
+	if refreshed.LatencyMilliseconds != 55 || !refreshed.LastContactTime.After(stale.LastContactTime.Time) {
+		t.Errorf("probe after the interval: got %+v, want the probed values", refreshed)
+	}
+}
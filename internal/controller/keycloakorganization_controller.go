@@ -4,26 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
-const (
-	// MinKeycloakVersionForOrganizations is the minimum version that supports organizations
-	MinKeycloakVersionForOrganizations = 26
-)
-
 // KeycloakOrganizationReconciler reconciles a KeycloakOrganization object
 type KeycloakOrganizationReconciler struct {
 	client.Client
@@ -58,22 +53,15 @@ func (r *KeycloakOrganizationReconciler) Reconcile(ctx context.Context, req ctrl
 		RecordReconcile(controllerName, org.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, org); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !org.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(org, FinalizerName) {
-			// Delete organization from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(org) {
-				log.Info("preserving organization in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteOrganization(ctx, org); err != nil {
-				log.Error(err, "failed to delete organization from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(org, FinalizerName)
-			if err := r.Update(ctx, org); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, org, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteOrganization(ctx, org)
+		})
 	}
 
 	// Add finalizer if not present
@@ -85,17 +73,17 @@ func (r *KeycloakOrganizationReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Get Keycloak client, realm info, and version
-	kc, realmName, keycloakVersion, err := r.getKeycloakClientRealmAndVersion(ctx, org)
+	// Get Keycloak client, realm info, and server capabilities
+	kc, realmName, caps, err := r.getKeycloakClientRealmAndCapabilities(ctx, org)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, org, false, "RealmNotReady", err.Error(), "")
+		return r.updateStatus(ctx, org, false, notReadyReason(err, "RealmNotReady"), err.Error(), "")
 	}
 
-	// Check Keycloak version - organizations require >= 26
-	if err := r.checkKeycloakVersionForOrganizations(keycloakVersion); err != nil {
-		RecordError(controllerName, "version_unsupported")
-		return r.updateStatus(ctx, org, false, "VersionUnsupported", err.Error(), "")
+	// Organizations need Keycloak 26+ with the organization feature enabled
+	if err := caps.Require(keycloak.CapabilityOrganizations); err != nil {
+		RecordError(controllerName, "unsupported_by_server")
+		return r.updateStatus(ctx, org, false, UnsupportedByServerReason, err.Error(), "")
 	}
 
 	// Parse organization definition
@@ -174,44 +162,16 @@ func (r *KeycloakOrganizationReconciler) Reconcile(ctx context.Context, req ctrl
 	return r.updateStatus(ctx, org, true, "Ready", "Organization synchronized", orgID)
 }
 
-func (r *KeycloakOrganizationReconciler) checkKeycloakVersionForOrganizations(version string) error {
-	if version == "" {
-		return fmt.Errorf("unable to determine Keycloak version - organizations require Keycloak %d.0.0 or later", MinKeycloakVersionForOrganizations)
-	}
-
-	// Parse major version
-	cleanVersion := version
-	if idx := strings.Index(version, "-"); idx > 0 {
-		cleanVersion = version[:idx]
-	}
-
-	parts := strings.Split(cleanVersion, ".")
-	if len(parts) < 1 {
-		return fmt.Errorf("invalid version format: %s", version)
-	}
-
-	majorVersion, err := strconv.Atoi(parts[0])
+func (r *KeycloakOrganizationReconciler) getKeycloakClientRealmAndCapabilities(ctx context.Context, org *keycloakv1beta1.KeycloakOrganization) (*keycloak.Client, string, *keycloak.Capabilities, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, org, org.Spec.RealmRef, org.Spec.ClusterRealmRef)
 	if err != nil {
-		return fmt.Errorf("invalid major version in %s: %w", version, err)
-	}
-
-	if majorVersion < MinKeycloakVersionForOrganizations {
-		return fmt.Errorf("organizations require Keycloak %d.0.0 or later (detected: %s)", MinKeycloakVersionForOrganizations, version)
+		return nil, "", nil, err
 	}
-
-	return nil
-}
-
-func (r *KeycloakOrganizationReconciler) getKeycloakClientRealmAndVersion(ctx context.Context, org *keycloakv1beta1.KeycloakOrganization) (*keycloak.Client, string, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, org.Namespace, org.Spec.RealmRef, org.Spec.ClusterRealmRef)
-	if err != nil {
-		return nil, "", "", err
-	}
-	return res.Client, res.RealmName, res.Version, nil
+	return res.Client, res.RealmName, res.Capabilities, nil
 }
 
 func (r *KeycloakOrganizationReconciler) deleteOrganization(ctx context.Context, org *keycloakv1beta1.KeycloakOrganization) error {
-	kc, realmName, _, err := r.getKeycloakClientRealmAndVersion(ctx, org)
+	kc, realmName, _, err := r.getKeycloakClientRealmAndCapabilities(ctx, org)
 	if err != nil {
 		return err
 	}
@@ -231,11 +191,6 @@ func (r *KeycloakOrganizationReconciler) updateStatus(ctx context.Context, org *
 		org.Status.OrganizationID = orgID
 	}
 
-	// Track observed generation to detect spec changes
-	if ready {
-		org.Status.ObservedGeneration = org.Generation
-	}
-
 	org.Status.Conditions = setReadyCondition(org.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, org, ready)
@@ -245,5 +200,15 @@ func (r *KeycloakOrganizationReconciler) updateStatus(ctx context.Context, org *
 func (r *KeycloakOrganizationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakOrganization{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakOrganizationList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakOrganizationList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -56,22 +57,15 @@ func (r *KeycloakProtocolMapperReconciler) Reconcile(ctx context.Context, req ct
 		RecordReconcile(controllerName, mapper.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, mapper); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !mapper.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(mapper, FinalizerName) {
-			// Delete protocol mapper from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(mapper) {
-				log.Info("preserving protocol mapper in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteMapper(ctx, mapper); err != nil {
-				log.Error(err, "failed to delete protocol mapper from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(mapper, FinalizerName)
-			if err := r.Update(ctx, mapper); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, mapper, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteMapper(ctx, mapper)
+		})
 	}
 
 	// Add finalizer if not present
@@ -93,7 +87,7 @@ func (r *KeycloakProtocolMapperReconciler) Reconcile(ctx context.Context, req ct
 	kc, realmName, parentType, parentID, err := r.getKeycloakClientAndParent(ctx, mapper)
 	if err != nil {
 		RecordError(controllerName, "parent_not_ready")
-		return r.updateStatus(ctx, mapper, false, "ParentNotReady", err.Error(), "", "", "", "")
+		return r.updateStatus(ctx, mapper, false, notReadyReason(err, "ParentNotReady"), err.Error(), "", "", "", "")
 	}
 
 	// Parse mapper definition to extract name
@@ -147,6 +141,10 @@ func (r *KeycloakProtocolMapperReconciler) Reconcile(ctx context.Context, req ct
 
 	if mapperID == "" {
 		// Create mapper
+		if err := checkQuota(ctx, r.Client, mapper); err != nil {
+			RecordError(controllerName, "quota_exceeded")
+			return r.updateStatus(ctx, mapper, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "", "", parentType, parentID)
+		}
 		log.Info("creating protocol mapper", "name", mapperName, "realm", realmName, "parentType", parentType)
 		var err error
 		if parentType == "client" {
@@ -212,7 +210,7 @@ func (r *KeycloakProtocolMapperReconciler) getFromClient(ctx context.Context, ma
 	}
 
 	// Get realm from client
-	kc, realmName, err := r.getKeycloakClientAndRealmFromClient(ctx, kcClient)
+	kc, realmName, err := r.getKeycloakClientAndRealmFromClient(ctx, mapper, kcClient)
 	if err != nil {
 		return nil, "", "", "", err
 	}
@@ -242,7 +240,7 @@ func (r *KeycloakProtocolMapperReconciler) getFromClientScope(ctx context.Contex
 	}
 
 	// Get realm from scope
-	kc, realmName, err := r.getKeycloakClientAndRealmFromScope(ctx, scope)
+	kc, realmName, err := r.getKeycloakClientAndRealmFromScope(ctx, mapper, scope)
 	if err != nil {
 		return nil, "", "", "", err
 	}
@@ -250,16 +248,21 @@ func (r *KeycloakProtocolMapperReconciler) getFromClientScope(ctx context.Contex
 	return kc, realmName, "clientScope", scopeID, nil
 }
 
-func (r *KeycloakProtocolMapperReconciler) getKeycloakClientAndRealmFromClient(ctx context.Context, kcClient *keycloakv1beta1.KeycloakClient) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, kcClient.Namespace, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
+// getKeycloakClientAndRealmFromClient resolves the realm of the mapper's
+// client. The mapper is the referrer, so allowedNamespaces rules are matched
+// against its own kind rather than the parent's.
+func (r *KeycloakProtocolMapperReconciler) getKeycloakClientAndRealmFromClient(ctx context.Context, mapper *keycloakv1beta1.KeycloakProtocolMapper, kcClient *keycloakv1beta1.KeycloakClient) (*keycloak.Client, string, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, mapper, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
 	return res.Client, res.RealmName, nil
 }
 
-func (r *KeycloakProtocolMapperReconciler) getKeycloakClientAndRealmFromScope(ctx context.Context, scope *keycloakv1beta1.KeycloakClientScope) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, scope.Namespace, scope.Spec.RealmRef, scope.Spec.ClusterRealmRef)
+// getKeycloakClientAndRealmFromScope is getKeycloakClientAndRealmFromClient
+// for mappers of a client scope.
+func (r *KeycloakProtocolMapperReconciler) getKeycloakClientAndRealmFromScope(ctx context.Context, mapper *keycloakv1beta1.KeycloakProtocolMapper, scope *keycloakv1beta1.KeycloakClientScope) (*keycloak.Client, string, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, mapper, scope.Spec.RealmRef, scope.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
@@ -291,10 +294,6 @@ func (r *KeycloakProtocolMapperReconciler) updateStatus(ctx context.Context, map
 	mapper.Status.ParentType = parentType
 	mapper.Status.ParentID = parentID
 
-	if ready {
-		mapper.Status.ObservedGeneration = mapper.Generation
-	}
-
 	mapper.Status.Conditions = setReadyCondition(mapper.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, mapper, ready)
@@ -332,6 +331,16 @@ func extractIDFromPath(path string) string {
 func (r *KeycloakProtocolMapperReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakProtocolMapper{}).
+		Watches(
+			&keycloakv1beta1.KeycloakClient{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForParent(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakProtocolMapperList{} }, "ClientRef")),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.KeycloakClientScope{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForParent(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakProtocolMapperList{} }, "ClientScopeRef")),
+			builder.WithPredicates(becameReady),
+		).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.findMappersForSecret),
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// KeycloakQuotaReconciler reports the usage of a KeycloakQuota. Enforcement
+// happens in the reconcilers of the limited kinds, see checkQuota.
+type KeycloakQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=keycloakquotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=keycloak.hostzero.com,resources=keycloakquotas/status,verbs=get;update;patch
+
+// Reconcile recomputes status.used of a KeycloakQuota
+func (r *KeycloakQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	startTime := time.Now()
+	controllerName := "KeycloakQuota"
+
+	quota := &keycloakv1beta1.KeycloakQuota{}
+	if err := r.Get(ctx, req.NamespacedName, quota); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch KeycloakQuota")
+		RecordReconcile(controllerName, false, time.Since(startTime).Seconds())
+		RecordError(controllerName, "fetch_error")
+		return ctrl.Result{}, err
+	}
+
+	var used keycloakv1beta1.QuotaCounts
+	for _, kind := range quotaKinds {
+		if *kind.field(&quota.Spec.Hard) == nil {
+			continue
+		}
+		count, err := quotaUsage(ctx, r.Client, quota, kind, nil)
+		if err != nil {
+			RecordReconcile(controllerName, false, time.Since(startTime).Seconds())
+			RecordError(controllerName, "usage_error")
+			return ctrl.Result{}, err
+		}
+		*kind.field(&used) = &count
+	}
+
+	if !equality.Semantic.DeepEqual(quota.Status.Used, used) || quota.Status.ObservedGeneration != quota.Generation {
+		quota.Status.Used = used
+		quota.Status.ObservedGeneration = quota.Generation
+		if err := r.Status().Update(ctx, quota); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	RecordReconcile(controllerName, true, time.Since(startTime).Seconds())
+	return ctrl.Result{RequeueAfter: GetSyncPeriod()}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *KeycloakQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&keycloakv1beta1.KeycloakQuota{})
+	for _, kind := range quotaKinds {
+		b = b.Watches(kind.newObject(),
+			handler.EnqueueRequestsFromMapFunc(r.findQuotasForObject),
+			builder.WithPredicates(resourcePathChanged),
+		)
+	}
+	return b.Complete(r)
+}
+
+// findQuotasForObject enqueues every KeycloakQuota in the object's namespace.
+func (r *KeycloakQuotaReconciler) findQuotasForObject(ctx context.Context, obj client.Object) []reconcile.Request {
+	quotas := &keycloakv1beta1.KeycloakQuotaList{}
+	if err := r.List(ctx, quotas, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(quotas.Items))
+	for _, quota := range quotas.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&quota)})
+	}
+	return requests
+}
+
+// resourcePathChanged passes creations, deletions and updates that change
+// Status.ResourcePath, the only events that can change quota usage.
+var resourcePathChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return statusResourcePath(e.ObjectOld) != statusResourcePath(e.ObjectNew)
+	},
+}
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -53,21 +54,15 @@ func (r *KeycloakRequiredActionReconciler) Reconcile(ctx context.Context, req ct
 		RecordReconcile(controllerName, ra.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, ra); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !ra.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(ra, FinalizerName) {
-			if ShouldPreserveResource(ra) {
-				log.Info("preserving required action in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteRequiredAction(ctx, ra); err != nil {
-				log.Error(err, "failed to delete required action from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(ra, FinalizerName)
-			if err := r.Update(ctx, ra); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, ra, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteRequiredAction(ctx, ra)
+		})
 	}
 
 	// Add finalizer
@@ -83,7 +78,7 @@ func (r *KeycloakRequiredActionReconciler) Reconcile(ctx context.Context, req ct
 	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, ra)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, ra, false, "RealmNotReady", err.Error(), "")
+		return r.updateStatus(ctx, ra, false, notReadyReason(err, "RealmNotReady"), err.Error(), "")
 	}
 
 	// Parse definition to extract alias
@@ -179,7 +174,7 @@ func (r *KeycloakRequiredActionReconciler) deleteRequiredAction(ctx context.Cont
 }
 
 func (r *KeycloakRequiredActionReconciler) getKeycloakClientAndRealm(ctx context.Context, ra *keycloakv1beta1.KeycloakRequiredAction) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, ra.Namespace, ra.Spec.RealmRef, ra.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, ra, ra.Spec.RealmRef, ra.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
@@ -192,10 +187,6 @@ func (r *KeycloakRequiredActionReconciler) updateStatus(ctx context.Context, ra
 	ra.Status.Message = message
 	ra.Status.Alias = alias
 
-	if ready {
-		ra.Status.ObservedGeneration = ra.Generation
-	}
-
 	ra.Status.Conditions = setReadyCondition(ra.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, ra, ready)
@@ -205,6 +196,16 @@ func (r *KeycloakRequiredActionReconciler) updateStatus(ctx context.Context, ra
 func (r *KeycloakRequiredActionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakRequiredAction{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakRequiredActionList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakRequiredActionList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.findRequiredActionsForSecret),
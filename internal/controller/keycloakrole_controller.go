@@ -10,8 +10,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
@@ -52,22 +54,15 @@ func (r *KeycloakRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		RecordReconcile(controllerName, role.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, role); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !role.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(role, FinalizerName) {
-			// Delete role from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(role) {
-				log.Info("preserving role in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteRole(ctx, role); err != nil {
-				log.Error(err, "failed to delete role from Keycloak")
-			}
-
-			controllerutil.RemoveFinalizer(role, FinalizerName)
-			if err := r.Update(ctx, role); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, role, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.deleteRole(ctx, role)
+		})
 	}
 
 	// Add finalizer if not present
@@ -86,6 +81,7 @@ func (r *KeycloakRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if role.Spec.ClientRef != nil {
 			reason, metric = "ClientNotReady", "client_not_ready"
 		}
+		reason = notReadyReason(err, reason)
 		RecordError(controllerName, metric)
 		return r.updateStatus(ctx, role, false, reason, err.Error(), "", "", false, "")
 	}
@@ -100,7 +96,7 @@ func (r *KeycloakRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	// Resolve the role name from spec.name.
-	roleName, err := resolveIdentifier("name", role.Spec.Name, roleDef.Name)
+	roleName, err := resolveIdentifierWithPolicy(ctx, r.Client, role, "name", role.Spec.Name, roleDef.Name)
 	if err != nil {
 		RecordError(controllerName, "invalid_identifier")
 		return r.updateStatus(ctx, role, false, InvalidIdentifierReason, err.Error(), "", "", false, "")
@@ -119,6 +115,10 @@ func (r *KeycloakRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if isClientRole {
 		existingRole, err := kc.GetClientRole(ctx, realmName, clientUUID, roleName)
 		if err != nil || existingRole == nil {
+			if err := checkQuota(ctx, r.Client, role); err != nil {
+				RecordError(controllerName, "quota_exceeded")
+				return r.updateStatus(ctx, role, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "", "", true, clientUUID)
+			}
 			log.Info("creating client role", "name", roleName, "realm", realmName, "client", clientUUID)
 			roleID, err = kc.CreateClientRole(ctx, realmName, clientUUID, definition)
 			if err != nil {
@@ -139,6 +139,10 @@ func (r *KeycloakRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	} else {
 		existingRole, err := kc.GetRealmRole(ctx, realmName, roleName)
 		if err != nil || existingRole == nil {
+			if err := checkQuota(ctx, r.Client, role); err != nil {
+				RecordError(controllerName, "quota_exceeded")
+				return r.updateStatus(ctx, role, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "", "", false, "")
+			}
 			log.Info("creating realm role", "name", roleName, "realm", realmName)
 			roleID, err = kc.CreateRealmRole(ctx, realmName, definition)
 			if err != nil {
@@ -302,7 +306,7 @@ func (r *KeycloakRoleReconciler) getKeycloakClientAndRealm(ctx context.Context,
 		return r.getKeycloakClientAndRealmFromClient(ctx, role)
 	}
 
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, role.Namespace, role.Spec.RealmRef, role.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, role, role.Spec.RealmRef, role.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -330,7 +334,7 @@ func (r *KeycloakRoleReconciler) getKeycloakClientAndRealmFromClient(ctx context
 		return nil, "", "", fmt.Errorf("KeycloakClient %s has no clientUUID", clientKey)
 	}
 
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, kcClient.Namespace, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, role, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -362,10 +366,6 @@ func (r *KeycloakRoleReconciler) updateStatus(ctx context.Context, role *keycloa
 	role.Status.IsClientRole = isClientRole
 	role.Status.ClientID = clientID
 
-	if ready {
-		role.Status.ObservedGeneration = role.Generation
-	}
-
 	role.Status.Conditions = setReadyCondition(role.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, role, ready)
@@ -375,5 +375,15 @@ func (r *KeycloakRoleReconciler) updateStatus(ctx context.Context, role *keycloa
 func (r *KeycloakRoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakRole{}).
+		Watches(
+			&keycloakv1beta1.KeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleList{} })),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.ClusterKeycloakRealm{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForRealm(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleList{} })),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
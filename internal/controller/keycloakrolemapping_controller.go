@@ -9,6 +9,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -66,22 +67,15 @@ func (r *KeycloakRoleMappingReconciler) Reconcile(ctx context.Context, req ctrl.
 		return r.updateStatus(ctx, mapping, false, "InvalidSpec", "Either role or roleRef must be specified", "", "", "", "")
 	}
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, mapping); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !mapping.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(mapping, FinalizerName) {
-			// Remove role mapping from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(mapping) {
-				log.Info("preserving role mapping in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.removeRoleMapping(ctx, mapping); err != nil {
-				log.Error(err, "failed to remove role mapping")
-			}
-
-			controllerutil.RemoveFinalizer(mapping, FinalizerName)
-			if err := r.Update(ctx, mapping); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+		return finalizeDeletion(ctx, r.Client, mapping, func(ctx context.Context, _ keycloakv1beta1.DeletionPolicy) error {
+			return r.removeRoleMapping(ctx, mapping)
+		})
 	}
 
 	// Add finalizer if not present
@@ -97,7 +91,7 @@ func (r *KeycloakRoleMappingReconciler) Reconcile(ctx context.Context, req ctrl.
 	subjectType, subjectID, realmName, kc, err := r.resolveSubject(ctx, mapping)
 	if err != nil {
 		RecordError(controllerName, "subject_not_ready")
-		return r.updateStatus(ctx, mapping, false, "SubjectNotReady", err.Error(), subjectType, "", "", "")
+		return r.updateStatus(ctx, mapping, false, notReadyReason(err, "SubjectNotReady"), err.Error(), subjectType, "", "", "")
 	}
 
 	// Resolve the role
@@ -196,7 +190,7 @@ func (r *KeycloakRoleMappingReconciler) resolveSubject(ctx context.Context, mapp
 			return "user", "", "", nil, fmt.Errorf("user %s is not ready", user.Name)
 		}
 
-		kc, realmName, err := r.getKeycloakClientFromUser(ctx, user)
+		kc, realmName, err := r.getKeycloakClientFromUser(ctx, mapping, user)
 		if err != nil {
 			return "user", "", "", nil, err
 		}
@@ -213,7 +207,7 @@ func (r *KeycloakRoleMappingReconciler) resolveSubject(ctx context.Context, mapp
 			return "group", "", "", nil, fmt.Errorf("group %s is not ready", group.Name)
 		}
 
-		kc, realmName, err := r.getKeycloakClientFromGroup(ctx, group)
+		kc, realmName, err := r.getKeycloakClientFromGroup(ctx, mapping, group)
 		if err != nil {
 			return "group", "", "", nil, err
 		}
@@ -336,15 +330,18 @@ func (r *KeycloakRoleMappingReconciler) getClient(ctx context.Context, mapping *
 	return client, nil
 }
 
-func (r *KeycloakRoleMappingReconciler) getKeycloakClientFromUser(ctx context.Context, user *keycloakv1beta1.KeycloakUser) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, user.Namespace, user.Spec.RealmRef, user.Spec.ClusterRealmRef)
+// getKeycloakClientFromUser resolves the realm of the subject user. The
+// mapping is the referrer, so allowedNamespaces rules are matched against its
+// own kind rather than the subject's.
+func (r *KeycloakRoleMappingReconciler) getKeycloakClientFromUser(ctx context.Context, mapping *keycloakv1beta1.KeycloakRoleMapping, user *keycloakv1beta1.KeycloakUser) (*keycloak.Client, string, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, mapping, user.Spec.RealmRef, user.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
 	return res.Client, res.RealmName, nil
 }
 
-func (r *KeycloakRoleMappingReconciler) getKeycloakClientFromGroup(ctx context.Context, group *keycloakv1beta1.KeycloakGroup) (*keycloak.Client, string, error) {
+func (r *KeycloakRoleMappingReconciler) getKeycloakClientFromGroup(ctx context.Context, mapping *keycloakv1beta1.KeycloakRoleMapping, group *keycloakv1beta1.KeycloakGroup) (*keycloak.Client, string, error) {
 	// A nested group carries no realm ref of its own; the realm is held by the
 	// root of its parent chain.
 	owner, err := resolveGroupRealmOwner(ctx, r.Client, group)
@@ -352,7 +349,7 @@ func (r *KeycloakRoleMappingReconciler) getKeycloakClientFromGroup(ctx context.C
 		return nil, "", err
 	}
 
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, owner.Namespace, owner.Spec.RealmRef, owner.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, mapping, owner.Spec.RealmRef, owner.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
@@ -369,7 +366,7 @@ func (r *KeycloakRoleMappingReconciler) resolveServiceAccountSubject(ctx context
 		return nil, nil, "", fmt.Errorf("client %s is not ready", client.Name)
 	}
 
-	kc, realmName, err := r.getKeycloakRealmFromClient(ctx, client)
+	kc, realmName, err := r.getKeycloakRealmFromClient(ctx, mapping, client)
 	if err != nil {
 		return nil, nil, "", err
 	}
@@ -377,29 +374,28 @@ func (r *KeycloakRoleMappingReconciler) resolveServiceAccountSubject(ctx context
 	return client, kc, realmName, nil
 }
 
-func (r *KeycloakRoleMappingReconciler) getKeycloakRealmFromClient(ctx context.Context, client *keycloakv1beta1.KeycloakClient) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, client.Namespace, client.Spec.RealmRef, client.Spec.ClusterRealmRef)
+func (r *KeycloakRoleMappingReconciler) getKeycloakRealmFromClient(ctx context.Context, mapping *keycloakv1beta1.KeycloakRoleMapping, client *keycloakv1beta1.KeycloakClient) (*keycloak.Client, string, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, mapping, client.Spec.RealmRef, client.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
 	return res.Client, res.RealmName, nil
 }
 
+// removeRoleMapping removes the mapped role from the subject in Keycloak. A
+// subject or role that no longer exists in Keycloak takes its mappings with
+// it, so there is nothing left to remove.
 func (r *KeycloakRoleMappingReconciler) removeRoleMapping(ctx context.Context, mapping *keycloakv1beta1.KeycloakRoleMapping) error {
-	log := log.FromContext(ctx)
-
 	// Resolve the subject
 	subjectType, subjectID, realmName, kc, err := r.resolveSubject(ctx, mapping)
 	if err != nil {
-		log.Error(err, "failed to resolve subject for cleanup")
-		return nil // Don't block deletion
+		return fmt.Errorf("failed to resolve subject: %w", err)
 	}
 
 	// Resolve the role
 	roleName, roleType, clientUUID, err := r.resolveRole(ctx, mapping, kc, realmName)
 	if err != nil {
-		log.Error(err, "failed to resolve role for cleanup")
-		return nil
+		return fmt.Errorf("failed to resolve role: %w", err)
 	}
 
 	// Get the role object
@@ -409,10 +405,12 @@ func (r *KeycloakRoleMappingReconciler) removeRoleMapping(ctx context.Context, m
 	} else {
 		role, err = kc.GetRealmRole(ctx, realmName, roleName)
 	}
-	if err != nil {
-		log.Error(err, "failed to get role for cleanup")
+	if keycloak.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return fmt.Errorf("failed to get role %s: %w", roleName, err)
+	}
 
 	roles := []keycloak.RoleRepresentation{*role}
 	if subjectType == "user" {
@@ -428,12 +426,10 @@ func (r *KeycloakRoleMappingReconciler) removeRoleMapping(ctx context.Context, m
 			err = kc.DeleteRealmRolesFromGroup(ctx, realmName, subjectID, roles)
 		}
 	}
-
-	if err != nil {
-		log.Error(err, "failed to remove role mapping")
+	if keycloak.IsNotFound(err) {
+		return nil
 	}
-
-	return nil
+	return err
 }
 
 func (r *KeycloakRoleMappingReconciler) updateStatus(ctx context.Context, mapping *keycloakv1beta1.KeycloakRoleMapping, ready bool, status, message, subjectType, subjectID, roleName, roleType string) (ctrl.Result, error) {
@@ -445,10 +441,6 @@ func (r *KeycloakRoleMappingReconciler) updateStatus(ctx context.Context, mappin
 	mapping.Status.RoleName = roleName
 	mapping.Status.RoleType = roleType
 
-	if ready {
-		mapping.Status.ObservedGeneration = mapping.Generation
-	}
-
 	mapping.Status.Conditions = setReadyCondition(mapping.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, mapping, ready)
@@ -470,6 +462,16 @@ func (r *KeycloakRoleMappingReconciler) SetupWithManager(mgr ctrl.Manager) error
 			&keycloakv1beta1.KeycloakClient{},
 			handler.EnqueueRequestsFromMapFunc(r.findRoleMappingsForServiceAccountClient),
 		).
+		Watches(
+			&keycloakv1beta1.KeycloakClient{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForParent(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleMappingList{} }, "Role.ClientRef")),
+			builder.WithPredicates(becameReady),
+		).
+		Watches(
+			&keycloakv1beta1.KeycloakRole{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForParent(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleMappingList{} }, "RoleRef")),
+			builder.WithPredicates(becameReady),
+		).
 		Complete(r)
 }
 
@@ -56,22 +56,18 @@ func (r *KeycloakUserReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		RecordReconcile(controllerName, user.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, user); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !user.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(user, FinalizerName) {
-			// Delete user from Keycloak unless preserve annotation is set
-			if ShouldPreserveResource(user) {
-				log.Info("preserving user in Keycloak due to annotation", "annotation", PreserveResourceAnnotation)
-			} else if err := r.deleteUser(ctx, user); err != nil {
-				log.Error(err, "failed to delete user from Keycloak")
+		return finalizeDeletion(ctx, r.Client, user, func(ctx context.Context, policy keycloakv1beta1.DeletionPolicy) error {
+			if policy == keycloakv1beta1.DeletionPolicyDisable {
+				return r.disableUser(ctx, user)
 			}
-
-			controllerutil.RemoveFinalizer(user, FinalizerName)
-			if err := r.Update(ctx, user); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
-		return ctrl.Result{}, nil
+			return r.deleteUser(ctx, user)
+		})
 	}
 
 	// Add finalizer if not present
@@ -92,36 +88,23 @@ func (r *KeycloakUserReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, user)
 	if err != nil {
 		RecordError(controllerName, "realm_not_ready")
-		return r.updateStatus(ctx, user, false, "RealmNotReady", err.Error(), "", false, "")
+		return r.updateStatus(ctx, user, false, notReadyReason(err, "RealmNotReady"), err.Error(), "", false, "")
 	}
 
-	// Parse any username in definition so resolveIdentifier can reject it; the
-	// username comes from spec.username and is injected into the definition
-	// before syncing to Keycloak. Role and group keys are probed so they can be
-	// rejected: those live in the typed spec fields.
-	var userDef struct {
-		Username    string          `json:"username"`
-		RealmRoles  json.RawMessage `json:"realmRoles"`
-		ClientRoles json.RawMessage `json:"clientRoles"`
-		Groups      json.RawMessage `json:"groups"`
+	// The username comes from spec.username and is injected into the
+	// definition before syncing to Keycloak.
+	defUsername, specErr := checkUserSpec(user)
+	if specErr != nil {
+		RecordError(controllerName, specErr.metric)
+		return r.updateStatus(ctx, user, false, specErr.reason, specErr.Error(), "", false, "")
 	}
 	var rawDef []byte
 	if user.Spec.Definition != nil {
 		rawDef = user.Spec.Definition.Raw
 	}
-	if len(rawDef) > 0 {
-		if err := json.Unmarshal(rawDef, &userDef); err != nil {
-			RecordError(controllerName, "invalid_definition")
-			return r.updateStatus(ctx, user, false, "InvalidDefinition", fmt.Sprintf("Failed to parse user definition: %v", err), "", false, "")
-		}
-	}
-	if err := rejectRoleGroupDefinitionKeys(userDef.RealmRoles, userDef.ClientRoles, userDef.Groups); err != nil {
-		RecordError(controllerName, "invalid_definition")
-		return r.updateStatus(ctx, user, false, "InvalidDefinition", err.Error(), "", false, "")
-	}
 
 	// Resolve the username from spec.username.
-	username, err := resolveIdentifier("username", user.Spec.Username, userDef.Username)
+	username, err := resolveIdentifier("username", user.Spec.Username, defUsername)
 	if err != nil {
 		RecordError(controllerName, "invalid_identifier")
 		return r.updateStatus(ctx, user, false, InvalidIdentifierReason, err.Error(), "", false, "")
@@ -146,6 +129,10 @@ func (r *KeycloakUserReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	var userID string
 	if err != nil || len(existingUsers) == 0 {
 		// User doesn't exist, create it
+		if err := checkQuota(ctx, r.Client, user); err != nil {
+			RecordError(controllerName, "quota_exceeded")
+			return r.updateStatus(ctx, user, false, notReadyReason(err, "QuotaCheckFailed"), err.Error(), "", false, "")
+		}
 		log.Info("creating user", "username", username, "realm", realmName)
 		userID, err = kc.CreateUser(ctx, realmName, definition)
 		if err != nil {
@@ -204,7 +191,7 @@ func (r *KeycloakUserReconciler) Reconcile(ctx context.Context, req ctrl.Request
 }
 
 func (r *KeycloakUserReconciler) getKeycloakClientAndRealm(ctx context.Context, user *keycloakv1beta1.KeycloakUser) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, user.Namespace, user.Spec.RealmRef, user.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, user, user.Spec.RealmRef, user.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
@@ -230,6 +217,49 @@ func (r *KeycloakUserReconciler) deleteUser(ctx context.Context, user *keycloakv
 	return kc.DeleteUser(ctx, realmName, user.Status.UserID)
 }
 
+// disableUser keeps the user in Keycloak but sets enabled to false, for
+// deletionPolicy Disable.
+func (r *KeycloakUserReconciler) disableUser(ctx context.Context, user *keycloakv1beta1.KeycloakUser) error {
+	// Service account users are managed by the client, don't touch them
+	if user.Status.IsServiceAccount || user.Status.UserID == "" {
+		return nil
+	}
+
+	kc, realmName, err := r.getKeycloakClientAndRealm(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	err = kc.DisableUser(ctx, realmName, user.Status.UserID)
+	if keycloak.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// checkUserSpec makes the checks on user's spec that need no Keycloak call
+// and returns the username found in spec.definition, which resolveIdentifier
+// rejects unless it matches spec.username. Role and group keys are probed so
+// they can be rejected: those live in the typed spec fields.
+func checkUserSpec(user *keycloakv1beta1.KeycloakUser) (string, *specError) {
+	if user.Spec.Definition == nil || len(user.Spec.Definition.Raw) == 0 {
+		return "", nil
+	}
+	var def struct {
+		Username    string          `json:"username"`
+		RealmRoles  json.RawMessage `json:"realmRoles"`
+		ClientRoles json.RawMessage `json:"clientRoles"`
+		Groups      json.RawMessage `json:"groups"`
+	}
+	if err := json.Unmarshal(user.Spec.Definition.Raw, &def); err != nil {
+		return "", invalidDefinition(fmt.Errorf("Failed to parse user definition: %w", err))
+	}
+	if err := rejectRoleGroupDefinitionKeys(def.RealmRoles, def.ClientRoles, def.Groups); err != nil {
+		return "", invalidDefinition(err)
+	}
+	return def.Username, nil
+}
+
 // rejectRoleGroupDefinitionKeys enforces the one-home invariant for role and
 // group assignments: they are reconciled from the typed spec fields via
 // dedicated Keycloak endpoints (the keys are ignored by Keycloak's user PUT
@@ -537,7 +567,7 @@ func (r *KeycloakUserReconciler) reconcileServiceAccountUser(ctx context.Context
 	kc, realmName, clientUUID, err := r.getKeycloakClientAndRealmFromClient(ctx, user)
 	if err != nil {
 		RecordError(controllerName, "client_not_ready")
-		return r.updateStatus(ctx, user, false, "ClientNotReady", err.Error(), "", false, "")
+		return r.updateStatus(ctx, user, false, notReadyReason(err, "ClientNotReady"), err.Error(), "", false, "")
 	}
 
 	// Get the service account user for this client
@@ -562,18 +592,9 @@ func (r *KeycloakUserReconciler) reconcileServiceAccountUser(ctx context.Context
 
 	// If a definition is provided, update the service account user with it
 	if user.Spec.Definition != nil && len(user.Spec.Definition.Raw) > 0 {
-		var defKeys struct {
-			RealmRoles  json.RawMessage `json:"realmRoles"`
-			ClientRoles json.RawMessage `json:"clientRoles"`
-			Groups      json.RawMessage `json:"groups"`
-		}
-		if err := json.Unmarshal(user.Spec.Definition.Raw, &defKeys); err != nil {
-			RecordError(controllerName, "invalid_definition")
-			return r.updateStatus(ctx, user, false, "InvalidDefinition", fmt.Sprintf("Failed to parse user definition: %v", err), userID, true, clientUUID)
-		}
-		if err := rejectRoleGroupDefinitionKeys(defKeys.RealmRoles, defKeys.ClientRoles, defKeys.Groups); err != nil {
-			RecordError(controllerName, "invalid_definition")
-			return r.updateStatus(ctx, user, false, "InvalidDefinition", err.Error(), userID, true, clientUUID)
+		if _, specErr := checkUserSpec(user); specErr != nil {
+			RecordError(controllerName, specErr.metric)
+			return r.updateStatus(ctx, user, false, specErr.reason, specErr.Error(), userID, true, clientUUID)
 		}
 
 		// Merge ID and username into the definition to preserve service account identity
@@ -640,7 +661,7 @@ func (r *KeycloakUserReconciler) getKeycloakClientAndRealmFromClient(ctx context
 	}
 
 	// Get realm from client
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, kcClient.Namespace, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, user, kcClient.Spec.RealmRef, kcClient.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -658,10 +679,6 @@ func (r *KeycloakUserReconciler) updateStatus(ctx context.Context, user *keycloa
 	user.Status.IsServiceAccount = isServiceAccount
 	user.Status.ClientID = clientID
 
-	if ready {
-		user.Status.ObservedGeneration = user.Generation
-	}
-
 	user.Status.Conditions = setReadyCondition(user.Status.Conditions, ready, status, message)
 
 	return writeStatusIfChanged(ctx, r.Client, user, ready)
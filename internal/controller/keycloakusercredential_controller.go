@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -61,6 +62,10 @@ func (r *KeycloakUserCredentialReconciler) Reconcile(ctx context.Context, req ct
 		RecordReconcile(controllerName, cred.Status.Ready, time.Since(startTime).Seconds())
 	}()
 
+	if result, suspended, err := reconcileSuspended(ctx, r.Client, cred); suspended {
+		return result, err
+	}
+
 	// Handle deletion
 	if !cred.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(cred, FinalizerName) {
@@ -99,26 +104,26 @@ func (r *KeycloakUserCredentialReconciler) Reconcile(ctx context.Context, req ct
 	user, err := r.getReferencedUser(ctx, cred)
 	if err != nil {
 		RecordError(controllerName, "user_not_ready")
-		return r.updateStatus(ctx, cred, false, "UserNotReady", err.Error(), "", 0)
+		return r.updateStatus(ctx, cred, false, "UserNotReady", err.Error(), "")
 	}
 
 	// Check if user is ready
 	if !user.Status.Ready || user.Status.UserID == "" {
-		return r.updateStatus(ctx, cred, false, "UserNotReady", "Referenced user is not ready", "", 0)
+		return r.updateStatus(ctx, cred, false, "UserNotReady", "Referenced user is not ready", "")
 	}
 
 	// Get Keycloak client
-	kc, realmName, err := r.getKeycloakClient(ctx, user)
+	kc, realmName, err := r.getKeycloakClient(ctx, cred, user)
 	if err != nil {
 		RecordError(controllerName, "instance_not_ready")
-		return r.updateStatus(ctx, cred, false, "InstanceNotReady", err.Error(), "", 0)
+		return r.updateStatus(ctx, cred, false, notReadyReason(err, "InstanceNotReady"), err.Error(), "")
 	}
 
 	// Get or create the secret
 	secret, created, err := r.ensureSecret(ctx, cred, user)
 	if err != nil {
 		RecordError(controllerName, "secret_error")
-		return r.updateStatus(ctx, cred, false, "SecretError", err.Error(), "", 0)
+		return r.updateStatus(ctx, cred, false, "SecretError", err.Error(), "")
 	}
 
 	// Get password from secret
@@ -130,7 +135,7 @@ func (r *KeycloakUserCredentialReconciler) Reconcile(ctx context.Context, req ct
 	password, ok := secret.Data[passwordKey]
 	if !ok || len(password) == 0 {
 		RecordError(controllerName, "invalid_secret")
-		return r.updateStatus(ctx, cred, false, "InvalidSecret", fmt.Sprintf("Secret missing key: %s", passwordKey), "", 0)
+		return r.updateStatus(ctx, cred, false, "InvalidSecret", fmt.Sprintf("Secret missing key: %s", passwordKey), "")
 	}
 
 	// Calculate password hash to detect changes
@@ -145,7 +150,7 @@ func (r *KeycloakUserCredentialReconciler) Reconcile(ctx context.Context, req ct
 		// Set the password in Keycloak
 		if err := kc.SetPassword(ctx, realmName, user.Status.UserID, string(password), false); err != nil {
 			RecordError(controllerName, "keycloak_api_error")
-			return r.updateStatus(ctx, cred, false, "PasswordSyncFailed", fmt.Sprintf("Failed to set password: %v", err), "", 0)
+			return r.updateStatus(ctx, cred, false, "PasswordSyncFailed", fmt.Sprintf("Failed to set password: %v", err), "")
 		}
 		log.Info("password synchronized", "user", user.Name, "secret", secret.Name, "hashChanged", cred.Status.PasswordHash != passwordHash)
 	} else {
@@ -160,7 +165,7 @@ func (r *KeycloakUserCredentialReconciler) Reconcile(ctx context.Context, req ct
 	cred.Status.ResourcePath = user.Status.ResourcePath
 	cred.Status.PasswordHash = passwordHash
 	cred.Status.SecretResourceVersion = secret.ResourceVersion
-	return r.updateStatus(ctx, cred, true, "Ready", "Credentials synchronized", passwordHash, cred.Generation)
+	return r.updateStatus(ctx, cred, true, "Ready", "Credentials synchronized", passwordHash)
 }
 
 func (r *KeycloakUserCredentialReconciler) getReferencedUser(ctx context.Context, cred *keycloakv1beta1.KeycloakUserCredential) (*keycloakv1beta1.KeycloakUser, error) {
@@ -175,8 +180,10 @@ func (r *KeycloakUserCredentialReconciler) getReferencedUser(ctx context.Context
 	return user, nil
 }
 
-func (r *KeycloakUserCredentialReconciler) getKeycloakClient(ctx context.Context, user *keycloakv1beta1.KeycloakUser) (*keycloak.Client, string, error) {
-	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, user.Namespace, user.Spec.RealmRef, user.Spec.ClusterRealmRef)
+// getKeycloakClient resolves the realm of the credential's user, with the
+// credential as the referrer for allowedNamespaces rules.
+func (r *KeycloakUserCredentialReconciler) getKeycloakClient(ctx context.Context, cred *keycloakv1beta1.KeycloakUserCredential, user *keycloakv1beta1.KeycloakUser) (*keycloak.Client, string, error) {
+	res, err := ResolveRealm(ctx, r.Client, r.ClientManager, cred, user.Spec.RealmRef, user.Spec.ClusterRealmRef)
 	if err != nil {
 		return nil, "", err
 	}
@@ -283,16 +290,13 @@ func (r *KeycloakUserCredentialReconciler) generatePassword(policy *keycloakv1be
 	return password, nil
 }
 
-func (r *KeycloakUserCredentialReconciler) updateStatus(ctx context.Context, cred *keycloakv1beta1.KeycloakUserCredential, ready bool, status, message, passwordHash string, observedGeneration int64) (ctrl.Result, error) {
+func (r *KeycloakUserCredentialReconciler) updateStatus(ctx context.Context, cred *keycloakv1beta1.KeycloakUserCredential, ready bool, status, message, passwordHash string) (ctrl.Result, error) {
 	cred.Status.Ready = ready
 	cred.Status.Status = status
 	cred.Status.Message = message
 	if passwordHash != "" {
 		cred.Status.PasswordHash = passwordHash
 	}
-	if observedGeneration > 0 {
-		cred.Status.ObservedGeneration = observedGeneration
-	}
 
 	cred.Status.Conditions = setReadyCondition(cred.Status.Conditions, ready, status, message)
 
@@ -310,6 +314,11 @@ func (r *KeycloakUserCredentialReconciler) SetupWithManager(mgr ctrl.Manager) er
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&keycloakv1beta1.KeycloakUserCredential{}).
 		Owns(&corev1.Secret{}).
+		Watches(
+			&keycloakv1beta1.KeycloakUser{},
+			handler.EnqueueRequestsFromMapFunc(findDependentsForParent(r.Client, func() client.ObjectList { return &keycloakv1beta1.KeycloakUserCredentialList{} }, "UserRef")),
+			builder.WithPredicates(becameReady),
+		).
 		// Watch for changes to referenced Secrets (for existing secrets not created by us)
 		Watches(
 			&corev1.Secret{},
@@ -3,6 +3,8 @@ package controller
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
 const (
@@ -71,6 +73,46 @@ var (
 		[]string{"instance", "namespace"},
 	)
 
+	// InstanceLastContact tracks when each Keycloak instance last answered a probe
+	InstanceLastContact = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "instance_last_contact_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful probe of a Keycloak instance",
+		},
+		[]string{"instance", "namespace"},
+	)
+
+	// InstanceLatency tracks the round-trip time of the last instance probe
+	InstanceLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "instance_latency_seconds",
+			Help:      "Round-trip time of the last successful probe of a Keycloak instance",
+		},
+		[]string{"instance", "namespace"},
+	)
+
+	// InstanceTokenExpiry tracks when the admin access token of each instance expires
+	InstanceTokenExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "instance_token_expiry_seconds",
+			Help:      "Unix timestamp at which the operator's admin access token for a Keycloak instance expires",
+		},
+		[]string{"instance", "namespace"},
+	)
+
+	// InstanceCertExpiry tracks when the TLS certificate chain of each instance expires
+	InstanceCertExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "instance_cert_expiry_seconds",
+			Help:      "Unix timestamp of the earliest notAfter in a Keycloak instance's TLS certificate chain",
+		},
+		[]string{"instance", "namespace"},
+	)
+
 	// KeycloakAPIRequestsTotal counts API requests to Keycloak
 	KeycloakAPIRequestsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -122,6 +164,10 @@ func init() {
 		ResourcesManaged,
 		ResourcesReady,
 		KeycloakConnectionStatus,
+		InstanceLastContact,
+		InstanceLatency,
+		InstanceTokenExpiry,
+		InstanceCertExpiry,
 		KeycloakAPIRequestsTotal,
 		KeycloakAPILatency,
 		WorkQueueDepth,
@@ -162,6 +208,28 @@ func SetKeycloakConnectionStatus(instance, namespace string, connected bool) {
 	KeycloakConnectionStatus.WithLabelValues(instance, namespace).Set(status)
 }
 
+// SetInstanceHealth updates the instance health gauges from a probe. The
+// certificate gauge is removed for instances reached over plain HTTP.
+func SetInstanceHealth(instance, namespace string, health *keycloak.Health) {
+	InstanceLastContact.WithLabelValues(instance, namespace).SetToCurrentTime()
+	InstanceLatency.WithLabelValues(instance, namespace).Set(health.Latency.Seconds())
+	InstanceTokenExpiry.WithLabelValues(instance, namespace).Set(float64(health.TokenExpiry.Unix()))
+	if health.CertNotAfter.IsZero() {
+		InstanceCertExpiry.DeleteLabelValues(instance, namespace)
+	} else {
+		InstanceCertExpiry.WithLabelValues(instance, namespace).Set(float64(health.CertNotAfter.Unix()))
+	}
+}
+
+// DeleteInstanceHealth removes the health gauges of a deleted instance so
+// alerts on them don't fire for servers that are no longer managed.
+func DeleteInstanceHealth(instance, namespace string) {
+	InstanceLastContact.DeleteLabelValues(instance, namespace)
+	InstanceLatency.DeleteLabelValues(instance, namespace)
+	InstanceTokenExpiry.DeleteLabelValues(instance, namespace)
+	InstanceCertExpiry.DeleteLabelValues(instance, namespace)
+}
+
 // RecordKeycloakAPIRequest records a Keycloak API request
 func RecordKeycloakAPIRequest(instance, method, endpoint, status string, latency float64) {
 	KeycloakAPIRequestsTotal.WithLabelValues(instance, method, endpoint, status).Inc()
@@ -3,9 +3,12 @@ package controller
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
 func TestRecordReconcile_Success(t *testing.T) {
@@ -228,3 +231,38 @@ func TestErrorTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestSetInstanceHealth(t *testing.T) {
+	InstanceCertExpiry.Reset()
+	InstanceTokenExpiry.Reset()
+	InstanceLatency.Reset()
+	InstanceLastContact.Reset()
+
+	notAfter := time.Unix(1900000000, 0)
+	SetInstanceHealth("kc", "ns", &keycloak.Health{
+		Latency:      250 * time.Millisecond,
+		TokenExpiry:  time.Unix(1800000000, 0),
+		CertNotAfter: notAfter,
+	})
+
+	if got := testutil.ToFloat64(InstanceCertExpiry.WithLabelValues("kc", "ns")); got != 1900000000 {
+		t.Errorf("expected cert expiry 1900000000, got %v", got)
+	}
+	if got := testutil.ToFloat64(InstanceTokenExpiry.WithLabelValues("kc", "ns")); got != 1800000000 {
+		t.Errorf("expected token expiry 1800000000, got %v", got)
+	}
+	if got := testutil.ToFloat64(InstanceLatency.WithLabelValues("kc", "ns")); got != 0.25 {
+		t.Errorf("expected latency 0.25, got %v", got)
+	}
+
+	// A plain-HTTP probe drops the certificate series.
+	SetInstanceHealth("kc", "ns", &keycloak.Health{TokenExpiry: time.Unix(1800000000, 0)})
+	if n := testutil.CollectAndCount(InstanceCertExpiry); n != 0 {
+		t.Errorf("expected no cert expiry series, got %d", n)
+	}
+
+	DeleteInstanceHealth("kc", "ns")
+	if n := testutil.CollectAndCount(InstanceLastContact); n != 0 {
+		t.Errorf("expected no last contact series after delete, got %d", n)
+	}
+}
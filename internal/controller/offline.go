@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/export"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// This file exposes the reconcilers' interpretation of specs to the
+// subcommands that work on manifests without a cluster, so that both agree
+// on identifiers, secrets and flows.
+
+// ResolveIdentifier returns the resource identifier from its required spec
+// field, rejecting a conflicting identifier in spec.definition.
+func ResolveIdentifier(specField string, specVal *string, defVal string) (string, error) {
+	return resolveIdentifier(specField, specVal, defVal)
+}
+
+// ApplyNamingPolicy prefixes id if the naming policy of the named
+// ClusterKeycloakRealm asks for it and returns an error if id violates it.
+func ApplyNamingPolicy(policy *keycloakv1beta1.NamingPolicy, realm, namespace, specField, id string) (string, error) {
+	return applyNamingPolicy(policy, realm, namespace, specField, id)
+}
+
+// MergeDefinitionConfig merges Secret data into definition.config. wrapAsList
+// is true for ComponentRepresentation config (map[string][]string).
+func MergeDefinitionConfig(definition json.RawMessage, secretData map[string]string, wrapAsList bool) json.RawMessage {
+	return mergeDefinitionConfig(definition, secretData, wrapAsList)
+}
+
+// MergeSmtpCredentials sets the SMTP user and password of a realm definition.
+func MergeSmtpCredentials(definition json.RawMessage, user, password string) json.RawMessage {
+	return mergeSmtpCredentials(definition, user, password)
+}
+
+// ResolveFlowBindingAliases replaces the alias keys of a client's
+// authenticationFlowBindingOverrides (browserFlowAlias, directGrantFlowAlias)
+// with the flow IDs returned by lookup.
+func ResolveFlowBindingAliases(definition json.RawMessage, lookup func(alias string) (string, error)) (json.RawMessage, error) {
+	return resolveFlowBindingAliasesWithLookup(context.Background(), "", definition, func(_ context.Context, _, alias string) (string, error) {
+		return lookup(alias)
+	})
+}
+
+// FlowImport is an authentication flow in the shape of a realm import: the
+// top-level flow and its sub-flows as a flat list of
+// AuthenticationFlowRepresentations, and the AuthenticatorConfigRepresentations
+// their executions refer to by alias.
+type FlowImport struct {
+	Flows   []map[string]interface{}
+	Configs []map[string]interface{}
+}
+
+// BuildFlowImport validates the executions of flow and flattens them into a
+// FlowImport. Config aliases and priorities follow the reconciler, so an
+// imported flow matches the one the operator would create.
+func BuildFlowImport(flow *keycloakv1beta1.KeycloakAuthenticationFlow) (*FlowImport, error) {
+	execs, err := parseExecutions(flow.Spec.Executions)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &FlowImport{}
+	fi.addFlow(flowDefinition{
+		Alias:       flow.Spec.Alias,
+		Description: flow.Spec.Description,
+		ProviderID:  flow.Spec.ProviderId,
+	}, execs, true)
+	return fi, nil
+}
+
+func (fi *FlowImport) addFlow(def flowDefinition, execs []flowExecution, topLevel bool) {
+	flow := map[string]interface{}{
+		"alias":      def.Alias,
+		"providerId": def.ProviderID,
+		"topLevel":   topLevel,
+		"builtIn":    false,
+	}
+	if def.Description != "" {
+		flow["description"] = def.Description
+	}
+	fi.Flows = append(fi.Flows, flow)
+
+	executions := make([]map[string]interface{}, 0, len(execs))
+	for i, e := range execs {
+		execution := map[string]interface{}{
+			"requirement": e.Requirement,
+			"priority":    (i + 1) * reorderPriorityStep,
+		}
+		if e.SubFlow != nil {
+			execution["authenticatorFlow"] = true
+			execution["flowAlias"] = e.SubFlow.Alias
+			if e.SubFlow.ProviderID == "form-flow" {
+				execution["authenticator"] = "registration-page-form"
+			}
+			fi.addFlow(*e.SubFlow, e.children(), false)
+		} else {
+			execution["authenticatorFlow"] = false
+			execution["authenticator"] = e.Authenticator
+			if len(e.AuthenticatorConfig) > 0 {
+				configAlias := def.Alias + "-" + e.Authenticator + "-config"
+				execution["authenticatorConfig"] = configAlias
+				fi.Configs = append(fi.Configs, map[string]interface{}{
+					"alias":  configAlias,
+					"config": e.AuthenticatorConfig,
+				})
+			}
+		}
+		executions = append(executions, execution)
+	}
+	flow["authenticationExecutions"] = executions
+}
+
+// DefinitionsMatch reports whether the fields of desired match current, the
+// check the reconcilers use to decide whether an object needs an update.
+func DefinitionsMatch(desired, current json.RawMessage) bool {
+	return definitionsMatch(desired, current)
+}
+
+// RealmDefinitionsMatch is DefinitionsMatch for realms, ignoring the SMTP
+// password Keycloak masks on read.
+func RealmDefinitionsMatch(desired, current json.RawMessage) bool {
+	return realmDefinitionsMatch(desired, current)
+}
+
+// IdentityProviderDefinitionsMatch is DefinitionsMatch for identity
+// providers, ignoring the client secret Keycloak masks on read.
+func IdentityProviderDefinitionsMatch(desired, current json.RawMessage) bool {
+	return idpDefinitionsMatch(desired, current)
+}
+
+// OrganizationDefinitionsMatch is DefinitionsMatch for organizations,
+// ignoring domains[].verified.
+func OrganizationDefinitionsMatch(desired, current json.RawMessage) bool {
+	return organizationDefinitionsMatch(desired, current)
+}
+
+// FlowExecutionChanges describes the changes the reconciler would make to the
+// executions of an existing flow on a server with caps: executions to add and
+// remove, requirements and configs to update, and flows to reorder. It is
+// empty if the flow is in sync.
+func FlowExecutionChanges(ctx context.Context, kc *keycloak.Client, caps *keycloak.Capabilities, realmName string, flow *keycloakv1beta1.KeycloakAuthenticationFlow) ([]string, error) {
+	desired, err := parseExecutions(flow.Spec.Executions)
+	if err != nil {
+		return nil, err
+	}
+	r := &KeycloakAuthenticationFlowReconciler{}
+	live, err := r.readLiveTree(ctx, kc, realmName, flow.Spec.Alias)
+	if err != nil {
+		return nil, fmt.Errorf("reading live execution tree for flow %q: %w", flow.Spec.Alias, err)
+	}
+	var changes []string
+	for _, change := range diffExecutions(caps, flow.Spec.Alias, desired, live) {
+		changes = append(changes, change.String())
+	}
+	return changes, nil
+}
+
+// PruneCandidates returns the objects spec.prune of realm, a KeycloakRealm or
+// ClusterKeycloakRealm, covers without deleting any: those of types in
+// Delete mode, which the reconciler would delete, and those in DryRun mode,
+// which it would report in status.unmanaged. c must hold the realm's
+// resources; caps are those of the server kc talks to.
+func PruneCandidates(ctx context.Context, c client.Client, kc *keycloak.Client, caps *keycloak.Capabilities, realm client.Object, realmName string) (deletes, unmanaged []keycloakv1beta1.UnmanagedObject, err error) {
+	var policy *keycloakv1beta1.RealmPruneSpec
+	var definition []byte
+	switch r := realm.(type) {
+	case *keycloakv1beta1.KeycloakRealm:
+		policy, definition = r.Spec.Prune, r.Spec.Definition.Raw
+	case *keycloakv1beta1.ClusterKeycloakRealm:
+		policy, definition = r.Spec.Prune, r.Spec.Definition.Raw
+	default:
+		return nil, nil, fmt.Errorf("%T is not a realm", realm)
+	}
+	target := realmPruneTarget(realm)
+	target.realmName = realmName
+	target.definition = definition
+	if policy == nil {
+		return nil, nil, nil
+	}
+
+	// Run every step in DryRun mode and remember which would delete.
+	dryRun := *policy
+	modes := map[string]*keycloakv1beta1.PruneMode{
+		export.ResourceTypeClients:             &dryRun.Clients,
+		export.ResourceTypeRoles:               &dryRun.Roles,
+		export.ResourceTypeGroups:              &dryRun.Groups,
+		export.ResourceTypeClientScopes:        &dryRun.ClientScopes,
+		export.ResourceTypeIdentityProviders:   &dryRun.IdentityProviders,
+		export.ResourceTypeComponents:          &dryRun.Components,
+		export.ResourceTypeRequiredActions:     &dryRun.RequiredActions,
+		export.ResourceTypeAuthenticationFlows: &dryRun.AuthenticationFlows,
+	}
+	deleteTypes := map[string]bool{}
+	for objType, mode := range modes {
+		if *mode == keycloakv1beta1.PruneModeDelete {
+			deleteTypes[objType] = true
+			*mode = keycloakv1beta1.PruneModeDryRun
+		}
+	}
+
+	found, _, err := pruneRealm(ctx, c, kc, caps, target, &dryRun)
+	for _, obj := range found {
+		if deleteTypes[obj.Type] {
+			deletes = append(deletes, obj)
+		} else {
+			unmanaged = append(unmanaged, obj)
+		}
+	}
+	return deletes, unmanaged, err
+}
+
+// ReadyStatus returns obj's Status.Ready and the reason and message of its
+// Ready condition.
+func ReadyStatus(obj client.Object) (ready bool, reason, message string) {
+	ready, reason = isReady(obj), readyReason(obj)
+	if status := statusOf(obj); status != nil {
+		if field := reflect.ValueOf(status).FieldByName("Conditions"); field.IsValid() {
+			conditions, _ := field.Interface().([]metav1.Condition)
+			if c := meta.FindStatusCondition(conditions, ReadyConditionType); c != nil {
+				message = c.Message
+			}
+		}
+	}
+	return ready, reason, message
+}
+
+// ValidateSpec runs the checks the reconciler of obj's kind makes on its spec
+// before it calls Keycloak, and returns the identifier obj resolves to if its
+// kind has one. The ClusterKeycloakRealm whose naming policy applies is read
+// from c.
+func ValidateSpec(ctx context.Context, c client.Client, obj client.Object) (string, []error) {
+	var errs []error
+	var specErr *specError
+	switch o := obj.(type) {
+	case *keycloakv1beta1.KeycloakClient:
+		_, specErr = checkClientSpec(o)
+	case *keycloakv1beta1.KeycloakClientScope:
+		_, specErr = checkClientScopeSpec(o)
+	case *keycloakv1beta1.KeycloakUser:
+		_, specErr = checkUserSpec(o)
+	case *keycloakv1beta1.KeycloakAuthenticationFlow:
+		if _, specErr = checkAuthenticationFlowSpec(o); specErr != nil {
+			errs = append(errs, specErr)
+		}
+		return o.Spec.Alias, errs
+	}
+	if specErr != nil {
+		errs = append(errs, specErr)
+	}
+
+	id, ok := identifierOf(obj)
+	if !ok {
+		return "", errs
+	}
+	defVal, err := definitionIdentifier(id.definition, id.definitionKey)
+	if err != nil {
+		return "", append(errs, err)
+	}
+	var resolved string
+	if id.namingPolicy {
+		resolved, err = resolveIdentifierWithPolicy(ctx, c, obj, id.specField, id.specVal, defVal)
+	} else {
+		resolved, err = resolveIdentifier(id.specField, id.specVal, defVal)
+	}
+	if err != nil {
+		return "", append(errs, err)
+	}
+	return resolved, errs
+}
+
+// objectIdentifier describes where a kind takes its Keycloak identifier
+// from.
+type objectIdentifier struct {
+	specField     string
+	specVal       *string
+	definition    []byte
+	definitionKey string
+	// namingPolicy is set for identifiers a ClusterKeycloakRealm's naming
+	// policy applies to.
+	namingPolicy bool
+}
+
+func identifierOf(obj client.Object) (objectIdentifier, bool) {
+	switch o := obj.(type) {
+	case *keycloakv1beta1.KeycloakRealm:
+		return objectIdentifier{"realmName", o.Spec.RealmName, o.Spec.Definition.Raw, "realm", false}, true
+	case *keycloakv1beta1.ClusterKeycloakRealm:
+		return objectIdentifier{"realmName", o.Spec.RealmName, o.Spec.Definition.Raw, "realm", false}, true
+	case *keycloakv1beta1.KeycloakClient:
+		var def []byte
+		if o.Spec.Definition != nil {
+			def = o.Spec.Definition.Raw
+		}
+		return objectIdentifier{"clientId", o.Spec.ClientId, def, "clientId", true}, true
+	case *keycloakv1beta1.KeycloakClientScope:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", true}, true
+	case *keycloakv1beta1.KeycloakRole:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", true}, true
+	case *keycloakv1beta1.KeycloakGroup:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", true}, true
+	case *keycloakv1beta1.KeycloakIdentityProvider:
+		return objectIdentifier{"alias", o.Spec.Alias, o.Spec.Definition.Raw, "alias", true}, true
+	case *keycloakv1beta1.KeycloakUser:
+		var def []byte
+		if o.Spec.Definition != nil {
+			def = o.Spec.Definition.Raw
+		}
+		return objectIdentifier{"username", o.Spec.Username, def, "username", false}, true
+	case *keycloakv1beta1.KeycloakProtocolMapper:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", false}, true
+	case *keycloakv1beta1.KeycloakIdentityProviderMapper:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", false}, true
+	case *keycloakv1beta1.KeycloakComponent:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", false}, true
+	case *keycloakv1beta1.KeycloakOrganization:
+		return objectIdentifier{"name", o.Spec.Name, o.Spec.Definition.Raw, "name", false}, true
+	case *keycloakv1beta1.KeycloakRequiredAction:
+		return objectIdentifier{"alias", o.Spec.Alias, o.Spec.Definition.Raw, "alias", false}, true
+	}
+	return objectIdentifier{}, false
+}
+
+// definitionIdentifier returns the string at key of definition, if any.
+func definitionIdentifier(definition []byte, key string) (string, error) {
+	if len(definition) == 0 {
+		return "", nil
+	}
+	var def map[string]json.RawMessage
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return "", fmt.Errorf("failed to parse spec.definition: %w", err)
+	}
+	var id string
+	if raw, ok := def[key]; ok {
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return "", fmt.Errorf("failed to parse spec.definition.%s: %w", key, err)
+		}
+	}
+	return id, nil
+}
+
+// MissingReferences returns the resources obj depends on, such as its
+// realm, parent group or role mapping subject, that do not exist in c, as
+// "Kind namespace/name".
+func MissingReferences(ctx context.Context, c client.Client, obj client.Object) ([]string, error) {
+	var missing []string
+	for _, p := range parentRefs(obj) {
+		if err := c.Get(ctx, p.key, p.obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			name := p.key.Name
+			if p.key.Namespace != "" {
+				name = p.key.String()
+			}
+			missing = append(missing, kindOf(p.obj)+" "+name)
+		}
+	}
+	return missing, nil
+}
+
+// PlacementRealm returns the KeycloakRealm or ClusterKeycloakRealm obj
+// belongs to, following its client, client scope or parent group.
+func PlacementRealm(ctx context.Context, c client.Client, obj client.Object) (keycloakv1beta1.RealmRef, error) {
+	return placementRealm(ctx, c, obj)
+}
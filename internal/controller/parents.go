@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// nearestInChain returns obj if match(obj), otherwise the nearest parent
+// (realm, instance or enclosing resource, see parentRefs) that matches, or nil
+// if none does. Parents that do not exist are skipped; their absence is
+// reported by the regular reconcile.
+func nearestInChain(ctx context.Context, c client.Client, obj client.Object, match func(client.Object) bool) (client.Object, error) {
+	level := []client.Object{obj}
+	for depth := 0; depth < 8 && len(level) > 0; depth++ {
+		var next []client.Object
+		for _, o := range level {
+			if match(o) {
+				return o, nil
+			}
+			for _, p := range parentRefs(o) {
+				if err := c.Get(ctx, p.key, p.obj); err != nil {
+					if apierrors.IsNotFound(err) {
+						continue
+					}
+					return nil, fmt.Errorf("failed to get %s %s: %w", kindOf(p.obj), p.key, err)
+				}
+				next = append(next, p.obj)
+			}
+		}
+		level = next
+	}
+	return nil, nil
+}
+
+// parentRef is an object to fetch into obj from key.
+type parentRef struct {
+	obj client.Object
+	key types.NamespacedName
+}
+
+// parentRefs returns the resources obj depends on: its instance, its realm,
+// or the resource it is nested in.
+func parentRefs(obj client.Object) []parentRef {
+	namespace := obj.GetNamespace()
+	var parents []parentRef
+	add := func(parent client.Object, namespace, name string) {
+		if name != "" {
+			parents = append(parents, parentRef{obj: parent, key: types.NamespacedName{Namespace: namespace, Name: name}})
+		}
+	}
+	refName := func(ref *keycloakv1beta1.ResourceRef) string {
+		if ref == nil {
+			return ""
+		}
+		return ref.Name
+	}
+
+	switch o := obj.(type) {
+	case *keycloakv1beta1.KeycloakRealm:
+		add(&keycloakv1beta1.KeycloakInstance{}, namespace, specRefName(o, "InstanceRef"))
+		add(&keycloakv1beta1.ClusterKeycloakInstance{}, "", specRefName(o, "ClusterInstanceRef"))
+	case *keycloakv1beta1.ClusterKeycloakRealm:
+		if o.Spec.InstanceRef != nil {
+			add(&keycloakv1beta1.KeycloakInstance{}, o.Spec.InstanceRef.Namespace, o.Spec.InstanceRef.Name)
+		}
+		add(&keycloakv1beta1.ClusterKeycloakInstance{}, "", specRefName(o, "ClusterInstanceRef"))
+	case *keycloakv1beta1.KeycloakIdentityProviderMapper:
+		add(&keycloakv1beta1.KeycloakIdentityProvider{}, namespace, o.Spec.IdentityProviderRef.Name)
+	case *keycloakv1beta1.KeycloakUserCredential:
+		add(&keycloakv1beta1.KeycloakUser{}, namespace, o.Spec.UserRef.Name)
+	case *keycloakv1beta1.KeycloakRoleMapping:
+		add(&keycloakv1beta1.KeycloakUser{}, namespace, refName(o.Spec.Subject.UserRef))
+		add(&keycloakv1beta1.KeycloakGroup{}, namespace, refName(o.Spec.Subject.GroupRef))
+		add(&keycloakv1beta1.KeycloakClient{}, namespace, refName(o.Spec.Subject.ServiceAccountRef))
+		add(&keycloakv1beta1.KeycloakRole{}, namespace, refName(o.Spec.RoleRef))
+		if o.Spec.Role != nil {
+			add(&keycloakv1beta1.KeycloakClient{}, namespace, refName(o.Spec.Role.ClientRef))
+		}
+	default:
+		add(&keycloakv1beta1.KeycloakRealm{}, namespace, specRefName(obj, "RealmRef"))
+		add(&keycloakv1beta1.ClusterKeycloakRealm{}, "", specRefName(obj, "ClusterRealmRef"))
+		add(&keycloakv1beta1.KeycloakClient{}, namespace, specRefName(obj, "ClientRef"))
+		add(&keycloakv1beta1.KeycloakClientScope{}, namespace, specRefName(obj, "ClientScopeRef"))
+		add(&keycloakv1beta1.KeycloakGroup{}, namespace, specRefName(obj, "ParentGroupRef"))
+	}
+	return parents
+}
+
+// objectName returns namespace/name, or name for cluster-scoped objects.
+func objectName(obj client.Object) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
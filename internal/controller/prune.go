@@ -0,0 +1,854 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/export"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// maxUnmanagedObjects caps status.unmanaged so that a realm full of unmanaged
+// objects cannot push the status past the object size limit.
+const maxUnmanagedObjects = 500
+
+// protectedComponentTypes are component provider types Keycloak creates for
+// every realm beyond the key providers the export filter skips. Deleting the
+// client registration policies would open anonymous client registration, and
+// deleting the user profile resets it, so they are never pruned.
+var protectedComponentTypes = map[string]bool{
+	"org.keycloak.services.clientregistration.policy.ClientRegistrationPolicy": true,
+	"org.keycloak.userprofile.UserProfileProvider":                             true,
+}
+
+// protectedClientScopes are client scopes Keycloak creates for every realm
+// beyond those the export filter skips. Clients reference them by default, so
+// they are never pruned.
+var protectedClientScopes = map[string]bool{
+	"role_list":         true,
+	"saml_organization": true,
+	"organization":      true,
+	"service_account":   true,
+}
+
+// PruneRestrictedConditionType is the condition set to True while the Delete
+// modes of spec.prune run as DryRun because the watch scope may hide the
+// resources that manage some of the realm's objects.
+const PruneRestrictedConditionType = "PruneRestricted"
+
+// LabelSelectorScopeReason is the reason of the PruneRestricted condition
+// under --watch-label-selector.
+const LabelSelectorScopeReason = "LabelSelectorScope"
+
+// restrictPrunePolicy returns the prune policy to apply under scope and
+// records the outcome in the PruneRestricted condition. A label selector
+// leaves the resources of other shards out of the cache, and even the API
+// server cannot tell which deployment they belong to, so their objects would
+// look unmanaged: Delete is downgraded to DryRun.
+func restrictPrunePolicy(scope WatchScope, policy *keycloakv1beta1.RealmPruneSpec, conditions *[]metav1.Condition, generation int64) *keycloakv1beta1.RealmPruneSpec {
+	if policy == nil || scope.LabelSelector == nil {
+		meta.RemoveStatusCondition(conditions, PruneRestrictedConditionType)
+		return policy
+	}
+
+	restricted := policy.DeepCopy()
+	var downgraded bool
+	for _, mode := range []*keycloakv1beta1.PruneMode{
+		&restricted.Clients, &restricted.Roles, &restricted.Groups, &restricted.ClientScopes,
+		&restricted.IdentityProviders, &restricted.Components, &restricted.RequiredActions, &restricted.AuthenticationFlows,
+	} {
+		if *mode == keycloakv1beta1.PruneModeDelete {
+			*mode = keycloakv1beta1.PruneModeDryRun
+			downgraded = true
+		}
+	}
+	if !downgraded {
+		meta.RemoveStatusCondition(conditions, PruneRestrictedConditionType)
+		return policy
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               PruneRestrictedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             LabelSelectorScopeReason,
+		Message:            fmt.Sprintf("prune Delete runs as DryRun: the operator only watches resources matching %q", scope.LabelSelector),
+		ObservedGeneration: generation,
+	})
+	return restricted
+}
+
+// PrunedConditionType is the condition reporting the outcome of the last
+// spec.prune pass. It is absent while spec.prune is unset.
+const PrunedConditionType = "Pruned"
+
+// Reasons of the Pruned condition.
+const (
+	// PruneSucceededReason means the last pass handled every object type.
+	PruneSucceededReason = "PruneSucceeded"
+
+	// PruneFailedReason means objects could not be listed or deleted.
+	PruneFailedReason = "PruneFailed"
+)
+
+// setPrunedCondition records the outcome of a prune pass under policy in the
+// Pruned condition.
+func setPrunedCondition(conditions *[]metav1.Condition, policy *keycloakv1beta1.RealmPruneSpec, err error, generation int64) {
+	if policy == nil {
+		meta.RemoveStatusCondition(conditions, PrunedConditionType)
+		return
+	}
+	cond := metav1.Condition{
+		Type:               PrunedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             PruneSucceededReason,
+		Message:            "Unmanaged objects handled",
+		ObservedGeneration: generation,
+	}
+	if err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = PruneFailedReason
+		cond.Message = err.Error()
+	}
+	meta.SetStatusCondition(conditions, cond)
+}
+
+// pruneTarget identifies the realm being pruned.
+type pruneTarget struct {
+	// ref is the realm as placementRealm reports it for its resources.
+	ref keycloakv1beta1.RealmRef
+	// namespace limits the resources considered; empty for cluster realms.
+	namespace string
+	// realmName is the realm name in Keycloak.
+	realmName string
+	// definition is the realm's spec.definition.
+	definition []byte
+	// namingPolicy is the naming policy of a cluster realm.
+	namingPolicy *keycloakv1beta1.NamingPolicy
+	// orphaned is the realm's status.orphaned.
+	orphaned []keycloakv1beta1.UnmanagedObject
+}
+
+// pruneRealm handles the objects of the target realm that are not backed by a
+// resource or by the realm definition according to policy. Objects found in
+// DryRun mode are returned; objects in Delete mode are deleted. Built-in
+// objects and the orphaned objects of target are skipped. The orphaned
+// objects are returned without the entries whose object is gone or managed
+// again. caps are the server's capabilities; nil stands for an unprobed server.
+func pruneRealm(ctx context.Context, c client.Client, kc *keycloak.Client, caps *keycloak.Capabilities, target pruneTarget, policy *keycloakv1beta1.RealmPruneSpec) (unmanaged, orphaned []keycloakv1beta1.UnmanagedObject, err error) {
+	if policy == nil {
+		return nil, target.orphaned, nil
+	}
+
+	managed, err := collectManagedObjects(ctx, c, target)
+	if err != nil {
+		return nil, target.orphaned, err
+	}
+	p := &realmPruner{
+		kc:       kc,
+		caps:     caps,
+		realm:    target.realmName,
+		managed:  managed,
+		orphaned: map[keycloakv1beta1.UnmanagedObject]bool{},
+		filter:   export.NewFilter(nil, nil, true),
+	}
+	for _, obj := range target.orphaned {
+		p.orphaned[obj] = true
+	}
+	if err := p.loadRealm(ctx, policy); err != nil {
+		return nil, target.orphaned, err
+	}
+
+	p.run(ctx, policy.Clients, export.ResourceTypeClients, p.pruneClients)
+	p.run(ctx, policy.Roles, export.ResourceTypeRoles, p.pruneRoles)
+	p.run(ctx, policy.Groups, export.ResourceTypeGroups, p.pruneGroups)
+	p.run(ctx, policy.ClientScopes, export.ResourceTypeClientScopes, p.pruneClientScopes)
+	p.run(ctx, policy.IdentityProviders, export.ResourceTypeIdentityProviders, p.pruneIdentityProviders)
+	p.run(ctx, policy.Components, export.ResourceTypeComponents, p.pruneComponents)
+	p.run(ctx, policy.RequiredActions, export.ResourceTypeRequiredActions, p.pruneRequiredActions)
+	p.run(ctx, policy.AuthenticationFlows, export.ResourceTypeAuthenticationFlows, p.pruneAuthenticationFlows)
+
+	if len(p.unmanaged) > maxUnmanagedObjects {
+		ctrl.LoggerFrom(ctx).Info("too many unmanaged objects, truncating status.unmanaged",
+			"count", len(p.unmanaged), "limit", maxUnmanagedObjects)
+		p.unmanaged = p.unmanaged[:maxUnmanagedObjects]
+	}
+	for _, obj := range target.orphaned {
+		if p.orphaned[obj] {
+			orphaned = append(orphaned, obj)
+		}
+	}
+	return p.unmanaged, orphaned, errors.Join(p.errs...)
+}
+
+// managedObjects holds the identifiers of a realm's objects that are backed
+// by a resource or by the realm definition.
+type managedObjects struct {
+	// names holds identifiers by object type. Client roles are keyed
+	// "<clientId>/<role>", groups by their path without the leading slash.
+	names map[string]map[string]bool
+	// ids holds the Keycloak IDs resources recorded in their status.
+	ids map[string]bool
+}
+
+func (m *managedObjects) add(objType, name, id string) {
+	if name != "" {
+		if m.names[objType] == nil {
+			m.names[objType] = map[string]bool{}
+		}
+		m.names[objType][name] = true
+	}
+	if id != "" {
+		m.ids[id] = true
+	}
+}
+
+// addGroup adds the group at path together with its ancestors, which must
+// survive for the group to exist.
+func (m *managedObjects) addGroup(path, id string) {
+	m.add(export.ResourceTypeGroups, path, id)
+	for i := strings.LastIndex(path, "/"); i > 0; i = strings.LastIndex(path, "/") {
+		path = path[:i]
+		m.add(export.ResourceTypeGroups, path, "")
+	}
+}
+
+func (m *managedObjects) has(objType, name, id string) bool {
+	return m.names[objType][name] || (id != "" && m.ids[id])
+}
+
+// prunedKinds lists the kinds whose Keycloak objects spec.prune handles.
+var prunedKinds = []struct {
+	name    string
+	newList func() client.ObjectList
+}{
+	{"KeycloakClients", func() client.ObjectList { return &keycloakv1beta1.KeycloakClientList{} }},
+	{"KeycloakRoles", func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleList{} }},
+	{"KeycloakGroups", func() client.ObjectList { return &keycloakv1beta1.KeycloakGroupList{} }},
+	{"KeycloakClientScopes", func() client.ObjectList { return &keycloakv1beta1.KeycloakClientScopeList{} }},
+	{"KeycloakIdentityProviders", func() client.ObjectList { return &keycloakv1beta1.KeycloakIdentityProviderList{} }},
+	{"KeycloakComponents", func() client.ObjectList { return &keycloakv1beta1.KeycloakComponentList{} }},
+	{"KeycloakRequiredActions", func() client.ObjectList { return &keycloakv1beta1.KeycloakRequiredActionList{} }},
+	{"KeycloakAuthenticationFlows", func() client.ObjectList { return &keycloakv1beta1.KeycloakAuthenticationFlowList{} }},
+}
+
+// collectManagedObjects gathers the objects of the target realm managed by
+// resources and by the realm definition. The resources of a cluster realm
+// may live in any namespace, so they are listed from the API server when the
+// cache is restricted to some namespaces.
+func collectManagedObjects(ctx context.Context, c client.Client, target pruneTarget) (*managedObjects, error) {
+	m := &managedObjects{names: map[string]map[string]bool{}, ids: map[string]bool{}}
+	if err := addDefinitionObjects(m, target.definition); err != nil {
+		return nil, err
+	}
+
+	reader := client.Reader(c)
+	if target.namespace == "" {
+		reader = allNamespacesReader(c)
+	}
+	for _, kind := range prunedKinds {
+		list := kind.newList()
+		if err := reader.List(ctx, list, client.InNamespace(target.namespace)); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind.name, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			// Resources whose parent is gone cannot have an object in
+			// Keycloak and are ignored.
+			ref, err := placementRealm(ctx, c, obj)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			if ref != target.ref {
+				continue
+			}
+			objType, name, id, err := prunedIdentity(ctx, c, target, obj)
+			if err != nil {
+				return nil, err
+			}
+			if objType == export.ResourceTypeGroups {
+				m.addGroup(name, id)
+			} else {
+				m.add(objType, name, id)
+			}
+		}
+	}
+	return m, nil
+}
+
+// realmWideIdentifier returns the identifier of an object whose name is
+// unique in the target realm, applying the naming policy to objects not
+// reconciled yet.
+func (t pruneTarget) realmWideIdentifier(obj client.Object, statusVal string, specVal *string) string {
+	if statusVal != "" || t.namingPolicy == nil {
+		return syncedIdentifier(statusVal, specVal)
+	}
+	id, err := applyNamingPolicy(t.namingPolicy, t.ref.ClusterRealmRef, obj.GetNamespace(), "", identifierValue(specVal))
+	if err != nil {
+		return identifierValue(specVal)
+	}
+	return id
+}
+
+// prunedIdentity returns the object type, identifier and Keycloak ID under
+// which the prune steps of target find the Keycloak object of obj, which
+// lives in target's realm. Client roles are named "<clientId>/<role>", groups
+// by their path. objType is empty for kinds spec.prune does not handle.
+func prunedIdentity(ctx context.Context, c client.Client, target pruneTarget, obj client.Object) (objType, name, id string, err error) {
+	switch o := obj.(type) {
+	case *keycloakv1beta1.KeycloakClient:
+		return export.ResourceTypeClients, target.realmWideIdentifier(o, o.Status.ClientID, o.Spec.ClientId), o.Status.ClientUUID, nil
+	case *keycloakv1beta1.KeycloakRole:
+		if o.Spec.ClientRef == nil {
+			return export.ResourceTypeRoles, target.realmWideIdentifier(o, o.Status.RoleName, o.Spec.Name), o.Status.RoleID, nil
+		}
+		kcClient := &keycloakv1beta1.KeycloakClient{}
+		key := types.NamespacedName{Name: o.Spec.ClientRef.Name, Namespace: o.Namespace}
+		if err := c.Get(ctx, key, kcClient); err != nil {
+			return "", "", "", fmt.Errorf("failed to get KeycloakClient %s: %w", key, err)
+		}
+		clientID := target.realmWideIdentifier(kcClient, kcClient.Status.ClientID, kcClient.Spec.ClientId)
+		return export.ResourceTypeRoles, clientID + "/" + syncedIdentifier(o.Status.RoleName, o.Spec.Name), o.Status.RoleID, nil
+	case *keycloakv1beta1.KeycloakGroup:
+		// Only the top-level name is realm-wide; subgroup names are scoped by
+		// their parent.
+		var segments []string
+		top := o
+		for depth := 0; top.Spec.ParentGroupRef != nil; depth++ {
+			if depth >= maxGroupNestingDepth {
+				return "", "", "", fmt.Errorf("parentGroupRef chain from KeycloakGroup %s/%s exceeds %d levels", o.Namespace, o.Name, maxGroupNestingDepth)
+			}
+			segments = append([]string{syncedIdentifier(top.Status.GroupName, top.Spec.Name)}, segments...)
+			parent := &keycloakv1beta1.KeycloakGroup{}
+			key := types.NamespacedName{Name: top.Spec.ParentGroupRef.Name, Namespace: top.Namespace}
+			if err := c.Get(ctx, key, parent); err != nil {
+				return "", "", "", fmt.Errorf("failed to get KeycloakGroup %s: %w", key, err)
+			}
+			top = parent
+		}
+		segments = append([]string{target.realmWideIdentifier(top, top.Status.GroupName, top.Spec.Name)}, segments...)
+		return export.ResourceTypeGroups, strings.Join(segments, "/"), o.Status.GroupID, nil
+	case *keycloakv1beta1.KeycloakClientScope:
+		return export.ResourceTypeClientScopes, target.realmWideIdentifier(o, o.Status.ClientScopeName, o.Spec.Name), "", nil
+	case *keycloakv1beta1.KeycloakIdentityProvider:
+		return export.ResourceTypeIdentityProviders, target.realmWideIdentifier(o, o.Status.Alias, o.Spec.Alias), "", nil
+	case *keycloakv1beta1.KeycloakComponent:
+		return export.ResourceTypeComponents, syncedIdentifier(o.Status.ComponentName, o.Spec.Name), o.Status.ComponentID, nil
+	case *keycloakv1beta1.KeycloakRequiredAction:
+		return export.ResourceTypeRequiredActions, syncedIdentifier(o.Status.Alias, o.Spec.Alias), "", nil
+	case *keycloakv1beta1.KeycloakAuthenticationFlow:
+		return export.ResourceTypeAuthenticationFlows, o.Spec.Alias, o.Status.FlowID, nil
+	}
+	return "", "", "", nil
+}
+
+// realmPruneTarget returns the prune target of realm, a KeycloakRealm or
+// ClusterKeycloakRealm, without its realm name and definition.
+func realmPruneTarget(realm client.Object) pruneTarget {
+	if cluster, ok := realm.(*keycloakv1beta1.ClusterKeycloakRealm); ok {
+		return pruneTarget{
+			ref:          keycloakv1beta1.RealmRef{ClusterRealmRef: cluster.Name},
+			namingPolicy: cluster.Spec.NamingPolicy,
+			orphaned:     cluster.Status.Orphaned,
+		}
+	}
+	namespaced := realm.(*keycloakv1beta1.KeycloakRealm)
+	return pruneTarget{
+		ref:       keycloakv1beta1.RealmRef{RealmRef: namespaced.Namespace + "/" + namespaced.Name},
+		namespace: namespaced.Namespace,
+		orphaned:  namespaced.Status.Orphaned,
+	}
+}
+
+// orphanedObjectsOf returns the status.orphaned list of realm.
+func orphanedObjectsOf(realm client.Object) *[]keycloakv1beta1.UnmanagedObject {
+	if cluster, ok := realm.(*keycloakv1beta1.ClusterKeycloakRealm); ok {
+		return &cluster.Status.Orphaned
+	}
+	return &realm.(*keycloakv1beta1.KeycloakRealm).Status.Orphaned
+}
+
+// recordOrphan adds the Keycloak object of obj, deleted with the Orphan
+// policy, to status.orphaned of its realm so that spec.prune leaves it
+// alone. Objects whose realm or parent is gone are not recorded.
+func recordOrphan(ctx context.Context, c client.Client, obj client.Object) error {
+	realm, err := nearestInChain(ctx, c, obj, isRealm)
+	if err != nil || realm == nil || realm == obj {
+		return err
+	}
+	objType, name, _, err := prunedIdentity(ctx, c, realmPruneTarget(realm), obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil || objType == "" || name == "" {
+		return err
+	}
+
+	entry := keycloakv1beta1.UnmanagedObject{Type: objType, Name: name}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(realm), realm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		orphaned := orphanedObjectsOf(realm)
+		if slices.Contains(*orphaned, entry) {
+			return nil
+		}
+		*orphaned = append(*orphaned, entry)
+		return c.Status().Update(ctx, realm)
+	})
+}
+
+// definitionGroup is a group inlined in a realm definition.
+type definitionGroup struct {
+	Name      string            `json:"name"`
+	SubGroups []definitionGroup `json:"subGroups"`
+}
+
+// addDefinitionObjects adds the objects inlined in a realm definition, which
+// Keycloak imports when it creates the realm.
+func addDefinitionObjects(m *managedObjects, definition []byte) error {
+	if len(definition) == 0 {
+		return nil
+	}
+	type named struct {
+		Name string `json:"name"`
+	}
+	type aliased struct {
+		Alias string `json:"alias"`
+	}
+	var def struct {
+		Clients []struct {
+			ClientID string `json:"clientId"`
+		} `json:"clients"`
+		Roles struct {
+			Realm  []named            `json:"realm"`
+			Client map[string][]named `json:"client"`
+		} `json:"roles"`
+		Groups              []definitionGroup  `json:"groups"`
+		ClientScopes        []named            `json:"clientScopes"`
+		IdentityProviders   []aliased          `json:"identityProviders"`
+		Components          map[string][]named `json:"components"`
+		RequiredActions     []aliased          `json:"requiredActions"`
+		AuthenticationFlows []aliased          `json:"authenticationFlows"`
+	}
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return fmt.Errorf("failed to parse realm definition: %w", err)
+	}
+
+	for _, cl := range def.Clients {
+		m.add(export.ResourceTypeClients, cl.ClientID, "")
+	}
+	for _, role := range def.Roles.Realm {
+		m.add(export.ResourceTypeRoles, role.Name, "")
+	}
+	for clientID, roles := range def.Roles.Client {
+		for _, role := range roles {
+			m.add(export.ResourceTypeRoles, clientID+"/"+role.Name, "")
+		}
+	}
+	var addGroups func(prefix string, groups []definitionGroup)
+	addGroups = func(prefix string, groups []definitionGroup) {
+		for _, group := range groups {
+			m.addGroup(prefix+group.Name, "")
+			addGroups(prefix+group.Name+"/", group.SubGroups)
+		}
+	}
+	addGroups("", def.Groups)
+	for _, scope := range def.ClientScopes {
+		m.add(export.ResourceTypeClientScopes, scope.Name, "")
+	}
+	for _, idp := range def.IdentityProviders {
+		m.add(export.ResourceTypeIdentityProviders, idp.Alias, "")
+	}
+	for _, components := range def.Components {
+		for _, component := range components {
+			m.add(export.ResourceTypeComponents, component.Name, "")
+		}
+	}
+	for _, action := range def.RequiredActions {
+		m.add(export.ResourceTypeRequiredActions, action.Alias, "")
+	}
+	for _, flow := range def.AuthenticationFlows {
+		m.add(export.ResourceTypeAuthenticationFlows, flow.Alias, "")
+	}
+	return nil
+}
+
+// realmPruner walks the live objects of one realm.
+type realmPruner struct {
+	kc      *keycloak.Client
+	caps    *keycloak.Capabilities
+	realm   string
+	managed *managedObjects
+	filter  *export.Filter
+	// orphaned holds the realm's orphaned objects, which are never pruned.
+	orphaned map[keycloakv1beta1.UnmanagedObject]bool
+
+	// realmID is the realm's internal ID, the parent of its components.
+	realmID string
+	// clients and idps are the live clients and identity providers, loaded
+	// when a prune step needs them.
+	clients []json.RawMessage
+	idps    []json.RawMessage
+
+	unmanaged []keycloakv1beta1.UnmanagedObject
+	errs      []error
+}
+
+// realmFlowBindings are the realm attributes that bind authentication flows.
+var realmFlowBindings = []string{
+	"browserFlow", "registrationFlow", "directGrantFlow", "resetCredentialsFlow",
+	"clientAuthenticationFlow", "dockerAuthenticationFlow", "firstBrokerLoginFlow",
+}
+
+// loadRealm fetches the realm and the live objects shared by several prune
+// steps, and marks flows bound to the realm as managed.
+func (p *realmPruner) loadRealm(ctx context.Context, policy *keycloakv1beta1.RealmPruneSpec) error {
+	raw, err := p.kc.GetRealmRaw(ctx, p.realm)
+	if err != nil {
+		return fmt.Errorf("failed to get realm %s: %w", p.realm, err)
+	}
+	var rep map[string]interface{}
+	if err := json.Unmarshal(raw, &rep); err != nil {
+		return fmt.Errorf("failed to parse realm %s: %w", p.realm, err)
+	}
+	p.realmID, _ = rep["id"].(string)
+	for _, binding := range realmFlowBindings {
+		if alias, _ := rep[binding].(string); alias != "" {
+			p.managed.add(export.ResourceTypeAuthenticationFlows, alias, "")
+		}
+	}
+
+	if policy.Clients != "" || policy.Roles != "" || policy.AuthenticationFlows != "" {
+		if p.clients, err = p.kc.GetClientsRaw(ctx, p.realm); err != nil {
+			return fmt.Errorf("failed to list clients: %w", err)
+		}
+	}
+	if policy.IdentityProviders != "" || policy.AuthenticationFlows != "" {
+		if p.idps, err = p.kc.GetIdentityProvidersRaw(ctx, p.realm); err != nil {
+			return fmt.Errorf("failed to list identity providers: %w", err)
+		}
+	}
+	return nil
+}
+
+// run executes one prune step unless mode is unset. Its findings are sorted
+// by name so status.unmanaged stays stable between reconciles. Orphaned
+// objects of objType the step did not come across are forgotten once it
+// succeeded: they no longer exist or are managed again.
+func (p *realmPruner) run(ctx context.Context, mode keycloakv1beta1.PruneMode, objType string, step func(context.Context, func(name string, del func() error)) error) {
+	if mode == "" {
+		return
+	}
+	log := ctrl.LoggerFrom(ctx)
+	start := len(p.unmanaged)
+	seen := map[keycloakv1beta1.UnmanagedObject]bool{}
+	err := step(ctx, func(name string, del func() error) {
+		obj := keycloakv1beta1.UnmanagedObject{Type: objType, Name: name}
+		if p.orphaned[obj] {
+			seen[obj] = true
+			return
+		}
+		if mode == keycloakv1beta1.PruneModeDryRun {
+			p.unmanaged = append(p.unmanaged, obj)
+			return
+		}
+		log.Info("pruning unmanaged object", "realm", p.realm, "type", objType, "name", name)
+		if err := del(); err != nil {
+			p.errs = append(p.errs, fmt.Errorf("failed to prune %s %q: %w", objType, name, err))
+		}
+	})
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("failed to list %s: %w", objType, err))
+	} else {
+		for obj := range p.orphaned {
+			if obj.Type == objType && !seen[obj] {
+				delete(p.orphaned, obj)
+			}
+		}
+	}
+	found := p.unmanaged[start:]
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+}
+
+// liveObject holds the fields of listed objects the prune steps need.
+type liveObject struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	ClientID      string            `json:"clientId"`
+	Alias         string            `json:"alias"`
+	ProviderType  string            `json:"providerType"`
+	BuiltIn       bool              `json:"builtIn"`
+	SubGroupCount int               `json:"subGroupCount"`
+	SubGroups     []json.RawMessage `json:"subGroups"`
+
+	FirstBrokerLoginFlowAlias          string            `json:"firstBrokerLoginFlowAlias"`
+	PostBrokerLoginFlowAlias           string            `json:"postBrokerLoginFlowAlias"`
+	AuthenticationFlowBindingOverrides map[string]string `json:"authenticationFlowBindingOverrides"`
+}
+
+func decodeLiveObjects(raws []json.RawMessage) ([]liveObject, error) {
+	objects := make([]liveObject, 0, len(raws))
+	for _, raw := range raws {
+		var obj liveObject
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+func (p *realmPruner) pruneClients(ctx context.Context, handle func(string, func() error)) error {
+	clients, err := decodeLiveObjects(p.clients)
+	if err != nil {
+		return err
+	}
+	for _, cl := range clients {
+		if p.filter.ShouldSkipClient(cl.ClientID) || p.managed.has(export.ResourceTypeClients, cl.ClientID, cl.ID) {
+			continue
+		}
+		handle(cl.ClientID, func() error { return p.kc.DeleteClient(ctx, p.realm, cl.ID) })
+	}
+	return nil
+}
+
+// pruneRoles prunes realm roles and the client roles of managed clients; the
+// roles of unmanaged clients go with their client.
+func (p *realmPruner) pruneRoles(ctx context.Context, handle func(string, func() error)) error {
+	raws, err := p.kc.GetRealmRolesRaw(ctx, p.realm)
+	if err != nil {
+		return err
+	}
+	roles, err := decodeLiveObjects(raws)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if p.filter.ShouldSkipRole(role.Name, false) || p.managed.has(export.ResourceTypeRoles, role.Name, role.ID) {
+			continue
+		}
+		handle(role.Name, func() error { return p.kc.DeleteRealmRole(ctx, p.realm, role.Name) })
+	}
+
+	clients, err := decodeLiveObjects(p.clients)
+	if err != nil {
+		return err
+	}
+	for _, cl := range clients {
+		if !p.managed.has(export.ResourceTypeClients, cl.ClientID, cl.ID) {
+			continue
+		}
+		raws, err := p.kc.GetClientRolesRaw(ctx, p.realm, cl.ID)
+		if err != nil {
+			return err
+		}
+		roles, err := decodeLiveObjects(raws)
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			name := cl.ClientID + "/" + role.Name
+			if p.filter.ShouldSkipRole(role.Name, true) || p.managed.has(export.ResourceTypeRoles, name, role.ID) {
+				continue
+			}
+			handle(name, func() error { return p.kc.DeleteClientRole(ctx, p.realm, cl.ID, role.Name) })
+		}
+	}
+	return nil
+}
+
+// groupChildrenPageSize is the page size used to list subgroups.
+const groupChildrenPageSize = 100
+
+// pruneGroups prunes top-level groups and, below managed groups, subgroups.
+// Deleting a group deletes its subgroups, so they are not reported.
+func (p *realmPruner) pruneGroups(ctx context.Context, handle func(string, func() error)) error {
+	raws, err := p.kc.GetGroupsRaw(ctx, p.realm)
+	if err != nil {
+		return err
+	}
+	return p.pruneGroupLevel(ctx, "", raws, handle)
+}
+
+func (p *realmPruner) pruneGroupLevel(ctx context.Context, prefix string, raws []json.RawMessage, handle func(string, func() error)) error {
+	groups, err := decodeLiveObjects(raws)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		path := prefix + group.Name
+		if !p.managed.has(export.ResourceTypeGroups, path, group.ID) {
+			handle(path, func() error { return p.kc.DeleteGroup(ctx, p.realm, group.ID) })
+			continue
+		}
+		children := group.SubGroups
+		if len(children) == 0 && group.SubGroupCount > 0 && p.caps.Supports(keycloak.CapabilityGroupChildren) {
+			if children, err = p.listGroupChildren(ctx, group.ID); err != nil {
+				return err
+			}
+		}
+		if err := p.pruneGroupLevel(ctx, path+"/", children, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listGroupChildren pages through the children of a group. Keycloak 23+ no
+// longer inlines them in the group listing; older servers do, and lack the
+// endpoint, so callers check CapabilityGroupChildren first.
+func (p *realmPruner) listGroupChildren(ctx context.Context, groupID string) ([]json.RawMessage, error) {
+	var all []json.RawMessage
+	for offset := 0; ; offset += groupChildrenPageSize {
+		page, err := p.kc.GetGroupChildrenRaw(ctx, p.realm, groupID, map[string]string{
+			"first":               strconv.Itoa(offset),
+			"max":                 strconv.Itoa(groupChildrenPageSize),
+			"briefRepresentation": "false",
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < groupChildrenPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (p *realmPruner) pruneClientScopes(ctx context.Context, handle func(string, func() error)) error {
+	raws, err := p.kc.GetClientScopesRaw(ctx, p.realm)
+	if err != nil {
+		return err
+	}
+	scopes, err := decodeLiveObjects(raws)
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		if p.filter.ShouldSkipClientScope(scope.Name) || protectedClientScopes[scope.Name] || p.managed.has(export.ResourceTypeClientScopes, scope.Name, scope.ID) {
+			continue
+		}
+		handle(scope.Name, func() error { return p.kc.DeleteClientScope(ctx, p.realm, scope.ID) })
+	}
+	return nil
+}
+
+func (p *realmPruner) pruneIdentityProviders(ctx context.Context, handle func(string, func() error)) error {
+	idps, err := decodeLiveObjects(p.idps)
+	if err != nil {
+		return err
+	}
+	for _, idp := range idps {
+		if p.managed.has(export.ResourceTypeIdentityProviders, idp.Alias, "") {
+			continue
+		}
+		handle(idp.Alias, func() error { return p.kc.DeleteIdentityProvider(ctx, p.realm, idp.Alias) })
+	}
+	return nil
+}
+
+// pruneComponents prunes components whose parent is the realm. Components
+// below them, such as LDAP mappers, go with their parent.
+func (p *realmPruner) pruneComponents(ctx context.Context, handle func(string, func() error)) error {
+	if p.realmID == "" {
+		return fmt.Errorf("realm %s has no id", p.realm)
+	}
+	raws, err := p.kc.GetComponentsRaw(ctx, p.realm, map[string]string{"parent": p.realmID})
+	if err != nil {
+		return err
+	}
+	components, err := decodeLiveObjects(raws)
+	if err != nil {
+		return err
+	}
+	for _, component := range components {
+		if p.filter.ShouldSkipComponent(component.Name, component.ProviderType) || protectedComponentTypes[component.ProviderType] ||
+			p.managed.has(export.ResourceTypeComponents, component.Name, component.ID) {
+			continue
+		}
+		handle(component.Name, func() error { return p.kc.DeleteComponent(ctx, p.realm, component.ID) })
+	}
+	return nil
+}
+
+func (p *realmPruner) pruneRequiredActions(ctx context.Context, handle func(string, func() error)) error {
+	raws, err := p.kc.GetRequiredActionsRaw(ctx, p.realm)
+	if err != nil {
+		return err
+	}
+	actions, err := decodeLiveObjects(raws)
+	if err != nil {
+		return err
+	}
+	for _, action := range actions {
+		if p.filter.ShouldSkipRequiredAction(action.Alias) || p.managed.has(export.ResourceTypeRequiredActions, action.Alias, "") {
+			continue
+		}
+		handle(action.Alias, func() error { return p.kc.DeleteRequiredAction(ctx, p.realm, action.Alias) })
+	}
+	return nil
+}
+
+// pruneAuthenticationFlows prunes top-level flows. Flows bound to the realm,
+// to an identity provider or to a client override are in use and kept.
+func (p *realmPruner) pruneAuthenticationFlows(ctx context.Context, handle func(string, func() error)) error {
+	inUse := map[string]bool{}
+	idps, err := decodeLiveObjects(p.idps)
+	if err != nil {
+		return err
+	}
+	for _, idp := range idps {
+		inUse[idp.FirstBrokerLoginFlowAlias] = true
+		inUse[idp.PostBrokerLoginFlowAlias] = true
+	}
+	clients, err := decodeLiveObjects(p.clients)
+	if err != nil {
+		return err
+	}
+	for _, cl := range clients {
+		for _, flowID := range cl.AuthenticationFlowBindingOverrides {
+			inUse[flowID] = true
+		}
+	}
+
+	raws, err := p.kc.GetAuthenticationFlowsRaw(ctx, p.realm)
+	if err != nil {
+		return err
+	}
+	flows, err := decodeLiveObjects(raws)
+	if err != nil {
+		return err
+	}
+	for _, flow := range flows {
+		if p.filter.ShouldSkipAuthenticationFlow(flow.BuiltIn) || inUse[flow.Alias] || inUse[flow.ID] ||
+			p.managed.has(export.ResourceTypeAuthenticationFlows, flow.Alias, flow.ID) {
+			continue
+		}
+		handle(flow.Alias, func() error { return p.kc.DeleteAuthenticationFlow(ctx, p.realm, flow.ID) })
+	}
+	return nil
+}
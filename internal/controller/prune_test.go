@@ -0,0 +1,344 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
+)
+
+func TestPruneRealm(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+	appID, err := kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"app"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"stray"}`))
+	require.NoError(t, err)
+	for _, role := range []string{`{"name":"admin-role"}`, `{"name":"old"}`} {
+		_, err = kc.CreateRealmRole(ctx, "test", json.RawMessage(role))
+		require.NoError(t, err)
+	}
+	for _, role := range []string{`{"name":"reader"}`, `{"name":"legacy"}`} {
+		_, err = kc.CreateClientRole(ctx, "test", appID, json.RawMessage(role))
+		require.NoError(t, err)
+	}
+	teamID, err := kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"team"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateChildGroup(ctx, "test", teamID, json.RawMessage(`{"name":"sub"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"orphans"}`))
+	require.NoError(t, err)
+	for _, scope := range []string{`{"name":"extra","protocol":"openid-connect"}`, `{"name":"role_list","protocol":"saml"}`} {
+		_, err = kc.CreateClientScope(ctx, "test", json.RawMessage(scope))
+		require.NoError(t, err)
+	}
+	_, err = kc.CreateIdentityProvider(ctx, "test", json.RawMessage(`{"alias":"old-idp","providerId":"oidc"}`))
+	require.NoError(t, err)
+	topLevel := true
+	_, err = kc.CreateAuthenticationFlow(ctx, "test", keycloak.AuthenticationFlowRepresentation{
+		Alias:      strPtr("custom"),
+		ProviderID: strPtr("basic-flow"),
+		TopLevel:   &topLevel,
+	})
+	require.NoError(t, err)
+
+	realmRef := &keycloakv1beta1.ResourceRef{Name: "realm"}
+	c := newAuthTestClient(t,
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: realmRef, ClientId: strPtr("app")},
+		},
+		// Backed by a resource, but in another realm.
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "stray", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "other"}, ClientId: strPtr("stray")},
+		},
+		&keycloakv1beta1.KeycloakRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakRoleSpec{ClientRef: &keycloakv1beta1.ResourceRef{Name: "app"}, Name: strPtr("reader")},
+		},
+		&keycloakv1beta1.KeycloakGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakGroupSpec{RealmRef: realmRef, Name: strPtr("team")},
+		},
+	)
+	target := pruneTarget{
+		ref:        keycloakv1beta1.RealmRef{RealmRef: "team/realm"},
+		namespace:  "team",
+		realmName:  "test",
+		definition: []byte(`{"roles":{"realm":[{"name":"admin-role"}]}}`),
+	}
+	all := func(mode keycloakv1beta1.PruneMode) *keycloakv1beta1.RealmPruneSpec {
+		return &keycloakv1beta1.RealmPruneSpec{
+			Clients: mode, Roles: mode, Groups: mode, ClientScopes: mode, IdentityProviders: mode,
+			Components: mode, RequiredActions: mode, AuthenticationFlows: mode,
+		}
+	}
+
+	unmanaged, _, err := pruneRealm(ctx, c, kc, nil, target, nil)
+	require.NoError(t, err)
+	require.Empty(t, unmanaged)
+
+	unmanaged, _, err = pruneRealm(ctx, c, kc, nil, target, all(keycloakv1beta1.PruneModeDryRun))
+	require.NoError(t, err)
+	require.Equal(t, []keycloakv1beta1.UnmanagedObject{
+		{Type: "clients", Name: "stray"},
+		{Type: "roles", Name: "app/legacy"},
+		{Type: "roles", Name: "old"},
+		{Type: "groups", Name: "orphans"},
+		{Type: "groups", Name: "team/sub"},
+		{Type: "client-scopes", Name: "extra"},
+		{Type: "identity-providers", Name: "old-idp"},
+		{Type: "authentication-flows", Name: "custom"},
+	}, unmanaged)
+	stray, err := kc.GetClients(ctx, "test", map[string]string{"clientId": "stray"})
+	require.NoError(t, err)
+	require.Len(t, stray, 1, "dry run deleted objects")
+
+	unmanaged, _, err = pruneRealm(ctx, c, kc, nil, target, all(keycloakv1beta1.PruneModeDelete))
+	require.NoError(t, err)
+	require.Empty(t, unmanaged)
+
+	unmanaged, _, err = pruneRealm(ctx, c, kc, nil, target, all(keycloakv1beta1.PruneModeDryRun))
+	require.NoError(t, err)
+	require.Empty(t, unmanaged)
+
+	// Managed and built-in objects survive.
+	clients, err := kc.GetClients(ctx, "test", map[string]string{"clientId": "app"})
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	_, err = kc.GetRealmRole(ctx, "test", "admin-role")
+	require.NoError(t, err)
+	_, err = kc.GetAuthenticationFlowByAlias(ctx, "test", "browser")
+	require.NoError(t, err)
+	scope, err := kc.GetClientScopeByName(ctx, "test", "role_list")
+	require.NoError(t, err)
+	require.NotNil(t, scope)
+}
+
+// TestPruneRealm_GroupChildren checks that subgroups are found on servers
+// with and without the children endpoint.
+func TestPruneRealm_GroupChildren(t *testing.T) {
+	for _, version := range []string{"22.0.5", "24.0.5"} {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+			srv := fake.NewServer(fake.WithVersion(version))
+			defer srv.Close()
+			kc := keycloak.NewClient(keycloak.Config{
+				BaseURL:  srv.URL,
+				Username: fake.AdminUsername,
+				Password: fake.AdminPassword,
+			}, testr.New(t))
+
+			require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+			teamID, err := kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"team"}`))
+			require.NoError(t, err)
+			_, err = kc.CreateChildGroup(ctx, "test", teamID, json.RawMessage(`{"name":"sub"}`))
+			require.NoError(t, err)
+
+			c := newAuthTestClient(t, &keycloakv1beta1.KeycloakGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "team"},
+				Spec:       keycloakv1beta1.KeycloakGroupSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "realm"}, Name: strPtr("team")},
+			})
+			target := pruneTarget{
+				ref:       keycloakv1beta1.RealmRef{RealmRef: "team/realm"},
+				namespace: "team",
+				realmName: "test",
+			}
+			caps := keycloak.CapabilitiesFromStatus(version, nil, false)
+			unmanaged, _, err := pruneRealm(ctx, c, kc, caps, target, &keycloakv1beta1.RealmPruneSpec{Groups: keycloakv1beta1.PruneModeDryRun})
+			require.NoError(t, err)
+			require.Equal(t, []keycloakv1beta1.UnmanagedObject{{Type: "groups", Name: "team/sub"}}, unmanaged)
+		})
+	}
+}
+
+func TestPruneRealm_ScopedClient(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"shared","enabled":true}`)))
+	for _, cl := range []string{`{"clientId":"app"}`, `{"clientId":"stray"}`} {
+		_, err := kc.CreateClient(ctx, "shared", json.RawMessage(cl))
+		require.NoError(t, err)
+	}
+
+	// The cache only holds the watched namespace team-a; the client backing
+	// "app" in team-b is only visible to the direct reader.
+	outOfScope := &keycloakv1beta1.KeycloakClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-b"},
+		Spec: keycloakv1beta1.KeycloakClientSpec{
+			ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "shared"}, ClientId: strPtr("app"),
+		},
+	}
+	scope, err := ParseWatchScope("team-a", "")
+	require.NoError(t, err)
+	c := &scopedClient{Client: newAuthTestClient(t), direct: newAuthTestClient(t, outOfScope), scope: scope}
+
+	target := pruneTarget{
+		ref:       keycloakv1beta1.RealmRef{ClusterRealmRef: "shared"},
+		realmName: "shared",
+	}
+	unmanaged, _, err := pruneRealm(ctx, c, kc, nil, target, &keycloakv1beta1.RealmPruneSpec{Clients: keycloakv1beta1.PruneModeDelete})
+	require.NoError(t, err)
+	require.Empty(t, unmanaged)
+
+	clients, err := kc.GetClients(ctx, "shared", map[string]string{"clientId": "app"})
+	require.NoError(t, err)
+	require.Len(t, clients, 1, "client managed outside the watched namespaces was pruned")
+	stray, err := kc.GetClients(ctx, "shared", map[string]string{"clientId": "stray"})
+	require.NoError(t, err)
+	require.Empty(t, stray)
+}
+
+func TestPruneRealm_Orphaned(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+	appID, err := kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"app"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"stray"}`))
+	require.NoError(t, err)
+
+	realmKey := types.NamespacedName{Name: "realm", Namespace: "team"}
+	kcClient := &keycloakv1beta1.KeycloakClient{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "app",
+			Namespace:         "team",
+			Finalizers:        []string{FinalizerName},
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+		},
+		Spec: keycloakv1beta1.KeycloakClientSpec{
+			RealmRef:       &keycloakv1beta1.ResourceRef{Name: "realm"},
+			ClientId:       strPtr("app"),
+			DeletionPolicy: keycloakv1beta1.DeletionPolicyOrphan,
+		},
+		Status: keycloakv1beta1.KeycloakClientStatus{ClientID: "app", ClientUUID: appID},
+	}
+	c := ctrlfake.NewClientBuilder().
+		WithScheme(newScheme(t)).
+		WithStatusSubresource(&keycloakv1beta1.KeycloakRealm{}).
+		WithObjects(kcClient, &keycloakv1beta1.KeycloakRealm{
+			ObjectMeta: metav1.ObjectMeta{Name: realmKey.Name, Namespace: realmKey.Namespace},
+		}).
+		Build()
+
+	// Deleting the resource with the Orphan policy records its client.
+	_, err = finalizeDeletion(ctx, c, kcClient, func(context.Context, keycloakv1beta1.DeletionPolicy) error {
+		t.Fatal("cleanup called for an orphaned client")
+		return nil
+	})
+	require.NoError(t, err)
+	realm := &keycloakv1beta1.KeycloakRealm{}
+	require.NoError(t, c.Get(ctx, realmKey, realm))
+	want := []keycloakv1beta1.UnmanagedObject{{Type: "clients", Name: "app"}}
+	require.Equal(t, want, realm.Status.Orphaned)
+
+	target := realmPruneTarget(realm)
+	target.realmName = "test"
+	policy := &keycloakv1beta1.RealmPruneSpec{Clients: keycloakv1beta1.PruneModeDelete}
+	unmanaged, orphaned, err := pruneRealm(ctx, c, kc, nil, target, policy)
+	require.NoError(t, err)
+	require.Empty(t, unmanaged)
+	require.Equal(t, want, orphaned)
+	clients, err := kc.GetClients(ctx, "test", map[string]string{"clientId": "app"})
+	require.NoError(t, err)
+	require.Len(t, clients, 1, "orphaned client was pruned")
+	stray, err := kc.GetClients(ctx, "test", map[string]string{"clientId": "stray"})
+	require.NoError(t, err)
+	require.Empty(t, stray)
+
+	// Once the client is gone, a new one under the same clientId is pruned.
+	require.NoError(t, kc.DeleteClient(ctx, "test", appID))
+	_, orphaned, err = pruneRealm(ctx, c, kc, nil, target, policy)
+	require.NoError(t, err)
+	require.Empty(t, orphaned)
+}
+
+func TestRestrictPrunePolicy(t *testing.T) {
+	policy := &keycloakv1beta1.RealmPruneSpec{
+		Clients: keycloakv1beta1.PruneModeDelete,
+		Groups:  keycloakv1beta1.PruneModeDryRun,
+	}
+
+	var conditions []metav1.Condition
+	require.Same(t, policy, restrictPrunePolicy(WatchScope{}, policy, &conditions, 1))
+	require.Empty(t, conditions)
+
+	scope, err := ParseWatchScope("", "shard=a")
+	require.NoError(t, err)
+	restricted := restrictPrunePolicy(scope, policy, &conditions, 2)
+	require.Equal(t, &keycloakv1beta1.RealmPruneSpec{
+		Clients: keycloakv1beta1.PruneModeDryRun,
+		Groups:  keycloakv1beta1.PruneModeDryRun,
+	}, restricted)
+	require.Equal(t, keycloakv1beta1.PruneModeDelete, policy.Clients, "spec modified")
+	cond := meta.FindStatusCondition(conditions, PruneRestrictedConditionType)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+	require.Equal(t, LabelSelectorScopeReason, cond.Reason)
+	require.Equal(t, int64(2), cond.ObservedGeneration)
+
+	// Nothing to downgrade, or the selector is gone: the condition is cleared.
+	dryRun := &keycloakv1beta1.RealmPruneSpec{Groups: keycloakv1beta1.PruneModeDryRun}
+	require.Same(t, dryRun, restrictPrunePolicy(scope, dryRun, &conditions, 3))
+	require.Empty(t, conditions)
+	restrictPrunePolicy(scope, policy, &conditions, 4)
+	require.Same(t, policy, restrictPrunePolicy(WatchScope{}, policy, &conditions, 5))
+	require.Empty(t, conditions)
+}
+
+func TestSetPrunedCondition(t *testing.T) {
+	policy := &keycloakv1beta1.RealmPruneSpec{Clients: keycloakv1beta1.PruneModeDelete}
+	var conditions []metav1.Condition
+
+	setPrunedCondition(&conditions, policy, nil, 2)
+	cond := meta.FindStatusCondition(conditions, PrunedConditionType)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+	require.Equal(t, PruneSucceededReason, cond.Reason)
+
+	setPrunedCondition(&conditions, policy, errors.New("failed to prune clients \"stray\": forbidden"), 3)
+	cond = meta.FindStatusCondition(conditions, PrunedConditionType)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, PruneFailedReason, cond.Reason)
+	require.Contains(t, cond.Message, "stray")
+	require.Equal(t, int64(3), cond.ObservedGeneration)
+
+	setPrunedCondition(&conditions, nil, nil, 4)
+	require.Empty(t, conditions)
+}
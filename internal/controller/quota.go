@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// QuotaExceededReason is the status/condition reason used when creating an
+// object in Keycloak would exceed a KeycloakQuota in the object's namespace.
+// Objects that already exist in Keycloak are never blocked, so lowering a
+// quota does not break running workloads.
+const QuotaExceededReason = "QuotaExceeded"
+
+// QuotaExceededError is returned by checkQuota when a KeycloakQuota is full.
+type QuotaExceededError struct {
+	Quota types.NamespacedName
+	Kind  string
+	Used  int32
+	Hard  int32
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("KeycloakQuota %s allows %d %s objects, %d already exist", e.Quota, e.Hard, e.Kind, e.Used)
+}
+
+// IsQuotaExceeded reports whether err (or anything it wraps) is a
+// *QuotaExceededError.
+func IsQuotaExceeded(err error) bool {
+	var target *QuotaExceededError
+	return errors.As(err, &target)
+}
+
+// quotaKind ties a limited CR kind to its QuotaCounts field.
+type quotaKind struct {
+	Kind      string
+	newObject func() client.Object
+	newList   func() client.ObjectList
+	field     func(*keycloakv1beta1.QuotaCounts) **int32
+}
+
+var quotaKinds = []quotaKind{
+	{"KeycloakClient", func() client.Object { return &keycloakv1beta1.KeycloakClient{} },
+		func() client.ObjectList { return &keycloakv1beta1.KeycloakClientList{} },
+		func(q *keycloakv1beta1.QuotaCounts) **int32 { return &q.Clients }},
+	{"KeycloakUser", func() client.Object { return &keycloakv1beta1.KeycloakUser{} },
+		func() client.ObjectList { return &keycloakv1beta1.KeycloakUserList{} },
+		func(q *keycloakv1beta1.QuotaCounts) **int32 { return &q.Users }},
+	{"KeycloakRole", func() client.Object { return &keycloakv1beta1.KeycloakRole{} },
+		func() client.ObjectList { return &keycloakv1beta1.KeycloakRoleList{} },
+		func(q *keycloakv1beta1.QuotaCounts) **int32 { return &q.Roles }},
+	{"KeycloakGroup", func() client.Object { return &keycloakv1beta1.KeycloakGroup{} },
+		func() client.ObjectList { return &keycloakv1beta1.KeycloakGroupList{} },
+		func(q *keycloakv1beta1.QuotaCounts) **int32 { return &q.Groups }},
+	{"KeycloakIdentityProvider", func() client.Object { return &keycloakv1beta1.KeycloakIdentityProvider{} },
+		func() client.ObjectList { return &keycloakv1beta1.KeycloakIdentityProviderList{} },
+		func(q *keycloakv1beta1.QuotaCounts) **int32 { return &q.IdentityProviders }},
+	{"KeycloakProtocolMapper", func() client.Object { return &keycloakv1beta1.KeycloakProtocolMapper{} },
+		func() client.ObjectList { return &keycloakv1beta1.KeycloakProtocolMapperList{} },
+		func(q *keycloakv1beta1.QuotaCounts) **int32 { return &q.ProtocolMappers }},
+}
+
+func quotaKindFor(kind string) (quotaKind, bool) {
+	for _, k := range quotaKinds {
+		if k.Kind == kind {
+			return k, true
+		}
+	}
+	return quotaKind{}, false
+}
+
+// checkQuota must be called right before obj is created in Keycloak. It
+// returns a *QuotaExceededError if any KeycloakQuota in obj's namespace that
+// covers obj's kind and realm is already full.
+//
+// Usage counts the CRs whose Status.ResourcePath is set, i.e. those the
+// operator has created. Creates are serialized per kind by the controller, so
+// a quota is only overshot if the informer cache lags behind a status write.
+func checkQuota(ctx context.Context, c client.Client, obj client.Object) error {
+	kind, ok := quotaKindFor(kindOf(obj))
+	if !ok {
+		return nil
+	}
+	quotas := &keycloakv1beta1.KeycloakQuotaList{}
+	if err := c.List(ctx, quotas, client.InNamespace(obj.GetNamespace())); err != nil {
+		return fmt.Errorf("failed to list KeycloakQuotas: %w", err)
+	}
+
+	var realm *keycloakv1beta1.RealmRef
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		hard := *kind.field(&quota.Spec.Hard)
+		if hard == nil {
+			continue
+		}
+		if quotaRealm(quota) != nil {
+			if realm == nil {
+				r, err := placementRealm(ctx, c, obj)
+				if err != nil {
+					return err
+				}
+				realm = &r
+			}
+			if *realm != *quotaRealm(quota) {
+				continue
+			}
+		}
+		used, err := quotaUsage(ctx, c, quota, kind, obj)
+		if err != nil {
+			return err
+		}
+		if used >= *hard {
+			return &QuotaExceededError{Quota: client.ObjectKeyFromObject(quota), Kind: kind.Kind, Used: used, Hard: *hard}
+		}
+	}
+	return nil
+}
+
+// quotaRealm returns the realm a quota is limited to, or nil if it covers all
+// realms.
+func quotaRealm(quota *keycloakv1beta1.KeycloakQuota) *keycloakv1beta1.RealmRef {
+	switch {
+	case quota.Spec.ClusterRealmRef != nil:
+		return &keycloakv1beta1.RealmRef{ClusterRealmRef: quota.Spec.ClusterRealmRef.Name}
+	case quota.Spec.RealmRef != nil:
+		return &keycloakv1beta1.RealmRef{RealmRef: quota.Namespace + "/" + quota.Spec.RealmRef.Name}
+	}
+	return nil
+}
+
+// quotaUsage counts the objects of kind in the quota's namespace and realm
+// that exist in Keycloak, leaving out exclude (the object about to be
+// created) if set.
+func quotaUsage(ctx context.Context, c client.Client, quota *keycloakv1beta1.KeycloakQuota, kind quotaKind, exclude client.Object) (int32, error) {
+	list := kind.newList()
+	if err := c.List(ctx, list, client.InNamespace(quota.Namespace)); err != nil {
+		return 0, fmt.Errorf("failed to list %s objects: %w", kind.Kind, err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return 0, err
+	}
+	scope := quotaRealm(quota)
+
+	var used int32
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok || statusResourcePath(obj) == "" {
+			continue
+		}
+		if exclude != nil && obj.GetName() == exclude.GetName() {
+			continue
+		}
+		if scope != nil {
+			// Objects whose placement no longer resolves are not counted.
+			realm, err := placementRealm(ctx, c, obj)
+			if err != nil || realm != *scope {
+				continue
+			}
+		}
+		used++
+	}
+	return used, nil
+}
+
+// statusResourcePath returns obj's Status.ResourcePath, which reconcilers set
+// once the object exists in Keycloak.
+func statusResourcePath(obj client.Object) string {
+	status := statusOf(obj)
+	if status == nil {
+		return ""
+	}
+	field := reflect.ValueOf(status).FieldByName("ResourcePath")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// placementRealm returns the realm obj belongs to, following spec.realmRef or
+// spec.clusterRealmRef, or the parent reference (clientRef, clientScopeRef,
+// parentGroupRef) that implies the realm. Only the Kubernetes objects are
+// read; Keycloak is not contacted.
+func placementRealm(ctx context.Context, c client.Client, obj client.Object) (keycloakv1beta1.RealmRef, error) {
+	for depth := 0; depth < 8; depth++ {
+		if name := specRefName(obj, "ClusterRealmRef"); name != "" {
+			return keycloakv1beta1.RealmRef{ClusterRealmRef: name}, nil
+		}
+		if name := specRefName(obj, "RealmRef"); name != "" {
+			return keycloakv1beta1.RealmRef{RealmRef: obj.GetNamespace() + "/" + name}, nil
+		}
+
+		var parent client.Object
+		var name string
+		switch {
+		case specRefName(obj, "ClientRef") != "":
+			parent, name = &keycloakv1beta1.KeycloakClient{}, specRefName(obj, "ClientRef")
+		case specRefName(obj, "ClientScopeRef") != "":
+			parent, name = &keycloakv1beta1.KeycloakClientScope{}, specRefName(obj, "ClientScopeRef")
+		case specRefName(obj, "ParentGroupRef") != "":
+			parent, name = &keycloakv1beta1.KeycloakGroup{}, specRefName(obj, "ParentGroupRef")
+		default:
+			return keycloakv1beta1.RealmRef{}, fmt.Errorf("%s %s/%s has no realm reference", kindOf(obj), obj.GetNamespace(), obj.GetName())
+		}
+		key := types.NamespacedName{Name: name, Namespace: obj.GetNamespace()}
+		if err := c.Get(ctx, key, parent); err != nil {
+			return keycloakv1beta1.RealmRef{}, fmt.Errorf("failed to get %s %s: %w", kindOf(parent), key, err)
+		}
+		obj = parent
+	}
+	return keycloakv1beta1.RealmRef{}, fmt.Errorf("realm reference chain of %s %s/%s is too deep", kindOf(obj), obj.GetNamespace(), obj.GetName())
+}
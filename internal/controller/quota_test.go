@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func quotaRole(name, realm string, created bool) *keycloakv1beta1.KeycloakRole {
+	role := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: realm}},
+	}
+	if created {
+		role.Status.ResourcePath = "/admin/realms/" + realm + "/roles/" + name
+	}
+	return role
+}
+
+func TestCheckQuota(t *testing.T) {
+	ctx := context.Background()
+	unscoped := &keycloakv1beta1.KeycloakQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakQuotaSpec{Hard: keycloakv1beta1.QuotaCounts{Roles: int32Ptr(2)}},
+	}
+	scoped := &keycloakv1beta1.KeycloakQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "team"},
+		Spec: keycloakv1beta1.KeycloakQuotaSpec{
+			RealmRef: &keycloakv1beta1.ResourceRef{Name: "a"},
+			Hard:     keycloakv1beta1.QuotaCounts{Roles: int32Ptr(1)},
+		},
+	}
+	kcClient := &keycloakv1beta1.KeycloakClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "a"}},
+	}
+	clientRole := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-admin", Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{ClientRef: &keycloakv1beta1.ResourceRef{Name: "app"}},
+	}
+
+	cases := []struct {
+		name     string
+		objs     []client.Object
+		obj      client.Object
+		exceeded bool
+	}{
+		{name: "no quota", objs: []client.Object{quotaRole("r1", "a", true)}, obj: quotaRole("new", "a", false)},
+		{name: "other kind", objs: []client.Object{unscoped, quotaRole("r1", "a", true), quotaRole("r2", "b", true)},
+			obj: kcClientIn("team")},
+		{name: "unscoped below limit", objs: []client.Object{unscoped, quotaRole("r1", "a", true), quotaRole("r2", "b", false)},
+			obj: quotaRole("new", "a", false)},
+		{name: "unscoped full", objs: []client.Object{unscoped, quotaRole("r1", "a", true), quotaRole("r2", "b", true)},
+			obj: quotaRole("new", "a", false), exceeded: true},
+		{name: "object itself is not counted", objs: []client.Object{unscoped, quotaRole("r1", "a", true), quotaRole("r2", "b", true)},
+			obj: quotaRole("r2", "b", true)},
+		{name: "scoped to other realm", objs: []client.Object{scoped, quotaRole("r1", "a", true)},
+			obj: quotaRole("new", "b", false)},
+		{name: "scoped full", objs: []client.Object{scoped, quotaRole("r1", "a", true), quotaRole("r2", "b", true)},
+			obj: quotaRole("new", "a", false), exceeded: true},
+		{name: "realm derived from clientRef", objs: []client.Object{scoped, kcClient, quotaRole("r1", "a", true)},
+			obj: clientRole, exceeded: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkQuota(ctx, newAuthTestClient(t, tc.objs...), tc.obj)
+			if !tc.exceeded {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !IsQuotaExceeded(err) {
+				t.Fatalf("got %v, want QuotaExceededError", err)
+			}
+			if got := notReadyReason(err, "Error"); got != QuotaExceededReason {
+				t.Errorf("reason: got %q, want %q", got, QuotaExceededReason)
+			}
+		})
+	}
+}
+
+func TestKeycloakQuotaReconciler_Usage(t *testing.T) {
+	quota := &keycloakv1beta1.KeycloakQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "q", Namespace: "team", Generation: 3},
+		Spec: keycloakv1beta1.KeycloakQuotaSpec{
+			ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "shared"},
+			Hard:            keycloakv1beta1.QuotaCounts{Roles: int32Ptr(5)},
+		},
+	}
+	inShared := &keycloakv1beta1.KeycloakRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "r1", Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakRoleSpec{ClusterRealmRef: &keycloakv1beta1.ClusterResourceRef{Name: "shared"}},
+		Status:     keycloakv1beta1.KeycloakRoleStatus{ResourcePath: "/admin/realms/shared/roles/r1"},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(newScheme(t)).
+		WithStatusSubresource(&keycloakv1beta1.KeycloakQuota{}).
+		WithObjects(quota, inShared, quotaRole("r2", "a", true)).
+		Build()
+	r := &KeycloakQuotaReconciler{Client: c}
+
+	key := types.NamespacedName{Name: "q", Namespace: "team"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	got := &keycloakv1beta1.KeycloakQuota{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get quota: %v", err)
+	}
+	if got.Status.Used.Roles == nil || *got.Status.Used.Roles != 1 {
+		t.Errorf("used roles: got %v, want 1", got.Status.Used.Roles)
+	}
+	if got.Status.Used.Clients != nil {
+		t.Errorf("used clients: got %v, want unset", *got.Status.Used.Clients)
+	}
+	if got.Status.ObservedGeneration != 3 {
+		t.Errorf("observedGeneration: got %d, want 3", got.Status.ObservedGeneration)
+	}
+}
+
+func int32Ptr(i int32) *int32 { return &i }
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// requireReferenceGrants controls whether cross-namespace Secret and ConfigMap
+// references need a KeycloakReferenceGrant in the target namespace. Set once
+// at startup from --require-reference-grants.
+var requireReferenceGrants = true
+
+// SetRequireReferenceGrants enables or disables KeycloakReferenceGrant checks.
+// Disable only on single-tenant clusters, where every namespace may read every
+// other namespace's credentials through the operator.
+func SetRequireReferenceGrants(required bool) {
+	requireReferenceGrants = required
+}
+
+// checkReferenceGrant returns a *ReferenceNotAllowedError unless referrer may
+// read the toKind object namespace/name. References within the referrer's own
+// namespace, and from cluster-scoped referrers, need no grant.
+func checkReferenceGrant(ctx context.Context, c client.Client, referrer client.Object, toKind, namespace, name string) error {
+	if !requireReferenceGrants || referrer.GetNamespace() == "" || referrer.GetNamespace() == namespace {
+		return nil
+	}
+	referrerKind := kindOf(referrer)
+
+	grants := &keycloakv1beta1.KeycloakReferenceGrantList{}
+	if err := c.List(ctx, grants, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list KeycloakReferenceGrants in namespace %s: %w", namespace, err)
+	}
+	for i := range grants.Items {
+		if grantAllows(&grants.Items[i].Spec, referrerKind, referrer.GetNamespace(), toKind, name) {
+			return nil
+		}
+	}
+	return &ReferenceNotAllowedError{
+		Kind:         toKind,
+		Name:         namespace + "/" + name,
+		ReferrerKind: referrerKind,
+		Namespace:    referrer.GetNamespace(),
+		Hint:         "no KeycloakReferenceGrant in namespace " + namespace + " allows it",
+	}
+}
+
+// grantAllows reports whether a single grant admits the reference.
+func grantAllows(spec *keycloakv1beta1.KeycloakReferenceGrantSpec, fromKind, fromNamespace, toKind, toName string) bool {
+	fromOK := false
+	for _, from := range spec.From {
+		if from.Kind == fromKind && from.Namespace == fromNamespace {
+			fromOK = true
+			break
+		}
+	}
+	if !fromOK {
+		return false
+	}
+	for _, to := range spec.To {
+		if to.Kind == toKind && (to.Name == nil || *to.Name == toName) {
+			return true
+		}
+	}
+	return false
+}
+
+// findInstancesForGrant maps a KeycloakReferenceGrant to the KeycloakInstances
+// in the namespaces it admits, so they pick up a new or revoked grant without
+// waiting for the next sync.
+func findInstancesForGrant(c client.Client) func(context.Context, client.Object) []reconcile.Request {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		grant, ok := obj.(*keycloakv1beta1.KeycloakReferenceGrant)
+		if !ok {
+			return nil
+		}
+		var requests []reconcile.Request
+		seen := map[string]bool{}
+		for _, from := range grant.Spec.From {
+			if from.Kind != "KeycloakInstance" || seen[from.Namespace] {
+				continue
+			}
+			seen[from.Namespace] = true
+			instances := &keycloakv1beta1.KeycloakInstanceList{}
+			if err := c.List(ctx, instances, client.InNamespace(from.Namespace)); err != nil {
+				continue
+			}
+			for _, instance := range instances.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&instance)})
+			}
+		}
+		return requests
+	}
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func crossNamespaceInstance() *keycloakv1beta1.KeycloakInstance {
+	return &keycloakv1beta1.KeycloakInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kci", Namespace: "tenant"},
+		Spec: keycloakv1beta1.KeycloakInstanceSpec{
+			Auth: keycloakv1beta1.AuthSpec{
+				ClientCredentials: &keycloakv1beta1.ClientCredentialsSpec{
+					SecretRef: keycloakv1beta1.ClientCredentialsSecretRefSpec{Name: "creds", Namespace: strPtr("platform")},
+				},
+			},
+			TLS: &keycloakv1beta1.TLSSpec{
+				CACert: &keycloakv1beta1.CACertSource{
+					ConfigMapRef: &keycloakv1beta1.CACertConfigMapRefSpec{Name: "ca", Namespace: strPtr("platform")},
+				},
+			},
+		},
+	}
+}
+
+func TestGetKeycloakConfigFromInstance_ReferenceGrant(t *testing.T) {
+	creds := mkSecret("creds", "platform", map[string]string{"client-id": "op", "client-secret": "s"})
+	ca := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "platform"},
+		Data:       map[string]string{"ca.crt": "PEM"},
+	}
+	grant := func(to ...keycloakv1beta1.ReferenceGrantTo) *keycloakv1beta1.KeycloakReferenceGrant {
+		return &keycloakv1beta1.KeycloakReferenceGrant{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "platform"},
+			Spec: keycloakv1beta1.KeycloakReferenceGrantSpec{
+				From: []keycloakv1beta1.ReferenceGrantFrom{{Kind: "KeycloakInstance", Namespace: "tenant"}},
+				To:   to,
+			},
+		}
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		grant   *keycloakv1beta1.KeycloakReferenceGrant
+		allowed bool
+	}{
+		{name: "no grant"},
+		{name: "secret only", grant: grant(keycloakv1beta1.ReferenceGrantTo{Kind: "Secret"})},
+		{name: "other secret name", grant: grant(
+			keycloakv1beta1.ReferenceGrantTo{Kind: "Secret", Name: strPtr("other")},
+			keycloakv1beta1.ReferenceGrantTo{Kind: "ConfigMap"},
+		)},
+		{name: "named secret and all configmaps", grant: grant(
+			keycloakv1beta1.ReferenceGrantTo{Kind: "Secret", Name: strPtr("creds")},
+			keycloakv1beta1.ReferenceGrantTo{Kind: "ConfigMap"},
+		), allowed: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := crossNamespaceInstance()
+			objs := []client.Object{creds, ca, instance}
+			if tc.grant != nil {
+				objs = append(objs, tc.grant)
+			}
+			cfg, err := GetKeycloakConfigFromInstance(ctx, newAuthTestClient(t, objs...), instance)
+			if tc.allowed {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if cfg.ClientSecret != "s" || cfg.CACert != "PEM" {
+					t.Errorf("got secret %q, caCert %q", cfg.ClientSecret, cfg.CACert)
+				}
+				return
+			}
+			if !IsReferenceNotAllowed(err) {
+				t.Fatalf("got %v, want ReferenceNotAllowedError", err)
+			}
+			if got := notReadyReason(err, "Error"); got != ReferenceNotAllowedReason {
+				t.Errorf("reason: got %q, want %q", got, ReferenceNotAllowedReason)
+			}
+		})
+	}
+
+	t.Run("grants disabled", func(t *testing.T) {
+		SetRequireReferenceGrants(false)
+		t.Cleanup(func() { SetRequireReferenceGrants(true) })
+		instance := crossNamespaceInstance()
+		if _, err := GetKeycloakConfigFromInstance(ctx, newAuthTestClient(t, creds, ca, instance), instance); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestFindInstancesForGrant(t *testing.T) {
+	c := newAuthTestClient(t,
+		&keycloakv1beta1.KeycloakInstance{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "tenant"}},
+		&keycloakv1beta1.KeycloakInstance{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "other"}},
+	)
+	grant := &keycloakv1beta1.KeycloakReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "g", Namespace: "platform"},
+		Spec: keycloakv1beta1.KeycloakReferenceGrantSpec{
+			From: []keycloakv1beta1.ReferenceGrantFrom{
+				{Kind: "KeycloakInstance", Namespace: "tenant"},
+				{Kind: "KeycloakInstance", Namespace: "tenant"},
+			},
+			To: []keycloakv1beta1.ReferenceGrantTo{{Kind: "Secret"}},
+		},
+	}
+	got := findInstancesForGrant(c)(context.Background(), grant)
+	want := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "a", Namespace: "tenant"}}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ReconcileRequestedAtAnnotation requests an immediate reconcile when set or
+// changed, e.g. to re-check drift after a manual change in Keycloak. Any value
+// works; a timestamp is conventional. Once handled, the value is echoed to
+// status.lastHandledReconcileAt.
+const ReconcileRequestedAtAnnotation = "keycloak.hostzero.com/reconcile-requested-at"
+
+// syncJitter is the maximum fraction added to a resource's sync interval.
+const syncJitter = 0.1
+
+// recordReconcileRequest copies the ReconcileRequestedAtAnnotation of obj to
+// its status.LastHandledReconcileAt in memory; the status write that ends the
+// reconcile persists it. The annotation update itself enqueues the object, as
+// controllers watch every update of their primary kind, or at least those that
+// pass specOrAnnotationChanged.
+func recordReconcileRequest(obj client.Object) {
+	requested, ok := obj.GetAnnotations()[ReconcileRequestedAtAnnotation]
+	if !ok {
+		return
+	}
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	status := v.Elem().FieldByName("Status")
+	if !status.IsValid() {
+		return
+	}
+	field := status.FieldByName("LastHandledReconcileAt")
+	if field.IsValid() && field.Kind() == reflect.String && field.CanSet() {
+		field.SetString(requested)
+	}
+}
+
+// specOrAnnotationChanged passes updates that change the spec, and with it the
+// generation, or the annotations, such as ReconcileRequestedAtAnnotation, of a
+// primary resource, but not status-only updates. Reconcilers whose every pass
+// refreshes some status field watch their kind through it, so that their own
+// status writes do not enqueue them again; RequeueAfter drives their periodic
+// checks instead.
+var specOrAnnotationChanged = predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+
+// syncIntervalFor returns the delay until the next drift check of a ready obj:
+// its syncInterval plus up to syncJitter so that resources created together do
+// not keep hitting Keycloak in the same second.
+func syncIntervalFor(ctx context.Context, c client.Client, obj client.Object) time.Duration {
+	return wait.Jitter(syncInterval(ctx, c, obj), syncJitter)
+}
+
+// syncInterval returns the spec.syncInterval of obj or of its nearest parent
+// that sets one, or --sync-period.
+func syncInterval(ctx context.Context, c client.Client, obj client.Object) time.Duration {
+	owner, err := nearestInChain(ctx, c, obj, func(o client.Object) bool { return specSyncInterval(o) != nil })
+	if err != nil {
+		ctrl.LoggerFrom(ctx).V(1).Info("using the default sync period", "error", err.Error())
+	} else if owner != nil {
+		return specSyncInterval(owner).Duration
+	}
+	return GetSyncPeriod()
+}
+
+// specSyncInterval returns obj's spec.syncInterval, or nil when unset.
+func specSyncInterval(obj client.Object) *metav1.Duration {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	spec := v.Elem().FieldByName("Spec")
+	if !spec.IsValid() {
+		return nil
+	}
+	field := spec.FieldByName("SyncInterval")
+	if !field.IsValid() || field.Kind() != reflect.Ptr || field.IsNil() {
+		return nil
+	}
+	interval, ok := field.Interface().(*metav1.Duration)
+	if !ok || interval.Duration <= 0 {
+		return nil
+	}
+	return interval
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func TestRecordReconcileRequest(t *testing.T) {
+	kcClient := &keycloakv1beta1.KeycloakClient{}
+	recordReconcileRequest(kcClient)
+	if kcClient.Status.LastHandledReconcileAt != "" {
+		t.Errorf("got %q without annotation, want empty", kcClient.Status.LastHandledReconcileAt)
+	}
+
+	kcClient.Annotations = map[string]string{ReconcileRequestedAtAnnotation: "2026-01-02T03:04:05Z"}
+	recordReconcileRequest(kcClient)
+	if kcClient.Status.LastHandledReconcileAt != "2026-01-02T03:04:05Z" {
+		t.Errorf("got %q, want the annotation value", kcClient.Status.LastHandledReconcileAt)
+	}
+}
+
+func TestSyncIntervalFor(t *testing.T) {
+	realm := &keycloakv1beta1.KeycloakRealm{
+		ObjectMeta: metav1.ObjectMeta{Name: "realm", Namespace: "team"},
+		Spec:       keycloakv1beta1.KeycloakRealmSpec{SyncInterval: &metav1.Duration{Duration: time.Hour}},
+	}
+	c := newAuthTestClient(t, realm)
+	kcClient := func(interval *metav1.Duration) *keycloakv1beta1.KeycloakClient {
+		return &keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+			Spec: keycloakv1beta1.KeycloakClientSpec{
+				RealmRef:     &keycloakv1beta1.ResourceRef{Name: "realm"},
+				SyncInterval: interval,
+			},
+		}
+	}
+
+	cases := []struct {
+		name string
+		obj  *keycloakv1beta1.KeycloakClient
+		want time.Duration
+	}{
+		{name: "inherited from realm", obj: kcClient(nil), want: time.Hour},
+		{name: "own interval wins", obj: kcClient(&metav1.Duration{Duration: time.Minute}), want: time.Minute},
+		{name: "zero interval is ignored", obj: kcClient(&metav1.Duration{}), want: time.Hour},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := syncIntervalFor(context.Background(), c, tc.obj)
+			if got < tc.want || got > tc.want+time.Duration(float64(tc.want)*syncJitter) {
+				t.Errorf("got %v, want %v plus up to %.0f%%", got, tc.want, syncJitter*100)
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		orphan := kcClient(nil)
+		orphan.Spec.RealmRef.Name = "missing"
+		got := syncIntervalFor(context.Background(), c, orphan)
+		if got < GetSyncPeriod() || got > GetSyncPeriod()+time.Duration(float64(GetSyncPeriod())*syncJitter) {
+			t.Errorf("got %v, want the sync period %v plus jitter", got, GetSyncPeriod())
+		}
+	})
+}
+
+func TestSpecOrAnnotationChanged(t *testing.T) {
+	old := &keycloakv1beta1.KeycloakInstance{ObjectMeta: metav1.ObjectMeta{Name: "kc", Namespace: "team", Generation: 1}}
+
+	statusOnly := old.DeepCopy()
+	statusOnly.Status.Health = &keycloakv1beta1.InstanceHealthStatus{LatencyMilliseconds: 42}
+	specChanged := old.DeepCopy()
+	specChanged.Generation = 2
+	requested := old.DeepCopy()
+	requested.Annotations = map[string]string{ReconcileRequestedAtAnnotation: "2026-01-02T03:04:05Z"}
+
+	cases := []struct {
+		name string
+		obj  *keycloakv1beta1.KeycloakInstance
+		want bool
+	}{
+		{name: "status only", obj: statusOnly, want: false},
+		{name: "spec changed", obj: specChanged, want: true},
+		{name: "reconcile requested", obj: requested, want: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := specOrAnnotationChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: tc.obj}); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// WatchScope restricts which objects one operator deployment sees, so several
+// deployments can split a cluster between them (per team, or sharded by
+// label).
+//
+// Namespaces limits the namespaced kinds; cluster-scoped kinds are always
+// watched cluster-wide. LabelSelector applies to every Keycloak kind,
+// namespaced and cluster-scoped, so it has to match all objects of a reference
+// chain (instance, realm, children). Secrets, ConfigMaps, Namespaces,
+// KeycloakReferenceGrants and KeycloakQuotas are exempt from the label
+// selector: they are rarely labelled for the operator, and access and quota
+// checks must see all of them.
+type WatchScope struct {
+	Namespaces    []string
+	LabelSelector labels.Selector
+}
+
+// ParseWatchScope parses the --watch-namespaces (comma separated) and
+// --watch-label-selector flag values. Empty values mean no restriction.
+func ParseWatchScope(namespaces, labelSelector string) (WatchScope, error) {
+	var scope WatchScope
+	seen := map[string]bool{}
+	for _, ns := range strings.Split(namespaces, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		scope.Namespaces = append(scope.Namespaces, ns)
+	}
+	sort.Strings(scope.Namespaces)
+
+	if strings.TrimSpace(labelSelector) != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return WatchScope{}, fmt.Errorf("invalid label selector %q: %w", labelSelector, err)
+		}
+		scope.LabelSelector = selector
+	}
+	return scope, nil
+}
+
+// IsClusterWide reports whether the scope imposes no restriction at all.
+func (s WatchScope) IsClusterWide() bool {
+	return len(s.Namespaces) == 0 && s.LabelSelector == nil
+}
+
+// CoversNamespace reports whether the cache holds namespaced objects from
+// namespace.
+func (s WatchScope) CoversNamespace(namespace string) bool {
+	if len(s.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range s.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheOptions returns the manager cache configuration for the scope.
+func (s WatchScope) CacheOptions() cache.Options {
+	opts := cache.Options{
+		DefaultLabelSelector: s.LabelSelector,
+	}
+	if len(s.Namespaces) > 0 {
+		opts.DefaultNamespaces = make(map[string]cache.Config, len(s.Namespaces))
+		for _, ns := range s.Namespaces {
+			opts.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	if s.LabelSelector != nil {
+		opts.ByObject = map[client.Object]cache.ByObject{
+			&corev1.Secret{}:                          {Label: labels.Everything()},
+			&corev1.ConfigMap{}:                       {Label: labels.Everything()},
+			&corev1.Namespace{}:                       {Label: labels.Everything()},
+			&keycloakv1beta1.KeycloakReferenceGrant{}: {Label: labels.Everything()},
+			&keycloakv1beta1.KeycloakQuota{}:          {Label: labels.Everything()},
+		}
+	}
+	return opts
+}
+
+// NewClient returns a manager client constructor that reads through the
+// cache inside the scope and goes to the API server for namespaced objects
+// outside it. Cluster-scoped resources may reference Secrets and instances in
+// any namespace, which a namespace-restricted cache cannot serve.
+func (s WatchScope) NewClient(config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.New(config, options)
+	if err != nil || len(s.Namespaces) == 0 {
+		return c, err
+	}
+	direct, err := client.New(config, client.Options{
+		HTTPClient: options.HTTPClient,
+		Scheme:     options.Scheme,
+		Mapper:     options.Mapper,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &scopedClient{Client: c, direct: direct, scope: s}, nil
+}
+
+// scopedClient falls back to uncached reads for namespaces outside its scope.
+type scopedClient struct {
+	client.Client
+	direct client.Reader
+	scope  WatchScope
+}
+
+func (c *scopedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if key.Namespace != "" && !c.scope.CoversNamespace(key.Namespace) {
+		return c.direct.Get(ctx, key, obj, opts...)
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *scopedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.Namespace != "" && !c.scope.CoversNamespace(listOpts.Namespace) {
+		return c.direct.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+// allNamespacesReader returns a reader whose lists across all namespaces are
+// complete: the direct reader of a namespace-restricted client, c otherwise.
+// The cache of a namespace-restricted scope silently leaves the other
+// namespaces out of such lists, which is wrong wherever a missing object must
+// not be mistaken for an absent one.
+func allNamespacesReader(c client.Client) client.Reader {
+	if scoped, ok := c.(*scopedClient); ok {
+		return scoped.direct
+	}
+	return c
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func TestParseWatchScope(t *testing.T) {
+	scope, err := ParseWatchScope("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !scope.IsClusterWide() {
+		t.Errorf("empty flags should watch the whole cluster, got %+v", scope)
+	}
+
+	scope, err = ParseWatchScope(" team-b,team-a,,team-b ", "shard in (a,b)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := scope.Namespaces; len(got) != 2 || got[0] != "team-a" || got[1] != "team-b" {
+		t.Errorf("namespaces: got %v, want [team-a team-b]", got)
+	}
+	if !scope.CoversNamespace("team-a") || scope.CoversNamespace("other") {
+		t.Error("CoversNamespace should only cover the listed namespaces")
+	}
+	if scope.LabelSelector.String() != "shard in (a,b)" {
+		t.Errorf("label selector: got %q", scope.LabelSelector.String())
+	}
+
+	if _, err := ParseWatchScope("", "shard in (a"); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+}
+
+func TestWatchScopeCacheOptions(t *testing.T) {
+	scope, _ := ParseWatchScope("team-a", "shard=a")
+	opts := scope.CacheOptions()
+
+	if _, ok := opts.DefaultNamespaces["team-a"]; !ok || len(opts.DefaultNamespaces) != 1 {
+		t.Errorf("DefaultNamespaces: got %v", opts.DefaultNamespaces)
+	}
+	if opts.DefaultLabelSelector.String() != "shard=a" {
+		t.Errorf("DefaultLabelSelector: got %v", opts.DefaultLabelSelector)
+	}
+	exempt := map[string]bool{}
+	for obj, byObject := range opts.ByObject {
+		if byObject.Label.Empty() {
+			exempt[fmt.Sprintf("%T", obj)] = true
+		}
+	}
+	for _, obj := range []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}} {
+		if !exempt[fmt.Sprintf("%T", obj)] {
+			t.Errorf("%T should not be filtered by label", obj)
+		}
+	}
+
+	if opts := (WatchScope{}).CacheOptions(); opts.DefaultNamespaces != nil || opts.DefaultLabelSelector != nil || opts.ByObject != nil {
+		t.Errorf("cluster-wide scope should leave the cache unrestricted, got %+v", opts)
+	}
+}
+
+func TestScopedClientFallsBackOutsideScope(t *testing.T) {
+	inScope := mkSecret("creds", "team-a", map[string]string{"password": "a"})
+	outOfScope := mkSecret("creds", "shared", map[string]string{"password": "shared"})
+
+	// The cached client only holds the watched namespace; the direct reader
+	// sees everything.
+	cached := newAuthTestClient(t, inScope)
+	direct := newAuthTestClient(t, inScope, outOfScope, &keycloakv1beta1.KeycloakRealm{
+		ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: "shared"},
+	})
+	scope, _ := ParseWatchScope("team-a", "")
+	c := &scopedClient{Client: cached, direct: direct, scope: scope}
+	ctx := context.Background()
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "creds", Namespace: "shared"}, secret); err != nil {
+		t.Fatalf("out-of-scope get: %v", err)
+	}
+	if string(secret.Data["password"]) != "shared" {
+		t.Errorf("out-of-scope get returned %q", secret.Data["password"])
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "creds", Namespace: "team-a"}, secret); err != nil {
+		t.Fatalf("in-scope get: %v", err)
+	}
+
+	var realms keycloakv1beta1.KeycloakRealmList
+	if err := c.List(ctx, &realms, client.InNamespace("shared")); err != nil || len(realms.Items) != 1 {
+		t.Errorf("out-of-scope list: got %d items, err %v", len(realms.Items), err)
+	}
+	if err := c.List(ctx, &realms, client.InNamespace("team-a")); err != nil || len(realms.Items) != 0 {
+		t.Errorf("in-scope list should use the cache: got %d items, err %v", len(realms.Items), err)
+	}
+}
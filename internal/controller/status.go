@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -12,6 +13,31 @@ import (
 
 const ReadyConditionType = "Ready"
 
+// ReconcilingConditionType and StalledConditionType follow the kstatus
+// conventions used by Argo CD and Flux health checks. Both are derived from the
+// Ready condition on every status write and are only present while True:
+// Reconciling while a not-ready resource is expected to converge on its own
+// (waiting for a parent, Keycloak unreachable, …), Stalled while it needs a
+// change to its spec or environment first.
+const (
+	ReconcilingConditionType = "Reconciling"
+	StalledConditionType     = "Stalled"
+)
+
+// stalledReasons are the Ready reasons retrying cannot fix.
+var stalledReasons = map[string]bool{
+	"InvalidDefinition":              true,
+	"InvalidSecret":                  true,
+	"InvalidSpec":                    true,
+	"OrganizationRealmMismatch":      true,
+	"ProviderChangeUnsupported":      true,
+	InvalidIdentifierReason:          true,
+	QuotaExceededReason:              true,
+	ReferenceNotAllowedReason:        true,
+	UnsupportedByServerReason:        true,
+	UnsupportedDefinitionFieldReason: true,
+}
+
 // setReadyCondition adds or updates the "Ready" condition in the supplied slice,
 // preserving LastTransitionTime while the condition status does not flip.
 //
@@ -47,11 +73,13 @@ func setReadyCondition(conditions []metav1.Condition, ready bool, reason, messag
 
 // writeStatusIfChanged persists obj's status only when it differs from what the
 // API server already holds, then returns the requeue result for the reconcile
-// outcome.
+// outcome. A handled reconcile request is recorded on the way.
 //
 // Skipping no-op writes is what keeps a steady-state resource quiescent; see
 // setReadyCondition for why an unconditional write self-triggers.
 func writeStatusIfChanged(ctx context.Context, c client.Client, obj client.Object, ready bool) (ctrl.Result, error) {
+	recordReconcileRequest(obj)
+	setObservedGeneration(obj)
 	if !statusMatchesStored(ctx, c, obj) {
 		if err := c.Status().Update(ctx, obj); err != nil {
 			return ctrl.Result{}, err
@@ -59,11 +87,61 @@ func writeStatusIfChanged(ctx context.Context, c client.Client, obj client.Objec
 	}
 
 	if ready {
-		return ctrl.Result{RequeueAfter: GetSyncPeriod()}, nil
+		return ctrl.Result{RequeueAfter: syncIntervalFor(ctx, c, obj)}, nil
+	}
+	if readyReason(obj) == InstanceUnavailableReason {
+		return ctrl.Result{RequeueAfter: unavailableRequeueDelay()}, nil
 	}
 	return ctrl.Result{RequeueAfter: ErrorRequeueDelay}, nil
 }
 
+// setObservedGeneration records obj's generation as observed, on the status
+// and on the Ready condition, and derives the Reconciling and Stalled
+// conditions from the Ready condition.
+func setObservedGeneration(obj client.Object) {
+	generation := obj.GetGeneration()
+	if statusOf(obj) != nil {
+		field := reflect.ValueOf(obj).Elem().FieldByName("Status").FieldByName("ObservedGeneration")
+		if field.IsValid() && field.Kind() == reflect.Int64 {
+			field.SetInt(generation)
+		}
+	}
+
+	conditions := conditionsOf(obj)
+	if conditions == nil {
+		return
+	}
+	ready := meta.FindStatusCondition(*conditions, ReadyConditionType)
+	if ready == nil {
+		return
+	}
+	ready.ObservedGeneration = generation
+
+	switch {
+	case ready.Status == metav1.ConditionTrue:
+		meta.RemoveStatusCondition(conditions, ReconcilingConditionType)
+		meta.RemoveStatusCondition(conditions, StalledConditionType)
+	case stalledReasons[ready.Reason]:
+		meta.RemoveStatusCondition(conditions, ReconcilingConditionType)
+		setDerivedCondition(conditions, StalledConditionType, *ready)
+	default:
+		meta.RemoveStatusCondition(conditions, StalledConditionType)
+		setDerivedCondition(conditions, ReconcilingConditionType, *ready)
+	}
+}
+
+// setDerivedCondition sets a True condition of the given type carrying the
+// reason and message of the Ready condition it is derived from.
+func setDerivedCondition(conditions *[]metav1.Condition, conditionType string, ready metav1.Condition) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             ready.Reason,
+		Message:            ready.Message,
+		ObservedGeneration: ready.ObservedGeneration,
+	})
+}
+
 // statusMatchesStored reports whether obj's in-memory status already equals the
 // stored one. The comparison reads through the informer cache rather than
 // snapshotting at the top of updateStatus, because reconcilers routinely set
@@ -4,7 +4,10 @@ import (
 	"testing"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 )
 
 func TestSetReadyCondition(t *testing.T) {
@@ -135,3 +138,53 @@ func TestSetReadyCondition(t *testing.T) {
 		}
 	})
 }
+
+func TestSetObservedGeneration(t *testing.T) {
+	cases := []struct {
+		name            string
+		ready           bool
+		reason          string
+		wantReconciling bool
+		wantStalled     bool
+	}{
+		{name: "ready", ready: true, reason: "Ready"},
+		{name: "transient", reason: "ParentNotReady", wantReconciling: true},
+		{name: "instance unavailable", reason: InstanceUnavailableReason, wantReconciling: true},
+		{name: "invalid definition", reason: "InvalidDefinition", wantStalled: true},
+		{name: "reference not allowed", reason: ReferenceNotAllowedReason, wantStalled: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			realm := &keycloakv1beta1.KeycloakRealm{ObjectMeta: metav1.ObjectMeta{Generation: 4}}
+			// Left over from an earlier pass in the opposite state.
+			realm.Status.Conditions = []metav1.Condition{
+				{Type: ReconcilingConditionType, Status: metav1.ConditionTrue, Reason: "CreateFailed"},
+				{Type: StalledConditionType, Status: metav1.ConditionTrue, Reason: "InvalidSpec"},
+			}
+			realm.Status.Conditions = setReadyCondition(realm.Status.Conditions, tc.ready, tc.reason, "message")
+
+			setObservedGeneration(realm)
+
+			if realm.Status.ObservedGeneration != 4 {
+				t.Errorf("status.observedGeneration: got %d, want 4", realm.Status.ObservedGeneration)
+			}
+			conditions := realm.Status.Conditions
+			if ready := meta.FindStatusCondition(conditions, ReadyConditionType); ready.ObservedGeneration != 4 {
+				t.Errorf("Ready observedGeneration: got %d, want 4", ready.ObservedGeneration)
+			}
+			for conditionType, want := range map[string]bool{
+				ReconcilingConditionType: tc.wantReconciling,
+				StalledConditionType:     tc.wantStalled,
+			} {
+				cond := meta.FindStatusCondition(conditions, conditionType)
+				if (cond != nil) != want {
+					t.Errorf("%s: got %+v, want present=%v", conditionType, cond, want)
+					continue
+				}
+				if cond != nil && (cond.Status != metav1.ConditionTrue || cond.Reason != tc.reason || cond.ObservedGeneration != 4) {
+					t.Errorf("%s: got %+v", conditionType, cond)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SuspendedConditionType is the condition set to True while reconciliation of
+// a resource is suspended, by its own spec.suspend or by a suspended parent.
+const SuspendedConditionType = "Suspended"
+
+// SuspendedReason is the reason of the Suspended condition.
+const SuspendedReason = "Suspended"
+
+// reconcileSuspended ends the reconcile of obj early while obj or one of its
+// parents is suspended, recording the Suspended condition. Nothing is sent to
+// Keycloak and the finalizer stays, so a deletion waits for the resume.
+//
+// Resuming a parent does not enqueue its dependents, so suspended objects are
+// requeued after ErrorRequeueDelay; the check only reads the informer cache.
+func reconcileSuspended(ctx context.Context, c client.Client, obj client.Object) (ctrl.Result, bool, error) {
+	by, err := suspendedBy(ctx, c, obj)
+	if err != nil {
+		return ctrl.Result{}, true, err
+	}
+	conditions := conditionsOf(obj)
+	if by == nil {
+		if conditions != nil {
+			// Dropped from the stored status by the next status write.
+			meta.RemoveStatusCondition(conditions, SuspendedConditionType)
+		}
+		return ctrl.Result{}, false, nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+	message := "spec.suspend is set"
+	if by != obj {
+		message = fmt.Sprintf("%s %s is suspended", kindOf(by), objectName(by))
+	}
+	log.V(1).Info("reconciliation suspended", "reason", message)
+	if conditions != nil {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               SuspendedConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             SuspendedReason,
+			Message:            message,
+			ObservedGeneration: obj.GetGeneration(),
+		})
+		if !statusMatchesStored(ctx, c, obj) {
+			if err := c.Status().Update(ctx, obj); err != nil {
+				return ctrl.Result{}, true, err
+			}
+		}
+	}
+	return ctrl.Result{RequeueAfter: ErrorRequeueDelay}, true, nil
+}
+
+// suspendedBy returns obj if its spec.suspend is set, otherwise the nearest
+// suspended parent, or nil if none is suspended.
+func suspendedBy(ctx context.Context, c client.Client, obj client.Object) (client.Object, error) {
+	return nearestInChain(ctx, c, obj, specSuspend)
+}
+
+// specSuspend reports obj's spec.suspend.
+func specSuspend(obj client.Object) bool {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return false
+	}
+	spec := v.Elem().FieldByName("Spec")
+	if !spec.IsValid() {
+		return false
+	}
+	field := spec.FieldByName("Suspend")
+	return field.IsValid() && field.Kind() == reflect.Bool && field.Bool()
+}
+
+// conditionsOf returns a pointer to obj's Status.Conditions, or nil if the
+// type has none.
+func conditionsOf(obj client.Object) *[]metav1.Condition {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	status := v.Elem().FieldByName("Status")
+	if !status.IsValid() {
+		return nil
+	}
+	field := status.FieldByName("Conditions")
+	if !field.IsValid() || !field.CanAddr() {
+		return nil
+	}
+	conditions, ok := field.Addr().Interface().(*[]metav1.Condition)
+	if !ok {
+		return nil
+	}
+	return conditions
+}
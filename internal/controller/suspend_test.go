@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func suspendChain(instanceSuspended, realmSuspended bool) []client.Object {
+	return []client.Object{
+		&keycloakv1beta1.ClusterKeycloakInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kc"},
+			Spec:       keycloakv1beta1.ClusterKeycloakInstanceSpec{Suspend: instanceSuspended},
+		},
+		&keycloakv1beta1.KeycloakRealm{
+			ObjectMeta: metav1.ObjectMeta{Name: "realm", Namespace: "team"},
+			Spec: keycloakv1beta1.KeycloakRealmSpec{
+				ClusterInstanceRef: &keycloakv1beta1.ClusterResourceRef{Name: "kc"},
+				Suspend:            realmSuspended,
+			},
+		},
+		&keycloakv1beta1.KeycloakClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakClientSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "realm"}},
+		},
+	}
+}
+
+func TestSuspendedBy(t *testing.T) {
+	ctx := context.Background()
+	clientRole := func() *keycloakv1beta1.KeycloakRole {
+		return &keycloakv1beta1.KeycloakRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "team"},
+			Spec:       keycloakv1beta1.KeycloakRoleSpec{ClientRef: &keycloakv1beta1.ResourceRef{Name: "app"}},
+		}
+	}
+
+	cases := []struct {
+		name              string
+		instance, realm   bool
+		self              bool
+		wantKind, wantObj string
+	}{
+		{name: "nothing suspended"},
+		{name: "self", self: true, wantKind: "KeycloakRole", wantObj: "admin"},
+		{name: "realm", realm: true, wantKind: "KeycloakRealm", wantObj: "realm"},
+		{name: "instance", instance: true, wantKind: "ClusterKeycloakInstance", wantObj: "kc"},
+		{name: "nearest first", instance: true, realm: true, wantKind: "KeycloakRealm", wantObj: "realm"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			role := clientRole()
+			role.Spec.Suspend = tc.self
+			c := newAuthTestClient(t, suspendChain(tc.instance, tc.realm)...)
+			by, err := suspendedBy(ctx, c, role)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantObj == "" {
+				if by != nil {
+					t.Errorf("got %s %s, want not suspended", kindOf(by), by.GetName())
+				}
+				return
+			}
+			if by == nil || kindOf(by) != tc.wantKind || by.GetName() != tc.wantObj {
+				t.Fatalf("got %v, want %s %s", by, tc.wantKind, tc.wantObj)
+			}
+		})
+	}
+
+	t.Run("missing parent", func(t *testing.T) {
+		if by, err := suspendedBy(ctx, newAuthTestClient(t), clientRole()); err != nil || by != nil {
+			t.Errorf("got %v, %v; want nil, nil", by, err)
+		}
+	})
+}
+
+func TestReconcileSuspended_DefersDeletion(t *testing.T) {
+	objs := suspendChain(false, true)
+	group := &keycloakv1beta1.KeycloakGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "devs",
+			Namespace:         "team",
+			Finalizers:        []string{FinalizerName},
+			DeletionTimestamp: &metav1.Time{Time: metav1.Now().Time},
+		},
+		Spec: keycloakv1beta1.KeycloakGroupSpec{RealmRef: &keycloakv1beta1.ResourceRef{Name: "realm"}},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(newScheme(t)).
+		WithStatusSubresource(&keycloakv1beta1.KeycloakGroup{}).
+		WithObjects(append(objs, group)...).
+		Build()
+	r := &KeycloakGroupReconciler{Client: c}
+
+	key := types.NamespacedName{Name: "devs", Namespace: "team"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if result.RequeueAfter != ErrorRequeueDelay {
+		t.Errorf("requeueAfter: got %v, want %v", result.RequeueAfter, ErrorRequeueDelay)
+	}
+
+	got := &keycloakv1beta1.KeycloakGroup{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get group: %v", err)
+	}
+	if !controllerutil.ContainsFinalizer(got, FinalizerName) {
+		t.Error("finalizer removed while the realm is suspended")
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, SuspendedConditionType)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Message != "KeycloakRealm team/realm is suspended" {
+		t.Errorf("Suspended condition: got %+v", cond)
+	}
+}
+
+func TestReconcileSuspended_ClearsCondition(t *testing.T) {
+	kcClient := &keycloakv1beta1.KeycloakClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+		Status: keycloakv1beta1.KeycloakClientStatus{
+			Conditions: []metav1.Condition{{Type: SuspendedConditionType, Status: metav1.ConditionTrue, Reason: SuspendedReason}},
+		},
+	}
+	_, suspended, err := reconcileSuspended(context.Background(), newAuthTestClient(t), kcClient)
+	if err != nil || suspended {
+		t.Fatalf("got suspended=%v, err=%v; want false, nil", suspended, err)
+	}
+	if meta.FindStatusCondition(kcClient.Status.Conditions, SuspendedConditionType) != nil {
+		t.Error("Suspended condition not removed")
+	}
+}
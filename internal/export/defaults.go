@@ -0,0 +1,245 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// Defaults holds, per kind, the representation Keycloak returns for an
+// object created with nothing but its identifier. Exported definitions are
+// pruned of the fields equal to these defaults.
+type Defaults struct {
+	// Version is the Keycloak version the defaults were derived from
+	Version string `json:"version"`
+
+	// Kinds maps a CRD kind to the default representation of its objects
+	Kinds map[string]map[string]interface{} `json:"kinds"`
+}
+
+// defaultsDiscriminators are the fields whose value decides the defaults of
+// the other fields of a kind. Objects with a different value than the
+// derived default, such as SAML clients, are not pruned; a missing value is
+// the default.
+var defaultsDiscriminators = map[string]string{
+	"KeycloakClient":           "protocol",
+	"KeycloakClientScope":      "protocol",
+	"KeycloakIdentityProvider": "providerId",
+}
+
+// defaultsIgnoredFields are fields that differ between any two objects, and
+// are removed from the derived defaults.
+var defaultsIgnoredFields = []string{"id", "containerId", "createdTimestamp", "secret", "realm", "clientId", "name", "username", "alias", "internalId", "path"}
+
+// DeriveDefaults creates a blank object of each kind the exporter prunes in a
+// temporary realm, reads it back, and deletes the realm again.
+func DeriveDefaults(ctx context.Context, client *keycloak.Client) (*Defaults, error) {
+	info, err := client.GetServerInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	realm := "keycloak-operator-defaults-" + hex.EncodeToString(suffix)
+	if err := client.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"`+realm+`"}`)); err != nil {
+		return nil, fmt.Errorf("failed to create realm %s: %w", realm, err)
+	}
+	defer func() { _ = client.DeleteRealm(context.WithoutCancel(ctx), realm) }()
+
+	defaults := &Defaults{Version: info.SystemInfo.Version, Kinds: map[string]map[string]interface{}{}}
+	objects := []struct {
+		kind   string
+		create func() (string, error)
+		get    func(id string) (json.RawMessage, error)
+	}{
+		{
+			kind:   "KeycloakRealm",
+			create: func() (string, error) { return realm, nil },
+			get:    func(id string) (json.RawMessage, error) { return client.GetRealmRaw(ctx, id) },
+		},
+		{
+			kind: "KeycloakClient",
+			create: func() (string, error) {
+				return client.CreateClient(ctx, realm, json.RawMessage(`{"clientId":"defaults"}`))
+			},
+			get: func(id string) (json.RawMessage, error) { return client.GetClientRaw(ctx, realm, id) },
+		},
+		{
+			kind: "KeycloakClientScope",
+			create: func() (string, error) {
+				return client.CreateClientScope(ctx, realm, json.RawMessage(`{"name":"defaults","protocol":"openid-connect"}`))
+			},
+			get: func(id string) (json.RawMessage, error) { return client.GetClientScopeRaw(ctx, realm, id) },
+		},
+		{
+			kind: "KeycloakUser",
+			create: func() (string, error) {
+				return client.CreateUser(ctx, realm, json.RawMessage(`{"username":"defaults"}`))
+			},
+			get: func(id string) (json.RawMessage, error) { return client.GetUserRaw(ctx, realm, id) },
+		},
+		{
+			kind: "KeycloakGroup",
+			create: func() (string, error) {
+				return client.CreateGroup(ctx, realm, json.RawMessage(`{"name":"defaults"}`))
+			},
+			get: func(id string) (json.RawMessage, error) { return client.GetGroupRaw(ctx, realm, id) },
+		},
+		{
+			kind: "KeycloakRole",
+			create: func() (string, error) {
+				_, err := client.CreateRealmRole(ctx, realm, json.RawMessage(`{"name":"defaults"}`))
+				return "defaults", err
+			},
+			get: func(name string) (json.RawMessage, error) { return client.GetRealmRoleRaw(ctx, realm, name) },
+		},
+		{
+			kind: "KeycloakIdentityProvider",
+			create: func() (string, error) {
+				_, err := client.CreateIdentityProvider(ctx, realm, json.RawMessage(`{"alias":"defaults","providerId":"oidc"}`))
+				return "defaults", err
+			},
+			get: func(alias string) (json.RawMessage, error) { return client.GetIdentityProviderRaw(ctx, realm, alias) },
+		},
+	}
+
+	for _, obj := range objects {
+		id, err := obj.create()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create blank %s: %w", obj.kind, err)
+		}
+		raw, err := obj.get(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read blank %s: %w", obj.kind, err)
+		}
+		var rep map[string]interface{}
+		if err := json.Unmarshal(removeServerFields(raw, defaultsIgnoredFields...), &rep); err != nil {
+			return nil, fmt.Errorf("failed to decode blank %s: %w", obj.kind, err)
+		}
+		defaults.Kinds[obj.kind] = rep
+	}
+	return defaults, nil
+}
+
+// DefaultsCacheDir returns the directory defaults are cached in by default.
+func DefaultsCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keycloak-operator", "defaults"), nil
+}
+
+// LoadDefaults returns the defaults of the server's version from the cache
+// in dir, deriving and caching them if they are not cached yet.
+func LoadDefaults(ctx context.Context, client *keycloak.Client, dir string) (*Defaults, error) {
+	info, err := client.GetServerInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+	if defaults, err := CachedDefaults(dir, info.SystemInfo.Version); err == nil {
+		return defaults, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	defaults, err := DeriveDefaults(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(defaults, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create defaults cache: %w", err)
+	}
+	if err := os.WriteFile(defaultsFile(dir, defaults.Version), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write defaults cache: %w", err)
+	}
+	return defaults, nil
+}
+
+// CachedDefaults returns the defaults of version from the cache in dir. The
+// error wraps os.ErrNotExist if they are not cached.
+func CachedDefaults(dir, version string) (*Defaults, error) {
+	if version == "" {
+		return nil, fmt.Errorf("the Keycloak version is unknown")
+	}
+	data, err := os.ReadFile(defaultsFile(dir, version))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no defaults cached for Keycloak %s: %w", version, err)
+		}
+		return nil, err
+	}
+	var defaults Defaults
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, fmt.Errorf("failed to decode cached defaults for Keycloak %s: %w", version, err)
+	}
+	return &defaults, nil
+}
+
+func defaultsFile(dir, version string) string {
+	return filepath.Join(dir, sanitizeName(version)+".json")
+}
+
+// prune removes the fields of definition equal to the defaults of kind. It
+// returns definition unchanged if d is nil or has no defaults for the kind.
+func (d *Defaults) prune(kind string, definition json.RawMessage) json.RawMessage {
+	if d == nil || d.Kinds[kind] == nil {
+		return definition
+	}
+	defaults := d.Kinds[kind]
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(definition, &data); err != nil {
+		return definition
+	}
+	if field, ok := defaultsDiscriminators[kind]; ok {
+		if value, set := data[field]; set && !reflect.DeepEqual(value, defaults[field]) {
+			return definition
+		}
+	}
+
+	pruneMap(data, defaults)
+	result, err := json.Marshal(data)
+	if err != nil {
+		return definition
+	}
+	return result
+}
+
+// pruneMap removes the keys of data whose values equal those in defaults,
+// recursing into objects. Objects left empty are removed too.
+func pruneMap(data, defaults map[string]interface{}) {
+	for key, value := range data {
+		def, ok := defaults[key]
+		if !ok {
+			continue
+		}
+		if reflect.DeepEqual(value, def) {
+			delete(data, key)
+			continue
+		}
+		nested, ok := value.(map[string]interface{})
+		nestedDefaults, defOK := def.(map[string]interface{})
+		if ok && defOK {
+			pruneMap(nested, nestedDefaults)
+			if len(nested) == 0 {
+				delete(data, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	dir := t.TempDir()
+	_, err := CachedDefaults(dir, "24.0.5")
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	defaults, err := LoadDefaults(ctx, kc, dir)
+	require.NoError(t, err)
+	require.Equal(t, "24.0.5", defaults.Version)
+	require.Equal(t, true, defaults.Kinds["KeycloakClient"]["standardFlowEnabled"])
+	require.NotContains(t, defaults.Kinds["KeycloakClient"], "clientId")
+	for _, kind := range []string{"KeycloakRealm", "KeycloakClientScope", "KeycloakUser", "KeycloakGroup", "KeycloakRole", "KeycloakIdentityProvider"} {
+		require.Contains(t, defaults.Kinds, kind)
+	}
+	require.FileExists(t, filepath.Join(dir, "24-0-5.json"))
+
+	realms, err := kc.GetRealms(ctx)
+	require.NoError(t, err)
+	require.Len(t, realms, 1, "the temporary realm is deleted")
+
+	cached, err := CachedDefaults(dir, "24.0.5")
+	require.NoError(t, err)
+	require.Equal(t, defaults, cached)
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"acme","enabled":true}`)))
+	_, err = kc.CreateClient(ctx, "acme", json.RawMessage(`{"clientId":"app","directAccessGrantsEnabled":true}`))
+	require.NoError(t, err)
+	resources, err := NewExporter(kc, testr.New(t), ExporterOptions{
+		Realm:        "acme",
+		Include:      []string{ResourceTypeClients},
+		SkipDefaults: true,
+		Defaults:     cached,
+	}).Export(ctx)
+	require.NoError(t, err)
+	client := resources[0].Object.(*keycloakv1beta1.KeycloakClient)
+	def := definitionOf(t, client.Spec.Definition.Raw)
+	require.Equal(t, true, def["directAccessGrantsEnabled"])
+	require.NotContains(t, def, "standardFlowEnabled")
+	require.NotContains(t, def, "protocol")
+}
+
+func TestDefaultsPrune(t *testing.T) {
+	defaults := &Defaults{Kinds: map[string]map[string]interface{}{
+		"KeycloakClient": {
+			"protocol":           "openid-connect",
+			"frontchannelLogout": true,
+			"attributes": map[string]interface{}{
+				"post.logout.redirect.uris":  "+",
+				"pkce.code.challenge.method": "",
+			},
+			"defaultClientScopes": []interface{}{"profile", "email"},
+		},
+	}}
+
+	pruned := definitionOf(t, defaults.prune("KeycloakClient", json.RawMessage(`{
+		"protocol": "openid-connect",
+		"frontchannelLogout": false,
+		"attributes": {"post.logout.redirect.uris": "+", "pkce.code.challenge.method": "S256"},
+		"defaultClientScopes": ["profile", "email"]
+	}`)))
+	require.Equal(t, map[string]interface{}{
+		"frontchannelLogout": false,
+		"attributes":         map[string]interface{}{"pkce.code.challenge.method": "S256"},
+	}, pruned)
+
+	pruned = definitionOf(t, defaults.prune("KeycloakClient", json.RawMessage(`{
+		"attributes": {"post.logout.redirect.uris": "+"}
+	}`)))
+	require.Empty(t, pruned, "objects left empty are removed")
+
+	saml := json.RawMessage(`{"protocol":"saml","frontchannelLogout":true}`)
+	require.Equal(t, saml, defaults.prune("KeycloakClient", saml), "other protocols have other defaults")
+
+	var none *Defaults
+	require.Equal(t, saml, none.prune("KeycloakClient", saml))
+}
+
+func TestFileVersion(t *testing.T) {
+	version, err := FileVersion([]byte(`[{"realm":"a","keycloakVersion":"25.0.1"},{"realm":"b"}]`))
+	require.NoError(t, err)
+	require.Equal(t, "25.0.1", version)
+
+	version, err = FileVersion([]byte(`{"realm":"a"}`))
+	require.NoError(t, err)
+	require.Empty(t, version)
+}
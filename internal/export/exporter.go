@@ -18,6 +18,10 @@ import (
 // /admin/realms/{realm}/groups/{id}/children.
 const groupChildrenPageSize = 100
 
+// defaultInstanceRef is the instance generated manifests reference unless
+// ExporterOptions.InstanceRef is set.
+const defaultInstanceRef = "keycloak-instance"
+
 // ExporterOptions configures the export behavior
 type ExporterOptions struct {
 	// Realm to export
@@ -32,6 +36,25 @@ type ExporterOptions struct {
 	// Realm reference for generated manifests (defaults to realm name)
 	RealmRef string
 
+	// Emit a ClusterKeycloakRealm and clusterRealmRef references instead of
+	// a KeycloakRealm and realmRef
+	ClusterRealm bool
+
+	// Reference InstanceRef as a ClusterKeycloakInstance
+	ClusterInstance bool
+
+	// Prefix for the names of all generated objects but the realm
+	NamePrefix string
+
+	// How secret-bearing fields are exported (default: SecretModeNone)
+	Secrets SecretMode
+
+	// Secret store the ExternalSecrets of SecretModeExternalSecret read from
+	SecretStore SecretStoreOptions
+
+	// Defaults to prune from the exported definitions (nil keeps them)
+	Defaults *Defaults
+
 	// Include only these resource types (empty means all)
 	Include []string
 
@@ -53,25 +76,40 @@ type Exporter struct {
 
 // NewExporter creates a new exporter
 func NewExporter(client *keycloak.Client, log logr.Logger, opts ExporterOptions) *Exporter {
-	// Set defaults
-	if opts.RealmRef == "" {
-		opts.RealmRef = sanitizeName(opts.Realm)
-	}
-	if opts.InstanceRef == "" {
-		opts.InstanceRef = "keycloak-instance"
-	}
+	opts.setDefaults()
 
 	return &Exporter{
-		client: client,
-		log:    log.WithName("exporter"),
-		opts:   opts,
-		filter: NewFilter(opts.Include, opts.Exclude, opts.SkipDefaults),
-		transformer: NewTransformer(TransformerOptions{
-			TargetNamespace: opts.TargetNamespace,
-			InstanceRef:     opts.InstanceRef,
-			RealmRef:        opts.RealmRef,
-		}),
+		client:      client,
+		log:         log.WithName("exporter"),
+		opts:        opts,
+		filter:      NewFilter(opts.Include, opts.Exclude, opts.SkipDefaults),
+		transformer: newTransformerFor(opts),
+	}
+}
+
+// setDefaults fills in the realm and instance references left empty.
+func (o *ExporterOptions) setDefaults() {
+	if o.RealmRef == "" {
+		o.RealmRef = sanitizeName(o.Realm)
 	}
+	if o.InstanceRef == "" {
+		o.InstanceRef = defaultInstanceRef
+	}
+}
+
+// newTransformerFor creates the transformer for the manifest options in opts.
+func newTransformerFor(opts ExporterOptions) *Transformer {
+	return NewTransformer(TransformerOptions{
+		TargetNamespace: opts.TargetNamespace,
+		InstanceRef:     opts.InstanceRef,
+		RealmRef:        opts.RealmRef,
+		ClusterRealm:    opts.ClusterRealm,
+		ClusterInstance: opts.ClusterInstance,
+		NamePrefix:      opts.NamePrefix,
+		Secrets:         opts.Secrets,
+		SecretStore:     opts.SecretStore,
+		Defaults:        opts.Defaults,
+	})
 }
 
 // ExportedResource represents an exported Keycloak resource
@@ -80,6 +118,10 @@ type ExportedResource struct {
 	Name       string
 	APIVersion string
 	Object     interface{}
+
+	// Realm is the Keycloak realm the resource belongs to, empty for the
+	// instance and its Secret
+	Realm string
 }
 
 // Export exports all resources from the realm
@@ -93,6 +135,8 @@ func (e *Exporter) Export(ctx context.Context) ([]ExportedResource, error) {
 		fn       func(ctx context.Context) ([]ExportedResource, error)
 	}{
 		{"realm", ResourceTypeRealm, e.exportRealm},
+		{"required-actions", ResourceTypeRequiredActions, e.exportRequiredActions},
+		{"authentication-flows", ResourceTypeAuthenticationFlows, e.exportAuthenticationFlows},
 		{"client-scopes", ResourceTypeClientScopes, e.exportClientScopes},
 		{"clients", ResourceTypeClients, e.exportClients},
 		{"groups", ResourceTypeGroups, e.exportGroups},
@@ -119,10 +163,48 @@ func (e *Exporter) Export(ctx context.Context) ([]ExportedResource, error) {
 		}
 		resources = append(resources, res...)
 	}
+	resources = append(resources, e.transformer.Secrets()...)
+
+	for i := range resources {
+		resources[i].Realm = e.opts.Realm
+	}
+	return resources, nil
+}
+
+// ExportAllRealms exports every realm of the server, except the master realm
+// when opts.SkipDefaults is set. opts.Realm and opts.RealmRef are ignored.
+// The names of all objects but the realms are prefixed with their realm, so
+// the realms can share a namespace.
+func ExportAllRealms(ctx context.Context, client *keycloak.Client, log logr.Logger, opts ExporterOptions) ([]ExportedResource, error) {
+	realms, err := client.GetRealms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list realms: %w", err)
+	}
 
+	filter := NewFilter(opts.Include, opts.Exclude, opts.SkipDefaults)
+	var resources []ExportedResource
+	for _, realm := range realms {
+		name := stringValue(realm.Realm)
+		if filter.ShouldSkipRealm(name) {
+			continue
+		}
+		res, err := NewExporter(client, log, realmOptions(opts, name)).Export(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export realm %s: %w", name, err)
+		}
+		resources = append(resources, res...)
+	}
 	return resources, nil
 }
 
+// realmOptions returns opts for exporting realm as one of several.
+func realmOptions(opts ExporterOptions, realm string) ExporterOptions {
+	opts.Realm = realm
+	opts.RealmRef = ""
+	opts.NamePrefix = sanitizeName(realm)
+	return opts
+}
+
 func (e *Exporter) exportRealm(ctx context.Context) ([]ExportedResource, error) {
 	raw, err := e.client.GetRealmRaw(ctx, e.opts.Realm)
 	if err != nil {
@@ -184,6 +266,8 @@ func (e *Exporter) exportClients(ctx context.Context) ([]ExportedResource, error
 		return nil, fmt.Errorf("failed to get clients: %w", err)
 	}
 
+	e.transformer.SetFlowAliases(e.loadFlowAliases(ctx))
+
 	var resources []ExportedResource
 	for _, raw := range rawClients {
 		// Parse to check if we should skip
@@ -199,6 +283,10 @@ func (e *Exporter) exportClients(ctx context.Context) ([]ExportedResource, error
 			continue
 		}
 
+		if e.opts.Secrets == SecretModeValue {
+			raw = e.withClientSecret(ctx, raw, client.ID, client.ClientID)
+		}
+
 		resource, err := e.transformer.TransformClient(raw, client.ClientID)
 		if err != nil {
 			e.log.Error(err, "Failed to transform client", "clientId", client.ClientID)
@@ -230,6 +318,30 @@ func (e *Exporter) exportClients(ctx context.Context) ([]ExportedResource, error
 	return resources, nil
 }
 
+// withClientSecret returns raw with the secret of a confidential client read
+// from Keycloak, which may mask it in the client list.
+func (e *Exporter) withClientSecret(ctx context.Context, raw json.RawMessage, clientUUID, clientID string) json.RawMessage {
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return raw
+	}
+	if data["publicClient"] == true || data["bearerOnly"] == true {
+		return raw
+	}
+
+	secret, err := e.client.GetClientSecret(ctx, e.opts.Realm, clientUUID)
+	if err != nil {
+		e.log.Error(err, "Failed to get client secret", "clientId", clientID)
+		return raw
+	}
+	data["secret"] = secret
+	result, err := json.Marshal(data)
+	if err != nil {
+		return raw
+	}
+	return result
+}
+
 // groupHeader captures the fields we need from a raw group response to
 // determine its identity and whether it has children to fetch.
 type groupHeader struct {
@@ -556,6 +668,166 @@ func (e *Exporter) exportComponents(ctx context.Context) ([]ExportedResource, er
 	return resources, nil
 }
 
+func (e *Exporter) exportRequiredActions(ctx context.Context) ([]ExportedResource, error) {
+	rawActions, err := e.client.GetRequiredActionsRaw(ctx, e.opts.Realm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get required actions: %w", err)
+	}
+
+	var resources []ExportedResource
+	for _, raw := range rawActions {
+		var action struct {
+			Alias string `json:"alias"`
+		}
+		if err := json.Unmarshal(raw, &action); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipRequiredAction(action.Alias) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformRequiredAction(raw)
+		if err != nil {
+			e.log.Error(err, "Failed to transform required action", "alias", action.Alias)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func (e *Exporter) exportAuthenticationFlows(ctx context.Context) ([]ExportedResource, error) {
+	flows, err := e.client.GetAuthenticationFlows(ctx, e.opts.Realm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authentication flows: %w", err)
+	}
+
+	var resources []ExportedResource
+	for _, flow := range flows {
+		if flow.Alias == nil {
+			continue
+		}
+		if e.filter.ShouldSkipAuthenticationFlow(flow.BuiltIn != nil && *flow.BuiltIn) {
+			continue
+		}
+
+		infos, err := e.client.GetFlowExecutions(ctx, e.opts.Realm, *flow.Alias)
+		if err != nil {
+			e.log.Error(err, "Failed to get executions of authentication flow", "alias", *flow.Alias)
+			continue
+		}
+		executions, err := e.buildExecutionTree(ctx, infos)
+		if err != nil {
+			e.log.Error(err, "Failed to export executions of authentication flow", "alias", *flow.Alias)
+			continue
+		}
+
+		resource, err := e.transformer.TransformAuthenticationFlow(flow, executions)
+		if err != nil {
+			e.log.Error(err, "Failed to transform authentication flow", "alias", *flow.Alias)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// buildExecutionTree rebuilds the nested executions of a flow from the flat,
+// depth-first list Keycloak returns, where each entry carries its nesting
+// level.
+func (e *Exporter) buildExecutionTree(ctx context.Context, infos []keycloak.AuthenticationExecutionInfo) ([]FlowExecution, error) {
+	next := 0
+	var build func(level int) ([]FlowExecution, error)
+	build = func(level int) ([]FlowExecution, error) {
+		var executions []FlowExecution
+		for next < len(infos) {
+			info := infos[next]
+			infoLevel := 0
+			if info.Level != nil {
+				infoLevel = *info.Level
+			}
+			if infoLevel < level {
+				break
+			}
+			next++
+			if infoLevel > level {
+				// Children of a sub-flow that could not be read.
+				continue
+			}
+
+			execution, err := e.buildExecution(ctx, info)
+			if err != nil {
+				return nil, err
+			}
+			if execution.SubFlow != nil {
+				if execution.Executions, err = build(level + 1); err != nil {
+					return nil, err
+				}
+			}
+			executions = append(executions, execution)
+		}
+		return executions, nil
+	}
+	return build(0)
+}
+
+// buildExecution converts one execution into the shape the flow controller
+// parses. Sub-flows are looked up by ID for their provider, which the
+// execution listing does not report reliably.
+func (e *Exporter) buildExecution(ctx context.Context, info keycloak.AuthenticationExecutionInfo) (FlowExecution, error) {
+	var execution FlowExecution
+	if info.Requirement != nil {
+		execution.Requirement = *info.Requirement
+	}
+
+	if info.AuthenticationFlow != nil && *info.AuthenticationFlow {
+		if info.FlowID == nil {
+			return FlowExecution{}, fmt.Errorf("sub-flow execution %s has no flow ID", stringValue(info.ID))
+		}
+		flow, err := e.client.GetAuthenticationFlow(ctx, e.opts.Realm, *info.FlowID)
+		if err != nil {
+			return FlowExecution{}, fmt.Errorf("failed to get sub-flow %s: %w", *info.FlowID, err)
+		}
+		execution.SubFlow = &FlowDefinition{
+			Alias:       stringValue(flow.Alias),
+			ProviderID:  stringValue(flow.ProviderID),
+			Description: stringValue(flow.Description),
+		}
+		return execution, nil
+	}
+
+	execution.Authenticator = stringValue(info.ProviderID)
+	if info.AuthenticationConfig != nil && *info.AuthenticationConfig != "" {
+		config, err := e.client.GetExecutionConfig(ctx, e.opts.Realm, *info.AuthenticationConfig)
+		if err != nil {
+			return FlowExecution{}, fmt.Errorf("failed to get config of execution %q: %w", execution.Authenticator, err)
+		}
+		execution.AuthenticatorConfig = config.Config
+	}
+	return execution, nil
+}
+
+// loadFlowAliases maps the IDs of all top-level authentication flows to their
+// aliases, so client flow binding overrides can be exported by alias.
+func (e *Exporter) loadFlowAliases(ctx context.Context) map[string]string {
+	flows, err := e.client.GetAuthenticationFlows(ctx, e.opts.Realm)
+	if err != nil {
+		e.log.Error(err, "Failed to list authentication flows for client flow binding resolution")
+		return nil
+	}
+
+	aliases := make(map[string]string, len(flows))
+	for _, flow := range flows {
+		if flow.ID != nil && flow.Alias != nil {
+			aliases[*flow.ID] = *flow.Alias
+		}
+	}
+	return aliases
+}
+
 // loadOrganizationNames fetches organizations independently of include/exclude
 // filters so identity-provider export can resolve organizationRef even when
 // organizations themselves are not being exported.
@@ -578,7 +850,7 @@ func (e *Exporter) loadOrganizationNames(ctx context.Context) map[string]string
 		if err := json.Unmarshal(raw, &org); err != nil || org.ID == "" || org.Name == "" {
 			continue
 		}
-		names[org.ID] = sanitizeName(org.Name)
+		names[org.ID] = e.transformer.objectName(org.Name)
 	}
 	return names
 }
@@ -11,9 +11,11 @@ import (
 	"testing"
 
 	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
 	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
 )
 
 // fakeKeycloak is a minimal Keycloak admin API stand-in used to exercise the
@@ -388,3 +390,153 @@ func resourceKinds(rs []ExportedResource) []string {
 	}
 	return out
 }
+
+func TestExportAuthenticationFlowsAndRequiredActions(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+	topLevel := true
+	flowID, err := kc.CreateAuthenticationFlow(ctx, "test", keycloak.AuthenticationFlowRepresentation{
+		Alias:       strPtr("custom-browser"),
+		Description: strPtr("Cookie or OTP"),
+		ProviderID:  strPtr("basic-flow"),
+		TopLevel:    &topLevel,
+	})
+	require.NoError(t, err)
+	setRequirement := func(flowAlias, provider, requirement string) keycloak.AuthenticationExecutionInfo {
+		execs, err := kc.GetFlowExecutions(ctx, "test", flowAlias)
+		require.NoError(t, err)
+		for _, exec := range execs {
+			if stringValue(exec.ProviderID) == provider || stringValue(exec.DisplayName) == provider {
+				exec.Requirement = &requirement
+				require.NoError(t, kc.UpdateFlowExecution(ctx, "test", flowAlias, exec))
+				return exec
+			}
+		}
+		t.Fatalf("execution %q not found in %q", provider, flowAlias)
+		return keycloak.AuthenticationExecutionInfo{}
+	}
+	_, err = kc.AddFlowExecution(ctx, "test", "custom-browser", "auth-cookie")
+	require.NoError(t, err)
+	setRequirement("custom-browser", "auth-cookie", "ALTERNATIVE")
+	_, err = kc.AddFlowSubFlow(ctx, "test", "custom-browser", map[string]interface{}{
+		"alias": "custom-browser-forms", "provider": "basic-flow", "type": "basic-flow",
+	})
+	require.NoError(t, err)
+	setRequirement("custom-browser", "custom-browser-forms", "ALTERNATIVE")
+	_, err = kc.AddFlowExecution(ctx, "test", "custom-browser-forms", "auth-otp-form")
+	require.NoError(t, err)
+	otp := setRequirement("custom-browser-forms", "auth-otp-form", "REQUIRED")
+	_, err = kc.CreateExecutionConfig(ctx, "test", *otp.ID, keycloak.AuthenticatorConfigRepresentation{
+		Alias:  strPtr("otp"),
+		Config: map[string]string{"otpPolicy": "totp"},
+	})
+	require.NoError(t, err)
+
+	_, err = kc.CreateClient(ctx, "test", mustJSON(t, map[string]interface{}{
+		"clientId":                           "app",
+		"publicClient":                       true,
+		"authenticationFlowBindingOverrides": map[string]string{"browser": flowID},
+	}))
+	require.NoError(t, err)
+
+	exporter := NewExporter(kc, testr.New(t), ExporterOptions{
+		Realm:        "test",
+		Include:      []string{ResourceTypeAuthenticationFlows, ResourceTypeRequiredActions, ResourceTypeClients},
+		SkipDefaults: true,
+	})
+	resources, err := exporter.Export(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"KeycloakAuthenticationFlow/custom-browser", "KeycloakClient/app"}, resourceKinds(resources),
+		"built-in flows and default required actions are skipped")
+
+	flow := resources[0].Object.(*keycloakv1beta1.KeycloakAuthenticationFlow)
+	require.Equal(t, "custom-browser", flow.Spec.Alias)
+	require.Equal(t, "Cookie or OTP", flow.Spec.Description)
+	require.Equal(t, "basic-flow", flow.Spec.ProviderId)
+	require.JSONEq(t, `[
+		{"authenticator": "auth-cookie", "requirement": "ALTERNATIVE"},
+		{
+			"subFlow": {"alias": "custom-browser-forms", "providerId": "basic-flow"},
+			"requirement": "ALTERNATIVE",
+			"executions": [
+				{"authenticator": "auth-otp-form", "requirement": "REQUIRED", "authenticatorConfig": {"otpPolicy": "totp"}}
+			]
+		}
+	]`, string(flow.Spec.Executions.Raw))
+
+	client := resources[1].Object.(*keycloakv1beta1.KeycloakClient)
+	var definition struct {
+		Overrides map[string]string `json:"authenticationFlowBindingOverrides"`
+	}
+	require.NoError(t, json.Unmarshal(client.Spec.Definition.Raw, &definition))
+	require.Equal(t, map[string]string{"browserFlowAlias": "custom-browser"}, definition.Overrides)
+
+	exporter = NewExporter(kc, testr.New(t), ExporterOptions{
+		Realm:   "test",
+		Include: []string{ResourceTypeRequiredActions},
+	})
+	resources, err = exporter.Export(ctx)
+	require.NoError(t, err)
+	var aliases []string
+	for _, res := range resources {
+		action := res.Object.(*keycloakv1beta1.KeycloakRequiredAction)
+		aliases = append(aliases, *action.Spec.Alias)
+	}
+	require.Contains(t, aliases, "CONFIGURE_TOTP")
+}
+
+func TestExportAllRealms(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	for _, realm := range []string{"alpha", "beta"} {
+		require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"`+realm+`","enabled":true}`)))
+		_, err := kc.CreateClient(ctx, realm, json.RawMessage(`{"clientId":"app","publicClient":true}`))
+		require.NoError(t, err)
+	}
+
+	opts := ExporterOptions{
+		Realm:        "ignored",
+		Include:      []string{ResourceTypeRealm, ResourceTypeClients},
+		SkipDefaults: true,
+	}
+	resources, err := ExportAllRealms(ctx, kc, testr.New(t), opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"KeycloakRealm/alpha",
+		"KeycloakClient/alpha-app",
+		"KeycloakRealm/beta",
+		"KeycloakClient/beta-app",
+	}, resourceKinds(resources), "master is skipped and names are prefixed with the realm")
+	require.Equal(t, "beta", resources[3].Realm)
+	client := resources[3].Object.(*keycloakv1beta1.KeycloakClient)
+	require.Equal(t, "beta", client.Spec.RealmRef.Name)
+
+	opts.ClusterRealm = true
+	opts.ClusterInstance = true
+	opts.InstanceRef = "shared"
+	resources, err = ExportAllRealms(ctx, kc, testr.New(t), opts)
+	require.NoError(t, err)
+	realm := resources[0].Object.(*keycloakv1beta1.ClusterKeycloakRealm)
+	require.Equal(t, "alpha", realm.Name)
+	require.Empty(t, realm.Namespace)
+	require.Equal(t, "shared", realm.Spec.ClusterInstanceRef.Name)
+	require.Nil(t, realm.Spec.InstanceRef)
+	client = resources[1].Object.(*keycloakv1beta1.KeycloakClient)
+	require.Nil(t, client.Spec.RealmRef)
+	require.Equal(t, "alpha", client.Spec.ClusterRealmRef.Name)
+}
@@ -0,0 +1,766 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// realmDocumentFields are the RealmRepresentation keys exported as resources
+// of their own, or that only appear in file exports and have no counterpart in
+// the realm returned by the Admin API.
+var realmDocumentFields = []string{
+	"clients", "clientScopes", "roles", "groups", "users",
+	"identityProviders", "identityProviderMappers", "components",
+	"authenticationFlows", "authenticatorConfig", "requiredActions",
+	"organizations", "scopeMappings", "clientScopeMappings", "federatedUsers",
+}
+
+// realmDocument is the part of a RealmRepresentation, as written by
+// `kc.sh export` or the admin console's partial export, that FileExporter
+// reads.
+type realmDocument struct {
+	Realm           string            `json:"realm"`
+	KeycloakVersion string            `json:"keycloakVersion"`
+	Clients         []json.RawMessage `json:"clients"`
+	ClientScopes    []json.RawMessage `json:"clientScopes"`
+	Roles           struct {
+		Realm  []json.RawMessage            `json:"realm"`
+		Client map[string][]json.RawMessage `json:"client"`
+	} `json:"roles"`
+	Groups                  []json.RawMessage            `json:"groups"`
+	Users                   []json.RawMessage            `json:"users"`
+	IdentityProviders       []json.RawMessage            `json:"identityProviders"`
+	IdentityProviderMappers []json.RawMessage            `json:"identityProviderMappers"`
+	Components              map[string][]json.RawMessage `json:"components"`
+	AuthenticationFlows     []documentFlow               `json:"authenticationFlows"`
+	AuthenticatorConfig     []struct {
+		Alias  string            `json:"alias"`
+		Config map[string]string `json:"config"`
+	} `json:"authenticatorConfig"`
+	RequiredActions []json.RawMessage `json:"requiredActions"`
+	Organizations   []json.RawMessage `json:"organizations"`
+
+	raw json.RawMessage
+}
+
+// documentFlow is an authentication flow in a realm document. Unlike the Admin
+// API, the document lists sub-flows as flows of their own that executions
+// reference by alias.
+type documentFlow struct {
+	ID                       string              `json:"id"`
+	Alias                    string              `json:"alias"`
+	Description              string              `json:"description"`
+	ProviderID               string              `json:"providerId"`
+	TopLevel                 bool                `json:"topLevel"`
+	BuiltIn                  bool                `json:"builtIn"`
+	AuthenticationExecutions []documentExecution `json:"authenticationExecutions"`
+}
+
+type documentExecution struct {
+	Authenticator       string `json:"authenticator"`
+	AuthenticatorConfig string `json:"authenticatorConfig"`
+	AuthenticatorFlow   bool   `json:"authenticatorFlow"`
+	FlowAlias           string `json:"flowAlias"`
+	Requirement         string `json:"requirement"`
+	Priority            int    `json:"priority"`
+}
+
+// FileExporter converts a realm export file into CRD manifests without
+// connecting to Keycloak. It applies the same Filter and Transformer as
+// Exporter.
+type FileExporter struct {
+	log         logr.Logger
+	opts        ExporterOptions
+	filter      *Filter
+	transformer *Transformer
+	doc         *realmDocument
+
+	// clientIDs maps client UUIDs in the document to their clientId.
+	clientIDs map[string]string
+}
+
+// NewFileExporter parses data, a RealmRepresentation or an array of them as
+// written by `kc.sh export --file`. opts.Realm selects the realm of an array
+// and defaults to the realm of a single document.
+func NewFileExporter(data []byte, log logr.Logger, opts ExporterOptions) (*FileExporter, error) {
+	doc, err := parseRealmDocument(data, opts.Realm)
+	if err != nil {
+		return nil, err
+	}
+	opts.Realm = doc.Realm
+	opts.setDefaults()
+
+	clientIDs := make(map[string]string, len(doc.Clients))
+	for _, raw := range doc.Clients {
+		var client struct {
+			ID       string `json:"id"`
+			ClientID string `json:"clientId"`
+		}
+		if err := json.Unmarshal(raw, &client); err == nil && client.ID != "" {
+			clientIDs[client.ID] = client.ClientID
+		}
+	}
+
+	return &FileExporter{
+		log:         log.WithName("file-exporter"),
+		opts:        opts,
+		filter:      NewFilter(opts.Include, opts.Exclude, opts.SkipDefaults),
+		transformer: newTransformerFor(opts),
+		doc:         doc,
+		clientIDs:   clientIDs,
+	}, nil
+}
+
+// parseRealmDocument decodes a single realm, or the one named realm of an
+// array of realms.
+func parseRealmDocument(data []byte, realm string) (*realmDocument, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var docs []json.RawMessage
+		if err := json.Unmarshal(data, &docs); err != nil {
+			return nil, fmt.Errorf("failed to parse realm export: %w", err)
+		}
+		var names []string
+		for _, raw := range docs {
+			doc, err := decodeRealmDocument(raw)
+			if err != nil {
+				return nil, err
+			}
+			if doc.Realm == realm || (realm == "" && len(docs) == 1) {
+				return doc, nil
+			}
+			names = append(names, doc.Realm)
+		}
+		if realm == "" {
+			return nil, fmt.Errorf("the file contains %d realms (%s); select one with --realm", len(docs), strings.Join(names, ", "))
+		}
+		return nil, fmt.Errorf("realm %q not found in the file (found: %s)", realm, strings.Join(names, ", "))
+	}
+
+	doc, err := decodeRealmDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if realm != "" && doc.Realm != realm {
+		return nil, fmt.Errorf("the file contains realm %q, not %q", doc.Realm, realm)
+	}
+	return doc, nil
+}
+
+func decodeRealmDocument(raw json.RawMessage) (*realmDocument, error) {
+	doc := &realmDocument{raw: raw}
+	if err := json.Unmarshal(raw, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse realm export: %w", err)
+	}
+	if doc.Realm == "" {
+		return nil, fmt.Errorf("the realm export has no realm name")
+	}
+	return doc, nil
+}
+
+// Export converts the realm document into resources, in the same order and
+// with the same filters as Exporter.Export.
+func (e *FileExporter) Export() ([]ExportedResource, error) {
+	var resources []ExportedResource
+
+	exporters := []struct {
+		name     string
+		typeName string
+		fn       func() []ExportedResource
+	}{
+		{"realm", ResourceTypeRealm, e.exportRealm},
+		{"required-actions", ResourceTypeRequiredActions, e.exportRequiredActions},
+		{"authentication-flows", ResourceTypeAuthenticationFlows, e.exportAuthenticationFlows},
+		{"client-scopes", ResourceTypeClientScopes, e.exportClientScopes},
+		{"clients", ResourceTypeClients, e.exportClients},
+		{"groups", ResourceTypeGroups, e.exportGroups},
+		{"users", ResourceTypeUsers, e.exportUsers},
+		{"realm-roles", ResourceTypeRoles, e.exportRealmRoles},
+		{"identity-providers", ResourceTypeIdentityProviders, e.exportIdentityProviders},
+		{"components", ResourceTypeComponents, e.exportComponents},
+		{"organizations", ResourceTypeOrganizations, e.exportOrganizations},
+	}
+
+	for _, exp := range exporters {
+		if !e.filter.ShouldIncludeType(exp.typeName) {
+			e.log.V(1).Info("Skipping resource type", "type", exp.name)
+			continue
+		}
+		e.log.V(1).Info("Exporting", "type", exp.name)
+		resources = append(resources, exp.fn()...)
+	}
+	resources = append(resources, e.transformer.Secrets()...)
+
+	for i := range resources {
+		resources[i].Realm = e.opts.Realm
+	}
+	return resources, nil
+}
+
+// ExportAllRealmsFromFile converts every realm of data, a RealmRepresentation
+// or an array of them, like ExportAllRealms.
+func ExportAllRealmsFromFile(data []byte, log logr.Logger, opts ExporterOptions) ([]ExportedResource, error) {
+	realms, err := realmNames(data)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := NewFilter(opts.Include, opts.Exclude, opts.SkipDefaults)
+	var resources []ExportedResource
+	for _, realm := range realms {
+		if filter.ShouldSkipRealm(realm) {
+			continue
+		}
+		exporter, err := NewFileExporter(data, log, realmOptions(opts, realm))
+		if err != nil {
+			return nil, err
+		}
+		res, err := exporter.Export()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export realm %s: %w", realm, err)
+		}
+		resources = append(resources, res...)
+	}
+	return resources, nil
+}
+
+// FileVersion returns the Keycloak version that wrote a realm export file,
+// or "" if the file does not record it. Of an array of realms, the first
+// realm's version is returned.
+func FileVersion(data []byte) (string, error) {
+	raw := json.RawMessage(data)
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		var docs []json.RawMessage
+		if err := json.Unmarshal(data, &docs); err != nil {
+			return "", fmt.Errorf("failed to parse realm export: %w", err)
+		}
+		if len(docs) == 0 {
+			return "", nil
+		}
+		raw = docs[0]
+	}
+
+	doc, err := decodeRealmDocument(raw)
+	if err != nil {
+		return "", err
+	}
+	return doc.KeycloakVersion, nil
+}
+
+// realmNames returns the names of the realms in a realm export file.
+func realmNames(data []byte) ([]string, error) {
+	var docs []json.RawMessage
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		if err := json.Unmarshal(data, &docs); err != nil {
+			return nil, fmt.Errorf("failed to parse realm export: %w", err)
+		}
+	} else {
+		docs = []json.RawMessage{data}
+	}
+
+	names := make([]string, 0, len(docs))
+	for _, raw := range docs {
+		doc, err := decodeRealmDocument(raw)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, doc.Realm)
+	}
+	return names, nil
+}
+
+func (e *FileExporter) exportRealm() []ExportedResource {
+	definition := removeServerFields(e.doc.raw, realmDocumentFields...)
+	resource, err := e.transformer.TransformRealm(definition, e.doc.Realm)
+	if err != nil {
+		e.log.Error(err, "Failed to transform realm")
+		return nil
+	}
+	return []ExportedResource{resource}
+}
+
+func (e *FileExporter) exportRequiredActions() []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range e.doc.RequiredActions {
+		var action struct {
+			Alias string `json:"alias"`
+		}
+		if err := json.Unmarshal(raw, &action); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipRequiredAction(action.Alias) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformRequiredAction(raw)
+		if err != nil {
+			e.log.Error(err, "Failed to transform required action", "alias", action.Alias)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func (e *FileExporter) exportAuthenticationFlows() []ExportedResource {
+	flows := make(map[string]documentFlow, len(e.doc.AuthenticationFlows))
+	for _, flow := range e.doc.AuthenticationFlows {
+		flows[flow.Alias] = flow
+	}
+	configs := make(map[string]map[string]string, len(e.doc.AuthenticatorConfig))
+	for _, config := range e.doc.AuthenticatorConfig {
+		configs[config.Alias] = config.Config
+	}
+
+	var resources []ExportedResource
+	for _, flow := range e.doc.AuthenticationFlows {
+		if !flow.TopLevel || e.filter.ShouldSkipAuthenticationFlow(flow.BuiltIn) {
+			continue
+		}
+
+		executions, err := buildDocumentExecutions(flow, flows, configs, 0)
+		if err != nil {
+			e.log.Error(err, "Failed to export executions of authentication flow", "alias", flow.Alias)
+			continue
+		}
+
+		resource, err := e.transformer.TransformAuthenticationFlow(keycloak.AuthenticationFlowRepresentation{
+			Alias:       strPtr(flow.Alias),
+			Description: strPtr(flow.Description),
+			ProviderID:  strPtr(flow.ProviderID),
+		}, executions)
+		if err != nil {
+			e.log.Error(err, "Failed to transform authentication flow", "alias", flow.Alias)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// maxFlowDepth guards against sub-flow cycles in hand-edited documents.
+const maxFlowDepth = 32
+
+// buildDocumentExecutions rebuilds the nested executions of flow, resolving
+// sub-flows and authenticator configs by alias.
+func buildDocumentExecutions(flow documentFlow, flows map[string]documentFlow, configs map[string]map[string]string, depth int) ([]FlowExecution, error) {
+	if depth > maxFlowDepth {
+		return nil, fmt.Errorf("sub-flows of %q are nested deeper than %d levels", flow.Alias, maxFlowDepth)
+	}
+
+	ordered := append([]documentExecution(nil), flow.AuthenticationExecutions...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Priority < ordered[j].Priority })
+
+	executions := make([]FlowExecution, 0, len(ordered))
+	for _, exec := range ordered {
+		execution := FlowExecution{Requirement: exec.Requirement}
+		if !exec.AuthenticatorFlow {
+			execution.Authenticator = exec.Authenticator
+			if exec.AuthenticatorConfig != "" {
+				config, ok := configs[exec.AuthenticatorConfig]
+				if !ok {
+					return nil, fmt.Errorf("authenticator config %q of %q not found", exec.AuthenticatorConfig, exec.Authenticator)
+				}
+				execution.AuthenticatorConfig = config
+			}
+			executions = append(executions, execution)
+			continue
+		}
+
+		sub, ok := flows[exec.FlowAlias]
+		if !ok {
+			return nil, fmt.Errorf("sub-flow %q of %q not found", exec.FlowAlias, flow.Alias)
+		}
+		children, err := buildDocumentExecutions(sub, flows, configs, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		execution.SubFlow = &FlowDefinition{
+			Alias:       sub.Alias,
+			Description: sub.Description,
+			ProviderID:  sub.ProviderID,
+		}
+		execution.Executions = children
+		executions = append(executions, execution)
+	}
+	return executions, nil
+}
+
+func (e *FileExporter) exportClientScopes() []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range e.doc.ClientScopes {
+		var scope struct {
+			Name            string            `json:"name"`
+			ProtocolMappers []json.RawMessage `json:"protocolMappers"`
+		}
+		if err := json.Unmarshal(raw, &scope); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipClientScope(scope.Name) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformClientScope(raw)
+		if err != nil {
+			e.log.Error(err, "Failed to transform client scope", "name", scope.Name)
+			continue
+		}
+		resources = append(resources, resource)
+
+		if e.filter.ShouldIncludeType(ResourceTypeProtocolMappers) {
+			resources = append(resources, e.exportProtocolMappers(scope.ProtocolMappers, "", scope.Name)...)
+		}
+	}
+	return resources
+}
+
+func (e *FileExporter) exportClients() []ExportedResource {
+	flowAliases := make(map[string]string, len(e.doc.AuthenticationFlows))
+	for _, flow := range e.doc.AuthenticationFlows {
+		if flow.ID != "" {
+			flowAliases[flow.ID] = flow.Alias
+		}
+	}
+	e.transformer.SetFlowAliases(flowAliases)
+
+	var resources []ExportedResource
+	for _, raw := range e.doc.Clients {
+		var client struct {
+			ID              string            `json:"id"`
+			ClientID        string            `json:"clientId"`
+			ProtocolMappers []json.RawMessage `json:"protocolMappers"`
+		}
+		if err := json.Unmarshal(raw, &client); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipClient(client.ClientID) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformClient(raw, client.ClientID)
+		if err != nil {
+			e.log.Error(err, "Failed to transform client", "clientId", client.ClientID)
+			continue
+		}
+		resources = append(resources, resource)
+
+		if e.filter.ShouldIncludeType(ResourceTypeProtocolMappers) {
+			resources = append(resources, e.exportProtocolMappers(client.ProtocolMappers, client.ClientID, "")...)
+		}
+
+		if e.filter.ShouldIncludeType(ResourceTypeRoles) {
+			for _, roleRaw := range e.doc.Roles.Client[client.ClientID] {
+				var role struct {
+					Name string `json:"name"`
+				}
+				if err := json.Unmarshal(roleRaw, &role); err != nil {
+					continue
+				}
+				if e.filter.ShouldSkipRole(role.Name, true) {
+					continue
+				}
+				resource, err := e.transformer.TransformRole(roleRaw, client.ClientID, client.ID)
+				if err != nil {
+					e.log.Error(err, "Failed to transform client role", "name", role.Name, "client", client.ClientID)
+					continue
+				}
+				resources = append(resources, resource)
+			}
+		}
+	}
+	return resources
+}
+
+func (e *FileExporter) exportProtocolMappers(mappers []json.RawMessage, clientID, scopeName string) []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range mappers {
+		var mapper struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &mapper); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipProtocolMapper(mapper.Name) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformProtocolMapper(raw, clientID, scopeName)
+		if err != nil {
+			e.log.Error(err, "Failed to transform protocol mapper", "name", mapper.Name, "client", clientID, "scope", scopeName)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// documentRoleMappings are the role mappings a user or group carries inline
+// in a realm document.
+type documentRoleMappings struct {
+	RealmRoles  []string            `json:"realmRoles"`
+	ClientRoles map[string][]string `json:"clientRoles"`
+}
+
+func (e *FileExporter) exportGroups() []ExportedResource {
+	return e.exportGroupTree(e.doc.Groups, "")
+}
+
+func (e *FileExporter) exportGroupTree(groups []json.RawMessage, parentName string) []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range groups {
+		var group struct {
+			Name      string            `json:"name"`
+			SubGroups []json.RawMessage `json:"subGroups"`
+			documentRoleMappings
+		}
+		if err := json.Unmarshal(raw, &group); err != nil {
+			continue
+		}
+
+		definition := removeServerFields(raw, "realmRoles", "clientRoles")
+		resource, err := e.transformer.TransformGroup(definition, parentName)
+		if err != nil {
+			e.log.Error(err, "Failed to transform group", "name", group.Name, "parent", parentName)
+			continue
+		}
+		resources = append(resources, resource)
+
+		fullPath := group.Name
+		if parentName != "" {
+			fullPath = parentName + "/" + group.Name
+		}
+		if e.filter.ShouldIncludeType(ResourceTypeRoleMappings) {
+			resources = append(resources, e.exportRoleMappings("group", fullPath, group.documentRoleMappings)...)
+		}
+
+		resources = append(resources, e.exportGroupTree(group.SubGroups, fullPath)...)
+	}
+	return resources
+}
+
+func (e *FileExporter) exportUsers() []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range e.doc.Users {
+		var user struct {
+			Username string `json:"username"`
+			documentRoleMappings
+		}
+		if err := json.Unmarshal(raw, &user); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipUser(user.Username) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformUser(raw)
+		if err != nil {
+			e.log.Error(err, "Failed to transform user", "username", user.Username)
+			continue
+		}
+		resources = append(resources, resource)
+
+		if e.filter.ShouldIncludeType(ResourceTypeRoleMappings) {
+			resources = append(resources, e.exportRoleMappings("user", user.Username, user.documentRoleMappings)...)
+		}
+	}
+	return resources
+}
+
+func (e *FileExporter) exportRoleMappings(subjectType, subjectName string, mappings documentRoleMappings) []ExportedResource {
+	var resources []ExportedResource
+	for _, role := range mappings.RealmRoles {
+		if e.filter.ShouldSkipRole(role, false) {
+			continue
+		}
+		resource, err := e.transformer.TransformRoleMapping(subjectType, subjectName, role, "", "")
+		if err != nil {
+			e.log.Error(err, "Failed to transform role mapping", subjectType, subjectName, "role", role)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	clientIDs := make([]string, 0, len(mappings.ClientRoles))
+	for clientID := range mappings.ClientRoles {
+		clientIDs = append(clientIDs, clientID)
+	}
+	sort.Strings(clientIDs)
+	for _, clientID := range clientIDs {
+		for _, role := range mappings.ClientRoles[clientID] {
+			if e.filter.ShouldSkipRole(role, true) {
+				continue
+			}
+			resource, err := e.transformer.TransformRoleMapping(subjectType, subjectName, role, clientID, e.clientUUID(clientID))
+			if err != nil {
+				e.log.Error(err, "Failed to transform client role mapping", subjectType, subjectName, "role", role, "client", clientID)
+				continue
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// clientUUID returns the document's UUID of the client with clientID.
+func (e *FileExporter) clientUUID(clientID string) string {
+	for id, cid := range e.clientIDs {
+		if cid == clientID {
+			return id
+		}
+	}
+	return ""
+}
+
+func (e *FileExporter) exportRealmRoles() []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range e.doc.Roles.Realm {
+		var role struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &role); err != nil {
+			continue
+		}
+
+		if e.filter.ShouldSkipRole(role.Name, false) {
+			continue
+		}
+
+		resource, err := e.transformer.TransformRole(raw, "", "")
+		if err != nil {
+			e.log.Error(err, "Failed to transform realm role", "name", role.Name)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func (e *FileExporter) exportIdentityProviders() []ExportedResource {
+	organizationNames := make(map[string]string, len(e.doc.Organizations))
+	for _, raw := range e.doc.Organizations {
+		var org struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &org); err == nil && org.ID != "" && org.Name != "" {
+			organizationNames[org.ID] = e.transformer.objectName(org.Name)
+		}
+	}
+	e.transformer.SetOrganizationNames(organizationNames)
+
+	var resources []ExportedResource
+	for _, raw := range e.doc.IdentityProviders {
+		var idp struct {
+			Alias string `json:"alias"`
+		}
+		if err := json.Unmarshal(raw, &idp); err != nil {
+			continue
+		}
+
+		resource, err := e.transformer.TransformIdentityProvider(raw)
+		if err != nil {
+			e.log.Error(err, "Failed to transform identity provider", "alias", idp.Alias)
+			continue
+		}
+		resources = append(resources, resource)
+
+		if !e.filter.ShouldIncludeType(ResourceTypeIdentityProviderMappers) {
+			continue
+		}
+		for _, mapperRaw := range e.doc.IdentityProviderMappers {
+			var mapper struct {
+				Name                  string `json:"name"`
+				IdentityProviderAlias string `json:"identityProviderAlias"`
+			}
+			if err := json.Unmarshal(mapperRaw, &mapper); err != nil || mapper.IdentityProviderAlias != idp.Alias {
+				continue
+			}
+			resource, err := e.transformer.TransformIdentityProviderMapper(mapperRaw, idp.Alias)
+			if err != nil {
+				e.log.Error(err, "Failed to transform identity provider mapper", "alias", idp.Alias, "name", mapper.Name)
+				continue
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// exportComponents flattens the document's components, which are grouped by
+// provider type with sub-components nested in their parent, into the flat
+// list the Admin API returns.
+func (e *FileExporter) exportComponents() []ExportedResource {
+	return e.exportComponentGroups(e.doc.Components)
+}
+
+func (e *FileExporter) exportComponentGroups(groups map[string][]json.RawMessage) []ExportedResource {
+	providerTypes := make([]string, 0, len(groups))
+	for providerType := range groups {
+		providerTypes = append(providerTypes, providerType)
+	}
+	sort.Strings(providerTypes)
+
+	var resources []ExportedResource
+	for _, providerType := range providerTypes {
+		for _, raw := range groups[providerType] {
+			var component map[string]interface{}
+			if err := json.Unmarshal(raw, &component); err != nil {
+				continue
+			}
+			name, _ := component["name"].(string)
+			var subComponents map[string][]json.RawMessage
+			if sub, err := json.Marshal(component["subComponents"]); err == nil {
+				_ = json.Unmarshal(sub, &subComponents)
+			}
+			delete(component, "subComponents")
+			component["providerType"] = providerType
+
+			if !e.filter.ShouldSkipComponent(name, providerType) {
+				definition, err := json.Marshal(component)
+				if err != nil {
+					continue
+				}
+				resource, err := e.transformer.TransformComponent(definition)
+				if err != nil {
+					e.log.Error(err, "Failed to transform component", "name", name)
+					continue
+				}
+				resources = append(resources, resource)
+			}
+
+			resources = append(resources, e.exportComponentGroups(subComponents)...)
+		}
+	}
+	return resources
+}
+
+func (e *FileExporter) exportOrganizations() []ExportedResource {
+	var resources []ExportedResource
+	for _, raw := range e.doc.Organizations {
+		var org struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &org); err != nil {
+			continue
+		}
+
+		resource, err := e.transformer.TransformOrganization(raw)
+		if err != nil {
+			e.log.Error(err, "Failed to transform organization", "name", org.Name)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
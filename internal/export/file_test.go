@@ -0,0 +1,158 @@
+package export
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// realmExportFile is a trimmed `kc.sh export` document: sub-flows are listed
+// as flows of their own, roles and components are grouped, and users and
+// groups carry their role mappings inline.
+const realmExportFile = `{
+	"id": "0b1c",
+	"realm": "test",
+	"enabled": true,
+	"roles": {
+		"realm": [{"id": "r1", "name": "reader"}],
+		"client": {"app": [{"id": "r2", "name": "admin", "clientRole": true}]}
+	},
+	"groups": [{
+		"id": "g1", "name": "team", "path": "/team",
+		"realmRoles": ["reader"],
+		"subGroups": [{"id": "g2", "name": "ops", "path": "/team/ops", "clientRoles": {"app": ["admin"]}}]
+	}],
+	"users": [{"id": "u1", "username": "alice", "realmRoles": ["reader"]}],
+	"clients": [{
+		"id": "c1", "clientId": "app", "publicClient": true,
+		"authenticationFlowBindingOverrides": {"browser": "f1"},
+		"protocolMappers": [{"id": "m1", "name": "tenant", "protocol": "openid-connect", "protocolMapper": "oidc-hardcoded-claim-mapper"}]
+	}],
+	"authenticationFlows": [
+		{
+			"id": "f1", "alias": "custom-browser", "description": "Cookie or OTP", "providerId": "basic-flow",
+			"topLevel": true, "builtIn": false,
+			"authenticationExecutions": [
+				{"authenticatorFlow": true, "flowAlias": "custom-browser-forms", "requirement": "ALTERNATIVE", "priority": 20},
+				{"authenticator": "auth-cookie", "authenticatorFlow": false, "requirement": "ALTERNATIVE", "priority": 10}
+			]
+		},
+		{
+			"id": "f2", "alias": "custom-browser-forms", "providerId": "basic-flow", "topLevel": false, "builtIn": false,
+			"authenticationExecutions": [
+				{"authenticator": "auth-otp-form", "authenticatorConfig": "otp", "authenticatorFlow": false, "requirement": "REQUIRED", "priority": 10}
+			]
+		},
+		{"id": "f3", "alias": "browser", "providerId": "basic-flow", "topLevel": true, "builtIn": true, "authenticationExecutions": []}
+	],
+	"authenticatorConfig": [{"id": "a1", "alias": "otp", "config": {"otpPolicy": "totp"}}],
+	"components": {
+		"org.keycloak.storage.UserStorageProvider": [{
+			"id": "k1", "name": "ldap", "providerId": "ldap",
+			"config": {"bindCredential": ["secret"]},
+			"subComponents": {
+				"org.keycloak.storage.ldap.mappers.LDAPStorageMapper": [{"id": "k2", "name": "email", "providerId": "user-attribute-ldap-mapper"}]
+			}
+		}]
+	}
+}`
+
+func TestFileExporter(t *testing.T) {
+	exporter, err := NewFileExporter([]byte(realmExportFile), testr.New(t), ExporterOptions{SkipDefaults: true})
+	require.NoError(t, err)
+	resources, err := exporter.Export()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"KeycloakRealm/test",
+		"KeycloakAuthenticationFlow/custom-browser",
+		"KeycloakClient/app",
+		"KeycloakProtocolMapper/app-tenant",
+		"KeycloakRole/app-admin",
+		"KeycloakGroup/team",
+		"KeycloakRoleMapping/team-reader",
+		"KeycloakGroup/team-ops",
+		"KeycloakRoleMapping/team-ops-app-admin",
+		"KeycloakUser/alice",
+		"KeycloakRoleMapping/alice-reader",
+		"KeycloakRole/reader",
+		"KeycloakComponent/userstorageprovider-ldap",
+		"KeycloakComponent/ldapstoragemapper-email",
+	}, resourceKinds(resources))
+
+	realm := resources[0].Object.(*keycloakv1beta1.KeycloakRealm)
+	require.JSONEq(t, `{"enabled": true}`, string(realm.Spec.Definition.Raw),
+		"nested resources are exported on their own")
+
+	flow := resources[1].Object.(*keycloakv1beta1.KeycloakAuthenticationFlow)
+	require.JSONEq(t, `[
+		{"authenticator": "auth-cookie", "requirement": "ALTERNATIVE"},
+		{
+			"subFlow": {"alias": "custom-browser-forms", "providerId": "basic-flow"},
+			"requirement": "ALTERNATIVE",
+			"executions": [
+				{"authenticator": "auth-otp-form", "requirement": "REQUIRED", "authenticatorConfig": {"otpPolicy": "totp"}}
+			]
+		}
+	]`, string(flow.Spec.Executions.Raw))
+
+	client := resources[2].Object.(*keycloakv1beta1.KeycloakClient)
+	var definition map[string]interface{}
+	require.NoError(t, json.Unmarshal(client.Spec.Definition.Raw, &definition))
+	require.Equal(t, map[string]interface{}{"browserFlowAlias": "custom-browser"}, definition["authenticationFlowBindingOverrides"])
+	require.NotContains(t, definition, "protocolMappers")
+
+	group := resources[5].Object.(*keycloakv1beta1.KeycloakGroup)
+	require.JSONEq(t, `{"name": "team"}`, string(group.Spec.Definition.Raw),
+		"inline role mappings become KeycloakRoleMappings")
+
+	subgroup := resources[7].Object.(*keycloakv1beta1.KeycloakGroup)
+	require.Nil(t, subgroup.Spec.RealmRef, "subgroups reference only their parent")
+	require.Equal(t, "team", subgroup.Spec.ParentGroupRef.Name)
+	clientRole := resources[4].Object.(*keycloakv1beta1.KeycloakRole)
+	require.Nil(t, clientRole.Spec.RealmRef, "client roles reference only their client")
+	require.Equal(t, "app", clientRole.Spec.ClientRef.Name)
+
+	component := resources[12].Object.(*keycloakv1beta1.KeycloakComponent)
+	require.NotContains(t, string(component.Spec.Definition.Raw), "subComponents")
+	require.Contains(t, string(component.Spec.Definition.Raw), `"providerType":"org.keycloak.storage.UserStorageProvider"`)
+}
+
+func TestNewFileExporterSelectsRealm(t *testing.T) {
+	data := []byte(`[{"realm": "master"}, {"realm": "test"}]`)
+
+	_, err := NewFileExporter(data, testr.New(t), ExporterOptions{})
+	require.ErrorContains(t, err, "select one with --realm")
+
+	_, err = NewFileExporter(data, testr.New(t), ExporterOptions{Realm: "other"})
+	require.ErrorContains(t, err, `realm "other" not found`)
+
+	exporter, err := NewFileExporter(data, testr.New(t), ExporterOptions{Realm: "test"})
+	require.NoError(t, err)
+	resources, err := exporter.Export()
+	require.NoError(t, err)
+	require.Equal(t, []string{"KeycloakRealm/test"}, resourceKinds(resources))
+
+	_, err = NewFileExporter([]byte(`{"realm": "test"}`), testr.New(t), ExporterOptions{Realm: "other"})
+	require.ErrorContains(t, err, `contains realm "test"`)
+}
+
+func TestExportAllRealmsFromFile(t *testing.T) {
+	data := []byte(`[{"realm": "master"}, {"realm": "test"}, {"realm": "Other Realm"}]`)
+
+	resources, err := ExportAllRealmsFromFile(data, testr.New(t), ExporterOptions{SkipDefaults: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"KeycloakRealm/test", "KeycloakRealm/other-realm"}, resourceKinds(resources))
+	require.Equal(t, "Other Realm", resources[1].Realm)
+
+	resources, err = ExportAllRealmsFromFile([]byte(realmExportFile), testr.New(t), ExporterOptions{SkipDefaults: true})
+	require.NoError(t, err)
+	mapping := resources[10].Object.(*keycloakv1beta1.KeycloakRoleMapping)
+	require.Equal(t, "test-alice-reader", mapping.Name)
+	require.Equal(t, "test-alice", mapping.Spec.Subject.UserRef.Name, "references are prefixed too")
+	require.Equal(t, "test-reader", mapping.Spec.RoleRef.Name)
+}
@@ -16,6 +16,8 @@ const (
 	ResourceTypeComponents              = "components"
 	ResourceTypeProtocolMappers         = "protocol-mappers"
 	ResourceTypeOrganizations           = "organizations"
+	ResourceTypeRequiredActions         = "required-actions"
+	ResourceTypeAuthenticationFlows     = "authentication-flows"
 )
 
 // Default Keycloak built-in clients to skip
@@ -93,6 +95,24 @@ var defaultComponentProviderTypes = map[string]bool{
 	"org.keycloak.keys.KeyProvider": true,
 }
 
+// Default Keycloak required actions to skip
+var defaultRequiredActions = map[string]bool{
+	"CONFIGURE_TOTP":                 true,
+	"TERMS_AND_CONDITIONS":           true,
+	"UPDATE_PASSWORD":                true,
+	"UPDATE_PROFILE":                 true,
+	"UPDATE_EMAIL":                   true,
+	"VERIFY_EMAIL":                   true,
+	"VERIFY_PROFILE":                 true,
+	"CONFIGURE_RECOVERY_AUTHN_CODES": true,
+	"delete_account":                 true,
+	"delete_credential":              true,
+	"idp_link":                       true,
+	"update_user_locale":             true,
+	"webauthn-register":              true,
+	"webauthn-register-passwordless": true,
+}
+
 // Default protocol mappers to skip
 var defaultProtocolMappers = map[string]bool{
 	// Built-in protocol mappers
@@ -169,6 +189,12 @@ func (f *Filter) ShouldIncludeType(resourceType string) bool {
 	return true
 }
 
+// ShouldSkipRealm checks if a realm should be skipped when exporting all
+// realms. The master realm holds Keycloak's own administration.
+func (f *Filter) ShouldSkipRealm(name string) bool {
+	return f.skipDefaults && name == "master"
+}
+
 // ShouldSkipClient checks if a client should be skipped
 func (f *Filter) ShouldSkipClient(clientID string) bool {
 	if !f.skipDefaults {
@@ -243,6 +269,21 @@ func (f *Filter) ShouldSkipComponent(name, providerType string) bool {
 	return defaultComponentProviderTypes[providerType]
 }
 
+// ShouldSkipRequiredAction checks if a required action should be skipped
+func (f *Filter) ShouldSkipRequiredAction(alias string) bool {
+	if !f.skipDefaults {
+		return false
+	}
+
+	return defaultRequiredActions[alias]
+}
+
+// ShouldSkipAuthenticationFlow checks if an authentication flow should be
+// skipped. Keycloak marks the flows it creates itself as built in.
+func (f *Filter) ShouldSkipAuthenticationFlow(builtIn bool) bool {
+	return f.skipDefaults && builtIn
+}
+
 // ShouldSkipProtocolMapper checks if a protocol mapper should be skipped
 func (f *Filter) ShouldSkipProtocolMapper(name string) bool {
 	if !f.skipDefaults {
@@ -0,0 +1,97 @@
+package export
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// Placeholders for the admin credentials of a generated instance. The apply
+// subcommand replaces them with the environment variables of the same name.
+const (
+	usernamePlaceholder = "${KEYCLOAK_ADMIN_USERNAME}"
+	passwordPlaceholder = "${KEYCLOAK_ADMIN_PASSWORD}"
+)
+
+// InstanceManifests returns the KeycloakInstance the exported realms
+// reference, or a ClusterKeycloakInstance if opts.ClusterInstance is set,
+// and the Secret with its admin credentials. The password, and the username
+// if it is empty, are placeholders to be filled in before applying.
+func InstanceManifests(opts ExporterOptions, baseURL, username string) []ExportedResource {
+	name := opts.InstanceRef
+	if name == "" {
+		name = defaultInstanceRef
+	}
+	if username == "" {
+		username = usernamePlaceholder
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sanitizeName(name + "-admin"),
+			Namespace: opts.TargetNamespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"username": username,
+			"password": passwordPlaceholder,
+		},
+	}
+
+	var instance ExportedResource
+	if opts.ClusterInstance {
+		obj := &keycloakv1beta1.ClusterKeycloakInstance{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "keycloak.hostzero.com/v1beta1",
+				Kind:       "ClusterKeycloakInstance",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: keycloakv1beta1.ClusterKeycloakInstanceSpec{
+				BaseUrl: baseURL,
+				Auth: keycloakv1beta1.ClusterAuthSpec{
+					PasswordGrant: &keycloakv1beta1.ClusterPasswordGrantSpec{
+						SecretRef: keycloakv1beta1.ClusterPasswordGrantSecretRefSpec{
+							Name:      secret.Name,
+							Namespace: secret.Namespace,
+						},
+					},
+				},
+			},
+		}
+		instance = ExportedResource{Kind: obj.Kind, Name: obj.Name, APIVersion: obj.APIVersion, Object: obj}
+	} else {
+		obj := &keycloakv1beta1.KeycloakInstance{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "keycloak.hostzero.com/v1beta1",
+				Kind:       "KeycloakInstance",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: opts.TargetNamespace,
+			},
+			Spec: keycloakv1beta1.KeycloakInstanceSpec{
+				BaseUrl: baseURL,
+				Auth: keycloakv1beta1.AuthSpec{
+					PasswordGrant: &keycloakv1beta1.PasswordGrantSpec{
+						SecretRef: keycloakv1beta1.PasswordGrantSecretRefSpec{
+							Name: secret.Name,
+						},
+					},
+				},
+			},
+		}
+		instance = ExportedResource{Kind: obj.Kind, Name: obj.Name, APIVersion: obj.APIVersion, Object: obj}
+	}
+
+	return []ExportedResource{
+		instance,
+		{Kind: "Secret", Name: secret.Name, APIVersion: "v1", Object: secret},
+	}
+}
@@ -0,0 +1,349 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Layout is the structure the writer gives an output directory.
+type Layout string
+
+const (
+	// LayoutDirectory writes a file per resource, in a directory per kind.
+	LayoutDirectory Layout = "directory"
+
+	// LayoutKustomize writes the directory layout as a Kustomize base, with
+	// an overlay that sets the namespace and the instance the realms
+	// reference.
+	LayoutKustomize Layout = "kustomize"
+
+	// LayoutHelm writes the directory layout as the templates of a Helm
+	// chart, whose values are the namespace, the instance, the realm names
+	// and the Secret names.
+	LayoutHelm Layout = "helm"
+)
+
+// Layouts lists the valid layouts.
+var Layouts = []Layout{LayoutDirectory, LayoutKustomize, LayoutHelm}
+
+// valueKind is what a field that varies between environments holds.
+type valueKind int
+
+const (
+	valueNamespace valueKind = iota
+	valueInstanceRef
+	valueRealmName
+	valueSecretName
+)
+
+// valueField is a field of an exported object that varies between
+// environments.
+type valueField struct {
+	path  []string
+	kind  valueKind
+	value string
+}
+
+// valuePaths are, per kind, the fields that vary between environments, in
+// addition to metadata.namespace and spec.configSecretRef.name.
+var valuePaths = map[string][]struct {
+	path []string
+	kind valueKind
+}{
+	"KeycloakRealm": {
+		{[]string{"spec", "realmName"}, valueRealmName},
+		{[]string{"spec", "instanceRef", "name"}, valueInstanceRef},
+		{[]string{"spec", "clusterInstanceRef", "name"}, valueInstanceRef},
+		{[]string{"spec", "smtpSecretRef", "name"}, valueSecretName},
+	},
+	"ClusterKeycloakRealm": {
+		{[]string{"spec", "realmName"}, valueRealmName},
+		{[]string{"spec", "instanceRef", "name"}, valueInstanceRef},
+		{[]string{"spec", "instanceRef", "namespace"}, valueNamespace},
+		{[]string{"spec", "clusterInstanceRef", "name"}, valueInstanceRef},
+		{[]string{"spec", "smtpSecretRef", "name"}, valueSecretName},
+		{[]string{"spec", "smtpSecretRef", "namespace"}, valueNamespace},
+	},
+	"KeycloakInstance": {
+		{[]string{"metadata", "name"}, valueInstanceRef},
+		{[]string{"spec", "auth", "passwordGrant", "secretRef", "name"}, valueSecretName},
+	},
+	"ClusterKeycloakInstance": {
+		{[]string{"metadata", "name"}, valueInstanceRef},
+		{[]string{"spec", "auth", "passwordGrant", "secretRef", "name"}, valueSecretName},
+		{[]string{"spec", "auth", "passwordGrant", "secretRef", "namespace"}, valueNamespace},
+	},
+	"KeycloakClient": {
+		{[]string{"spec", "clientSecretRef", "name"}, valueSecretName},
+	},
+	"Secret": {
+		{[]string{"metadata", "name"}, valueSecretName},
+	},
+	"ExternalSecret": {
+		{[]string{"metadata", "name"}, valueSecretName},
+		{[]string{"spec", "target", "name"}, valueSecretName},
+	},
+}
+
+// valueFields returns the fields of obj, an object of kind, that vary
+// between environments and are set.
+func valueFields(kind string, obj map[string]interface{}) []valueField {
+	paths := append([]struct {
+		path []string
+		kind valueKind
+	}{
+		{[]string{"metadata", "namespace"}, valueNamespace},
+		{[]string{"spec", "configSecretRef", "name"}, valueSecretName},
+	}, valuePaths[kind]...)
+
+	var fields []valueField
+	for _, p := range paths {
+		if value, found, _ := unstructured.NestedString(obj, p.path...); found && value != "" {
+			fields = append(fields, valueField{path: p.path, kind: p.kind, value: value})
+		}
+	}
+	return fields
+}
+
+// toMap returns obj as it is marshaled.
+func toMap(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// instanceRef returns the instance the resources reference.
+func (w *Writer) instanceRef() string {
+	if w.opts.InstanceRef != "" {
+		return w.opts.InstanceRef
+	}
+	return defaultInstanceRef
+}
+
+type kustomization struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Namespace  string           `json:"namespace,omitempty"`
+	Resources  []string         `json:"resources"`
+	Patches    []kustomizePatch `json:"patches,omitempty"`
+}
+
+type kustomizePatch struct {
+	Target kustomizeTarget `json:"target"`
+	Patch  string          `json:"patch"`
+}
+
+type kustomizeTarget struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type jsonPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// writeKustomize writes the resources to OutputDir/base, listed by its
+// kustomization.yaml, and an overlay in OutputDir/overlays/<namespace> that
+// sets the namespace of the resources and the instance the realms
+// reference, to be copied per environment.
+func (w *Writer) writeKustomize(resources []ExportedResource) error {
+	baseDir := filepath.Join(w.opts.OutputDir, "base")
+	files, err := w.writeResources(baseDir, resources)
+	if err != nil {
+		return err
+	}
+
+	base := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+	}
+	for _, file := range files {
+		rel, err := filepath.Rel(baseDir, file)
+		if err != nil {
+			return err
+		}
+		base.Resources = append(base.Resources, filepath.ToSlash(rel))
+	}
+	if err := writeYAML(filepath.Join(baseDir, "kustomization.yaml"), base); err != nil {
+		return err
+	}
+
+	overlayName := w.opts.Namespace
+	if overlayName == "" {
+		overlayName = "default"
+	}
+	overlay := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Namespace:  w.opts.Namespace,
+		Resources:  []string{"../../base"},
+	}
+
+	// Patch the references kustomize's namespace transformer does not reach
+	sorted := sortedResources(resources)
+	for _, res := range sorted {
+		obj, err := toMap(res.Object)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s/%s: %w", res.Kind, res.Name, err)
+		}
+		var ops []jsonPatchOperation
+		for _, field := range valueFields(res.Kind, obj) {
+			if field.path[0] == "metadata" || (field.kind != valueInstanceRef && field.kind != valueNamespace) {
+				continue
+			}
+			ops = append(ops, jsonPatchOperation{Op: "replace", Path: "/" + strings.Join(field.path, "/"), Value: field.value})
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		patch, err := yaml.Marshal(ops)
+		if err != nil {
+			return err
+		}
+		overlay.Patches = append(overlay.Patches, kustomizePatch{
+			Target: kustomizeTarget{Kind: res.Kind, Name: res.Name},
+			Patch:  string(patch),
+		})
+	}
+
+	overlayDir := filepath.Join(w.opts.OutputDir, "overlays", sanitizeName(overlayName))
+	if err := os.MkdirAll(overlayDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", overlayDir, err)
+	}
+	return writeYAML(filepath.Join(overlayDir, "kustomization.yaml"), overlay)
+}
+
+type chart struct {
+	APIVersion  string `json:"apiVersion"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Version     string `json:"version"`
+}
+
+// writeHelm writes OutputDir as a Helm chart. The resources are templates,
+// in which the namespace, the instance, the realm names and the Secret names
+// are values.
+func (w *Writer) writeHelm(resources []ExportedResource) error {
+	namespaceExpr := "{{ .Values.namespace }}"
+	instanceExpr := "{{ .Values.instanceRef }}"
+	realmNames := map[string]string{}
+	secretNames := map[string]string{}
+	realms := map[string]bool{}
+
+	templated := make([]ExportedResource, 0, len(resources))
+	for _, res := range resources {
+		obj, err := toMap(res.Object)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s/%s: %w", res.Kind, res.Name, err)
+		}
+		for _, field := range valueFields(res.Kind, obj) {
+			var expr string
+			switch field.kind {
+			case valueNamespace:
+				expr = namespaceExpr
+			case valueInstanceRef:
+				expr = instanceExpr
+			case valueRealmName:
+				realmNames[field.value] = field.value
+				expr = fmt.Sprintf("{{ index .Values.realmNames %q }}", field.value)
+			case valueSecretName:
+				secretNames[field.value] = field.value
+				expr = fmt.Sprintf("{{ index .Values.secretNames %q }}", field.value)
+			}
+			if err := unstructured.SetNestedField(obj, expr, field.path...); err != nil {
+				return err
+			}
+		}
+		if res.Realm != "" {
+			realms[res.Realm] = true
+		}
+		res.Object = obj
+		templated = append(templated, res)
+	}
+
+	if _, err := w.writeResources(filepath.Join(w.opts.OutputDir, "templates"), templated); err != nil {
+		return err
+	}
+
+	name := "keycloak-realms"
+	if len(realms) == 1 {
+		for realm := range realms {
+			name = sanitizeName("keycloak-" + realm)
+		}
+	}
+	if err := writeYAML(filepath.Join(w.opts.OutputDir, "Chart.yaml"), chart{
+		APIVersion:  "v2",
+		Name:        name,
+		Description: "Keycloak realms exported by keycloak-operator",
+		Type:        "application",
+		Version:     "0.1.0",
+	}); err != nil {
+		return err
+	}
+
+	var values strings.Builder
+	sections := []struct {
+		comment string
+		key     string
+		value   interface{}
+	}{
+		{"Namespace of the resources", "namespace", w.opts.Namespace},
+		{"KeycloakInstance, or ClusterKeycloakInstance, the realms reference", "instanceRef", w.instanceRef()},
+		{"Realm names in Keycloak, by exported realm name", "realmNames", realmNames},
+		{"Secret names, by exported Secret name", "secretNames", secretNames},
+	}
+	for i, section := range sections {
+		data, err := yaml.Marshal(map[string]interface{}{section.key: section.value})
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			values.WriteString("\n")
+		}
+		fmt.Fprintf(&values, "# %s\n%s", section.comment, data)
+	}
+	filename := filepath.Join(w.opts.OutputDir, "values.yaml")
+	if err := os.WriteFile(filename, []byte(values.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// sortedResources returns resources ordered by kind and name.
+func sortedResources(resources []ExportedResource) []ExportedResource {
+	sorted := append([]ExportedResource(nil), resources...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Kind != sorted[j].Kind {
+			return sorted[i].Kind < sorted[j].Kind
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func writeYAML(filename string, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filename, err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
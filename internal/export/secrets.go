@@ -0,0 +1,190 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// SecretMode decides how the secret-bearing fields of the exported objects
+// are exported: client secrets, identity provider client secrets, LDAP bind
+// credentials and SMTP passwords.
+type SecretMode string
+
+const (
+	// SecretModeNone drops secret-bearing fields. Confidential clients still
+	// get a clientSecretRef, whose Secret the operator creates with the secret
+	// Keycloak generates.
+	SecretModeNone SecretMode = "none"
+
+	// SecretModeStub moves them to Secrets whose values are ${VAR}
+	// placeholders, referenced by clientSecretRef, configSecretRef and
+	// smtpSecretRef.
+	SecretModeStub SecretMode = "stub"
+
+	// SecretModeValue is SecretModeStub with the real values where they are
+	// known: client secrets read from Keycloak and unmasked values in a realm
+	// export file. Keycloak masks the others on read.
+	SecretModeValue SecretMode = "value"
+
+	// SecretModeExternalSecret moves them to ExternalSecrets that read the
+	// values from a secret store of the External Secrets Operator.
+	SecretModeExternalSecret SecretMode = "external-secret"
+)
+
+// SecretModes lists the valid secret modes.
+var SecretModes = []SecretMode{SecretModeNone, SecretModeStub, SecretModeValue, SecretModeExternalSecret}
+
+// maskedSecret is the value Keycloak returns on read in place of a stored
+// secret.
+const maskedSecret = "**********"
+
+// SecretStoreOptions configures the ExternalSecrets of
+// SecretModeExternalSecret.
+type SecretStoreOptions struct {
+	// Name of the SecretStore or ClusterSecretStore
+	Name string
+
+	// Kind is SecretStore (default) or ClusterSecretStore
+	Kind string
+
+	// Path the remote keys are under; the remote key of a Secret is
+	// <Path>/<secret name>, and its keys are properties of it
+	Path string
+}
+
+// exportsSecrets reports whether secret-bearing fields are moved to Secrets
+// rather than dropped.
+func (m SecretMode) exportsSecrets() bool {
+	return m != "" && m != SecretModeNone
+}
+
+// secretValue returns value as exported in mode: empty if it is unknown,
+// that is masked, or if mode does not export values.
+func secretValue(mode SecretMode, value string) string {
+	if mode != SecretModeValue || value == maskedSecret {
+		return ""
+	}
+	return value
+}
+
+// addSecret records a Secret referenced by an exported object. plain holds
+// its keys that are not secret, such as a client ID, and secret the others,
+// with empty values where they are unknown.
+func (t *Transformer) addSecret(name string, plain, secret map[string]string) {
+	if t.opts.Secrets == SecretModeExternalSecret {
+		t.secrets = append(t.secrets, t.externalSecret(name, plain, secret))
+		return
+	}
+
+	data := make(map[string]string, len(plain)+len(secret))
+	for key, value := range plain {
+		data[key] = value
+	}
+	for key, value := range secret {
+		if value = secretValue(t.opts.Secrets, value); value == "" {
+			value = "${" + manifest.PlaceholderName(name, key) + "}"
+		}
+		data[key] = value
+	}
+
+	obj := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: t.opts.TargetNamespace,
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: data,
+	}
+	t.secrets = append(t.secrets, ExportedResource{Kind: "Secret", Name: name, APIVersion: "v1", Object: obj})
+}
+
+// externalSecret returns an ExternalSecret that creates the Secret name, with
+// the secret keys read from the store and the others from a template.
+func (t *Transformer) externalSecret(name string, plain, secret map[string]string) ExportedResource {
+	store := t.opts.SecretStore
+	kind := store.Kind
+	if kind == "" {
+		kind = "SecretStore"
+	}
+
+	keys := make([]string, 0, len(secret))
+	for key := range secret {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		data = append(data, map[string]interface{}{
+			"secretKey": key,
+			"remoteRef": map[string]interface{}{
+				"key":      path.Join(store.Path, name),
+				"property": key,
+			},
+		})
+	}
+
+	target := map[string]interface{}{"name": name}
+	if len(plain) > 0 {
+		templateData := make(map[string]interface{}, len(plain))
+		for key, value := range plain {
+			templateData[key] = value
+		}
+		target["template"] = map[string]interface{}{
+			"mergePolicy": "Merge",
+			"data":        templateData,
+		}
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "external-secrets.io/v1beta1",
+		"kind":       "ExternalSecret",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+		"spec": map[string]interface{}{
+			"secretStoreRef": map[string]interface{}{
+				"name": store.Name,
+				"kind": kind,
+			},
+			"target": target,
+			"data":   data,
+		},
+	}}
+	if t.opts.TargetNamespace != "" {
+		obj.SetNamespace(t.opts.TargetNamespace)
+	}
+	return ExportedResource{Kind: "ExternalSecret", Name: name, APIVersion: "external-secrets.io/v1beta1", Object: obj}
+}
+
+// Secrets returns the Secrets, or ExternalSecrets, recorded for the objects
+// transformed so far and forgets them.
+func (t *Transformer) Secrets() []ExportedResource {
+	secrets := t.secrets
+	t.secrets = nil
+	return secrets
+}
+
+// configValue returns the value of key in the config of a representation,
+// unwrapping the single-element lists of component configs.
+func configValue(config map[string]interface{}, key string) (string, bool) {
+	switch v := config[key].(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if len(v) > 0 {
+			return fmt.Sprint(v[0]), true
+		}
+	}
+	return "", false
+}
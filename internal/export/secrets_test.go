@@ -0,0 +1,175 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
+)
+
+const secretsRealm = `{
+	"realm": "acme",
+	"smtpServer": {"host": "smtp.example.com", "auth": "true", "user": "mailer", "password": "**********"},
+	"clients": [
+		{"clientId": "api", "secret": "s3cr3t"},
+		{"clientId": "web", "publicClient": true}
+	],
+	"identityProviders": [
+		{"alias": "github", "providerId": "github", "config": {"clientId": "gh", "clientSecret": "**********"}}
+	],
+	"components": {
+		"org.keycloak.storage.UserStorageProvider": [
+			{"name": "ldap", "providerId": "ldap", "config": {"bindCredential": ["bind-pw"], "enabled": ["true"]}}
+		]
+	}
+}`
+
+func exportSecretsRealm(t *testing.T, opts ExporterOptions) map[string]ExportedResource {
+	t.Helper()
+	opts.TargetNamespace = "iam"
+	opts.Include = []string{ResourceTypeRealm, ResourceTypeClients, ResourceTypeIdentityProviders, ResourceTypeComponents}
+	exporter, err := NewFileExporter([]byte(secretsRealm), testr.New(t), opts)
+	require.NoError(t, err)
+	resources, err := exporter.Export()
+	require.NoError(t, err)
+
+	byName := make(map[string]ExportedResource, len(resources))
+	for _, res := range resources {
+		byName[res.Kind+"/"+res.Name] = res
+	}
+	return byName
+}
+
+func definitionOf(t *testing.T, raw []byte) map[string]interface{} {
+	t.Helper()
+	var def map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &def))
+	return def
+}
+
+func TestExportSecretsNone(t *testing.T) {
+	resources := exportSecretsRealm(t, ExporterOptions{})
+	for key := range resources {
+		require.NotContains(t, key, "Secret/")
+	}
+
+	realm := resources["KeycloakRealm/acme"].Object.(*keycloakv1beta1.KeycloakRealm)
+	require.Nil(t, realm.Spec.SmtpSecretRef)
+	smtp := definitionOf(t, realm.Spec.Definition.Raw)["smtpServer"].(map[string]interface{})
+	require.NotContains(t, smtp, "password", "the masked password is dropped")
+	require.Equal(t, "mailer", smtp["user"])
+
+	client := resources["KeycloakClient/api"].Object.(*keycloakv1beta1.KeycloakClient)
+	require.Equal(t, "api-secret", client.Spec.ClientSecretRef.Name)
+	require.Nil(t, client.Spec.ClientSecretRef.Create)
+
+	idp := resources["KeycloakIdentityProvider/github"].Object.(*keycloakv1beta1.KeycloakIdentityProvider)
+	require.Nil(t, idp.Spec.ConfigSecretRef)
+}
+
+func TestExportSecretsStub(t *testing.T) {
+	resources := exportSecretsRealm(t, ExporterOptions{Secrets: SecretModeStub})
+
+	realm := resources["KeycloakRealm/acme"].Object.(*keycloakv1beta1.KeycloakRealm)
+	require.Equal(t, "acme-smtp", realm.Spec.SmtpSecretRef.Name)
+	smtp := definitionOf(t, realm.Spec.Definition.Raw)["smtpServer"].(map[string]interface{})
+	require.NotContains(t, smtp, "user")
+	require.NotContains(t, smtp, "password")
+	require.Equal(t, map[string]string{
+		"user":     "mailer",
+		"password": "${ACME_SMTP_PASSWORD}",
+	}, resources["Secret/acme-smtp"].Object.(*corev1.Secret).StringData)
+
+	client := resources["KeycloakClient/api"].Object.(*keycloakv1beta1.KeycloakClient)
+	require.False(t, *client.Spec.ClientSecretRef.Create)
+	secret := resources["Secret/api-secret"].Object.(*corev1.Secret)
+	require.Equal(t, "iam", secret.Namespace)
+	require.Equal(t, map[string]string{
+		"client-id":     "api",
+		"client-secret": "${API_SECRET_CLIENT_SECRET}",
+	}, secret.StringData, "stubs never carry values")
+	require.NotContains(t, resources, "Secret/web-secret")
+
+	idp := resources["KeycloakIdentityProvider/github"].Object.(*keycloakv1beta1.KeycloakIdentityProvider)
+	require.Equal(t, "github-idp-secret", idp.Spec.ConfigSecretRef.Name)
+	require.Equal(t, map[string]string{"clientSecret": "${GITHUB_IDP_SECRET_CLIENTSECRET}"},
+		resources["Secret/github-idp-secret"].Object.(*corev1.Secret).StringData)
+
+	component := resources["KeycloakComponent/userstorageprovider-ldap"].Object.(*keycloakv1beta1.KeycloakComponent)
+	require.Equal(t, "userstorageprovider-ldap-secret", component.Spec.ConfigSecretRef.Name)
+	config := definitionOf(t, component.Spec.Definition.Raw)["config"].(map[string]interface{})
+	require.NotContains(t, config, "bindCredential")
+}
+
+func TestExportSecretsValue(t *testing.T) {
+	resources := exportSecretsRealm(t, ExporterOptions{Secrets: SecretModeValue})
+
+	require.Equal(t, "s3cr3t", resources["Secret/api-secret"].Object.(*corev1.Secret).StringData["client-secret"])
+	require.Equal(t, "bind-pw", resources["Secret/userstorageprovider-ldap-secret"].Object.(*corev1.Secret).StringData["bindCredential"])
+	require.Equal(t, "${GITHUB_IDP_SECRET_CLIENTSECRET}",
+		resources["Secret/github-idp-secret"].Object.(*corev1.Secret).StringData["clientSecret"],
+		"masked values become placeholders")
+}
+
+func TestExportSecretsExternalSecret(t *testing.T) {
+	resources := exportSecretsRealm(t, ExporterOptions{
+		Secrets:     SecretModeExternalSecret,
+		SecretStore: SecretStoreOptions{Name: "vault", Kind: "ClusterSecretStore", Path: "keycloak/acme"},
+	})
+	require.NotContains(t, resources, "Secret/api-secret")
+
+	obj := resources["ExternalSecret/api-secret"].Object.(*unstructured.Unstructured)
+	require.Equal(t, "iam", obj.GetNamespace())
+	require.Equal(t, map[string]interface{}{
+		"secretStoreRef": map[string]interface{}{"name": "vault", "kind": "ClusterSecretStore"},
+		"target": map[string]interface{}{
+			"name": "api-secret",
+			"template": map[string]interface{}{
+				"mergePolicy": "Merge",
+				"data":        map[string]interface{}{"client-id": "api"},
+			},
+		},
+		"data": []interface{}{
+			map[string]interface{}{
+				"secretKey": "client-secret",
+				"remoteRef": map[string]interface{}{"key": "keycloak/acme/api-secret", "property": "client-secret"},
+			},
+		},
+	}, obj.Object["spec"])
+}
+
+func TestExportClientSecretValue(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"acme","enabled":true}`)))
+	uuid, err := kc.CreateClient(ctx, "acme", json.RawMessage(`{"clientId":"api"}`))
+	require.NoError(t, err)
+	want, err := kc.GetClientSecret(ctx, "acme", uuid)
+	require.NoError(t, err)
+	require.NotEmpty(t, want)
+
+	resources, err := NewExporter(kc, testr.New(t), ExporterOptions{
+		Realm:        "acme",
+		Include:      []string{ResourceTypeClients},
+		SkipDefaults: true,
+		Secrets:      SecretModeValue,
+	}).Export(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"KeycloakClient/api", "Secret/api-secret"}, resourceKinds(resources))
+	require.Equal(t, want, resources[1].Object.(*corev1.Secret).StringData["client-secret"])
+}
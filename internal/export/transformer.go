@@ -9,6 +9,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
 )
 
 // TransformerOptions configures the transformer
@@ -16,14 +17,65 @@ type TransformerOptions struct {
 	TargetNamespace string
 	InstanceRef     string
 	RealmRef        string
+
+	// ClusterRealm emits a ClusterKeycloakRealm and clusterRealmRef
+	// references instead of a KeycloakRealm and realmRef.
+	ClusterRealm bool
+
+	// ClusterInstance references InstanceRef as a ClusterKeycloakInstance.
+	ClusterInstance bool
+
+	// NamePrefix is prepended to the names of all objects but the realm, to
+	// keep the objects of several realms apart in one namespace.
+	NamePrefix string
+
+	// Secrets decides how secret-bearing fields are exported.
+	Secrets SecretMode
+
+	// SecretStore configures the ExternalSecrets of SecretModeExternalSecret.
+	SecretStore SecretStoreOptions
+
+	// Defaults, if set, are pruned from the exported definitions.
+	Defaults *Defaults
 }
 
 // Transformer transforms Keycloak JSON to CRD structs
 type Transformer struct {
 	opts TransformerOptions
 	// organizationNames maps Keycloak organization IDs to the Kubernetes
-	// object name that TransformOrganization would emit (sanitizeName(org.name)).
+	// object name that TransformOrganization would emit (objectName(org.name)).
 	organizationNames map[string]string
+	// flowAliases maps Keycloak authentication flow IDs to their aliases.
+	flowAliases map[string]string
+	// secrets are the Secrets the objects transformed so far reference.
+	secrets []ExportedResource
+}
+
+// flowBindingAliasKeys maps the keys of a client's
+// authenticationFlowBindingOverrides, which hold flow IDs, to the alias-based
+// keys KeycloakClient resolves on apply.
+var flowBindingAliasKeys = map[string]string{
+	"browser":      "browserFlowAlias",
+	"direct_grant": "directGrantFlowAlias",
+}
+
+// FlowDefinition is a sub-flow in the executions of an exported
+// KeycloakAuthenticationFlow.
+type FlowDefinition struct {
+	Alias       string `json:"alias"`
+	Description string `json:"description,omitempty"`
+	ProviderID  string `json:"providerId"`
+}
+
+// FlowExecution is one node of the executions of an exported
+// KeycloakAuthenticationFlow: a leaf authenticator or a sub-flow with its
+// child executions.
+type FlowExecution struct {
+	Authenticator       string            `json:"authenticator,omitempty"`
+	SubFlow             *FlowDefinition   `json:"subFlow,omitempty"`
+	Requirement         string            `json:"requirement"`
+	AuthenticatorConfig map[string]string `json:"authenticatorConfig,omitempty"`
+	Executions          []FlowExecution   `json:"executions,omitempty"`
 }
 
 // NewTransformer creates a new transformer
@@ -37,10 +89,35 @@ func (t *Transformer) SetOrganizationNames(names map[string]string) {
 	t.organizationNames = names
 }
 
-// TransformRealm transforms a realm JSON to KeycloakRealm
+// SetFlowAliases supplies a Keycloak flow ID → alias map used to export client
+// flow binding overrides by alias.
+func (t *Transformer) SetFlowAliases(aliases map[string]string) {
+	t.flowAliases = aliases
+}
+
+// TransformRealm transforms a realm JSON to KeycloakRealm, or to
+// ClusterKeycloakRealm if the ClusterRealm option is set
 func (t *Transformer) TransformRealm(raw json.RawMessage, realmName string) (ExportedResource, error) {
-	// Remove server-managed fields
-	definition := removeServerFields(raw, "id")
+	// Remove server-managed fields and the SMTP password, which Keycloak masks.
+	// The name lives in spec.realmName only, so that changing it there, as the
+	// Helm layout does, does not conflict with the definition.
+	definition := removeServerFields(raw, "id", "realm", "smtpServer.password")
+	smtpSecret := t.smtpSecret(raw, realmName)
+	if smtpSecret != "" {
+		definition = removeServerFields(definition, "smtpServer.user")
+	}
+	definition = t.opts.Defaults.prune("KeycloakRealm", definition)
+
+	if t.opts.ClusterRealm {
+		resource := t.transformClusterRealm(definition, realmName)
+		if smtpSecret != "" {
+			resource.Object.(*keycloakv1beta1.ClusterKeycloakRealm).Spec.SmtpSecretRef = &keycloakv1beta1.ClusterSmtpSecretRefSpec{
+				Name:      smtpSecret,
+				Namespace: t.opts.TargetNamespace,
+			}
+		}
+		return resource, nil
+	}
 
 	realm := &keycloakv1beta1.KeycloakRealm{
 		TypeMeta: metav1.TypeMeta{
@@ -52,13 +129,18 @@ func (t *Transformer) TransformRealm(raw json.RawMessage, realmName string) (Exp
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakRealmSpec{
-			InstanceRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.InstanceRef,
-			},
 			RealmName:  strPtr(realmName),
 			Definition: runtime.RawExtension{Raw: definition},
 		},
 	}
+	if t.opts.ClusterInstance {
+		realm.Spec.ClusterInstanceRef = &keycloakv1beta1.ClusterResourceRef{Name: t.opts.InstanceRef}
+	} else {
+		realm.Spec.InstanceRef = &keycloakv1beta1.ResourceRef{Name: t.opts.InstanceRef}
+	}
+	if smtpSecret != "" {
+		realm.Spec.SmtpSecretRef = &keycloakv1beta1.SmtpSecretRefSpec{Name: smtpSecret}
+	}
 
 	return ExportedResource{
 		Kind:       "KeycloakRealm",
@@ -68,12 +150,92 @@ func (t *Transformer) TransformRealm(raw json.RawMessage, realmName string) (Exp
 	}, nil
 }
 
+// smtpSecret records the Secret for the SMTP credentials of a realm and
+// returns its name, or returns "" if the realm has no SMTP password or
+// secrets are not exported.
+func (t *Transformer) smtpSecret(raw json.RawMessage, realmName string) string {
+	var parsed struct {
+		SmtpServer map[string]interface{} `json:"smtpServer"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil || !t.opts.Secrets.exportsSecrets() {
+		return ""
+	}
+	password, ok := configValue(parsed.SmtpServer, "password")
+	if !ok {
+		return ""
+	}
+	user, _ := configValue(parsed.SmtpServer, "user")
+
+	name := sanitizeName(realmName + "-smtp")
+	t.addSecret(name, map[string]string{"user": user}, map[string]string{"password": password})
+	return name
+}
+
+func (t *Transformer) transformClusterRealm(definition json.RawMessage, realmName string) ExportedResource {
+	realm := &keycloakv1beta1.ClusterKeycloakRealm{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "keycloak.hostzero.com/v1beta1",
+			Kind:       "ClusterKeycloakRealm",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: sanitizeName(realmName),
+		},
+		Spec: keycloakv1beta1.ClusterKeycloakRealmSpec{
+			RealmName:  strPtr(realmName),
+			Definition: runtime.RawExtension{Raw: definition},
+		},
+	}
+	if t.opts.ClusterInstance {
+		realm.Spec.ClusterInstanceRef = &keycloakv1beta1.ClusterResourceRef{Name: t.opts.InstanceRef}
+	} else {
+		realm.Spec.InstanceRef = &keycloakv1beta1.NamespacedRef{
+			Name:      t.opts.InstanceRef,
+			Namespace: t.opts.TargetNamespace,
+		}
+	}
+
+	return ExportedResource{
+		Kind:       "ClusterKeycloakRealm",
+		Name:       realm.Name,
+		APIVersion: "keycloak.hostzero.com/v1beta1",
+		Object:     realm,
+	}
+}
+
+// realmRef returns the realmRef of realm-scoped objects, or nil if they
+// reference a ClusterKeycloakRealm.
+func (t *Transformer) realmRef() *keycloakv1beta1.ResourceRef {
+	if t.opts.ClusterRealm {
+		return nil
+	}
+	return &keycloakv1beta1.ResourceRef{Name: t.opts.RealmRef}
+}
+
+// clusterRealmRef returns the clusterRealmRef of realm-scoped objects, or
+// nil if they reference a KeycloakRealm.
+func (t *Transformer) clusterRealmRef() *keycloakv1beta1.ClusterResourceRef {
+	if !t.opts.ClusterRealm {
+		return nil
+	}
+	return &keycloakv1beta1.ClusterResourceRef{Name: t.opts.RealmRef}
+}
+
+// objectName returns the Kubernetes name of an object named name in
+// Keycloak.
+func (t *Transformer) objectName(name string) string {
+	if t.opts.NamePrefix != "" {
+		name = t.opts.NamePrefix + "-" + name
+	}
+	return sanitizeName(name)
+}
+
 // TransformClient transforms a client JSON to KeycloakClient
 func (t *Transformer) TransformClient(raw json.RawMessage, clientID string) (ExportedResource, error) {
 	// Parse client to check if it's confidential
 	var parsed struct {
-		PublicClient bool `json:"publicClient"`
-		BearerOnly   bool `json:"bearerOnly"`
+		PublicClient bool   `json:"publicClient"`
+		BearerOnly   bool   `json:"bearerOnly"`
+		Secret       string `json:"secret"`
 	}
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return ExportedResource{}, err
@@ -81,6 +243,8 @@ func (t *Transformer) TransformClient(raw json.RawMessage, clientID string) (Exp
 
 	// Remove server-managed fields, secrets, and protocolMappers (own CRD).
 	definition := removeServerFields(raw, "id", "secret", "registrationAccessToken", "protocolMappers")
+	definition = t.aliasFlowBindings(definition)
+	definition = t.opts.Defaults.prune("KeycloakClient", definition)
 
 	client := &keycloakv1beta1.KeycloakClient{
 		TypeMeta: metav1.TypeMeta{
@@ -88,22 +252,28 @@ func (t *Transformer) TransformClient(raw json.RawMessage, clientID string) (Exp
 			Kind:       "KeycloakClient",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(clientID),
+			Name:      t.objectName(clientID),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakClientSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			ClientId:   strPtr(clientID),
-			Definition: &runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			ClientId:        strPtr(clientID),
+			Definition:      &runtime.RawExtension{Raw: definition},
 		},
 	}
 
 	// Add clientSecretRef for confidential clients (not public, not bearer-only)
 	if !parsed.PublicClient && !parsed.BearerOnly {
 		client.Spec.ClientSecretRef = &keycloakv1beta1.ClientSecretRefSpec{
-			Name: sanitizeName(clientID) + "-secret",
+			Name: t.objectName(clientID) + "-secret",
+		}
+		// The exported Secret must exist, rather than be generated
+		if t.opts.Secrets.exportsSecrets() {
+			client.Spec.ClientSecretRef.Create = boolPtr(false)
+			t.addSecret(client.Spec.ClientSecretRef.Name,
+				map[string]string{"client-id": clientID},
+				map[string]string{"client-secret": parsed.Secret})
 		}
 	}
 
@@ -115,6 +285,45 @@ func (t *Transformer) TransformClient(raw json.RawMessage, clientID string) (Exp
 	}, nil
 }
 
+// aliasFlowBindings rewrites the flow IDs in a client's
+// authenticationFlowBindingOverrides to alias-based keys, so the manifest does
+// not depend on the IDs of the exporting server. IDs of unknown flows are
+// kept.
+func (t *Transformer) aliasFlowBindings(definition json.RawMessage) json.RawMessage {
+	var data map[string]interface{}
+	if err := json.Unmarshal(definition, &data); err != nil {
+		return definition
+	}
+	overrides, ok := data["authenticationFlowBindingOverrides"].(map[string]interface{})
+	if !ok {
+		return definition
+	}
+
+	changed := false
+	for key, aliasKey := range flowBindingAliasKeys {
+		id, ok := overrides[key].(string)
+		if !ok {
+			continue
+		}
+		alias, ok := t.flowAliases[id]
+		if !ok {
+			continue
+		}
+		delete(overrides, key)
+		overrides[aliasKey] = alias
+		changed = true
+	}
+	if !changed {
+		return definition
+	}
+
+	result, err := json.Marshal(data)
+	if err != nil {
+		return definition
+	}
+	return result
+}
+
 // TransformClientScope transforms a client scope JSON to KeycloakClientScope
 func (t *Transformer) TransformClientScope(raw json.RawMessage) (ExportedResource, error) {
 	var parsed struct {
@@ -126,6 +335,7 @@ func (t *Transformer) TransformClientScope(raw json.RawMessage) (ExportedResourc
 
 	// Remove server-managed fields and protocolMappers (own CRD).
 	definition := removeServerFields(raw, "id", "protocolMappers")
+	definition = t.opts.Defaults.prune("KeycloakClientScope", definition)
 
 	scope := &keycloakv1beta1.KeycloakClientScope{
 		TypeMeta: metav1.TypeMeta{
@@ -133,15 +343,14 @@ func (t *Transformer) TransformClientScope(raw json.RawMessage) (ExportedResourc
 			Kind:       "KeycloakClientScope",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(parsed.Name),
+			Name:      t.objectName(parsed.Name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakClientScopeSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Name:       strPtr(parsed.Name),
-			Definition: runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Name:            strPtr(parsed.Name),
+			Definition:      runtime.RawExtension{Raw: definition},
 		},
 	}
 
@@ -164,6 +373,7 @@ func (t *Transformer) TransformUser(raw json.RawMessage) (ExportedResource, erro
 
 	// Remove server-managed fields, secrets, and role/group keys (typed spec fields).
 	definition := removeServerFields(raw, "id", "createdTimestamp", "credentials", "federatedIdentities", "access", "realmRoles", "clientRoles", "groups")
+	definition = t.opts.Defaults.prune("KeycloakUser", definition)
 
 	user := &keycloakv1beta1.KeycloakUser{
 		TypeMeta: metav1.TypeMeta{
@@ -171,15 +381,14 @@ func (t *Transformer) TransformUser(raw json.RawMessage) (ExportedResource, erro
 			Kind:       "KeycloakUser",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(parsed.Username),
+			Name:      t.objectName(parsed.Username),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakUserSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Username:   strPtr(parsed.Username),
-			Definition: &runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Username:        strPtr(parsed.Username),
+			Definition:      &runtime.RawExtension{Raw: definition},
 		},
 	}
 
@@ -202,6 +411,7 @@ func (t *Transformer) TransformGroup(raw json.RawMessage, parentGroupName string
 
 	// Remove server-managed fields and subgroups (exported separately)
 	definition := removeServerFields(raw, "id", "subGroups", "path")
+	definition = t.opts.Defaults.prune("KeycloakGroup", definition)
 
 	name := parsed.Name
 	if parentGroupName != "" {
@@ -214,22 +424,22 @@ func (t *Transformer) TransformGroup(raw json.RawMessage, parentGroupName string
 			Kind:       "KeycloakGroup",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(name),
+			Name:      t.objectName(name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakGroupSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Name:       strPtr(parsed.Name),
-			Definition: runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Name:            strPtr(parsed.Name),
+			Definition:      runtime.RawExtension{Raw: definition},
 		},
 	}
 
-	// Add parent reference if this is a subgroup
+	// Subgroups reference their parent instead of the realm
 	if parentGroupName != "" {
+		group.Spec.RealmRef, group.Spec.ClusterRealmRef = nil, nil
 		group.Spec.ParentGroupRef = &keycloakv1beta1.ResourceRef{
-			Name: sanitizeName(parentGroupName),
+			Name: t.objectName(parentGroupName),
 		}
 	}
 
@@ -252,6 +462,7 @@ func (t *Transformer) TransformRole(raw json.RawMessage, clientID, clientUUID st
 
 	// Remove server-managed fields
 	definition := removeServerFields(raw, "id", "containerId")
+	definition = t.opts.Defaults.prune("KeycloakRole", definition)
 
 	name := parsed.Name
 	if clientID != "" {
@@ -264,22 +475,22 @@ func (t *Transformer) TransformRole(raw json.RawMessage, clientID, clientUUID st
 			Kind:       "KeycloakRole",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(name),
+			Name:      t.objectName(name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakRoleSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Name:       strPtr(parsed.Name),
-			Definition: runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Name:            strPtr(parsed.Name),
+			Definition:      runtime.RawExtension{Raw: definition},
 		},
 	}
 
-	// Add client reference if this is a client role
+	// Client roles reference their client instead of the realm
 	if clientID != "" {
+		role.Spec.RealmRef, role.Spec.ClusterRealmRef = nil, nil
 		role.Spec.ClientRef = &keycloakv1beta1.ResourceRef{
-			Name: sanitizeName(clientID),
+			Name: t.objectName(clientID),
 		}
 	}
 
@@ -294,8 +505,9 @@ func (t *Transformer) TransformRole(raw json.RawMessage, clientID, clientUUID st
 // TransformIdentityProvider transforms an identity provider JSON to KeycloakIdentityProvider
 func (t *Transformer) TransformIdentityProvider(raw json.RawMessage) (ExportedResource, error) {
 	var parsed struct {
-		Alias          string `json:"alias"`
-		OrganizationID string `json:"organizationId"`
+		Alias          string                 `json:"alias"`
+		OrganizationID string                 `json:"organizationId"`
+		Config         map[string]interface{} `json:"config"`
 	}
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return ExportedResource{}, err
@@ -304,6 +516,7 @@ func (t *Transformer) TransformIdentityProvider(raw json.RawMessage) (ExportedRe
 	// Remove sensitive/server-managed fields. organizationId is a Keycloak UUID
 	// and is expressed as spec.organizationRef instead.
 	definition := removeServerFields(raw, "internalId", "config.clientSecret", "organizationId")
+	definition = t.opts.Defaults.prune("KeycloakIdentityProvider", definition)
 
 	idp := &keycloakv1beta1.KeycloakIdentityProvider{
 		TypeMeta: metav1.TypeMeta{
@@ -311,15 +524,14 @@ func (t *Transformer) TransformIdentityProvider(raw json.RawMessage) (ExportedRe
 			Kind:       "KeycloakIdentityProvider",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(parsed.Alias),
+			Name:      t.objectName(parsed.Alias),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakIdentityProviderSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Alias:      strPtr(parsed.Alias),
-			Definition: runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Alias:           strPtr(parsed.Alias),
+			Definition:      runtime.RawExtension{Raw: definition},
 		},
 	}
 
@@ -329,6 +541,11 @@ func (t *Transformer) TransformIdentityProvider(raw json.RawMessage) (ExportedRe
 		}
 	}
 
+	if secret, ok := configValue(parsed.Config, "clientSecret"); ok && t.opts.Secrets.exportsSecrets() {
+		idp.Spec.ConfigSecretRef = &keycloakv1beta1.ConfigSecretRef{Name: sanitizeName(idp.Name + "-idp-secret")}
+		t.addSecret(idp.Spec.ConfigSecretRef.Name, nil, map[string]string{"clientSecret": secret})
+	}
+
 	return ExportedResource{
 		Kind:       "KeycloakIdentityProvider",
 		Name:       idp.Name,
@@ -355,12 +572,12 @@ func (t *Transformer) TransformIdentityProviderMapper(raw json.RawMessage, alias
 			Kind:       "KeycloakIdentityProviderMapper",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(alias + "-" + parsed.Name),
+			Name:      t.objectName(alias + "-" + parsed.Name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakIdentityProviderMapperSpec{
 			IdentityProviderRef: keycloakv1beta1.ResourceRef{
-				Name: sanitizeName(alias),
+				Name: t.objectName(alias),
 			},
 			Name:       strPtr(parsed.Name),
 			Definition: runtime.RawExtension{Raw: definition},
@@ -378,8 +595,9 @@ func (t *Transformer) TransformIdentityProviderMapper(raw json.RawMessage, alias
 // TransformComponent transforms a component JSON to KeycloakComponent
 func (t *Transformer) TransformComponent(raw json.RawMessage) (ExportedResource, error) {
 	var parsed struct {
-		Name         string `json:"name"`
-		ProviderType string `json:"providerType"`
+		Name         string                 `json:"name"`
+		ProviderType string                 `json:"providerType"`
+		Config       map[string]interface{} `json:"config"`
 	}
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return ExportedResource{}, err
@@ -403,18 +621,22 @@ func (t *Transformer) TransformComponent(raw json.RawMessage) (ExportedResource,
 			Kind:       "KeycloakComponent",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(name),
+			Name:      t.objectName(name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakComponentSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Name:       strPtr(parsed.Name),
-			Definition: runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Name:            strPtr(parsed.Name),
+			Definition:      runtime.RawExtension{Raw: definition},
 		},
 	}
 
+	if secret, ok := configValue(parsed.Config, "bindCredential"); ok && t.opts.Secrets.exportsSecrets() {
+		component.Spec.ConfigSecretRef = &keycloakv1beta1.ConfigSecretRef{Name: sanitizeName(component.Name + "-secret")}
+		t.addSecret(component.Spec.ConfigSecretRef.Name, nil, map[string]string{"bindCredential": secret})
+	}
+
 	return ExportedResource{
 		Kind:       "KeycloakComponent",
 		Name:       component.Name,
@@ -441,15 +663,14 @@ func (t *Transformer) TransformOrganization(raw json.RawMessage) (ExportedResour
 			Kind:       "KeycloakOrganization",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(parsed.Name),
+			Name:      t.objectName(parsed.Name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakOrganizationSpec{
-			RealmRef: &keycloakv1beta1.ResourceRef{
-				Name: t.opts.RealmRef,
-			},
-			Name:       strPtr(parsed.Name),
-			Definition: runtime.RawExtension{Raw: definition},
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Name:            strPtr(parsed.Name),
+			Definition:      runtime.RawExtension{Raw: definition},
 		},
 	}
 
@@ -461,6 +682,79 @@ func (t *Transformer) TransformOrganization(raw json.RawMessage) (ExportedResour
 	}, nil
 }
 
+// TransformRequiredAction transforms a required action JSON to KeycloakRequiredAction
+func (t *Transformer) TransformRequiredAction(raw json.RawMessage) (ExportedResource, error) {
+	var parsed struct {
+		Alias string `json:"alias"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return ExportedResource{}, err
+	}
+
+	action := &keycloakv1beta1.KeycloakRequiredAction{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "keycloak.hostzero.com/v1beta1",
+			Kind:       "KeycloakRequiredAction",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      t.objectName(parsed.Alias),
+			Namespace: t.opts.TargetNamespace,
+		},
+		Spec: keycloakv1beta1.KeycloakRequiredActionSpec{
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Alias:           strPtr(parsed.Alias),
+			Definition:      runtime.RawExtension{Raw: raw},
+		},
+	}
+
+	return ExportedResource{
+		Kind:       "KeycloakRequiredAction",
+		Name:       action.Name,
+		APIVersion: "keycloak.hostzero.com/v1beta1",
+		Object:     action,
+	}, nil
+}
+
+// TransformAuthenticationFlow transforms a top-level flow and its execution
+// tree to KeycloakAuthenticationFlow
+func (t *Transformer) TransformAuthenticationFlow(flow keycloak.AuthenticationFlowRepresentation, executions []FlowExecution) (ExportedResource, error) {
+	alias := stringValue(flow.Alias)
+
+	authFlow := &keycloakv1beta1.KeycloakAuthenticationFlow{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "keycloak.hostzero.com/v1beta1",
+			Kind:       "KeycloakAuthenticationFlow",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      t.objectName(alias),
+			Namespace: t.opts.TargetNamespace,
+		},
+		Spec: keycloakv1beta1.KeycloakAuthenticationFlowSpec{
+			RealmRef:        t.realmRef(),
+			ClusterRealmRef: t.clusterRealmRef(),
+			Alias:           alias,
+			Description:     stringValue(flow.Description),
+			ProviderId:      stringValue(flow.ProviderID),
+		},
+	}
+
+	if len(executions) > 0 {
+		raw, err := json.Marshal(executions)
+		if err != nil {
+			return ExportedResource{}, err
+		}
+		authFlow.Spec.Executions = runtime.RawExtension{Raw: raw}
+	}
+
+	return ExportedResource{
+		Kind:       "KeycloakAuthenticationFlow",
+		Name:       authFlow.Name,
+		APIVersion: "keycloak.hostzero.com/v1beta1",
+		Object:     authFlow,
+	}, nil
+}
+
 // TransformProtocolMapper transforms a protocol mapper JSON to KeycloakProtocolMapper
 func (t *Transformer) TransformProtocolMapper(raw json.RawMessage, clientID, scopeName string) (ExportedResource, error) {
 	var parsed struct {
@@ -478,10 +772,10 @@ func (t *Transformer) TransformProtocolMapper(raw json.RawMessage, clientID, sco
 	var parentRef string
 	if clientID != "" {
 		name = clientID + "-" + parsed.Name
-		parentRef = sanitizeName(clientID)
+		parentRef = t.objectName(clientID)
 	} else {
 		name = scopeName + "-" + parsed.Name
-		parentRef = sanitizeName(scopeName)
+		parentRef = t.objectName(scopeName)
 	}
 
 	mapper := &keycloakv1beta1.KeycloakProtocolMapper{
@@ -490,7 +784,7 @@ func (t *Transformer) TransformProtocolMapper(raw json.RawMessage, clientID, sco
 			Kind:       "KeycloakProtocolMapper",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(name),
+			Name:      t.objectName(name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakProtocolMapperSpec{
@@ -531,7 +825,7 @@ func (t *Transformer) TransformRoleMapping(subjectType, subjectName, roleName, c
 			Kind:       "KeycloakRoleMapping",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      sanitizeName(name),
+			Name:      t.objectName(name),
 			Namespace: t.opts.TargetNamespace,
 		},
 		Spec: keycloakv1beta1.KeycloakRoleMappingSpec{},
@@ -539,7 +833,7 @@ func (t *Transformer) TransformRoleMapping(subjectType, subjectName, roleName, c
 
 	// Set subject
 	subjectRef := &keycloakv1beta1.ResourceRef{
-		Name: sanitizeName(subjectName),
+		Name: t.objectName(subjectName),
 	}
 	if subjectType == "user" {
 		mapping.Spec.Subject = keycloakv1beta1.RoleMappingSubject{
@@ -557,7 +851,7 @@ func (t *Transformer) TransformRoleMapping(subjectType, subjectName, roleName, c
 		roleRefName = clientID + "-" + roleName
 	}
 	mapping.Spec.RoleRef = &keycloakv1beta1.ResourceRef{
-		Name: sanitizeName(roleRefName),
+		Name: t.objectName(roleRefName),
 	}
 
 	return ExportedResource{
@@ -639,3 +933,14 @@ func removeServerFields(raw json.RawMessage, fields ...string) json.RawMessage {
 func strPtr(s string) *string {
 	return &s
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
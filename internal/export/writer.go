@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"sigs.k8s.io/yaml"
@@ -13,6 +14,18 @@ import (
 type WriterOptions struct {
 	OutputFile string
 	OutputDir  string
+
+	// PerRealm writes the resources of each realm to a subdirectory of
+	// OutputDir named after the realm
+	PerRealm bool
+
+	// Layout of OutputDir (default: LayoutDirectory)
+	Layout Layout
+
+	// Namespace and InstanceRef the resources were exported with, which the
+	// Kustomize overlay and the Helm chart values start from
+	Namespace   string
+	InstanceRef string
 }
 
 // Writer writes exported resources to output
@@ -83,9 +96,50 @@ func (w *Writer) writeToFile(resources []ExportedResource) error {
 }
 
 func (w *Writer) writeToDirectory(resources []ExportedResource) error {
+	switch w.opts.Layout {
+	case LayoutKustomize:
+		return w.writeKustomize(resources)
+	case LayoutHelm:
+		return w.writeHelm(resources)
+	}
+	_, err := w.writeResources(w.opts.OutputDir, resources)
+	return err
+}
+
+// writeResources writes resources to dir, in a subdirectory per realm if
+// PerRealm is set, and returns the files written.
+func (w *Writer) writeResources(dir string, resources []ExportedResource) ([]string, error) {
+	if !w.opts.PerRealm {
+		return w.writeLayout(dir, resources)
+	}
+
+	// Resources outside any realm, such as the instance, stay at the top
+	byRealm := make(map[string][]ExportedResource)
+	for _, res := range resources {
+		byRealm[res.Realm] = append(byRealm[res.Realm], res)
+	}
+	var files []string
+	for realm, realmResources := range byRealm {
+		realmDir := dir
+		if realm != "" {
+			realmDir = filepath.Join(dir, sanitizeName(realm))
+		}
+		written, err := w.writeLayout(realmDir, realmResources)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, written...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// writeLayout writes resources to dir, in a subdirectory per kind, and
+// returns the files written.
+func (w *Writer) writeLayout(outputDir string, resources []ExportedResource) ([]string, error) {
 	// Create base directory
-	if err := os.MkdirAll(w.opts.OutputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Group resources by kind
@@ -95,34 +149,38 @@ func (w *Writer) writeToDirectory(resources []ExportedResource) error {
 	}
 
 	// Write each kind to its own subdirectory
+	var files []string
 	for kind, kindResources := range byKind {
 		dirName := kindToDirectory(kind)
-		dir := filepath.Join(w.opts.OutputDir, dirName)
+		dir := filepath.Join(outputDir, dirName)
 
 		// For single resources (like realm), write directly
-		if len(kindResources) == 1 && kind == "KeycloakRealm" {
+		if file := singletonFile(kind); len(kindResources) == 1 && file != "" {
 			res := kindResources[0]
-			filename := filepath.Join(w.opts.OutputDir, "realm.yaml")
+			filename := filepath.Join(outputDir, file)
 			if err := w.writeResourceToFile(res, filename); err != nil {
-				return err
+				return nil, err
 			}
+			files = append(files, filename)
 			continue
 		}
 
 		// Create subdirectory for multiple resources
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+			return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 
 		for _, res := range kindResources {
 			filename := filepath.Join(dir, res.Name+".yaml")
 			if err := w.writeResourceToFile(res, filename); err != nil {
-				return err
+				return nil, err
 			}
+			files = append(files, filename)
 		}
 	}
 
-	return nil
+	sort.Strings(files)
+	return files, nil
 }
 
 func (w *Writer) writeResourceToFile(res ExportedResource, filename string) error {
@@ -138,13 +196,36 @@ func (w *Writer) writeResourceToFile(res ExportedResource, filename string) erro
 	return nil
 }
 
+// singletonFile returns the file a kind is written to when there is one
+// resource of it, or "" for kinds that always get a directory.
+func singletonFile(kind string) string {
+	switch kind {
+	case "KeycloakRealm", "ClusterKeycloakRealm":
+		return "realm.yaml"
+	case "KeycloakInstance", "ClusterKeycloakInstance":
+		return "instance.yaml"
+	}
+	return ""
+}
+
 // kindToDirectory maps CRD kinds to directory names
 func kindToDirectory(kind string) string {
+	switch kind {
+	case "ClusterKeycloakInstance":
+		return "cluster-instances"
+	case "ClusterKeycloakRealm":
+		return "cluster-realms"
+	case "ExternalSecret":
+		return "external-secrets"
+	}
+
 	// Remove "Keycloak" prefix and convert to kebab-case
 	name := strings.TrimPrefix(kind, "Keycloak")
 
 	// Handle special cases
 	switch name {
+	case "AuthenticationFlow":
+		return "authentication-flows"
 	case "ClientScope":
 		return "client-scopes"
 	case "IdentityProvider":
@@ -153,6 +234,8 @@ func kindToDirectory(kind string) string {
 		return "identity-provider-mappers"
 	case "ProtocolMapper":
 		return "protocol-mappers"
+	case "RequiredAction":
+		return "required-actions"
 	case "RoleMapping":
 		return "role-mappings"
 	}
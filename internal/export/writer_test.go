@@ -0,0 +1,206 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"text/template"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func TestInstanceManifests(t *testing.T) {
+	resources := InstanceManifests(ExporterOptions{TargetNamespace: "iam"}, "https://keycloak.example.com", "")
+	require.Equal(t, []string{"KeycloakInstance/keycloak-instance", "Secret/keycloak-instance-admin"}, resourceKinds(resources))
+
+	instance := resources[0].Object.(*keycloakv1beta1.KeycloakInstance)
+	require.Equal(t, "https://keycloak.example.com", instance.Spec.BaseUrl)
+	require.Equal(t, "keycloak-instance-admin", instance.Spec.Auth.PasswordGrant.SecretRef.Name)
+	secret := resources[1].Object.(*corev1.Secret)
+	require.Equal(t, "iam", secret.Namespace)
+	require.Equal(t, map[string]string{
+		"username": "${KEYCLOAK_ADMIN_USERNAME}",
+		"password": "${KEYCLOAK_ADMIN_PASSWORD}",
+	}, secret.StringData)
+
+	resources = InstanceManifests(ExporterOptions{TargetNamespace: "iam", InstanceRef: "shared", ClusterInstance: true}, "http://keycloak:8080", "admin")
+	cluster := resources[0].Object.(*keycloakv1beta1.ClusterKeycloakInstance)
+	require.Empty(t, cluster.Namespace)
+	require.Equal(t, "iam", cluster.Spec.Auth.PasswordGrant.SecretRef.Namespace)
+	require.Equal(t, "admin", resources[1].Object.(*corev1.Secret).StringData["username"])
+}
+
+func TestWriterPerRealm(t *testing.T) {
+	data := []byte(`[{"realm": "alpha", "clients": [{"clientId": "app", "publicClient": true}]}, {"realm": "beta"}]`)
+	resources, err := ExportAllRealmsFromFile(data, testr.New(t), ExporterOptions{})
+	require.NoError(t, err)
+	resources = append(InstanceManifests(ExporterOptions{}, "http://keycloak:8080", ""), resources...)
+
+	dir := t.TempDir()
+	require.NoError(t, NewWriter(WriterOptions{OutputDir: dir, PerRealm: true}).Write(resources))
+
+	require.Equal(t, []string{
+		"alpha/clients/alpha-app.yaml",
+		"alpha/realm.yaml",
+		"beta/realm.yaml",
+		"instance.yaml",
+		"secrets/keycloak-instance-admin.yaml",
+	}, writtenFiles(t, dir))
+
+	realm, err := os.ReadFile(filepath.Join(dir, "alpha", "realm.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(realm), "realmName: alpha")
+}
+
+func writtenFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	sort.Strings(files)
+	return files
+}
+
+func layoutResources(t *testing.T) []ExportedResource {
+	t.Helper()
+	opts := ExporterOptions{TargetNamespace: "iam", Secrets: SecretModeStub}
+	exporter, err := NewFileExporter([]byte(`{"realm": "acme", "clients": [{"clientId": "api"}]}`), testr.New(t), opts)
+	require.NoError(t, err)
+	resources, err := exporter.Export()
+	require.NoError(t, err)
+	return append(InstanceManifests(opts, "http://keycloak:8080", "admin"), resources...)
+}
+
+func TestWriterKustomize(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewWriter(WriterOptions{OutputDir: dir, Layout: LayoutKustomize, Namespace: "iam"}).Write(layoutResources(t)))
+	require.Equal(t, []string{
+		"base/clients/api.yaml",
+		"base/instance.yaml",
+		"base/kustomization.yaml",
+		"base/realm.yaml",
+		"base/secrets/api-secret.yaml",
+		"base/secrets/keycloak-instance-admin.yaml",
+		"overlays/iam/kustomization.yaml",
+	}, writtenFiles(t, dir))
+
+	base, err := os.ReadFile(filepath.Join(dir, "base", "kustomization.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- clients/api.yaml
+- instance.yaml
+- realm.yaml
+- secrets/api-secret.yaml
+- secrets/keycloak-instance-admin.yaml
+`, string(base))
+
+	overlay, err := os.ReadFile(filepath.Join(dir, "overlays", "iam", "kustomization.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: iam
+patches:
+- patch: |
+    - op: replace
+      path: /spec/instanceRef/name
+      value: keycloak-instance
+  target:
+    kind: KeycloakRealm
+    name: acme
+resources:
+- ../../base
+`, string(overlay))
+}
+
+func TestWriterHelm(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewWriter(WriterOptions{OutputDir: dir, Layout: LayoutHelm, Namespace: "iam"}).Write(layoutResources(t)))
+	require.Equal(t, []string{
+		"Chart.yaml",
+		"templates/clients/api.yaml",
+		"templates/instance.yaml",
+		"templates/realm.yaml",
+		"templates/secrets/api-secret.yaml",
+		"templates/secrets/keycloak-instance-admin.yaml",
+		"values.yaml",
+	}, writtenFiles(t, dir))
+
+	chart, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(chart), "name: keycloak-acme\n")
+
+	values, err := os.ReadFile(filepath.Join(dir, "values.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, `# Namespace of the resources
+namespace: iam
+
+# KeycloakInstance, or ClusterKeycloakInstance, the realms reference
+instanceRef: keycloak-instance
+
+# Realm names in Keycloak, by exported realm name
+realmNames:
+  acme: acme
+
+# Secret names, by exported Secret name
+secretNames:
+  api-secret: api-secret
+  keycloak-instance-admin: keycloak-instance-admin
+`, string(values))
+
+	realm, err := os.ReadFile(filepath.Join(dir, "templates", "realm.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(realm), "namespace: '{{ .Values.namespace }}'\n")
+	require.Contains(t, string(realm), "name: '{{ .Values.instanceRef }}'\n")
+	require.Contains(t, string(realm), `realmName: '{{ index .Values.realmNames "acme" }}'`)
+
+	instance, err := os.ReadFile(filepath.Join(dir, "templates", "instance.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(instance), "  name: '{{ .Values.instanceRef }}'\n")
+	require.Contains(t, string(instance), `name: '{{ index .Values.secretNames "keycloak-instance-admin" }}'`)
+
+	client, err := os.ReadFile(filepath.Join(dir, "templates", "clients", "api.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(client), `name: '{{ index .Values.secretNames "api-secret" }}'`)
+	require.Contains(t, string(client), "realmRef:\n    name: acme\n", "object names are not values")
+
+	// Overriding a realm name renames the realm instead of conflicting with
+	// its definition, which the reconciler would reject if it kept the
+	// exported name.
+	rendered := renderHelmTemplate(t, dir, "templates/realm.yaml", map[string]interface{}{"acme": "acme-staging"})
+	renamed := &keycloakv1beta1.KeycloakRealm{}
+	require.NoError(t, yaml.Unmarshal(rendered, renamed))
+	require.Equal(t, "acme-staging", *renamed.Spec.RealmName)
+	require.NotContains(t, string(renamed.Spec.Definition.Raw), `"realm"`)
+}
+
+// renderHelmTemplate renders a template of the chart in dir with its values,
+// realmNames replaced by realmNames. The templates only use .Values, which
+// text/template evaluates like Helm.
+func renderHelmTemplate(t *testing.T, dir, name string, realmNames map[string]interface{}) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "values.yaml"))
+	require.NoError(t, err)
+	values := map[string]interface{}{}
+	require.NoError(t, yaml.Unmarshal(data, &values))
+	values["realmNames"] = realmNames
+
+	tmpl, err := template.ParseFiles(filepath.Join(dir, name))
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, tmpl.Execute(&out, map[string]interface{}{"Values": values}))
+	return out.Bytes()
+}
@@ -0,0 +1,118 @@
+package keycloak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInstanceUnavailable is returned, without contacting the server, while the
+// circuit breaker of a Keycloak instance is open.
+var ErrInstanceUnavailable = errors.New("Keycloak instance unavailable")
+
+// IsInstanceUnavailable reports whether err (or anything it wraps) is
+// ErrInstanceUnavailable.
+func IsInstanceUnavailable(err error) bool {
+	return errors.Is(err, ErrInstanceUnavailable)
+}
+
+// circuitBreaker stops requests to an instance after threshold consecutive
+// failures. Once cooldown has passed a single trial request is let through;
+// its success closes the breaker and its failure re-opens it for another
+// cooldown. A nil breaker never trips.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	// onChange is called outside the lock whenever the breaker opens or
+	// closes.
+	onChange func(open bool)
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, onChange func(open bool)) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, onChange: onChange, now: time.Now}
+}
+
+// allow returns an error wrapping ErrInstanceUnavailable while the breaker is
+// open and the cooldown has not passed. The first call after the cooldown is
+// the trial request and pushes openUntil out again, so concurrent callers keep
+// failing fast until the trial has been recorded.
+func (b *circuitBreaker) allow() error {
+	return b.check(true)
+}
+
+// available is allow without claiming the trial request.
+func (b *circuitBreaker) available() error {
+	return b.check(false)
+}
+
+func (b *circuitBreaker) check(claimTrial bool) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return fmt.Errorf("%w: circuit breaker open after %d consecutive failures, next attempt after %s",
+			ErrInstanceUnavailable, b.failures, b.openUntil.Format(time.RFC3339))
+	}
+	if claimTrial {
+		b.openUntil = now.Add(b.cooldown)
+	}
+	return nil
+}
+
+// record feeds the outcome of one request into the breaker.
+func (b *circuitBreaker) record(success bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	wasOpen := b.open
+	if success {
+		b.failures = 0
+		b.open = false
+	} else {
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open = true
+			b.openUntil = b.now().Add(b.cooldown)
+		}
+	}
+	open := b.open
+	b.mu.Unlock()
+
+	if wasOpen != open && b.onChange != nil {
+		b.onChange(open)
+	}
+}
+
+// isOpen reports whether the breaker is open, regardless of cooldown.
+func (b *circuitBreaker) isOpen() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// countsAsFailure reports whether a transport error says the server is
+// unreachable. Cancellations are the caller's doing and don't count.
+func countsAsFailure(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,151 @@
+package keycloak
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var changes []bool
+	b := newCircuitBreaker(3, time.Minute, func(open bool) { changes = append(changes, open) })
+	b.now = func() time.Time { return now }
+
+	b.record(false)
+	b.record(false)
+	assert.NoError(t, b.allow(), "below the threshold")
+
+	b.record(false)
+	err := b.allow()
+	require.Error(t, err)
+	assert.True(t, IsInstanceUnavailable(err))
+	assert.True(t, b.isOpen())
+
+	// The cooldown lets exactly one trial request through.
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.available(), "available does not claim the trial")
+	assert.NoError(t, b.allow())
+	assert.Error(t, b.allow(), "concurrent callers wait for the trial")
+
+	// A failed trial re-opens the breaker for another cooldown.
+	b.record(false)
+	assert.Error(t, b.allow())
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.allow())
+
+	b.record(true)
+	assert.NoError(t, b.allow())
+	assert.False(t, b.isOpen())
+
+	assert.Equal(t, []bool{true, false}, changes)
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute, nil)
+	assert.Nil(t, b)
+	for range 10 {
+		b.record(false)
+	}
+	assert.NoError(t, b.allow())
+	assert.False(t, b.isOpen())
+}
+
+func TestCountsAsFailure(t *testing.T) {
+	assert.False(t, countsAsFailure(nil))
+	assert.False(t, countsAsFailure(context.Canceled))
+	assert.True(t, countsAsFailure(context.DeadlineExceeded))
+	assert.True(t, countsAsFailure(errors.New("connection refused")))
+}
+
+func TestClientManager_Breaker(t *testing.T) {
+	var down atomic.Bool
+	var adminCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /realms/master/protocol/openid-connect/token", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"opaque","expires_in":300}`))
+	})
+	mux.HandleFunc("GET /admin/", func(w http.ResponseWriter, r *http.Request) {
+		adminCalls.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/admin/serverinfo" {
+			_, _ = w.Write([]byte(`{"systemInfo":{"version":"26.1.0"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mgr := NewClientManagerWithConfig(testr.New(t), ClientManagerConfig{
+		BreakerFailureThreshold: 2,
+		BreakerCooldown:         time.Hour,
+	})
+	type change struct {
+		key  string
+		open bool
+	}
+	var changes []change
+	mgr.AddBreakerListener(func(key string, open bool) { changes = append(changes, change{key, open}) })
+
+	kc := mgr.GetOrCreateClient("kc/main", Config{BaseURL: srv.URL, Username: "admin", Password: "admin"})
+	ctx := context.Background()
+
+	down.Store(true)
+	for range 2 {
+		_, err := kc.GetRaw(ctx, "/admin/realms/demo")
+		require.Error(t, err)
+		assert.False(t, IsInstanceUnavailable(err))
+	}
+	require.Equal(t, int32(2), adminCalls.Load())
+
+	// Open: requests fail fast without reaching the server, and a client
+	// recreated for new credentials shares the breaker.
+	_, err := kc.GetRaw(ctx, "/admin/realms/demo")
+	assert.True(t, IsInstanceUnavailable(err))
+	assert.True(t, IsInstanceUnavailable(kc.Available()))
+	kc = mgr.GetOrCreateClient("kc/main", Config{BaseURL: srv.URL, Username: "admin", Password: "rotated"})
+	assert.True(t, IsInstanceUnavailable(kc.Available()))
+	assert.Equal(t, int32(2), adminCalls.Load())
+
+	// The health probe bypasses the breaker; its success closes it.
+	down.Store(false)
+	_, err = kc.Probe(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, kc.Available())
+	_, err = kc.GetRaw(ctx, "/admin/realms/demo")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []change{{"kc/main", true}, {"kc/main", false}}, changes)
+
+	// Other instances are unaffected, and removing the instance resets it.
+	assert.NoError(t, mgr.GetOrCreateClient("kc/other", Config{BaseURL: srv.URL}).Available())
+	mgr.RemoveClient("kc/main")
+	_, ok := mgr.breakers.Load("kc/main")
+	assert.False(t, ok)
+}
+
+func TestWithRetry_StopsWhenInstanceUnavailable(t *testing.T) {
+	calls := 0
+	cfg := DefaultRetryConfig()
+	cfg.InitialDelay = time.Millisecond
+	_, err := WithRetry(context.Background(), cfg, "get", func() (struct{}, error) {
+		calls++
+		return struct{}{}, ErrInstanceUnavailable
+	})
+	assert.True(t, IsInstanceUnavailable(err))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,210 @@
+package keycloak
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Capability names an Admin API feature that is only available on some
+// Keycloak servers, either because it was added in a later release or
+// because it sits behind a server feature flag.
+type Capability string
+
+const (
+	// CapabilityOrganizations covers the /organizations endpoints.
+	CapabilityOrganizations Capability = "organizations"
+	// CapabilityExecutionPriority covers honouring the priority field when
+	// updating flow executions (keycloak/keycloak#27751).
+	CapabilityExecutionPriority Capability = "execution-priority"
+	// CapabilityGroupChildren covers the /groups/{id}/children endpoint and
+	// the lazy (non-inlined) subGroups listing that comes with it.
+	CapabilityGroupChildren Capability = "group-children"
+	// CapabilityAdminFineGrainedAuthz covers management permissions on
+	// clients, identity providers, etc.
+	CapabilityAdminFineGrainedAuthz Capability = "admin-fine-grained-authz"
+	// CapabilityTokenExchange covers the token-exchange permission scopes.
+	CapabilityTokenExchange Capability = "token-exchange"
+)
+
+// capabilityRequirement describes what a server needs for a capability.
+type capabilityRequirement struct {
+	// minMajor is the first Keycloak major version that has the capability.
+	minMajor int
+	// feature is the server feature that must be enabled, if any, in the
+	// normalized form used by normalizeFeature.
+	feature string
+}
+
+// capabilityRegistry is the single source of truth for version and feature
+// gates. Add an entry here rather than comparing version strings in
+// controllers.
+var capabilityRegistry = map[Capability]capabilityRequirement{
+	CapabilityOrganizations:         {minMajor: 25, feature: "organization"},
+	CapabilityExecutionPriority:     {minMajor: 25},
+	CapabilityGroupChildren:         {minMajor: 23},
+	CapabilityAdminFineGrainedAuthz: {feature: "admin-fine-grained-authz"},
+	CapabilityTokenExchange:         {feature: "token-exchange"},
+}
+
+// Capabilities is the set of capabilities a particular server supports. The
+// zero value (and a nil pointer) stands for a server that has not been probed
+// yet and supports everything, leaving the decision to the server.
+type Capabilities struct {
+	// Version is the server version the set was derived from.
+	Version   string
+	supported map[Capability]bool
+}
+
+// DetectCapabilities derives the capability set from /admin/serverinfo. The
+// features list is preferred when the server reports one; otherwise a feature
+// counts as enabled unless profileInfo lists it as disabled. Without server
+// info or a parseable version the result is unprobed.
+func DetectCapabilities(info *ServerInfo) *Capabilities {
+	if info == nil {
+		return &Capabilities{}
+	}
+
+	enabled := map[string]bool{}
+	disabled := map[string]bool{}
+	for _, f := range info.Features {
+		enabled[normalizeFeature(f.Name)] = f.Enabled
+	}
+	for _, name := range info.ProfileInfo.DisabledFeatures {
+		disabled[normalizeFeature(name)] = true
+	}
+	featureEnabled := func(name string) bool {
+		if v, ok := enabled[name]; ok {
+			return v
+		}
+		return !disabled[name]
+	}
+
+	return detect(info.SystemInfo.Version, featureEnabled)
+}
+
+// CapabilitiesFromStatus rebuilds the capability set recorded on an instance
+// status. detected tells a recorded empty list, a server without any of the
+// capabilities, from a status that predates capability detection. For the
+// latter the set is derived from the version alone, assuming default feature
+// flags; when the version is unknown too, the result is unprobed.
+func CapabilitiesFromStatus(version string, names []string, detected bool) *Capabilities {
+	if !detected && version == "" {
+		return &Capabilities{}
+	}
+	if !detected {
+		return detect(version, func(string) bool { return true })
+	}
+	caps := &Capabilities{Version: version, supported: map[Capability]bool{}}
+	for _, name := range names {
+		caps.supported[Capability(name)] = true
+	}
+	return caps
+}
+
+func detect(version string, featureEnabled func(string) bool) *Capabilities {
+	major, err := MajorVersion(version)
+	if err != nil {
+		return &Capabilities{Version: version}
+	}
+	caps := &Capabilities{Version: version, supported: map[Capability]bool{}}
+	for capability, req := range capabilityRegistry {
+		if major < req.minMajor {
+			continue
+		}
+		if req.feature != "" && !featureEnabled(req.feature) {
+			continue
+		}
+		caps.supported[capability] = true
+	}
+	return caps
+}
+
+// Supports reports whether the server has the capability. An unprobed set
+// reports true so callers fall back to letting the server decide.
+func (c *Capabilities) Supports(capability Capability) bool {
+	if c == nil || c.supported == nil {
+		return true
+	}
+	return c.supported[capability]
+}
+
+// Probed reports whether the set was derived from a server rather than
+// standing for an unprobed one.
+func (c *Capabilities) Probed() bool {
+	return c != nil && c.supported != nil
+}
+
+// Require returns an *UnsupportedError when the server lacks the capability.
+func (c *Capabilities) Require(capability Capability) error {
+	if c.Supports(capability) {
+		return nil
+	}
+	return &UnsupportedError{Capability: capability, Version: c.Version}
+}
+
+// List returns the supported capability names, sorted.
+func (c *Capabilities) List() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.supported))
+	for capability, ok := range c.supported {
+		if ok {
+			names = append(names, string(capability))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnsupportedError is returned when an operation needs a capability the
+// server does not have.
+type UnsupportedError struct {
+	Capability Capability
+	Version    string
+}
+
+func (e *UnsupportedError) Error() string {
+	req := capabilityRegistry[e.Capability]
+	var needs []string
+	if req.minMajor > 0 {
+		needs = append(needs, fmt.Sprintf("Keycloak %d or later", req.minMajor))
+	}
+	if req.feature != "" {
+		needs = append(needs, fmt.Sprintf("the %q server feature enabled", req.feature))
+	}
+	version := e.Version
+	if version == "" {
+		version = "unknown"
+	}
+	return fmt.Sprintf("%s requires %s (server version: %s)", e.Capability, strings.Join(needs, " with "), version)
+}
+
+// IsUnsupported reports whether err (or anything it wraps) is an
+// *UnsupportedError.
+func IsUnsupported(err error) bool {
+	var target *UnsupportedError
+	return errors.As(err, &target)
+}
+
+// MajorVersion parses the major component of a Keycloak version string such
+// as "26.0.5" or "24.0.0-SNAPSHOT".
+func MajorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+	major, _, _ = strings.Cut(major, "-")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Keycloak version %q", version)
+	}
+	return n, nil
+}
+
+// normalizeFeature maps the different spellings Keycloak uses for feature
+// names ("ADMIN_FINE_GRAINED_AUTHZ", "token-exchange:v1") to one form.
+func normalizeFeature(name string) string {
+	name, _, _ = strings.Cut(name, ":")
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
@@ -0,0 +1,147 @@
+package keycloak
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serverInfo(version string, disabled []string, features []ServerFeature) *ServerInfo {
+	info := &ServerInfo{Features: features}
+	info.SystemInfo.Version = version
+	info.ProfileInfo.DisabledFeatures = disabled
+	return info
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	tests := []struct {
+		name string
+		info *ServerInfo
+		want []string
+	}{
+		{
+			name: "keycloak 22 with default features",
+			info: serverInfo("22.0.5", nil, nil),
+			want: []string{"admin-fine-grained-authz", "token-exchange"},
+		},
+		{
+			name: "keycloak 24 snapshot",
+			info: serverInfo("24.0.0-SNAPSHOT", nil, nil),
+			want: []string{"admin-fine-grained-authz", "group-children", "token-exchange"},
+		},
+		{
+			name: "keycloak 25 with the organization preview enabled",
+			info: serverInfo("25.0.6", nil, []ServerFeature{{Name: "ORGANIZATION", Enabled: true}}),
+			want: []string{"admin-fine-grained-authz", "execution-priority", "group-children", "organizations", "token-exchange"},
+		},
+		{
+			name: "keycloak 25 with the organization preview disabled",
+			info: serverInfo("25.0.6", []string{"ORGANIZATION"}, nil),
+			want: []string{"admin-fine-grained-authz", "execution-priority", "group-children", "token-exchange"},
+		},
+		{
+			name: "keycloak 26 with everything enabled",
+			info: serverInfo("26.0.5", nil, nil),
+			want: []string{"admin-fine-grained-authz", "execution-priority", "group-children", "organizations", "token-exchange"},
+		},
+		{
+			name: "profile disables features",
+			info: serverInfo("26.0.5", []string{"ORGANIZATION", "TOKEN_EXCHANGE"}, nil),
+			want: []string{"admin-fine-grained-authz", "execution-priority", "group-children"},
+		},
+		{
+			name: "features list takes precedence over profile",
+			info: serverInfo("26.1.0", []string{"ORGANIZATION"}, []ServerFeature{
+				{Name: "ORGANIZATION", Enabled: true},
+				{Name: "ADMIN_FINE_GRAINED_AUTHZ", Enabled: false},
+				{Name: "TOKEN_EXCHANGE", Enabled: true},
+			}),
+			want: []string{"execution-priority", "group-children", "organizations", "token-exchange"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectCapabilities(tt.info).List())
+		})
+	}
+}
+
+func TestDetectCapabilitiesUnprobed(t *testing.T) {
+	for name, info := range map[string]*ServerInfo{
+		"nil server info":     nil,
+		"unparseable version": serverInfo("nightly", nil, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			caps := DetectCapabilities(info)
+			assert.False(t, caps.Probed())
+			assert.True(t, caps.Supports(CapabilityOrganizations))
+			assert.NoError(t, caps.Require(CapabilityExecutionPriority))
+			assert.NoError(t, caps.Require(CapabilityGroupChildren))
+		})
+	}
+}
+
+func TestCapabilitiesFromStatus(t *testing.T) {
+	t.Run("recorded list is used as is", func(t *testing.T) {
+		caps := CapabilitiesFromStatus("26.0.0", []string{"group-children"}, true)
+		assert.True(t, caps.Supports(CapabilityGroupChildren))
+		assert.False(t, caps.Supports(CapabilityOrganizations))
+		assert.Equal(t, "26.0.0", caps.Version)
+	})
+
+	t.Run("detected empty list supports nothing", func(t *testing.T) {
+		caps := CapabilitiesFromStatus("24.0.1", nil, true)
+		assert.False(t, caps.Supports(CapabilityTokenExchange))
+		assert.False(t, caps.Supports(CapabilityAdminFineGrainedAuthz))
+		assert.True(t, IsUnsupported(caps.Require(CapabilityTokenExchange)))
+	})
+
+	t.Run("falls back to version when detection never ran", func(t *testing.T) {
+		caps := CapabilitiesFromStatus("24.0.1", nil, false)
+		assert.True(t, caps.Supports(CapabilityGroupChildren))
+		assert.False(t, caps.Supports(CapabilityExecutionPriority))
+		assert.False(t, caps.Supports(CapabilityOrganizations))
+	})
+
+	t.Run("unknown version is unprobed", func(t *testing.T) {
+		caps := CapabilitiesFromStatus("", nil, false)
+		assert.True(t, caps.Supports(CapabilityOrganizations))
+		assert.NoError(t, caps.Require(CapabilityExecutionPriority))
+	})
+}
+
+func TestCapabilitiesRequire(t *testing.T) {
+	var unprobed *Capabilities
+	assert.NoError(t, unprobed.Require(CapabilityOrganizations))
+
+	caps := DetectCapabilities(serverInfo("24.0.5", nil, nil))
+	assert.NoError(t, caps.Require(CapabilityGroupChildren))
+
+	err := caps.Require(CapabilityOrganizations)
+	require.Error(t, err)
+	assert.True(t, IsUnsupported(err))
+	assert.EqualError(t, err, `organizations requires Keycloak 25 or later with the "organization" server feature enabled (server version: 24.0.5)`)
+
+	err = DetectCapabilities(serverInfo("26.0.0", []string{"token-exchange"}, nil)).Require(CapabilityTokenExchange)
+	assert.EqualError(t, err, `token-exchange requires the "token-exchange" server feature enabled (server version: 26.0.0)`)
+
+	assert.False(t, IsUnsupported(nil))
+}
+
+func TestMajorVersion(t *testing.T) {
+	for version, want := range map[string]int{
+		"26.0.5":          26,
+		"24.0.0-SNAPSHOT": 24,
+		"999":             999,
+		"25-rc1":          25,
+	} {
+		got, err := MajorVersion(version)
+		require.NoError(t, err, version)
+		assert.Equal(t, want, got, version)
+	}
+
+	_, err := MajorVersion("")
+	assert.Error(t, err)
+}
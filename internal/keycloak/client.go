@@ -8,7 +8,9 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
@@ -35,6 +37,9 @@ type Client struct {
 	tokenExpiry time.Time
 	tokenMutex  sync.RWMutex
 	log         logr.Logger
+
+	// breaker is shared by all clients of one instance; nil disables it.
+	breaker *circuitBreaker
 }
 
 // Config holds Keycloak client configuration
@@ -76,7 +81,7 @@ func NewClient(cfg Config, log logr.Logger) *Client {
 		httpClient.SetTLSClientConfig(tlsCfg)
 	}
 
-	return &Client{
+	c := &Client{
 		baseURL:            strings.TrimSuffix(cfg.BaseURL, "/"),
 		realm:              cfg.Realm,
 		username:           cfg.Username,
@@ -88,6 +93,26 @@ func NewClient(cfg Config, log logr.Logger) *Client {
 		httpClient:         httpClient,
 		log:                log.WithName("keycloak-client"),
 	}
+
+	// Every response, including token requests, feeds the circuit breaker:
+	// 5xx responses and transport errors count as failures, anything else
+	// shows the server is up.
+	httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		c.breaker.record(resp.StatusCode() < http.StatusInternalServerError)
+		return nil
+	})
+	httpClient.OnError(func(_ *resty.Request, err error) {
+		var respErr *resty.ResponseError
+		if errors.As(err, &respErr) {
+			// Already recorded by OnAfterResponse.
+			return
+		}
+		if countsAsFailure(err) {
+			c.breaker.record(false)
+		}
+	})
+
+	return c
 }
 
 // buildTLSConfig returns a *tls.Config when the cfg requests TLS customisation,
@@ -180,8 +205,25 @@ func (c *Client) Ping(ctx context.Context) error {
 	return err
 }
 
-// request creates an authenticated request
+// Available returns an error wrapping ErrInstanceUnavailable while the
+// instance's circuit breaker is open, without making a request.
+func (c *Client) Available() error {
+	return c.breaker.available()
+}
+
+// request creates an authenticated request, failing fast while the circuit
+// breaker is open.
 func (c *Client) request(ctx context.Context) (*resty.Request, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	return c.newRequest(ctx)
+}
+
+// newRequest creates an authenticated request without consulting the circuit
+// breaker. Used by the instance health probe, which has to keep reaching the
+// server to notice when it is back.
+func (c *Client) newRequest(ctx context.Context) (*resty.Request, error) {
 	token, err := c.getToken(ctx)
 	if err != nil {
 		return nil, err
@@ -225,6 +267,31 @@ func (c *Client) Create(ctx context.Context, path string, body interface{}) (str
 	return "", nil
 }
 
+// NotFoundError is returned for requests Keycloak answered with 404 Not Found.
+type NotFoundError struct {
+	Status string
+	Body   string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+// IsNotFound reports whether err (or anything it wraps) is a *NotFoundError.
+func IsNotFound(err error) bool {
+	var target *NotFoundError
+	return errors.As(err, &target)
+}
+
+// responseError returns the error for a failed response, a *NotFoundError
+// for 404 Not Found.
+func responseError(resp *resty.Response) error {
+	if resp.StatusCode() == http.StatusNotFound {
+		return &NotFoundError{Status: resp.Status(), Body: string(resp.Body())}
+	}
+	return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+}
+
 // Get retrieves a resource
 func (c *Client) Get(ctx context.Context, path string, result interface{}) error {
 	req, err := c.request(ctx)
@@ -238,7 +305,7 @@ func (c *Client) Get(ctx context.Context, path string, result interface{}) error
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return responseError(resp)
 	}
 
 	return nil
@@ -284,7 +351,8 @@ func (c *Client) put(ctx context.Context, path string, body interface{}, result
 	return nil
 }
 
-// Delete deletes a resource
+// Delete deletes a resource. A resource that is already gone counts as
+// deleted, so callers can retry a deletion safely.
 func (c *Client) Delete(ctx context.Context, path string) error {
 	req, err := c.request(ctx)
 	if err != nil {
@@ -296,6 +364,9 @@ func (c *Client) Delete(ctx context.Context, path string) error {
 		return fmt.Errorf("request failed: %w", err)
 	}
 
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil
+	}
 	if resp.IsError() {
 		return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
 	}
@@ -388,6 +459,20 @@ type ServerInfo struct {
 	SystemInfo struct {
 		Version string `json:"version"`
 	} `json:"systemInfo"`
+	ProfileInfo struct {
+		Name                 string   `json:"name"`
+		DisabledFeatures     []string `json:"disabledFeatures"`
+		PreviewFeatures      []string `json:"previewFeatures"`
+		ExperimentalFeatures []string `json:"experimentalFeatures"`
+	} `json:"profileInfo"`
+	// Features is only reported by Keycloak 24 and later.
+	Features []ServerFeature `json:"features"`
+}
+
+// ServerFeature is one entry of the serverinfo features list
+type ServerFeature struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
 }
 
 // GetServerInfo returns Keycloak server information
@@ -448,6 +533,15 @@ func (c *Client) GetRealm(ctx context.Context, realmName string) (*RealmRepresen
 	return &realm, nil
 }
 
+// GetRealms lists the realms the caller may see
+func (c *Client) GetRealms(ctx context.Context) ([]RealmRepresentation, error) {
+	var realms []RealmRepresentation
+	if err := c.Get(ctx, "/admin/realms", &realms); err != nil {
+		return nil, err
+	}
+	return realms, nil
+}
+
 // UpdateRealm updates a realm from raw JSON definition
 func (c *Client) UpdateRealm(ctx context.Context, realmName string, definition json.RawMessage) error {
 	cfg := DefaultRetryConfig()
@@ -615,6 +709,21 @@ func (c *Client) DeleteUser(ctx context.Context, realmName, userID string) error
 	return c.Delete(ctx, "/admin/realms/"+url.PathEscape(realmName)+"/users/"+url.PathEscape(userID))
 }
 
+// DisableUser sets enabled to false on a user, leaving the rest of its
+// representation as it is.
+func (c *Client) DisableUser(ctx context.Context, realmName, userID string) error {
+	path := "/admin/realms/" + url.PathEscape(realmName) + "/users/" + url.PathEscape(userID)
+	var user map[string]interface{}
+	if err := c.Get(ctx, path, &user); err != nil {
+		return err
+	}
+	if enabled, ok := user["enabled"].(bool); ok && !enabled {
+		return nil
+	}
+	user["enabled"] = false
+	return c.Update(ctx, path, user)
+}
+
 // SetPassword sets a user's password
 func (c *Client) SetPassword(ctx context.Context, realmName, userID, password string, temporary bool) error {
 	cfg := DefaultRetryConfig()
@@ -1283,7 +1392,7 @@ func (c *Client) DeleteRealmRolesFromUser(ctx context.Context, realmName, userID
 		return fmt.Errorf("request failed: %w", err)
 	}
 	if resp.IsError() {
-		return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return responseError(resp)
 	}
 	return nil
 }
@@ -1307,7 +1416,7 @@ func (c *Client) DeleteClientRolesFromUser(ctx context.Context, realmName, clien
 		return fmt.Errorf("request failed: %w", err)
 	}
 	if resp.IsError() {
-		return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return responseError(resp)
 	}
 	return nil
 }
@@ -1331,7 +1440,7 @@ func (c *Client) DeleteRealmRolesFromGroup(ctx context.Context, realmName, group
 		return fmt.Errorf("request failed: %w", err)
 	}
 	if resp.IsError() {
-		return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return responseError(resp)
 	}
 	return nil
 }
@@ -1355,7 +1464,7 @@ func (c *Client) DeleteClientRolesFromGroup(ctx context.Context, realmName, clie
 		return fmt.Errorf("request failed: %w", err)
 	}
 	if resp.IsError() {
-		return fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return responseError(resp)
 	}
 	return nil
 }
@@ -1616,6 +1725,11 @@ func (c *Client) GetRequiredActions(ctx context.Context, realmName string) ([]Re
 	return actions, nil
 }
 
+// GetRequiredActionsRaw gets all registered required actions as raw JSON
+func (c *Client) GetRequiredActionsRaw(ctx context.Context, realmName string) ([]json.RawMessage, error) {
+	return c.ListRaw(ctx, "/admin/realms/"+url.PathEscape(realmName)+"/authentication/required-actions", nil)
+}
+
 // GetRequiredAction gets a required action by alias
 func (c *Client) GetRequiredAction(ctx context.Context, realmName, alias string) (*RequiredActionProviderRepresentation, error) {
 	var action RequiredActionProviderRepresentation
@@ -1703,6 +1817,11 @@ func (c *Client) GetAuthenticationFlows(ctx context.Context, realmName string) (
 	return flows, nil
 }
 
+// GetAuthenticationFlowsRaw gets all top-level authentication flows as raw JSON
+func (c *Client) GetAuthenticationFlowsRaw(ctx context.Context, realmName string) ([]json.RawMessage, error) {
+	return c.ListRaw(ctx, "/admin/realms/"+url.PathEscape(realmName)+"/authentication/flows", nil)
+}
+
 // GetAuthenticationFlowByAlias finds an authentication flow by its alias
 func (c *Client) GetAuthenticationFlowByAlias(ctx context.Context, realmName, alias string) (*AuthenticationFlowRepresentation, error) {
 	flows, err := c.GetAuthenticationFlows(ctx, realmName)
@@ -1717,6 +1836,16 @@ func (c *Client) GetAuthenticationFlowByAlias(ctx context.Context, realmName, al
 	return nil, fmt.Errorf("authentication flow not found: %s", alias)
 }
 
+// GetAuthenticationFlow gets an authentication flow by ID. Unlike the list
+// endpoint it also returns sub-flows.
+func (c *Client) GetAuthenticationFlow(ctx context.Context, realmName, flowID string) (*AuthenticationFlowRepresentation, error) {
+	var flow AuthenticationFlowRepresentation
+	if err := c.Get(ctx, "/admin/realms/"+url.PathEscape(realmName)+"/authentication/flows/"+url.PathEscape(flowID), &flow); err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
+
 // CreateAuthenticationFlow creates a new top-level authentication flow
 func (c *Client) CreateAuthenticationFlow(ctx context.Context, realmName string, flow AuthenticationFlowRepresentation) (string, error) {
 	cfg := DefaultRetryConfig()
@@ -1846,7 +1975,7 @@ func (c *Client) GetRaw(ctx context.Context, path string) (json.RawMessage, erro
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return nil, responseError(resp)
 	}
 
 	return resp.Body(), nil
@@ -1869,7 +1998,7 @@ func (c *Client) ListRaw(ctx context.Context, path string, params map[string]str
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("%s: %s", resp.Status(), string(resp.Body()))
+		return nil, responseError(resp)
 	}
 
 	// Parse as array of raw messages
@@ -2104,8 +2233,15 @@ func (c *Client) GetClientScopeProtocolMappersRaw(ctx context.Context, realmName
 // ClientManager handles Keycloak client lifecycle and rate limiting
 type ClientManager struct {
 	clients   sync.Map // map[string]*Client - key is instance name
+	breakers  sync.Map // map[string]*circuitBreaker - key is instance name
 	log       logr.Logger
 	semaphore chan struct{}
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	listenersMu sync.RWMutex
+	listeners   []func(instanceName string, open bool)
 }
 
 // ClientManagerConfig holds configuration for the ClientManager
@@ -2114,12 +2250,25 @@ type ClientManagerConfig struct {
 	// This prevents overwhelming Keycloak when reconciling many resources.
 	// Default: 10 (0 means no limit)
 	MaxConcurrentRequests int
+
+	// BreakerFailureThreshold is the number of consecutive failed requests
+	// (transport errors and 5xx responses) after which an instance's circuit
+	// breaker opens and requests fail fast with ErrInstanceUnavailable.
+	// Default: 5 (0 disables the breaker)
+	BreakerFailureThreshold int
+
+	// BreakerCooldown is how long an open breaker rejects requests before a
+	// trial request is let through.
+	// Default: 30s
+	BreakerCooldown time.Duration
 }
 
 // DefaultClientManagerConfig returns default client manager configuration
 func DefaultClientManagerConfig() ClientManagerConfig {
 	return ClientManagerConfig{
-		MaxConcurrentRequests: 10,
+		MaxConcurrentRequests:   10,
+		BreakerFailureThreshold: 5,
+		BreakerCooldown:         30 * time.Second,
 	}
 }
 
@@ -2135,11 +2284,53 @@ func NewClientManagerWithConfig(log logr.Logger, cfg ClientManagerConfig) *Clien
 		sem = make(chan struct{}, cfg.MaxConcurrentRequests)
 	}
 	return &ClientManager{
-		log:       log.WithName("keycloak-manager"),
-		semaphore: sem,
+		log:              log.WithName("keycloak-manager"),
+		semaphore:        sem,
+		breakerThreshold: cfg.BreakerFailureThreshold,
+		breakerCooldown:  cfg.BreakerCooldown,
 	}
 }
 
+// AddBreakerListener registers fn to be called whenever the circuit breaker
+// of an instance opens or closes. fn must not block.
+func (m *ClientManager) AddBreakerListener(fn func(instanceName string, open bool)) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// breakerFor returns the circuit breaker of an instance, creating it on first
+// use. The breaker outlives client recreation on config changes.
+func (m *ClientManager) breakerFor(instanceName string) *circuitBreaker {
+	if existing, ok := m.breakers.Load(instanceName); ok {
+		return existing.(*circuitBreaker)
+	}
+	breaker := newCircuitBreaker(m.breakerThreshold, m.breakerCooldown, func(open bool) {
+		if open {
+			m.log.Info("circuit breaker opened, failing requests fast", "instance", instanceName, "cooldown", m.breakerCooldown)
+		} else {
+			m.log.Info("circuit breaker closed", "instance", instanceName)
+		}
+		m.listenersMu.RLock()
+		defer m.listenersMu.RUnlock()
+		for _, fn := range m.listeners {
+			fn(instanceName, open)
+		}
+	})
+	if breaker == nil {
+		return nil
+	}
+	actual, _ := m.breakers.LoadOrStore(instanceName, breaker)
+	return actual.(*circuitBreaker)
+}
+
+// newClient creates a client wired to the instance's circuit breaker.
+func (m *ClientManager) newClient(instanceName string, cfg Config) *Client {
+	client := NewClient(cfg, m.log)
+	client.breaker = m.breakerFor(instanceName)
+	return client
+}
+
 // AcquireSlot acquires a rate-limiting slot. The returned function must be called to release the slot.
 // If rate limiting is not configured, returns a no-op function immediately.
 func (m *ClientManager) AcquireSlot(ctx context.Context) (release func(), err error) {
@@ -2164,13 +2355,13 @@ func (m *ClientManager) GetOrCreateClient(instanceName string, cfg Config) *Clie
 		client := existing.(*Client)
 		// If the config has changed, recreate the client
 		if m.configChanged(client, cfg) {
-			client = NewClient(cfg, m.log)
+			client = m.newClient(instanceName, cfg)
 			m.clients.Store(instanceName, client)
 		}
 		return client
 	}
 
-	client := NewClient(cfg, m.log)
+	client := m.newClient(instanceName, cfg)
 	m.clients.Store(instanceName, client)
 	return client
 }
@@ -2190,6 +2381,7 @@ func (m *ClientManager) configChanged(client *Client, cfg Config) bool {
 // RemoveClient removes a client from the manager
 func (m *ClientManager) RemoveClient(instanceName string) {
 	m.clients.Delete(instanceName)
+	m.breakers.Delete(instanceName)
 }
 
 // ClearClients removes all clients
@@ -2198,6 +2390,10 @@ func (m *ClientManager) ClearClients() {
 		m.clients.Delete(key)
 		return true
 	})
+	m.breakers.Range(func(key, value interface{}) bool {
+		m.breakers.Delete(key)
+		return true
+	})
 }
 
 // ============================================================================
@@ -2278,6 +2474,11 @@ func WithRetry[T any](ctx context.Context, cfg RetryConfig, operation string, fn
 			return result, nil
 		}
 
+		// Backing off is pointless while the instance is known to be down
+		if IsInstanceUnavailable(lastErr) {
+			return result, lastErr
+		}
+
 		// Check if error is retryable
 		if cfg.RetryableFunc != nil && !cfg.RetryableFunc(lastErr) {
 			return result, lastErr
@@ -352,6 +352,8 @@ func TestIdentityProviderMappers_CRUD(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "INHERIT", got.Config["syncMode"])
 
+	require.NoError(t, c.DeleteIdentityProviderMapper(ctx, realm, alias, id))
+	// Deleting again is a no-op so finalizers can retry.
 	require.NoError(t, c.DeleteIdentityProviderMapper(ctx, realm, alias, id))
 
 	mappers, err = c.GetIdentityProviderMappers(ctx, realm, alias)
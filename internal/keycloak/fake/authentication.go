@@ -0,0 +1,576 @@
+package fake
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+func (s *Server) authenticationRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/authentication"
+	mux.HandleFunc("GET "+base+"/flows", s.inRealm(s.listFlows))
+	mux.HandleFunc("POST "+base+"/flows", s.inRealm(s.createFlow))
+	mux.HandleFunc("GET "+base+"/flows/{id}", s.inRealm(s.getFlow))
+	mux.HandleFunc("PUT "+base+"/flows/{id}", s.inRealm(s.updateFlow))
+	mux.HandleFunc("DELETE "+base+"/flows/{id}", s.inRealm(s.deleteFlow))
+
+	mux.HandleFunc("GET "+base+"/flows/{alias}/executions", s.inRealm(s.listExecutions))
+	mux.HandleFunc("PUT "+base+"/flows/{alias}/executions", s.inRealm(s.updateExecution))
+	mux.HandleFunc("POST "+base+"/flows/{alias}/executions/execution", s.inRealm(s.addExecution))
+	mux.HandleFunc("POST "+base+"/flows/{alias}/executions/flow", s.inRealm(s.addSubFlow))
+	mux.HandleFunc("DELETE "+base+"/executions/{id}", s.inRealm(s.deleteExecution))
+	mux.HandleFunc("POST "+base+"/executions/{id}/raise-priority", s.inRealm(s.raiseExecutionPriority))
+	mux.HandleFunc("POST "+base+"/executions/{id}/lower-priority", s.inRealm(s.lowerExecutionPriority))
+
+	mux.HandleFunc("POST "+base+"/executions/{id}/config", s.inRealm(s.createExecutionConfig))
+	mux.HandleFunc("GET "+base+"/config/{id}", s.inRealm(s.getExecutionConfig))
+	mux.HandleFunc("PUT "+base+"/config/{id}", s.inRealm(s.updateExecutionConfig))
+	mux.HandleFunc("DELETE "+base+"/config/{id}", s.inRealm(s.deleteExecutionConfig))
+
+	mux.HandleFunc("GET "+base+"/required-actions", s.inRealm(s.listRequiredActions))
+	mux.HandleFunc("GET "+base+"/required-actions/{alias}", s.inRealm(s.getRequiredAction))
+	mux.HandleFunc("PUT "+base+"/required-actions/{alias}", s.inRealm(s.updateRequiredAction))
+	mux.HandleFunc("DELETE "+base+"/required-actions/{alias}", s.inRealm(s.deleteRequiredAction))
+	mux.HandleFunc("POST "+base+"/register-required-action", s.inRealm(s.registerRequiredAction))
+}
+
+// ----------------------------------------------------------------------------
+// Flows
+// ----------------------------------------------------------------------------
+
+func (s *Server) listFlows(w http.ResponseWriter, _ *http.Request, rs *realmState) {
+	out := []object{}
+	for _, flow := range rs.flows.all() {
+		if boolVal(flow, "topLevel") {
+			out = append(out, deepCopy(flow))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createFlow(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	alias := str(rep, "alias")
+	if alias == "" {
+		writeError(w, http.StatusBadRequest, "Flow alias is required")
+		return
+	}
+	if rs.flowByAlias(alias) != nil {
+		writeError(w, http.StatusConflict, "Flow "+alias+" already exists")
+		return
+	}
+	flow := rs.addFlow(alias, str(rep, "providerId"), str(rep, "description"), true)
+	created(w, r, realmPath(rs)+"/authentication/flows/"+str(flow, "id"))
+}
+
+// addFlow stores a new custom flow.
+func (rs *realmState) addFlow(alias, provider, description string, topLevel bool) object {
+	if provider == "" {
+		provider = "basic-flow"
+	}
+	id := newID()
+	flow := object{
+		"id":                       id,
+		"alias":                    alias,
+		"description":              description,
+		"providerId":               provider,
+		"topLevel":                 topLevel,
+		"builtIn":                  false,
+		"authenticationExecutions": []interface{}{},
+	}
+	rs.flows.add(id, flow)
+	return flow
+}
+
+func (s *Server) getFlow(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	flow := rs.flows.get(r.PathValue("id"))
+	if flow == nil {
+		notFound(w, "Could not find flow with id")
+		return
+	}
+	writeJSON(w, http.StatusOK, deepCopy(flow))
+}
+
+func (s *Server) updateFlow(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	flow := rs.flows.get(r.PathValue("id"))
+	if flow == nil {
+		notFound(w, "Could not find flow with id")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if alias := str(rep, "alias"); alias != "" && alias != str(flow, "alias") {
+		if rs.flowByAlias(alias) != nil {
+			writeError(w, http.StatusConflict, "Flow "+alias+" already exists")
+			return
+		}
+		for binding, bound := range rs.rep {
+			if strings.HasSuffix(binding, "Flow") && bound == str(flow, "alias") {
+				rs.rep[binding] = alias
+			}
+		}
+	}
+	for _, field := range []string{"id", "providerId", "topLevel", "builtIn", "authenticationExecutions"} {
+		delete(rep, field)
+	}
+	merge(flow, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteFlow(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	flow := rs.flows.get(id)
+	if flow == nil {
+		notFound(w, "Could not find flow with id")
+		return
+	}
+	if boolVal(flow, "builtIn") {
+		writeError(w, http.StatusBadRequest, "Can't delete built in flow")
+		return
+	}
+	for binding, bound := range rs.rep {
+		if strings.HasSuffix(binding, "Flow") && bound == str(flow, "alias") {
+			writeError(w, http.StatusBadRequest, "Cannot delete flow, it is used as the realm's "+binding)
+			return
+		}
+	}
+	rs.removeFlow(id)
+	noContent(w)
+}
+
+// removeFlow deletes a flow together with its executions, sub-flows and
+// authenticator configs.
+func (rs *realmState) removeFlow(id string) {
+	for _, exec := range rs.executions[id] {
+		rs.removeExecutionData(exec)
+	}
+	delete(rs.executions, id)
+	rs.flows.remove(id)
+}
+
+func (rs *realmState) removeExecutionData(exec *execution) {
+	if exec.configID != "" {
+		rs.configs.remove(exec.configID)
+	}
+	if exec.flowID != "" {
+		rs.removeFlow(exec.flowID)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Executions
+// ----------------------------------------------------------------------------
+
+// requirementChoices mirrors what Keycloak offers for each execution kind.
+func requirementChoices(exec *execution) []string {
+	switch {
+	case exec.flowID != "":
+		return []string{"REQUIRED", "ALTERNATIVE", "DISABLED", "CONDITIONAL"}
+	case strings.HasPrefix(exec.provider, "conditional-"):
+		return []string{"REQUIRED", "DISABLED"}
+	default:
+		return []string{"REQUIRED", "ALTERNATIVE", "DISABLED"}
+	}
+}
+
+// findExecution locates an execution by ID in any flow of the realm.
+func (rs *realmState) findExecution(id string) (string, *execution) {
+	for flowID, execs := range rs.executions {
+		for _, exec := range execs {
+			if exec.id == id {
+				return flowID, exec
+			}
+		}
+	}
+	return "", nil
+}
+
+// sortExecutions orders a flow's executions by priority. Ties keep their
+// insertion order.
+func (rs *realmState) sortExecutions(flowID string) {
+	execs := rs.executions[flowID]
+	sort.SliceStable(execs, func(i, j int) bool { return execs[i].priority < execs[j].priority })
+}
+
+// flattenExecutions renders the flat, depth-first execution list returned by
+// GET /flows/{alias}/executions.
+func (s *Server) flattenExecutions(rs *realmState, flowID string, level int, out []object) []object {
+	for index, exec := range rs.executions[flowID] {
+		info := object{
+			"id":                 exec.id,
+			"requirement":        exec.requirement,
+			"requirementChoices": requirementChoices(exec),
+			"configurable":       exec.flowID == "",
+			"level":              level,
+			"index":              index,
+		}
+		if s.majorVersion() >= 25 {
+			info["priority"] = exec.priority
+		}
+		if exec.flowID != "" {
+			sub := rs.flows.get(exec.flowID)
+			info["authenticationFlow"] = true
+			info["flowId"] = exec.flowID
+			info["displayName"] = str(sub, "alias")
+			info["description"] = str(sub, "description")
+			info["providerId"] = str(sub, "providerId")
+		} else {
+			info["providerId"] = exec.provider
+			info["displayName"] = exec.provider
+		}
+		if exec.configID != "" {
+			info["authenticationConfig"] = exec.configID
+			if config := rs.configs.get(exec.configID); config != nil {
+				info["alias"] = str(config, "alias")
+			}
+		}
+		out = append(out, info)
+		if exec.flowID != "" {
+			out = s.flattenExecutions(rs, exec.flowID, level+1, out)
+		}
+	}
+	return out
+}
+
+func (s *Server) listExecutions(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	flow := rs.flowByAlias(r.PathValue("alias"))
+	if flow == nil {
+		notFound(w, "Flow not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.flattenExecutions(rs, str(flow, "id"), 0, []object{}))
+}
+
+// updateExecution changes an execution's requirement. Keycloak only honours
+// the priority field from version 25 on; older versions silently drop it.
+func (s *Server) updateExecution(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	if rs.flowByAlias(r.PathValue("alias")) == nil {
+		notFound(w, "Parent flow doesn't exist")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	flowID, exec := rs.findExecution(str(rep, "id"))
+	if exec == nil {
+		notFound(w, "Illegal execution")
+		return
+	}
+	if requirement := str(rep, "requirement"); requirement != "" {
+		if !contains(requirementChoices(exec), requirement) {
+			writeError(w, http.StatusBadRequest, "Invalid requirement "+requirement)
+			return
+		}
+		exec.requirement = requirement
+	}
+	if priority, ok := rep["priority"].(float64); ok && s.majorVersion() >= 25 {
+		exec.priority = int(priority)
+		rs.sortExecutions(flowID)
+	}
+	noContent(w)
+}
+
+// appendExecution adds exec to the end of a flow. Before Keycloak 25 new
+// executions get the next priority; from 25 on they all start at 0 and only
+// an explicit priority update orders them.
+func (s *Server) appendExecution(rs *realmState, flowID string, exec *execution) {
+	if s.majorVersion() < 25 {
+		exec.priority = 0
+		if execs := rs.executions[flowID]; len(execs) > 0 {
+			exec.priority = execs[len(execs)-1].priority
+		}
+		exec.priority += 10
+	}
+	rs.executions[flowID] = append(rs.executions[flowID], exec)
+	rs.sortExecutions(flowID)
+}
+
+func (s *Server) addExecution(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	parent := rs.flowByAlias(r.PathValue("alias"))
+	if parent == nil {
+		notFound(w, "Parent flow doesn't exist")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	provider := str(rep, "provider")
+	if provider == "" {
+		writeError(w, http.StatusBadRequest, "No provider found for id: ")
+		return
+	}
+	exec := &execution{id: newID(), provider: provider, requirement: "DISABLED"}
+	s.appendExecution(rs, str(parent, "id"), exec)
+	created(w, r, realmPath(rs)+"/authentication/executions/"+exec.id)
+}
+
+func (s *Server) addSubFlow(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	parent := rs.flowByAlias(r.PathValue("alias"))
+	if parent == nil {
+		notFound(w, "Parent flow doesn't exist")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	alias := str(rep, "alias")
+	if alias == "" {
+		writeError(w, http.StatusBadRequest, "Flow alias is required")
+		return
+	}
+	if rs.flowByAlias(alias) != nil {
+		writeError(w, http.StatusConflict, "New flow alias name already exists")
+		return
+	}
+	sub := rs.addFlow(alias, str(rep, "type"), str(rep, "description"), false)
+	exec := &execution{
+		id:          newID(),
+		provider:    str(rep, "provider"),
+		requirement: "DISABLED",
+		flowID:      str(sub, "id"),
+	}
+	s.appendExecution(rs, str(parent, "id"), exec)
+	created(w, r, realmPath(rs)+"/authentication/flows/"+str(sub, "id"))
+}
+
+func (s *Server) deleteExecution(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	flowID, exec := rs.findExecution(r.PathValue("id"))
+	if exec == nil {
+		notFound(w, "Illegal execution")
+		return
+	}
+	var kept []*execution
+	for _, e := range rs.executions[flowID] {
+		if e != exec {
+			kept = append(kept, e)
+		}
+	}
+	rs.executions[flowID] = kept
+	rs.removeExecutionData(exec)
+	noContent(w)
+}
+
+func (s *Server) raiseExecutionPriority(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	s.moveExecution(w, r, rs, -1)
+}
+
+func (s *Server) lowerExecutionPriority(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	s.moveExecution(w, r, rs, 1)
+}
+
+// moveExecution swaps an execution with its neighbour in direction delta,
+// exchanging their priorities like Keycloak does. Moving past either end is
+// a no-op.
+func (s *Server) moveExecution(w http.ResponseWriter, r *http.Request, rs *realmState, delta int) {
+	flowID, exec := rs.findExecution(r.PathValue("id"))
+	if exec == nil {
+		notFound(w, "Illegal execution")
+		return
+	}
+	execs := rs.executions[flowID]
+	for i, e := range execs {
+		if e != exec {
+			continue
+		}
+		j := i + delta
+		if j < 0 || j >= len(execs) {
+			break
+		}
+		execs[i].priority, execs[j].priority = execs[j].priority, execs[i].priority
+		execs[i], execs[j] = execs[j], execs[i]
+		break
+	}
+	noContent(w)
+}
+
+// ----------------------------------------------------------------------------
+// Authenticator configs
+// ----------------------------------------------------------------------------
+
+func (s *Server) createExecutionConfig(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	_, exec := rs.findExecution(r.PathValue("id"))
+	if exec == nil {
+		notFound(w, "Illegal execution")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if str(rep, "alias") == "" {
+		writeError(w, http.StatusBadRequest, "Authenticator config alias is required")
+		return
+	}
+	config := deepCopy(rep)
+	if _, ok := config["config"]; !ok {
+		config["config"] = object{}
+	}
+	id := newID()
+	config["id"] = id
+	if exec.configID != "" {
+		rs.configs.remove(exec.configID)
+	}
+	rs.configs.add(id, config)
+	exec.configID = id
+	created(w, r, realmPath(rs)+"/authentication/config/"+id)
+}
+
+func (s *Server) getExecutionConfig(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	config := rs.configs.get(r.PathValue("id"))
+	if config == nil {
+		notFound(w, "Could not find authenticator config")
+		return
+	}
+	writeJSON(w, http.StatusOK, deepCopy(config))
+}
+
+func (s *Server) updateExecutionConfig(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	config := rs.configs.get(r.PathValue("id"))
+	if config == nil {
+		notFound(w, "Could not find authenticator config")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	delete(rep, "id")
+	merge(config, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteExecutionConfig(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if !rs.configs.remove(id) {
+		notFound(w, "Could not find authenticator config")
+		return
+	}
+	for _, execs := range rs.executions {
+		for _, exec := range execs {
+			if exec.configID == id {
+				exec.configID = ""
+			}
+		}
+	}
+	noContent(w)
+}
+
+// ----------------------------------------------------------------------------
+// Required actions
+// ----------------------------------------------------------------------------
+
+func (s *Server) listRequiredActions(w http.ResponseWriter, _ *http.Request, rs *realmState) {
+	out := []object{}
+	for _, action := range rs.requiredActions.all() {
+		out = append(out, deepCopy(action))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		pi, _ := out[i]["priority"].(int)
+		pj, _ := out[j]["priority"].(int)
+		return pi < pj
+	})
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getRequiredAction(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	action := rs.requiredActions.get(r.PathValue("alias"))
+	if action == nil {
+		notFound(w, "Failed to find required action")
+		return
+	}
+	writeJSON(w, http.StatusOK, deepCopy(action))
+}
+
+func (s *Server) updateRequiredAction(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	action := rs.requiredActions.get(r.PathValue("alias"))
+	if action == nil {
+		notFound(w, "Failed to find required action")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	for _, field := range []string{"alias", "providerId"} {
+		delete(rep, field)
+	}
+	if priority, ok := rep["priority"].(float64); ok {
+		rep["priority"] = int(priority)
+	}
+	merge(action, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteRequiredAction(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	if !rs.requiredActions.remove(r.PathValue("alias")) {
+		notFound(w, "Failed to find required action")
+		return
+	}
+	noContent(w)
+}
+
+// requiredActionProvider returns the display name of a known required action
+// provider, whether it is currently registered or not.
+func requiredActionProvider(providerID string) (string, bool) {
+	for _, ra := range builtinRequiredActions {
+		if ra.alias == providerID {
+			return ra.name, true
+		}
+	}
+	name, ok := registrableRequiredActions[providerID]
+	return name, ok
+}
+
+func (s *Server) registerRequiredAction(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	providerID := str(rep, "providerId")
+	defaultName, known := requiredActionProvider(providerID)
+	if !known {
+		writeError(w, http.StatusBadRequest, "No provider found for providerId: "+providerID)
+		return
+	}
+	if rs.requiredActions.get(providerID) != nil {
+		writeError(w, http.StatusConflict, "Required action "+providerID+" already registered")
+		return
+	}
+	name := str(rep, "name")
+	if name == "" {
+		name = defaultName
+	}
+	priority := 0
+	for _, action := range rs.requiredActions.all() {
+		if p, _ := action["priority"].(int); p > priority {
+			priority = p
+		}
+	}
+	rs.requiredActions.add(providerID, object{
+		"alias":         providerID,
+		"name":          name,
+		"providerId":    providerID,
+		"enabled":       true,
+		"defaultAction": false,
+		"priority":      priority + 10,
+		"config":        object{},
+	})
+	noContent(w)
+}
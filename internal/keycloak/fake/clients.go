@@ -0,0 +1,346 @@
+package fake
+
+import "net/http"
+
+func (s *Server) clientRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/clients"
+	mux.HandleFunc("GET "+base, s.inRealm(s.listClients))
+	mux.HandleFunc("POST "+base, s.inRealm(s.createClient))
+	mux.HandleFunc("GET "+base+"/{id}", s.inRealm(s.getClient))
+	mux.HandleFunc("PUT "+base+"/{id}", s.inRealm(s.updateClient))
+	mux.HandleFunc("DELETE "+base+"/{id}", s.inRealm(s.deleteClient))
+	mux.HandleFunc("GET "+base+"/{id}/client-secret", s.inRealm(s.getClientSecret))
+	mux.HandleFunc("POST "+base+"/{id}/client-secret", s.inRealm(s.regenerateClientSecret))
+	mux.HandleFunc("GET "+base+"/{id}/service-account-user", s.inRealm(s.getServiceAccountUser))
+
+	mux.HandleFunc("GET "+base+"/{id}/default-client-scopes", s.inRealm(s.listClientScopeLinks(true)))
+	mux.HandleFunc("PUT "+base+"/{id}/default-client-scopes/{scope}", s.inRealm(s.addClientScopeLink(true)))
+	mux.HandleFunc("DELETE "+base+"/{id}/default-client-scopes/{scope}", s.inRealm(s.removeClientScopeLink(true)))
+	mux.HandleFunc("GET "+base+"/{id}/optional-client-scopes", s.inRealm(s.listClientScopeLinks(false)))
+	mux.HandleFunc("PUT "+base+"/{id}/optional-client-scopes/{scope}", s.inRealm(s.addClientScopeLink(false)))
+	mux.HandleFunc("DELETE "+base+"/{id}/optional-client-scopes/{scope}", s.inRealm(s.removeClientScopeLink(false)))
+}
+
+// addClient stores a new client, generating its ID and (for confidential
+// clients) its secret, linking the realm's default client scopes and creating
+// the service-account user when enabled.
+func (rs *realmState) addClient(rep object) object {
+	client := deepCopy(rep)
+	id := newID()
+	client["id"] = id
+	for key, def := range map[string]interface{}{
+		"enabled":                   true,
+		"protocol":                  "openid-connect",
+		"publicClient":              false,
+		"bearerOnly":                false,
+		"serviceAccountsEnabled":    false,
+		"clientAuthenticatorType":   "client-secret",
+		"standardFlowEnabled":       true,
+		"directAccessGrantsEnabled": false,
+	} {
+		if _, ok := client[key]; !ok {
+			client[key] = def
+		}
+	}
+	if !boolVal(client, "publicClient") && str(client, "secret") == "" {
+		client["secret"] = newID()
+	}
+
+	mappers, _ := client["protocolMappers"].([]interface{})
+	defaults, hasDefaults := client["defaultClientScopes"].([]interface{})
+	optionals, hasOptionals := client["optionalClientScopes"].([]interface{})
+	delete(client, "protocolMappers")
+	delete(client, "defaultClientScopes")
+	delete(client, "optionalClientScopes")
+	rs.clients.add(id, client)
+
+	for _, m := range mappers {
+		if mapper, ok := m.(map[string]interface{}); ok {
+			mapperID := newID()
+			mapper = deepCopy(mapper)
+			mapper["id"] = mapperID
+			rs.mappersOf(id).add(mapperID, mapper)
+		}
+	}
+
+	if str(client, "protocol") == "openid-connect" {
+		rs.defaultScopes[id] = rs.scopeIDs(hasDefaults, defaults, builtinDefaultScopes)
+		rs.optionalScopes[id] = rs.scopeIDs(hasOptionals, optionals, builtinOptionalScopes)
+	}
+
+	if boolVal(client, "serviceAccountsEnabled") {
+		rs.ensureServiceAccount(client)
+	}
+	return client
+}
+
+// scopeIDs resolves scope names to IDs, falling back to the built-in list
+// when the representation did not specify any.
+func (rs *realmState) scopeIDs(specified bool, names []interface{}, fallback []string) []string {
+	var wanted []string
+	if specified {
+		for _, n := range names {
+			if name, ok := n.(string); ok {
+				wanted = append(wanted, name)
+			}
+		}
+	} else {
+		wanted = fallback
+	}
+	ids := []string{}
+	for _, name := range wanted {
+		if scope := rs.clientScopeByName(name); scope != nil {
+			ids = append(ids, str(scope, "id"))
+		}
+	}
+	return ids
+}
+
+func (rs *realmState) ensureServiceAccount(client object) {
+	clientUUID := str(client, "id")
+	if rs.serviceAccountOf(clientUUID) != nil {
+		return
+	}
+	rs.addUser(object{
+		"username":                   "service-account-" + str(client, "clientId"),
+		"enabled":                    true,
+		"serviceAccountClientId":     clientUUID,
+		"emailVerified":              false,
+		"requiredActions":            []interface{}{},
+		"notBefore":                  0,
+		"disableableCredentialTypes": []interface{}{},
+	})
+}
+
+func (rs *realmState) serviceAccountOf(clientUUID string) object {
+	return rs.users.find(func(o object) bool {
+		return str(o, "serviceAccountClientId") == clientUUID
+	})
+}
+
+// clientRep renders a client like GET /clients/{id}: protocol mappers and
+// linked scope names are inlined.
+func (rs *realmState) clientRep(client object) object {
+	out := deepCopy(client)
+	id := str(client, "id")
+	if mappers := rs.mappersOf(id).all(); len(mappers) > 0 {
+		out["protocolMappers"] = mappers
+	}
+	if str(client, "protocol") == "openid-connect" {
+		out["defaultClientScopes"] = rs.scopeNames(rs.defaultScopes[id])
+		out["optionalClientScopes"] = rs.scopeNames(rs.optionalScopes[id])
+	}
+	return out
+}
+
+func (rs *realmState) scopeNames(ids []string) []string {
+	names := []string{}
+	for _, id := range ids {
+		if scope := rs.clientScopes.get(id); scope != nil {
+			names = append(names, str(scope, "name"))
+		}
+	}
+	return names
+}
+
+func (s *Server) listClients(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	q := r.URL.Query()
+	clientID := q.Get("clientId")
+	search := q.Get("search") == "true"
+
+	out := []object{}
+	for _, client := range rs.clients.all() {
+		if clientID != "" && !matches(str(client, "clientId"), clientID, !search) {
+			continue
+		}
+		out = append(out, rs.clientRep(client))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) createClient(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	clientID := str(rep, "clientId")
+	if clientID == "" {
+		writeError(w, http.StatusBadRequest, "Client id cannot be empty")
+		return
+	}
+	if rs.clientByClientID(clientID) != nil {
+		writeError(w, http.StatusConflict, "Client "+clientID+" already exists")
+		return
+	}
+	delete(rep, "id")
+	client := rs.addClient(rep)
+	created(w, r, realmPath(rs)+"/clients/"+str(client, "id"))
+}
+
+func (s *Server) getClient(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	client := rs.clients.get(r.PathValue("id"))
+	if client == nil {
+		notFound(w, "Could not find client")
+		return
+	}
+	writeJSON(w, http.StatusOK, rs.clientRep(client))
+}
+
+func (s *Server) updateClient(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	client := rs.clients.get(r.PathValue("id"))
+	if client == nil {
+		notFound(w, "Could not find client")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if newClientID := str(rep, "clientId"); newClientID != "" && newClientID != str(client, "clientId") {
+		if rs.clientByClientID(newClientID) != nil {
+			writeError(w, http.StatusConflict, "Client "+newClientID+" already exists")
+			return
+		}
+	}
+	// The update endpoint ignores nested mappers and scope links; those
+	// have their own sub-resources.
+	for _, field := range []string{"id", "protocolMappers", "defaultClientScopes", "optionalClientScopes"} {
+		delete(rep, field)
+	}
+	merge(client, rep)
+	if boolVal(client, "serviceAccountsEnabled") {
+		rs.ensureServiceAccount(client)
+	}
+	noContent(w)
+}
+
+func (s *Server) deleteClient(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if !rs.clients.remove(id) {
+		notFound(w, "Could not find client")
+		return
+	}
+	for _, role := range rs.rolesOf(id) {
+		rs.removeRole(str(role, "id"))
+	}
+	if sa := rs.serviceAccountOf(id); sa != nil {
+		rs.removeUser(str(sa, "id"))
+	}
+	delete(rs.protocolMappers, id)
+	delete(rs.defaultScopes, id)
+	delete(rs.optionalScopes, id)
+	noContent(w)
+}
+
+func (s *Server) getClientSecret(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	client := rs.clients.get(r.PathValue("id"))
+	if client == nil {
+		notFound(w, "Could not find client")
+		return
+	}
+	writeJSON(w, http.StatusOK, object{"type": "secret", "value": str(client, "secret")})
+}
+
+func (s *Server) regenerateClientSecret(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	client := rs.clients.get(r.PathValue("id"))
+	if client == nil {
+		notFound(w, "Could not find client")
+		return
+	}
+	if boolVal(client, "publicClient") {
+		writeError(w, http.StatusBadRequest, "Public clients do not have a secret")
+		return
+	}
+	client["secret"] = newID()
+	writeJSON(w, http.StatusOK, object{"type": "secret", "value": client["secret"]})
+}
+
+func (s *Server) getServiceAccountUser(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	client := rs.clients.get(id)
+	if client == nil {
+		notFound(w, "Could not find client")
+		return
+	}
+	sa := rs.serviceAccountOf(id)
+	if !boolVal(client, "serviceAccountsEnabled") || sa == nil {
+		writeError(w, http.StatusBadRequest, "Service account not enabled for the client '"+str(client, "clientId")+"'")
+		return
+	}
+	writeJSON(w, http.StatusOK, rs.userRep(sa))
+}
+
+func (rs *realmState) scopeLinks(isDefault bool) map[string][]string {
+	if isDefault {
+		return rs.defaultScopes
+	}
+	return rs.optionalScopes
+}
+
+func (s *Server) listClientScopeLinks(isDefault bool) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		id := r.PathValue("id")
+		if rs.clients.get(id) == nil {
+			notFound(w, "Could not find client")
+			return
+		}
+		out := []object{}
+		for _, scopeID := range rs.scopeLinks(isDefault)[id] {
+			if scope := rs.clientScopes.get(scopeID); scope != nil {
+				out = append(out, object{"id": scope["id"], "name": scope["name"]})
+			}
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func (s *Server) addClientScopeLink(isDefault bool) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		id, scopeID := r.PathValue("id"), r.PathValue("scope")
+		client := rs.clients.get(id)
+		if client == nil {
+			notFound(w, "Could not find client")
+			return
+		}
+		scope := rs.clientScopes.get(scopeID)
+		if scope == nil {
+			notFound(w, "Client scope not found")
+			return
+		}
+		if protocol := str(scope, "protocol"); protocol != "" && protocol != str(client, "protocol") {
+			writeError(w, http.StatusBadRequest, "Protocol mismatch")
+			return
+		}
+		links := rs.scopeLinks(isDefault)
+		if contains(links[id], scopeID) {
+			noContent(w)
+			return
+		}
+		// A scope can be linked as either default or optional, never both.
+		if contains(rs.scopeLinks(!isDefault)[id], scopeID) {
+			writeError(w, http.StatusConflict, "Client scope "+str(scope, "name")+" already linked to client")
+			return
+		}
+		links[id] = append(links[id], scopeID)
+		noContent(w)
+	}
+}
+
+func (s *Server) removeClientScopeLink(isDefault bool) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		id, scopeID := r.PathValue("id"), r.PathValue("scope")
+		if rs.clients.get(id) == nil {
+			notFound(w, "Could not find client")
+			return
+		}
+		if rs.clientScopes.get(scopeID) == nil {
+			notFound(w, "Client scope not found")
+			return
+		}
+		links := rs.scopeLinks(isDefault)
+		links[id] = without(links[id], scopeID)
+		noContent(w)
+	}
+}
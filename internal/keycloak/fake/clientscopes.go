@@ -0,0 +1,219 @@
+package fake
+
+import "net/http"
+
+func (s *Server) clientScopeRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/client-scopes"
+	mux.HandleFunc("GET "+base, s.inRealm(s.listClientScopes))
+	mux.HandleFunc("POST "+base, s.inRealm(s.createClientScope))
+	mux.HandleFunc("GET "+base+"/{scope}", s.inRealm(s.getClientScope))
+	mux.HandleFunc("PUT "+base+"/{scope}", s.inRealm(s.updateClientScope))
+	mux.HandleFunc("DELETE "+base+"/{scope}", s.inRealm(s.deleteClientScope))
+
+	for _, owner := range []string{"clients/{owner}", "client-scopes/{owner}"} {
+		models := "/admin/realms/{realm}/" + owner + "/protocol-mappers/models"
+		mux.HandleFunc("GET "+models, s.inRealm(s.listProtocolMappers))
+		mux.HandleFunc("POST "+models, s.inRealm(s.createProtocolMapper))
+		mux.HandleFunc("GET "+models+"/{mapper}", s.inRealm(s.getProtocolMapper))
+		mux.HandleFunc("PUT "+models+"/{mapper}", s.inRealm(s.updateProtocolMapper))
+		mux.HandleFunc("DELETE "+models+"/{mapper}", s.inRealm(s.deleteProtocolMapper))
+	}
+}
+
+// clientScopeRep renders a client scope with its protocol mappers inlined.
+func (rs *realmState) clientScopeRep(scope object) object {
+	out := deepCopy(scope)
+	if mappers := rs.mappersOf(str(scope, "id")).all(); len(mappers) > 0 {
+		out["protocolMappers"] = mappers
+	}
+	return out
+}
+
+func (s *Server) listClientScopes(w http.ResponseWriter, _ *http.Request, rs *realmState) {
+	out := []object{}
+	for _, scope := range rs.clientScopes.all() {
+		out = append(out, rs.clientScopeRep(scope))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createClientScope(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	name := str(rep, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Unexpected name \"\" for ClientScope")
+		return
+	}
+	if rs.clientScopeByName(name) != nil {
+		writeError(w, http.StatusConflict, "Client Scope "+name+" already exists")
+		return
+	}
+	scope := deepCopy(rep)
+	if str(scope, "protocol") == "" {
+		scope["protocol"] = "openid-connect"
+	}
+	mappers, _ := scope["protocolMappers"].([]interface{})
+	delete(scope, "protocolMappers")
+	id := newID()
+	scope["id"] = id
+	rs.clientScopes.add(id, scope)
+	for _, m := range mappers {
+		if mapper, ok := m.(map[string]interface{}); ok {
+			mapperID := newID()
+			mapper = deepCopy(mapper)
+			mapper["id"] = mapperID
+			rs.mappersOf(id).add(mapperID, mapper)
+		}
+	}
+	created(w, r, realmPath(rs)+"/client-scopes/"+id)
+}
+
+func (s *Server) getClientScope(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	scope := rs.clientScopes.get(r.PathValue("scope"))
+	if scope == nil {
+		notFound(w, "Could not find client scope")
+		return
+	}
+	writeJSON(w, http.StatusOK, rs.clientScopeRep(scope))
+}
+
+func (s *Server) updateClientScope(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	scope := rs.clientScopes.get(r.PathValue("scope"))
+	if scope == nil {
+		notFound(w, "Could not find client scope")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if name := str(rep, "name"); name != "" && name != str(scope, "name") && rs.clientScopeByName(name) != nil {
+		writeError(w, http.StatusConflict, "Client Scope "+name+" already exists")
+		return
+	}
+	delete(rep, "id")
+	delete(rep, "protocolMappers")
+	merge(scope, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteClientScope(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("scope")
+	if !rs.clientScopes.remove(id) {
+		notFound(w, "Could not find client scope")
+		return
+	}
+	delete(rs.protocolMappers, id)
+	for clientUUID, ids := range rs.defaultScopes {
+		rs.defaultScopes[clientUUID] = without(ids, id)
+	}
+	for clientUUID, ids := range rs.optionalScopes {
+		rs.optionalScopes[clientUUID] = without(ids, id)
+	}
+	noContent(w)
+}
+
+// mapperOwner resolves {owner} to the mapper collection of a client or
+// client scope, writing a 404 when the owner does not exist.
+func mapperOwner(w http.ResponseWriter, r *http.Request, rs *realmState) *collection {
+	id := r.PathValue("owner")
+	if rs.clients.get(id) == nil && rs.clientScopes.get(id) == nil {
+		notFound(w, "Could not find client")
+		return nil
+	}
+	return rs.mappersOf(id)
+}
+
+func (s *Server) listProtocolMappers(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	if mappers := mapperOwner(w, r, rs); mappers != nil {
+		writeJSON(w, http.StatusOK, mappers.all())
+	}
+}
+
+func (s *Server) createProtocolMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := mapperOwner(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	name := str(rep, "name")
+	if name == "" || str(rep, "protocolMapper") == "" {
+		writeError(w, http.StatusBadRequest, "Protocol mapper name and type are required")
+		return
+	}
+	if mappers.find(func(o object) bool { return str(o, "name") == name }) != nil {
+		writeError(w, http.StatusConflict, "Protocol mapper exists with same name")
+		return
+	}
+	mapper := deepCopy(rep)
+	if str(mapper, "protocol") == "" {
+		mapper["protocol"] = "openid-connect"
+	}
+	if _, ok := mapper["config"]; !ok {
+		mapper["config"] = object{}
+	}
+	id := newID()
+	mapper["id"] = id
+	mappers.add(id, mapper)
+	created(w, r, r.URL.EscapedPath()+"/"+id)
+}
+
+func (s *Server) getProtocolMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := mapperOwner(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	mapper := mappers.get(r.PathValue("mapper"))
+	if mapper == nil {
+		notFound(w, "Model not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, mapper)
+}
+
+func (s *Server) updateProtocolMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := mapperOwner(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	mapper := mappers.get(r.PathValue("mapper"))
+	if mapper == nil {
+		notFound(w, "Model not found")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if name := str(rep, "name"); name != "" && name != str(mapper, "name") {
+		if mappers.find(func(o object) bool { return str(o, "name") == name }) != nil {
+			writeError(w, http.StatusConflict, "Protocol mapper exists with same name")
+			return
+		}
+	}
+	delete(rep, "id")
+	merge(mapper, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteProtocolMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := mapperOwner(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	if !mappers.remove(r.PathValue("mapper")) {
+		notFound(w, "Model not found")
+		return
+	}
+	noContent(w)
+}
@@ -0,0 +1,132 @@
+package fake
+
+import "net/http"
+
+// secretComponentKeys are component config keys Keycloak masks on read.
+var secretComponentKeys = []string{"bindCredential", "clientSecret", "secret", "privateKey"}
+
+func (s *Server) componentRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/components"
+	mux.HandleFunc("GET "+base, s.inRealm(s.listComponents))
+	mux.HandleFunc("POST "+base, s.inRealm(s.createComponent))
+	mux.HandleFunc("GET "+base+"/{id}", s.inRealm(s.getComponent))
+	mux.HandleFunc("PUT "+base+"/{id}", s.inRealm(s.updateComponent))
+	mux.HandleFunc("DELETE "+base+"/{id}", s.inRealm(s.deleteComponent))
+}
+
+// maskComponent hides secret config values. Component config values are
+// lists of strings, so the mask replaces each list.
+func maskComponent(component object) object {
+	out := deepCopy(component)
+	config, ok := out["config"].(map[string]interface{})
+	if !ok {
+		return out
+	}
+	for _, key := range secretComponentKeys {
+		if _, has := config[key]; has {
+			config[key] = []interface{}{secretMask}
+		}
+	}
+	return out
+}
+
+// isMasked reports whether a component config value is the secret mask.
+func isMasked(v interface{}) bool {
+	list, ok := v.([]interface{})
+	return ok && len(list) == 1 && list[0] == secretMask
+}
+
+func (s *Server) listComponents(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	q := r.URL.Query()
+	out := []object{}
+	for _, component := range rs.components.all() {
+		if parent := q.Get("parent"); parent != "" && str(component, "parentId") != parent {
+			continue
+		}
+		if providerType := q.Get("type"); providerType != "" && str(component, "providerType") != providerType {
+			continue
+		}
+		if name := q.Get("name"); name != "" && str(component, "name") != name {
+			continue
+		}
+		out = append(out, maskComponent(component))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createComponent(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if str(rep, "providerId") == "" || str(rep, "providerType") == "" {
+		writeError(w, http.StatusBadRequest, "Component providerId and providerType are required")
+		return
+	}
+	component := deepCopy(rep)
+	if str(component, "parentId") == "" {
+		component["parentId"] = rs.rep["id"]
+	}
+	if _, ok := component["config"]; !ok {
+		component["config"] = object{}
+	}
+	id := newID()
+	component["id"] = id
+	rs.components.add(id, component)
+	created(w, r, realmPath(rs)+"/components/"+id)
+}
+
+func (s *Server) getComponent(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	component := rs.components.get(r.PathValue("id"))
+	if component == nil {
+		notFound(w, "Could not find component")
+		return
+	}
+	writeJSON(w, http.StatusOK, maskComponent(component))
+}
+
+func (s *Server) updateComponent(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	component := rs.components.get(r.PathValue("id"))
+	if component == nil {
+		notFound(w, "Could not find component")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	// Sending the mask back keeps the stored secret.
+	if config, ok := rep["config"].(map[string]interface{}); ok {
+		stored, _ := component["config"].(map[string]interface{})
+		for _, key := range secretComponentKeys {
+			if isMasked(config[key]) {
+				config[key] = stored[key]
+			}
+		}
+	}
+	delete(rep, "id")
+	merge(component, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteComponent(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if rs.components.get(id) == nil {
+		notFound(w, "Could not find component")
+		return
+	}
+	rs.removeComponent(id)
+	noContent(w)
+}
+
+// removeComponent deletes a component and its sub-components.
+func (rs *realmState) removeComponent(id string) {
+	for _, child := range rs.components.all() {
+		if str(child, "parentId") == id {
+			rs.removeComponent(str(child, "id"))
+		}
+	}
+	rs.components.remove(id)
+}
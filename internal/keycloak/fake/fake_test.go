@@ -0,0 +1,391 @@
+package fake_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
+)
+
+func newClient(t *testing.T, opts ...fake.Option) (*fake.Server, *keycloak.Client) {
+	t.Helper()
+	srv := fake.NewServer(opts...)
+	t.Cleanup(srv.Close)
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+	return srv, kc
+}
+
+func createRealm(t *testing.T, kc *keycloak.Client, def string) {
+	t.Helper()
+	require.NoError(t, kc.CreateRealmFromDefinition(context.Background(), json.RawMessage(def)))
+}
+
+func TestServerInfoAndAuth(t *testing.T) {
+	srv, kc := newClient(t, fake.WithVersion("24.0.5"))
+	ctx := context.Background()
+
+	require.NoError(t, kc.Ping(ctx))
+	info, err := kc.GetServerInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "24.0.5", info.SystemInfo.Version)
+
+	bad := keycloak.NewClient(keycloak.Config{BaseURL: srv.URL, Username: "admin", Password: "wrong"}, testr.New(t))
+	assert.Error(t, bad.Ping(ctx))
+
+	srv.SetUnavailable(true)
+	assert.Error(t, keycloak.NewClient(keycloak.Config{BaseURL: srv.URL, Username: "admin", Password: "admin"}, testr.New(t)).Ping(ctx))
+}
+
+func TestServerInfoCapabilities(t *testing.T) {
+	_, kc := newClient(t, fake.WithVersion("26.0.5"), fake.WithDisabledFeatures("ORGANIZATION"))
+
+	info, err := kc.GetServerInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ORGANIZATION"}, info.ProfileInfo.DisabledFeatures)
+
+	caps := keycloak.DetectCapabilities(info)
+	assert.True(t, caps.Supports(keycloak.CapabilityExecutionPriority))
+	assert.False(t, caps.Supports(keycloak.CapabilityOrganizations))
+
+	createRealm(t, kc, `{"realm":"test","enabled":true,"organizationsEnabled":true}`)
+	_, err = kc.GetOrganizations(context.Background(), "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestRealmLifecycle(t *testing.T) {
+	srv, kc := newClient(t)
+	ctx := context.Background()
+
+	createRealm(t, kc, `{"realm":"test","enabled":true,"displayName":"Test"}`)
+	assert.True(t, srv.HasRealm("test"))
+
+	err := kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "409")
+
+	realm, err := kc.GetRealm(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, "Test", *realm.DisplayName)
+
+	require.NoError(t, kc.UpdateRealm(ctx, "test", json.RawMessage(`{"displayName":"Renamed"}`)))
+	realm, err = kc.GetRealm(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", *realm.DisplayName)
+
+	require.NoError(t, kc.DeleteRealm(ctx, "test"))
+	assert.False(t, srv.HasRealm("test"))
+	_, err = kc.GetRealm(ctx, "test")
+	assert.Error(t, err)
+}
+
+func TestClientsAndSecrets(t *testing.T) {
+	srv, kc := newClient(t)
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	id, err := kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"app","serviceAccountsEnabled":true}`))
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	_, err = kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"app"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "409")
+
+	client, err := kc.GetClientByClientID(ctx, "test", "app")
+	require.NoError(t, err)
+	assert.Equal(t, id, *client.ID)
+
+	secret, err := kc.GetClientSecret(ctx, "test", id)
+	require.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	regenerated, err := kc.RegenerateClientSecret(ctx, "test", id)
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, regenerated)
+
+	sa, err := kc.GetClientServiceAccount(ctx, "test", id)
+	require.NoError(t, err)
+	assert.Equal(t, "service-account-app", *sa.Username)
+
+	defaults, err := kc.GetClientDefaultScopes(ctx, "test", id)
+	require.NoError(t, err)
+	assert.NotEmpty(t, defaults)
+
+	cc := keycloak.NewClient(keycloak.Config{BaseURL: srv.URL, Realm: "test", ClientID: "app", ClientSecret: regenerated}, testr.New(t))
+	assert.NoError(t, cc.Ping(ctx))
+}
+
+func TestUsersAndGroups(t *testing.T) {
+	srv, kc := newClient(t)
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	userID, err := kc.CreateUser(ctx, "test", json.RawMessage(`{"username":"Alice","email":"alice@example.com","enabled":true}`))
+	require.NoError(t, err)
+	_, err = kc.CreateUser(ctx, "test", json.RawMessage(`{"username":"alice"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "409")
+
+	user, err := kc.GetUserByUsername(ctx, "test", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, userID, *user.ID)
+
+	require.NoError(t, kc.SetPassword(ctx, "test", userID, "s3cret", false))
+	password, ok := srv.Password("test", "alice")
+	require.True(t, ok)
+	assert.Equal(t, "s3cret", password)
+
+	parentID, err := kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"parent"}`))
+	require.NoError(t, err)
+	childID, err := kc.CreateChildGroup(ctx, "test", parentID, json.RawMessage(`{"name":"child"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateChildGroup(ctx, "test", parentID, json.RawMessage(`{"name":"child"}`))
+	require.Error(t, err)
+
+	groups, err := kc.GetGroups(ctx, "test", nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, 1, *groups[0].SubGroupCount)
+	assert.Empty(t, groups[0].SubGroups, "Keycloak 23+ does not inline subGroups")
+
+	children, err := kc.GetGroupChildren(ctx, "test", parentID, nil)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, "/parent/child", *children[0].Path)
+
+	require.NoError(t, kc.AddUserToGroup(ctx, "test", userID, childID))
+	memberOf, err := kc.GetUserGroups(ctx, "test", userID)
+	require.NoError(t, err)
+	require.Len(t, memberOf, 1)
+
+	require.NoError(t, kc.DeleteGroup(ctx, "test", parentID))
+	memberOf, err = kc.GetUserGroups(ctx, "test", userID)
+	require.NoError(t, err)
+	assert.Empty(t, memberOf)
+
+	require.NoError(t, kc.DisableUser(ctx, "test", userID))
+	user, err = kc.GetUser(ctx, "test", userID)
+	require.NoError(t, err)
+	assert.False(t, *user.Enabled)
+	assert.Equal(t, "alice@example.com", *user.Email)
+
+	require.NoError(t, kc.DeleteUser(ctx, "test", userID))
+	require.NoError(t, kc.DeleteUser(ctx, "test", userID), "deleting a missing user succeeds")
+	_, err = kc.GetUser(ctx, "test", userID)
+	assert.True(t, keycloak.IsNotFound(err))
+}
+
+func TestGroupsInlinedBeforeKeycloak23(t *testing.T) {
+	_, kc := newClient(t, fake.WithVersion("22.0.5"))
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	parentID, err := kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"parent"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateChildGroup(ctx, "test", parentID, json.RawMessage(`{"name":"child"}`))
+	require.NoError(t, err)
+
+	groups, err := kc.GetGroups(ctx, "test", nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0].SubGroups, 1)
+}
+
+func TestRolesAndMappings(t *testing.T) {
+	_, kc := newClient(t)
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	_, err := kc.CreateRealmRole(ctx, "test", json.RawMessage(`{"name":"admin"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateRealmRole(ctx, "test", json.RawMessage(`{"name":"viewer"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateRealmRole(ctx, "test", json.RawMessage(`{"name":"admin"}`))
+	require.Error(t, err)
+
+	viewer, err := kc.GetRealmRole(ctx, "test", "viewer")
+	require.NoError(t, err)
+	require.NoError(t, kc.AddRealmRoleComposites(ctx, "test", "admin", []keycloak.RoleRepresentation{*viewer}))
+	admin, err := kc.GetRealmRole(ctx, "test", "admin")
+	require.NoError(t, err)
+	assert.True(t, *admin.Composite)
+
+	userID, err := kc.CreateUser(ctx, "test", json.RawMessage(`{"username":"bob"}`))
+	require.NoError(t, err)
+
+	mapped, err := kc.GetUserRealmRoleMappings(ctx, "test", userID)
+	require.NoError(t, err)
+	require.Len(t, mapped, 1)
+	assert.Equal(t, "default-roles-test", *mapped[0].Name)
+
+	require.NoError(t, kc.AddRealmRolesToUser(ctx, "test", userID, []keycloak.RoleRepresentation{*admin}))
+	composite, err := kc.GetUserRoleMappingsComposite(ctx, "test", userID)
+	require.NoError(t, err)
+	assert.Len(t, composite.RealmMappings, 2)
+
+	require.NoError(t, kc.DeleteRealmRolesFromUser(ctx, "test", userID, []keycloak.RoleRepresentation{*admin}))
+	mapped, err = kc.GetUserRealmRoleMappings(ctx, "test", userID)
+	require.NoError(t, err)
+	assert.Len(t, mapped, 1)
+}
+
+func TestIdentityProviderSecretIsMasked(t *testing.T) {
+	_, kc := newClient(t)
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	_, err := kc.CreateIdentityProvider(ctx, "test", json.RawMessage(`{"alias":"github","providerId":"github","config":{"clientId":"x","clientSecret":"real"}}`))
+	require.NoError(t, err)
+	_, err = kc.CreateIdentityProvider(ctx, "test", json.RawMessage(`{"alias":"github","providerId":"github"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "409")
+
+	raw, err := kc.GetIdentityProviderRaw(ctx, "test", "github")
+	require.NoError(t, err)
+	var idp struct {
+		Config map[string]string `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &idp))
+	assert.Equal(t, "**********", idp.Config["clientSecret"])
+}
+
+func TestOrganizationsRequireKeycloak25(t *testing.T) {
+	srv, kc := newClient(t, fake.WithVersion("24.0.5"))
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true,"organizationsEnabled":true}`)
+
+	_, err := kc.GetOrganizations(ctx, "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+
+	srv.SetVersion("25.0.6")
+	_, err = kc.CreateOrganization(ctx, "test", keycloak.OrganizationRepresentation{
+		Name:    "acme",
+		Domains: []keycloak.OrganizationDomain{{Name: "acme.com"}},
+	})
+	require.NoError(t, err)
+	_, err = kc.CreateOrganization(ctx, "test", keycloak.OrganizationRepresentation{
+		Name:    "other",
+		Domains: []keycloak.OrganizationDomain{{Name: "acme.com"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "409")
+
+	orgs, err := kc.GetOrganizations(ctx, "test")
+	require.NoError(t, err)
+	require.Len(t, orgs, 1)
+	assert.Equal(t, "acme", orgs[0].Alias)
+}
+
+func TestFlowExecutions(t *testing.T) {
+	_, kc := newClient(t)
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	alias := "custom"
+	_, err := kc.CreateAuthenticationFlow(ctx, "test", keycloak.AuthenticationFlowRepresentation{Alias: &alias})
+	require.NoError(t, err)
+
+	_, err = kc.AddFlowExecution(ctx, "test", alias, "auth-cookie")
+	require.NoError(t, err)
+	_, err = kc.AddFlowSubFlow(ctx, "test", alias, map[string]interface{}{"alias": "forms", "type": "basic-flow", "provider": "registration-page-form"})
+	require.NoError(t, err)
+	_, err = kc.AddFlowExecution(ctx, "test", "forms", "auth-username-password-form")
+	require.NoError(t, err)
+
+	execs, err := kc.GetFlowExecutions(ctx, "test", alias)
+	require.NoError(t, err)
+	require.Len(t, execs, 3)
+	assert.Equal(t, "auth-cookie", *execs[0].ProviderID)
+	assert.Equal(t, "DISABLED", *execs[0].Requirement)
+	assert.Equal(t, 0, *execs[0].Priority, "Keycloak 25+ adds executions with priority 0")
+	assert.Equal(t, "forms", *execs[1].DisplayName)
+	assert.True(t, *execs[1].AuthenticationFlow)
+	assert.Equal(t, 1, *execs[2].Level)
+
+	// Reorder via priority: move the sub-flow first.
+	first, second := 10, 20
+	required := "REQUIRED"
+	require.NoError(t, kc.UpdateFlowExecution(ctx, "test", alias, keycloak.AuthenticationExecutionInfo{ID: execs[1].ID, Priority: &first, Requirement: &required}))
+	require.NoError(t, kc.UpdateFlowExecution(ctx, "test", alias, keycloak.AuthenticationExecutionInfo{ID: execs[0].ID, Priority: &second}))
+	execs, err = kc.GetFlowExecutions(ctx, "test", alias)
+	require.NoError(t, err)
+	assert.Equal(t, "forms", *execs[0].DisplayName)
+	assert.Equal(t, "REQUIRED", *execs[0].Requirement)
+
+	configAlias := "cfg"
+	configID, err := kc.CreateExecutionConfig(ctx, "test", *execs[2].ID, keycloak.AuthenticatorConfigRepresentation{Alias: &configAlias, Config: map[string]string{"k": "v"}})
+	require.NoError(t, err)
+	config, err := kc.GetExecutionConfig(ctx, "test", configID)
+	require.NoError(t, err)
+	assert.Equal(t, "v", config.Config["k"])
+
+	flow, err := kc.GetAuthenticationFlowByAlias(ctx, "test", alias)
+	require.NoError(t, err)
+	require.NoError(t, kc.DeleteAuthenticationFlow(ctx, "test", *flow.ID))
+	_, err = kc.GetExecutionConfig(ctx, "test", configID)
+	assert.Error(t, err)
+
+	browser, err := kc.GetAuthenticationFlowByAlias(ctx, "test", "browser")
+	require.NoError(t, err)
+	assert.Error(t, kc.DeleteAuthenticationFlow(ctx, "test", *browser.ID), "built-in flows cannot be deleted")
+}
+
+func TestExecutionPriorityIgnoredBeforeKeycloak25(t *testing.T) {
+	_, kc := newClient(t, fake.WithVersion("24.0.5"))
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	alias := "custom"
+	_, err := kc.CreateAuthenticationFlow(ctx, "test", keycloak.AuthenticationFlowRepresentation{Alias: &alias})
+	require.NoError(t, err)
+	for _, provider := range []string{"auth-cookie", "auth-otp-form"} {
+		_, err = kc.AddFlowExecution(ctx, "test", alias, provider)
+		require.NoError(t, err)
+	}
+
+	execs, err := kc.GetFlowExecutions(ctx, "test", alias)
+	require.NoError(t, err)
+	priority := 1
+	require.NoError(t, kc.UpdateFlowExecution(ctx, "test", alias, keycloak.AuthenticationExecutionInfo{ID: execs[1].ID, Priority: &priority}))
+
+	execs, err = kc.GetFlowExecutions(ctx, "test", alias)
+	require.NoError(t, err)
+	assert.Equal(t, "auth-cookie", *execs[0].ProviderID)
+	assert.Nil(t, execs[0].Priority)
+
+	require.NoError(t, kc.RaiseExecutionPriority(ctx, "test", *execs[1].ID))
+	execs, err = kc.GetFlowExecutions(ctx, "test", alias)
+	require.NoError(t, err)
+	assert.Equal(t, "auth-otp-form", *execs[0].ProviderID)
+}
+
+func TestRequiredActions(t *testing.T) {
+	_, kc := newClient(t)
+	ctx := context.Background()
+	createRealm(t, kc, `{"realm":"test","enabled":true}`)
+
+	require.NoError(t, kc.RegisterRequiredAction(ctx, "test", json.RawMessage(`{"providerId":"CONFIGURE_RECOVERY_AUTHN_CODES","name":"Recovery Codes"}`)))
+	action, err := kc.GetRequiredAction(ctx, "test", "CONFIGURE_RECOVERY_AUTHN_CODES")
+	require.NoError(t, err)
+	assert.Equal(t, "Recovery Codes", *action.Name)
+
+	err = kc.RegisterRequiredAction(ctx, "test", json.RawMessage(`{"providerId":"CONFIGURE_RECOVERY_AUTHN_CODES"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "409")
+
+	require.NoError(t, kc.UpdateRequiredAction(ctx, "test", "CONFIGURE_TOTP", json.RawMessage(`{"enabled":false}`)))
+	action, err = kc.GetRequiredAction(ctx, "test", "CONFIGURE_TOTP")
+	require.NoError(t, err)
+	assert.False(t, *action.Enabled)
+}
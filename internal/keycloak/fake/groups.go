@@ -0,0 +1,271 @@
+package fake
+
+import (
+	"net/http"
+	"strings"
+)
+
+func (s *Server) groupRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/groups"
+	mux.HandleFunc("GET "+base, s.inRealm(s.listGroups))
+	mux.HandleFunc("POST "+base, s.inRealm(s.createTopLevelGroup))
+	mux.HandleFunc("GET "+base+"/{id}", s.inRealm(s.getGroup))
+	mux.HandleFunc("PUT "+base+"/{id}", s.inRealm(s.updateGroup))
+	mux.HandleFunc("DELETE "+base+"/{id}", s.inRealm(s.deleteGroup))
+	mux.HandleFunc("GET "+base+"/{id}/children", s.inRealm(s.listGroupChildren))
+	mux.HandleFunc("POST "+base+"/{id}/children", s.inRealm(s.createChildGroup))
+}
+
+// childrenOf returns the direct children of parentID ("" for top level).
+func (rs *realmState) childrenOf(parentID string) []object {
+	var out []object
+	for _, group := range rs.groups.all() {
+		if rs.groupParent[str(group, "id")] == parentID {
+			out = append(out, group)
+		}
+	}
+	return out
+}
+
+func (rs *realmState) groupPath(id string) string {
+	var names []string
+	for id != "" {
+		group := rs.groups.get(id)
+		if group == nil {
+			break
+		}
+		names = append([]string{str(group, "name")}, names...)
+		id = rs.groupParent[id]
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// siblingNamed returns the group named name under parentID, if any.
+func (rs *realmState) siblingNamed(parentID, name string) object {
+	for _, group := range rs.childrenOf(parentID) {
+		if str(group, "name") == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// groupRep renders a group. Keycloak 23+ never inlines subGroups in listings
+// and only reports subGroupCount; older versions inline the whole subtree.
+// When keep is non-nil (search results) only the matching branches are
+// inlined, regardless of version.
+func (rs *realmState) groupRep(group object, inline bool, keep func(id string) bool) object {
+	out := deepCopy(group)
+	id := str(group, "id")
+	out["path"] = rs.groupPath(id)
+	if parentID := rs.groupParent[id]; parentID != "" {
+		out["parentId"] = parentID
+	}
+	if _, ok := out["attributes"]; !ok {
+		out["attributes"] = object{}
+	}
+
+	children := rs.childrenOf(id)
+	out["subGroupCount"] = len(children)
+	subGroups := []object{}
+	for _, child := range children {
+		switch {
+		case keep != nil:
+			if keep(str(child, "id")) {
+				subGroups = append(subGroups, rs.groupRep(child, inline, keep))
+			}
+		case inline:
+			subGroups = append(subGroups, rs.groupRep(child, inline, nil))
+		}
+	}
+	out["subGroups"] = subGroups
+	return out
+}
+
+// searchKeep returns a predicate that is true for groups matching term or
+// having a matching descendant.
+func (rs *realmState) searchKeep(term string, exact bool) func(id string) bool {
+	var visit func(id string) bool
+	memo := make(map[string]bool)
+	visit = func(id string) bool {
+		if v, ok := memo[id]; ok {
+			return v
+		}
+		hit := matches(str(rs.groups.get(id), "name"), term, exact)
+		for _, child := range rs.childrenOf(id) {
+			if visit(str(child, "id")) {
+				hit = true
+			}
+		}
+		memo[id] = hit
+		return hit
+	}
+	return visit
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	q := r.URL.Query()
+	inline := s.majorVersion() < 23
+	var keep func(string) bool
+	if term := q.Get("search"); term != "" {
+		keep = rs.searchKeep(term, q.Get("exact") == "true")
+	}
+
+	out := []object{}
+	for _, group := range rs.childrenOf("") {
+		if keep != nil && !keep(str(group, "id")) {
+			continue
+		}
+		out = append(out, rs.groupRep(group, inline, keep))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) listGroupChildren(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	// Listing children arrived in Keycloak 23; older versions only accept POST.
+	if s.majorVersion() < 23 {
+		notFound(w, "HTTP 404 Not Found")
+		return
+	}
+	id := r.PathValue("id")
+	if rs.groups.get(id) == nil {
+		notFound(w, "Could not find group by id")
+		return
+	}
+	q := r.URL.Query()
+	term := q.Get("search")
+	exact := q.Get("exact") == "true"
+
+	out := []object{}
+	for _, child := range rs.childrenOf(id) {
+		if term != "" && !matches(str(child, "name"), term, exact) {
+			continue
+		}
+		out = append(out, rs.groupRep(child, false, nil))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) createTopLevelGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	s.addGroup(w, r, rs, "")
+}
+
+func (s *Server) createChildGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	parentID := r.PathValue("id")
+	if rs.groups.get(parentID) == nil {
+		notFound(w, "Could not find group by id")
+		return
+	}
+	s.addGroup(w, r, rs, parentID)
+}
+
+// addGroup creates a group under parentID, or moves an existing group there
+// when the body carries the ID of a known group.
+func (s *Server) addGroup(w http.ResponseWriter, r *http.Request, rs *realmState, parentID string) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	name := str(rep, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Group name is missing")
+		return
+	}
+	if existing := rs.siblingNamed(parentID, name); existing != nil && str(existing, "id") != str(rep, "id") {
+		if parentID == "" {
+			writeError(w, http.StatusConflict, "Top level group named '"+name+"' already exists.")
+		} else {
+			writeError(w, http.StatusConflict, "Sibling group named '"+name+"' already exists.")
+		}
+		return
+	}
+
+	if id := str(rep, "id"); id != "" {
+		if moved := rs.groups.get(id); moved != nil {
+			for p := parentID; p != ""; p = rs.groupParent[p] {
+				if p == id {
+					writeError(w, http.StatusBadRequest, "Cannot move group into itself")
+					return
+				}
+			}
+			if parentID == "" {
+				delete(rs.groupParent, id)
+			} else {
+				rs.groupParent[id] = parentID
+			}
+			noContent(w)
+			return
+		}
+	}
+
+	group := deepCopy(rep)
+	for _, field := range []string{"path", "parentId", "subGroups", "subGroupCount", "realmRoles", "clientRoles"} {
+		delete(group, field)
+	}
+	id := newID()
+	group["id"] = id
+	rs.groups.add(id, group)
+	if parentID != "" {
+		rs.groupParent[id] = parentID
+	}
+	created(w, r, realmPath(rs)+"/groups/"+id)
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	group := rs.groups.get(r.PathValue("id"))
+	if group == nil {
+		notFound(w, "Could not find group by id")
+		return
+	}
+	writeJSON(w, http.StatusOK, rs.groupRep(group, s.majorVersion() < 23, nil))
+}
+
+func (s *Server) updateGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	group := rs.groups.get(id)
+	if group == nil {
+		notFound(w, "Could not find group by id")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if name := str(rep, "name"); name != "" && name != str(group, "name") {
+		if rs.siblingNamed(rs.groupParent[id], name) != nil {
+			writeError(w, http.StatusConflict, "Sibling group named '"+name+"' already exists.")
+			return
+		}
+	}
+	for _, field := range []string{"id", "path", "parentId", "subGroups", "subGroupCount", "realmRoles", "clientRoles"} {
+		delete(rep, field)
+	}
+	merge(group, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if rs.groups.get(id) == nil {
+		notFound(w, "Could not find group by id")
+		return
+	}
+	rs.removeGroup(id)
+	noContent(w)
+}
+
+// removeGroup deletes a group together with its subtree, memberships and
+// role mappings.
+func (rs *realmState) removeGroup(id string) {
+	for _, child := range rs.childrenOf(id) {
+		rs.removeGroup(str(child, "id"))
+	}
+	rs.groups.remove(id)
+	delete(rs.groupParent, id)
+	delete(rs.roleMappings, id)
+	for userID, groups := range rs.userGroups {
+		rs.userGroups[userID] = without(groups, id)
+	}
+}
@@ -0,0 +1,222 @@
+package fake
+
+import (
+	"net/http"
+	"net/url"
+)
+
+func (s *Server) identityProviderRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/identity-provider/instances"
+	mux.HandleFunc("GET "+base, s.inRealm(s.listIdentityProviders))
+	mux.HandleFunc("POST "+base, s.inRealm(s.createIdentityProvider))
+	mux.HandleFunc("GET "+base+"/{alias}", s.inRealm(s.getIdentityProvider))
+	mux.HandleFunc("PUT "+base+"/{alias}", s.inRealm(s.updateIdentityProvider))
+	mux.HandleFunc("DELETE "+base+"/{alias}", s.inRealm(s.deleteIdentityProvider))
+
+	mux.HandleFunc("GET "+base+"/{alias}/mappers", s.inRealm(s.listIdentityProviderMappers))
+	mux.HandleFunc("POST "+base+"/{alias}/mappers", s.inRealm(s.createIdentityProviderMapper))
+	mux.HandleFunc("GET "+base+"/{alias}/mappers/{mapper}", s.inRealm(s.getIdentityProviderMapper))
+	mux.HandleFunc("PUT "+base+"/{alias}/mappers/{mapper}", s.inRealm(s.updateIdentityProviderMapper))
+	mux.HandleFunc("DELETE "+base+"/{alias}/mappers/{mapper}", s.inRealm(s.deleteIdentityProviderMapper))
+}
+
+// maskIdentityProvider hides config.clientSecret, as Keycloak does on read.
+func maskIdentityProvider(idp object) object {
+	out := deepCopy(idp)
+	if config, ok := out["config"].(map[string]interface{}); ok {
+		if _, has := config["clientSecret"]; has {
+			config["clientSecret"] = secretMask
+		}
+	}
+	return out
+}
+
+func (s *Server) listIdentityProviders(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	term := r.URL.Query().Get("search")
+	out := []object{}
+	for _, idp := range rs.idps.all() {
+		if term != "" && !matches(str(idp, "alias"), term, false) {
+			continue
+		}
+		out = append(out, maskIdentityProvider(idp))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) createIdentityProvider(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	alias := str(rep, "alias")
+	if alias == "" || str(rep, "providerId") == "" {
+		writeError(w, http.StatusBadRequest, "Identity provider alias and providerId are required")
+		return
+	}
+	if rs.idps.get(alias) != nil {
+		writeError(w, http.StatusConflict, "Identity Provider "+alias+" already exists")
+		return
+	}
+	idp := deepCopy(rep)
+	idp["internalId"] = newID()
+	for key, def := range map[string]interface{}{
+		"enabled":                   true,
+		"trustEmail":                false,
+		"storeToken":                false,
+		"addReadTokenRoleOnCreate":  false,
+		"authenticateByDefault":     false,
+		"linkOnly":                  false,
+		"hideOnLogin":               false,
+		"firstBrokerLoginFlowAlias": "first broker login",
+		"config":                    object{},
+	} {
+		if _, ok := idp[key]; !ok {
+			idp[key] = def
+		}
+	}
+	rs.idps.add(alias, idp)
+	created(w, r, realmPath(rs)+"/identity-provider/instances/"+url.PathEscape(alias))
+}
+
+func (s *Server) getIdentityProvider(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	idp := rs.idps.get(r.PathValue("alias"))
+	if idp == nil {
+		notFound(w, "Could not find identity provider")
+		return
+	}
+	writeJSON(w, http.StatusOK, maskIdentityProvider(idp))
+}
+
+func (s *Server) updateIdentityProvider(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	idp := rs.idps.get(r.PathValue("alias"))
+	if idp == nil {
+		notFound(w, "Could not find identity provider")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	// Sending the mask back keeps the stored secret.
+	if config, ok := rep["config"].(map[string]interface{}); ok && config["clientSecret"] == secretMask {
+		if stored, ok := idp["config"].(map[string]interface{}); ok {
+			config["clientSecret"] = stored["clientSecret"]
+		}
+	}
+	for _, field := range []string{"alias", "internalId"} {
+		delete(rep, field)
+	}
+	merge(idp, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteIdentityProvider(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	alias := r.PathValue("alias")
+	if !rs.idps.remove(alias) {
+		notFound(w, "Could not find identity provider")
+		return
+	}
+	delete(rs.idpMappers, alias)
+	noContent(w)
+}
+
+// idpMapperCollection resolves {alias} to its mapper collection, writing a 404
+// when the identity provider does not exist.
+func idpMapperCollection(w http.ResponseWriter, r *http.Request, rs *realmState) *collection {
+	alias := r.PathValue("alias")
+	if rs.idps.get(alias) == nil {
+		notFound(w, "Could not find identity provider")
+		return nil
+	}
+	c, ok := rs.idpMappers[alias]
+	if !ok {
+		c = newCollection()
+		rs.idpMappers[alias] = c
+	}
+	return c
+}
+
+func (s *Server) listIdentityProviderMappers(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	if mappers := idpMapperCollection(w, r, rs); mappers != nil {
+		writeJSON(w, http.StatusOK, mappers.all())
+	}
+}
+
+func (s *Server) createIdentityProviderMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := idpMapperCollection(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	name := str(rep, "name")
+	if name == "" || str(rep, "identityProviderMapper") == "" {
+		writeError(w, http.StatusBadRequest, "Identity provider mapper name and type are required")
+		return
+	}
+	if mappers.find(func(o object) bool { return str(o, "name") == name }) != nil {
+		writeError(w, http.StatusConflict, "identity provider mapper name must be unique per identity provider")
+		return
+	}
+	mapper := deepCopy(rep)
+	mapper["identityProviderAlias"] = r.PathValue("alias")
+	if _, ok := mapper["config"]; !ok {
+		mapper["config"] = object{}
+	}
+	id := newID()
+	mapper["id"] = id
+	mappers.add(id, mapper)
+	created(w, r, r.URL.EscapedPath()+"/"+id)
+}
+
+func (s *Server) getIdentityProviderMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := idpMapperCollection(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	mapper := mappers.get(r.PathValue("mapper"))
+	if mapper == nil {
+		notFound(w, "Model not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, mapper)
+}
+
+func (s *Server) updateIdentityProviderMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := idpMapperCollection(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	mapper := mappers.get(r.PathValue("mapper"))
+	if mapper == nil {
+		notFound(w, "Model not found")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	for _, field := range []string{"id", "identityProviderAlias"} {
+		delete(rep, field)
+	}
+	merge(mapper, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteIdentityProviderMapper(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	mappers := idpMapperCollection(w, r, rs)
+	if mappers == nil {
+		return
+	}
+	if !mappers.remove(r.PathValue("mapper")) {
+		notFound(w, "Model not found")
+		return
+	}
+	noContent(w)
+}
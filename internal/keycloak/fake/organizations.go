@@ -0,0 +1,166 @@
+package fake
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+func (s *Server) organizationRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/organizations"
+	mux.HandleFunc("GET "+base, s.inOrganizations(s.listOrganizations))
+	mux.HandleFunc("POST "+base, s.inOrganizations(s.createOrganization))
+	mux.HandleFunc("GET "+base+"/{id}", s.inOrganizations(s.getOrganization))
+	mux.HandleFunc("PUT "+base+"/{id}", s.inOrganizations(s.updateOrganization))
+	mux.HandleFunc("DELETE "+base+"/{id}", s.inOrganizations(s.deleteOrganization))
+}
+
+// inOrganizations gates the organization endpoints: they do not exist before
+// Keycloak 25 or with the organization feature disabled, and reject requests
+// while the realm has organizations disabled.
+func (s *Server) inOrganizations(h realmHandler) http.HandlerFunc {
+	return s.inRealm(func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		if s.majorVersion() < 25 || slices.Contains(s.disabled, "ORGANIZATION") {
+			notFound(w, "RESTEASY003210: Could not find resource for full path: "+r.URL.EscapedPath())
+			return
+		}
+		if !boolVal(rs.rep, "organizationsEnabled") {
+			writeError(w, http.StatusNotFound, "Organizations not enabled for this realm.")
+			return
+		}
+		h(w, r, rs)
+	})
+}
+
+// domainOwner returns the organization that already claims domain, ignoring
+// exceptID.
+func (rs *realmState) domainOwner(domain, exceptID string) object {
+	return rs.organizations.find(func(o object) bool {
+		if str(o, "id") == exceptID {
+			return false
+		}
+		domains, _ := o["domains"].([]interface{})
+		for _, d := range domains {
+			if entry, ok := d.(map[string]interface{}); ok && strings.EqualFold(str(entry, "name"), domain) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// checkOrganization validates name and domain uniqueness, writing a 409 on
+// conflict.
+func (rs *realmState) checkOrganization(w http.ResponseWriter, rep object, exceptID string) bool {
+	if name := str(rep, "name"); name != "" {
+		if rs.organizations.find(func(o object) bool {
+			return str(o, "id") != exceptID && str(o, "name") == name
+		}) != nil {
+			writeError(w, http.StatusConflict, "A organization with the same name already exists.")
+			return false
+		}
+	}
+	domains, _ := rep["domains"].([]interface{})
+	for _, d := range domains {
+		entry, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if rs.domainOwner(str(entry, "name"), exceptID) != nil {
+			writeError(w, http.StatusConflict, "Domain "+str(entry, "name")+" is already linked to another organization")
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) listOrganizations(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	q := r.URL.Query()
+	term := q.Get("search")
+	exact := q.Get("exact") == "true"
+	out := []object{}
+	for _, org := range rs.organizations.all() {
+		if term != "" && !matches(str(org, "name"), term, exact) {
+			continue
+		}
+		out = append(out, deepCopy(org))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) createOrganization(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if str(rep, "name") == "" {
+		writeError(w, http.StatusBadRequest, "Name can not be null")
+		return
+	}
+	if !rs.checkOrganization(w, rep, "") {
+		return
+	}
+	org := deepCopy(rep)
+	if str(org, "alias") == "" {
+		org["alias"] = org["name"]
+	}
+	if _, ok := org["enabled"]; !ok {
+		org["enabled"] = true
+	}
+	if _, ok := org["domains"]; !ok {
+		org["domains"] = []interface{}{}
+	}
+	id := newID()
+	org["id"] = id
+	rs.organizations.add(id, org)
+	created(w, r, realmPath(rs)+"/organizations/"+id)
+}
+
+func (s *Server) getOrganization(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	org := rs.organizations.get(r.PathValue("id"))
+	if org == nil {
+		notFound(w, "Organization not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, deepCopy(org))
+}
+
+func (s *Server) updateOrganization(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	org := rs.organizations.get(id)
+	if org == nil {
+		notFound(w, "Organization not found")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if !rs.checkOrganization(w, rep, id) {
+		return
+	}
+	// The alias is immutable once set.
+	if alias := str(rep, "alias"); alias != "" && alias != str(org, "alias") {
+		writeError(w, http.StatusBadRequest, "Cannot change the alias")
+		return
+	}
+	delete(rep, "id")
+	merge(org, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteOrganization(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if !rs.organizations.remove(id) {
+		notFound(w, "Organization not found")
+		return
+	}
+	for _, idp := range rs.idps.all() {
+		if str(idp, "organizationId") == id {
+			delete(idp, "organizationId")
+		}
+	}
+	noContent(w)
+}
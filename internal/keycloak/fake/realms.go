@@ -0,0 +1,125 @@
+package fake
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// realmImportFields are nested collections accepted by a realm import. The
+// fake does not import them; they are dropped so realm GETs look like the
+// real endpoint, which never inlines them.
+var realmImportFields = []string{
+	"clients", "users", "groups", "roles", "clientScopes", "identityProviders",
+	"identityProviderMappers", "components", "authenticationFlows",
+	"authenticatorConfig", "requiredActions", "organizations",
+}
+
+func (s *Server) realmRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/realms", s.admin(s.listRealms))
+	mux.HandleFunc("POST /admin/realms", s.admin(s.createRealm))
+	mux.HandleFunc("GET /admin/realms/{realm}", s.inRealm(s.getRealm))
+	mux.HandleFunc("PUT /admin/realms/{realm}", s.inRealm(s.updateRealm))
+	mux.HandleFunc("DELETE /admin/realms/{realm}", s.inRealm(s.deleteRealm))
+}
+
+func (s *Server) listRealms(w http.ResponseWriter, _ *http.Request) {
+	out := make([]object, 0, len(s.realmOrder))
+	for _, name := range s.realmOrder {
+		out = append(out, maskRealm(s.realms[name].rep))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createRealm(w http.ResponseWriter, r *http.Request) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	name := str(rep, "realm")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Realm name cannot be empty")
+		return
+	}
+	if _, exists := s.realms[name]; exists {
+		writeError(w, http.StatusConflict, "Conflict detected. See logs for details")
+		return
+	}
+	for _, field := range realmImportFields {
+		delete(rep, field)
+	}
+	s.addRealm(rep)
+	created(w, r, "/admin/realms/"+url.PathEscape(name))
+}
+
+func (s *Server) getRealm(w http.ResponseWriter, _ *http.Request, rs *realmState) {
+	writeJSON(w, http.StatusOK, maskRealm(rs.rep))
+}
+
+func (s *Server) updateRealm(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	for _, field := range realmImportFields {
+		delete(rep, field)
+	}
+	delete(rep, "id")
+
+	// A masked SMTP password in the body means "keep the stored one".
+	if smtp, ok := rep["smtpServer"].(map[string]interface{}); ok && smtp["password"] == secretMask {
+		if stored, ok := rs.rep["smtpServer"].(map[string]interface{}); ok {
+			smtp["password"] = stored["password"]
+		} else {
+			delete(smtp, "password")
+		}
+	}
+
+	oldName := str(rs.rep, "realm")
+	newName := str(rep, "realm")
+	if newName != "" && newName != oldName {
+		if _, exists := s.realms[newName]; exists {
+			writeError(w, http.StatusConflict, "Realm with same name exists")
+			return
+		}
+		delete(s.realms, oldName)
+		s.realms[newName] = rs
+		for i, name := range s.realmOrder {
+			if name == oldName {
+				s.realmOrder[i] = newName
+			}
+		}
+	}
+
+	merge(rs.rep, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteRealm(w http.ResponseWriter, _ *http.Request, rs *realmState) {
+	name := str(rs.rep, "realm")
+	if name == "master" {
+		writeError(w, http.StatusBadRequest, "Cannot delete master realm")
+		return
+	}
+	delete(s.realms, name)
+	for i, existing := range s.realmOrder {
+		if existing == name {
+			s.realmOrder = append(s.realmOrder[:i], s.realmOrder[i+1:]...)
+			break
+		}
+	}
+	noContent(w)
+}
+
+// maskRealm returns a copy of the realm representation with the SMTP
+// password replaced by the mask, as Keycloak does.
+func maskRealm(rep object) object {
+	out := deepCopy(rep)
+	if smtp, ok := out["smtpServer"].(map[string]interface{}); ok {
+		if _, has := smtp["password"]; has {
+			smtp["password"] = secretMask
+		}
+	}
+	return out
+}
@@ -0,0 +1,378 @@
+package fake
+
+import (
+	"net/http"
+	"net/url"
+)
+
+func (s *Server) roleRoutes(mux *http.ServeMux) {
+	realmRoles := "/admin/realms/{realm}/roles"
+	mux.HandleFunc("GET "+realmRoles, s.inRealm(s.listRoles))
+	mux.HandleFunc("POST "+realmRoles, s.inRealm(s.createRole))
+	mux.HandleFunc("GET "+realmRoles+"/{role}", s.inRealm(s.getRole))
+	mux.HandleFunc("PUT "+realmRoles+"/{role}", s.inRealm(s.updateRole))
+	mux.HandleFunc("DELETE "+realmRoles+"/{role}", s.inRealm(s.deleteRole))
+	mux.HandleFunc("GET "+realmRoles+"/{role}/composites", s.inRealm(s.listComposites))
+	mux.HandleFunc("POST "+realmRoles+"/{role}/composites", s.inRealm(s.addComposites))
+	mux.HandleFunc("DELETE "+realmRoles+"/{role}/composites", s.inRealm(s.removeComposites))
+
+	clientRoles := "/admin/realms/{realm}/clients/{id}/roles"
+	mux.HandleFunc("GET "+clientRoles, s.inRealm(s.listRoles))
+	mux.HandleFunc("POST "+clientRoles, s.inRealm(s.createRole))
+	mux.HandleFunc("GET "+clientRoles+"/{role}", s.inRealm(s.getRole))
+	mux.HandleFunc("PUT "+clientRoles+"/{role}", s.inRealm(s.updateRole))
+	mux.HandleFunc("DELETE "+clientRoles+"/{role}", s.inRealm(s.deleteRole))
+	mux.HandleFunc("GET "+clientRoles+"/{role}/composites", s.inRealm(s.listComposites))
+	mux.HandleFunc("POST "+clientRoles+"/{role}/composites", s.inRealm(s.addComposites))
+	mux.HandleFunc("DELETE "+clientRoles+"/{role}/composites", s.inRealm(s.removeComposites))
+
+	for _, kind := range []string{"users", "groups"} {
+		base := "/admin/realms/{realm}/" + kind + "/{subject}/role-mappings"
+		mux.HandleFunc("GET "+base, s.inRealm(s.getAllRoleMappings(kind)))
+		mux.HandleFunc("GET "+base+"/realm", s.inRealm(s.listRoleMappings(kind)))
+		mux.HandleFunc("POST "+base+"/realm", s.inRealm(s.addRoleMappings(kind)))
+		mux.HandleFunc("DELETE "+base+"/realm", s.inRealm(s.removeRoleMappings(kind)))
+		mux.HandleFunc("GET "+base+"/clients/{id}", s.inRealm(s.listRoleMappings(kind)))
+		mux.HandleFunc("POST "+base+"/clients/{id}", s.inRealm(s.addRoleMappings(kind)))
+		mux.HandleFunc("DELETE "+base+"/clients/{id}", s.inRealm(s.removeRoleMappings(kind)))
+	}
+}
+
+// roleContainer resolves the optional {id} client path value. It returns
+// false (after writing a 404) when the client does not exist.
+func roleContainer(w http.ResponseWriter, r *http.Request, rs *realmState) (string, bool) {
+	clientUUID := r.PathValue("id")
+	if clientUUID == "" {
+		return "", true
+	}
+	if rs.clients.get(clientUUID) == nil {
+		notFound(w, "Could not find client")
+		return "", false
+	}
+	return clientUUID, true
+}
+
+func (rs *realmState) roleIn(clientUUID, name string) object {
+	if clientUUID == "" {
+		return rs.realmRole(name)
+	}
+	return rs.clientRole(clientUUID, name)
+}
+
+// roleRep renders a role; composite reflects whether it has members.
+func (rs *realmState) roleRep(role object) object {
+	out := deepCopy(role)
+	out["composite"] = len(rs.composites[str(role, "id")]) > 0
+	if _, ok := out["attributes"]; !ok {
+		out["attributes"] = object{}
+	}
+	return out
+}
+
+func (s *Server) listRoles(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	clientUUID, ok := roleContainer(w, r, rs)
+	if !ok {
+		return
+	}
+	term := r.URL.Query().Get("search")
+	out := []object{}
+	for _, role := range rs.rolesOf(clientUUID) {
+		if term != "" && !matches(str(role, "name"), term, false) {
+			continue
+		}
+		out = append(out, rs.roleRep(role))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) createRole(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	clientUUID, ok := roleContainer(w, r, rs)
+	if !ok {
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	name := str(rep, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Role name is missing")
+		return
+	}
+	if rs.roleIn(clientUUID, name) != nil {
+		writeError(w, http.StatusConflict, "Role with name "+name+" already exists")
+		return
+	}
+	for _, field := range []string{"id", "composites", "clientRole", "containerId"} {
+		delete(rep, field)
+	}
+	rep["composite"] = false
+	rs.addRole(rep, clientUUID)
+
+	location := realmPath(rs) + "/roles/" + url.PathEscape(name)
+	if clientUUID != "" {
+		location = realmPath(rs) + "/clients/" + clientUUID + "/roles/" + url.PathEscape(name)
+	}
+	created(w, r, location)
+}
+
+// lookupRole resolves {role} in the realm or client container, writing a 404
+// when it does not exist.
+func lookupRole(w http.ResponseWriter, r *http.Request, rs *realmState) object {
+	clientUUID, ok := roleContainer(w, r, rs)
+	if !ok {
+		return nil
+	}
+	role := rs.roleIn(clientUUID, r.PathValue("role"))
+	if role == nil {
+		notFound(w, "Could not find role")
+	}
+	return role
+}
+
+func (s *Server) getRole(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	if role := lookupRole(w, r, rs); role != nil {
+		writeJSON(w, http.StatusOK, rs.roleRep(role))
+	}
+}
+
+func (s *Server) updateRole(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	role := lookupRole(w, r, rs)
+	if role == nil {
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if name := str(rep, "name"); name != "" && name != str(role, "name") {
+		clientUUID := ""
+		if boolVal(role, "clientRole") {
+			clientUUID = str(role, "containerId")
+		}
+		if rs.roleIn(clientUUID, name) != nil {
+			writeError(w, http.StatusConflict, "Role with name "+name+" already exists")
+			return
+		}
+	}
+	for _, field := range []string{"id", "composite", "composites", "clientRole", "containerId"} {
+		delete(rep, field)
+	}
+	merge(role, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteRole(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	role := lookupRole(w, r, rs)
+	if role == nil {
+		return
+	}
+	if defaultRole, ok := rs.rep["defaultRole"].(map[string]interface{}); ok && defaultRole["id"] == role["id"] {
+		writeError(w, http.StatusBadRequest, "You cannot delete a default realm role.")
+		return
+	}
+	rs.removeRole(str(role, "id"))
+	noContent(w)
+}
+
+func (s *Server) listComposites(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	role := lookupRole(w, r, rs)
+	if role == nil {
+		return
+	}
+	out := []object{}
+	for _, id := range rs.composites[str(role, "id")] {
+		if member := rs.roles.get(id); member != nil {
+			out = append(out, rs.roleRep(member))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// addComposites resolves members by ID, like Keycloak does.
+func (s *Server) addComposites(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	role := lookupRole(w, r, rs)
+	if role == nil {
+		return
+	}
+	reps, err := decodeList(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	id := str(role, "id")
+	for _, rep := range reps {
+		memberID := str(rep, "id")
+		if rs.roles.get(memberID) == nil {
+			notFound(w, "Could not find composite role")
+			return
+		}
+		if !contains(rs.composites[id], memberID) {
+			rs.composites[id] = append(rs.composites[id], memberID)
+		}
+	}
+	noContent(w)
+}
+
+func (s *Server) removeComposites(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	role := lookupRole(w, r, rs)
+	if role == nil {
+		return
+	}
+	reps, err := decodeList(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	id := str(role, "id")
+	for _, rep := range reps {
+		memberID := str(rep, "id")
+		if rs.roles.get(memberID) == nil {
+			notFound(w, "Could not find composite role")
+			return
+		}
+		rs.composites[id] = without(rs.composites[id], memberID)
+	}
+	noContent(w)
+}
+
+// subjectExists checks the {subject} path value against users or groups,
+// writing a 404 when it is missing.
+func subjectExists(w http.ResponseWriter, r *http.Request, rs *realmState, kind string) (string, bool) {
+	id := r.PathValue("subject")
+	if kind == "users" {
+		if rs.users.get(id) == nil {
+			notFound(w, "User not found")
+			return "", false
+		}
+	} else if rs.groups.get(id) == nil {
+		notFound(w, "Could not find group by id")
+		return "", false
+	}
+	return id, true
+}
+
+// mappedRoles returns the subject's direct role mappings within a container.
+func (rs *realmState) mappedRoles(subjectID, clientUUID string) []object {
+	out := []object{}
+	for _, roleID := range rs.roleMappings[subjectID] {
+		role := rs.roles.get(roleID)
+		if role == nil {
+			continue
+		}
+		if clientUUID == "" && !boolVal(role, "clientRole") || clientUUID != "" && str(role, "containerId") == clientUUID {
+			out = append(out, rs.roleRep(role))
+		}
+	}
+	return out
+}
+
+func (s *Server) getAllRoleMappings(kind string) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		subjectID, ok := subjectExists(w, r, rs, kind)
+		if !ok {
+			return
+		}
+		out := object{}
+		if realmMappings := rs.mappedRoles(subjectID, ""); len(realmMappings) > 0 {
+			out["realmMappings"] = realmMappings
+		}
+		clientMappings := object{}
+		for _, client := range rs.clients.all() {
+			clientUUID := str(client, "id")
+			if mappings := rs.mappedRoles(subjectID, clientUUID); len(mappings) > 0 {
+				// Keyed by clientId, not UUID.
+				clientMappings[str(client, "clientId")] = object{
+					"id":       clientUUID,
+					"client":   client["clientId"],
+					"mappings": mappings,
+				}
+			}
+		}
+		if len(clientMappings) > 0 {
+			out["clientMappings"] = clientMappings
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func (s *Server) listRoleMappings(kind string) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		subjectID, ok := subjectExists(w, r, rs, kind)
+		if !ok {
+			return
+		}
+		clientUUID, ok := roleContainer(w, r, rs)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, rs.mappedRoles(subjectID, clientUUID))
+	}
+}
+
+// resolveMappedRoles looks each role up by name within the container and
+// requires the ID in the body to match, which is how Keycloak validates role
+// mapping requests.
+func resolveMappedRoles(w http.ResponseWriter, r *http.Request, rs *realmState, clientUUID string) ([]string, bool) {
+	reps, err := decodeList(r)
+	if err != nil {
+		badBody(w, err)
+		return nil, false
+	}
+	ids := make([]string, 0, len(reps))
+	for _, rep := range reps {
+		role := rs.roleIn(clientUUID, str(rep, "name"))
+		if role == nil || str(role, "id") != str(rep, "id") {
+			notFound(w, "Role not found")
+			return nil, false
+		}
+		ids = append(ids, str(role, "id"))
+	}
+	return ids, true
+}
+
+func (s *Server) addRoleMappings(kind string) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		subjectID, ok := subjectExists(w, r, rs, kind)
+		if !ok {
+			return
+		}
+		clientUUID, ok := roleContainer(w, r, rs)
+		if !ok {
+			return
+		}
+		ids, ok := resolveMappedRoles(w, r, rs, clientUUID)
+		if !ok {
+			return
+		}
+		for _, id := range ids {
+			if !contains(rs.roleMappings[subjectID], id) {
+				rs.roleMappings[subjectID] = append(rs.roleMappings[subjectID], id)
+			}
+		}
+		noContent(w)
+	}
+}
+
+func (s *Server) removeRoleMappings(kind string) realmHandler {
+	return func(w http.ResponseWriter, r *http.Request, rs *realmState) {
+		subjectID, ok := subjectExists(w, r, rs, kind)
+		if !ok {
+			return
+		}
+		clientUUID, ok := roleContainer(w, r, rs)
+		if !ok {
+			return
+		}
+		ids, ok := resolveMappedRoles(w, r, rs, clientUUID)
+		if !ok {
+			return
+		}
+		for _, id := range ids {
+			rs.roleMappings[subjectID] = without(rs.roleMappings[subjectID], id)
+		}
+		noContent(w)
+	}
+}
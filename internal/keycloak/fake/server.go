@@ -0,0 +1,404 @@
+// Package fake provides an in-memory, httptest-backed implementation of the
+// Keycloak Admin REST API endpoints used by keycloak.Client.
+//
+// It is meant for reconciler and export tests that need a stateful Keycloak
+// without starting a real server. The fake mirrors the server behaviour the
+// operator depends on, including the awkward parts: IDs are only returned via
+// the Location header, duplicates are rejected with 409 Conflict, secrets are
+// masked on read, Keycloak 23+ group listings omit nested subGroups, and
+// execution priorities are only honoured on Keycloak 25+.
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// AdminUsername and AdminPassword are the master realm credentials the
+	// token endpoint accepts for the password grant.
+	AdminUsername = "admin"
+	AdminPassword = "admin"
+
+	// DefaultVersion is the Keycloak version reported by /admin/serverinfo
+	// unless overridden with WithVersion.
+	DefaultVersion = "26.0.0"
+
+	// secretMask is what Keycloak returns in place of stored secrets.
+	secretMask = "**********"
+
+	// tokenSignature ends every issued access token. Tokens are JWT-shaped so
+	// callers can read their claims, but the signature is not real.
+	tokenSignature = "fake-signature"
+)
+
+// Server is an in-memory Keycloak Admin API. Create it with NewServer and
+// point keycloak.Config.BaseURL at Server.URL.
+type Server struct {
+	// URL is the base URL of the running server (no trailing slash).
+	URL string
+
+	srv *httptest.Server
+
+	mu          sync.Mutex
+	version     string
+	disabled    []string
+	realms      map[string]*realmState
+	realmOrder  []string
+	unavailable bool
+	calls       map[string]int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithVersion sets the Keycloak version reported by /admin/serverinfo and
+// used to gate version-specific behaviour (organizations, execution priority).
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// WithDisabledFeatures lists server features reported as disabled in
+// /admin/serverinfo profileInfo, e.g. "ORGANIZATION" or "TOKEN_EXCHANGE".
+func WithDisabledFeatures(features ...string) Option {
+	return func(s *Server) {
+		s.disabled = append(s.disabled, features...)
+	}
+}
+
+// NewServer starts a new fake Keycloak with an empty master realm. Callers
+// must Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		version: DefaultVersion,
+		realms:  make(map[string]*realmState),
+		calls:   make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.addRealm(object{"realm": "master", "enabled": true})
+
+	s.srv = httptest.NewServer(s.routes())
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SetVersion changes the reported Keycloak version at runtime.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// SetUnavailable makes every request, including token requests, fail with
+// 503 Service Unavailable until it is called again with false.
+func (s *Server) SetUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = unavailable
+}
+
+// Calls returns how many requests were served for the given method and
+// escaped URL path, e.g. Calls("POST", "/admin/realms/test/users").
+func (s *Server) Calls(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method+" "+path]
+}
+
+// HasRealm reports whether a realm with the given name exists.
+func (s *Server) HasRealm(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.realms[name]
+	return ok
+}
+
+// majorVersion returns the major component of the configured version, or 0
+// when it cannot be parsed.
+func (s *Server) majorVersion() int {
+	major, _, _ := strings.Cut(s.version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// realmHandler is the signature of handlers scoped to an existing realm.
+type realmHandler func(w http.ResponseWriter, r *http.Request, rs *realmState)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /realms/{realm}/protocol/openid-connect/token", s.handleToken)
+	mux.HandleFunc("GET /admin/serverinfo", s.admin(s.handleServerInfo))
+
+	s.realmRoutes(mux)
+	s.clientRoutes(mux)
+	s.userRoutes(mux)
+	s.groupRoutes(mux)
+	s.roleRoutes(mux)
+	s.clientScopeRoutes(mux)
+	s.identityProviderRoutes(mux)
+	s.componentRoutes(mux)
+	s.organizationRoutes(mux)
+	s.authenticationRoutes(mux)
+
+	return s.record(mux)
+}
+
+// record counts requests and applies the unavailable switch before handing
+// off to the mux.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.calls[r.Method+" "+r.URL.EscapedPath()]++
+		unavailable := s.unavailable
+		s.mu.Unlock()
+
+		if unavailable {
+			writeError(w, http.StatusServiceUnavailable, "Service Unavailable")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// admin wraps a handler with bearer-token authentication and the server lock.
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasSuffix(token, "."+tokenSignature) {
+			writeJSON(w, http.StatusUnauthorized, object{"error": "HTTP 401 Unauthorized"})
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h(w, r)
+	}
+}
+
+// inRealm is like admin but additionally resolves the {realm} path value.
+func (s *Server) inRealm(h realmHandler) http.HandlerFunc {
+	return s.admin(func(w http.ResponseWriter, r *http.Request) {
+		rs, ok := s.realms[r.PathValue("realm")]
+		if !ok {
+			notFound(w, "Realm not found.")
+			return
+		}
+		h(w, r, rs)
+	})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, object{"error": "invalid_request"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.realms[r.PathValue("realm")]
+	if !ok {
+		notFound(w, "Realm does not exist")
+		return
+	}
+
+	var principal string
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		principal = AdminUsername
+		if r.PostForm.Get("username") != AdminUsername || r.PostForm.Get("password") != AdminPassword {
+			writeJSON(w, http.StatusUnauthorized, object{"error": "invalid_grant", "error_description": "Invalid user credentials"})
+			return
+		}
+	case "client_credentials":
+		client := rs.clientByClientID(r.PostForm.Get("client_id"))
+		if client == nil || str(client, "secret") != r.PostForm.Get("client_secret") {
+			writeJSON(w, http.StatusUnauthorized, object{"error": "unauthorized_client", "error_description": "Invalid client or Invalid client credentials"})
+			return
+		}
+		if !boolVal(client, "serviceAccountsEnabled") {
+			writeJSON(w, http.StatusUnauthorized, object{"error": "unauthorized_client", "error_description": "Client not enabled to retrieve service account"})
+			return
+		}
+		principal = "service-account-" + str(client, "clientId")
+	default:
+		writeJSON(w, http.StatusBadRequest, object{"error": "unsupported_grant_type"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, object{
+		"access_token": issueToken(principal, r.PostForm.Get("client_id")),
+		"expires_in":   300,
+		"token_type":   "Bearer",
+	})
+}
+
+// issueToken builds an unsigned JWT-shaped access token for principal.
+func issueToken(principal, clientID string) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	claims, _ := json.Marshal(object{"preferred_username": principal, "azp": clientID})
+	return header + "." + enc.EncodeToString(claims) + "." + tokenSignature
+}
+
+func (s *Server) handleServerInfo(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, object{
+		"systemInfo": object{"version": s.version},
+		"profileInfo": object{
+			"name":                 "community",
+			"disabledFeatures":     append([]string{}, s.disabled...),
+			"previewFeatures":      []string{},
+			"experimentalFeatures": []string{},
+		},
+	})
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+// object is a decoded JSON object. Representations are stored as objects so
+// the fake round-trips fields it does not know about, like Keycloak does.
+type object = map[string]interface{}
+
+func decodeObject(r *http.Request) (object, error) {
+	var o object
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		return nil, fmt.Errorf("unable to parse request body: %w", err)
+	}
+	if o == nil {
+		o = object{}
+	}
+	return o, nil
+}
+
+func decodeList(r *http.Request) ([]object, error) {
+	var list []object
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unable to parse request body: %w", err)
+	}
+	return list, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes Keycloak's ErrorRepresentation shape.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, object{"errorMessage": msg})
+}
+
+// notFound writes the body JAX-RS NotFoundException produces in Keycloak.
+func notFound(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusNotFound, object{
+		"error":             msg,
+		"error_description": "For more on this error consult the server log.",
+	})
+}
+
+func badBody(w http.ResponseWriter, err error) {
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
+// created writes 201 with a Location header pointing at path.
+func created(w http.ResponseWriter, r *http.Request, path string) {
+	w.Header().Set("Location", "http://"+r.Host+path)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func noContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newID returns a random UUIDv4 string.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func str(o object, key string) string {
+	v, _ := o[key].(string)
+	return v
+}
+
+func boolVal(o object, key string) bool {
+	v, _ := o[key].(bool)
+	return v
+}
+
+// deepCopy clones an object so callers can mutate the result freely.
+func deepCopy(o object) object {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return object{}
+	}
+	var out object
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+// merge applies the top-level fields of src onto dst, mirroring Keycloak's
+// "only non-null fields are updated" semantics for PUT.
+func merge(dst, src object) {
+	for k, v := range src {
+		if v == nil {
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// paginate applies the first/max query parameters to items.
+func paginate[T any](r *http.Request, items []T) []T {
+	q := r.URL.Query()
+	first, _ := strconv.Atoi(q.Get("first"))
+	if first < 0 {
+		first = 0
+	}
+	if first >= len(items) {
+		return []T{}
+	}
+	items = items[first:]
+	if max, err := strconv.Atoi(q.Get("max")); err == nil && max >= 0 && max < len(items) {
+		items = items[:max]
+	}
+	return items
+}
+
+// matches implements Keycloak's exact/substring search semantics.
+func matches(value, query string, exact bool) bool {
+	if exact {
+		return strings.EqualFold(value, query)
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(query))
+}
+
+func realmPath(rs *realmState) string {
+	return "/admin/realms/" + url.PathEscape(str(rs.rep, "realm"))
+}
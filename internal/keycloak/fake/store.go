@@ -0,0 +1,376 @@
+package fake
+
+import "strings"
+
+// collection is an insertion-ordered set of objects keyed by ID.
+type collection struct {
+	ids   []string
+	items map[string]object
+}
+
+func newCollection() *collection {
+	return &collection{items: make(map[string]object)}
+}
+
+func (c *collection) add(id string, o object) {
+	if _, ok := c.items[id]; !ok {
+		c.ids = append(c.ids, id)
+	}
+	c.items[id] = o
+}
+
+func (c *collection) get(id string) object {
+	return c.items[id]
+}
+
+func (c *collection) remove(id string) bool {
+	if _, ok := c.items[id]; !ok {
+		return false
+	}
+	delete(c.items, id)
+	for i, existing := range c.ids {
+		if existing == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (c *collection) all() []object {
+	out := make([]object, 0, len(c.ids))
+	for _, id := range c.ids {
+		out = append(out, c.items[id])
+	}
+	return out
+}
+
+func (c *collection) find(match func(object) bool) object {
+	for _, id := range c.ids {
+		if o := c.items[id]; match(o) {
+			return o
+		}
+	}
+	return nil
+}
+
+// execution is a node in an authentication flow. Sub-flow executions point
+// at another (non top-level) flow via flowID.
+type execution struct {
+	id          string
+	provider    string
+	requirement string
+	flowID      string
+	configID    string
+	priority    int
+}
+
+// realmState holds everything stored for a single realm.
+type realmState struct {
+	rep object
+
+	clients *collection
+	users   *collection
+	// passwords holds the current password per user ID.
+	passwords map[string]string
+
+	groups *collection
+	// groupParent maps a child group ID to its parent group ID.
+	groupParent map[string]string
+	// userGroups maps a user ID to the IDs of the groups it is a member of.
+	userGroups map[string][]string
+
+	// roles holds realm and client roles keyed by role ID. Client roles carry
+	// clientRole=true and containerId=<client UUID>.
+	roles *collection
+	// composites maps a composite role ID to its member role IDs.
+	composites map[string][]string
+	// roleMappings maps a user or group ID to its directly assigned role IDs.
+	roleMappings map[string][]string
+
+	clientScopes *collection
+	// defaultScopes and optionalScopes map a client UUID to scope IDs.
+	defaultScopes  map[string][]string
+	optionalScopes map[string][]string
+	// protocolMappers maps a client or client-scope ID to its mappers.
+	protocolMappers map[string]*collection
+
+	// idps is keyed by alias; idpMappers maps an alias to its mappers.
+	idps       *collection
+	idpMappers map[string]*collection
+
+	components    *collection
+	organizations *collection
+
+	// requiredActions is keyed by alias.
+	requiredActions *collection
+
+	flows *collection
+	// executions maps a flow ID to its direct child executions.
+	executions map[string][]*execution
+	configs    *collection
+}
+
+// builtinClients are created with every realm, like Keycloak does.
+var builtinClients = []string{
+	"account",
+	"account-console",
+	"admin-cli",
+	"broker",
+	"realm-management",
+	"security-admin-console",
+}
+
+// builtinDefaultScopes and builtinOptionalScopes are the realm's default
+// client scopes, assigned to every new client that does not specify its own.
+var (
+	builtinDefaultScopes  = []string{"acr", "basic", "email", "profile", "roles", "web-origins"}
+	builtinOptionalScopes = []string{"address", "microprofile-jwt", "offline_access", "phone"}
+)
+
+// builtinRequiredActions are registered with every realm, in priority order.
+var builtinRequiredActions = []struct {
+	alias   string
+	name    string
+	enabled bool
+}{
+	{"CONFIGURE_TOTP", "Configure OTP", true},
+	{"TERMS_AND_CONDITIONS", "Terms and Conditions", false},
+	{"UPDATE_PASSWORD", "Update Password", true},
+	{"UPDATE_PROFILE", "Update Profile", true},
+	{"VERIFY_EMAIL", "Verify Email", true},
+	{"delete_account", "Delete Account", false},
+	{"webauthn-register", "Webauthn Register", true},
+	{"webauthn-register-passwordless", "Webauthn Register Passwordless", true},
+	{"VERIFY_PROFILE", "Verify Profile", true},
+	{"delete_credential", "Delete Credential", true},
+	{"update_user_locale", "Update User Locale", true},
+}
+
+// registrableRequiredActions are providers that exist on the server but are
+// not registered by default; they can be added via register-required-action.
+var registrableRequiredActions = map[string]string{
+	"CONFIGURE_RECOVERY_AUTHN_CODES": "Recovery Authentication Codes",
+	"idp_link":                       "Linking Identity Provider",
+}
+
+// builtinFlows are the top-level flows every realm starts with, together
+// with the realm attribute that binds them.
+var builtinFlows = []struct {
+	alias    string
+	provider string
+	binding  string
+}{
+	{"browser", "basic-flow", "browserFlow"},
+	{"direct grant", "basic-flow", "directGrantFlow"},
+	{"registration", "basic-flow", "registrationFlow"},
+	{"reset credentials", "basic-flow", "resetCredentialsFlow"},
+	{"clients", "client-flow", "clientAuthenticationFlow"},
+	{"first broker login", "basic-flow", "firstBrokerLoginFlow"},
+	{"docker auth", "basic-flow", "dockerAuthenticationFlow"},
+}
+
+// addRealm creates and seeds a realm from its representation. The caller
+// must hold s.mu (or be constructing the server).
+func (s *Server) addRealm(rep object) *realmState {
+	rep = deepCopy(rep)
+	name := str(rep, "realm")
+	if str(rep, "id") == "" {
+		rep["id"] = newID()
+	}
+
+	rs := &realmState{
+		rep:             object{},
+		clients:         newCollection(),
+		users:           newCollection(),
+		passwords:       make(map[string]string),
+		groups:          newCollection(),
+		groupParent:     make(map[string]string),
+		userGroups:      make(map[string][]string),
+		roles:           newCollection(),
+		composites:      make(map[string][]string),
+		roleMappings:    make(map[string][]string),
+		clientScopes:    newCollection(),
+		defaultScopes:   make(map[string][]string),
+		optionalScopes:  make(map[string][]string),
+		protocolMappers: make(map[string]*collection),
+		idps:            newCollection(),
+		idpMappers:      make(map[string]*collection),
+		components:      newCollection(),
+		organizations:   newCollection(),
+		requiredActions: newCollection(),
+		flows:           newCollection(),
+		executions:      make(map[string][]*execution),
+		configs:         newCollection(),
+	}
+	rs.rep["realm"] = name
+	rs.rep["id"] = rep["id"]
+	rs.rep["enabled"] = false
+
+	s.seedRealm(rs)
+	merge(rs.rep, rep)
+
+	s.realms[name] = rs
+	s.realmOrder = append(s.realmOrder, name)
+	return rs
+}
+
+func (s *Server) seedRealm(rs *realmState) {
+	name := str(rs.rep, "realm")
+
+	defaultRoles := rs.addRole(object{"name": "default-roles-" + strings.ToLower(name), "description": "${role_default-roles}"}, "")
+	offline := rs.addRole(object{"name": "offline_access", "description": "${role_offline-access}"}, "")
+	uma := rs.addRole(object{"name": "uma_authorization", "description": "${role_uma_authorization}"}, "")
+	rs.composites[str(defaultRoles, "id")] = []string{str(offline, "id"), str(uma, "id")}
+	defaultRoles["composite"] = true
+	rs.rep["defaultRole"] = object{
+		"id":          defaultRoles["id"],
+		"name":        defaultRoles["name"],
+		"composite":   true,
+		"clientRole":  false,
+		"containerId": rs.rep["id"],
+	}
+
+	for _, scope := range append(append([]string{}, builtinDefaultScopes...), builtinOptionalScopes...) {
+		id := newID()
+		rs.clientScopes.add(id, object{"id": id, "name": scope, "protocol": "openid-connect"})
+	}
+
+	for _, clientID := range builtinClients {
+		rs.addClient(object{"clientId": clientID, "enabled": true, "publicClient": clientID == "admin-cli" || clientID == "security-admin-console" || clientID == "account-console"})
+	}
+
+	for i, ra := range builtinRequiredActions {
+		rs.requiredActions.add(ra.alias, object{
+			"alias":         ra.alias,
+			"name":          ra.name,
+			"providerId":    ra.alias,
+			"enabled":       ra.enabled,
+			"defaultAction": false,
+			"priority":      (i + 1) * 10,
+			"config":        object{},
+		})
+	}
+
+	for _, f := range builtinFlows {
+		id := newID()
+		rs.flows.add(id, object{
+			"id":                       id,
+			"alias":                    f.alias,
+			"description":              "",
+			"providerId":               f.provider,
+			"topLevel":                 true,
+			"builtIn":                  true,
+			"authenticationExecutions": []interface{}{},
+		})
+		rs.rep[f.binding] = f.alias
+	}
+}
+
+// addRole stores a new realm role (clientUUID == "") or client role.
+func (rs *realmState) addRole(rep object, clientUUID string) object {
+	role := deepCopy(rep)
+	id := newID()
+	role["id"] = id
+	if _, ok := role["composite"]; !ok {
+		role["composite"] = false
+	}
+	if clientUUID == "" {
+		role["clientRole"] = false
+		role["containerId"] = rs.rep["id"]
+	} else {
+		role["clientRole"] = true
+		role["containerId"] = clientUUID
+	}
+	rs.roles.add(id, role)
+	return role
+}
+
+func (rs *realmState) realmRole(name string) object {
+	return rs.roles.find(func(o object) bool {
+		return !boolVal(o, "clientRole") && str(o, "name") == name
+	})
+}
+
+func (rs *realmState) clientRole(clientUUID, name string) object {
+	return rs.roles.find(func(o object) bool {
+		return boolVal(o, "clientRole") && str(o, "containerId") == clientUUID && str(o, "name") == name
+	})
+}
+
+func (rs *realmState) rolesOf(clientUUID string) []object {
+	var out []object
+	for _, role := range rs.roles.all() {
+		if clientUUID == "" && !boolVal(role, "clientRole") {
+			out = append(out, role)
+		} else if clientUUID != "" && str(role, "containerId") == clientUUID {
+			out = append(out, role)
+		}
+	}
+	return out
+}
+
+// removeRole deletes a role and every reference to it.
+func (rs *realmState) removeRole(id string) {
+	rs.roles.remove(id)
+	delete(rs.composites, id)
+	for owner, members := range rs.composites {
+		rs.composites[owner] = without(members, id)
+	}
+	for subject, roles := range rs.roleMappings {
+		rs.roleMappings[subject] = without(roles, id)
+	}
+}
+
+func (rs *realmState) clientByClientID(clientID string) object {
+	return rs.clients.find(func(o object) bool {
+		return str(o, "clientId") == clientID
+	})
+}
+
+func (rs *realmState) clientScopeByName(name string) object {
+	return rs.clientScopes.find(func(o object) bool {
+		return str(o, "name") == name
+	})
+}
+
+func (rs *realmState) userByUsername(username string) object {
+	username = strings.ToLower(username)
+	return rs.users.find(func(o object) bool {
+		return str(o, "username") == username
+	})
+}
+
+func (rs *realmState) flowByAlias(alias string) object {
+	return rs.flows.find(func(o object) bool {
+		return str(o, "alias") == alias
+	})
+}
+
+func (rs *realmState) mappersOf(ownerID string) *collection {
+	c, ok := rs.protocolMappers[ownerID]
+	if !ok {
+		c = newCollection()
+		rs.protocolMappers[ownerID] = c
+	}
+	return c
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func without(list []string, v string) []string {
+	out := list[:0:0]
+	for _, item := range list {
+		if item != v {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,310 @@
+package fake
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (s *Server) userRoutes(mux *http.ServeMux) {
+	base := "/admin/realms/{realm}/users"
+	mux.HandleFunc("GET "+base, s.inRealm(s.listUsers))
+	mux.HandleFunc("POST "+base, s.inRealm(s.createUser))
+	mux.HandleFunc("GET "+base+"/{id}", s.inRealm(s.getUser))
+	mux.HandleFunc("PUT "+base+"/{id}", s.inRealm(s.updateUser))
+	mux.HandleFunc("DELETE "+base+"/{id}", s.inRealm(s.deleteUser))
+	mux.HandleFunc("PUT "+base+"/{id}/reset-password", s.inRealm(s.resetPassword))
+	mux.HandleFunc("GET "+base+"/{id}/groups", s.inRealm(s.listUserGroups))
+	mux.HandleFunc("PUT "+base+"/{id}/groups/{group}", s.inRealm(s.joinGroup))
+	mux.HandleFunc("DELETE "+base+"/{id}/groups/{group}", s.inRealm(s.leaveGroup))
+}
+
+// addUser stores a new user. Keycloak lower-cases usernames and grants every
+// new user the realm's default role.
+func (rs *realmState) addUser(rep object) object {
+	user := deepCopy(rep)
+	id := newID()
+	user["id"] = id
+	user["username"] = strings.ToLower(str(user, "username"))
+	user["createdTimestamp"] = time.Now().UnixMilli()
+	for key, def := range map[string]interface{}{
+		"enabled":       false,
+		"emailVerified": false,
+		"totp":          false,
+	} {
+		if _, ok := user[key]; !ok {
+			user[key] = def
+		}
+	}
+	if email := str(user, "email"); email != "" {
+		user["email"] = strings.ToLower(email)
+	}
+	for _, field := range []string{"credentials", "groups", "realmRoles", "clientRoles", "federatedIdentities"} {
+		delete(user, field)
+	}
+	rs.users.add(id, user)
+
+	if defaultRole, ok := rs.rep["defaultRole"].(map[string]interface{}); ok {
+		if roleID, _ := defaultRole["id"].(string); roleID != "" {
+			rs.roleMappings[id] = []string{roleID}
+		}
+	}
+	return user
+}
+
+func (rs *realmState) removeUser(id string) {
+	rs.users.remove(id)
+	delete(rs.passwords, id)
+	delete(rs.userGroups, id)
+	delete(rs.roleMappings, id)
+}
+
+// userRep renders a user like GET /users/{id}; credentials are never returned.
+func (rs *realmState) userRep(user object) object {
+	return deepCopy(user)
+}
+
+func (rs *realmState) emailTaken(email, exceptID string) bool {
+	if email == "" || boolVal(rs.rep, "duplicateEmailsAllowed") {
+		return false
+	}
+	email = strings.ToLower(email)
+	return rs.users.find(func(o object) bool {
+		return str(o, "id") != exceptID && str(o, "email") == email
+	}) != nil
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	q := r.URL.Query()
+	exact := q.Get("exact") == "true"
+	filters := map[string]string{
+		"username":  q.Get("username"),
+		"email":     q.Get("email"),
+		"firstName": q.Get("firstName"),
+		"lastName":  q.Get("lastName"),
+	}
+	search := q.Get("search")
+
+	out := []object{}
+	for _, user := range rs.users.all() {
+		// Service-account users are hidden from the user listing.
+		if str(user, "serviceAccountClientId") != "" {
+			continue
+		}
+		ok := true
+		for field, value := range filters {
+			if value != "" && !matches(str(user, field), value, exact) {
+				ok = false
+			}
+		}
+		if search != "" {
+			term := strings.Trim(search, "*")
+			hit := false
+			for _, field := range []string{"username", "email", "firstName", "lastName"} {
+				if matches(str(user, field), term, false) {
+					hit = true
+				}
+			}
+			ok = ok && hit
+		}
+		if ok {
+			out = append(out, rs.userRep(user))
+		}
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if str(rep, "username") == "" && boolVal(rs.rep, "registrationEmailAsUsername") {
+		rep["username"] = str(rep, "email")
+	}
+	username := str(rep, "username")
+	if username == "" {
+		writeError(w, http.StatusBadRequest, "User name is missing")
+		return
+	}
+	if rs.userByUsername(username) != nil {
+		writeError(w, http.StatusConflict, "User exists with same username")
+		return
+	}
+	if rs.emailTaken(str(rep, "email"), "") {
+		writeError(w, http.StatusConflict, "User exists with same email")
+		return
+	}
+
+	password := initialPassword(rep)
+	delete(rep, "id")
+	user := rs.addUser(rep)
+	if password != "" {
+		rs.passwords[str(user, "id")] = password
+	}
+	created(w, r, realmPath(rs)+"/users/"+str(user, "id"))
+}
+
+// initialPassword returns the value of the first password credential in a
+// user representation, if any.
+func initialPassword(rep object) string {
+	creds, _ := rep["credentials"].([]interface{})
+	for _, c := range creds {
+		cred, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, _ := cred["type"].(string); t == "password" {
+			v, _ := cred["value"].(string)
+			return v
+		}
+	}
+	return ""
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	user := rs.users.get(r.PathValue("id"))
+	if user == nil {
+		notFound(w, "User not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, rs.userRep(user))
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	user := rs.users.get(id)
+	if user == nil {
+		notFound(w, "User not found")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if username := str(rep, "username"); username != "" && !strings.EqualFold(username, str(user, "username")) {
+		if !boolVal(rs.rep, "editUsernameAllowed") {
+			writeError(w, http.StatusBadRequest, "error-user-attribute-read-only")
+			return
+		}
+		if rs.userByUsername(username) != nil {
+			writeError(w, http.StatusConflict, "User exists with same username")
+			return
+		}
+		rep["username"] = strings.ToLower(username)
+	} else {
+		delete(rep, "username")
+	}
+	if rs.emailTaken(str(rep, "email"), id) {
+		writeError(w, http.StatusConflict, "User exists with same email")
+		return
+	}
+	if email := str(rep, "email"); email != "" {
+		rep["email"] = strings.ToLower(email)
+	}
+	if password := initialPassword(rep); password != "" {
+		rs.passwords[id] = password
+	}
+	for _, field := range []string{"id", "createdTimestamp", "credentials", "groups", "realmRoles", "clientRoles", "federatedIdentities"} {
+		delete(rep, field)
+	}
+	merge(user, rep)
+	noContent(w)
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if rs.users.get(id) == nil {
+		notFound(w, "User not found")
+		return
+	}
+	rs.removeUser(id)
+	noContent(w)
+}
+
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if rs.users.get(id) == nil {
+		notFound(w, "User not found")
+		return
+	}
+	rep, err := decodeObject(r)
+	if err != nil {
+		badBody(w, err)
+		return
+	}
+	if t := str(rep, "type"); t != "" && t != "password" {
+		writeError(w, http.StatusBadRequest, "Unsupported credential type")
+		return
+	}
+	value := str(rep, "value")
+	if value == "" {
+		writeError(w, http.StatusBadRequest, "Password value is missing")
+		return
+	}
+	rs.passwords[id] = value
+	noContent(w)
+}
+
+// Password returns the current password of a user, for assertions in tests.
+func (s *Server) Password(realm, username string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.realms[realm]
+	if !ok {
+		return "", false
+	}
+	user := rs.userByUsername(username)
+	if user == nil {
+		return "", false
+	}
+	password, ok := rs.passwords[str(user, "id")]
+	return password, ok
+}
+
+func (s *Server) listUserGroups(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id := r.PathValue("id")
+	if rs.users.get(id) == nil {
+		notFound(w, "User not found")
+		return
+	}
+	out := []object{}
+	for _, groupID := range rs.userGroups[id] {
+		if group := rs.groups.get(groupID); group != nil {
+			out = append(out, rs.groupRep(group, false, nil))
+		}
+	}
+	writeJSON(w, http.StatusOK, paginate(r, out))
+}
+
+func (s *Server) joinGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id, groupID := r.PathValue("id"), r.PathValue("group")
+	if rs.users.get(id) == nil {
+		notFound(w, "User not found")
+		return
+	}
+	if rs.groups.get(groupID) == nil {
+		notFound(w, "Group not found")
+		return
+	}
+	if !contains(rs.userGroups[id], groupID) {
+		rs.userGroups[id] = append(rs.userGroups[id], groupID)
+	}
+	noContent(w)
+}
+
+func (s *Server) leaveGroup(w http.ResponseWriter, r *http.Request, rs *realmState) {
+	id, groupID := r.PathValue("id"), r.PathValue("group")
+	if rs.users.get(id) == nil {
+		notFound(w, "User not found")
+		return
+	}
+	if rs.groups.get(groupID) == nil {
+		notFound(w, "Group not found")
+		return
+	}
+	rs.userGroups[id] = without(rs.userGroups[id], groupID)
+	noContent(w)
+}
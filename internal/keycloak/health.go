@@ -0,0 +1,94 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Health is the outcome of a successful connection probe.
+type Health struct {
+	// ServerInfo is the /admin/serverinfo response used for the probe.
+	ServerInfo *ServerInfo
+	// Latency is the round-trip time of the serverinfo request.
+	Latency time.Duration
+	// TokenExpiry is when the cached admin access token expires.
+	TokenExpiry time.Time
+	// CertNotAfter is the earliest notAfter in the server's certificate
+	// chain. Zero when the server is reached over plain HTTP.
+	CertNotAfter time.Time
+	// Principal is the user name the operator authenticates as; for the
+	// client credentials grant this is the service account user.
+	Principal string
+}
+
+// Probe fetches /admin/serverinfo and reports connection health alongside it.
+// It bypasses the circuit breaker so a successful probe closes it.
+func (c *Client) Probe(ctx context.Context) (*Health, error) {
+	req, err := c.newRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var info ServerInfo
+	start := time.Now()
+	resp, err := req.SetResult(&info).Get(c.baseURL + "/admin/serverinfo")
+	latency := time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("GET /admin/serverinfo failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("GET /admin/serverinfo failed: %s: %s", resp.Status(), string(resp.Body()))
+	}
+
+	health := &Health{ServerInfo: &info, Latency: latency}
+	if state := resp.RawResponse.TLS; state != nil {
+		for _, cert := range state.PeerCertificates {
+			if health.CertNotAfter.IsZero() || cert.NotAfter.Before(health.CertNotAfter) {
+				health.CertNotAfter = cert.NotAfter
+			}
+		}
+	}
+
+	c.tokenMutex.RLock()
+	health.TokenExpiry = c.tokenExpiry
+	if c.token != nil {
+		health.Principal = tokenPrincipal(c.token.AccessToken)
+	}
+	c.tokenMutex.RUnlock()
+
+	return health, nil
+}
+
+// tokenPrincipal extracts the user name from a JWT access token without
+// verifying it; the token was just issued to us by the server. Returns "" for
+// tokens that are not JWTs.
+func tokenPrincipal(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		AuthorizedParty   string `json:"azp"`
+		Subject           string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	switch {
+	case claims.PreferredUsername != "":
+		return claims.PreferredUsername
+	case claims.AuthorizedParty != "":
+		return claims.AuthorizedParty
+	default:
+		return claims.Subject
+	}
+}
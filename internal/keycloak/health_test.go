@@ -0,0 +1,78 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jwtWithClaims(claims string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestProbe(t *testing.T) {
+	token := jwtWithClaims(`{"preferred_username":"service-account-operator","azp":"operator"}`)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /realms/master/protocol/openid-connect/token", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"` + token + `","expires_in":300,"token_type":"Bearer"}`))
+	})
+	mux.HandleFunc("GET /admin/serverinfo", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"systemInfo":{"version":"26.1.0"}}`))
+	})
+	srv, caPEM := mkSelfSignedHTTPSServer(t, mux)
+
+	kc := NewClient(Config{BaseURL: srv.URL, ClientID: "operator", ClientSecret: "s3cret", CACert: caPEM}, testr.New(t))
+	health, err := kc.Probe(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "26.1.0", health.ServerInfo.SystemInfo.Version)
+	assert.Equal(t, "service-account-operator", health.Principal)
+	assert.Positive(t, health.Latency)
+	assert.WithinDuration(t, time.Now().Add(300*time.Second), health.TokenExpiry, 5*time.Second)
+	assert.Equal(t, srv.Certificate().NotAfter, health.CertNotAfter)
+}
+
+func TestProbe_ServerError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /realms/master/protocol/openid-connect/token", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"opaque","expires_in":300}`))
+	})
+	mux.HandleFunc("GET /admin/serverinfo", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	srv, caPEM := mkSelfSignedHTTPSServer(t, mux)
+
+	kc := NewClient(Config{BaseURL: srv.URL, Username: "admin", Password: "admin", CACert: caPEM}, testr.New(t))
+	_, err := kc.Probe(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestTokenPrincipal(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"preferred username", jwtWithClaims(`{"preferred_username":"admin","azp":"admin-cli","sub":"123"}`), "admin"},
+		{"falls back to azp", jwtWithClaims(`{"azp":"operator","sub":"123"}`), "operator"},
+		{"falls back to sub", jwtWithClaims(`{"sub":"123"}`), "123"},
+		{"opaque token", "not-a-jwt", ""},
+		{"undecodable payload", "a.!!!.c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tokenPrincipal(tt.token))
+		})
+	}
+}
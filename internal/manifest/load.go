@@ -0,0 +1,259 @@
+// Package manifest loads operator custom resources and their Secrets from
+// files, for the subcommands that work on manifests outside a cluster.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+// Scheme knows the operator's kinds and the core kinds manifests may carry
+// alongside them, such as Secrets.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(Scheme))
+	utilruntime.Must(keycloakv1beta1.AddToScheme(Scheme))
+}
+
+var decoder = serializer.NewCodecFactory(Scheme).UniversalDeserializer()
+
+// Document is one object read from a manifest file.
+type Document struct {
+	// File is the file the document was read from.
+	File string
+
+	// Object is the decoded object.
+	Object client.Object
+
+	// Raw is the document as JSON, including fields the object's Go type
+	// does not know.
+	Raw []byte
+}
+
+// Load reads the manifests in paths. Directories are walked recursively for
+// .yaml, .yml and .json files, and multi-document YAML is split. Objects
+// without a namespace are placed in namespace, except cluster-scoped kinds.
+//
+// Documents of kinds outside the operator's API group that the scheme does
+// not know, such as a kustomization.yaml, are skipped. Unknown kinds in the
+// operator's group are an error, as they are most likely typos.
+func Load(namespace string, paths ...string) ([]client.Object, error) {
+	docs, err := LoadDocuments(namespace, paths...)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]client.Object, 0, len(docs))
+	for _, doc := range docs {
+		objects = append(objects, doc.Object)
+	}
+	return objects, nil
+}
+
+// LoadDocuments reads the manifests in paths like Load and returns them with
+// the file they were read from.
+func LoadDocuments(namespace string, paths ...string) ([]Document, error) {
+	files, err := manifestFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		fileDocs, err := decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for i := range fileDocs {
+			fileDocs[i].File = file
+		}
+		docs = append(docs, fileDocs...)
+	}
+
+	for _, doc := range docs {
+		if doc.Object.GetNamespace() == "" && !isClusterScoped(doc.Object) {
+			doc.Object.SetNamespace(namespace)
+		}
+	}
+	return docs, nil
+}
+
+// manifestFiles expands paths into a sorted list of manifest files.
+func manifestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(p)) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// decode splits data into documents and decodes each into a typed object.
+func decode(data []byte) ([]Document, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var docs []Document
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		var meta metav1.TypeMeta
+		if err := utilyaml.Unmarshal(doc, &meta); err != nil {
+			return nil, err
+		}
+		if meta.Kind == "" {
+			continue
+		}
+		obj, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) && !strings.HasPrefix(meta.APIVersion, keycloakv1beta1.GroupVersion.Group+"/") {
+				continue
+			}
+			return nil, fmt.Errorf("failed to decode %s: %w", meta.Kind, err)
+		}
+		cobj, ok := obj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", gvk.Kind)
+		}
+		cobj.GetObjectKind().SetGroupVersionKind(*gvk)
+		if secret, ok := cobj.(*corev1.Secret); ok {
+			mergeStringData(secret)
+		}
+		raw, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, Document{Object: cobj, Raw: raw})
+	}
+}
+
+// mergeStringData folds stringData into data, as the API server does on write.
+func mergeStringData(secret *corev1.Secret) {
+	if len(secret.StringData) == 0 {
+		return
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte, len(secret.StringData))
+	}
+	for k, v := range secret.StringData {
+		secret.Data[k] = []byte(v)
+	}
+	secret.StringData = nil
+}
+
+// isClusterScoped reports whether obj is one of the operator's cluster-scoped
+// kinds.
+func isClusterScoped(obj client.Object) bool {
+	switch obj.(type) {
+	case *keycloakv1beta1.ClusterKeycloakInstance, *keycloakv1beta1.ClusterKeycloakRealm:
+		return true
+	}
+	return false
+}
+
+// LoadSecretsDir reads Secrets laid out like mounted Secret volumes: every
+// subdirectory of dir is a Secret of that name in namespace, and every file
+// in it one key.
+func LoadSecretsDir(namespace, dir string) ([]client.Object, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []client.Object
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		keys, err := os.ReadDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		secret := &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: entry.Name(), Namespace: namespace},
+			Data:       make(map[string][]byte, len(keys)),
+		}
+		for _, key := range keys {
+			// Mounted volumes keep the real files in ..data and symlink them.
+			if key.IsDir() || strings.HasPrefix(key.Name(), ".") {
+				continue
+			}
+			value, err := os.ReadFile(filepath.Join(dir, entry.Name(), key.Name()))
+			if err != nil {
+				return nil, err
+			}
+			secret.Data[key.Name()] = value
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// WriteSecretsDir writes secret to dir in the layout LoadSecretsDir reads,
+// replacing the keys it already holds there.
+func WriteSecretsDir(dir string, secret *corev1.Secret) error {
+	secretDir := filepath.Join(dir, secret.Name)
+	if err := os.MkdirAll(secretDir, 0o700); err != nil {
+		return err
+	}
+	for key, value := range secret.Data {
+		if err := os.WriteFile(filepath.Join(secretDir, key), value, 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
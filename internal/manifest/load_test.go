@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "realm.yaml"), `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  realmName: test
+  definition: {}
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: smtp
+  namespace: mail
+stringData:
+  password: hunter2
+`)
+	writeFile(t, filepath.Join(dir, "cluster", "realm.yaml"), `apiVersion: keycloak.hostzero.com/v1beta1
+kind: ClusterKeycloakRealm
+metadata:
+  name: shared
+spec:
+  realmName: shared
+  definition: {}
+`)
+	writeFile(t, filepath.Join(dir, "kustomization.yaml"), `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources: [realm.yaml]
+`)
+	writeFile(t, filepath.Join(dir, "README.md"), "not a manifest")
+
+	objects, err := Load("team", dir)
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+
+	shared := objects[0].(*keycloakv1beta1.ClusterKeycloakRealm)
+	require.Empty(t, shared.Namespace, "cluster-scoped kinds get no namespace")
+
+	realm := objects[1].(*keycloakv1beta1.KeycloakRealm)
+	require.Equal(t, "team", realm.Namespace)
+	require.Equal(t, "test", *realm.Spec.RealmName)
+
+	secret := objects[2].(*corev1.Secret)
+	require.Equal(t, "mail", secret.Namespace)
+	require.Equal(t, "hunter2", string(secret.Data["password"]))
+
+	writeFile(t, filepath.Join(dir, "typo.yaml"), `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClinet
+metadata:
+  name: app
+`)
+	_, err = Load("team", dir)
+	require.ErrorContains(t, err, "KeycloakClinet")
+}
+
+func TestLoadDocuments(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "client.yaml")
+	writeFile(t, file, `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  clientId: app
+  clientID: typo
+`)
+
+	docs, err := LoadDocuments("team", dir)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Equal(t, file, docs[0].File)
+	require.Equal(t, "team", docs[0].Object.GetNamespace())
+	require.JSONEq(t, `{
+		"apiVersion": "keycloak.hostzero.com/v1beta1",
+		"kind": "KeycloakClient",
+		"metadata": {"name": "app"},
+		"spec": {"clientId": "app", "clientID": "typo"}
+	}`, string(docs[0].Raw), "unknown fields are kept")
+}
+
+func TestLoadSecretsDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app-credentials", "client-secret"), "s3cret")
+	writeFile(t, filepath.Join(dir, "app-credentials", "..data", "client-secret"), "ignored")
+
+	secrets, err := LoadSecretsDir("team", dir)
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	secret := secrets[0].(*corev1.Secret)
+	require.Equal(t, "app-credentials", secret.Name)
+	require.Equal(t, "team", secret.Namespace)
+	require.Equal(t, map[string][]byte{"client-secret": []byte("s3cret")}, secret.Data)
+}
+
+func TestWriteSecretsDir(t *testing.T) {
+	dir := t.TempDir()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "team"},
+		Data:       map[string][]byte{"username": []byte("alice"), "password": []byte("s3cret")},
+	}
+	require.NoError(t, WriteSecretsDir(dir, secret))
+
+	secrets, err := LoadSecretsDir("team", dir)
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	require.Equal(t, secret.Data, secrets[0].(*corev1.Secret).Data)
+}
@@ -0,0 +1,16 @@
+package manifest
+
+import (
+	"regexp"
+	"strings"
+)
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// PlaceholderName derives the environment variable name that stands for a
+// Secret key, e.g. app-credentials/client-secret ->
+// APP_CREDENTIALS_CLIENT_SECRET. Export writes it into Secret stubs, compile
+// into the realm import, and apply expands it from the environment.
+func PlaceholderName(secret, key string) string {
+	return nonIdentifierChars.ReplaceAllString(strings.ToUpper(secret+"_"+key), "_")
+}
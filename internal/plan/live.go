@@ -0,0 +1,270 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+)
+
+// groupChildrenPageSize is the page size for listing subgroups.
+const groupChildrenPageSize = 100
+
+// get fetches one object, returning nil if it does not exist.
+func (p *planner) get(load func() (json.RawMessage, error)) (map[string]interface{}, error) {
+	raw, err := load()
+	if keycloak.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return obj, nil
+}
+
+// list fetches the objects under key once.
+func (p *planner) list(key string, load func() ([]json.RawMessage, error)) ([]map[string]interface{}, error) {
+	if objects, ok := p.lists[key]; ok {
+		return objects, nil
+	}
+	raws, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", key, err)
+	}
+	objects := make([]map[string]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		objects = append(objects, obj)
+	}
+	p.lists[key] = objects
+	return objects, nil
+}
+
+// find returns the object of the list under key whose field equals value.
+func (p *planner) find(key, field, value string, load func() ([]json.RawMessage, error)) (map[string]interface{}, error) {
+	objects, err := p.list(key, load)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		if stringField(obj, field) == value {
+			return obj, nil
+		}
+	}
+	return nil, nil
+}
+
+// findIn returns the object of list whose field equals value.
+func findIn(list []interface{}, field, value string) map[string]interface{} {
+	for _, item := range list {
+		if obj, ok := item.(map[string]interface{}); ok && stringField(obj, field) == value {
+			return obj
+		}
+	}
+	return nil
+}
+
+// client returns the full representation of the client with clientID.
+func (p *planner) client(ctx context.Context, clientID string) (map[string]interface{}, error) {
+	key := "clients/" + clientID
+	if objects, ok := p.lists[key]; ok {
+		return objects[0], nil
+	}
+	listed, err := p.find("clients", "clientId", clientID, func() ([]json.RawMessage, error) {
+		return p.kc.GetClientsRaw(ctx, p.realm)
+	})
+	if err != nil || listed == nil {
+		return nil, err
+	}
+	client, err := p.get(func() (json.RawMessage, error) {
+		return p.kc.GetClientRaw(ctx, p.realm, stringField(listed, "id"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client %s: %w", clientID, err)
+	}
+	p.lists[key] = []map[string]interface{}{client}
+	return client, nil
+}
+
+func (p *planner) clientScope(ctx context.Context, name string) (map[string]interface{}, error) {
+	return p.find("client-scopes", "name", name, func() ([]json.RawMessage, error) {
+		return p.kc.GetClientScopesRaw(ctx, p.realm)
+	})
+}
+
+// role returns the realm role name or, if clientID is set, the client role.
+func (p *planner) role(ctx context.Context, clientID, name string) (map[string]interface{}, error) {
+	if clientID == "" {
+		return p.find("roles", "name", name, func() ([]json.RawMessage, error) {
+			return p.kc.GetRealmRolesRaw(ctx, p.realm)
+		})
+	}
+	client, err := p.client(ctx, clientID)
+	if err != nil || client == nil {
+		return nil, err
+	}
+	return p.find("roles/"+clientID, "name", name, func() ([]json.RawMessage, error) {
+		return p.kc.GetClientRolesRaw(ctx, p.realm, stringField(client, "id"))
+	})
+}
+
+// group returns the full representation of the group at path.
+func (p *planner) group(ctx context.Context, path string) (map[string]interface{}, error) {
+	var found map[string]interface{}
+	listKey := "groups"
+	load := func() ([]json.RawMessage, error) { return p.kc.GetGroupsRaw(ctx, p.realm) }
+	for _, name := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		var err error
+		if found, err = p.find(listKey, "name", name, load); err != nil || found == nil {
+			return nil, err
+		}
+		parentID := stringField(found, "id")
+		listKey = "groups/" + parentID
+		load = func() ([]json.RawMessage, error) { return p.groupChildren(ctx, parentID) }
+	}
+	return p.get(func() (json.RawMessage, error) {
+		return p.kc.GetGroupRaw(ctx, p.realm, stringField(found, "id"))
+	})
+}
+
+// groupChildren lists the subgroups of a group. Servers without the children
+// endpoint inline them in the group's subGroups.
+func (p *planner) groupChildren(ctx context.Context, groupID string) ([]json.RawMessage, error) {
+	if !p.caps.Supports(keycloak.CapabilityGroupChildren) {
+		raw, err := p.kc.GetGroupRaw(ctx, p.realm, groupID)
+		if err != nil {
+			return nil, err
+		}
+		var group struct {
+			SubGroups []json.RawMessage `json:"subGroups"`
+		}
+		if err := json.Unmarshal(raw, &group); err != nil {
+			return nil, err
+		}
+		return group.SubGroups, nil
+	}
+	var all []json.RawMessage
+	for offset := 0; ; offset += groupChildrenPageSize {
+		page, err := p.kc.GetGroupChildrenRaw(ctx, p.realm, groupID, map[string]string{
+			"first": strconv.Itoa(offset),
+			"max":   strconv.Itoa(groupChildrenPageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < groupChildrenPageSize {
+			return all, nil
+		}
+	}
+}
+
+// user returns the user with username.
+func (p *planner) user(ctx context.Context, username string) (map[string]interface{}, error) {
+	users, err := p.list("users/"+username, func() ([]json.RawMessage, error) {
+		return p.kc.GetUsersRaw(ctx, p.realm, map[string]string{"username": username, "exact": "true"})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		// Keycloak stores usernames in lower case.
+		if strings.EqualFold(stringField(user, "username"), username) {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// subject is a user or group that roles are mapped to.
+type subject struct {
+	kind string
+	id   string
+}
+
+// subject returns the user or group a compiled role mapping applies to, or
+// nil if it does not exist yet.
+func (p *planner) subject(ctx context.Context, mapping map[string]interface{}) (*subject, error) {
+	if path := stringField(mapping, "group"); path != "" {
+		group, err := p.group(ctx, path)
+		if err != nil || group == nil {
+			return nil, err
+		}
+		return &subject{kind: "group", id: stringField(group, "id")}, nil
+	}
+	user, err := p.user(ctx, stringField(mapping, "user"))
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &subject{kind: "user", id: stringField(user, "id")}, nil
+}
+
+// missingRoles reports whether any of the realmRoles and clientRoles of def
+// is not mapped to s directly.
+func (p *planner) missingRoles(ctx context.Context, s *subject, def map[string]interface{}) (bool, error) {
+	if roles, _ := def["realmRoles"].([]interface{}); len(roles) > 0 {
+		var mapped []keycloak.RoleRepresentation
+		var err error
+		if s.kind == "group" {
+			mapped, err = p.kc.GetGroupRealmRoleMappings(ctx, p.realm, s.id)
+		} else {
+			mapped, err = p.kc.GetUserRealmRoleMappings(ctx, p.realm, s.id)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get realm role mappings: %w", err)
+		}
+		if !containsRoles(mapped, roles) {
+			return true, nil
+		}
+	}
+
+	clientRoles, _ := def["clientRoles"].(map[string]interface{})
+	for clientID, value := range clientRoles {
+		roles, _ := value.([]interface{})
+		client, err := p.client(ctx, clientID)
+		if err != nil {
+			return false, err
+		}
+		if client == nil {
+			return true, nil
+		}
+		var mapped []keycloak.RoleRepresentation
+		if s.kind == "group" {
+			mapped, err = p.kc.GetGroupClientRoleMappings(ctx, p.realm, s.id, stringField(client, "id"))
+		} else {
+			mapped, err = p.kc.GetUserClientRoleMappings(ctx, p.realm, s.id, stringField(client, "id"))
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get role mappings of client %s: %w", clientID, err)
+		}
+		if !containsRoles(mapped, roles) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsRoles(mapped []keycloak.RoleRepresentation, names []interface{}) bool {
+	have := make(map[string]bool, len(mapped))
+	for _, role := range mapped {
+		if role.Name != nil {
+			have[*role.Name] = true
+		}
+	}
+	for _, name := range names {
+		if s, _ := name.(string); !have[s] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,537 @@
+// Package plan compares operator manifests with the live state of a Keycloak
+// realm and describes the changes the operator would make, without a cluster.
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/compile"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// Action is what the operator would do to an object.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is one object the operator would change.
+type Change struct {
+	Action Action
+
+	// Kind is the kind of the custom resource, or for deletes the prune
+	// type, e.g. clients.
+	Kind string
+
+	// Resource is the namespace/name of the custom resource. Empty for
+	// deletes.
+	Resource string
+
+	// Name identifies the object in Keycloak. Objects scoped to a parent
+	// are prefixed with it, e.g. <clientId>/<role>.
+	Name string
+
+	// Details are the fields an update changes or, for authentication
+	// flows, the changes to their executions.
+	Details []string
+}
+
+// Options configures Plan.
+type Options struct {
+	// Realm selects the realm to plan, as for compile.Options.
+	Realm string
+}
+
+// Result is the plan for one realm.
+type Result struct {
+	// Realm is the realm name.
+	Realm string
+
+	// Changes are sorted creates first, then updates in compile order,
+	// then deletes.
+	Changes []Change
+
+	// Unmanaged are the objects spec.prune reports in DryRun mode. The
+	// operator leaves them alone, so they are not changes.
+	Unmanaged []keycloakv1beta1.UnmanagedObject
+
+	// Placeholders are the secrets that were not found. Fields set from
+	// them are not compared.
+	Placeholders []string
+
+	// Warnings describe settings the operator applies but Plan does not
+	// compare.
+	Warnings []string
+}
+
+// HasChanges reports whether the operator would change anything.
+func (r *Result) HasChanges() bool {
+	return len(r.Changes) > 0
+}
+
+// Plan compiles the selected realm from objects like compile.Compile, then
+// compares every resource with the live realm using the comparators of the
+// reconcilers. Objects spec.prune would delete are reported as deletes.
+func Plan(ctx context.Context, kc *keycloak.Client, objects []client.Object, opts Options) (*Result, error) {
+	compiled, err := compile.Compile(objects, compile.Options{Realm: opts.Realm})
+	if err != nil {
+		return nil, err
+	}
+	realm := compiled.Objects[0]
+	p := &planner{
+		kc:     kc,
+		realm:  realm.Name,
+		lists:  make(map[string][]map[string]interface{}),
+		result: &Result{Realm: realm.Name, Placeholders: compiled.Placeholders, Warnings: compiled.Warnings},
+	}
+
+	liveRealm, err := p.get(func() (json.RawMessage, error) { return kc.GetRealmRaw(ctx, p.realm) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to get realm %s: %w", p.realm, err)
+	}
+	if liveRealm == nil {
+		// A new realm: everything in it is created.
+		for _, obj := range compiled.Objects {
+			p.change(ActionCreate, obj, nil)
+		}
+		return p.finish(), nil
+	}
+
+	// Whether flows need reordering depends on the server's capabilities.
+	info, err := kc.GetServerInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+	p.caps = keycloak.DetectCapabilities(info)
+
+	// Client flow overrides refer to flows by ID, so compile again with the
+	// IDs of the live flows.
+	flows, err := p.list("flows", func() ([]json.RawMessage, error) { return kc.GetAuthenticationFlowsRaw(ctx, p.realm) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authentication flows: %w", err)
+	}
+	flowIDs := make(map[string]string, len(flows))
+	for _, flow := range flows {
+		flowIDs[stringField(flow, "alias")] = stringField(flow, "id")
+	}
+	if compiled, err = compile.Compile(objects, compile.Options{Realm: opts.Realm, FlowIDs: flowIDs}); err != nil {
+		return nil, err
+	}
+
+	p.placeholders = make(map[string]bool, len(compiled.Placeholders))
+	for _, name := range compiled.Placeholders {
+		p.placeholders["${"+name+"}"] = true
+	}
+	for _, obj := range compiled.Objects {
+		if err := p.planObject(ctx, obj, liveRealm); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", kindOf(obj.Source), resourceName(obj.Source), err)
+		}
+	}
+
+	// Prune works on the resources in a cluster; give it the manifests.
+	c := fake.NewClientBuilder().WithScheme(manifest.Scheme).WithObjects(objects...).Build()
+	deletes, unmanaged, err := controller.PruneCandidates(ctx, c, kc, p.caps, realm.Source, p.realm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unmanaged objects: %w", err)
+	}
+	for _, obj := range deletes {
+		p.result.Changes = append(p.result.Changes, Change{Action: ActionDelete, Kind: obj.Type, Name: obj.Name})
+	}
+	p.result.Unmanaged = unmanaged
+	return p.finish(), nil
+}
+
+// planner holds the live objects of the realm, fetched on first use.
+type planner struct {
+	kc           *keycloak.Client
+	caps         *keycloak.Capabilities
+	realm        string
+	lists        map[string][]map[string]interface{}
+	placeholders map[string]bool
+	result       *Result
+}
+
+func (p *planner) finish() *Result {
+	order := map[Action]int{ActionCreate: 0, ActionUpdate: 1, ActionDelete: 2}
+	sort.SliceStable(p.result.Changes, func(i, j int) bool {
+		return order[p.result.Changes[i].Action] < order[p.result.Changes[j].Action]
+	})
+	return p.result
+}
+
+func (p *planner) change(action Action, obj compile.Object, details []string) {
+	name := obj.Name
+	if obj.Parent != "" {
+		name = obj.Parent + "/" + name
+	}
+	if _, ok := obj.Source.(*keycloakv1beta1.KeycloakRoleMapping); ok {
+		name = roleMappingName(obj.Definition)
+	}
+	p.result.Changes = append(p.result.Changes, Change{
+		Action:   action,
+		Kind:     kindOf(obj.Source),
+		Resource: resourceName(obj.Source),
+		Name:     name,
+		Details:  details,
+	})
+}
+
+// compare records obj as created if live is nil and as updated if match
+// finds a difference between desired and live. extra lists further changes
+// found by the caller.
+func (p *planner) compare(obj compile.Object, desired, live map[string]interface{}, match func(desired, current json.RawMessage) bool, extra ...string) {
+	if live == nil {
+		p.change(ActionCreate, obj, nil)
+		return
+	}
+	p.dropPlaceholders(desired)
+	details := changedFields(desired, live, match)
+	details = append(details, extra...)
+	if len(details) > 0 {
+		p.change(ActionUpdate, obj, details)
+	}
+}
+
+// changedFields lists the top-level fields of desired that match reports as
+// different from live.
+func changedFields(desired, live map[string]interface{}, match func(desired, current json.RawMessage) bool) []string {
+	// Both hold decoded JSON values only, so encoding cannot fail.
+	liveJSON, _ := json.Marshal(live)
+	desiredJSON, _ := json.Marshal(desired)
+	if match(desiredJSON, liveJSON) {
+		return nil
+	}
+
+	var fields []string
+	for key, value := range desired {
+		fieldJSON, _ := json.Marshal(map[string]interface{}{key: value})
+		if !match(fieldJSON, liveJSON) {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// dropPlaceholders removes the values of missing secrets from def, since
+// their real values are unknown.
+func (p *planner) dropPlaceholders(def map[string]interface{}) {
+	for key, value := range def {
+		switch v := value.(type) {
+		case string:
+			if p.placeholders[v] {
+				delete(def, key)
+			}
+		case map[string]interface{}:
+			p.dropPlaceholders(v)
+		}
+	}
+}
+
+func (p *planner) planObject(ctx context.Context, obj compile.Object, liveRealm map[string]interface{}) error {
+	def := obj.Definition
+	switch source := obj.Source.(type) {
+	case *keycloakv1beta1.KeycloakRealm, *keycloakv1beta1.ClusterKeycloakRealm:
+		p.compare(obj, def, liveRealm, controller.RealmDefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakRequiredAction:
+		live, err := p.find("required-actions", "alias", obj.Name, func() ([]json.RawMessage, error) {
+			return p.kc.GetRequiredActionsRaw(ctx, p.realm)
+		})
+		if err != nil {
+			return err
+		}
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakAuthenticationFlow:
+		return p.planFlow(ctx, obj, source)
+
+	case *keycloakv1beta1.KeycloakClientScope:
+		live, err := p.clientScope(ctx, obj.Name)
+		if err != nil {
+			return err
+		}
+		delete(def, "protocolMappers")
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakClient:
+		live, err := p.client(ctx, obj.Name)
+		if err != nil {
+			return err
+		}
+		delete(def, "protocolMappers")
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakProtocolMapper:
+		var parent map[string]interface{}
+		var err error
+		if source.Spec.ClientRef != nil {
+			parent, err = p.client(ctx, obj.Parent)
+		} else {
+			parent, err = p.clientScope(ctx, obj.Parent)
+		}
+		if err != nil {
+			return err
+		}
+		mappers, _ := parent["protocolMappers"].([]interface{})
+		p.compare(obj, def, findIn(mappers, "name", obj.Name), controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakRole:
+		live, err := p.role(ctx, obj.Parent, obj.Name)
+		if err != nil {
+			return err
+		}
+		// The reconciler manages composites through their own endpoint.
+		delete(def, "composites")
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakGroup:
+		live, err := p.group(ctx, obj.Name)
+		if err != nil {
+			return err
+		}
+		delete(def, "subGroups")
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakUser:
+		return p.planUser(ctx, obj)
+
+	case *keycloakv1beta1.KeycloakRoleMapping:
+		subject, err := p.subject(ctx, def)
+		if err != nil {
+			return err
+		}
+		missing := subject == nil
+		if subject != nil {
+			if missing, err = p.missingRoles(ctx, subject, def); err != nil {
+				return err
+			}
+		}
+		if missing {
+			p.change(ActionCreate, obj, nil)
+		}
+
+	case *keycloakv1beta1.KeycloakIdentityProvider:
+		live, err := p.find("identity-providers", "alias", obj.Name, func() ([]json.RawMessage, error) {
+			return p.kc.GetIdentityProvidersRaw(ctx, p.realm)
+		})
+		if err != nil {
+			return err
+		}
+		if source.Spec.TokenExchange != nil {
+			p.warnf("KeycloakIdentityProvider %s: spec.tokenExchange is not compared", resourceName(source))
+		}
+		p.compare(obj, def, live, controller.IdentityProviderDefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakIdentityProviderMapper:
+		live, err := p.find("identity-provider-mappers/"+obj.Parent, "name", obj.Name, func() ([]json.RawMessage, error) {
+			raws, err := p.kc.GetIdentityProviderMappersRaw(ctx, p.realm, obj.Parent)
+			if keycloak.IsNotFound(err) {
+				return nil, nil
+			}
+			return raws, err
+		})
+		if err != nil {
+			return err
+		}
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakComponent:
+		components, err := p.list("components", func() ([]json.RawMessage, error) {
+			return p.kc.GetComponentsRaw(ctx, p.realm, nil)
+		})
+		if err != nil {
+			return err
+		}
+		var live map[string]interface{}
+		for _, component := range components {
+			if stringField(component, "name") == obj.Name && stringField(component, "providerType") == obj.Parent {
+				live = component
+				break
+			}
+		}
+		def["providerType"] = obj.Parent
+		p.compare(obj, def, live, controller.DefinitionsMatch)
+
+	case *keycloakv1beta1.KeycloakOrganization:
+		live, err := p.find("organizations", "name", obj.Name, func() ([]json.RawMessage, error) {
+			return p.kc.GetOrganizationsRaw(ctx, p.realm)
+		})
+		if err != nil {
+			return err
+		}
+		// Identity providers join organizations through their organizationRef.
+		delete(def, "identityProviders")
+		p.compare(obj, def, live, controller.OrganizationDefinitionsMatch)
+	}
+	return nil
+}
+
+func (p *planner) planFlow(ctx context.Context, obj compile.Object, flow *keycloakv1beta1.KeycloakAuthenticationFlow) error {
+	live, err := p.find("flows", "alias", obj.Name, func() ([]json.RawMessage, error) {
+		return p.kc.GetAuthenticationFlowsRaw(ctx, p.realm)
+	})
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		p.change(ActionCreate, obj, nil)
+		return nil
+	}
+
+	var details []string
+	if provider := stringField(live, "providerId"); provider != flow.Spec.ProviderId {
+		details = append(details, fmt.Sprintf("providerId %q cannot be changed to %q; the flow must be recreated", provider, flow.Spec.ProviderId))
+	}
+	if stringField(live, "description") != flow.Spec.Description {
+		details = append(details, "description")
+	}
+	changes, err := controller.FlowExecutionChanges(ctx, p.kc, p.caps, p.realm, flow)
+	if err != nil {
+		return err
+	}
+	details = append(details, changes...)
+	if len(details) > 0 {
+		p.change(ActionUpdate, obj, details)
+	}
+	return nil
+}
+
+// userMemberships are the fields of compiled users the reconciler applies
+// through the role mapping and group endpoints rather than the user.
+var userMemberships = []string{"realmRoles", "clientRoles", "groups"}
+
+func (p *planner) planUser(ctx context.Context, obj compile.Object) error {
+	def := obj.Definition
+	live, err := p.user(ctx, obj.Name)
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		p.change(ActionCreate, obj, nil)
+		return nil
+	}
+
+	var extra []string
+	missingRoles, err := p.missingRoles(ctx, &subject{kind: "user", id: stringField(live, "id")}, def)
+	if err != nil {
+		return err
+	}
+	if missingRoles {
+		extra = append(extra, "roles")
+	}
+	if groups, _ := def["groups"].([]interface{}); len(groups) > 0 {
+		live, err := p.kc.GetUserGroups(ctx, p.realm, stringField(live, "id"))
+		if err != nil {
+			return fmt.Errorf("failed to get groups of user %s: %w", obj.Name, err)
+		}
+		paths := make(map[string]bool, len(live))
+		for _, g := range live {
+			if g.Path != nil {
+				paths[*g.Path] = true
+			}
+		}
+		for _, path := range groups {
+			if s, _ := path.(string); !paths[s] {
+				extra = append(extra, "groups")
+				break
+			}
+		}
+	}
+
+	// Initial passwords only apply on creation.
+	for _, key := range append(userMemberships, "credentials", "serviceAccountClientId") {
+		delete(def, key)
+	}
+	p.compare(obj, def, live, controller.DefinitionsMatch, extra...)
+	return nil
+}
+
+func (p *planner) warnf(format string, args ...interface{}) {
+	p.result.Warnings = append(p.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Write prints r in a form similar to `terraform plan`.
+func (r *Result) Write(w io.Writer) error {
+	var b strings.Builder
+	symbols := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}
+	counts := map[Action]int{}
+
+	fmt.Fprintf(&b, "Realm %s:\n", r.Realm)
+	for _, change := range r.Changes {
+		counts[change.Action]++
+		if change.Resource == "" {
+			fmt.Fprintf(&b, "  %s %s %s\n", symbols[change.Action], change.Kind, change.Name)
+		} else {
+			fmt.Fprintf(&b, "  %s %s %s (%s)\n", symbols[change.Action], change.Kind, change.Resource, change.Name)
+		}
+		for _, detail := range change.Details {
+			fmt.Fprintf(&b, "      %s\n", detail)
+		}
+	}
+	if len(r.Unmanaged) > 0 {
+		b.WriteString("\nUnmanaged objects (spec.prune DryRun):\n")
+		for _, obj := range r.Unmanaged {
+			fmt.Fprintf(&b, "    %s %s\n", obj.Type, obj.Name)
+		}
+	}
+
+	if !r.HasChanges() {
+		b.WriteString("\nNo changes. Keycloak matches the manifests.\n")
+	} else {
+		fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to delete.\n",
+			counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func kindOf(obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", obj), "*v1beta1.")
+}
+
+func resourceName(obj client.Object) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func roleMappingName(def map[string]interface{}) string {
+	subject := "user " + stringField(def, "user")
+	if group := stringField(def, "group"); group != "" {
+		subject = "group " + group
+	}
+	if roles, _ := def["realmRoles"].([]interface{}); len(roles) > 0 {
+		return fmt.Sprintf("%s: %v", subject, roles[0])
+	}
+	clientRoles, _ := def["clientRoles"].(map[string]interface{})
+	for clientID, roles := range clientRoles {
+		if list, _ := roles.([]interface{}); len(list) > 0 {
+			return fmt.Sprintf("%s: %s/%v", subject, clientID, list[0])
+		}
+	}
+	return subject
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
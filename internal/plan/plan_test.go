@@ -0,0 +1,324 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/keycloak/fake"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// load decodes manifests the way the plan subcommand reads them.
+func load(t *testing.T, manifests string) []client.Object {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(manifests), 0644))
+	objects, err := manifest.Load("team", path)
+	require.NoError(t, err)
+	return objects
+}
+
+const manifests = `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  realmName: test
+  definition:
+    enabled: true
+    displayName: New
+  prune:
+    clients: Delete
+    groups: DryRun
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakAuthenticationFlow
+metadata:
+  name: flow
+spec:
+  realmRef:
+    name: realm
+  alias: custom
+  providerId: basic-flow
+  executions:
+  - authenticator: auth-cookie
+    requirement: ALTERNATIVE
+  - authenticator: auth-otp-form
+    requirement: ALTERNATIVE
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  realmRef:
+    name: realm
+  clientId: app
+  definition:
+    publicClient: true
+    redirectUris: ["https://b.example.com/*"]
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: reader
+spec:
+  realmRef:
+    name: realm
+  name: reader
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: writer
+spec:
+  realmRef:
+    name: realm
+  name: writer
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: team
+spec:
+  realmRef:
+    name: realm
+  name: team
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUser
+metadata:
+  name: alice
+spec:
+  realmRef:
+    name: realm
+  username: alice
+  definition:
+    email: alice@example.com
+    enabled: true
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: alice-reader
+spec:
+  subject:
+    userRef:
+      name: alice
+  roleRef:
+    name: reader
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: alice-writer
+spec:
+  subject:
+    userRef:
+      name: alice
+  roleRef:
+    name: writer
+`
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true,"displayName":"Old"}`)))
+	_, err := kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"app","publicClient":true,"redirectUris":["https://a.example.com/*"]}`))
+	require.NoError(t, err)
+	_, err = kc.CreateClient(ctx, "test", json.RawMessage(`{"clientId":"stray"}`))
+	require.NoError(t, err)
+	_, err = kc.CreateRealmRole(ctx, "test", json.RawMessage(`{"name":"reader"}`))
+	require.NoError(t, err)
+	for _, group := range []string{`{"name":"team"}`, `{"name":"orphans"}`} {
+		_, err = kc.CreateGroup(ctx, "test", json.RawMessage(group))
+		require.NoError(t, err)
+	}
+	userID, err := kc.CreateUser(ctx, "test", json.RawMessage(`{"username":"alice","email":"alice@example.com","enabled":true}`))
+	require.NoError(t, err)
+	reader, err := kc.GetRealmRole(ctx, "test", "reader")
+	require.NoError(t, err)
+	require.NoError(t, kc.AddRealmRolesToUser(ctx, "test", userID, []keycloak.RoleRepresentation{*reader}))
+	alias, providerID, topLevel, alternative := "custom", "basic-flow", true, "ALTERNATIVE"
+	_, err = kc.CreateAuthenticationFlow(ctx, "test", keycloak.AuthenticationFlowRepresentation{
+		Alias:      &alias,
+		ProviderID: &providerID,
+		TopLevel:   &topLevel,
+	})
+	require.NoError(t, err)
+	_, err = kc.AddFlowExecution(ctx, "test", "custom", "auth-cookie")
+	require.NoError(t, err)
+	execs, err := kc.GetFlowExecutions(ctx, "test", "custom")
+	require.NoError(t, err)
+	execs[0].Requirement = &alternative
+	require.NoError(t, kc.UpdateFlowExecution(ctx, "test", "custom", execs[0]))
+
+	result, err := Plan(ctx, kc, load(t, manifests), Options{})
+	require.NoError(t, err)
+	require.Equal(t, "test", result.Realm)
+	require.Equal(t, []Change{
+		{Action: ActionCreate, Kind: "KeycloakRole", Resource: "team/writer", Name: "writer"},
+		{Action: ActionCreate, Kind: "KeycloakRoleMapping", Resource: "team/alice-writer", Name: "user alice: writer"},
+		{Action: ActionUpdate, Kind: "KeycloakRealm", Resource: "team/realm", Name: "test", Details: []string{"displayName"}},
+		{Action: ActionUpdate, Kind: "KeycloakAuthenticationFlow", Resource: "team/flow", Name: "custom",
+			Details: []string{`add authenticator "auth-otp-form" to flow "custom"`}},
+		{Action: ActionUpdate, Kind: "KeycloakClient", Resource: "team/app", Name: "app", Details: []string{"redirectUris"}},
+		{Action: ActionDelete, Kind: "clients", Name: "stray"},
+	}, result.Changes)
+	require.Equal(t, []keycloakv1beta1.UnmanagedObject{{Type: "groups", Name: "orphans"}}, result.Unmanaged)
+	require.True(t, result.HasChanges())
+
+	stray, err := kc.GetClients(ctx, "test", map[string]string{"clientId": "stray"})
+	require.NoError(t, err)
+	require.Len(t, stray, 1, "plan deleted objects")
+
+	var out bytes.Buffer
+	require.NoError(t, result.Write(&out))
+	require.Contains(t, out.String(), "  ~ KeycloakClient team/app (app)\n      redirectUris\n")
+	require.Contains(t, out.String(), "  - clients stray\n")
+	require.Contains(t, out.String(), "Plan: 2 to create, 3 to update, 1 to delete.\n")
+}
+
+func TestPlanNewRealm(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	objects := load(t, manifests)
+	result, err := Plan(ctx, kc, objects, Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Changes, len(objects))
+	for _, change := range result.Changes {
+		require.Equal(t, ActionCreate, change.Action, change.Kind)
+	}
+}
+
+func TestPlanNoChanges(t *testing.T) {
+	ctx := context.Background()
+	srv := fake.NewServer(fake.WithVersion("24.0.5"))
+	defer srv.Close()
+	kc := keycloak.NewClient(keycloak.Config{
+		BaseURL:  srv.URL,
+		Username: fake.AdminUsername,
+		Password: fake.AdminPassword,
+	}, testr.New(t))
+
+	require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true,"displayName":"New"}`)))
+	_, err := kc.CreateIdentityProvider(ctx, "test", json.RawMessage(`{"alias":"corp","providerId":"oidc","config":{"clientId":"kc","clientSecret":"s3cret"}}`))
+	require.NoError(t, err)
+
+	objects := load(t, `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  realmName: test
+  definition:
+    enabled: true
+    displayName: New
+---
+# The secret is missing, so its placeholder is not compared.
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakIdentityProvider
+metadata:
+  name: corp
+spec:
+  realmRef:
+    name: realm
+  alias: corp
+  definition:
+    providerId: oidc
+    config:
+      clientId: kc
+  configSecretRef:
+    name: corp-oidc
+`)
+	result, err := Plan(ctx, kc, objects, Options{})
+	require.NoError(t, err)
+	require.Empty(t, result.Changes)
+	require.False(t, result.HasChanges())
+
+	var out bytes.Buffer
+	require.NoError(t, result.Write(&out))
+	require.Contains(t, out.String(), "No changes.")
+}
+
+// TestPlanNestedGroup finds a subgroup on servers with and without the
+// children endpoint.
+func TestPlanNestedGroup(t *testing.T) {
+	for _, version := range []string{"22.0.5", "24.0.5"} {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+			srv := fake.NewServer(fake.WithVersion(version))
+			defer srv.Close()
+			kc := keycloak.NewClient(keycloak.Config{
+				BaseURL:  srv.URL,
+				Username: fake.AdminUsername,
+				Password: fake.AdminPassword,
+			}, testr.New(t))
+
+			require.NoError(t, kc.CreateRealmFromDefinition(ctx, json.RawMessage(`{"realm":"test","enabled":true}`)))
+			teamID, err := kc.CreateGroup(ctx, "test", json.RawMessage(`{"name":"team"}`))
+			require.NoError(t, err)
+			_, err = kc.CreateChildGroup(ctx, "test", teamID, json.RawMessage(`{"name":"sub"}`))
+			require.NoError(t, err)
+
+			result, err := Plan(ctx, kc, load(t, `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  realmName: test
+  definition:
+    enabled: true
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: team
+spec:
+  realmRef:
+    name: realm
+  name: team
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: sub
+spec:
+  parentGroupRef:
+    name: team
+  name: sub
+  definition: {}
+`), Options{})
+			require.NoError(t, err)
+			require.Empty(t, result.Changes)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	apiextensionsinternal "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	structurallisttype "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/listtype"
+	structuralpruning "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/config/crd"
+)
+
+// crdSchema validates objects of one kind and version as the API server
+// does on create.
+type crdSchema struct {
+	structural *structuralschema.Structural
+	validator  apiservervalidation.SchemaValidator
+	cel        *cel.Validator
+}
+
+// loadSchemas reads the schemas of every version of the embedded CRDs.
+func loadSchemas() (map[schema.GroupVersionKind]*crdSchema, error) {
+	files, err := fs.Glob(crd.Bases, "bases/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemas := map[schema.GroupVersionKind]*crdSchema{}
+	for _, file := range files {
+		data, err := crd.Bases.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var def apiextensionsv1.CustomResourceDefinition
+		if err := yaml.Unmarshal(data, &def); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, version := range def.Spec.Versions {
+			if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			s, err := newCRDSchema(version.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", def.Spec.Names.Kind, version.Name, err)
+			}
+			gvk := schema.GroupVersionKind{Group: def.Spec.Group, Version: version.Name, Kind: def.Spec.Names.Kind}
+			schemas[gvk] = s
+		}
+	}
+	return schemas, nil
+}
+
+func newCRDSchema(validation *apiextensionsv1.CustomResourceValidation) (*crdSchema, error) {
+	internal := &apiextensionsinternal.CustomResourceValidation{}
+	if err := apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(validation, internal, nil); err != nil {
+		return nil, err
+	}
+	structural, err := structuralschema.NewStructural(internal.OpenAPIV3Schema)
+	if err != nil {
+		return nil, err
+	}
+	validator, _, err := apiservervalidation.NewSchemaValidator(internal.OpenAPIV3Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &crdSchema{
+		structural: structural,
+		validator:  validator,
+		cel:        cel.NewValidator(structural, true, celconfig.PerCallLimit),
+	}, nil
+}
+
+// validate checks the JSON document raw against s: unknown fields, the
+// OpenAPI schema and the x-kubernetes-validations rules. Defaults are
+// applied first, as the API server does.
+func (s *crdSchema) validate(ctx context.Context, raw []byte) (field.ErrorList, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	// Status is written by the operator and not part of a manifest.
+	delete(obj, "status")
+
+	var errs field.ErrorList
+	unknown := structuralpruning.PruneWithOptions(obj, s.structural, true, structuralschema.UnknownFieldPathOptions{
+		TrackUnknownFieldPaths: true,
+	})
+	for _, path := range unknown {
+		errs = append(errs, field.Forbidden(field.NewPath(path), "unknown field"))
+	}
+	structuraldefaulting.Default(obj, s.structural)
+
+	errs = append(errs, apiservervalidation.ValidateCustomResource(nil, obj, s.validator)...)
+	errs = append(errs, structurallisttype.ValidateListSetsAndMaps(nil, s.structural, obj)...)
+	if len(errs) > 0 {
+		// Rules may rely on the types the schema guarantees.
+		return errs, nil
+	}
+	celErrs, _ := s.cel.Validate(ctx, nil, s.structural, obj, nil, celconfig.RuntimeCELCostBudget)
+	return append(errs, celErrs...), nil
+}
@@ -0,0 +1,216 @@
+// Package validate checks operator manifests offline: against the CRD
+// schemas, the reconcilers' own spec checks, and each other, without a
+// cluster or Keycloak.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	keycloakv1beta1 "github.com/Hostzero-GmbH/keycloak-operator/api/v1beta1"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/controller"
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+// Issue is one problem found in a manifest.
+type Issue struct {
+	// File is the file the object was read from.
+	File string
+
+	Kind      string
+	Namespace string
+	Name      string
+
+	Message string
+}
+
+// Result is the outcome of Validate.
+type Result struct {
+	// Objects is the number of objects checked.
+	Objects int
+
+	// Issues are in the order of the documents they were found in.
+	Issues []Issue
+}
+
+// Write prints the issues and a summary line.
+func (r *Result) Write(w io.Writer) error {
+	var b strings.Builder
+	for _, issue := range r.Issues {
+		name := issue.Name
+		if issue.Namespace != "" {
+			name = issue.Namespace + "/" + name
+		}
+		fmt.Fprintf(&b, "%s: %s %s: %s\n", issue.File, issue.Kind, name, issue.Message)
+	}
+	if len(r.Issues) == 0 {
+		fmt.Fprintf(&b, "Validated %d objects: no issues found.\n", r.Objects)
+	} else {
+		fmt.Fprintf(&b, "\nValidated %d objects: %d issues found.\n", r.Objects, len(r.Issues))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var schemas = sync.OnceValues(loadSchemas)
+
+// Validate checks docs, which are the complete set of manifests: a
+// reference to an object that is not among them is reported as dangling.
+//
+// Each object is checked against the schema of its CRD, including unknown
+// fields and x-kubernetes-validations rules, and with the checks its
+// reconciler makes before calling Keycloak. Across objects, Validate
+// reports references to missing objects and identifiers used twice in the
+// same scope, such as two clients with the same clientId in one realm.
+func Validate(ctx context.Context, docs []manifest.Document) (*Result, error) {
+	crdSchemas, err := schemas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CRD schemas: %w", err)
+	}
+
+	objects := make([]client.Object, 0, len(docs))
+	for _, doc := range docs {
+		objects = append(objects, doc.Object.DeepCopyObject().(client.Object))
+	}
+	c := fake.NewClientBuilder().WithScheme(manifest.Scheme).WithObjects(objects...).Build()
+
+	result := &Result{Objects: len(docs)}
+	seen := map[string]manifest.Document{}
+	for _, doc := range docs {
+		obj := doc.Object
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if gvk.Group != keycloakv1beta1.GroupVersion.Group {
+			continue
+		}
+		report := func(format string, args ...interface{}) {
+			result.Issues = append(result.Issues, Issue{
+				File:      doc.File,
+				Kind:      gvk.Kind,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+
+		s, ok := crdSchemas[gvk]
+		if !ok {
+			report("no CRD schema for %s", gvk.GroupVersion())
+			continue
+		}
+		schemaErrs, err := s.validate(ctx, doc.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.File, err)
+		}
+		for _, err := range schemaErrs {
+			report("%s", err.Error())
+		}
+
+		missing, err := controller.MissingReferences(ctx, c, obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range missing {
+			report("references %s, which is not defined", ref)
+		}
+
+		// The reconcilers only see objects the API server accepted.
+		if len(schemaErrs) > 0 {
+			continue
+		}
+		id, specErrs := controller.ValidateSpec(ctx, c, obj)
+		for _, err := range specErrs {
+			report("%s", err.Error())
+		}
+		if id == "" || len(specErrs) > 0 || len(missing) > 0 {
+			continue
+		}
+		scope, key, ok := identifierScope(ctx, c, gvk, obj, id)
+		if !ok {
+			continue
+		}
+		if other, dup := seen[key]; dup {
+			report("%q is also used by %s %s (%s) in %s", id, other.Object.GetObjectKind().GroupVersionKind().Kind, qualifiedName(other.Object), other.File, scope)
+			continue
+		}
+		seen[key] = doc
+	}
+	return result, nil
+}
+
+// identifierScope returns the scope in which Keycloak requires the
+// identifier id of obj to be unique, and a key for id within it. ok is false
+// for kinds without a unique identifier.
+func identifierScope(ctx context.Context, c client.Client, gvk schema.GroupVersionKind, obj client.Object, id string) (scope, key string, ok bool) {
+	namespace := obj.GetNamespace()
+	switch o := obj.(type) {
+	case *keycloakv1beta1.KeycloakRealm:
+		switch {
+		case o.Spec.InstanceRef != nil:
+			scope = "KeycloakInstance " + namespace + "/" + o.Spec.InstanceRef.Name
+		case o.Spec.ClusterInstanceRef != nil:
+			scope = "ClusterKeycloakInstance " + o.Spec.ClusterInstanceRef.Name
+		}
+		return scope, scope + "\x00realm\x00" + id, scope != ""
+	case *keycloakv1beta1.ClusterKeycloakRealm:
+		switch {
+		case o.Spec.InstanceRef != nil:
+			scope = "KeycloakInstance " + o.Spec.InstanceRef.Namespace + "/" + o.Spec.InstanceRef.Name
+		case o.Spec.ClusterInstanceRef != nil:
+			scope = "ClusterKeycloakInstance " + o.Spec.ClusterInstanceRef.Name
+		}
+		return scope, scope + "\x00realm\x00" + id, scope != ""
+	case *keycloakv1beta1.KeycloakRole:
+		if o.Spec.ClientRef != nil {
+			scope = "KeycloakClient " + namespace + "/" + o.Spec.ClientRef.Name
+			return scope, scope + "\x00role\x00" + id, true
+		}
+	case *keycloakv1beta1.KeycloakGroup:
+		if o.Spec.ParentGroupRef != nil {
+			scope = "KeycloakGroup " + namespace + "/" + o.Spec.ParentGroupRef.Name
+			return scope, scope + "\x00group\x00" + id, true
+		}
+	case *keycloakv1beta1.KeycloakProtocolMapper:
+		switch {
+		case o.Spec.ClientRef != nil:
+			scope = "KeycloakClient " + namespace + "/" + o.Spec.ClientRef.Name
+		case o.Spec.ClientScopeRef != nil:
+			scope = "KeycloakClientScope " + namespace + "/" + o.Spec.ClientScopeRef.Name
+		}
+		return scope, scope + "\x00mapper\x00" + id, scope != ""
+	case *keycloakv1beta1.KeycloakIdentityProviderMapper:
+		scope = "KeycloakIdentityProvider " + namespace + "/" + o.Spec.IdentityProviderRef.Name
+		return scope, scope + "\x00mapper\x00" + id, true
+	case *keycloakv1beta1.KeycloakUser:
+		// Keycloak stores usernames in lower case.
+		id = strings.ToLower(id)
+	case *keycloakv1beta1.KeycloakComponent:
+		// Component names are not unique, e.g. the mappers of two LDAP
+		// providers.
+		return "", "", false
+	}
+
+	realm, err := controller.PlacementRealm(ctx, c, obj)
+	if err != nil {
+		return "", "", false
+	}
+	if realm.ClusterRealmRef != "" {
+		scope = "ClusterKeycloakRealm " + realm.ClusterRealmRef
+	} else {
+		scope = "KeycloakRealm " + realm.RealmRef
+	}
+	return scope, scope + "\x00" + gvk.Kind + "\x00" + id, true
+}
+
+func qualifiedName(obj client.Object) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
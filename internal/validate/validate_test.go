@@ -0,0 +1,270 @@
+package validate
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Hostzero-GmbH/keycloak-operator/internal/manifest"
+)
+
+const validManifests = `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakInstance
+metadata:
+  name: keycloak
+spec:
+  baseUrl: http://keycloak:8080
+  auth:
+    passwordGrant:
+      secretRef:
+        name: admin
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRealm
+metadata:
+  name: realm
+spec:
+  instanceRef:
+    name: keycloak
+  realmName: test
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app
+spec:
+  realmRef:
+    name: realm
+  clientId: app
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: app-admin
+spec:
+  clientRef:
+    name: app
+  name: admin
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: admin
+spec:
+  realmRef:
+    name: realm
+  name: admin
+  definition: {}
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUser
+metadata:
+  name: alice
+spec:
+  realmRef:
+    name: realm
+  username: alice
+---
+apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: alice-admin
+spec:
+  subject:
+    userRef:
+      name: alice
+  roleRef:
+    name: admin
+`
+
+func load(t *testing.T, content string) []manifest.Document {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	docs, err := manifest.LoadDocuments("team", path)
+	require.NoError(t, err)
+	return docs
+}
+
+func TestValidate(t *testing.T) {
+	result, err := Validate(context.Background(), load(t, validManifests))
+	require.NoError(t, err)
+	require.Empty(t, result.Issues)
+
+	var out bytes.Buffer
+	require.NoError(t, result.Write(&out))
+	require.Equal(t, "Validated 7 objects: no issues found.\n", out.String())
+}
+
+func TestValidateSamples(t *testing.T) {
+	docs, err := manifest.LoadDocuments("default", "../../config/samples")
+	require.NoError(t, err)
+	result, err := Validate(context.Background(), docs)
+	require.NoError(t, err)
+	require.Empty(t, result.Issues)
+}
+
+func TestValidateIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		issue    string
+	}{
+		{
+			name: "unknown field",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: web
+spec:
+  realmRef:
+    name: realm
+  clientId: web
+  publicClient: true
+`,
+			issue: "KeycloakClient team/web: spec.publicClient: Forbidden: unknown field",
+		},
+		{
+			name: "schema",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: viewer
+spec:
+  realmRef:
+    name: realm
+  name: viewer
+`,
+			issue: "KeycloakRole team/viewer: spec.definition: Required value",
+		},
+		{
+			name: "CEL rule",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: web
+spec:
+  clientId: web
+`,
+			issue: "KeycloakClient team/web: spec: Invalid value: exactly one of realmRef or clusterRealmRef must be set",
+		},
+		{
+			name: "reconciler check",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: web
+spec:
+  realmRef:
+    name: realm
+  clientId: web
+  definition:
+    protocolMappers: []
+`,
+			issue: "KeycloakClient team/web: spec.definition.protocolMappers is not supported",
+		},
+		{
+			name: "identifier mismatch",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: web
+spec:
+  realmRef:
+    name: realm
+  clientId: web
+  definition:
+    clientId: website
+`,
+			issue: `KeycloakClient team/web: the identifier in spec.definition ("website") conflicts with spec.clientId ("web")`,
+		},
+		{
+			name: "dangling parent group",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakGroup
+metadata:
+  name: ops
+spec:
+  parentGroupRef:
+    name: staff
+  name: ops
+  definition: {}
+`,
+			issue: "KeycloakGroup team/ops: references KeycloakGroup team/staff, which is not defined",
+		},
+		{
+			name: "missing role mapping target",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRoleMapping
+metadata:
+  name: alice-auditor
+spec:
+  subject:
+    userRef:
+      name: alice
+  roleRef:
+    name: auditor
+`,
+			issue: "KeycloakRoleMapping team/alice-auditor: references KeycloakRole team/auditor, which is not defined",
+		},
+		{
+			name: "duplicate clientId",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakClient
+metadata:
+  name: app2
+spec:
+  realmRef:
+    name: realm
+  clientId: app
+`,
+			issue: `KeycloakClient team/app2: "app" is also used by KeycloakClient team/app (`,
+		},
+		{
+			name: "duplicate username",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakUser
+metadata:
+  name: alice2
+spec:
+  realmRef:
+    name: realm
+  username: Alice
+`,
+			issue: `KeycloakUser team/alice2: "Alice" is also used by KeycloakUser team/alice (`,
+		},
+		{
+			name: "duplicate client role",
+			manifest: `apiVersion: keycloak.hostzero.com/v1beta1
+kind: KeycloakRole
+metadata:
+  name: app-admin2
+spec:
+  clientRef:
+    name: app
+  name: admin
+  definition: {}
+`,
+			issue: `KeycloakRole team/app-admin2: "admin" is also used by KeycloakRole team/app-admin (`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Validate(context.Background(), load(t, validManifests+"---\n"+tt.manifest))
+			require.NoError(t, err)
+			require.Len(t, result.Issues, 1, "%+v", result.Issues)
+
+			var out bytes.Buffer
+			require.NoError(t, result.Write(&out))
+			require.Contains(t, out.String(), tt.issue)
+			require.Contains(t, out.String(), "Validated 8 objects: 1 issues found.\n")
+		})
+	}
+}